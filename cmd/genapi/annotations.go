@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// apiInfo holds the general API annotations from cmd/server/main.go
+type apiInfo struct {
+	Title, Version, Description, TermsOfService string
+	ContactName, ContactURL, ContactEmail       string
+	LicenseName, LicenseURL                     string
+	Host, BasePath                              string
+	SecurityName, SecurityIn, SecurityHeader    string
+}
+
+// operation is one handler's godoc annotations
+type operation struct {
+	Handler     string // e.g. AuthHandler.Login
+	Pos         string
+	Imports     map[string]string // imports of the handler's package (its own file first), to resolve model types
+	Summary     string
+	Description string
+	Tags        []string
+	Accept      []string
+	Produce     []string
+	Security    []string
+	Params      []param
+	Responses   []response
+	Path        string // as written in @Router, e.g. /users/{id}/profile
+	Method      string // lowercase
+}
+
+type param struct {
+	Name, In, Type, Description string
+	Required                    bool
+	Enum                        []string
+}
+
+type response struct {
+	Code        int
+	Kind        string // object, array or file
+	Type        string // e.g. model.UserResponse
+	Description string
+}
+
+var (
+	paramPattern    = regexp.MustCompile(`^(\S+)\s+(\S+)\s+(\S+)\s+(true|false)(?:\s+"([^"]*)")?(?:\s+Enums\(([^)]*)\))?$`)
+	responsePattern = regexp.MustCompile(`^(\d+)\s+\{(\w+)\}\s+(\S+)(?:\s+"([^"]*)")?$`)
+	routerPattern   = regexp.MustCompile(`^(\S+)\s+\[(\w+)\]$`)
+)
+
+// parseInfo reads the general API annotations (@title, @host, ...) of a file
+func parseInfo(file string) (apiInfo, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+	if err != nil {
+		return apiInfo{}, err
+	}
+
+	var info apiInfo
+	for _, group := range f.Comments {
+		for _, c := range group.List {
+			key, value, ok := annotation(c.Text)
+			if !ok {
+				continue
+			}
+			switch key {
+			case "title":
+				info.Title = value
+			case "version":
+				info.Version = value
+			case "description":
+				info.Description = value
+			case "termsOfService":
+				info.TermsOfService = value
+			case "contact.name":
+				info.ContactName = value
+			case "contact.url":
+				info.ContactURL = value
+			case "contact.email":
+				info.ContactEmail = value
+			case "license.name":
+				info.LicenseName = value
+			case "license.url":
+				info.LicenseURL = value
+			case "host":
+				info.Host = value
+			case "BasePath":
+				info.BasePath = value
+			case "securityDefinitions.apikey":
+				info.SecurityName = value
+			case "in":
+				info.SecurityIn = value
+			case "name":
+				info.SecurityHeader = value
+			}
+		}
+	}
+	if info.Title == "" || info.BasePath == "" {
+		return info, fmt.Errorf("%s: missing @title or @BasePath", file)
+	}
+	return info, nil
+}
+
+// parseOperations reads the annotations of every handler method in dir
+func parseOperations(dir string) (map[string]*operation, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	fset := token.NewFileSet()
+	ops := map[string]*operation{}
+	pkgImports := map[string]string{}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		imports := fileImports(f)
+		for name, path := range imports {
+			pkgImports[name] = path
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || fn.Doc == nil {
+				continue
+			}
+			op, err := parseOperation(fn.Doc)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", fset.Position(fn.Pos()), err)
+			}
+			if op == nil {
+				continue
+			}
+			op.Handler = receiverName(fn.Recv) + "." + fn.Name.Name
+			op.Pos = fset.Position(fn.Pos()).String()
+			op.Imports = imports
+			ops[op.Handler] = op
+		}
+	}
+
+	// Annotations may name types (e.g. model.ErrorResponse) that only other
+	// files of the package import
+	for _, op := range ops {
+		for name, path := range pkgImports {
+			if _, ok := op.Imports[name]; !ok {
+				op.Imports[name] = path
+			}
+		}
+	}
+	return ops, nil
+}
+
+// parseOperation parses one doc comment, returning nil if it has no @Router
+func parseOperation(doc *ast.CommentGroup) (*operation, error) {
+	op := &operation{}
+	for _, c := range doc.List {
+		key, value, ok := annotation(c.Text)
+		if !ok {
+			continue
+		}
+		switch key {
+		case "Summary":
+			op.Summary = value
+		case "Description":
+			op.Description = strings.TrimSpace(op.Description + " " + value)
+		case "Tags":
+			op.Tags = splitList(value)
+		case "Accept":
+			op.Accept = splitList(value)
+		case "Produce":
+			op.Produce = splitList(value)
+		case "Security":
+			op.Security = append(op.Security, value)
+		case "Param":
+			m := paramPattern.FindStringSubmatch(value)
+			if m == nil {
+				return nil, fmt.Errorf("malformed @Param %q", value)
+			}
+			op.Params = append(op.Params, param{
+				Name: m[1], In: m[2], Type: m[3], Required: m[4] == "true",
+				Description: m[5], Enum: splitList(m[6]),
+			})
+		case "Success", "Failure":
+			m := responsePattern.FindStringSubmatch(value)
+			if m == nil {
+				return nil, fmt.Errorf("malformed @%s %q", key, value)
+			}
+			code, _ := strconv.Atoi(m[1])
+			op.Responses = append(op.Responses, response{Code: code, Kind: m[2], Type: m[3], Description: m[4]})
+		case "Router":
+			m := routerPattern.FindStringSubmatch(value)
+			if m == nil {
+				return nil, fmt.Errorf("malformed @Router %q", value)
+			}
+			op.Path, op.Method = m[1], strings.ToLower(m[2])
+		}
+	}
+	if op.Path == "" {
+		return nil, nil
+	}
+	return op, nil
+}
+
+// annotation splits "// @key value" into key and value
+func annotation(comment string) (string, string, bool) {
+	text := strings.TrimSpace(strings.TrimPrefix(comment, "//"))
+	if !strings.HasPrefix(text, "@") {
+		return "", "", false
+	}
+	key, value, _ := strings.Cut(text[1:], " ")
+	return key, strings.TrimSpace(value), true
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func receiverName(recv *ast.FieldList) string {
+	expr := recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// readModule returns the module path from go.mod
+func readModule(root string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(line, "module "); ok {
+			return strings.TrimSpace(rest), nil
+		}
+	}
+	return "", fmt.Errorf("no module line in go.mod")
+}
@@ -0,0 +1,573 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+const generatedHeader = "// Code generated by cmd/genapi from the API's routes and DTOs. DO NOT EDIT.\n\n"
+
+// initialisms are written in capitals in Go names, e.g. user_id -> UserID
+var initialisms = map[string]string{
+	"id": "ID", "ids": "IDs", "url": "URL", "uri": "URI", "uris": "URIs",
+	"ip": "IP", "html": "HTML", "json": "JSON", "api": "API", "http": "HTTP",
+}
+
+// goClient renders the Go client package from the spec: one struct per
+// component schema, one service per handler with a method per operation, and
+// the WebSocket event types
+func goClient(doc *document) (map[string][]byte, error) {
+	g := &goGenerator{doc: doc, names: map[string]string{}, taken: map[string]bool{}}
+	for _, component := range sortedKeys(doc.Components.Schemas) {
+		if strings.HasPrefix(component, "ws.") {
+			continue
+		}
+		name := goComponentName(component)
+		if g.taken[name] {
+			return nil, fmt.Errorf("client: %s and another schema are both named %s", component, name)
+		}
+		g.names[component] = name
+		g.taken[name] = true
+	}
+
+	files := map[string][]byte{}
+	for file, render := range map[string]func() (string, error){
+		"types.gen.go":  g.types,
+		"api.gen.go":    g.api,
+		"events.gen.go": g.events,
+	} {
+		body, err := render()
+		if err != nil {
+			return nil, fmt.Errorf("client: %s: %v", file, err)
+		}
+		imports, err := goImports(body)
+		if err != nil {
+			return nil, fmt.Errorf("client: %s: %v", file, err)
+		}
+		data, err := format.Source([]byte(generatedHeader + "package client\n\n" + imports + body))
+		if err != nil {
+			return nil, fmt.Errorf("client: %s: %v", file, err)
+		}
+		files[file] = data
+	}
+	return files, nil
+}
+
+type goGenerator struct {
+	doc   *document
+	names map[string]string // component -> Go type name
+	taken map[string]bool   // Go type names in use
+}
+
+// types declares the component schemas
+func (g *goGenerator) types() (string, error) {
+	var b strings.Builder
+	for _, component := range sortedKeys(g.names) {
+		s := g.doc.Components.Schemas[component]
+		name := g.names[component]
+		fields, err := g.structFields(s)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", component, err)
+		}
+		comment(&b, "", s.Description)
+		fmt.Fprintf(&b, "type %s struct {\n%s}\n\n", name, fields)
+	}
+	return b.String(), nil
+}
+
+// structFields declares the properties of an object schema as struct fields
+func (g *goGenerator) structFields(s *Schema) (string, error) {
+	required := map[string]bool{}
+	for _, name := range s.Required {
+		required[name] = true
+	}
+
+	var b strings.Builder
+	seen := map[string]bool{}
+	for _, prop := range sortedKeys(s.Properties) {
+		p := s.Properties[prop]
+		field := goName(prop)
+		if seen[field] {
+			return "", fmt.Errorf("properties map to the same field %s", field)
+		}
+		seen[field] = true
+
+		typ, err := g.goType(p)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", prop, err)
+		}
+		if p.Ref != "" && !required[prop] {
+			typ = "*" + typ
+		}
+		tag := prop
+		if !required[prop] {
+			if typ == "time.Time" || typ == "uuid.UUID" {
+				tag += ",omitzero"
+			} else {
+				tag += ",omitempty"
+			}
+		}
+		comment(&b, "\t", p.Description)
+		fmt.Fprintf(&b, "\t%s %s `json:%q`\n", field, typ, tag)
+	}
+	return b.String(), nil
+}
+
+// goType is the Go type of a schema
+func (g *goGenerator) goType(s *Schema) (string, error) {
+	if s.Ref != "" {
+		component := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+		name, ok := g.names[component]
+		if !ok {
+			return "", fmt.Errorf("no client type for %s", component)
+		}
+		return name, nil
+	}
+
+	var typ string
+	switch s.Type {
+	case "string":
+		switch s.Format {
+		case "date-time":
+			typ = "time.Time"
+		case "uuid":
+			typ = "uuid.UUID"
+		case "byte":
+			return "[]byte", nil
+		default:
+			typ = "string"
+		}
+	case "integer":
+		typ = "int"
+		if s.Format == "int64" {
+			typ = "int64"
+		}
+	case "number":
+		typ = "float64"
+	case "boolean":
+		typ = "bool"
+	case "array":
+		items, err := g.goType(s.Items)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + items, nil
+	case "object":
+		switch {
+		case s.AdditionalProperties != nil:
+			values, err := g.goType(s.AdditionalProperties)
+			if err != nil {
+				return "", err
+			}
+			return "map[string]" + values, nil
+		case len(s.Properties) > 0:
+			fields, err := g.structFields(s)
+			if err != nil {
+				return "", err
+			}
+			typ = "struct {\n" + fields + "}"
+		default:
+			return "json.RawMessage", nil
+		}
+	default:
+		return "json.RawMessage", nil // any JSON value
+	}
+	if s.Nullable {
+		typ = "*" + typ
+	}
+	return typ, nil
+}
+
+// clientOperation is an operation as a client method
+type clientOperation struct {
+	Service, Method string
+	HTTPMethod      string
+	Path            string
+	Op              *operationObject
+}
+
+// api declares the services and their methods
+func (g *goGenerator) api() (string, error) {
+	services := map[string][]clientOperation{}
+	for _, path := range sortedKeys(g.doc.Paths) {
+		for method, op := range g.doc.Paths[path] {
+			handlerName, methodName, _ := strings.Cut(op.OperationID, ".")
+			service := strings.TrimSuffix(handlerName, "Handler")
+			services[service] = append(services[service], clientOperation{
+				Service: service, Method: methodName, HTTPMethod: strings.ToUpper(method), Path: path, Op: op,
+			})
+		}
+	}
+	names := sortedKeys(services)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// BasePath is where the REST API is mounted\nconst BasePath = %q\n\n", g.doc.basePath)
+	b.WriteString("// services are the API's endpoints, grouped like the server's handlers\ntype services struct {\n")
+	for _, service := range names {
+		fmt.Fprintf(&b, "\t%s *%sService\n", service, service)
+	}
+	b.WriteString("}\n\nfunc (s *services) init(c *Client) {\n")
+	for _, service := range names {
+		fmt.Fprintf(&b, "\ts.%s = &%sService{client: c}\n", service, service)
+	}
+	b.WriteString("}\n\n")
+
+	for _, service := range names {
+		ops := services[service]
+		sort.Slice(ops, func(i, j int) bool { return ops[i].Method < ops[j].Method })
+		fmt.Fprintf(&b, "// %sService calls the endpoints of the server's %sHandler\ntype %sService struct {\n\tclient *Client\n}\n\n", service, service, service)
+		for _, op := range ops {
+			if err := g.method(&b, op); err != nil {
+				return "", fmt.Errorf("%s.%s: %v", service, op.Method, err)
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// method declares one operation's method, with its query parameters or form
+// type when it has them. Operations without a success response (browser
+// redirects) have no method.
+func (g *goGenerator) method(b *strings.Builder, op clientOperation) error {
+	codes := sortedKeys(op.Op.Responses)
+	var success *responseObject
+	for _, code := range codes {
+		if strings.HasPrefix(code, "2") {
+			success = op.Op.Responses[code]
+			break
+		}
+	}
+	if success == nil {
+		return nil
+	}
+
+	args := []string{"ctx context.Context"}
+	var query []parameterObject
+	var headers []string
+	for _, p := range op.Op.Parameters {
+		switch p.In {
+		case "path":
+			args = append(args, lowerName(p.Name)+" string")
+		case "query":
+			query = append(query, p)
+		case "header":
+			headers = append(headers, p.Name)
+		}
+	}
+
+	queryExpr := "nil"
+	if len(query) > 0 {
+		name := g.uniqueName(op, "Params")
+		if err := g.params(b, name, op, query); err != nil {
+			return err
+		}
+		args = append(args, "params *"+name)
+		queryExpr = "params.values()"
+	}
+
+	bodyExpr := "nil"
+	if rb := op.Op.RequestBody; rb != nil {
+		mime, media := firstMedia(rb.Content)
+		switch {
+		case mime == "application/json":
+			typ, err := g.goType(media.Schema)
+			if err != nil {
+				return err
+			}
+			if media.Schema.Ref != "" {
+				typ = "*" + typ
+			}
+			args = append(args, "body "+typ)
+			bodyExpr = "body"
+		case mime == "multipart/form-data" || mime == "application/x-www-form-urlencoded":
+			name := g.uniqueName(op, "Form")
+			if err := g.form(b, name, op, media.Schema, mime == "multipart/form-data"); err != nil {
+				return err
+			}
+			args = append(args, "form *"+name)
+			bodyExpr = "form.encode()"
+		default:
+			return fmt.Errorf("unsupported request body %s", mime)
+		}
+	}
+	args = append(args, "opts ...RequestOption")
+
+	mime, media := firstMedia(success.Content)
+	var result string
+	raw := media != nil && mime != "application/json"
+	switch {
+	case media == nil:
+		result = "error"
+	case raw:
+		result = "(io.ReadCloser, error)"
+	default:
+		typ, err := g.goType(media.Schema)
+		if err != nil {
+			return err
+		}
+		if media.Schema.Ref != "" {
+			typ = "*" + typ
+		}
+		result = "(" + typ + ", error)"
+	}
+
+	summary := op.HTTPMethod + " " + op.Path
+	if op.Op.Summary != "" {
+		summary += " (" + strings.TrimSuffix(op.Op.Summary, ".") + ")"
+	}
+	fmt.Fprintf(b, "// %s calls %s", op.Method, summary)
+	if raw {
+		fmt.Fprintf(b, ". The caller closes the %s body", mime)
+	}
+	if len(headers) > 0 {
+		fmt.Fprintf(b, ". It takes the %s header", strings.Join(headers, ", "))
+		if len(headers) > 1 {
+			b.WriteString("s")
+		}
+		b.WriteString(", see WithHeader")
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(b, "func (s *%sService) %s(%s) %s {\n", op.Service, op.Method, strings.Join(args, ", "), result)
+
+	call := fmt.Sprintf("http.Method%s, %s, %s, %s", methodConst(op.HTTPMethod), pathExpr(op.Path), queryExpr, bodyExpr)
+	switch {
+	case media == nil:
+		fmt.Fprintf(b, "\treturn s.client.call(ctx, %s, nil, opts)\n", call)
+	case raw:
+		fmt.Fprintf(b, "\treturn s.client.open(ctx, %s, opts)\n", call)
+	case media.Schema.Ref != "":
+		typ, _ := g.goType(media.Schema)
+		fmt.Fprintf(b, "\tout := new(%s)\n\tif err := s.client.call(ctx, %s, out, opts); err != nil {\n\t\treturn nil, err\n\t}\n\treturn out, nil\n", typ, call)
+	default:
+		typ, _ := g.goType(media.Schema)
+		fmt.Fprintf(b, "\tvar out %s\n\tif err := s.client.call(ctx, %s, &out, opts); err != nil {\n\t\treturn nil, err\n\t}\n\treturn out, nil\n", typ, call)
+	}
+	b.WriteString("}\n\n")
+	return nil
+}
+
+// params declares the query parameters of an operation
+func (g *goGenerator) params(b *strings.Builder, name string, op clientOperation, query []parameterObject) error {
+	var fields, values strings.Builder
+	for _, p := range query {
+		field := goName(p.Name)
+		comment(&fields, "\t", p.Description)
+		switch p.Schema.Type {
+		case "string":
+			fmt.Fprintf(&fields, "\t%s string\n", field)
+			fmt.Fprintf(&values, "\tif p.%s != \"\" {\n\t\tq.Set(%q, p.%s)\n\t}\n", field, p.Name, field)
+		case "integer":
+			fmt.Fprintf(&fields, "\t%s int\n", field)
+			fmt.Fprintf(&values, "\tif p.%s != 0 {\n\t\tq.Set(%q, strconv.Itoa(p.%s))\n\t}\n", field, p.Name, field)
+		case "boolean":
+			fmt.Fprintf(&fields, "\t%s bool\n", field)
+			fmt.Fprintf(&values, "\tif p.%s {\n\t\tq.Set(%q, \"true\")\n\t}\n", field, p.Name)
+		default:
+			return fmt.Errorf("query parameter %s: unsupported type %q", p.Name, p.Schema.Type)
+		}
+	}
+	fmt.Fprintf(b, "// %s are the query parameters of %sService.%s\ntype %s struct {\n%s}\n\n", name, op.Service, op.Method, name, fields.String())
+	fmt.Fprintf(b, "func (p *%s) values() url.Values {\n\tif p == nil {\n\t\treturn nil\n\t}\n\tq := url.Values{}\n%s\treturn q\n}\n\n", name, values.String())
+	return nil
+}
+
+// form declares the form fields of an operation
+func (g *goGenerator) form(b *strings.Builder, name string, op clientOperation, s *Schema, multipart bool) error {
+	var fields, encode strings.Builder
+	for _, prop := range sortedKeys(s.Properties) {
+		p := s.Properties[prop]
+		field := goName(prop)
+		comment(&fields, "\t", p.Description)
+		switch {
+		case p.Type == "string" && p.Format == "binary":
+			fmt.Fprintf(&fields, "\t%s *File\n", field)
+			fmt.Fprintf(&encode, "\tform.file(%q, f.%s)\n", prop, field)
+		case p.Type == "array" && p.Items != nil && p.Items.Format == "binary":
+			fmt.Fprintf(&fields, "\t%s []*File\n", field)
+			fmt.Fprintf(&encode, "\tfor _, file := range f.%s {\n\t\tform.file(%q, file)\n\t}\n", field, prop)
+		case p.Type == "string":
+			fmt.Fprintf(&fields, "\t%s string\n", field)
+			fmt.Fprintf(&encode, "\tif f.%s != \"\" {\n\t\tform.values.Set(%q, f.%s)\n\t}\n", field, prop, field)
+		default:
+			return fmt.Errorf("form field %s: unsupported type %q", prop, p.Type)
+		}
+	}
+	fmt.Fprintf(b, "// %s is the form sent by %sService.%s\ntype %s struct {\n%s}\n\n", name, op.Service, op.Method, name, fields.String())
+	fmt.Fprintf(b, "func (f *%s) encode() *form {\n\tform := newForm(%v)\n\tif f == nil {\n\t\treturn form\n\t}\n%s\treturn form\n}\n\n", name, multipart, encode.String())
+	return nil
+}
+
+// uniqueName names a type of an operation, e.g. GetMessagesParams, adding
+// the service when another operation or a schema took the name
+func (g *goGenerator) uniqueName(op clientOperation, suffix string) string {
+	name := op.Method + suffix
+	if g.taken[name] {
+		name = op.Service + name
+	}
+	g.taken[name] = true
+	return name
+}
+
+// events declares the WebSocket event types and their payloads
+func (g *goGenerator) events() (string, error) {
+	envelope := g.doc.Components.Schemas["ws.Event"]
+	if envelope == nil || envelope.Discriminator == nil {
+		return "", fmt.Errorf("no ws.Event schema")
+	}
+
+	var consts, payloads strings.Builder
+	for _, value := range sortedKeys(envelope.Discriminator.Mapping) {
+		component := strings.TrimPrefix(envelope.Discriminator.Mapping[value], "#/components/schemas/")
+		event := g.doc.Components.Schemas[component]
+		payload, err := g.goType(event.Properties["payload"])
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", component, err)
+		}
+		name := "Event" + strings.TrimPrefix(component, "ws.")
+		fmt.Fprintf(&consts, "\t%s = %q // payload: %s\n", name, value, payload)
+		fmt.Fprintf(&payloads, "\t%s: func() any { return new(%s) },\n", name, payload)
+	}
+
+	return "// WebSocket event types\nconst (\n" + consts.String() + ")\n\n" +
+		"// eventPayloads makes an empty payload of each event type, to decode into\n" +
+		"var eventPayloads = map[string]func() any{\n" + payloads.String() + "}\n", nil
+}
+
+// goComponentName names the Go type of a component: model types keep their
+// name, other packages' types get the package as a prefix (mailer.Job -> MailerJob)
+func goComponentName(component string) string {
+	pkg, name, _ := strings.Cut(component, ".")
+	if pkg == "model" {
+		return name
+	}
+	return goName(pkg) + name
+}
+
+// goName converts a snake_case JSON or parameter name into an exported Go name
+func goName(s string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+		if upper, ok := initialisms[strings.ToLower(part)]; ok {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	name := b.String()
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "X" + name
+	}
+	return name
+}
+
+// lowerName converts a path parameter into a Go argument name, e.g. user_id -> userID
+func lowerName(s string) string {
+	first, rest, _ := strings.Cut(s, "_")
+	if rest == "" {
+		return strings.ToLower(first)
+	}
+	return strings.ToLower(first) + goName(rest)
+}
+
+// pathExpr builds a Go expression for an operation's path, escaping its parameters
+func pathExpr(path string) string {
+	var parts []string
+	for path != "" {
+		start := strings.Index(path, "{")
+		if start < 0 {
+			parts = append(parts, fmt.Sprintf("%q", path))
+			break
+		}
+		end := strings.Index(path, "}")
+		if start > 0 {
+			parts = append(parts, fmt.Sprintf("%q", path[:start]))
+		}
+		parts = append(parts, "url.PathEscape("+lowerName(path[start+1:end])+")")
+		path = path[end+1:]
+	}
+	return strings.Join(parts, "+")
+}
+
+func methodConst(method string) string {
+	return method[:1] + strings.ToLower(method[1:])
+}
+
+// firstMedia returns the JSON media type of a content map, or else the first one
+func firstMedia(content map[string]*mediaType) (string, *mediaType) {
+	if media, ok := content["application/json"]; ok {
+		return "application/json", media
+	}
+	for _, mime := range sortedKeys(content) {
+		return mime, content[mime]
+	}
+	return "", nil
+}
+
+// comment writes a doc comment, wrapping long text
+func comment(b *strings.Builder, indent, text string) {
+	if text == "" {
+		return
+	}
+	line := indent + "//"
+	for _, word := range strings.Fields(text) {
+		if len(line) > len(indent)+2 && len(line)+1+len(word) > 80 {
+			b.WriteString(line + "\n")
+			line = indent + "//"
+		}
+		line += " " + word
+	}
+	b.WriteString(line + "\n")
+}
+
+// goImports declares the packages a generated file's body uses
+func goImports(body string) (string, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), "", "package client\n\n"+body, 0)
+	if err != nil {
+		return "", err
+	}
+	used := map[string]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+
+	var std, external []string
+	for _, path := range []string{"context", "encoding/json", "io", "net/http", "net/url", "strconv", "time", "github.com/google/uuid"} {
+		if !used[path[strings.LastIndex(path, "/")+1:]] {
+			continue
+		}
+		if strings.Contains(path, ".") {
+			external = append(external, fmt.Sprintf("%q", path))
+		} else {
+			std = append(std, fmt.Sprintf("%q", path))
+		}
+	}
+	if len(std)+len(external) == 0 {
+		return "", nil
+	}
+	groups := []string{}
+	for _, group := range [][]string{std, external} {
+		if len(group) > 0 {
+			groups = append(groups, strings.Join(group, "\n"))
+		}
+	}
+	return "import (\n" + strings.Join(groups, "\n\n") + "\n)\n\n", nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
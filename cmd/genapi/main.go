@@ -0,0 +1,113 @@
+// Command genapi generates the OpenAPI 3 spec (docs/openapi.json), the Go
+// client (gen/client) and the WebSocket event schema registry
+// (internal/ws/events.schema.json) from the code: the routes mounted by
+// handler.RegisterRoutes, the handlers' godoc annotations, the DTOs in
+// internal/model and the WebSocket event payloads. Only files whose content
+// changed are rewritten.
+//
+//	go run ./cmd/genapi                    # regenerate the spec and the client
+//	go run ./cmd/genapi -check             # exit non-zero if routes, annotations, the spec or the client drifted
+//	go run ./cmd/genapi -ts web/gotalk.ts  # also write TypeScript types
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+func main() {
+	root := flag.String("root", ".", "repository root")
+	out := flag.String("out", "docs/openapi.json", "spec file, relative to root")
+	client := flag.String("client", "gen/client", "Go client package directory, relative to root")
+	events := flag.String("events", "internal/ws/events.schema.json", "WebSocket event schema file, relative to root")
+	ts := flag.String("ts", "", "TypeScript types file, relative to root; none when empty")
+	check := flag.Bool("check", false, "verify the generated files are up to date instead of writing them")
+	flag.Parse()
+
+	doc, err := generate(*root)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	files, err := render(doc, *out, *client, *events, *ts)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var outdated []string
+	wrote := 0
+	for _, name := range names {
+		path := filepath.Join(*root, name)
+		current, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Fatalf("❌ %v", err)
+		}
+		if bytes.Equal(current, files[name]) {
+			continue
+		}
+		if *check {
+			outdated = append(outdated, name)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		if err := os.WriteFile(path, files[name], 0o644); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		fmt.Printf("✅ Wrote %s\n", name)
+		wrote++
+	}
+
+	if len(outdated) > 0 {
+		log.Fatalf("❌ %v out of date, run: go run ./cmd/genapi", outdated)
+	}
+	if wrote == 0 {
+		fmt.Printf("✅ %s, %s and %s are up to date\n", *out, *client, *events)
+	}
+}
+
+// render produces every generated file, keyed by path relative to the root
+func render(doc *document, specPath, clientDir, eventsPath, tsPath string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	spec, err := doc.JSON()
+	if err != nil {
+		return nil, err
+	}
+	files[specPath] = spec
+
+	client, err := goClient(doc)
+	if err != nil {
+		return nil, err
+	}
+	for name, data := range client {
+		files[filepath.Join(clientDir, name)] = data
+	}
+
+	events, err := eventSchema(doc)
+	if err != nil {
+		return nil, err
+	}
+	files[eventsPath] = events
+
+	if tsPath != "" {
+		types, err := typeScript(doc)
+		if err != nil {
+			return nil, err
+		}
+		files[tsPath] = types
+	}
+	return files, nil
+}
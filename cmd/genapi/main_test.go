@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// TestGeneratedFilesUpToDate is `go run ./cmd/genapi -check` as a test: the
+// committed spec, client and event schema match the routes, annotations and DTOs
+func TestGeneratedFilesUpToDate(t *testing.T) {
+	root := filepath.Join("..", "..")
+	doc, err := generate(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := render(doc, "docs/openapi.json", "gen/client", "internal/ws/events.schema.json", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		current, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(current, files[name]) {
+			t.Errorf("%s is out of date, run: go run ./cmd/genapi", name)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quocanhngo/gotalk/internal/handler"
+)
+
+var (
+	// gin reports method values as "pkg/handler.(*AuthHandler).Login-fm"
+	handlerNamePattern = regexp.MustCompile(`\.\(\*?(\w+)\)\.(\w+)(?:-fm)?$`)
+	ginParamPattern    = regexp.MustCompile(`[:*](\w+)`)
+)
+
+// checkRoutes mounts the real API routes and verifies that every route has a
+// handler with a matching @Router annotation, and every annotation is served
+func checkRoutes(basePath string, ops map[string]*operation) error {
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	noop := func(*gin.Context) {}
+	// Handlers are never called, so nil receivers are fine
+	handler.RegisterRoutes(router.Group(basePath), handler.Handlers{}, noop, noop, noop, noop)
+
+	var problems []string
+	served := map[string]bool{}
+	for _, route := range router.Routes() {
+		m := handlerNamePattern.FindStringSubmatch(route.Handler)
+		if m == nil {
+			problems = append(problems, fmt.Sprintf("%s %s: unrecognized handler %s", route.Method, route.Path, route.Handler))
+			continue
+		}
+		name := m[1] + "." + m[2]
+		path := ginParamPattern.ReplaceAllString(strings.TrimPrefix(route.Path, basePath), "{$1}")
+		method := strings.ToLower(route.Method)
+
+		op, ok := ops[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s %s: %s has no @Router annotation", route.Method, route.Path, name))
+			continue
+		}
+		if op.Path != path || op.Method != method {
+			problems = append(problems, fmt.Sprintf("%s: %s is documented as %s [%s] but mounted at %s %s",
+				op.Pos, name, op.Path, op.Method, route.Method, route.Path))
+		}
+		served[name] = true
+	}
+
+	for name, op := range ops {
+		if !served[name] {
+			problems = append(problems, fmt.Sprintf("%s: %s documents %s [%s] but no route serves it", op.Pos, name, op.Path, op.Method))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("routes and annotations drifted:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
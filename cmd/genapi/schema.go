@@ -0,0 +1,405 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Schema is an OpenAPI 3.0 schema object
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Discriminator        *Discriminator     `json:"discriminator,omitempty"`
+}
+
+type Discriminator struct {
+	PropertyName string            `json:"propertyName"`
+	Mapping      map[string]string `json:"mapping,omitempty"`
+}
+
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// goPackage is a parsed package of the module
+type goPackage struct {
+	Name   string
+	Types  map[string]*typeDecl
+	Enums  map[string][]string  // named type -> values of its typed string constants
+	Consts map[string]constDecl // constant name -> value and line comment
+}
+
+type typeDecl struct {
+	Spec    *ast.TypeSpec
+	Doc     string
+	Imports map[string]string // file's import name -> path
+}
+
+type constDecl struct {
+	Value   string
+	Comment string
+}
+
+// schemaBuilder converts Go types of the module into OpenAPI component schemas
+type schemaBuilder struct {
+	root     string
+	module   string
+	packages map[string]*goPackage // import path -> package
+	Schemas  map[string]*Schema    // component name -> schema
+}
+
+func newSchemaBuilder(root, module string) *schemaBuilder {
+	return &schemaBuilder{
+		root:     root,
+		module:   module,
+		packages: map[string]*goPackage{},
+		Schemas:  map[string]*Schema{},
+	}
+}
+
+// pkg parses (once) a package of the module by import path
+func (b *schemaBuilder) pkg(importPath string) (*goPackage, error) {
+	if p, ok := b.packages[importPath]; ok {
+		return p, nil
+	}
+	rel, ok := strings.CutPrefix(importPath, b.module)
+	if !ok {
+		return nil, fmt.Errorf("package %s is outside the module", importPath)
+	}
+
+	files, err := filepath.Glob(filepath.Join(b.root, filepath.FromSlash(rel), "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	p := &goPackage{Types: map[string]*typeDecl{}, Enums: map[string][]string{}, Consts: map[string]constDecl{}}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		p.Name = f.Name.Name
+
+		imports := fileImports(f)
+
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					doc := gen.Doc
+					if s.Doc != nil {
+						doc = s.Doc
+					}
+					p.Types[s.Name.Name] = &typeDecl{Spec: s, Doc: docText(doc), Imports: imports}
+				case *ast.ValueSpec:
+					if gen.Tok != token.CONST || len(s.Values) != len(s.Names) {
+						continue
+					}
+					for i, name := range s.Names {
+						lit, ok := s.Values[i].(*ast.BasicLit)
+						if !ok || lit.Kind != token.STRING {
+							continue
+						}
+						value, _ := strconv.Unquote(lit.Value)
+						p.Consts[name.Name] = constDecl{Value: value, Comment: docText(s.Comment)}
+						if typ, ok := s.Type.(*ast.Ident); ok {
+							p.Enums[typ.Name] = append(p.Enums[typ.Name], value)
+						}
+					}
+				}
+			}
+		}
+	}
+	b.packages[importPath] = p
+	return p, nil
+}
+
+// Named returns the schema of a type written as "model.UserResponse" in a file with the given imports
+func (b *schemaBuilder) Named(imports map[string]string, qualified string) (*Schema, error) {
+	pkgName, name, ok := strings.Cut(qualified, ".")
+	if !ok {
+		return nil, fmt.Errorf("type %q must be package-qualified", qualified)
+	}
+	importPath, ok := imports[pkgName]
+	if !ok {
+		return nil, fmt.Errorf("type %q: package %s is not imported", qualified, pkgName)
+	}
+	return b.named(importPath, name)
+}
+
+func (b *schemaBuilder) named(importPath, name string) (*Schema, error) {
+	p, err := b.pkg(importPath)
+	if err != nil {
+		return nil, err
+	}
+	decl, ok := p.Types[name]
+	if !ok {
+		return nil, fmt.Errorf("type %s.%s not found", p.Name, name)
+	}
+
+	// Non-struct named types (string enums, aliases) are inlined
+	if _, isStruct := decl.Spec.Type.(*ast.StructType); !isStruct {
+		s, err := b.typeSchema(importPath, decl, decl.Spec.Type)
+		if err != nil {
+			return nil, err
+		}
+		if enum := p.Enums[name]; len(enum) > 0 && s.Type == "string" {
+			s.Enum = enum
+		}
+		return s, nil
+	}
+
+	component := p.Name + "." + name
+	if _, ok := b.Schemas[component]; !ok {
+		b.Schemas[component] = &Schema{} // placeholder so recursive types terminate
+		s, err := b.typeSchema(importPath, decl, decl.Spec.Type)
+		if err != nil {
+			return nil, err
+		}
+		s.Description = decl.Doc
+		b.Schemas[component] = s
+	}
+	return ref(component), nil
+}
+
+// typeSchema converts a type expression declared in importPath
+func (b *schemaBuilder) typeSchema(importPath string, decl *typeDecl, expr ast.Expr) (*Schema, error) {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return &Schema{Type: "string"}, nil
+		case "bool":
+			return &Schema{Type: "boolean"}, nil
+		case "int", "int8", "int16", "int32", "uint", "uint8", "uint16", "uint32":
+			return &Schema{Type: "integer"}, nil
+		case "int64", "uint64":
+			return &Schema{Type: "integer", Format: "int64"}, nil
+		case "float32", "float64":
+			return &Schema{Type: "number"}, nil
+		case "any":
+			return &Schema{}, nil
+		}
+		return b.named(importPath, t.Name)
+
+	case *ast.StarExpr:
+		s, err := b.typeSchema(importPath, decl, t.X)
+		if err != nil {
+			return nil, err
+		}
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+		return s, nil
+
+	case *ast.ArrayType:
+		if ident, ok := t.Elt.(*ast.Ident); ok && ident.Name == "byte" {
+			return &Schema{Type: "string", Format: "byte"}, nil
+		}
+		items, err := b.typeSchema(importPath, decl, t.Elt)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+
+	case *ast.MapType:
+		values, err := b.typeSchema(importPath, decl, t.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", AdditionalProperties: values}, nil
+
+	case *ast.InterfaceType:
+		return &Schema{}, nil
+
+	case *ast.SelectorExpr:
+		pkgIdent, _ := t.X.(*ast.Ident)
+		if pkgIdent == nil {
+			return &Schema{}, nil
+		}
+		path := decl.Imports[pkgIdent.Name]
+		switch path + "." + t.Sel.Name {
+		case "github.com/google/uuid.UUID":
+			return &Schema{Type: "string", Format: "uuid"}, nil
+		case "time.Time":
+			return &Schema{Type: "string", Format: "date-time"}, nil
+		case "time.Duration":
+			return &Schema{Type: "integer", Format: "int64", Description: "nanoseconds"}, nil
+		}
+		if strings.HasPrefix(path, b.module+"/") {
+			return b.named(path, t.Sel.Name)
+		}
+		return &Schema{}, nil // third-party type: any JSON value
+
+	case *ast.StructType:
+		return b.structSchema(importPath, decl, t)
+	}
+	return nil, fmt.Errorf("unsupported type %T in %s", expr, decl.Spec.Name.Name)
+}
+
+// structSchema converts a struct following encoding/json rules and gin binding tags
+func (b *schemaBuilder) structSchema(importPath string, decl *typeDecl, st *ast.StructType) (*Schema, error) {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for _, field := range st.Fields.List {
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			raw, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(raw)
+		}
+		jsonName, _, _ := strings.Cut(tag.Get("json"), ",")
+		if jsonName == "-" {
+			continue
+		}
+
+		// Embedded structs without a json name are flattened into the parent
+		if len(field.Names) == 0 && jsonName == "" {
+			embedded, err := b.typeSchema(importPath, decl, field.Type)
+			if err != nil {
+				return nil, err
+			}
+			if embedded.Ref != "" {
+				embedded = b.Schemas[strings.TrimPrefix(embedded.Ref, "#/components/schemas/")]
+			}
+			for name, prop := range embedded.Properties {
+				s.Properties[name] = prop
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{ast.NewIdent(jsonName)}
+		}
+		for _, ident := range names {
+			if !ident.IsExported() && jsonName == "" {
+				continue
+			}
+			prop, err := b.typeSchema(importPath, decl, field.Type)
+			if err != nil {
+				return nil, err
+			}
+			name := jsonName
+			if name == "" {
+				name = ident.Name
+			}
+			if prop.Ref == "" {
+				if comment := docText(field.Comment); comment != "" {
+					prop.Description = comment
+				}
+			}
+			if applyBinding(prop, tag.Get("binding")) {
+				s.Required = append(s.Required, name)
+			}
+			s.Properties[name] = prop
+		}
+	}
+	sort.Strings(s.Required)
+	return s, nil
+}
+
+// applyBinding maps gin validator rules onto the schema and reports whether the field is required
+func applyBinding(s *Schema, binding string) bool {
+	required := false
+	for _, rule := range strings.Split(binding, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			s.Format = "email"
+		case "url":
+			s.Format = "uri"
+		case "uuid":
+			s.Format = "uuid"
+		case "oneof":
+			s.Enum = strings.Fields(arg)
+		case "min", "max", "len":
+			n, err := strconv.Atoi(arg)
+			if err != nil || s.Ref != "" {
+				continue
+			}
+			setBound(s, name, n)
+		}
+	}
+	return required
+}
+
+func setBound(s *Schema, rule string, n int) {
+	f := float64(n)
+	switch s.Type {
+	case "string":
+		if rule != "max" {
+			s.MinLength = &n
+		}
+		if rule != "min" {
+			s.MaxLength = &n
+		}
+	case "array":
+		if rule != "max" {
+			s.MinItems = &n
+		}
+		if rule != "min" {
+			s.MaxItems = &n
+		}
+	case "integer", "number":
+		if rule != "max" {
+			s.Minimum = &f
+		}
+		if rule != "min" {
+			s.Maximum = &f
+		}
+	}
+}
+
+// fileImports maps the names a file uses for its imports to their paths
+func fileImports(f *ast.File) map[string]string {
+	imports := map[string]string{}
+	for _, imp := range f.Imports {
+		path, _ := strconv.Unquote(imp.Path.Value)
+		name := path[strings.LastIndex(path, "/")+1:]
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		imports[name] = path
+	}
+	return imports
+}
+
+func docText(group *ast.CommentGroup) string {
+	if group == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(group.Text()), " ")
+}
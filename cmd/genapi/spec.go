@@ -0,0 +1,391 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// document is the root of an OpenAPI 3.0 document
+type document struct {
+	OpenAPI    string                                 `json:"openapi"`
+	Info       info                                   `json:"info"`
+	Servers    []server                               `json:"servers"`
+	Paths      map[string]map[string]*operationObject `json:"paths"`
+	Components components                             `json:"components"`
+
+	basePath string // e.g. /api/v1, for the client
+}
+
+type info struct {
+	Title          string   `json:"title"`
+	Description    string   `json:"description,omitempty"`
+	TermsOfService string   `json:"termsOfService,omitempty"`
+	Contact        *contact `json:"contact,omitempty"`
+	License        *license `json:"license,omitempty"`
+	Version        string   `json:"version"`
+}
+
+type contact struct {
+	Name  string `json:"name,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+type license struct {
+	Name string `json:"name"`
+	URL  string `json:"url,omitempty"`
+}
+
+type server struct {
+	URL string `json:"url"`
+}
+
+type components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*securityScheme `json:"securitySchemes,omitempty"`
+}
+
+type securityScheme struct {
+	Type string `json:"type"`
+	In   string `json:"in"`
+	Name string `json:"name"`
+}
+
+type operationObject struct {
+	Tags        []string                   `json:"tags,omitempty"`
+	Summary     string                     `json:"summary,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	OperationID string                     `json:"operationId"`
+	Parameters  []parameterObject          `json:"parameters,omitempty"`
+	RequestBody *requestBody               `json:"requestBody,omitempty"`
+	Responses   map[string]*responseObject `json:"responses"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+}
+
+type parameterObject struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*mediaType `json:"content"`
+}
+
+type responseObject struct {
+	Description string                `json:"description"`
+	Content     map[string]*mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// generate builds the spec and checks it against the registered routes
+func generate(root string) (*document, error) {
+	module, err := readModule(root)
+	if err != nil {
+		return nil, err
+	}
+	apiInfo, err := parseInfo(filepath.Join(root, "cmd", "server", "main.go"))
+	if err != nil {
+		return nil, err
+	}
+	ops, err := parseOperations(filepath.Join(root, "internal", "handler"))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRoutes(apiInfo.BasePath, ops); err != nil {
+		return nil, err
+	}
+
+	schemas := newSchemaBuilder(root, module)
+	doc := &document{
+		OpenAPI: "3.0.3",
+		Info: info{
+			Title:          apiInfo.Title,
+			Description:    apiInfo.Description,
+			TermsOfService: apiInfo.TermsOfService,
+			Version:        apiInfo.Version,
+		},
+		Paths:    map[string]map[string]*operationObject{},
+		basePath: apiInfo.BasePath,
+	}
+	if apiInfo.ContactName != "" || apiInfo.ContactURL != "" || apiInfo.ContactEmail != "" {
+		doc.Info.Contact = &contact{Name: apiInfo.ContactName, URL: apiInfo.ContactURL, Email: apiInfo.ContactEmail}
+	}
+	if apiInfo.LicenseName != "" {
+		doc.Info.License = &license{Name: apiInfo.LicenseName, URL: apiInfo.LicenseURL}
+	}
+	for _, scheme := range []string{"http", "https"} {
+		doc.Servers = append(doc.Servers, server{URL: scheme + "://" + apiInfo.Host + apiInfo.BasePath})
+	}
+
+	names := make([]string, 0, len(ops))
+	for name := range ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		op := ops[name]
+		obj, err := buildOperation(schemas, op)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", op.Pos, err)
+		}
+		if doc.Paths[op.Path] == nil {
+			doc.Paths[op.Path] = map[string]*operationObject{}
+		}
+		doc.Paths[op.Path][op.Method] = obj
+	}
+
+	if err := addWSEvents(schemas, module); err != nil {
+		return nil, err
+	}
+
+	doc.Components.Schemas = schemas.Schemas
+	if apiInfo.SecurityName != "" {
+		doc.Components.SecuritySchemes = map[string]*securityScheme{
+			apiInfo.SecurityName: {Type: "apiKey", In: apiInfo.SecurityIn, Name: apiInfo.SecurityHeader},
+		}
+	}
+
+	return doc, nil
+}
+
+// JSON renders the spec
+func (d *document) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// buildOperation converts a handler's annotations into an OpenAPI operation
+func buildOperation(schemas *schemaBuilder, op *operation) (*operationObject, error) {
+	obj := &operationObject{
+		Tags:        op.Tags,
+		Summary:     op.Summary,
+		Description: op.Description,
+		OperationID: op.Handler,
+		Responses:   map[string]*responseObject{},
+	}
+	for _, name := range op.Security {
+		obj.Security = append(obj.Security, map[string][]string{name: {}})
+	}
+
+	var form *Schema
+	for _, p := range op.Params {
+		switch p.In {
+		case "body":
+			s, err := typeRef(schemas, op, p.Type)
+			if err != nil {
+				return nil, err
+			}
+			content := map[string]*mediaType{}
+			for _, mime := range mimeTypes(op.Accept) {
+				content[mime] = &mediaType{Schema: s}
+			}
+			obj.RequestBody = &requestBody{Required: p.Required, Content: content}
+
+		case "formData":
+			if form == nil {
+				form = &Schema{Type: "object", Properties: map[string]*Schema{}}
+				content := map[string]*mediaType{}
+				for _, mime := range formTypes(op.Accept) {
+					content[mime] = &mediaType{Schema: form}
+				}
+				obj.RequestBody = &requestBody{Content: content}
+			}
+			s, err := paramSchema(schemas, op, p)
+			if err != nil {
+				return nil, err
+			}
+			s.Description = p.Description
+			form.Properties[p.Name] = s
+			if p.Required {
+				form.Required = append(form.Required, p.Name)
+				obj.RequestBody.Required = true
+			}
+
+		case "query", "path", "header":
+			s, err := paramSchema(schemas, op, p)
+			if err != nil {
+				return nil, err
+			}
+			obj.Parameters = append(obj.Parameters, parameterObject{
+				Name: p.Name, In: p.In, Description: p.Description,
+				Required: p.Required || p.In == "path", Schema: s,
+			})
+
+		default:
+			return nil, fmt.Errorf("@Param %s: unknown location %q", p.Name, p.In)
+		}
+	}
+
+	for _, r := range op.Responses {
+		desc := r.Description
+		if desc == "" {
+			desc = http.StatusText(r.Code)
+		}
+		resp := &responseObject{Description: desc}
+
+		var s *Schema
+		switch r.Kind {
+		case "object":
+			ref, err := typeRef(schemas, op, r.Type)
+			if err != nil {
+				return nil, err
+			}
+			s = ref
+		case "array":
+			items, err := typeRef(schemas, op, r.Type)
+			if err != nil {
+				return nil, err
+			}
+			s = &Schema{Type: "array", Items: items}
+		case "file":
+			s = &Schema{Type: "string", Format: "binary"}
+		case "string", "integer", "number", "boolean":
+			// e.g. an HTML page: {string} string "Confirmation page"
+			s = primitive(r.Kind)
+		default:
+			return nil, fmt.Errorf("@Success/@Failure %d: unknown kind {%s}", r.Code, r.Kind)
+		}
+
+		resp.Content = map[string]*mediaType{}
+		for _, mime := range mimeTypes(op.Produce) {
+			resp.Content[mime] = &mediaType{Schema: s}
+		}
+		obj.Responses[strconv.Itoa(r.Code)] = resp
+	}
+	if len(obj.Responses) == 0 {
+		return nil, fmt.Errorf("no @Success response")
+	}
+	return obj, nil
+}
+
+// typeRef resolves a type named in an annotation (model.X or a primitive)
+func typeRef(schemas *schemaBuilder, op *operation, typ string) (*Schema, error) {
+	if s := primitive(typ); s != nil {
+		return s, nil
+	}
+	return schemas.Named(op.Imports, typ)
+}
+
+func paramSchema(schemas *schemaBuilder, op *operation, p param) (*Schema, error) {
+	switch p.Type {
+	case "file":
+		return &Schema{Type: "string", Format: "binary"}, nil
+	case "[]file":
+		return &Schema{Type: "array", Items: &Schema{Type: "string", Format: "binary"}}, nil
+	}
+	s, err := typeRef(schemas, op, p.Type)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Enum) > 0 {
+		s.Enum = p.Enum
+	}
+	return s, nil
+}
+
+func primitive(typ string) *Schema {
+	switch typ {
+	case "string":
+		return &Schema{Type: "string"}
+	case "int", "integer":
+		return &Schema{Type: "integer"}
+	case "number":
+		return &Schema{Type: "number"}
+	case "bool", "boolean":
+		return &Schema{Type: "boolean"}
+	case "object":
+		return &Schema{Type: "object"}
+	}
+	return nil
+}
+
+// mimeTypes expands swag's short MIME names, defaulting to JSON
+func mimeTypes(list []string) []string {
+	if len(list) == 0 {
+		return []string{"application/json"}
+	}
+	var types []string
+	for _, t := range list {
+		switch t {
+		case "json":
+			types = append(types, "application/json")
+		case "plain":
+			types = append(types, "text/plain")
+		default:
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// formTypes returns the media types of a form request body: multipart unless
+// @Accept names others (e.g. application/x-www-form-urlencoded)
+func formTypes(accept []string) []string {
+	if len(accept) == 0 {
+		return []string{"multipart/form-data"}
+	}
+	return mimeTypes(accept)
+}
+
+// addWSEvents documents the WebSocket events from the WSEvent* constants in the
+// model package, whose line comments name the payload type ("// payload: Message")
+func addWSEvents(schemas *schemaBuilder, module string) error {
+	importPath := module + "/internal/model"
+	p, err := schemas.pkg(importPath)
+	if err != nil {
+		return err
+	}
+
+	var constNames []string
+	for name := range p.Consts {
+		if strings.HasPrefix(name, "WSEvent") {
+			constNames = append(constNames, name)
+		}
+	}
+	sort.Strings(constNames)
+
+	envelope := &Schema{
+		Description:   "Frame sent over the /ws WebSocket (connect with ?token=<jwt>), discriminated by type",
+		Discriminator: &Discriminator{PropertyName: "type", Mapping: map[string]string{}},
+	}
+	for _, name := range constNames {
+		c := p.Consts[name]
+		payloadType, ok := strings.CutPrefix(c.Comment, "payload: ")
+		if !ok {
+			return fmt.Errorf("model.%s: missing \"// payload: <Type>\" comment", name)
+		}
+		payload, err := schemas.named(importPath, strings.TrimSpace(payloadType))
+		if err != nil {
+			return fmt.Errorf("model.%s: %v", name, err)
+		}
+
+		component := "ws." + strings.TrimPrefix(name, "WSEvent")
+		schemas.Schemas[component] = &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"type":    {Type: "string", Enum: []string{c.Value}},
+				"payload": payload,
+			},
+			Required: []string{"payload", "type"},
+		}
+		envelope.OneOf = append(envelope.OneOf, ref(component))
+		envelope.Discriminator.Mapping[c.Value] = ref(component).Ref
+	}
+	schemas.Schemas["ws.Event"] = envelope
+	return nil
+}
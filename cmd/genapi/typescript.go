@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// typeScript renders TypeScript declarations of the component schemas and the
+// WebSocket events, for web clients
+func typeScript(doc *document) ([]byte, error) {
+	var b strings.Builder
+	b.WriteString(generatedHeader)
+	fmt.Fprintf(&b, "export const basePath = %q;\n\n", doc.basePath)
+
+	for _, component := range sortedKeys(doc.Components.Schemas) {
+		if strings.HasPrefix(component, "ws.") {
+			continue
+		}
+		s := doc.Components.Schemas[component]
+		comment(&b, "", s.Description)
+		typ, err := tsType(s, "")
+		if err != nil {
+			return nil, fmt.Errorf("typescript: %s: %v", component, err)
+		}
+		fmt.Fprintf(&b, "export interface %s %s\n\n", goComponentName(component), typ)
+	}
+
+	envelope := doc.Components.Schemas["ws.Event"]
+	if envelope == nil || envelope.Discriminator == nil {
+		return nil, fmt.Errorf("typescript: no ws.Event schema")
+	}
+	comment(&b, "", envelope.Description)
+	b.WriteString("export type WSEvent =\n")
+	values := sortedKeys(envelope.Discriminator.Mapping)
+	for i, value := range values {
+		component := strings.TrimPrefix(envelope.Discriminator.Mapping[value], "#/components/schemas/")
+		payload, err := tsType(doc.Components.Schemas[component].Properties["payload"], "  ")
+		if err != nil {
+			return nil, fmt.Errorf("typescript: %s: %v", component, err)
+		}
+		end := ""
+		if i == len(values)-1 {
+			end = ";"
+		}
+		fmt.Fprintf(&b, "  | { type: %q; payload: %s }%s\n", value, payload, end)
+	}
+	return []byte(b.String()), nil
+}
+
+// tsType is the TypeScript type of a schema; indent is the indentation of
+// the line it starts on
+func tsType(s *Schema, indent string) (string, error) {
+	var typ string
+	switch {
+	case s.Ref != "":
+		typ = goComponentName(strings.TrimPrefix(s.Ref, "#/components/schemas/"))
+	case s.Type == "string" && len(s.Enum) > 0:
+		quoted := make([]string, len(s.Enum))
+		for i, value := range s.Enum {
+			quoted[i] = fmt.Sprintf("%q", value)
+		}
+		typ = strings.Join(quoted, " | ")
+	case s.Type == "string":
+		typ = "string"
+	case s.Type == "integer" || s.Type == "number":
+		typ = "number"
+	case s.Type == "boolean":
+		typ = "boolean"
+	case s.Type == "array":
+		items, err := tsType(s.Items, indent)
+		if err != nil {
+			return "", err
+		}
+		if strings.Contains(items, " ") && !strings.HasPrefix(items, "{") {
+			items = "(" + items + ")"
+		}
+		typ = items + "[]"
+	case s.Type == "object" && s.AdditionalProperties != nil:
+		values, err := tsType(s.AdditionalProperties, indent)
+		if err != nil {
+			return "", err
+		}
+		typ = "Record<string, " + values + ">"
+	case s.Type == "object":
+		required := map[string]bool{}
+		for _, name := range s.Required {
+			required[name] = true
+		}
+		var b strings.Builder
+		b.WriteString("{\n")
+		for _, prop := range sortedKeys(s.Properties) {
+			p := s.Properties[prop]
+			propType, err := tsType(p, indent+"  ")
+			if err != nil {
+				return "", fmt.Errorf("%s: %v", prop, err)
+			}
+			optional := "?"
+			if required[prop] {
+				optional = ""
+			}
+			comment(&b, indent+"  ", p.Description)
+			fmt.Fprintf(&b, "%s  %s%s: %s;\n", indent, prop, optional, propType)
+		}
+		b.WriteString(indent + "}")
+		typ = b.String()
+	default:
+		typ = "unknown"
+	}
+	if s.Nullable {
+		typ += " | null"
+	}
+	return typ, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// jsonSchema is a JSON Schema (draft 2020-12) object, the subset the event
+// registry uses and internal/ws validates
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 interface{}            `json:"type,omitempty"` // a type, or [type, "null"]
+	Const                string                 `json:"const,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"` // false, or the values' schema
+	Items                *jsonSchema            `json:"items,omitempty"`
+	AnyOf                []*jsonSchema          `json:"anyOf,omitempty"`
+	OneOf                []*jsonSchema          `json:"oneOf,omitempty"`
+	Defs                 map[string]*jsonSchema `json:"$defs,omitempty"`
+}
+
+var jsonNull = &jsonSchema{Type: "null"}
+
+// eventSchema renders the registry of WebSocket events: a JSON Schema of a
+// frame, one of the event types with its payload. Objects are closed, so a
+// payload with fields the DTOs don't declare fails; arrays, maps and nested
+// objects may be null, as Go encodes nil ones.
+func eventSchema(doc *document) ([]byte, error) {
+	envelope := doc.Components.Schemas["ws.Event"]
+	if envelope == nil || envelope.Discriminator == nil {
+		return nil, fmt.Errorf("ws schema: no ws.Event schema")
+	}
+
+	root := &jsonSchema{
+		Schema:      "https://json-schema.org/draft/2020-12/schema",
+		Title:       "GoTalk WebSocket event",
+		Description: envelope.Description,
+		Defs:        map[string]*jsonSchema{},
+	}
+	pending := []string{}
+	for _, value := range sortedKeys(envelope.Discriminator.Mapping) {
+		component := strings.TrimPrefix(envelope.Discriminator.Mapping[value], "#/components/schemas/")
+		pending = append(pending, component)
+		root.OneOf = append(root.OneOf, &jsonSchema{Ref: "#/$defs/" + component})
+	}
+
+	// Convert the events and every schema they reach
+	for len(pending) > 0 {
+		component := pending[0]
+		pending = pending[1:]
+		if _, ok := root.Defs[component]; ok {
+			continue
+		}
+		s, ok := doc.Components.Schemas[component]
+		if !ok {
+			return nil, fmt.Errorf("ws schema: no schema %s", component)
+		}
+		root.Defs[component] = toJSONSchema(s, func(ref string) {
+			pending = append(pending, ref)
+		})
+	}
+
+	// An event's type is a constant
+	for name, def := range root.Defs {
+		if !strings.HasPrefix(name, "ws.") {
+			continue
+		}
+		eventType := def.Properties["type"]
+		eventType.Const, eventType.Enum = fmt.Sprint(eventType.Enum[0]), nil
+	}
+
+	data, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// toJSONSchema converts an OpenAPI schema, reporting the components it references
+func toJSONSchema(s *Schema, reference func(component string)) *jsonSchema {
+	if s.Ref != "" {
+		component := strings.TrimPrefix(s.Ref, "#/components/schemas/")
+		reference(component)
+		return &jsonSchema{Ref: "#/$defs/" + component}
+	}
+
+	out := &jsonSchema{Description: s.Description, Format: s.Format}
+	if s.Type != "" {
+		out.Type = s.Type
+	}
+	for _, value := range s.Enum {
+		out.Enum = append(out.Enum, value)
+	}
+	if s.Items != nil {
+		out.Items = toJSONSchema(s.Items, reference)
+	}
+	if s.AdditionalProperties != nil {
+		out.AdditionalProperties = toJSONSchema(s.AdditionalProperties, reference)
+	}
+	if len(s.Properties) > 0 {
+		out.Properties = map[string]*jsonSchema{}
+		for name, prop := range s.Properties {
+			p := toJSONSchema(prop, reference)
+			if p.Ref != "" || p.Type == "array" || p.Type == "object" {
+				p = nullable(p)
+			}
+			out.Properties[name] = p
+		}
+		out.Required = s.Required
+		if s.AdditionalProperties == nil {
+			out.AdditionalProperties = false
+		}
+	}
+	for _, one := range s.OneOf {
+		out.OneOf = append(out.OneOf, toJSONSchema(one, reference))
+	}
+
+	if s.Nullable && out.Type != nil {
+		out.Type = []string{s.Type, "null"}
+		if len(out.Enum) > 0 {
+			out.Enum = append(out.Enum, nil)
+		}
+	}
+	return out
+}
+
+// nullable allows null besides a schema
+func nullable(s *jsonSchema) *jsonSchema {
+	switch {
+	case s.Ref != "":
+		return &jsonSchema{AnyOf: []*jsonSchema{s, jsonNull}}
+	case s.Type == "array" || s.Type == "object":
+		s.Type = []string{s.Type.(string), "null"}
+	}
+	return s
+}
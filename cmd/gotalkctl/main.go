@@ -0,0 +1,113 @@
+package main
+
+import (
+	"log"
+
+	server "github.com/quocanhngo/gotalk/internal/app"
+	"github.com/quocanhngo/gotalk/internal/config"
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// gotalkctl runs operational tasks against the database and Redis the server
+// is configured with (.env, CONFIG_FILE and the environment):
+//
+//	go run ./cmd/gotalkctl <command> [flags]
+//	go run ./cmd/gotalkctl help <command>
+
+func main() {
+	log.SetFlags(0)
+	app := &app{}
+	cmd, err := newRootCmd(app).ExecuteC()
+	app.close()
+	if err != nil {
+		log.Fatalf("❌ %s: %v", cmd.Name(), err)
+	}
+}
+
+// newRootCmd builds the gotalkctl command tree
+func newRootCmd(app *app) *cobra.Command {
+	root := &cobra.Command{
+		Use:   "gotalkctl",
+		Short: "Operational tasks against the server's database and Redis",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Cobra checks these after this hook; usage is only shown for them
+			// and bad arguments, not for what fails once the command runs
+			if err := cmd.ValidateRequiredFlags(); err != nil {
+				return err
+			}
+			if err := cmd.ValidateFlagGroups(); err != nil {
+				return err
+			}
+			cmd.SilenceUsage = true
+			return nil
+		},
+		SilenceErrors: true, // printed by main
+	}
+	root.AddCommand(
+		newMigrateCmd(app),
+		newCreateAdminCmd(app),
+		newResetPasswordCmd(app),
+		newPurgeUserCmd(app),
+		newReindexSearchCmd(app),
+		newReindexMessagesCmd(app),
+		newRequeueFailedEmailsCmd(app),
+		newStatsCmd(app),
+	)
+	return root
+}
+
+// app loads the configuration and connects to PostgreSQL and Redis on first
+// use, the way the server does, read replicas and pool settings included, so
+// help works without any of them
+type app struct {
+	cfg *config.Config
+	db  *gorm.DB
+	rdb *redis.Client
+}
+
+func (a *app) Config() *config.Config {
+	if a.cfg == nil {
+		cfg, err := config.Load()
+		if err != nil {
+			log.Fatalf("❌ Invalid configuration:\n%v", err)
+		}
+		a.cfg = cfg
+	}
+	return a.cfg
+}
+
+func (a *app) DB() *gorm.DB {
+	if a.db == nil {
+		db, err := server.OpenDatabase(a.Config(), nil)
+		if err != nil {
+			log.Fatalf("❌ Failed to connect to database: %v", err)
+		}
+		// Commands print their own output, not every query
+		db.Logger = logger.Default.LogMode(logger.Silent)
+		a.db = db
+	}
+	return a.db
+}
+
+func (a *app) Redis() *redis.Client {
+	if a.rdb == nil {
+		a.rdb = server.OpenRedis(a.Config())
+	}
+	return a.rdb
+}
+
+func (a *app) close() {
+	if a.db != nil {
+		if sqlDB, err := a.db.DB(); err == nil {
+			sqlDB.Close()
+		}
+		a.db = nil
+	}
+	if a.rdb != nil {
+		a.rdb.Close()
+		a.rdb = nil
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/migrations"
+	"github.com/quocanhngo/gotalk/pkg/mailer"
+	"github.com/quocanhngo/gotalk/pkg/opensearch"
+	"github.com/spf13/cobra"
+)
+
+// searchTables are the tables user search reads from
+var searchTables = []string{"users"}
+
+func newReindexSearchCmd(app *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "reindex-search",
+		Short: "Rebuild the indexes behind user search",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReindexSearch(app)
+		},
+	}
+}
+
+func runReindexSearch(app *app) error {
+	db := app.DB()
+	for _, table := range searchTables {
+		start := time.Now()
+		// CONCURRENTLY keeps the table writable while its indexes are rebuilt
+		if err := db.Exec("REINDEX TABLE CONCURRENTLY " + table).Error; err != nil {
+			return fmt.Errorf("reindex %s: %w", table, err)
+		}
+		if err := db.Exec("ANALYZE " + table).Error; err != nil {
+			return fmt.Errorf("analyze %s: %w", table, err)
+		}
+		fmt.Printf("✅ Reindexed %s in %s\n", table, time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
+
+func newReindexMessagesCmd(app *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "reindex-messages",
+		Short: "Rebuild the OpenSearch message index",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReindexMessages(app)
+		},
+	}
+}
+
+func runReindexMessages(app *app) error {
+	cfg := app.Config().OpenSearch
+	if cfg.URL == "" {
+		return fmt.Errorf("OPENSEARCH_URL is not set")
+	}
+	index := service.NewMessageIndex(opensearch.New(opensearch.Config{
+		URL:      cfg.URL,
+		Index:    cfg.Index,
+		Username: cfg.Username,
+		Password: cfg.Password,
+	}), repository.NewMessageRepository(app.DB()))
+
+	start := time.Now()
+	total := 0
+	err := index.Rebuild(context.Background(), func(indexed int) {
+		total = indexed
+		fmt.Printf("\r🔎 Indexed %d messages", indexed)
+	})
+	fmt.Println()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Rebuilt %s with %d messages in %s\n", cfg.Index, total, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+func newRequeueFailedEmailsCmd(app *app) *cobra.Command {
+	var id string
+	cmd := &cobra.Command{
+		Use:   "requeue-failed-emails [--id JOB_ID]",
+		Short: "Move dead-lettered emails back onto the queue",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRequeueFailedEmails(app, id)
+		},
+	}
+	cmd.Flags().StringVar(&id, "id", "", "requeue only this job (default: all)")
+	return cmd
+}
+
+func runRequeueFailedEmails(app *app, id string) error {
+	ctx := context.Background()
+	queue := mailer.NewQueue(app.Redis(), nil)
+	if id != "" {
+		if err := queue.Resend(ctx, id); err != nil {
+			return err
+		}
+		fmt.Printf("📬 Requeued email %s\n", id)
+		return nil
+	}
+
+	jobs, err := queue.DeadLetters(ctx)
+	if err != nil {
+		return err
+	}
+	requeued := 0
+	for _, job := range jobs {
+		if err := queue.Resend(ctx, job.ID); err != nil {
+			fmt.Printf("⚠️  Failed to requeue email %s to %s: %v\n", job.ID, job.Message.To, err)
+			continue
+		}
+		requeued++
+	}
+	fmt.Printf("📬 Requeued %d of %d failed email(s)\n", requeued, len(jobs))
+	return nil
+}
+
+func newStatsCmd(app *app) *cobra.Command {
+	return &cobra.Command{
+		Use:   "stats",
+		Short: "Show user, conversation, message and queue counts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStats(app)
+		},
+	}
+}
+
+func runStats(app *app) error {
+	db := app.DB()
+	var (
+		users, verified, deactivated, online int64
+		private, groups                      int64
+		messages, messagesToday              int64
+		outbox                               int64
+	)
+	counts := []struct {
+		dest  *int64
+		model interface{}
+		where string
+		args  []interface{}
+	}{
+		{&users, &model.User{}, "", nil},
+		{&verified, &model.User{}, "email_verified_at IS NOT NULL", nil},
+		{&deactivated, &model.User{}, "deactivated_at IS NOT NULL", nil},
+		{&online, &model.User{}, "is_online = ?", []interface{}{true}},
+		{&private, &model.Conversation{}, "type = ?", []interface{}{model.ConversationTypePrivate}},
+		{&groups, &model.Conversation{}, "type = ?", []interface{}{model.ConversationTypeGroup}},
+		{&messages, &model.Message{}, "", nil},
+		{&messagesToday, &model.Message{}, "created_at > ?", []interface{}{time.Now().Add(-24 * time.Hour)}},
+		{&outbox, &model.OutboxEvent{}, "", nil},
+	}
+	for _, c := range counts {
+		query := db.Model(c.model)
+		if c.where != "" {
+			query = query.Where(c.where, c.args...)
+		}
+		if err := query.Count(c.dest).Error; err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Users:         %d (%d verified, %d deactivated, %d online)\n", users, verified, deactivated, online)
+	fmt.Printf("Conversations: %d private, %d groups\n", private, groups)
+	fmt.Printf("Messages:      %d (%d in the last 24h)\n", messages, messagesToday)
+	fmt.Printf("Outbox:        %d pending\n", outbox)
+
+	if stats, err := mailer.NewQueue(app.Redis(), nil).Stats(context.Background()); err != nil {
+		fmt.Printf("Email queue:   unavailable (%v)\n", err)
+	} else {
+		fmt.Printf("Email queue:   %d queued, %d retrying, %d failed\n", stats.Queued, stats.Retrying, stats.Dead)
+	}
+
+	if status, err := migrations.Status(app.Config().DB.URL()); err != nil {
+		fmt.Printf("Migrations:    unavailable (%v)\n", err)
+	} else {
+		fmt.Printf("Migrations:    version %d of %d (dirty: %v)\n", status.Version, status.Latest, status.Dirty)
+	}
+	return nil
+}
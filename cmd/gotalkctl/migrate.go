@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/quocanhngo/gotalk/migrations"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCmd(app *app) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply, roll back the last, show or force database migrations",
+	}
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "up",
+			Short: "Apply pending migrations",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				dbURL := app.Config().DB.URL()
+				status, err := migrations.Status(dbURL)
+				if err != nil {
+					return err
+				}
+				if err := status.Diverged(); err != nil {
+					return err
+				}
+				return migrations.Run(dbURL)
+			},
+		},
+		&cobra.Command{
+			Use:   "down",
+			Short: "Roll back the last migration",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return migrations.Rollback(app.Config().DB.URL())
+			},
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "Show the applied and latest migration versions",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				status, err := migrations.Status(app.Config().DB.URL())
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Version: %d\n", status.Version)
+				fmt.Printf("Latest:  %d\n", status.Latest)
+				fmt.Printf("Dirty:   %v\n", status.Dirty)
+				if err := status.Diverged(); err != nil {
+					fmt.Printf("⚠️  %v\n", err)
+				} else if status.Pending() {
+					fmt.Printf("%d migration(s) pending, apply with: gotalkctl migrate up\n", status.Latest-status.Version)
+				}
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "force <version>",
+			Short: "Record a version as applied without running it; -1 records that nothing is applied",
+			Args:  cobra.ExactArgs(1),
+			// So -1 is read as a version, not a flag
+			DisableFlagParsing: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				version, err := strconv.Atoi(args[0])
+				if err != nil {
+					return fmt.Errorf("invalid version %q", args[0])
+				}
+				return migrations.Force(app.Config().DB.URL(), version)
+			},
+		},
+	)
+	return cmd
+}
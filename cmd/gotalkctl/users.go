@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/pkg/auth"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// minPasswordLength matches the API's password rules
+const minPasswordLength = 6
+
+func newCreateAdminCmd(app *app) *cobra.Command {
+	var email, name, password string
+	cmd := &cobra.Command{
+		Use:   "create-admin --email EMAIL --name NAME [--password PASSWORD]",
+		Short: "Create a verified account for an administrator",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runCreateAdmin(app, email, name, password)
+		},
+	}
+	cmd.Flags().StringVar(&email, "email", "", "email address")
+	cmd.Flags().StringVar(&name, "name", "", "display name")
+	cmd.Flags().StringVar(&password, "password", "", "password (generated and printed when empty)")
+	_ = cmd.MarkFlagRequired("email")
+	_ = cmd.MarkFlagRequired("name")
+	return cmd
+}
+
+func runCreateAdmin(app *app, email, name, password string) error {
+	userRepo := repository.NewUserRepository(app.DB())
+	if _, err := userRepo.FindByEmail(email); err == nil {
+		return fmt.Errorf("an account with email %s already exists", email)
+	}
+
+	plain, generated, err := passwordOrGenerate(password)
+	if err != nil {
+		return err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(plain), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user := &model.User{
+		Name:            name,
+		Email:           email,
+		Password:        string(hashed),
+		AuthProvider:    model.AuthProviderEmail,
+		EmailVerifiedAt: &now,
+	}
+	if err := userRepo.Create(user); err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	fmt.Printf("✅ Created %s (%s, @%s)\n", user.Email, user.ID, user.Handle)
+	if generated {
+		fmt.Printf("🔑 Password: %s\n", plain)
+	}
+	if !isAdminEmail(app.Config().App.AdminEmails, user.Email) {
+		fmt.Printf("⚠️  Add %s to ADMIN_EMAILS to grant access to the admin API\n", user.Email)
+	}
+	return nil
+}
+
+func newResetPasswordCmd(app *app) *cobra.Command {
+	var email, password string
+	cmd := &cobra.Command{
+		Use:   "reset-password --email EMAIL [--password PASSWORD]",
+		Short: "Set a new password for an account",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runResetPassword(app, email, password)
+		},
+	}
+	cmd.Flags().StringVar(&email, "email", "", "email address")
+	cmd.Flags().StringVar(&password, "password", "", "new password (generated and printed when empty)")
+	_ = cmd.MarkFlagRequired("email")
+	return cmd
+}
+
+func runResetPassword(app *app, email, password string) error {
+	userRepo := repository.NewUserRepository(app.DB())
+	user, err := userRepo.FindByEmail(email)
+	if err != nil {
+		return fmt.Errorf("no account with email %s", email)
+	}
+	if user.AuthProvider == model.AuthProviderSSO {
+		return fmt.Errorf("%s signs in with single sign-on and has no password", user.Email)
+	}
+
+	plain, generated, err := passwordOrGenerate(password)
+	if err != nil {
+		return err
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(plain), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := userRepo.UpdatePassword(user.ID, string(hashed)); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Password reset for %s\n", user.Email)
+	if generated {
+		fmt.Printf("🔑 Password: %s\n", plain)
+	}
+	return nil
+}
+
+var errUserHeld = errors.New("under legal hold")
+
+// refuseHeldPurge fails when purging the user would destroy messages a legal
+// hold preserves: theirs, or any in a conversation they belong or belonged to
+func refuseHeldPurge(db *gorm.DB, user *model.User) error {
+	held, err := repository.NewLegalHoldRepository(db).CoversUser(user.ID)
+	if err != nil {
+		return err
+	}
+	if held {
+		return fmt.Errorf("%s (%s) is %w, directly or through a conversation; release the hold before purging", user.Email, user.ID, errUserHeld)
+	}
+	return nil
+}
+
+func newPurgeUserCmd(app *app) *cobra.Command {
+	var email, id string
+	var yes bool
+	cmd := &cobra.Command{
+		Use:   "purge-user --email EMAIL | --id ID --yes",
+		Short: "Permanently delete an account with its messages",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPurgeUser(app, email, id, yes)
+		},
+	}
+	cmd.Flags().StringVar(&email, "email", "", "email address")
+	cmd.Flags().StringVar(&id, "id", "", "user ID")
+	cmd.Flags().BoolVar(&yes, "yes", false, "confirm the deletion, which can't be undone")
+	cmd.MarkFlagsOneRequired("email", "id")
+	cmd.MarkFlagsMutuallyExclusive("email", "id")
+	return cmd
+}
+
+func runPurgeUser(app *app, email, id string, yes bool) error {
+	// Soft-deleted accounts can be purged too
+	db := app.DB()
+	var user model.User
+	query := db.Unscoped()
+	if id != "" {
+		userID, err := uuid.Parse(id)
+		if err != nil {
+			return fmt.Errorf("invalid user ID %q", id)
+		}
+		query = query.Where("id = ?", userID)
+	} else {
+		query = query.Where("email = ?", email)
+	}
+	if err := query.First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("no such user")
+		}
+		return err
+	}
+
+	if err := refuseHeldPurge(db, &user); err != nil {
+		return err
+	}
+	if !yes {
+		return fmt.Errorf("this permanently deletes %s (%s) with their messages; rerun with --yes to confirm", user.Email, user.ID)
+	}
+
+	var released int
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// A hold may have been placed since the check above
+		if err := refuseHeldPurge(tx, &user); err != nil {
+			return err
+		}
+
+		// Drop the blob references held by the user's attachments so unused files get purged
+		var hashes []string
+		if err := tx.Model(&model.MessageAttachment{}).
+			Joins("JOIN messages ON messages.id = message_attachments.message_id").
+			Where("messages.sender_id = ? AND message_attachments.blob_hash IS NOT NULL", user.ID).
+			Pluck("message_attachments.blob_hash", &hashes).Error; err != nil {
+			return err
+		}
+		blobRepo := repository.NewBlobRepository(tx)
+		for _, hash := range hashes {
+			if err := blobRepo.DecrementRef(hash); err != nil {
+				return err
+			}
+		}
+		released = len(hashes)
+
+		// Memberships, messages, devices and notifications go with the user (ON DELETE CASCADE)
+		return tx.Unscoped().Where("id = ?", user.ID).Delete(&model.User{}).Error
+	})
+	if err != nil {
+		if errors.Is(err, errUserHeld) {
+			return err
+		}
+		return fmt.Errorf("failed to purge user: %w", err)
+	}
+
+	// Reject tokens still in circulation
+	if err := app.Redis().Set(context.Background(), auth.RevokedUserKey(user.ID), "purged", app.Config().JWT.Expiry).Err(); err != nil {
+		fmt.Printf("⚠️  Failed to revoke tokens for %s, they stay valid until they expire: %v\n", user.ID, err)
+	}
+
+	fmt.Printf("🗑️  Purged %s (%s), released %d file reference(s)\n", user.Email, user.ID, released)
+	return nil
+}
+
+// passwordOrGenerate returns the given password after checking its length, or a random one
+func passwordOrGenerate(password string) (plain string, generated bool, err error) {
+	if password != "" {
+		if len(password) < minPasswordLength {
+			return "", false, fmt.Errorf("password must be at least %d characters", minPasswordLength)
+		}
+		return password, false, nil
+	}
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", false, err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), true, nil
+}
+
+func isAdminEmail(adminEmails []string, email string) bool {
+	for _, admin := range adminEmails {
+		if strings.EqualFold(strings.TrimSpace(admin), email) {
+			return true
+		}
+	}
+	return false
+}
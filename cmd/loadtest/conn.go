@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/gen/client"
+)
+
+// readyTimeout is how long a connection waits for its bootstrap event, which
+// the server sends once the Hub registered it, before it sends anyway
+const readyTimeout = 5 * time.Second
+
+// typingFor is how long a connection shows as typing before stop_typing
+const typingFor = 2 * time.Second
+
+// connect opens one connection as the account and has it type and send
+// messages until sending ends, then keeps receiving until closing
+func (r *run) connect(sending, closing context.Context, acc *account, index int) {
+	dialCtx, cancel := context.WithTimeout(closing, 30*time.Second)
+	conn, err := client.New(client.Config{URL: r.opts.URL, Token: acc.token}).Connect(dialCtx)
+	cancel()
+	if err != nil {
+		if r.stats.connectFailed.Add(1) <= 3 {
+			log.Printf("⚠️  Connection %d (%s) failed: %v", index, acc.email, err)
+		}
+		return
+	}
+	r.stats.connected.Add(1)
+
+	ready := make(chan struct{})
+	lost := make(chan struct{})
+	go func() {
+		defer close(lost)
+		r.receive(conn, ready)
+		if closing.Err() == nil {
+			r.stats.disconnected.Add(1)
+		}
+	}()
+
+	defer func() {
+		conn.Close()
+		<-lost
+	}()
+
+	select {
+	case <-ready:
+	case <-time.After(readyTimeout):
+	case <-lost:
+		return
+	case <-closing.Done():
+		return
+	}
+	acc.online.Add(1)
+	defer acc.online.Add(-1)
+
+	r.send(sending, lost, conn, acc, index)
+
+	select {
+	case <-closing.Done():
+	case <-lost:
+	}
+}
+
+// receive reads events until the connection ends, matching new messages to
+// the ones this run sent
+func (r *run) receive(conn *client.Conn, ready chan struct{}) {
+	var once sync.Once
+	for {
+		event, err := conn.Next()
+		if err != nil {
+			return
+		}
+		switch event.Type {
+		case client.EventBootstrap:
+			once.Do(func() { close(ready) })
+		case client.EventNewMessage:
+			var payload struct {
+				Content string `json:"content"`
+			}
+			if json.Unmarshal(event.Payload, &payload) == nil {
+				r.stats.delivered(r.id, payload.Content)
+			}
+		case client.EventTyping, client.EventTypingSummary:
+			r.stats.typingReceived.Add(1)
+		case client.EventError:
+			var payload client.ErrorResponse
+			json.Unmarshal(event.Payload, &payload)
+			if r.stats.serverErrors.Add(1) <= 3 {
+				log.Printf("⚠️  Server error event: %s: %s", payload.Code, payload.Error)
+			}
+		}
+	}
+}
+
+// send types and sends messages at random intervals averaging the configured
+// rates, so connections don't act in lockstep
+func (r *run) send(sending context.Context, lost <-chan struct{}, conn *client.Conn, acc *account, index int) {
+	rnd := rand.New(rand.NewPCG(uint64(index), uint64(time.Now().UnixNano())))
+	next := func(rate float64) <-chan time.Time {
+		if rate == 0 {
+			return nil
+		}
+		return time.After(time.Duration(rnd.ExpFloat64() / rate * float64(time.Second)))
+	}
+	messages, typing := next(r.opts.Rate), next(r.opts.Typing)
+	var stopTyping <-chan time.Time
+	var typingIn uuid.UUID
+
+	for seq := 0; ; seq++ {
+		var err error
+		select {
+		case <-sending.Done():
+			return
+		case <-lost:
+			return
+		case <-messages:
+			messages = next(r.opts.Rate)
+			convID := acc.conversations[rnd.IntN(len(acc.conversations))]
+			marker := fmt.Sprintf("[load %s.%d.%d]", r.id, index, seq)
+			r.stats.track(marker, r.receivers(convID))
+			err = conn.Send(client.EventNewMessage, map[string]interface{}{
+				"conversation_id": convID,
+				"content":         filler(marker, r.opts.Size),
+			})
+			if err == nil {
+				r.stats.sent.Add(1)
+			} else {
+				r.stats.forget(marker)
+			}
+		case <-typing:
+			typing = next(r.opts.Typing)
+			typingIn = acc.conversations[rnd.IntN(len(acc.conversations))]
+			err = conn.Send(client.EventTyping, map[string]interface{}{"conversation_id": typingIn})
+			stopTyping = time.After(typingFor)
+			if err == nil {
+				r.stats.typingSent.Add(1)
+			}
+		case <-stopTyping:
+			stopTyping = nil
+			err = conn.Send(client.EventStopTyping, map[string]interface{}{"conversation_id": typingIn})
+		}
+		if err != nil {
+			r.stats.sendFailed.Add(1)
+			return
+		}
+	}
+}
+
+// receivers counts the connections that should get a message to the
+// conversation: every ready one of its members, the sender's included
+func (r *run) receivers(convID uuid.UUID) int64 {
+	var n int64
+	for _, acc := range r.members[convID] {
+		n += acc.online.Load()
+	}
+	return n
+}
+
+// filler pads a marker to a message of size bytes
+func filler(marker string, size int) string {
+	const text = " The quick brown fox jumps over the lazy dog."
+	var b strings.Builder
+	b.WriteString(marker)
+	for b.Len() < size {
+		b.WriteString(text)
+	}
+	return b.String()[:max(size, len(marker))]
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/config"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/pkg/auth"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// loadtest soaks a running server's Hub: it connects thousands of WebSocket
+// clients as the seeded test accounts, has them type and send messages to
+// their conversations and reports delivery latency and the deliveries that
+// never arrived. Tokens are signed with the JWT secret of the configuration
+// (.env, CONFIG_FILE and the environment), which must match the server's:
+//
+//	go run ./cmd/loadtest -url http://localhost:8080 -clients 2000 -join 100 -rate 0.2 -duration 5m
+
+// options controls a run
+type options struct {
+	URL      string
+	Clients  int
+	Accounts int
+	Join     float64
+	Rate     float64
+	Typing   float64
+	Size     int
+	Duration time.Duration
+	Drain    time.Duration
+}
+
+// account is a test account with the conversations it can send to
+type account struct {
+	id            uuid.UUID
+	email         string
+	token         string
+	conversations []uuid.UUID
+
+	online atomic.Int64 // connections ready to receive
+}
+
+// run is one load test
+type run struct {
+	opts     options
+	id       string                   // tags this run's messages, so older ones are ignored
+	accounts []*account               // the test accounts in use
+	members  map[uuid.UUID][]*account // conversation -> its members among them
+	stats    *stats
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.URL, "url", "http://localhost:8080", "server root; the WebSocket is at /ws")
+	flag.IntVar(&opts.Clients, "clients", 1000, "WebSocket connections, spread over the test accounts")
+	flag.IntVar(&opts.Accounts, "accounts", 0, "test accounts to connect as (0 for every seeded account with conversations)")
+	flag.Float64Var(&opts.Join, "join", 50, "connections opened per second while ramping up")
+	flag.Float64Var(&opts.Rate, "rate", 0.1, "messages per second per connection (on average)")
+	flag.Float64Var(&opts.Typing, "typing", 0.2, "typing indicators per second per connection (on average); 0 for none")
+	flag.IntVar(&opts.Size, "size", 64, "message length in bytes")
+	flag.DurationVar(&opts.Duration, "duration", time.Minute, "how long connections send, counted from the first join")
+	flag.DurationVar(&opts.Drain, "drain", 10*time.Second, "how long to wait for deliveries after sending stops")
+	flag.Parse()
+
+	if err := opts.validate(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("❌ Invalid configuration:\n%v", err)
+	}
+	db, err := gorm.Open(postgres.Open(cfg.DB.DSN()), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to database: %v", err)
+	}
+
+	// Tokens outlive the run, so none expires mid-test
+	jwtManager := auth.NewJWTManager(cfg.JWT.Secret, opts.Duration+opts.Drain+time.Hour)
+	r, err := newRun(db, jwtManager, opts)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	r.start()
+}
+
+func (o options) validate() error {
+	if o.Clients <= 0 || o.Join <= 0 || o.Rate < 0 || o.Typing < 0 || o.Duration <= 0 || o.Drain < 0 || o.Accounts < 0 {
+		return errors.New("-clients, -join and -duration must be positive; -rate, -typing, -drain and -accounts can't be negative")
+	}
+	if o.Size < 32 {
+		return errors.New("-size must be at least 32, to fit the message marker")
+	}
+	u, err := url.Parse(o.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("-url must be an http:// or https:// server root, got %q", o.URL)
+	}
+	return nil
+}
+
+// newRun loads the seeded test accounts that belong to a conversation and
+// signs their tokens
+func newRun(db *gorm.DB, jwtManager *auth.JWTManager, opts options) (*run, error) {
+	var users []model.User
+	if err := db.Where("email LIKE ?", "user%@gotalk.local").Order("created_at").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	var members []model.ConversationMember
+	if err := db.Where("user_id IN (?)", db.Model(&model.User{}).Select("id").Where("email LIKE ?", "user%@gotalk.local")).
+		Find(&members).Error; err != nil {
+		return nil, err
+	}
+	conversations := map[uuid.UUID][]uuid.UUID{}
+	for _, m := range members {
+		conversations[m.UserID] = append(conversations[m.UserID], m.ConversationID)
+	}
+
+	id := make([]byte, 3)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	r := &run{opts: opts, id: hex.EncodeToString(id), members: map[uuid.UUID][]*account{}, stats: newStats()}
+	for _, u := range users {
+		if len(conversations[u.ID]) == 0 {
+			continue
+		}
+		if opts.Accounts > 0 && len(r.accounts) == opts.Accounts {
+			break
+		}
+		token, err := jwtManager.GenerateToken(u.ID, u.Email, u.Name)
+		if err != nil {
+			return nil, err
+		}
+		acc := &account{id: u.ID, email: u.Email, token: token, conversations: conversations[u.ID]}
+		r.accounts = append(r.accounts, acc)
+		for _, convID := range acc.conversations {
+			r.members[convID] = append(r.members[convID], acc)
+		}
+	}
+	if len(r.accounts) == 0 {
+		return nil, errors.New("no test accounts with conversations; run the seeder with -conversations first")
+	}
+	return r, nil
+}
+
+// start ramps up the connections, lets them send for the duration, waits for
+// the last deliveries and reports
+func (r *run) start() {
+	log.Printf("🔥 Load %s: %d clients over %d accounts joining at %.0f/s, %.2f msg/s and %.2f typing/s each, for %s against %s",
+		r.id, r.opts.Clients, len(r.accounts), r.opts.Join, r.opts.Rate, r.opts.Typing, r.opts.Duration, r.opts.URL)
+
+	// Connections send until sending ends, and stay open to receive until closing
+	sending, stopSending := context.WithTimeout(context.Background(), r.opts.Duration)
+	defer stopSending()
+	closing, closeAll := context.WithCancel(context.Background())
+	defer closeAll()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		joins := time.NewTicker(time.Duration(float64(time.Second) / r.opts.Join))
+		defer joins.Stop()
+		for i := 0; i < r.opts.Clients; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				r.connect(sending, closing, r.accounts[i%len(r.accounts)], i)
+			}(i)
+			select {
+			case <-sending.Done():
+				return
+			case <-joins.C:
+			}
+		}
+	}()
+
+	progress := time.NewTicker(10 * time.Second)
+	defer progress.Stop()
+	for sending.Err() == nil {
+		select {
+		case <-progress.C:
+			r.stats.progress(time.Since(start))
+		case <-sending.Done():
+		}
+	}
+
+	log.Printf("⏳ Sending stopped, waiting up to %s for deliveries", r.opts.Drain)
+	deadline := time.Now().Add(r.opts.Drain)
+	for time.Now().Before(deadline) && r.stats.undelivered() > 0 {
+		time.Sleep(100 * time.Millisecond)
+	}
+	closeAll()
+	wg.Wait()
+
+	r.stats.report(r.opts.Duration)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// stats collects the results of a run
+type stats struct {
+	connected, connectFailed, disconnected atomic.Int64
+	sent, sendFailed, serverErrors         atomic.Int64
+	typingSent, typingReceived             atomic.Int64
+	unexpected                             atomic.Int64 // deliveries of a message beyond its receivers
+
+	mu        sync.Mutex
+	pending   map[string]*pendingMessage // marker -> message still to be delivered somewhere
+	latencies []time.Duration            // one per delivery
+}
+
+// pendingMessage is a sent message waiting for its deliveries
+type pendingMessage struct {
+	sentAt  time.Time
+	missing int64 // receivers that haven't got it yet
+}
+
+func newStats() *stats {
+	return &stats{pending: map[string]*pendingMessage{}}
+}
+
+// track records a message about to be sent to receivers connections
+func (s *stats) track(marker string, receivers int64) {
+	s.mu.Lock()
+	s.pending[marker] = &pendingMessage{sentAt: time.Now(), missing: receivers}
+	s.mu.Unlock()
+}
+
+// forget drops a message that couldn't be sent
+func (s *stats) forget(marker string) {
+	s.mu.Lock()
+	delete(s.pending, marker)
+	s.mu.Unlock()
+}
+
+// delivered records a new_message received by a connection; messages of
+// other runs or senders are ignored
+func (s *stats) delivered(runID, content string) {
+	end := strings.Index(content, "]")
+	if !strings.HasPrefix(content, "[load "+runID+".") || end < 0 {
+		return
+	}
+	marker := content[:end+1]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msg, ok := s.pending[marker]
+	if !ok {
+		s.unexpected.Add(1)
+		return
+	}
+	s.latencies = append(s.latencies, time.Since(msg.sentAt))
+	if msg.missing--; msg.missing <= 0 {
+		delete(s.pending, marker)
+	}
+}
+
+// undelivered counts the deliveries still missing
+func (s *stats) undelivered() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int64
+	for _, msg := range s.pending {
+		n += msg.missing
+	}
+	return n
+}
+
+func (s *stats) progress(elapsed time.Duration) {
+	s.mu.Lock()
+	deliveries := len(s.latencies)
+	s.mu.Unlock()
+	log.Printf("⏱️  %s: %d connected, %d sent, %d delivered, %d pending, %d typing received", elapsed.Round(time.Second),
+		s.connected.Load()-s.disconnected.Load(), s.sent.Load(), deliveries, s.undelivered(), s.typingReceived.Load())
+}
+
+func (s *stats) report(duration time.Duration) {
+	dropped := s.undelivered()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delivered := int64(len(s.latencies))
+	expected := delivered + dropped
+
+	log.Println("📊 Load test results")
+	log.Printf("   Connections: %d ok, %d failed, %d lost before the end", s.connected.Load(), s.connectFailed.Load(), s.disconnected.Load())
+	log.Printf("   Messages:    %d sent (%.1f msg/s), %d send failures, %d error events", s.sent.Load(),
+		float64(s.sent.Load())/duration.Seconds(), s.sendFailed.Load(), s.serverErrors.Load())
+	if expected > 0 {
+		log.Printf("   Deliveries:  %d of %d, %d dropped (%.3f%%), %d unexpected", delivered, expected, dropped,
+			100*float64(dropped)/float64(expected), s.unexpected.Load())
+	}
+	log.Printf("   Typing:      %d sent, %d indicators received", s.typingSent.Load(), s.typingReceived.Load())
+	if len(s.latencies) == 0 {
+		return
+	}
+	sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+	percentile := func(p float64) time.Duration {
+		return s.latencies[int(p*float64(len(s.latencies)-1))].Round(100 * time.Microsecond)
+	}
+	log.Printf("   Latency:     p50 %s, p90 %s, p99 %s, p99.9 %s, max %s",
+		percentile(0.50), percentile(0.90), percentile(0.99), percentile(0.999), percentile(1))
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+)
+
+// faker generates plausible names and chat content from a seeded source, so a
+// given -seed always produces the same data
+type faker struct {
+	rnd *rand.Rand
+}
+
+func newFaker(seed uint64) *faker {
+	return &faker{rnd: rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))}
+}
+
+var (
+	firstNames = []string{
+		"An", "Binh", "Chi", "Dung", "Giang", "Hanh", "Huy", "Khanh", "Linh", "Minh", "Nam", "Ngoc",
+		"Phuong", "Quan", "Thao", "Trang", "Tuan", "Vy", "Alice", "Ben", "Carla", "David", "Emma",
+		"Felix", "Grace", "Hugo", "Isla", "Jonas", "Kira", "Leo", "Maya", "Noah", "Olivia", "Paul",
+		"Rosa", "Sam", "Tara", "Victor", "Wen", "Yuki", "Zoe",
+	}
+	lastNames = []string{
+		"Nguyen", "Tran", "Le", "Pham", "Hoang", "Vu", "Dang", "Bui", "Do", "Ngo", "Smith", "Garcia",
+		"Muller", "Rossi", "Dubois", "Kim", "Sato", "Silva", "Novak", "Jensen", "Cohen", "Walker",
+	}
+	groupNames = []string{
+		"Weekend Plans", "Project Phoenix", "Book Club", "Family", "Design Review", "Running Crew",
+		"Backend Team", "Trip to Da Lat", "Coffee Lovers", "Standup", "Movie Night", "Release Train",
+		"Study Group", "Football Friday", "Product Launch", "Neighbours",
+	}
+	openers = []string{
+		"hey", "ok so", "quick question:", "heads up,", "lol", "honestly", "btw", "morning!", "fyi", "wait,",
+	}
+	phrases = []string{
+		"are we still on for tonight", "can you send me the link", "the build is green again",
+		"I'll be 10 minutes late", "did you see the new design", "let's push it to tomorrow",
+		"that sounds great", "I just landed", "who's bringing snacks", "the meeting moved to 3pm",
+		"check the doc when you have a sec", "thanks a lot", "I can't find my keys",
+		"the deploy went fine", "should we order pizza", "see you at the station",
+		"I left a few comments on the PR", "happy birthday", "can someone review this",
+		"traffic is terrible today", "the demo went really well", "what time works for you",
+	}
+	closers   = []string{"", "", "", "?", "!", " 😂", " 🙏", " 👍", " 🎉", " ☕", " 🚀", "..."}
+	fileNames = []string{
+		"report.pdf", "slides.pdf", "invoice.pdf", "notes.txt", "budget.xlsx", "contract.docx", "roadmap.pdf",
+	}
+)
+
+// Name returns a random full name
+func (f *faker) Name() string {
+	return pick(f, firstNames) + " " + pick(f, lastNames)
+}
+
+// GroupName returns a random group conversation name
+func (f *faker) GroupName() string {
+	return pick(f, groupNames)
+}
+
+// Message returns one or two chat-like sentences
+func (f *faker) Message() string {
+	var b strings.Builder
+	if f.rnd.IntN(3) == 0 {
+		b.WriteString(pick(f, openers) + " ")
+	}
+	b.WriteString(pick(f, phrases))
+	if f.rnd.IntN(4) == 0 {
+		b.WriteString(", " + pick(f, phrases))
+	}
+	b.WriteString(pick(f, closers))
+	return b.String()
+}
+
+// Image returns an image URL, file name and dimensions
+func (f *faker) Image() (url, fileName string, width, height int) {
+	width, height = 800, 600
+	if f.rnd.IntN(2) == 0 {
+		width, height = 600, 800
+	}
+	seed := f.rnd.Uint32()
+	return fmt.Sprintf("https://picsum.photos/seed/%d/%d/%d", seed, width, height), fmt.Sprintf("IMG_%04d.jpg", seed%10000), width, height
+}
+
+// FileName returns a document file name
+func (f *faker) FileName() string {
+	return pick(f, fileNames)
+}
+
+// Times returns n sorted times within span before now, spread according to
+// the distribution: "uniform", or "recent" to favour the last few days
+func (f *faker) Times(n int, span time.Duration, distribution string) []time.Time {
+	now := time.Now()
+	times := make([]time.Time, n)
+	for i := range times {
+		u := f.rnd.Float64()
+		if distribution == "recent" {
+			u = u * u * u // most of the mass close to now
+		}
+		times[i] = now.Add(-time.Duration(u * float64(span)))
+	}
+	// Oldest first, like a conversation history
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	return times
+}
+
+func pick[T any](f *faker, items []T) T {
+	return items[f.rnd.IntN(len(items))]
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"time"
@@ -14,10 +15,45 @@ import (
 	"gorm.io/gorm/logger"
 )
 
+// seedOptions controls how much data is generated
+type seedOptions struct {
+	Users         int
+	Conversations int
+	GroupRatio    float64
+	Messages      int
+	Attachments   float64
+	Span          time.Duration
+	Distribution  string
+	Seed          uint64
+}
+
+const seedPassword = "password123" // shared by every seeded account
+
 func main() {
+	var opts seedOptions
+	flag.IntVar(&opts.Users, "users", 10, "number of test accounts (user1@gotalk.local ...)")
+	flag.IntVar(&opts.Conversations, "conversations", 0, "random conversations to create between the test accounts")
+	flag.Float64Var(&opts.GroupRatio, "group-ratio", 0.3, "share of the random conversations that are groups")
+	flag.IntVar(&opts.Messages, "messages", 20, "messages per random conversation")
+	flag.Float64Var(&opts.Attachments, "attachments", 0.1, "share of messages with an attachment (0-1)")
+	flag.DurationVar(&opts.Span, "span", 30*24*time.Hour, "how far back message timestamps go")
+	flag.StringVar(&opts.Distribution, "distribution", "recent", "message time distribution: uniform or recent")
+	flag.Uint64Var(&opts.Seed, "seed", uint64(time.Now().UnixNano()), "random seed, for reproducible data")
+	flag.Parse()
+
+	if opts.Distribution != "uniform" && opts.Distribution != "recent" {
+		log.Fatalf("❌ -distribution must be uniform or recent, got %q", opts.Distribution)
+	}
+	if opts.Attachments < 0 || opts.Attachments > 1 || opts.GroupRatio < 0 || opts.GroupRatio > 1 {
+		log.Fatal("❌ -attachments and -group-ratio must be between 0 and 1")
+	}
+
 	// Load config
-	cfg := config.Load()
-	
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("❌ Invalid configuration:\n%v", err)
+	}
+
 	// Force DB logging off to avoid noise
 	db, err := gorm.Open(postgres.Open(cfg.DB.DSN()), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
@@ -27,53 +63,73 @@ func main() {
 	}
 	log.Println("✅ Connected to Database")
 
-	// Common password for all users
-	password := "password123"
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	fake := newFaker(opts.Seed)
+	log.Printf("🎲 Seed: %d (pass -seed %d to reproduce)", opts.Seed, opts.Seed)
+
+	users := seedUsers(db, fake, opts.Users)
+
+	// Create a demo group conversation
+	seedGroupChat(db)
+
+	if opts.Conversations > 0 {
+		seedConversations(db, fake, users, opts)
+	}
+
+	log.Println("🎉 Seeding completed!")
+}
+
+// seedUsers creates the missing test accounts user1..userN and returns all of them
+func seedUsers(db *gorm.DB, fake *faker, count int) []model.User {
+	log.Printf("🌱 Seeding %d users...", count)
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(seedPassword), bcrypt.DefaultCost)
 	if err != nil {
 		log.Fatalf("❌ Failed to hash password: %v", err)
 	}
 
-	// Create 10 users
-	log.Println("🌱 Seeding 10 users...")
-	
-	for i := 1; i <= 10; i++ {
-		username := fmt.Sprintf("user%d", i)
-		email := fmt.Sprintf("user%d@gotalk.local", i)
-		
-		// Check if exists
-		var existing model.User
-		if err := db.Where("email = ?", email).First(&existing).Error; err == nil {
-			if existing.Name == "" {
-				db.Model(&existing).Update("name", fmt.Sprintf("User Number %d", i))
-				log.Printf("🔄 Updated user name: %s", username)
-			}
-			continue
+	emails := make([]string, count)
+	for i := range emails {
+		emails[i] = fmt.Sprintf("user%d@gotalk.local", i+1)
+	}
+	var existing []model.User
+	if err := db.Where("email IN ?", emails).Find(&existing).Error; err != nil {
+		log.Fatalf("❌ Failed to load existing users: %v", err)
+	}
+	found := make(map[string]bool, len(existing))
+	for _, u := range existing {
+		found[u.Email] = true
+		if u.Name == "" {
+			db.Model(&u).Update("name", fake.Name())
+			log.Printf("🔄 Updated user name: %s", u.Handle)
 		}
+	}
 
-		now := time.Now()
-		user := model.User{
+	now := time.Now()
+	var created []model.User
+	for i, email := range emails {
+		if found[email] {
+			continue
+		}
+		username := fmt.Sprintf("user%d", i+1)
+		created = append(created, model.User{
 			ID:              uuid.New(),
-			Name:            fmt.Sprintf("User Number %d", i),
+			Name:            fake.Name(),
+			Handle:          username,
 			Email:           email,
 			Password:        string(hashedPassword),
 			AuthProvider:    model.AuthProviderEmail,
-			EmailVerifiedAt: &now, // Verified immediately
-			IsOnline:        i%3 == 0, // Randomly online (user3, user6, user9)
+			EmailVerifiedAt: &now,                                                                        // Verified immediately
+			IsOnline:        (i+1)%3 == 0,                                                                // Randomly online (user3, user6, user9, ...)
 			Avatar:          fmt.Sprintf("https://api.dicebear.com/7.x/avataaars/svg?seed=%s", username), // Random avatar
-		}
-
-		if err := db.Create(&user).Error; err != nil {
-			log.Printf("❌ Failed to create user %s: %v", username, err)
-		} else {
-			log.Printf("✅ Created user: %s | Email: %s | Pass: %s", username, email, password)
+		})
+	}
+	if len(created) > 0 {
+		if err := db.CreateInBatches(created, 500).Error; err != nil {
+			log.Fatalf("❌ Failed to create users: %v", err)
 		}
 	}
-
-	// Create a demo group conversation
-	seedGroupChat(db)
-
-	log.Println("🎉 Seeding completed!")
+	log.Printf("✅ Created %d users, %d already existed (password for all: %s)", len(created), len(existing), seedPassword)
+	return append(existing, created...)
 }
 
 func seedGroupChat(db *gorm.DB) {
@@ -122,7 +178,7 @@ func seedGroupChat(db *gorm.DB) {
 			Role:           model.MemberRoleMember,
 		})
 	}
-	
+
 	// Add a welcome message
 	msg := model.Message{
 		ID:             uuid.New(),
@@ -136,3 +192,165 @@ func seedGroupChat(db *gorm.DB) {
 
 	log.Println("✅ Created demo group: 'General Chat' with 3 members")
 }
+
+// seedConversations creates random private and group conversations between the
+// users, each with a message history
+func seedConversations(db *gorm.DB, fake *faker, users []model.User, opts seedOptions) {
+	if len(users) < 2 {
+		log.Println("⚠️  Need at least 2 users to create conversations")
+		return
+	}
+	log.Printf("🌱 Seeding %d conversations with %d messages each...", opts.Conversations, opts.Messages)
+
+	// Private conversations are unique per pair
+	pairs := map[[2]uuid.UUID]bool{}
+	var existingPairs []struct{ A, B uuid.UUID }
+	db.Raw(`
+		SELECT LEAST(a.user_id, b.user_id) AS a, GREATEST(a.user_id, b.user_id) AS b
+		FROM conversations c
+		JOIN conversation_members a ON a.conversation_id = c.id
+		JOIN conversation_members b ON b.conversation_id = c.id AND b.user_id > a.user_id
+		WHERE c.type = ? AND c.deleted_at IS NULL`, model.ConversationTypePrivate).Scan(&existingPairs)
+	for _, p := range existingPairs {
+		pairs[[2]uuid.UUID{p.A, p.B}] = true
+	}
+
+	start := time.Now()
+	var messageCount, attachmentCount int
+	for i := 0; i < opts.Conversations; i++ {
+		var members []model.User
+		conv := model.Conversation{ID: uuid.New(), Type: model.ConversationTypePrivate}
+
+		if len(users) >= 3 && fake.rnd.Float64() < opts.GroupRatio {
+			size := 3 + fake.rnd.IntN(min(6, len(users)-2))
+			for _, idx := range fake.rnd.Perm(len(users))[:size] {
+				members = append(members, users[idx])
+			}
+			conv.Type = model.ConversationTypeGroup
+			conv.Name = fake.GroupName()
+			conv.Avatar = fmt.Sprintf("https://api.dicebear.com/7.x/initials/svg?seed=%s", conv.ID)
+			conv.CreatorID = &members[0].ID
+		} else {
+			a, b := users[fake.rnd.IntN(len(users))], users[fake.rnd.IntN(len(users))]
+			key := [2]uuid.UUID{a.ID, b.ID}
+			if b.ID.String() < a.ID.String() {
+				key = [2]uuid.UUID{b.ID, a.ID}
+			}
+			if a.ID == b.ID || pairs[key] {
+				continue
+			}
+			pairs[key] = true
+			members = []model.User{a, b}
+		}
+
+		messages, attachments := fakeMessages(fake, conv.ID, members, opts)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if len(messages) > 0 {
+				conv.CreatedAt = messages[0].CreatedAt
+				conv.UpdatedAt = messages[len(messages)-1].CreatedAt
+			}
+			if err := tx.Create(&conv).Error; err != nil {
+				return err
+			}
+
+			convMembers := make([]model.ConversationMember, len(members))
+			for j, m := range members {
+				role := model.MemberRoleMember
+				if conv.Type == model.ConversationTypeGroup && j == 0 {
+					role = model.MemberRoleAdmin
+				}
+				convMembers[j] = model.ConversationMember{
+					ConversationID: conv.ID,
+					UserID:         m.ID,
+					Role:           role,
+					JoinedAt:       conv.CreatedAt,
+					LastReadAt:     lastRead(fake, messages),
+				}
+			}
+			if err := tx.Create(&convMembers).Error; err != nil {
+				return err
+			}
+
+			if len(messages) > 0 {
+				if err := tx.Omit("Sender", "Conversation", "ReplyTo").CreateInBatches(messages, 500).Error; err != nil {
+					return err
+				}
+			}
+			if len(attachments) > 0 {
+				if err := tx.Omit("Message").CreateInBatches(attachments, 500).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("❌ Failed to create conversation: %v", err)
+			continue
+		}
+		messageCount += len(messages)
+		attachmentCount += len(attachments)
+	}
+
+	log.Printf("✅ Created %d messages (%d attachments) in %s", messageCount, attachmentCount, time.Since(start).Round(time.Millisecond))
+}
+
+// fakeMessages generates a conversation's history, oldest first
+func fakeMessages(fake *faker, convID uuid.UUID, members []model.User, opts seedOptions) ([]model.Message, []model.MessageAttachment) {
+	times := fake.Times(opts.Messages, opts.Span, opts.Distribution)
+	messages := make([]model.Message, len(times))
+	var attachments []model.MessageAttachment
+
+	for i, at := range times {
+		messages[i] = model.Message{
+			ID:             uuid.New(),
+			ConversationID: convID,
+			SenderID:       members[fake.rnd.IntN(len(members))].ID,
+			Content:        fake.Message(),
+			Type:           model.MessageTypeText,
+			Status:         model.MessageStatusRead,
+			CreatedAt:      at,
+			UpdatedAt:      at,
+		}
+		if i > 0 && fake.rnd.IntN(10) == 0 {
+			messages[i].ReplyToID = &messages[fake.rnd.IntN(i)].ID
+		}
+
+		if fake.rnd.Float64() >= opts.Attachments {
+			continue
+		}
+		att := model.MessageAttachment{MessageID: messages[i].ID, CreatedAt: at}
+		if fake.rnd.IntN(10) < 7 {
+			att.Type = model.AttachmentTypeImage
+			att.URL, att.FileName, att.Width, att.Height = fake.Image()
+			att.MimeType = "image/jpeg"
+			att.FileSize = int64(100_000 + fake.rnd.IntN(2_000_000))
+			messages[i].Type = model.MessageTypeImage
+		} else {
+			att.Type = model.AttachmentTypeFile
+			att.FileName = fake.FileName()
+			att.URL = fmt.Sprintf("https://files.gotalk.local/seed/%s/%s", messages[i].ID, att.FileName)
+			att.MimeType = "application/octet-stream"
+			att.FileSize = int64(10_000 + fake.rnd.IntN(5_000_000))
+			messages[i].Type = model.MessageTypeFile
+		}
+		if fake.rnd.IntN(2) == 0 {
+			messages[i].Content = "" // attachment only
+		}
+		attachments = append(attachments, att)
+	}
+	return messages, attachments
+}
+
+// lastRead picks a read position near the end of the history, so some
+// conversations have unread messages
+func lastRead(fake *faker, messages []model.Message) *time.Time {
+	if len(messages) == 0 {
+		return nil
+	}
+	unread := 0
+	if fake.rnd.IntN(3) == 0 {
+		unread = fake.rnd.IntN(min(10, len(messages)))
+	}
+	at := messages[len(messages)-1-unread].CreatedAt
+	return &at
+}
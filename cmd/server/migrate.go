@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/quocanhngo/gotalk/internal/config"
+	"github.com/quocanhngo/gotalk/migrations"
+)
+
+// runMigrateCommand runs a -migrate action against the database instead of
+// starting the server. version is nil when -migrate-version wasn't given.
+func runMigrateCommand(cfg *config.Config, action string, version *int) error {
+	dbURL := cfg.DB.URL()
+	switch action {
+	case "up":
+		status, err := migrations.Status(dbURL)
+		if err != nil {
+			return err
+		}
+		if err := status.Diverged(); err != nil {
+			return err
+		}
+		return migrations.Run(dbURL)
+	case "down":
+		return migrations.Rollback(dbURL)
+	case "status":
+		status, err := migrations.Status(dbURL)
+		if err != nil {
+			return err
+		}
+		log.Printf("📦 Migrations: version %d of %d (dirty: %v)", status.Version, status.Latest, status.Dirty)
+		if err := status.Diverged(); err != nil {
+			log.Printf("⚠️  %v", err)
+		} else if status.Pending() {
+			log.Printf("📦 %d migration(s) pending, apply with: -migrate=up", status.Latest-status.Version)
+		}
+		return nil
+	case "force":
+		if version == nil {
+			return errors.New("-migrate=force needs -migrate-version (-1 records that nothing is applied)")
+		}
+		return migrations.Force(dbURL, *version)
+	}
+	return fmt.Errorf("unknown -migrate action %q, expected one of up, down, status, force", action)
+}
+
+// migrateOnStart applies pending migrations at startup. In production it
+// refuses when the database is dirty or newer than this binary, and doesn't
+// fall back to AutoMigrate, so a bad schema state stops the rollout instead
+// of being papered over.
+func migrateOnStart(cfg *config.Config) error {
+	dbURL := cfg.DB.URL()
+	if cfg.App.Env != "production" {
+		return migrations.Run(dbURL)
+	}
+
+	status, err := migrations.Status(dbURL)
+	if err != nil {
+		return err
+	}
+	if err := status.Diverged(); err != nil {
+		return fmt.Errorf("%w; resolve it with -migrate=status|down|force before starting", err)
+	}
+	return migrations.Run(dbURL)
+}
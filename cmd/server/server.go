@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/quocanhngo/gotalk/internal/config"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// server serves the API on APP_PORT over HTTP, or HTTPS when TLS is
+// configured, with a plain HTTP listener on TLS_HTTP_PORT redirecting to it
+type server struct {
+	api      *http.Server
+	redirect *http.Server // nil without TLS_HTTP_PORT
+	tls      config.TLSConfig
+}
+
+func newServer(cfg *config.Config, handler http.Handler) (*server, error) {
+	s := &server{api: newHTTPServer(cfg.HTTP, ":"+cfg.App.Port, handler), tls: cfg.TLS}
+	// HTTP/2 is offered to TLS clients through ALPN; the protocols also
+	// decide which of autocert's NextProtos are kept
+	s.api.Protocols.SetHTTP2(cfg.HTTP.HTTP2)
+	s.api.Protocols.SetUnencryptedHTTP2(cfg.HTTP.H2C)
+	if !cfg.TLS.Enabled() {
+		return s, nil
+	}
+
+	s.api.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	redirect := redirectToHTTPS(cfg.App.Port)
+	if len(cfg.TLS.AutocertDomains) > 0 {
+		if err := os.MkdirAll(cfg.TLS.AutocertCacheDir, 0o700); err != nil {
+			return nil, err
+		}
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.TLS.AutocertDomains...),
+			Cache:      autocert.DirCache(cfg.TLS.AutocertCacheDir),
+			Email:      cfg.TLS.AutocertEmail,
+		}
+		s.api.TLSConfig = manager.TLSConfig()
+		s.api.TLSConfig.MinVersion = tls.VersionTLS12
+		// Let's Encrypt's HTTP-01 challenges arrive on port 80
+		redirect = manager.HTTPHandler(redirect)
+	} else {
+		certs, err := newCertReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		s.api.TLSConfig.GetCertificate = certs.get
+	}
+	if cfg.TLS.HTTPPort != "" {
+		s.redirect = newHTTPServer(cfg.HTTP, ":"+cfg.TLS.HTTPPort, redirect)
+	}
+	return s, nil
+}
+
+// newHTTPServer applies the HTTP_* limits to a server on addr
+func newHTTPServer(cfg config.HTTPConfig, addr string, handler http.Handler) *http.Server {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		Protocols:         new(http.Protocols),
+	}
+	srv.Protocols.SetHTTP1(true)
+	return srv
+}
+
+// scheme is the API's URL scheme, for the startup log
+func (s *server) scheme() string {
+	if s.tls.Enabled() {
+		return "https"
+	}
+	return "http"
+}
+
+// serve listens until Shutdown, returning http.ErrServerClosed then
+func (s *server) serve() error {
+	if s.redirect != nil {
+		go func() {
+			if err := s.redirect.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("❌ HTTP redirect server failed: %v", err)
+			}
+		}()
+	}
+	if s.tls.Enabled() {
+		// The certificates come from TLSConfig
+		return s.api.ListenAndServeTLS("", "")
+	}
+	return s.api.ListenAndServe()
+}
+
+// shutdown stops accepting connections and waits for ongoing requests
+func (s *server) shutdown(ctx context.Context) error {
+	var errs []error
+	if s.redirect != nil {
+		errs = append(errs, s.redirect.Shutdown(ctx))
+	}
+	errs = append(errs, s.api.Shutdown(ctx))
+	return errors.Join(errs...)
+}
+
+// redirectToHTTPS sends plain HTTP requests to the same URL over HTTPS on port
+func redirectToHTTPS(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
+
+// certReloadInterval is how often the certificate files are checked for changes
+const certReloadInterval = time.Minute
+
+// certReloader serves a certificate from files, loading it again when they
+// change, so renewed certificates (e.g. by cert-manager) apply without a restart
+type certReloader struct {
+	certFile, keyFile string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time // of the loaded files, the later of both
+	checkedAt time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.checkedAt) > certReloadInterval {
+		if err := r.load(); err != nil {
+			// Keep serving the certificate loaded before
+			log.Printf("⚠️  Failed to reload the TLS certificate: %v", err)
+		}
+	}
+	return r.cert, nil
+}
+
+// load reads the files if they changed since the last load. Callers hold r.mu,
+// except newCertReloader.
+func (r *certReloader) load() error {
+	r.checkedAt = time.Now()
+	var modTime time.Time
+	for _, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	if r.cert != nil && modTime.Equal(r.modTime) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+	if r.cert != nil {
+		log.Println("🔐 TLS certificate reloaded")
+	}
+	r.cert, r.modTime = &cert, modTime
+	return nil
+}
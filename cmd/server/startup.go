@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quocanhngo/gotalk/internal/config"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+	"github.com/quocanhngo/gotalk/pkg/retry"
+)
+
+// startup waits for the server's dependencies with retries instead of
+// exiting when one isn't up yet, as happens when docker-compose or Kubernetes
+// start everything at once. Until the API is ready it serves the port itself:
+// /health answers, so the process isn't killed for being unresponsive, and
+// everything else gets 503.
+type startup struct {
+	cfg config.StartupConfig
+	ctx context.Context // ends at STARTUP_TIMEOUT or on SIGINT/SIGTERM
+
+	mu         sync.Mutex
+	waitingFor string // dependency being waited for, for /health/ready
+
+	api atomic.Pointer[http.Handler] // the router once it's ready
+}
+
+func newStartup(ctx context.Context, cfg config.StartupConfig) *startup {
+	return &startup{cfg: cfg, ctx: ctx}
+}
+
+// waitFor calls ping until the dependency answers
+func (s *startup) waitFor(name string, ping func(ctx context.Context) error) error {
+	s.mu.Lock()
+	s.waitingFor = name
+	s.mu.Unlock()
+
+	started := time.Now()
+	err := retry.Do(s.ctx, retry.Backoff{Initial: s.cfg.RetryInitial, Max: s.cfg.RetryMax}, func(ctx context.Context) error {
+		attemptCtx, cancel := context.WithTimeout(ctx, s.cfg.AttemptTimeout)
+		defer cancel()
+		return ping(attemptCtx)
+	}, func(attempt int, err error, wait time.Duration) {
+		log.Printf("⏳ %s isn't ready (attempt %d): %v; retrying in %s", name, attempt, err, wait.Round(10*time.Millisecond))
+	})
+	if err == nil && time.Since(started) > time.Second {
+		log.Printf("✅ %s is up after %s", name, time.Since(started).Round(time.Second))
+	}
+	return err
+}
+
+// timedOut reports whether err means STARTUP_TIMEOUT passed, rather than a signal
+func (s *startup) timedOut(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// ready hands every request to the API from now on
+func (s *startup) ready(api http.Handler) {
+	s.api.Store(&api)
+}
+
+func (s *startup) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if api := s.api.Load(); api != nil {
+		(*api).ServeHTTP(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	waitingFor := s.waitingFor
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	switch r.URL.Path {
+	case "/health":
+		json.NewEncoder(w).Encode(map[string]string{"status": "starting", "service": "gotalk-api"})
+	case "/health/ready":
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "starting", "waiting_for": waitingFor})
+	default:
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(model.ErrorResponse{Code: apperror.CodeUnavailable, Error: "The server is starting"})
+	}
+}
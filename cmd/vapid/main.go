@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/quocanhngo/gotalk/pkg/notification"
+)
+
+// Generates a VAPID key pair for Web Push and prints it as .env lines
+func main() {
+	publicKey, privateKey, err := notification.GenerateVAPIDKeys()
+	if err != nil {
+		log.Fatalf("❌ Failed to generate VAPID keys: %v", err)
+	}
+
+	fmt.Printf("VAPID_PUBLIC_KEY=%s\n", publicKey)
+	fmt.Printf("VAPID_PRIVATE_KEY=%s\n", privateKey)
+}
@@ -0,0 +1,1917 @@
+// Code generated by cmd/genapi from the API's routes and DTOs. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// BasePath is where the REST API is mounted
+const BasePath = "/api/v1"
+
+// services are the API's endpoints, grouped like the server's handlers
+type services struct {
+	Admin        *AdminService
+	Attachment   *AttachmentService
+	Auth         *AuthService
+	Call         *CallService
+	Chat         *ChatService
+	Compliance   *ComplianceService
+	Image        *ImageService
+	Import       *ImportService
+	Matrix       *MatrixService
+	Notification *NotificationService
+	OAuth        *OAuthService
+	Onboarding   *OnboardingService
+	Profile      *ProfileService
+	SSO          *SSOService
+	Search       *SearchService
+	Template     *TemplateService
+	Token        *TokenService
+	Upload       *UploadService
+}
+
+func (s *services) init(c *Client) {
+	s.Admin = &AdminService{client: c}
+	s.Attachment = &AttachmentService{client: c}
+	s.Auth = &AuthService{client: c}
+	s.Call = &CallService{client: c}
+	s.Chat = &ChatService{client: c}
+	s.Compliance = &ComplianceService{client: c}
+	s.Image = &ImageService{client: c}
+	s.Import = &ImportService{client: c}
+	s.Matrix = &MatrixService{client: c}
+	s.Notification = &NotificationService{client: c}
+	s.OAuth = &OAuthService{client: c}
+	s.Onboarding = &OnboardingService{client: c}
+	s.Profile = &ProfileService{client: c}
+	s.SSO = &SSOService{client: c}
+	s.Search = &SearchService{client: c}
+	s.Template = &TemplateService{client: c}
+	s.Token = &TokenService{client: c}
+	s.Upload = &UploadService{client: c}
+}
+
+// AdminService calls the endpoints of the server's AdminHandler
+type AdminService struct {
+	client *Client
+}
+
+// CreateInvitation calls POST /admin/invitations (Issue an invitation code)
+func (s *AdminService) CreateInvitation(ctx context.Context, body *CreateInvitationRequest, opts ...RequestOption) (*Invitation, error) {
+	out := new(Invitation)
+	if err := s.client.call(ctx, http.MethodPost, "/admin/invitations", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteInvitation calls DELETE /admin/invitations/{id} (Revoke an invitation code)
+func (s *AdminService) DeleteInvitation(ctx context.Context, id string, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodDelete, "/admin/invitations/"+url.PathEscape(id), nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DiscardFailedEmail calls DELETE /admin/emails/failed/{id} (Discard a dead-lettered email)
+func (s *AdminService) DiscardFailedEmail(ctx context.Context, id string, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodDelete, "/admin/emails/failed/"+url.PathEscape(id), nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetFailedEmails calls GET /admin/emails/failed (List dead-lettered emails)
+func (s *AdminService) GetFailedEmails(ctx context.Context, opts ...RequestOption) (*FailedEmailsResponse, error) {
+	out := new(FailedEmailsResponse)
+	if err := s.client.call(ctx, http.MethodGet, "/admin/emails/failed", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetFeatureFlags calls GET /admin/flags (Get the runtime feature flags)
+func (s *AdminService) GetFeatureFlags(ctx context.Context, opts ...RequestOption) (*FeatureFlags, error) {
+	out := new(FeatureFlags)
+	if err := s.client.call(ctx, http.MethodGet, "/admin/flags", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetMembershipCacheStats calls GET /admin/membership/stats (Get conversation membership cache stats)
+func (s *AdminService) GetMembershipCacheStats(ctx context.Context, opts ...RequestOption) (*MembershipCacheStats, error) {
+	out := new(MembershipCacheStats)
+	if err := s.client.call(ctx, http.MethodGet, "/admin/membership/stats", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetUsageStatsParams are the query parameters of AdminService.GetUsageStats
+type GetUsageStatsParams struct {
+	// Only this metric
+	Metric string
+	// First day, YYYY-MM-DD (default: 29 days before to)
+	From string
+	// Last day, YYYY-MM-DD (default: today)
+	To string
+}
+
+func (p *GetUsageStatsParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	if p.Metric != "" {
+		q.Set("metric", p.Metric)
+	}
+	if p.From != "" {
+		q.Set("from", p.From)
+	}
+	if p.To != "" {
+		q.Set("to", p.To)
+	}
+	return q
+}
+
+// GetUsageStats calls GET /admin/stats (Get usage statistics)
+func (s *AdminService) GetUsageStats(ctx context.Context, params *GetUsageStatsParams, opts ...RequestOption) (*UsageStatsResponse, error) {
+	out := new(UsageStatsResponse)
+	if err := s.client.call(ctx, http.MethodGet, "/admin/stats", params.values(), nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetWebSocketStats calls GET /admin/ws/stats (Get WebSocket connection stats)
+func (s *AdminService) GetWebSocketStats(ctx context.Context, opts ...RequestOption) (*WSStats, error) {
+	out := new(WSStats)
+	if err := s.client.call(ctx, http.MethodGet, "/admin/ws/stats", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListInvitations calls GET /admin/invitations (List invitation codes)
+func (s *AdminService) ListInvitations(ctx context.Context, opts ...RequestOption) ([]Invitation, error) {
+	var out []Invitation
+	if err := s.client.call(ctx, http.MethodGet, "/admin/invitations", nil, nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LogoutUser calls POST /admin/users/{id}/logout (Sign a user out on all devices)
+func (s *AdminService) LogoutUser(ctx context.Context, id string, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/admin/users/"+url.PathEscape(id)+"/logout", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ResendFailedEmail calls POST /admin/emails/failed/{id}/resend (Resend a dead-lettered email)
+func (s *AdminService) ResendFailedEmail(ctx context.Context, id string, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/admin/emails/failed/"+url.PathEscape(id)+"/resend", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SendNotice calls POST /admin/notices (Send an admin notice to the notification center)
+func (s *AdminService) SendNotice(ctx context.Context, body *AdminNoticeRequest, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/admin/notices", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SyncLDAP calls POST /admin/ldap/sync (Sync the LDAP directory now)
+func (s *AdminService) SyncLDAP(ctx context.Context, opts ...RequestOption) (*LDAPSyncResult, error) {
+	out := new(LDAPSyncResult)
+	if err := s.client.call(ctx, http.MethodPost, "/admin/ldap/sync", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateFeatureFlags calls PATCH /admin/flags (Change runtime feature flags)
+func (s *AdminService) UpdateFeatureFlags(ctx context.Context, body *UpdateFeatureFlagsRequest, opts ...RequestOption) (*FeatureFlags, error) {
+	out := new(FeatureFlags)
+	if err := s.client.call(ctx, http.MethodPatch, "/admin/flags", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AttachmentService calls the endpoints of the server's AttachmentHandler
+type AttachmentService struct {
+	client *Client
+}
+
+// GetAttachmentParams are the query parameters of AttachmentService.GetAttachment
+type GetAttachmentParams struct {
+	// original (default), processed (the transcoded video) or poster (the video's
+	// poster image)
+	Rendition string
+	// Save the file (Content-Disposition: attachment) instead of showing it
+	Download bool
+}
+
+func (p *GetAttachmentParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	if p.Rendition != "" {
+		q.Set("rendition", p.Rendition)
+	}
+	if p.Download {
+		q.Set("download", "true")
+	}
+	return q
+}
+
+// GetAttachment calls GET /attachments/{id} (Download a message attachment). The caller closes the application/octet-stream body. It takes the Range header, see WithHeader
+func (s *AttachmentService) GetAttachment(ctx context.Context, id string, params *GetAttachmentParams, opts ...RequestOption) (io.ReadCloser, error) {
+	return s.client.open(ctx, http.MethodGet, "/attachments/"+url.PathEscape(id), params.values(), nil, opts)
+}
+
+// AuthService calls the endpoints of the server's AuthHandler
+type AuthService struct {
+	client *Client
+}
+
+// CheckHandleParams are the query parameters of AuthService.CheckHandle
+type CheckHandleParams struct {
+	// Handle to check (with or without @)
+	Handle string
+}
+
+func (p *CheckHandleParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	if p.Handle != "" {
+		q.Set("handle", p.Handle)
+	}
+	return q
+}
+
+// CheckHandle calls GET /users/handle-availability (Check whether a handle is available)
+func (s *AuthService) CheckHandle(ctx context.Context, params *CheckHandleParams, opts ...RequestOption) (*HandleAvailabilityResponse, error) {
+	out := new(HandleAvailabilityResponse)
+	if err := s.client.call(ctx, http.MethodGet, "/users/handle-availability", params.values(), nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ForgotPassword calls POST /auth/forgot-password (Request password reset OTP)
+func (s *AuthService) ForgotPassword(ctx context.Context, body *ForgotPasswordRequest, opts ...RequestOption) (*OTPSentResponse, error) {
+	out := new(OTPSentResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/auth/forgot-password", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetLoginHistory calls GET /auth/logins (List recent sign-ins)
+func (s *AuthService) GetLoginHistory(ctx context.Context, opts ...RequestOption) ([]LoginEvent, error) {
+	var out []LoginEvent
+	if err := s.client.call(ctx, http.MethodGet, "/auth/logins", nil, nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetProfile calls GET /auth/profile (Get current user profile). It takes the If-None-Match, If-Modified-Since headers, see WithHeader
+func (s *AuthService) GetProfile(ctx context.Context, opts ...RequestOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	if err := s.client.call(ctx, http.MethodGet, "/auth/profile", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetSettings calls GET /auth/settings (Get user settings). It takes the If-None-Match, If-Modified-Since headers, see WithHeader
+func (s *AuthService) GetSettings(ctx context.Context, opts ...RequestOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	if err := s.client.call(ctx, http.MethodGet, "/auth/settings", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetUserByHandle calls GET /users/by-handle/{handle} (Get a user by @handle)
+func (s *AuthService) GetUserByHandle(ctx context.Context, handle string, opts ...RequestOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	if err := s.client.call(ctx, http.MethodGet, "/users/by-handle/"+url.PathEscape(handle), nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetWebPushKey calls GET /auth/device/webpush/key (Get the VAPID public key for browser push subscriptions)
+func (s *AuthService) GetWebPushKey(ctx context.Context, opts ...RequestOption) (*WebPushKeyResponse, error) {
+	out := new(WebPushKeyResponse)
+	if err := s.client.call(ctx, http.MethodGet, "/auth/device/webpush/key", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GoogleLogin calls POST /auth/google (Login with Google OAuth2)
+func (s *AuthService) GoogleLogin(ctx context.Context, body *GoogleLoginRequest, opts ...RequestOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/auth/google", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Login calls POST /auth/login (Login with email and password)
+func (s *AuthService) Login(ctx context.Context, body *LoginRequest, opts ...RequestOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/auth/login", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Logout calls POST /auth/logout (Logout)
+func (s *AuthService) Logout(ctx context.Context, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/auth/logout", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LogoutAll calls POST /auth/logout-all (Log out on all devices)
+func (s *AuthService) LogoutAll(ctx context.Context, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/auth/logout-all", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MagicLinkPageParams are the query parameters of AuthService.MagicLinkPage
+type MagicLinkPageParams struct {
+	// Token from the sign-in link
+	Token string
+}
+
+func (p *MagicLinkPageParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	if p.Token != "" {
+		q.Set("token", p.Token)
+	}
+	return q
+}
+
+// MagicLinkPage calls GET /auth/magic-link/verify (Confirm a magic link sign-in). The caller closes the text/html body
+func (s *AuthService) MagicLinkPage(ctx context.Context, params *MagicLinkPageParams, opts ...RequestOption) (io.ReadCloser, error) {
+	return s.client.open(ctx, http.MethodGet, "/auth/magic-link/verify", params.values(), nil, opts)
+}
+
+// Register calls POST /auth/register (Register a new user (sends OTP for verification))
+func (s *AuthService) Register(ctx context.Context, body *RegisterRequest, opts ...RequestOption) (*OTPSentResponse, error) {
+	out := new(OTPSentResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/auth/register", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RegisterDevice calls POST /auth/device (Register device for push notifications)
+func (s *AuthService) RegisterDevice(ctx context.Context, body *RegisterDeviceRequest, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/auth/device", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RegisterWebPush calls POST /auth/device/webpush (Subscribe this browser to Web Push notifications)
+func (s *AuthService) RegisterWebPush(ctx context.Context, body *WebPushSubscribeRequest, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/auth/device/webpush", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ResendOTP calls POST /auth/resend-otp (Resend OTP verification code)
+func (s *AuthService) ResendOTP(ctx context.Context, body *ResendOTPRequest, opts ...RequestOption) (*OTPSentResponse, error) {
+	out := new(OTPSentResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/auth/resend-otp", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ResetPassword calls POST /auth/reset-password (Reset password with OTP code)
+func (s *AuthService) ResetPassword(ctx context.Context, body *ResetPasswordRequest, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/auth/reset-password", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RevokeLoginAlert calls POST /auth/login-alerts/revoke (Sign out everywhere from a new sign-in alert)
+func (s *AuthService) RevokeLoginAlert(ctx context.Context, body *LoginAlertRevokeRequest, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/auth/login-alerts/revoke", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SearchUsersParams are the query parameters of AuthService.SearchUsers
+type SearchUsersParams struct {
+	// Search query
+	Q string
+}
+
+func (p *SearchUsersParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	if p.Q != "" {
+		q.Set("q", p.Q)
+	}
+	return q
+}
+
+// SearchUsers calls GET /users/search (Search users by username or email)
+func (s *AuthService) SearchUsers(ctx context.Context, params *SearchUsersParams, opts ...RequestOption) ([]UserResponse, error) {
+	var out []UserResponse
+	if err := s.client.call(ctx, http.MethodGet, "/users/search", params.values(), nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SendMagicLink calls POST /auth/magic-link (Email a one-time sign-in link)
+func (s *AuthService) SendMagicLink(ctx context.Context, body *MagicLinkRequest, opts ...RequestOption) (*OTPSentResponse, error) {
+	out := new(OTPSentResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/auth/magic-link", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UnregisterWebPush calls DELETE /auth/device/webpush (Remove this browser's Web Push subscription)
+func (s *AuthService) UnregisterWebPush(ctx context.Context, body *WebPushUnsubscribeRequest, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodDelete, "/auth/device/webpush", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateHandle calls PUT /auth/handle (Change the current user's @handle)
+func (s *AuthService) UpdateHandle(ctx context.Context, body *UpdateHandleRequest, opts ...RequestOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	if err := s.client.call(ctx, http.MethodPut, "/auth/handle", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateProfileForm is the form sent by AuthService.UpdateProfile
+type UpdateProfileForm struct {
+	// Avatar image file
+	Avatar *File
+	// Short bio (empty clears it)
+	Bio string
+	// Public display name (empty clears it)
+	DisplayName string
+	// User name
+	Name string
+}
+
+func (f *UpdateProfileForm) encode() *form {
+	form := newForm(true)
+	if f == nil {
+		return form
+	}
+	form.file("avatar", f.Avatar)
+	if f.Bio != "" {
+		form.values.Set("bio", f.Bio)
+	}
+	if f.DisplayName != "" {
+		form.values.Set("display_name", f.DisplayName)
+	}
+	if f.Name != "" {
+		form.values.Set("name", f.Name)
+	}
+	return form
+}
+
+// UpdateProfile calls PUT /auth/profile (Update user profile)
+func (s *AuthService) UpdateProfile(ctx context.Context, form *UpdateProfileForm, opts ...RequestOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	if err := s.client.call(ctx, http.MethodPut, "/auth/profile", nil, form.encode(), out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateSettings calls PUT /auth/settings (Update user settings)
+func (s *AuthService) UpdateSettings(ctx context.Context, body *UpdateSettingsRequest, opts ...RequestOption) (*UserResponse, error) {
+	out := new(UserResponse)
+	if err := s.client.call(ctx, http.MethodPut, "/auth/settings", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// VerifyMagicLink calls POST /auth/magic-link/verify (Sign in with a magic link)
+func (s *AuthService) VerifyMagicLink(ctx context.Context, body *VerifyMagicLinkRequest, opts ...RequestOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/auth/magic-link/verify", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// VerifyOTP calls POST /auth/verify-otp (Verify email with OTP code)
+func (s *AuthService) VerifyOTP(ctx context.Context, body *VerifyOTPRequest, opts ...RequestOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/auth/verify-otp", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CallService calls the endpoints of the server's CallHandler
+type CallService struct {
+	client *Client
+}
+
+// GetCallQualityParams are the query parameters of CallService.GetCallQuality
+type GetCallQualityParams struct {
+	// First day, YYYY-MM-DD (default: 29 days before to)
+	From string
+	// Last day, YYYY-MM-DD (default: today)
+	To string
+}
+
+func (p *GetCallQualityParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	if p.From != "" {
+		q.Set("from", p.From)
+	}
+	if p.To != "" {
+		q.Set("to", p.To)
+	}
+	return q
+}
+
+// GetCallQuality calls GET /admin/calls/quality (Get call quality by network and region)
+func (s *CallService) GetCallQuality(ctx context.Context, params *GetCallQualityParams, opts ...RequestOption) (*CallQualityReport, error) {
+	out := new(CallQualityReport)
+	if err := s.client.call(ctx, http.MethodGet, "/admin/calls/quality", params.values(), nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReportStats calls POST /calls/{id}/stats (Report WebRTC stats during a call)
+func (s *CallService) ReportStats(ctx context.Context, id string, body *CallStatsRequest, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/calls/"+url.PathEscape(id)+"/stats", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SubmitFeedback calls POST /calls/{id}/feedback (Rate a call)
+func (s *CallService) SubmitFeedback(ctx context.Context, id string, body *CallFeedbackRequest, opts ...RequestOption) (*CallFeedback, error) {
+	out := new(CallFeedback)
+	if err := s.client.call(ctx, http.MethodPost, "/calls/"+url.PathEscape(id)+"/feedback", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ChatService calls the endpoints of the server's ChatHandler
+type ChatService struct {
+	client *Client
+}
+
+// AddMembers calls POST /conversations/{id}/members (Add people to a group)
+func (s *ChatService) AddMembers(ctx context.Context, id string, body *AddMembersRequest, opts ...RequestOption) (*Conversation, error) {
+	out := new(Conversation)
+	if err := s.client.call(ctx, http.MethodPost, "/conversations/"+url.PathEscape(id)+"/members", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ClearHistory calls POST /conversations/{id}/clear (Clear a conversation's history for yourself)
+func (s *ChatService) ClearHistory(ctx context.Context, id string, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/conversations/"+url.PathEscape(id)+"/clear", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateConversation calls POST /conversations (Create a new conversation)
+func (s *ChatService) CreateConversation(ctx context.Context, body *CreateConversationRequest, opts ...RequestOption) (*Conversation, error) {
+	out := new(Conversation)
+	if err := s.client.call(ctx, http.MethodPost, "/conversations", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteConversation calls DELETE /conversations/{id} (Delete a conversation for everyone)
+func (s *ChatService) DeleteConversation(ctx context.Context, id string, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodDelete, "/conversations/"+url.PathEscape(id), nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteRole calls DELETE /conversations/{id}/roles/{role} (Delete a custom role)
+func (s *ChatService) DeleteRole(ctx context.Context, id string, role string, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodDelete, "/conversations/"+url.PathEscape(id)+"/roles/"+url.PathEscape(role), nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ExportConversation calls POST /conversations/{id}/export (Export a conversation)
+func (s *ChatService) ExportConversation(ctx context.Context, id string, body *ExportConversationRequest, opts ...RequestOption) (*ConversationExport, error) {
+	out := new(ConversationExport)
+	if err := s.client.call(ctx, http.MethodPost, "/conversations/"+url.PathEscape(id)+"/export", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FreezeConversation calls POST /conversations/{id}/freeze (Freeze a group so only members who manage it can post)
+func (s *ChatService) FreezeConversation(ctx context.Context, id string, opts ...RequestOption) (*Conversation, error) {
+	out := new(Conversation)
+	if err := s.client.call(ctx, http.MethodPost, "/conversations/"+url.PathEscape(id)+"/freeze", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetConversation calls GET /conversations/{id} (Get a specific conversation). It takes the If-None-Match, If-Modified-Since headers, see WithHeader
+func (s *ChatService) GetConversation(ctx context.Context, id string, opts ...RequestOption) (*Conversation, error) {
+	out := new(Conversation)
+	if err := s.client.call(ctx, http.MethodGet, "/conversations/"+url.PathEscape(id), nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetConversations calls GET /conversations (Get all conversations for the current user). It takes the If-None-Match, If-Modified-Since headers, see WithHeader
+func (s *ChatService) GetConversations(ctx context.Context, opts ...RequestOption) ([]ConversationResponse, error) {
+	var out []ConversationResponse
+	if err := s.client.call(ctx, http.MethodGet, "/conversations", nil, nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetExport calls GET /conversations/{id}/exports/{export_id} (Get a conversation export)
+func (s *ChatService) GetExport(ctx context.Context, id string, exportID string, opts ...RequestOption) (*ConversationExport, error) {
+	out := new(ConversationExport)
+	if err := s.client.call(ctx, http.MethodGet, "/conversations/"+url.PathEscape(id)+"/exports/"+url.PathEscape(exportID), nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetInsightsParams are the query parameters of ChatService.GetInsights
+type GetInsightsParams struct {
+	// First day, YYYY-MM-DD (default: 29 days before to)
+	From string
+	// Last day, YYYY-MM-DD (default: today)
+	To string
+}
+
+func (p *GetInsightsParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	if p.From != "" {
+		q.Set("from", p.From)
+	}
+	if p.To != "" {
+		q.Set("to", p.To)
+	}
+	return q
+}
+
+// GetInsights calls GET /conversations/{id}/insights (Get a group's activity insights)
+func (s *ChatService) GetInsights(ctx context.Context, id string, params *GetInsightsParams, opts ...RequestOption) (*ConversationInsights, error) {
+	out := new(ConversationInsights)
+	if err := s.client.call(ctx, http.MethodGet, "/conversations/"+url.PathEscape(id)+"/insights", params.values(), nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetMessageInfo calls GET /messages/{id}/info (Get delivery and read times of a message)
+func (s *ChatService) GetMessageInfo(ctx context.Context, id string, opts ...RequestOption) (*MessageInfo, error) {
+	out := new(MessageInfo)
+	if err := s.client.call(ctx, http.MethodGet, "/messages/"+url.PathEscape(id)+"/info", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetMessagesParams are the query parameters of ChatService.GetMessages
+type GetMessagesParams struct {
+	// Cursor: message ID to get messages before
+	Before string
+	// Cursor: message ID to get messages after
+	After string
+	// Message ID to center the page on
+	Around string
+	// RFC 3339 time or YYYY-MM-DD date to center the page on
+	Date string
+	// Number of messages to return (default: 50)
+	Limit int
+}
+
+func (p *GetMessagesParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	if p.Before != "" {
+		q.Set("before", p.Before)
+	}
+	if p.After != "" {
+		q.Set("after", p.After)
+	}
+	if p.Around != "" {
+		q.Set("around", p.Around)
+	}
+	if p.Date != "" {
+		q.Set("date", p.Date)
+	}
+	if p.Limit != 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	return q
+}
+
+// GetMessages calls GET /conversations/{id}/messages (Get messages for a conversation)
+func (s *ChatService) GetMessages(ctx context.Context, id string, params *GetMessagesParams, opts ...RequestOption) ([]Message, error) {
+	var out []Message
+	if err := s.client.call(ctx, http.MethodGet, "/conversations/"+url.PathEscape(id)+"/messages", params.values(), nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetOrCreateDirect calls POST /conversations/direct (Get or create direct conversation)
+func (s *ChatService) GetOrCreateDirect(ctx context.Context, body *DirectConversationRequest, opts ...RequestOption) (*DirectConversationResponse, error) {
+	out := new(DirectConversationResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/conversations/direct", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListMembersParams are the query parameters of ChatService.ListMembers
+type ListMembersParams struct {
+	// Cursor: user ID of the last member of the previous page
+	After string
+	// Only members whose name, display name or handle contains this
+	Q string
+	// Number of members to return (default: 50, max: 200)
+	Limit int
+}
+
+func (p *ListMembersParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	if p.After != "" {
+		q.Set("after", p.After)
+	}
+	if p.Q != "" {
+		q.Set("q", p.Q)
+	}
+	if p.Limit != 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	return q
+}
+
+// ListMembers calls GET /conversations/{id}/members (List the members of a conversation)
+func (s *ChatService) ListMembers(ctx context.Context, id string, params *ListMembersParams, opts ...RequestOption) ([]ConversationMember, error) {
+	var out []ConversationMember
+	if err := s.client.call(ctx, http.MethodGet, "/conversations/"+url.PathEscape(id)+"/members", params.values(), nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListPins calls GET /conversations/{id}/pins (List a conversation's pinned messages)
+func (s *ChatService) ListPins(ctx context.Context, id string, opts ...RequestOption) ([]PinnedMessage, error) {
+	var out []PinnedMessage
+	if err := s.client.call(ctx, http.MethodGet, "/conversations/"+url.PathEscape(id)+"/pins", nil, nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListRoles calls GET /conversations/{id}/roles (List a group's roles and their permissions)
+func (s *ChatService) ListRoles(ctx context.Context, id string, opts ...RequestOption) ([]ConversationRole, error) {
+	var out []ConversationRole
+	if err := s.client.call(ctx, http.MethodGet, "/conversations/"+url.PathEscape(id)+"/roles", nil, nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MarkAllAsRead calls POST /conversations/read-all (Mark several or all conversations as read)
+func (s *ChatService) MarkAllAsRead(ctx context.Context, body *MarkReadRequest, opts ...RequestOption) (*MarkReadResponse, error) {
+	out := new(MarkReadResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/conversations/read-all", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MarkAsRead calls POST /conversations/{id}/read (Mark all messages in a conversation as read)
+func (s *ChatService) MarkAsRead(ctx context.Context, id string, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/conversations/"+url.PathEscape(id)+"/read", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PinMessage calls POST /conversations/{id}/pins (Pin a message)
+func (s *ChatService) PinMessage(ctx context.Context, id string, body *PinMessageRequest, opts ...RequestOption) (*PinnedMessage, error) {
+	out := new(PinnedMessage)
+	if err := s.client.call(ctx, http.MethodPost, "/conversations/"+url.PathEscape(id)+"/pins", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReorderPins calls PUT /conversations/{id}/pins/order (Reorder a conversation's pins)
+func (s *ChatService) ReorderPins(ctx context.Context, id string, body *ReorderPinsRequest, opts ...RequestOption) ([]PinnedMessage, error) {
+	var out []PinnedMessage
+	if err := s.client.call(ctx, http.MethodPut, "/conversations/"+url.PathEscape(id)+"/pins/order", nil, body, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RestoreConversation calls POST /conversations/{id}/restore (Restore a deleted conversation)
+func (s *ChatService) RestoreConversation(ctx context.Context, id string, opts ...RequestOption) (*Conversation, error) {
+	out := new(Conversation)
+	if err := s.client.call(ctx, http.MethodPost, "/conversations/"+url.PathEscape(id)+"/restore", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SaveRole calls PUT /conversations/{id}/roles/{role} (Create a custom role or change a role's permissions)
+func (s *ChatService) SaveRole(ctx context.Context, id string, role string, body *SaveRoleRequest, opts ...RequestOption) (*ConversationRole, error) {
+	out := new(ConversationRole)
+	if err := s.client.call(ctx, http.MethodPut, "/conversations/"+url.PathEscape(id)+"/roles/"+url.PathEscape(role), nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SendMessage calls POST /conversations/{id}/messages (Send a message to a conversation). It takes the Idempotency-Key header, see WithHeader
+func (s *ChatService) SendMessage(ctx context.Context, id string, body *SendMessageRequest, opts ...RequestOption) (*Message, error) {
+	out := new(Message)
+	if err := s.client.call(ctx, http.MethodPost, "/conversations/"+url.PathEscape(id)+"/messages", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetMemberRole calls PUT /conversations/{id}/members/{user_id}/role (Give a member of a group another role)
+func (s *ChatService) SetMemberRole(ctx context.Context, id string, userID string, body *SetMemberRoleRequest, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodPut, "/conversations/"+url.PathEscape(id)+"/members/"+url.PathEscape(userID)+"/role", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UnfreezeConversation calls DELETE /conversations/{id}/freeze (Let every member of a frozen group post again)
+func (s *ChatService) UnfreezeConversation(ctx context.Context, id string, opts ...RequestOption) (*Conversation, error) {
+	out := new(Conversation)
+	if err := s.client.call(ctx, http.MethodDelete, "/conversations/"+url.PathEscape(id)+"/freeze", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UnpinMessage calls DELETE /conversations/{id}/pins/{message_id} (Unpin a message)
+func (s *ChatService) UnpinMessage(ctx context.Context, id string, messageID string, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodDelete, "/conversations/"+url.PathEscape(id)+"/pins/"+url.PathEscape(messageID), nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ComplianceService calls the endpoints of the server's ComplianceHandler
+type ComplianceService struct {
+	client *Client
+}
+
+// DeleteConversationRetention calls DELETE /admin/conversations/{id}/retention (Remove a conversation's message retention)
+func (s *ComplianceService) DeleteConversationRetention(ctx context.Context, id string, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodDelete, "/admin/conversations/"+url.PathEscape(id)+"/retention", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteRetention calls DELETE /admin/retention (Remove the deployment's message retention)
+func (s *ComplianceService) DeleteRetention(ctx context.Context, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodDelete, "/admin/retention", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ExportMessagesParams are the query parameters of ComplianceService.ExportMessages
+type ExportMessagesParams struct {
+	// Messages sent by or to this user
+	UserID string
+	// Messages in this conversation
+	ConversationID string
+	// Sent at or after (RFC 3339)
+	From string
+	// Sent before (RFC 3339)
+	To string
+}
+
+func (p *ExportMessagesParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	if p.UserID != "" {
+		q.Set("user_id", p.UserID)
+	}
+	if p.ConversationID != "" {
+		q.Set("conversation_id", p.ConversationID)
+	}
+	if p.From != "" {
+		q.Set("from", p.From)
+	}
+	if p.To != "" {
+		q.Set("to", p.To)
+	}
+	return q
+}
+
+// ExportMessages calls GET /admin/compliance/export (Export messages for compliance review). The caller closes the application/x-ndjson body
+func (s *ComplianceService) ExportMessages(ctx context.Context, params *ExportMessagesParams, opts ...RequestOption) (io.ReadCloser, error) {
+	return s.client.open(ctx, http.MethodGet, "/admin/compliance/export", params.values(), nil, opts)
+}
+
+// GetRetention calls GET /admin/retention (List the message retention policies)
+func (s *ComplianceService) GetRetention(ctx context.Context, opts ...RequestOption) (*RetentionPoliciesResponse, error) {
+	out := new(RetentionPoliciesResponse)
+	if err := s.client.call(ctx, http.MethodGet, "/admin/retention", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListAuditEventsParams are the query parameters of ComplianceService.ListAuditEvents
+type ListAuditEventsParams struct {
+	// Only this action, e.g. retention.purge
+	Action string
+	// Only events about this conversation
+	ConversationID string
+	// Cursor: RFC 3339 timestamp
+	Before string
+	// Number of events to return (default: 50, max: 200)
+	Limit int
+}
+
+func (p *ListAuditEventsParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	if p.Action != "" {
+		q.Set("action", p.Action)
+	}
+	if p.ConversationID != "" {
+		q.Set("conversation_id", p.ConversationID)
+	}
+	if p.Before != "" {
+		q.Set("before", p.Before)
+	}
+	if p.Limit != 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	return q
+}
+
+// ListAuditEvents calls GET /admin/audit (List the audit log)
+func (s *ComplianceService) ListAuditEvents(ctx context.Context, params *ListAuditEventsParams, opts ...RequestOption) ([]AuditEvent, error) {
+	var out []AuditEvent
+	if err := s.client.call(ctx, http.MethodGet, "/admin/audit", params.values(), nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListLegalHolds calls GET /admin/legal-holds (List the legal holds)
+func (s *ComplianceService) ListLegalHolds(ctx context.Context, opts ...RequestOption) ([]LegalHold, error) {
+	var out []LegalHold
+	if err := s.client.call(ctx, http.MethodGet, "/admin/legal-holds", nil, nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PlaceLegalHold calls POST /admin/legal-holds (Place a legal hold)
+func (s *ComplianceService) PlaceLegalHold(ctx context.Context, body *CreateLegalHoldRequest, opts ...RequestOption) (*LegalHold, error) {
+	out := new(LegalHold)
+	if err := s.client.call(ctx, http.MethodPost, "/admin/legal-holds", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReleaseLegalHold calls DELETE /admin/legal-holds/{id} (Release a legal hold)
+func (s *ComplianceService) ReleaseLegalHold(ctx context.Context, id string, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodDelete, "/admin/legal-holds/"+url.PathEscape(id), nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetConversationRetention calls PUT /admin/conversations/{id}/retention (Set a conversation's message retention)
+func (s *ComplianceService) SetConversationRetention(ctx context.Context, id string, body *SetRetentionPolicyRequest, opts ...RequestOption) (*RetentionPolicy, error) {
+	out := new(RetentionPolicy)
+	if err := s.client.call(ctx, http.MethodPut, "/admin/conversations/"+url.PathEscape(id)+"/retention", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetRetention calls PUT /admin/retention (Set the deployment's message retention)
+func (s *ComplianceService) SetRetention(ctx context.Context, body *SetRetentionPolicyRequest, opts ...RequestOption) (*RetentionPolicy, error) {
+	out := new(RetentionPolicy)
+	if err := s.client.call(ctx, http.MethodPut, "/admin/retention", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ImageService calls the endpoints of the server's ImageHandler
+type ImageService struct {
+	client *Client
+}
+
+// GetImageParams are the query parameters of ImageService.GetImage
+type GetImageParams struct {
+	// Target width (max 2048)
+	W int
+	// Target height (max 2048)
+	H int
+	// Fit mode
+	Fit string
+}
+
+func (p *GetImageParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	if p.W != 0 {
+		q.Set("w", strconv.Itoa(p.W))
+	}
+	if p.H != 0 {
+		q.Set("h", strconv.Itoa(p.H))
+	}
+	if p.Fit != "" {
+		q.Set("fit", p.Fit)
+	}
+	return q
+}
+
+// GetImage calls GET /images/{key} (Get a resized image). The caller closes the image/jpeg body
+func (s *ImageService) GetImage(ctx context.Context, key string, params *GetImageParams, opts ...RequestOption) (io.ReadCloser, error) {
+	return s.client.open(ctx, http.MethodGet, "/images/"+url.PathEscape(key), params.values(), nil, opts)
+}
+
+// ImportService calls the endpoints of the server's ImportHandler
+type ImportService struct {
+	client *Client
+}
+
+// CreateImportForm is the form sent by ImportService.CreateImport
+type CreateImportForm struct {
+	// Export archive (.zip)
+	File *File
+	// App the chat was exported from
+	Source string
+	// IANA time zone of the exporting phone, for WhatsApp timestamps (default UTC)
+	Timezone string
+}
+
+func (f *CreateImportForm) encode() *form {
+	form := newForm(true)
+	if f == nil {
+		return form
+	}
+	form.file("file", f.File)
+	if f.Source != "" {
+		form.values.Set("source", f.Source)
+	}
+	if f.Timezone != "" {
+		form.values.Set("timezone", f.Timezone)
+	}
+	return form
+}
+
+// CreateImport calls POST /imports (Import a chat from another app)
+func (s *ImportService) CreateImport(ctx context.Context, form *CreateImportForm, opts ...RequestOption) (*ChatImport, error) {
+	out := new(ChatImport)
+	if err := s.client.call(ctx, http.MethodPost, "/imports", nil, form.encode(), out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetImport calls GET /imports/{id} (Get a chat import)
+func (s *ImportService) GetImport(ctx context.Context, id string, opts ...RequestOption) (*ChatImport, error) {
+	out := new(ChatImport)
+	if err := s.client.call(ctx, http.MethodGet, "/imports/"+url.PathEscape(id), nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MatrixService calls the endpoints of the server's MatrixHandler
+type MatrixService struct {
+	client *Client
+}
+
+// LinkRoom calls POST /admin/matrix/links (Bridge a group conversation to a Matrix room)
+func (s *MatrixService) LinkRoom(ctx context.Context, body *MatrixLinkRequest, opts ...RequestOption) (*MatrixRoomLink, error) {
+	out := new(MatrixRoomLink)
+	if err := s.client.call(ctx, http.MethodPost, "/admin/matrix/links", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListLinks calls GET /admin/matrix/links (List conversations bridged to Matrix)
+func (s *MatrixService) ListLinks(ctx context.Context, opts ...RequestOption) ([]MatrixRoomLink, error) {
+	var out []MatrixRoomLink
+	if err := s.client.call(ctx, http.MethodGet, "/admin/matrix/links", nil, nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UnlinkRoom calls DELETE /admin/matrix/links/{conversation_id} (Stop bridging a conversation to Matrix)
+func (s *MatrixService) UnlinkRoom(ctx context.Context, conversationID string, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodDelete, "/admin/matrix/links/"+url.PathEscape(conversationID), nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// NotificationService calls the endpoints of the server's NotificationHandler
+type NotificationService struct {
+	client *Client
+}
+
+// GetNotificationsParams are the query parameters of NotificationService.GetNotifications
+type GetNotificationsParams struct {
+	// Cursor: RFC 3339 timestamp
+	Before string
+	// Only unread notifications
+	Unread bool
+	// Number of notifications to return (default: 30, max: 100)
+	Limit int
+}
+
+func (p *GetNotificationsParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	if p.Before != "" {
+		q.Set("before", p.Before)
+	}
+	if p.Unread {
+		q.Set("unread", "true")
+	}
+	if p.Limit != 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	return q
+}
+
+// GetNotifications calls GET /notifications (List notifications)
+func (s *NotificationService) GetNotifications(ctx context.Context, params *GetNotificationsParams, opts ...RequestOption) (*NotificationListResponse, error) {
+	out := new(NotificationListResponse)
+	if err := s.client.call(ctx, http.MethodGet, "/notifications", params.values(), nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MarkAllAsRead calls POST /notifications/read-all (Mark all notifications as read)
+func (s *NotificationService) MarkAllAsRead(ctx context.Context, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/notifications/read-all", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MarkAsRead calls POST /notifications/{id}/read (Mark a notification as read)
+func (s *NotificationService) MarkAsRead(ctx context.Context, id string, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/notifications/"+url.PathEscape(id)+"/read", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OAuthService calls the endpoints of the server's OAuthHandler
+type OAuthService struct {
+	client *Client
+}
+
+// AuthorizeParams are the query parameters of OAuthService.Authorize
+type AuthorizeParams struct {
+	// Must be code
+	ResponseType string
+	// Client ID
+	ClientID string
+	// One of the app's redirect URIs; optional when it registered only one
+	RedirectURI string
+	// Space-separated scopes
+	Scope string
+	// Returned to the app unchanged
+	State string
+	// PKCE code challenge; required for public apps
+	CodeChallenge string
+	// Must be S256
+	CodeChallengeMethod string
+	// Echoed in the ID token
+	Nonce string
+}
+
+func (p *AuthorizeParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	if p.ResponseType != "" {
+		q.Set("response_type", p.ResponseType)
+	}
+	if p.ClientID != "" {
+		q.Set("client_id", p.ClientID)
+	}
+	if p.RedirectURI != "" {
+		q.Set("redirect_uri", p.RedirectURI)
+	}
+	if p.Scope != "" {
+		q.Set("scope", p.Scope)
+	}
+	if p.State != "" {
+		q.Set("state", p.State)
+	}
+	if p.CodeChallenge != "" {
+		q.Set("code_challenge", p.CodeChallenge)
+	}
+	if p.CodeChallengeMethod != "" {
+		q.Set("code_challenge_method", p.CodeChallengeMethod)
+	}
+	if p.Nonce != "" {
+		q.Set("nonce", p.Nonce)
+	}
+	return q
+}
+
+// Authorize calls POST /oauth/authorize (Approve or deny an authorization request)
+func (s *OAuthService) Authorize(ctx context.Context, params *AuthorizeParams, body *OAuthDecisionRequest, opts ...RequestOption) (*OAuthRedirect, error) {
+	out := new(OAuthRedirect)
+	if err := s.client.call(ctx, http.MethodPost, "/oauth/authorize", params.values(), body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteClient calls DELETE /oauth/clients/{client_id} (Delete one of my OAuth apps)
+func (s *OAuthService) DeleteClient(ctx context.Context, clientID string, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodDelete, "/oauth/clients/"+url.PathEscape(clientID), nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DisconnectApp calls DELETE /auth/apps/{client_id} (Take an app's access to my account away)
+func (s *OAuthService) DisconnectApp(ctx context.Context, clientID string, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodDelete, "/auth/apps/"+url.PathEscape(clientID), nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetAuthorizationParams are the query parameters of OAuthService.GetAuthorization
+type GetAuthorizationParams struct {
+	// Must be code
+	ResponseType string
+	// Client ID
+	ClientID string
+	// One of the app's redirect URIs; optional when it registered only one
+	RedirectURI string
+	// Space-separated scopes, e.g. openid read:messages
+	Scope string
+	// Returned to the app unchanged
+	State string
+	// PKCE code challenge; required for public apps
+	CodeChallenge string
+	// Must be S256
+	CodeChallengeMethod string
+	// Echoed in the ID token
+	Nonce string
+}
+
+func (p *GetAuthorizationParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	if p.ResponseType != "" {
+		q.Set("response_type", p.ResponseType)
+	}
+	if p.ClientID != "" {
+		q.Set("client_id", p.ClientID)
+	}
+	if p.RedirectURI != "" {
+		q.Set("redirect_uri", p.RedirectURI)
+	}
+	if p.Scope != "" {
+		q.Set("scope", p.Scope)
+	}
+	if p.State != "" {
+		q.Set("state", p.State)
+	}
+	if p.CodeChallenge != "" {
+		q.Set("code_challenge", p.CodeChallenge)
+	}
+	if p.CodeChallengeMethod != "" {
+		q.Set("code_challenge_method", p.CodeChallengeMethod)
+	}
+	if p.Nonce != "" {
+		q.Set("nonce", p.Nonce)
+	}
+	return q
+}
+
+// GetAuthorization calls GET /oauth/authorize (Check an authorization request)
+func (s *OAuthService) GetAuthorization(ctx context.Context, params *GetAuthorizationParams, opts ...RequestOption) (*OAuthConsent, error) {
+	out := new(OAuthConsent)
+	if err := s.client.call(ctx, http.MethodGet, "/oauth/authorize", params.values(), nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// JWKS calls GET /oauth/jwks (Get the ID token signing keys)
+func (s *OAuthService) JWKS(ctx context.Context, opts ...RequestOption) (*JSONWebKeySet, error) {
+	out := new(JSONWebKeySet)
+	if err := s.client.call(ctx, http.MethodGet, "/oauth/jwks", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListApps calls GET /auth/apps (List apps with access to my account)
+func (s *OAuthService) ListApps(ctx context.Context, opts ...RequestOption) ([]ConnectedApp, error) {
+	var out []ConnectedApp
+	if err := s.client.call(ctx, http.MethodGet, "/auth/apps", nil, nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListClients calls GET /oauth/clients (List my OAuth apps)
+func (s *OAuthService) ListClients(ctx context.Context, opts ...RequestOption) ([]OAuthClientResponse, error) {
+	var out []OAuthClientResponse
+	if err := s.client.call(ctx, http.MethodGet, "/oauth/clients", nil, nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RegisterClient calls POST /oauth/clients (Register an OAuth app)
+func (s *OAuthService) RegisterClient(ctx context.Context, body *CreateOAuthClientRequest, opts ...RequestOption) (*OAuthClientResponse, error) {
+	out := new(OAuthClientResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/oauth/clients", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RevokeForm is the form sent by OAuthService.Revoke
+type RevokeForm struct {
+	// Client ID, unless sent with HTTP Basic
+	ClientID string
+	// Client secret of confidential apps, unless sent with HTTP Basic
+	ClientSecret string
+	// Access or refresh token
+	Token string
+	// access_token or refresh_token
+	TokenTypeHint string
+}
+
+func (f *RevokeForm) encode() *form {
+	form := newForm(false)
+	if f == nil {
+		return form
+	}
+	if f.ClientID != "" {
+		form.values.Set("client_id", f.ClientID)
+	}
+	if f.ClientSecret != "" {
+		form.values.Set("client_secret", f.ClientSecret)
+	}
+	if f.Token != "" {
+		form.values.Set("token", f.Token)
+	}
+	if f.TokenTypeHint != "" {
+		form.values.Set("token_type_hint", f.TokenTypeHint)
+	}
+	return form
+}
+
+// Revoke calls POST /oauth/revoke (Revoke an OAuth token)
+func (s *OAuthService) Revoke(ctx context.Context, form *RevokeForm, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/oauth/revoke", nil, form.encode(), out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TokenForm is the form sent by OAuthService.Token
+type TokenForm struct {
+	// Client ID, unless sent with HTTP Basic
+	ClientID string
+	// Client secret of confidential apps, unless sent with HTTP Basic
+	ClientSecret string
+	// Authorization code
+	Code string
+	// PKCE code verifier
+	CodeVerifier string
+	// authorization_code or refresh_token
+	GrantType string
+	// Required when the authorization request had one
+	RedirectURI string
+	// Refresh token
+	RefreshToken string
+	// Narrower scopes for a refresh
+	Scope string
+}
+
+func (f *TokenForm) encode() *form {
+	form := newForm(false)
+	if f == nil {
+		return form
+	}
+	if f.ClientID != "" {
+		form.values.Set("client_id", f.ClientID)
+	}
+	if f.ClientSecret != "" {
+		form.values.Set("client_secret", f.ClientSecret)
+	}
+	if f.Code != "" {
+		form.values.Set("code", f.Code)
+	}
+	if f.CodeVerifier != "" {
+		form.values.Set("code_verifier", f.CodeVerifier)
+	}
+	if f.GrantType != "" {
+		form.values.Set("grant_type", f.GrantType)
+	}
+	if f.RedirectURI != "" {
+		form.values.Set("redirect_uri", f.RedirectURI)
+	}
+	if f.RefreshToken != "" {
+		form.values.Set("refresh_token", f.RefreshToken)
+	}
+	if f.Scope != "" {
+		form.values.Set("scope", f.Scope)
+	}
+	return form
+}
+
+// Token calls POST /oauth/token (Get OAuth tokens)
+func (s *OAuthService) Token(ctx context.Context, form *TokenForm, opts ...RequestOption) (*OAuthTokenResponse, error) {
+	out := new(OAuthTokenResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/oauth/token", nil, form.encode(), out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UserInfo calls GET /oauth/userinfo (Get the signed-in user's OpenID claims)
+func (s *OAuthService) UserInfo(ctx context.Context, opts ...RequestOption) (*UserInfo, error) {
+	out := new(UserInfo)
+	if err := s.client.call(ctx, http.MethodGet, "/oauth/userinfo", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OnboardingService calls the endpoints of the server's OnboardingHandler
+type OnboardingService struct {
+	client *Client
+}
+
+// CreateRule calls POST /conversations/{id}/onboarding-rules (Add an onboarding rule to a group)
+func (s *OnboardingService) CreateRule(ctx context.Context, id string, body *OnboardingRuleRequest, opts ...RequestOption) (*OnboardingRule, error) {
+	out := new(OnboardingRule)
+	if err := s.client.call(ctx, http.MethodPost, "/conversations/"+url.PathEscape(id)+"/onboarding-rules", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteRule calls DELETE /conversations/{id}/onboarding-rules/{rule_id} (Delete an onboarding rule)
+func (s *OnboardingService) DeleteRule(ctx context.Context, id string, ruleID string, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodDelete, "/conversations/"+url.PathEscape(id)+"/onboarding-rules/"+url.PathEscape(ruleID), nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListRules calls GET /conversations/{id}/onboarding-rules (List a group's onboarding rules)
+func (s *OnboardingService) ListRules(ctx context.Context, id string, opts ...RequestOption) ([]OnboardingRule, error) {
+	var out []OnboardingRule
+	if err := s.client.call(ctx, http.MethodGet, "/conversations/"+url.PathEscape(id)+"/onboarding-rules", nil, nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ProfileService calls the endpoints of the server's ProfileHandler
+type ProfileService struct {
+	client *Client
+}
+
+// ClearAutoReply calls DELETE /auth/auto-reply (Turn the current user's auto-reply off)
+func (s *ProfileService) ClearAutoReply(ctx context.Context, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodDelete, "/auth/auto-reply", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ClearStatus calls DELETE /auth/status (Clear the current user's custom status)
+func (s *ProfileService) ClearStatus(ctx context.Context, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodDelete, "/auth/status", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetAutoReply calls GET /auth/auto-reply (Get the current user's auto-reply)
+func (s *ProfileService) GetAutoReply(ctx context.Context, opts ...RequestOption) (*AutoReply, error) {
+	out := new(AutoReply)
+	if err := s.client.call(ctx, http.MethodGet, "/auth/auto-reply", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetPresence calls GET /auth/presence (Get the current user's presence)
+func (s *ProfileService) GetPresence(ctx context.Context, opts ...RequestOption) (*PresenceResponse, error) {
+	out := new(PresenceResponse)
+	if err := s.client.call(ctx, http.MethodGet, "/auth/presence", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetProfile calls GET /users/{id}/profile (Get a user's profile page)
+func (s *ProfileService) GetProfile(ctx context.Context, id string, opts ...RequestOption) (*UserProfileResponse, error) {
+	out := new(UserProfileResponse)
+	if err := s.client.call(ctx, http.MethodGet, "/users/"+url.PathEscape(id)+"/profile", nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetAutoReply calls PUT /auth/auto-reply (Set the current user's auto-reply (away message))
+func (s *ProfileService) SetAutoReply(ctx context.Context, body *SetAutoReplyRequest, opts ...RequestOption) (*AutoReply, error) {
+	out := new(AutoReply)
+	if err := s.client.call(ctx, http.MethodPut, "/auth/auto-reply", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetPresence calls PUT /auth/presence (Set the current user's presence)
+func (s *ProfileService) SetPresence(ctx context.Context, body *SetPresenceRequest, opts ...RequestOption) (*PresenceResponse, error) {
+	out := new(PresenceResponse)
+	if err := s.client.call(ctx, http.MethodPut, "/auth/presence", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateStatus calls PUT /auth/status (Set the current user's custom status)
+func (s *ProfileService) UpdateStatus(ctx context.Context, body *UpdateStatusRequest, opts ...RequestOption) (*UserStatus, error) {
+	out := new(UserStatus)
+	if err := s.client.call(ctx, http.MethodPut, "/auth/status", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SSOService calls the endpoints of the server's SSOHandler
+type SSOService struct {
+	client *Client
+}
+
+// CallbackParams are the query parameters of SSOService.Callback
+type CallbackParams struct {
+	// Authorization code from the identity provider
+	Code string
+	// State from the login redirect
+	State string
+}
+
+func (p *CallbackParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	if p.Code != "" {
+		q.Set("code", p.Code)
+	}
+	if p.State != "" {
+		q.Set("state", p.State)
+	}
+	return q
+}
+
+// Callback calls GET /auth/sso/callback (Finish single sign-on)
+func (s *SSOService) Callback(ctx context.Context, params *CallbackParams, opts ...RequestOption) (*SSOCodeResponse, error) {
+	out := new(SSOCodeResponse)
+	if err := s.client.call(ctx, http.MethodGet, "/auth/sso/callback", params.values(), nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Token calls POST /auth/sso/token (Exchange a single sign-on code for a token)
+func (s *SSOService) Token(ctx context.Context, body *SSOTokenRequest, opts ...RequestOption) (*LoginResponse, error) {
+	out := new(LoginResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/auth/sso/token", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SearchService calls the endpoints of the server's SearchHandler
+type SearchService struct {
+	client *Client
+}
+
+// SearchParams are the query parameters of SearchService.Search
+type SearchParams struct {
+	// Search query (2-200 characters)
+	Q string
+	// Comma-separated: message, conversation, user, file (default: all)
+	Types string
+	// Only messages and files of this conversation
+	ConversationID string
+	// Number of results to return (default: 20, max: 50)
+	Limit int
+}
+
+func (p *SearchParams) values() url.Values {
+	if p == nil {
+		return nil
+	}
+	q := url.Values{}
+	if p.Q != "" {
+		q.Set("q", p.Q)
+	}
+	if p.Types != "" {
+		q.Set("types", p.Types)
+	}
+	if p.ConversationID != "" {
+		q.Set("conversation_id", p.ConversationID)
+	}
+	if p.Limit != 0 {
+		q.Set("limit", strconv.Itoa(p.Limit))
+	}
+	return q
+}
+
+// Search calls GET /search (Search messages, groups, people and files)
+func (s *SearchService) Search(ctx context.Context, params *SearchParams, opts ...RequestOption) (*SearchResponse, error) {
+	out := new(SearchResponse)
+	if err := s.client.call(ctx, http.MethodGet, "/search", params.values(), nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TemplateService calls the endpoints of the server's TemplateHandler
+type TemplateService struct {
+	client *Client
+}
+
+// CreateFromTemplate calls POST /conversations/from-template (Create a group from a template)
+func (s *TemplateService) CreateFromTemplate(ctx context.Context, body *CreateFromTemplateRequest, opts ...RequestOption) (*Conversation, error) {
+	out := new(Conversation)
+	if err := s.client.call(ctx, http.MethodPost, "/conversations/from-template", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateTemplate calls POST /admin/conversation-templates (Create a conversation template)
+func (s *TemplateService) CreateTemplate(ctx context.Context, body *ConversationTemplateRequest, opts ...RequestOption) (*ConversationTemplate, error) {
+	out := new(ConversationTemplate)
+	if err := s.client.call(ctx, http.MethodPost, "/admin/conversation-templates", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteTemplate calls DELETE /admin/conversation-templates/{id} (Delete a conversation template)
+func (s *TemplateService) DeleteTemplate(ctx context.Context, id string, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodDelete, "/admin/conversation-templates/"+url.PathEscape(id), nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListTemplates calls GET /conversation-templates (List conversation templates)
+func (s *TemplateService) ListTemplates(ctx context.Context, opts ...RequestOption) ([]ConversationTemplate, error) {
+	var out []ConversationTemplate
+	if err := s.client.call(ctx, http.MethodGet, "/conversation-templates", nil, nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateTemplate calls PUT /admin/conversation-templates/{id} (Replace a conversation template)
+func (s *TemplateService) UpdateTemplate(ctx context.Context, id string, body *ConversationTemplateRequest, opts ...RequestOption) (*ConversationTemplate, error) {
+	out := new(ConversationTemplate)
+	if err := s.client.call(ctx, http.MethodPut, "/admin/conversation-templates/"+url.PathEscape(id), nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TokenService calls the endpoints of the server's TokenHandler
+type TokenService struct {
+	client *Client
+}
+
+// CreateToken calls POST /auth/tokens (Create a personal access token)
+func (s *TokenService) CreateToken(ctx context.Context, body *CreatePersonalTokenRequest, opts ...RequestOption) (*CreatePersonalTokenResponse, error) {
+	out := new(CreatePersonalTokenResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/auth/tokens", nil, body, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListTokens calls GET /auth/tokens (List my personal access tokens)
+func (s *TokenService) ListTokens(ctx context.Context, opts ...RequestOption) ([]PersonalAccessToken, error) {
+	var out []PersonalAccessToken
+	if err := s.client.call(ctx, http.MethodGet, "/auth/tokens", nil, nil, &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RevokeToken calls DELETE /auth/tokens/{id} (Revoke a personal access token)
+func (s *TokenService) RevokeToken(ctx context.Context, id string, opts ...RequestOption) (*SuccessResponse, error) {
+	out := new(SuccessResponse)
+	if err := s.client.call(ctx, http.MethodDelete, "/auth/tokens/"+url.PathEscape(id), nil, nil, out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UploadService calls the endpoints of the server's UploadHandler
+type UploadService struct {
+	client *Client
+}
+
+// UploadFileForm is the form sent by UploadService.UploadFile
+type UploadFileForm struct {
+	// File to upload
+	File *File
+	// File type hint: image, video, file
+	Type string
+}
+
+func (f *UploadFileForm) encode() *form {
+	form := newForm(true)
+	if f == nil {
+		return form
+	}
+	form.file("file", f.File)
+	if f.Type != "" {
+		form.values.Set("type", f.Type)
+	}
+	return form
+}
+
+// UploadFile calls POST /upload (Upload a file (image, video, or document)). It takes the Idempotency-Key header, see WithHeader
+func (s *UploadService) UploadFile(ctx context.Context, form *UploadFileForm, opts ...RequestOption) (*UploadResponse, error) {
+	out := new(UploadResponse)
+	if err := s.client.call(ctx, http.MethodPost, "/upload", nil, form.encode(), out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UploadMultipleForm is the form sent by UploadService.UploadMultiple
+type UploadMultipleForm struct {
+	// Files to upload (max 10)
+	Files []*File
+}
+
+func (f *UploadMultipleForm) encode() *form {
+	form := newForm(true)
+	if f == nil {
+		return form
+	}
+	for _, file := range f.Files {
+		form.file("files", file)
+	}
+	return form
+}
+
+// UploadMultiple calls POST /upload/multiple (Upload multiple files). It takes the Idempotency-Key header, see WithHeader
+func (s *UploadService) UploadMultiple(ctx context.Context, form *UploadMultipleForm, opts ...RequestOption) ([]UploadResponse, error) {
+	var out []UploadResponse
+	if err := s.client.call(ctx, http.MethodPost, "/upload/multiple", nil, form.encode(), &out, opts); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,214 @@
+// Package client is a typed Go client for the GoTalk REST and WebSocket API,
+// for bots and integrations. The types, services and event constants are
+// generated by cmd/genapi from the server's routes and DTOs, so they can't
+// drift from the API; this file and ws.go are the hand-written transport.
+//
+//	c := client.New(client.Config{URL: "https://chat.example.com", Token: token})
+//	msg, err := c.Chat.SendMessage(ctx, conversationID, &client.SendMessageRequest{Content: "Hi"})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Config says where the server is and who calls it
+type Config struct {
+	URL        string       // server root, e.g. https://chat.example.com; BasePath is added
+	Token      string       // access token or personal access token; empty for none
+	HTTPClient *http.Client // nil for a default with a 30s timeout
+}
+
+// Client calls the API. Its services (Chat, Auth, ...) hold one method per
+// endpoint.
+type Client struct {
+	services
+	cfg    Config
+	client *http.Client
+}
+
+// New creates a client
+func New(cfg Config) *Client {
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+	c := &Client{cfg: cfg, client: cfg.HTTPClient}
+	if c.client == nil {
+		c.client = &http.Client{Timeout: 30 * time.Second}
+	}
+	c.services.init(c)
+	return c
+}
+
+// Error is an error response from the server
+type Error struct {
+	Status  int
+	Code    string          // stable, for programs, e.g. not_found (see docs/errors.md)
+	Message string          // for humans
+	Details json.RawMessage // e.g. the failing fields; null when none
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("gotalk: %d %s: %s", e.Status, e.Code, e.Message)
+}
+
+// RequestOption changes one request, e.g. to add a header
+type RequestOption func(*http.Request)
+
+// WithHeader sets a request header, e.g. Idempotency-Key or If-None-Match
+func WithHeader(key, value string) RequestOption {
+	return func(req *http.Request) {
+		req.Header.Set(key, value)
+	}
+}
+
+// File is a file to upload
+type File struct {
+	Name string // file name, e.g. photo.jpg
+	Body io.Reader
+}
+
+// form is a request body of form fields and files
+type form struct {
+	multipart bool
+	values    url.Values
+	files     []formFile
+}
+
+type formFile struct {
+	field string
+	file  *File
+}
+
+func newForm(multipart bool) *form {
+	return &form{multipart: multipart, values: url.Values{}}
+}
+
+func (f *form) file(field string, file *File) {
+	if file != nil {
+		f.files = append(f.files, formFile{field, file})
+	}
+}
+
+// encode returns the form's body and content type
+func (f *form) encode() (io.Reader, string, error) {
+	if !f.multipart {
+		return strings.NewReader(f.values.Encode()), "application/x-www-form-urlencoded", nil
+	}
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for key, values := range f.values {
+		for _, value := range values {
+			if err := w.WriteField(key, value); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	for _, ff := range f.files {
+		part, err := w.CreateFormFile(ff.field, ff.file.Name)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := io.Copy(part, ff.file.Body); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return &body, w.FormDataContentType(), nil
+}
+
+// call sends a request and decodes the JSON response into out, unless nil
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, body interface{}, out interface{}, opts []RequestOption) error {
+	respBody, err := c.open(ctx, method, path, query, body, opts)
+	if err != nil {
+		return err
+	}
+	defer respBody.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(respBody).Decode(out); err != nil {
+		return fmt.Errorf("gotalk: %s %s: decoding response: %w", method, path, err)
+	}
+	return nil
+}
+
+// open sends a request and returns the body of a successful response, which
+// the caller closes. body is a JSON value, a form or nil.
+func (c *Client) open(ctx context.Context, method, path string, query url.Values, body interface{}, opts []RequestOption) (io.ReadCloser, error) {
+	var reader io.Reader
+	var contentType string
+	switch b := body.(type) {
+	case *form:
+		var err error
+		if reader, contentType, err = b.encode(); err != nil {
+			return nil, err
+		}
+	default:
+		if v := reflect.ValueOf(body); body != nil && !(v.Kind() == reflect.Pointer && v.IsNil()) {
+			data, err := json.Marshal(body)
+			if err != nil {
+				return nil, err
+			}
+			reader, contentType = bytes.NewReader(data), "application/json"
+		}
+	}
+
+	target := c.cfg.URL + BasePath + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.Token)
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("gotalk: %s %s: %w", method, path, err)
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp.Body, nil
+}
+
+// responseError reads an error response
+func responseError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	var body struct {
+		Code    string          `json:"code"`
+		Error   string          `json:"error"`
+		Message string          `json:"message"`
+		Details json.RawMessage `json:"details"`
+	}
+	apiErr := &Error{Status: resp.StatusCode}
+	if json.Unmarshal(data, &body) == nil && body.Code != "" {
+		apiErr.Code, apiErr.Message, apiErr.Details = body.Code, body.Message, body.Details
+		if apiErr.Message == "" {
+			apiErr.Message = body.Error
+		}
+	} else {
+		apiErr.Code, apiErr.Message = "unknown", strings.TrimSpace(string(data))
+	}
+	return apiErr
+}
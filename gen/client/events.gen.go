@@ -0,0 +1,93 @@
+// Code generated by cmd/genapi from the API's routes and DTOs. DO NOT EDIT.
+
+package client
+
+// WebSocket event types
+const (
+	EventAttachmentProcessed  = "attachment_processed"  // payload: MessageAttachment
+	EventBootstrap            = "bootstrap"             // payload: BootstrapEvent
+	EventCallAnswer           = "call_answer"           // payload: CallAnswerEvent
+	EventCallChat             = "call_chat"             // payload: CallChatEvent
+	EventCallHangup           = "call_hangup"           // payload: CallHangupEvent
+	EventCallICE              = "call_ice_candidate"    // payload: ICECandidateEvent
+	EventCallOffer            = "call_offer"            // payload: CallOfferEvent
+	EventCallReaction         = "call_reaction"         // payload: CallReactionEvent
+	EventCallRenegotiate      = "call_renegotiate"      // payload: CallRenegotiateEvent
+	EventCallScreenShare      = "call_screen_share"     // payload: CallScreenShareEvent
+	EventCallTrackState       = "call_track_state"      // payload: CallTrackStateEvent
+	EventConnectionUnstable   = "connection_unstable"   // payload: ConnectionUnstableEvent
+	EventConversationDeleted  = "conversation_deleted"  // payload: ConversationChangeEvent
+	EventConversationFrozen   = "conversation_frozen"   // payload: ConversationFrozenEvent
+	EventConversationRestored = "conversation_restored" // payload: ConversationChangeEvent
+	EventConversationsRead    = "conversations_read"    // payload: ConversationsReadEvent
+	EventDisconnect           = "disconnect"            // payload: DisconnectEvent
+	EventError                = "error"                 // payload: ErrorResponse
+	EventHistoryCleared       = "history_cleared"       // payload: HistoryClearedEvent
+	EventMemberAdded          = "member_added"          // payload: MemberAddedEvent
+	EventMemberRoleChanged    = "member_role_changed"   // payload: MemberRoleChangedEvent
+	EventMessageDelivered     = "message_delivered"     // payload: MessageDeliveredEvent
+	EventMessageRead          = "message_read"          // payload: MessageReadEvent
+	EventNewMessage           = "new_message"           // payload: Message
+	EventNotification         = "notification"          // payload: Notification
+	EventOffline              = "offline"               // payload: OnlineEvent
+	EventOnline               = "online"                // payload: OnlineEvent
+	EventPinsChanged          = "pins_changed"          // payload: PinsChangedEvent
+	EventPresenceChanged      = "presence_changed"      // payload: PresenceResponse
+	EventPresenceState        = "presence_state"        // payload: PresenceStateEvent
+	EventPresenceSubscribe    = "presence_subscribe"    // payload: PresenceSubscription
+	EventPresenceUnsubscribe  = "presence_unsubscribe"  // payload: PresenceSubscription
+	EventRefreshToken         = "refresh_token"         // payload: RefreshTokenRequest
+	EventRolesChanged         = "roles_changed"         // payload: RolesChangedEvent
+	EventSetPresence          = "set_presence"          // payload: SetPresenceRequest
+	EventStatusChanged        = "status_changed"        // payload: StatusChangedEvent
+	EventStopTyping           = "stop_typing"           // payload: TypingEvent
+	EventTokenExpiring        = "token_expiring"        // payload: TokenExpiryEvent
+	EventTokenRefreshed       = "token_refreshed"       // payload: TokenExpiryEvent
+	EventTyping               = "typing"                // payload: TypingEvent
+	EventTypingSummary        = "typing_summary"        // payload: TypingSummaryEvent
+)
+
+// eventPayloads makes an empty payload of each event type, to decode into
+var eventPayloads = map[string]func() any{
+	EventAttachmentProcessed:  func() any { return new(MessageAttachment) },
+	EventBootstrap:            func() any { return new(BootstrapEvent) },
+	EventCallAnswer:           func() any { return new(CallAnswerEvent) },
+	EventCallChat:             func() any { return new(CallChatEvent) },
+	EventCallHangup:           func() any { return new(CallHangupEvent) },
+	EventCallICE:              func() any { return new(ICECandidateEvent) },
+	EventCallOffer:            func() any { return new(CallOfferEvent) },
+	EventCallReaction:         func() any { return new(CallReactionEvent) },
+	EventCallRenegotiate:      func() any { return new(CallRenegotiateEvent) },
+	EventCallScreenShare:      func() any { return new(CallScreenShareEvent) },
+	EventCallTrackState:       func() any { return new(CallTrackStateEvent) },
+	EventConnectionUnstable:   func() any { return new(ConnectionUnstableEvent) },
+	EventConversationDeleted:  func() any { return new(ConversationChangeEvent) },
+	EventConversationFrozen:   func() any { return new(ConversationFrozenEvent) },
+	EventConversationRestored: func() any { return new(ConversationChangeEvent) },
+	EventConversationsRead:    func() any { return new(ConversationsReadEvent) },
+	EventDisconnect:           func() any { return new(DisconnectEvent) },
+	EventError:                func() any { return new(ErrorResponse) },
+	EventHistoryCleared:       func() any { return new(HistoryClearedEvent) },
+	EventMemberAdded:          func() any { return new(MemberAddedEvent) },
+	EventMemberRoleChanged:    func() any { return new(MemberRoleChangedEvent) },
+	EventMessageDelivered:     func() any { return new(MessageDeliveredEvent) },
+	EventMessageRead:          func() any { return new(MessageReadEvent) },
+	EventNewMessage:           func() any { return new(Message) },
+	EventNotification:         func() any { return new(Notification) },
+	EventOffline:              func() any { return new(OnlineEvent) },
+	EventOnline:               func() any { return new(OnlineEvent) },
+	EventPinsChanged:          func() any { return new(PinsChangedEvent) },
+	EventPresenceChanged:      func() any { return new(PresenceResponse) },
+	EventPresenceState:        func() any { return new(PresenceStateEvent) },
+	EventPresenceSubscribe:    func() any { return new(PresenceSubscription) },
+	EventPresenceUnsubscribe:  func() any { return new(PresenceSubscription) },
+	EventRefreshToken:         func() any { return new(RefreshTokenRequest) },
+	EventRolesChanged:         func() any { return new(RolesChangedEvent) },
+	EventSetPresence:          func() any { return new(SetPresenceRequest) },
+	EventStatusChanged:        func() any { return new(StatusChangedEvent) },
+	EventStopTyping:           func() any { return new(TypingEvent) },
+	EventTokenExpiring:        func() any { return new(TokenExpiryEvent) },
+	EventTokenRefreshed:       func() any { return new(TokenExpiryEvent) },
+	EventTyping:               func() any { return new(TypingEvent) },
+	EventTypingSummary:        func() any { return new(TypingSummaryEvent) },
+}
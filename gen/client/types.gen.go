@@ -0,0 +1,1542 @@
+// Code generated by cmd/genapi from the API's routes and DTOs. DO NOT EDIT.
+
+package client
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Job is a queued email and its delivery history
+type MailerJob struct {
+	Attempts  int            `json:"attempts,omitempty"`
+	CreatedAt time.Time      `json:"created_at,omitzero"`
+	FailedAt  *time.Time     `json:"failed_at,omitempty"`
+	ID        string         `json:"id,omitempty"`
+	LastError string         `json:"last_error,omitempty"`
+	Message   *MailerMessage `json:"message,omitempty"`
+}
+
+// Message is a provider-agnostic email
+type MailerMessage struct {
+	FromEmail string `json:"from_email,omitempty"`
+	FromName  string `json:"from_name,omitempty"`
+	HTML      string `json:"html,omitempty"`
+	ReplyTo   string `json:"reply_to,omitempty"`
+	Subject   string `json:"subject,omitempty"`
+	To        string `json:"to,omitempty"`
+}
+
+// QueueStats summarizes the queue state
+type MailerQueueStats struct {
+	Dead     int64 `json:"dead,omitempty"`
+	Queued   int64 `json:"queued,omitempty"`
+	Retrying int64 `json:"retrying,omitempty"`
+}
+
+// AddMembersRequest adds users to a group
+type AddMembersRequest struct {
+	MemberIDs []uuid.UUID `json:"member_ids"`
+}
+
+type AdminNoticeRequest struct {
+	Body  string `json:"body"`
+	Title string `json:"title"`
+	// empty = all verified users
+	UserIDs []uuid.UUID `json:"user_ids,omitempty"`
+}
+
+// AttachmentInput is used when sending a message with attachments
+type AttachmentInput struct {
+	FileName string `json:"file_name,omitempty"`
+	FileSize int64  `json:"file_size,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
+	Type     string `json:"type"`
+	URL      string `json:"url"`
+}
+
+// AuditEvent records an administrative or compliance action, for operators to
+// review. Events outlive the conversations they are about.
+type AuditEvent struct {
+	Action string `json:"action,omitempty"`
+	// admin who acted; nil for background jobs
+	ActorID        *uuid.UUID                 `json:"actor_id,omitempty"`
+	ConversationID *uuid.UUID                 `json:"conversation_id,omitempty"`
+	CreatedAt      time.Time                  `json:"created_at,omitzero"`
+	Details        map[string]json.RawMessage `json:"details,omitempty"`
+	ID             uuid.UUID                  `json:"id,omitzero"`
+}
+
+// AutoReply is the away message the server answers direct messages with
+type AutoReply struct {
+	// now within the window
+	Active   bool       `json:"active,omitempty"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	Text     string     `json:"text,omitempty"`
+}
+
+// BootstrapEvent is sent once right after a WebSocket connects, so clients can
+// render without a burst of REST calls
+type BootstrapEvent struct {
+	Conversations       []ConversationResponse `json:"conversations,omitempty"`
+	OnlineContactIDs    []uuid.UUID            `json:"online_contact_ids,omitempty"`
+	UnreadNotifications int64                  `json:"unread_notifications,omitempty"`
+}
+
+type CallAnswerEvent struct {
+	CallID         uuid.UUID       `json:"call_id,omitzero"`
+	ConversationID uuid.UUID       `json:"conversation_id,omitzero"`
+	From           uuid.UUID       `json:"from,omitzero"`
+	Sdp            json.RawMessage `json:"sdp,omitempty"`
+	To             uuid.UUID       `json:"to,omitzero"`
+}
+
+// CallChatEvent is a text message within a call. It only goes to the other
+// participant, unless Persist also posts it to the conversation.
+type CallChatEvent struct {
+	CallID         uuid.UUID `json:"call_id,omitzero"`
+	ConversationID uuid.UUID `json:"conversation_id,omitzero"`
+	From           uuid.UUID `json:"from,omitzero"`
+	// set by the server when persisted
+	MessageID *uuid.UUID `json:"message_id,omitempty"`
+	Persist   bool       `json:"persist,omitempty"`
+	Text      string     `json:"text,omitempty"`
+	To        uuid.UUID  `json:"to,omitzero"`
+}
+
+// CallFeedback is a participant's rating of a call, one per participant
+type CallFeedback struct {
+	CallID    uuid.UUID `json:"call_id,omitzero"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	ID        uuid.UUID `json:"id,omitzero"`
+	Issues    []string  `json:"issues,omitempty"`
+	// 1-5 stars
+	Rating    int       `json:"rating,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+	UserID    uuid.UUID `json:"user_id,omitzero"`
+}
+
+// CallFeedbackRequest rates a call
+type CallFeedbackRequest struct {
+	Comment string `json:"comment,omitempty"`
+	// see CallIssues
+	Issues []string `json:"issues,omitempty"`
+	Rating int      `json:"rating"`
+}
+
+type CallHangupEvent struct {
+	CallID         uuid.UUID `json:"call_id,omitzero"`
+	ConversationID uuid.UUID `json:"conversation_id,omitzero"`
+	From           uuid.UUID `json:"from,omitzero"`
+	To             uuid.UUID `json:"to,omitzero"`
+}
+
+type CallOfferEvent struct {
+	// set by the server when the caller leaves it out
+	CallID uuid.UUID `json:"call_id,omitzero"`
+	// "audio" or "video"
+	CallType       string          `json:"call_type,omitempty"`
+	ConversationID uuid.UUID       `json:"conversation_id,omitzero"`
+	From           uuid.UUID       `json:"from,omitzero"`
+	Sdp            json.RawMessage `json:"sdp,omitempty"`
+	To             uuid.UUID       `json:"to,omitzero"`
+}
+
+// CallQualityReport is call quality by network and region, most packet loss
+// first
+type CallQualityReport struct {
+	From string           `json:"from,omitempty"`
+	Rows []CallQualityRow `json:"rows,omitempty"`
+	To   string           `json:"to,omitempty"`
+}
+
+// CallQualityRow is call quality on one kind of network in one region. Stats
+// are averaged per participant of a call first, so long calls don't weigh more.
+type CallQualityRow struct {
+	AvgJitterMs   float64 `json:"avg_jitter_ms,omitempty"`
+	AvgPacketLoss float64 `json:"avg_packet_loss,omitempty"`
+	// null without ratings
+	AvgRating *float64 `json:"avg_rating,omitempty"`
+	AvgRttMs  float64  `json:"avg_rtt_ms,omitempty"`
+	// participants' calls reporting stats
+	Calls   int64  `json:"calls,omitempty"`
+	Network string `json:"network,omitempty"`
+	Ratings int64  `json:"ratings,omitempty"`
+	Region  string `json:"region,omitempty"`
+	Samples int64  `json:"samples,omitempty"`
+}
+
+// CallReactionEvent is an emoji reaction within a call; never persisted
+type CallReactionEvent struct {
+	CallID         uuid.UUID `json:"call_id,omitzero"`
+	ConversationID uuid.UUID `json:"conversation_id,omitzero"`
+	Emoji          string    `json:"emoji,omitempty"`
+	From           uuid.UUID `json:"from,omitzero"`
+	To             uuid.UUID `json:"to,omitzero"`
+}
+
+// CallRenegotiateEvent carries a new SDP offer or answer mid-call, e.g. when a
+// track is added
+type CallRenegotiateEvent struct {
+	CallID         uuid.UUID       `json:"call_id,omitzero"`
+	ConversationID uuid.UUID       `json:"conversation_id,omitzero"`
+	From           uuid.UUID       `json:"from,omitzero"`
+	Sdp            json.RawMessage `json:"sdp,omitempty"`
+	To             uuid.UUID       `json:"to,omitzero"`
+}
+
+// CallScreenShareEvent starts or stops sharing the sender's screen
+type CallScreenShareEvent struct {
+	CallID         uuid.UUID `json:"call_id,omitzero"`
+	ConversationID uuid.UUID `json:"conversation_id,omitzero"`
+	From           uuid.UUID `json:"from,omitzero"`
+	Sharing        bool      `json:"sharing,omitempty"`
+	To             uuid.UUID `json:"to,omitzero"`
+}
+
+// CallStatsRequest is a periodic WebRTC stats report, e.g. every 10 seconds
+// from RTCPeerConnection.getStats()
+type CallStatsRequest struct {
+	JitterMs *float64 `json:"jitter_ms"`
+	Network  string   `json:"network,omitempty"`
+	// percent, since the last report
+	PacketLoss *float64 `json:"packet_loss"`
+	RttMs      *float64 `json:"rtt_ms"`
+}
+
+// CallTrackStateEvent mutes or unmutes one of the sender's tracks
+type CallTrackStateEvent struct {
+	CallID         uuid.UUID `json:"call_id,omitzero"`
+	ConversationID uuid.UUID `json:"conversation_id,omitzero"`
+	From           uuid.UUID `json:"from,omitzero"`
+	Muted          bool      `json:"muted,omitempty"`
+	To             uuid.UUID `json:"to,omitzero"`
+	// audio or video
+	Track string `json:"track,omitempty"`
+}
+
+// ChatImport is an uploaded chat export from another app being turned into a
+// GoTalk conversation. Jobs live in Redis for a week.
+type ChatImport struct {
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// set once the conversation is created
+	ConversationID   *uuid.UUID `json:"conversation_id,omitempty"`
+	CreatedAt        time.Time  `json:"created_at,omitzero"`
+	Error            string     `json:"error,omitempty"`
+	ID               uuid.UUID  `json:"id,omitzero"`
+	ImportedMessages int        `json:"imported_messages,omitempty"`
+	// missing from the archive or of an unsupported type
+	SkippedAttachments int `json:"skipped_attachments,omitempty"`
+	// whatsapp, telegram
+	Source        string    `json:"source,omitempty"`
+	Status        string    `json:"status,omitempty"`
+	TotalMessages int       `json:"total_messages,omitempty"`
+	UserID        uuid.UUID `json:"user_id,omitzero"`
+}
+
+// ComplianceRecord is a message in a compliance export, one per NDJSON line.
+// Deleted messages are included, with deleted_at.
+type ComplianceRecord struct {
+	Attachments    []MessageAttachment `json:"attachments,omitempty"`
+	Content        string              `json:"content,omitempty"`
+	ConversationID uuid.UUID           `json:"conversation_id,omitzero"`
+	CreatedAt      time.Time           `json:"created_at,omitzero"`
+	DeletedAt      *time.Time          `json:"deleted_at,omitempty"`
+	FileName       string              `json:"file_name,omitempty"`
+	FileURL        string              `json:"file_url,omitempty"`
+	ID             uuid.UUID           `json:"id,omitzero"`
+	ImportedSender string              `json:"imported_sender,omitempty"`
+	ReplyToID      *uuid.UUID          `json:"reply_to_id,omitempty"`
+	SenderEmail    string              `json:"sender_email,omitempty"`
+	SenderID       uuid.UUID           `json:"sender_id,omitzero"`
+	Type           string              `json:"type,omitempty"`
+	UpdatedAt      time.Time           `json:"updated_at,omitzero"`
+}
+
+// ConnectedApp is an app the user has given access to their account
+type ConnectedApp struct {
+	AuthorizedAt time.Time `json:"authorized_at,omitzero"`
+	ClientID     string    `json:"client_id,omitempty"`
+	Name         string    `json:"name,omitempty"`
+	Scopes       []string  `json:"scopes,omitempty"`
+	// last time the user agreed to more scopes
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+// ConnectionUnstableEvent warns that the server heard nothing from the
+// connection, not even a pong, for SilentSeconds and closes it in
+// ClosesInSeconds. Sending any event proves it alive; clients that get no
+// further traffic should reconnect.
+type ConnectionUnstableEvent struct {
+	ClosesInSeconds int `json:"closes_in_seconds,omitempty"`
+	SilentSeconds   int `json:"silent_seconds,omitempty"`
+}
+
+// Conversation represents a chat conversation (1-1 or group)
+type Conversation struct {
+	Announcement *Message `json:"announcement,omitempty"`
+	// group avatar
+	Avatar    string    `json:"avatar,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	// group creator
+	CreatorID *uuid.UUID `json:"creator_id,omitempty"`
+	// only members with manage_settings can post (announcements)
+	Frozen bool      `json:"frozen,omitempty"`
+	ID     uuid.UUID `json:"id,omitzero"`
+	// app the history was imported from (whatsapp, telegram)
+	ImportedFrom string   `json:"imported_from,omitempty"`
+	LastMessage  *Message `json:"last_message,omitempty"`
+	// all members; list them with GET /conversations/:id/members
+	MemberCount int64 `json:"member_count,omitempty"`
+	// in API payloads, a preview: see MemberCount
+	Members []ConversationMember `json:"members,omitempty"`
+	// group name, empty for private
+	Name      string    `json:"name,omitempty"`
+	Type      string    `json:"type,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+// ConversationChangeEvent tells the members who deleted or restored a
+// conversation
+type ConversationChangeEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id,omitzero"`
+	UserID         uuid.UUID `json:"user_id,omitzero"`
+}
+
+// ConversationExport is a requested export of a conversation's history. Jobs
+// live in Redis and expire together with the exported file.
+type ConversationExport struct {
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	ConversationID uuid.UUID  `json:"conversation_id,omitzero"`
+	CreatedAt      time.Time  `json:"created_at,omitzero"`
+	// presigned on each read, valid until URLExpiresAt
+	DownloadURL string `json:"download_url,omitempty"`
+	Error       string `json:"error,omitempty"`
+	// the export and its file are deleted after this
+	ExpiresAt    time.Time  `json:"expires_at,omitzero"`
+	FileSize     int64      `json:"file_size,omitempty"`
+	Format       string     `json:"format,omitempty"`
+	ID           uuid.UUID  `json:"id,omitzero"`
+	MessageCount int        `json:"message_count,omitempty"`
+	Status       string     `json:"status,omitempty"`
+	URLExpiresAt *time.Time `json:"url_expires_at,omitempty"`
+	UserID       uuid.UUID  `json:"user_id,omitzero"`
+}
+
+// ConversationFrozenEvent tells the members someone froze the conversation, so
+// only members who manage it can post, or unfroze it
+type ConversationFrozenEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id,omitzero"`
+	Frozen         bool      `json:"frozen,omitempty"`
+	// who froze or unfroze it
+	UserID uuid.UUID `json:"user_id,omitzero"`
+}
+
+// ConversationInsights summarizes a group's activity over a range of days
+type ConversationInsights struct {
+	// one per day of the range
+	Days []InsightsDay `json:"days,omitempty"`
+	From string        `json:"from,omitempty"`
+	// messages sent in each UTC hour of the day, 24 entries
+	Hours  []int64 `json:"hours,omitempty"`
+	Joins  int64   `json:"joins,omitempty"`
+	Leaves int64   `json:"leaves,omitempty"`
+	// most active first, at most 50
+	Members  []MemberActivity `json:"members,omitempty"`
+	Messages int64            `json:"messages,omitempty"`
+	To       string           `json:"to,omitempty"`
+}
+
+// ConversationMember represents a user's membership in a conversation
+type ConversationMember struct {
+	// messages before this are hidden from this member
+	ClearedBefore  *time.Time `json:"cleared_before,omitempty"`
+	ConversationID uuid.UUID  `json:"conversation_id,omitzero"`
+	ID             uuid.UUID  `json:"id,omitzero"`
+	JoinedAt       time.Time  `json:"joined_at,omitzero"`
+	LastReadAt     *time.Time `json:"last_read_at,omitempty"`
+	MutedUntil     *time.Time `json:"muted_until,omitempty"`
+	Role           string     `json:"role,omitempty"`
+	User           *User      `json:"user,omitempty"`
+	UserID         uuid.UUID  `json:"user_id,omitzero"`
+}
+
+type ConversationResponse struct {
+	Announcement *Message `json:"announcement,omitempty"`
+	// group avatar
+	Avatar    string    `json:"avatar,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	// group creator
+	CreatorID *uuid.UUID `json:"creator_id,omitempty"`
+	// only members with manage_settings can post (announcements)
+	Frozen bool      `json:"frozen,omitempty"`
+	ID     uuid.UUID `json:"id,omitzero"`
+	// app the history was imported from (whatsapp, telegram)
+	ImportedFrom string   `json:"imported_from,omitempty"`
+	LastMessage  *Message `json:"last_message,omitempty"`
+	// all members; list them with GET /conversations/:id/members
+	MemberCount int64 `json:"member_count,omitempty"`
+	// in API payloads, a preview: see MemberCount
+	Members []ConversationMember `json:"members,omitempty"`
+	// group name, empty for private
+	Name        string    `json:"name,omitempty"`
+	Type        string    `json:"type,omitempty"`
+	UnreadCount int       `json:"unread_count,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitzero"`
+}
+
+// ConversationRole is a role of a group: a custom one, or the member role once
+// the group changed its permissions. The admin role always has every permission
+// and is never stored.
+type ConversationRole struct {
+	// admin and member, which can't be deleted
+	BuiltIn     bool      `json:"built_in,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitzero"`
+	Name        string    `json:"name,omitempty"`
+	Permissions []string  `json:"permissions,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at,omitzero"`
+}
+
+// ConversationTemplate sets up a new group: the messages are posted to it as
+// system messages when it's created, and pinned if they say so. Admins manage
+// templates; the project and support templates come predefined.
+type ConversationTemplate struct {
+	CreatedAt   time.Time         `json:"created_at,omitzero"`
+	Description string            `json:"description,omitempty"`
+	ID          uuid.UUID         `json:"id,omitzero"`
+	Messages    []TemplateMessage `json:"messages,omitempty"`
+	// what clients pass to create from it, e.g. "project"
+	Name string `json:"name,omitempty"`
+	// default group name
+	Title     string    `json:"title,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+// ConversationTemplateRequest creates or replaces a template
+type ConversationTemplateRequest struct {
+	Description string `json:"description,omitempty"`
+	// posted in order
+	Messages []TemplateMessage `json:"messages,omitempty"`
+	// 2-50 lowercase letters, digits, underscores or hyphens
+	Name  string `json:"name"`
+	Title string `json:"title"`
+}
+
+// ConversationsReadEvent tells members that a user caught up on several
+// conversations at once; each recipient gets only the conversations it's in
+type ConversationsReadEvent struct {
+	ConversationIDs []uuid.UUID `json:"conversation_ids,omitempty"`
+	ReadAt          time.Time   `json:"read_at,omitzero"`
+	UserID          uuid.UUID   `json:"user_id,omitzero"`
+}
+
+type CreateConversationRequest struct {
+	MemberIDs []uuid.UUID `json:"member_ids"`
+	// required for group
+	Name string `json:"name,omitempty"`
+	Type string `json:"type"`
+}
+
+// CreateFromTemplateRequest creates a group from a template
+type CreateFromTemplateRequest struct {
+	MemberIDs []uuid.UUID `json:"member_ids"`
+	// default: the template's title
+	Name string `json:"name,omitempty"`
+	// template name
+	Template string `json:"template"`
+}
+
+// CreateInvitationRequest issues an invitation code
+type CreateInvitationRequest struct {
+	// restrict the code to this address
+	Email     string     `json:"email,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// default 1; 0 means unlimited
+	MaxUses *int `json:"max_uses,omitempty"`
+}
+
+// CreateLegalHoldRequest places a legal hold on a user or a conversation
+type CreateLegalHoldRequest struct {
+	ConversationID *uuid.UUID `json:"conversation_id,omitempty"`
+	Reason         string     `json:"reason"`
+	UserID         *uuid.UUID `json:"user_id,omitempty"`
+}
+
+// CreateOAuthClientRequest registers an app
+type CreateOAuthClientRequest struct {
+	Name string `json:"name"`
+	// no client secret; the app must use PKCE
+	Public       bool     `json:"public,omitempty"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+}
+
+// CreatePersonalTokenRequest creates a personal access token
+type CreatePersonalTokenRequest struct {
+	// empty never expires
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+}
+
+// CreatePersonalTokenResponse is a new personal access token. The token itself
+// is only returned here.
+type CreatePersonalTokenResponse struct {
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	// nil never expires
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// the token's first characters, to tell tokens apart
+	Hint       string     `json:"hint,omitempty"`
+	ID         uuid.UUID  `json:"id,omitzero"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	Scopes     []string   `json:"scopes,omitempty"`
+	Token      string     `json:"token,omitempty"`
+}
+
+type DirectConversationRequest struct {
+	ReceiverID uuid.UUID `json:"receiver_id"`
+}
+
+type DirectConversationResponse struct {
+	Conversation *ConversationResponse `json:"conversation,omitempty"`
+	IsNew        bool                  `json:"is_new,omitempty"`
+	Messages     []Message             `json:"messages,omitempty"`
+}
+
+// DisconnectEvent is the last event before the server closes a connection. Code
+// is also the close frame's code (4000-4999, see the README) and Reconnect
+// tells the client whether to sign in again, retry or give up.
+type DisconnectEvent struct {
+	Code              int    `json:"code,omitempty"`
+	Reason            string `json:"reason,omitempty"`
+	Reconnect         string `json:"reconnect,omitempty"`
+	RetryAfterSeconds int    `json:"retry_after_seconds,omitempty"`
+}
+
+// ErrorResponse is the body of every error response. Code is stable and meant
+// for programs; Error and Message are for humans (see docs/errors.md)
+type ErrorResponse struct {
+	Code    string          `json:"code,omitempty"`
+	Details json.RawMessage `json:"details,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
+
+type ExportConversationRequest struct {
+	Format string `json:"format"`
+}
+
+type FailedEmailsResponse struct {
+	Jobs  []MailerJob       `json:"jobs,omitempty"`
+	Stats *MailerQueueStats `json:"stats,omitempty"`
+}
+
+// FeatureFlags are runtime switches operators change through the admin API,
+// without a redeploy
+type FeatureFlags struct {
+	// members of a new group including its creator; 0 = unlimited
+	MaxGroupSize int `json:"max_group_size,omitempty"`
+	// email sign-up; existing accounts can still sign in
+	RegistrationOpen bool `json:"registration_open,omitempty"`
+	UploadsEnabled   bool `json:"uploads_enabled,omitempty"`
+}
+
+// FileSearchHit is an attachment whose file name matches a search
+type FileSearchHit struct {
+	AttachmentID   uuid.UUID `json:"attachment_id,omitzero"`
+	ConversationID uuid.UUID `json:"conversation_id,omitzero"`
+	CreatedAt      time.Time `json:"created_at,omitzero"`
+	FileName       string    `json:"file_name,omitempty"`
+	FileSize       int64     `json:"file_size,omitempty"`
+	MessageID      uuid.UUID `json:"message_id,omitzero"`
+	MimeType       string    `json:"mime_type,omitempty"`
+	SenderID       uuid.UUID `json:"sender_id,omitzero"`
+	Type           string    `json:"type,omitempty"`
+	URL            string    `json:"url,omitempty"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type GoogleLoginRequest struct {
+	// Google ID token from frontend
+	IDToken string `json:"id_token"`
+	// for a new account when registration is invitation-only
+	InviteCode string `json:"invite_code,omitempty"`
+}
+
+type HandleAvailabilityResponse struct {
+	Available bool   `json:"available,omitempty"`
+	Handle    string `json:"handle,omitempty"`
+	// invalid, reserved or taken
+	Reason string `json:"reason,omitempty"`
+}
+
+// Histogram is a distribution of observations; each bucket counts those up to
+// its upper bound, the last ("+Inf") all of them
+type Histogram struct {
+	Buckets []HistogramBucket `json:"buckets,omitempty"`
+	Count   int64             `json:"count,omitempty"`
+	Sum     float64           `json:"sum,omitempty"`
+}
+
+type HistogramBucket struct {
+	Count int64  `json:"count,omitempty"`
+	Le    string `json:"le,omitempty"`
+}
+
+// HistoryClearedEvent tells a user's devices that they cleared a conversation's
+// history: messages sent before cleared_before are no longer shown
+type HistoryClearedEvent struct {
+	ClearedBefore  time.Time `json:"cleared_before,omitzero"`
+	ConversationID uuid.UUID `json:"conversation_id,omitzero"`
+}
+
+type ICECandidateEvent struct {
+	CallID         uuid.UUID       `json:"call_id,omitzero"`
+	Candidate      json.RawMessage `json:"candidate,omitempty"`
+	ConversationID uuid.UUID       `json:"conversation_id,omitzero"`
+	From           uuid.UUID       `json:"from,omitzero"`
+	To             uuid.UUID       `json:"to,omitzero"`
+}
+
+// InsightsDay is a group's activity on a day
+type InsightsDay struct {
+	Day      string `json:"day,omitempty"`
+	Joins    int64  `json:"joins,omitempty"`
+	Leaves   int64  `json:"leaves,omitempty"`
+	Messages int64  `json:"messages,omitempty"`
+}
+
+// Invitation is a code issued by an admin that lets people sign up when
+// registration is invitation-only
+type Invitation struct {
+	Code      string    `json:"code,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	CreatedBy uuid.UUID `json:"created_by,omitzero"`
+	// only this address may use it; empty allows anyone
+	Email     string     `json:"email,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	ID        uuid.UUID  `json:"id,omitzero"`
+	// 0 means unlimited
+	MaxUses int `json:"max_uses,omitempty"`
+	Uses    int `json:"uses,omitempty"`
+}
+
+// JSONWebKey is a public RSA signing key
+type JSONWebKey struct {
+	Alg string `json:"alg,omitempty"`
+	E   string `json:"e,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Kty string `json:"kty,omitempty"`
+	N   string `json:"n,omitempty"`
+	Use string `json:"use,omitempty"`
+}
+
+// JSONWebKeySet publishes the keys that verify ID tokens (RFC 7517)
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys,omitempty"`
+}
+
+// LDAPSyncResult counts what a directory sync changed
+type LDAPSyncResult struct {
+	Created     int `json:"created,omitempty"`
+	Deactivated int `json:"deactivated,omitempty"`
+	// group conversations created or brought in line with the directory
+	Groups      int `json:"groups,omitempty"`
+	Reactivated int `json:"reactivated,omitempty"`
+	// entries without an email or ID
+	Skipped int `json:"skipped,omitempty"`
+	Updated int `json:"updated,omitempty"`
+	// directory entries that map to users
+	Users int `json:"users,omitempty"`
+}
+
+// LegalHold keeps a user's or a conversation's messages from being purged by
+// retention policies or conversation deletion while it's in place. A hold on a
+// user covers every conversation they belong or belonged to.
+type LegalHold struct {
+	ConversationID *uuid.UUID `json:"conversation_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at,omitzero"`
+	CreatedBy      uuid.UUID  `json:"created_by,omitzero"`
+	ID             uuid.UUID  `json:"id,omitzero"`
+	// e.g. the matter or case reference
+	Reason string     `json:"reason,omitempty"`
+	UserID *uuid.UUID `json:"user_id,omitempty"`
+}
+
+// LoginAlertRevokeRequest carries the token of a new sign-in alert's "this
+// wasn't me" link
+type LoginAlertRevokeRequest struct {
+	Token string `json:"token"`
+}
+
+// LoginEvent is one sign-in in a user's login history
+type LoginEvent struct {
+	CountryCode string    `json:"country_code,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitzero"`
+	// e.g. "Chrome on Windows"
+	Device string    `json:"device,omitempty"`
+	ID     uuid.UUID `json:"id,omitzero"`
+	IP     string    `json:"ip,omitempty"`
+	// "City, Country", when known
+	Location string `json:"location,omitempty"`
+	// the user was alerted
+	NewDevice bool      `json:"new_device,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	UserID    uuid.UUID `json:"user_id,omitzero"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type LoginResponse struct {
+	// empty when the sign-in asked for a cookie session (X-Session-Mode: cookie)
+	Token string        `json:"token,omitempty"`
+	User  *UserResponse `json:"user,omitempty"`
+}
+
+// MagicLinkRequest asks for a one-time sign-in link by email
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+// MarkReadRequest is the optional body of POST /conversations/read-all
+type MarkReadRequest struct {
+	// empty = every conversation
+	ConversationIDs []uuid.UUID `json:"conversation_ids,omitempty"`
+}
+
+type MarkReadResponse struct {
+	// the conversations that had unread messages
+	ConversationIDs []uuid.UUID `json:"conversation_ids,omitempty"`
+}
+
+// MatrixLinkRequest links a conversation to a Matrix room, given by ID or alias
+type MatrixLinkRequest struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	// !roomid:example.org or #alias:example.org
+	Room string `json:"room"`
+}
+
+// MatrixRoomLink bridges a conversation to a Matrix room
+type MatrixRoomLink struct {
+	ConversationID uuid.UUID `json:"conversation_id,omitzero"`
+	CreatedAt      time.Time `json:"created_at,omitzero"`
+	// !opaque:example.org
+	RoomID string `json:"room_id,omitempty"`
+}
+
+// MemberActivity is how many messages a member, current or former, sent
+type MemberActivity struct {
+	Messages int64     `json:"messages,omitempty"`
+	Name     string    `json:"name,omitempty"`
+	UserID   uuid.UUID `json:"user_id,omitzero"`
+}
+
+// MemberAddedEvent tells the members of a group, including the new ones, that
+// people were added
+type MemberAddedEvent struct {
+	ConversationID uuid.UUID   `json:"conversation_id,omitzero"`
+	MemberIDs      []uuid.UUID `json:"member_ids,omitempty"`
+	// who added them
+	UserID uuid.UUID `json:"user_id,omitzero"`
+}
+
+// MemberRoleChangedEvent tells the members someone was given another role
+type MemberRoleChangedEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id,omitzero"`
+	MemberID       uuid.UUID `json:"member_id,omitzero"`
+	Role           string    `json:"role,omitempty"`
+	// who changed it
+	UserID uuid.UUID `json:"user_id,omitzero"`
+}
+
+// MembershipCacheStats describes the conversation membership cache on one
+// instance: lookups answered from Redis (hits) against those loaded from the
+// database (misses). Errors counts Redis failures answered from the database.
+type MembershipCacheStats struct {
+	AvgHitMs  float64 `json:"avg_hit_ms,omitempty"`
+	AvgMissMs float64 `json:"avg_miss_ms,omitempty"`
+	Errors    int64   `json:"errors,omitempty"`
+	HitRate   float64 `json:"hit_rate,omitempty"`
+	Hits      int64   `json:"hits,omitempty"`
+	Misses    int64   `json:"misses,omitempty"`
+}
+
+// Message represents a chat message
+type Message struct {
+	Attachments []MessageAttachment `json:"attachments,omitempty"`
+	// sent by the server from the sender's auto-reply
+	AutoReply bool `json:"auto_reply,omitempty"`
+	// content is a preview, see Collapse
+	Collapsed      bool      `json:"collapsed,omitempty"`
+	Content        string    `json:"content,omitempty"`
+	ConversationID uuid.UUID `json:"conversation_id,omitzero"`
+	CreatedAt      time.Time `json:"created_at,omitzero"`
+	// formatting of content
+	Entities []MessageEntity `json:"entities,omitempty"`
+	FileName string          `json:"file_name,omitempty"`
+	FileSize int64           `json:"file_size,omitempty"`
+	FileURL  string          `json:"file_url,omitempty"`
+	ID       uuid.UUID       `json:"id,omitzero"`
+	// copied from another app's chat export
+	Imported bool `json:"imported,omitempty"`
+	// sender's name in the export
+	ImportedSender string `json:"imported_sender,omitempty"`
+	// code messages, for highlighting; see CodeLanguages
+	Language     string        `json:"language,omitempty"`
+	ReadReceipts []ReadReceipt `json:"read_receipts,omitempty"`
+	ReplyTo      *Message      `json:"reply_to,omitempty"`
+	ReplyToID    *uuid.UUID    `json:"reply_to_id,omitempty"`
+	Sender       *User         `json:"sender,omitempty"`
+	SenderID     uuid.UUID     `json:"sender_id,omitzero"`
+	Status       string        `json:"status,omitempty"`
+	Type         string        `json:"type,omitempty"`
+	UpdatedAt    time.Time     `json:"updated_at,omitzero"`
+}
+
+// MessageAttachment represents a file attached to a message
+type MessageAttachment struct {
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	// for audio/video (seconds)
+	Duration float64 `json:"duration,omitempty"`
+	FileName string  `json:"file_name,omitempty"`
+	FileSize int64   `json:"file_size,omitempty"`
+	// for images/videos
+	Height           int       `json:"height,omitempty"`
+	ID               uuid.UUID `json:"id,omitzero"`
+	MessageID        uuid.UUID `json:"message_id,omitzero"`
+	MimeType         string    `json:"mime_type,omitempty"`
+	PosterURL        string    `json:"poster_url,omitempty"`
+	ProcessedURL     string    `json:"processed_url,omitempty"`
+	ProcessingStatus string    `json:"processing_status,omitempty"`
+	Type             string    `json:"type,omitempty"`
+	URL              string    `json:"url,omitempty"`
+	// for images/videos
+	Width int `json:"width,omitempty"`
+}
+
+// MessageDeliveredEvent is sent by a client when a message reached it (user_id
+// is filled in by the server) and forwarded to the message's sender
+type MessageDeliveredEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id,omitzero"`
+	MessageID      uuid.UUID `json:"message_id,omitzero"`
+	UserID         uuid.UUID `json:"user_id,omitzero"`
+}
+
+// MessageEntity formats a span of a message's text, Telegram style: the text
+// stays plain and clients render the spans, so formatting can't carry markup or
+// scripts. Offset and Length count characters (Unicode code points) of the text
+// as stored, i.e. after normalization.
+type MessageEntity struct {
+	// pre only
+	Language string `json:"language,omitempty"`
+	Length   int    `json:"length,omitempty"`
+	Offset   int    `json:"offset,omitempty"`
+	Type     string `json:"type,omitempty"`
+	// link only; http, https or mailto
+	URL string `json:"url,omitempty"`
+}
+
+// MessageInfo is the delivery and read breakdown of a message, for its sender
+type MessageInfo struct {
+	DeliveredCount int                    `json:"delivered_count,omitempty"`
+	MessageID      uuid.UUID              `json:"message_id,omitzero"`
+	ReadCount      int                    `json:"read_count,omitempty"`
+	Recipients     []MessageRecipientInfo `json:"recipients,omitempty"`
+}
+
+// MessageReadEvent tells members that user_id read the conversation up to
+// message_id (nil when read without one). The reader's own other devices get it
+// too, with unread_count set to what's left unread for them.
+type MessageReadEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id,omitzero"`
+	MessageID      uuid.UUID `json:"message_id,omitzero"`
+	UnreadCount    *int64    `json:"unread_count,omitempty"`
+	UserID         uuid.UUID `json:"user_id,omitzero"`
+}
+
+// MessageRecipientInfo is when a message reached and was read by one member.
+// Reading or receiving a later message counts for the earlier ones too.
+type MessageRecipientInfo struct {
+	// nil = not delivered yet
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	// nil = not read yet
+	ReadAt *time.Time `json:"read_at,omitempty"`
+	User   *User      `json:"user,omitempty"`
+}
+
+// Notification is a persisted notification center entry
+type Notification struct {
+	Body      string    `json:"body,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	// e.g. conversation_id, caller_id
+	Data map[string]string `json:"data,omitempty"`
+	ID   uuid.UUID         `json:"id,omitzero"`
+	// NULL = unread
+	ReadAt *time.Time `json:"read_at,omitempty"`
+	Title  string     `json:"title,omitempty"`
+	Type   string     `json:"type,omitempty"`
+	UserID uuid.UUID  `json:"user_id,omitzero"`
+}
+
+type NotificationListResponse struct {
+	Notifications []Notification `json:"notifications,omitempty"`
+	UnreadCount   int64          `json:"unread_count,omitempty"`
+}
+
+// OAuthClientResponse describes a registered app to its owner
+type OAuthClientResponse struct {
+	ClientID string `json:"client_id,omitempty"`
+	// only in the registration response
+	ClientSecret string    `json:"client_secret,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitzero"`
+	Name         string    `json:"name,omitempty"`
+	Public       bool      `json:"public,omitempty"`
+	RedirectURIs []string  `json:"redirect_uris,omitempty"`
+	Scopes       []string  `json:"scopes,omitempty"`
+}
+
+// OAuthConsent is what the consent screen shows
+type OAuthConsent struct {
+	ClientID   string `json:"client_id,omitempty"`
+	ClientName string `json:"client_name,omitempty"`
+	// the user already agreed to all of these scopes
+	Granted     bool         `json:"granted,omitempty"`
+	RedirectURI string       `json:"redirect_uri,omitempty"`
+	Scopes      []OAuthScope `json:"scopes,omitempty"`
+}
+
+// OAuthDecisionRequest answers an authorization request
+type OAuthDecisionRequest struct {
+	Approve *bool `json:"approve"`
+}
+
+// OAuthErrorResponse is the error body of the token and revocation endpoints,
+// in the form OAuth client libraries expect (RFC 6749 section 5.2)
+type OAuthErrorResponse struct {
+	// e.g. invalid_grant
+	Error            string `json:"error,omitempty"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// OAuthRedirect is where the consent page sends the browser back to the app
+type OAuthRedirect struct {
+	RedirectTo string `json:"redirect_to,omitempty"`
+}
+
+// OAuthScope describes a scope on the consent screen
+type OAuthScope struct {
+	Description string `json:"description,omitempty"`
+	Name        string `json:"name,omitempty"`
+}
+
+// OAuthTokenResponse carries the tokens issued to an app
+type OAuthTokenResponse struct {
+	AccessToken string `json:"access_token,omitempty"`
+	// seconds
+	ExpiresIn int `json:"expires_in,omitempty"`
+	// with the openid scope
+	IDToken      string `json:"id_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	TokenType    string `json:"token_type,omitempty"`
+}
+
+type OTPSentResponse struct {
+	Email string `json:"email,omitempty"`
+	// seconds until code expires
+	ExpiresIn int    `json:"expires_in,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// OnboardingRule is an action a group takes automatically when a member is
+// added. It runs on behalf of the admin who created it, and is skipped once
+// they no longer manage the group.
+type OnboardingRule struct {
+	Action string `json:"action,omitempty"`
+	// assign_role: days after joining; 0 is right away
+	AfterDays int `json:"after_days,omitempty"`
+	// welcome_dm and post_rules
+	Content        string    `json:"content,omitempty"`
+	ConversationID uuid.UUID `json:"conversation_id,omitzero"`
+	CreatedAt      time.Time `json:"created_at,omitzero"`
+	CreatedBy      uuid.UUID `json:"created_by,omitzero"`
+	ID             uuid.UUID `json:"id,omitzero"`
+	// assign_role
+	Role string `json:"role,omitempty"`
+}
+
+// OnboardingRuleRequest adds an onboarding rule to a group
+type OnboardingRuleRequest struct {
+	Action string `json:"action"`
+	// assign_role
+	AfterDays int `json:"after_days,omitempty"`
+	// welcome_dm and post_rules
+	Content string `json:"content,omitempty"`
+	// assign_role: a custom role of the group
+	Role string `json:"role,omitempty"`
+}
+
+// OnlineEvent announces that a user came online or went offline, or changed
+// state (online, away, busy) while connected
+type OnlineEvent struct {
+	IsOnline bool      `json:"is_online,omitempty"`
+	State    string    `json:"state,omitempty"`
+	UserID   uuid.UUID `json:"user_id,omitzero"`
+}
+
+// PersonalAccessToken is a long-lived, scoped API token a user creates for
+// scripts and integrations that can't sign in interactively. Only its hash is
+// stored.
+type PersonalAccessToken struct {
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	// nil never expires
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// the token's first characters, to tell tokens apart
+	Hint       string     `json:"hint,omitempty"`
+	ID         uuid.UUID  `json:"id,omitzero"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	Name       string     `json:"name,omitempty"`
+	Scopes     []string   `json:"scopes,omitempty"`
+}
+
+// PinMessageRequest pins a message, or changes whether a pinned one is the
+// announcement
+type PinMessageRequest struct {
+	// show it as the conversation's banner, replacing the current one
+	Announcement bool      `json:"announcement,omitempty"`
+	MessageID    uuid.UUID `json:"message_id"`
+}
+
+// PinnedMessage is a message pinned to the top of a conversation. Pins are
+// ordered by Position, new ones on top; at most one is the announcement, which
+// conversation payloads carry as a banner.
+type PinnedMessage struct {
+	Announcement   bool      `json:"announcement,omitempty"`
+	ConversationID uuid.UUID `json:"conversation_id,omitzero"`
+	Message        *Message  `json:"message,omitempty"`
+	MessageID      uuid.UUID `json:"message_id,omitzero"`
+	PinnedAt       time.Time `json:"pinned_at,omitzero"`
+	PinnedBy       uuid.UUID `json:"pinned_by,omitzero"`
+	// 0 is the top
+	Position int `json:"position,omitempty"`
+}
+
+// PinsChangedEvent tells the members of a conversation that its pins changed
+type PinsChangedEvent struct {
+	// the banner, if any
+	AnnouncementID *uuid.UUID `json:"announcement_id,omitempty"`
+	ConversationID uuid.UUID  `json:"conversation_id,omitzero"`
+	// pinned messages, top first
+	MessageIDs []uuid.UUID `json:"message_ids,omitempty"`
+	// who changed them
+	UserID uuid.UUID `json:"user_id,omitzero"`
+}
+
+// PresenceResponse is the current user's chosen presence and how they show now
+// (away when idle, offline when not connected)
+type PresenceResponse struct {
+	Current string `json:"current,omitempty"`
+	State   string `json:"state,omitempty"`
+}
+
+// PresenceStateEvent answers presence_subscribe with which of the newly watched
+// users are online now; online/offline events follow
+type PresenceStateEvent struct {
+	OnlineUserIDs []uuid.UUID `json:"online_user_ids,omitempty"`
+	// online, away or busy, for each online user
+	States map[string]string `json:"states,omitempty"`
+}
+
+// PresenceSubscription is sent by clients to start or stop getting the
+// online/offline events of some users, e.g. their contacts and the members of
+// the open conversation. A connection watches up to 1000 users.
+type PresenceSubscription struct {
+	UserIDs []uuid.UUID `json:"user_ids,omitempty"`
+}
+
+// Privacy holds the user's visibility settings
+type Privacy struct {
+	Discoverability string `json:"discoverability,omitempty"`
+	LastSeen        string `json:"last_seen,omitempty"`
+	Online          string `json:"online,omitempty"`
+}
+
+// QuietHours is the do-not-disturb schedule exposed in user settings
+type QuietHours struct {
+	AllowMentions bool   `json:"allow_mentions,omitempty"`
+	Enabled       bool   `json:"enabled,omitempty"`
+	End           string `json:"end,omitempty"`
+	Start         string `json:"start,omitempty"`
+	Summary       bool   `json:"summary,omitempty"`
+}
+
+// ReadReceipt tracks when a user reads a message
+type ReadReceipt struct {
+	ID        uuid.UUID `json:"id,omitzero"`
+	MessageID uuid.UUID `json:"message_id,omitzero"`
+	ReadAt    time.Time `json:"read_at,omitzero"`
+	User      *User     `json:"user,omitempty"`
+	UserID    uuid.UUID `json:"user_id,omitzero"`
+}
+
+// RefreshTokenRequest is sent by clients to swap the token a live connection is
+// authenticated with for a newer one of the same user
+type RefreshTokenRequest struct {
+	Token string `json:"token,omitempty"`
+}
+
+type RegisterDeviceRequest struct {
+	// android, ios, ios_voip (PushKit token) or web
+	DeviceType string `json:"device_type"`
+	FcmToken   string `json:"fcm_token"`
+}
+
+type RegisterRequest struct {
+	Email string `json:"email"`
+	// required when registration is invitation-only
+	InviteCode string `json:"invite_code,omitempty"`
+	Name       string `json:"name"`
+	Password   string `json:"password"`
+}
+
+// ReorderPinsRequest orders a conversation's pins, top first
+type ReorderPinsRequest struct {
+	// every pinned message, once
+	MessageIDs []uuid.UUID `json:"message_ids"`
+}
+
+type ResendOTPRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Code        string `json:"code"`
+	Email       string `json:"email"`
+	NewPassword string `json:"new_password"`
+}
+
+// RetentionPoliciesResponse lists the retention policies in force
+type RetentionPoliciesResponse struct {
+	Conversations []RetentionPolicy `json:"conversations,omitempty"`
+	Deployment    *RetentionPolicy  `json:"deployment,omitempty"`
+}
+
+// RetentionPolicy limits how long messages are kept, across the deployment or
+// for one conversation, overriding the deployment's
+type RetentionPolicy struct {
+	// nil for the deployment
+	ConversationID *uuid.UUID `json:"conversation_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at,omitzero"`
+	ID             uuid.UUID  `json:"id,omitzero"`
+	// older messages are purged; 0 keeps them
+	MaxAgeDays int       `json:"max_age_days,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at,omitzero"`
+	UpdatedBy  uuid.UUID `json:"updated_by,omitzero"`
+}
+
+// RolesChangedEvent tells the members a role of the conversation was created,
+// changed or deleted; clients reload them with GET /conversations/:id/roles
+type RolesChangedEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id,omitzero"`
+	Role           string    `json:"role,omitempty"`
+	// who changed it
+	UserID uuid.UUID `json:"user_id,omitzero"`
+}
+
+// SSOCodeResponse carries the one-time code when no SSO frontend URL is
+// configured
+type SSOCodeResponse struct {
+	Code string `json:"code,omitempty"`
+}
+
+// SSOTokenRequest trades the one-time code from the SSO callback for a session
+type SSOTokenRequest struct {
+	Code string `json:"code"`
+}
+
+// SaveRoleRequest creates a custom role or changes a role's permissions
+type SaveRoleRequest struct {
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// SearchResponse mixes the hits of every searched type, best first
+type SearchResponse struct {
+	// results per type
+	Counts  map[string]int `json:"counts,omitempty"`
+	Query   string         `json:"query,omitempty"`
+	Results []SearchResult `json:"results,omitempty"`
+}
+
+// SearchResult is one hit of a search; the field named by Type is set
+type SearchResult struct {
+	Conversation *Conversation  `json:"conversation,omitempty"`
+	File         *FileSearchHit `json:"file,omitempty"`
+	Message      *Message       `json:"message,omitempty"`
+	// 0-1, higher first
+	Score float64       `json:"score,omitempty"`
+	Type  string        `json:"type,omitempty"`
+	User  *UserResponse `json:"user,omitempty"`
+}
+
+type SendMessageRequest struct {
+	Attachments []AttachmentInput `json:"attachments,omitempty"`
+	Content     string            `json:"content,omitempty"`
+	// bold, italic, code, links... over content
+	Entities []MessageEntity `json:"entities,omitempty"`
+	FileName string          `json:"file_name,omitempty"`
+	FileSize int64           `json:"file_size,omitempty"`
+	FileURL  string          `json:"file_url,omitempty"`
+	// code messages: one of model.CodeLanguages, plaintext by default
+	Language  string     `json:"language,omitempty"`
+	ReplyToID *uuid.UUID `json:"reply_to_id,omitempty"`
+	Type      string     `json:"type,omitempty"`
+}
+
+// SetAutoReplyRequest sets the current user's auto-reply
+type SetAutoReplyRequest struct {
+	// omit to keep it until cleared
+	EndsAt *time.Time `json:"ends_at,omitempty"`
+	// omit to start now
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	Text     string     `json:"text"`
+}
+
+// SetMemberRoleRequest gives a member a role
+type SetMemberRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// SetPresenceRequest is the body of PUT /auth/presence and the payload of the
+// set_presence WebSocket event
+type SetPresenceRequest struct {
+	State string `json:"state"`
+}
+
+// SetRetentionPolicyRequest sets a retention policy
+type SetRetentionPolicyRequest struct {
+	// 0 keeps messages forever
+	MaxAgeDays *int `json:"max_age_days"`
+}
+
+type StatusChangedEvent struct {
+	Status *UserStatus `json:"status,omitempty"`
+	UserID uuid.UUID   `json:"user_id,omitzero"`
+}
+
+type SuccessResponse struct {
+	Data    json.RawMessage `json:"data,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
+
+// TemplateMessage is a message posted to groups created from a template
+type TemplateMessage struct {
+	Content string `json:"content"`
+	// formatting of content
+	Entities []MessageEntity `json:"entities,omitempty"`
+	// pin it once posted
+	Pin bool `json:"pin,omitempty"`
+}
+
+// TokenExpiryEvent tells a connection when its token expires: token_expiring
+// warns once shortly before, token_refreshed confirms a refresh_token. At
+// expiry the connection is closed with auth_expired.
+type TokenExpiryEvent struct {
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+}
+
+type TypingEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id,omitzero"`
+	Name           string    `json:"name,omitempty"`
+	UserID         uuid.UUID `json:"user_id,omitzero"`
+}
+
+// TypingSummaryEvent says who is typing in a large conversation, replacing
+// typing/stop_typing there. It lists up to 3 names in the order they started;
+// Count is everyone typing, so "Ann, Bob and 4 others" is Count - len(Names)
+// others. A Count of 0 means nobody is typing anymore.
+type TypingSummaryEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id,omitzero"`
+	Count          int       `json:"count,omitempty"`
+	Names          []string  `json:"names,omitempty"`
+}
+
+// UpdateFeatureFlagsRequest changes some flags; omitted flags keep their value
+type UpdateFeatureFlagsRequest struct {
+	MaxGroupSize     *int  `json:"max_group_size,omitempty"`
+	RegistrationOpen *bool `json:"registration_open,omitempty"`
+	UploadsEnabled   *bool `json:"uploads_enabled,omitempty"`
+}
+
+type UpdateHandleRequest struct {
+	// a leading @ is allowed
+	Handle string `json:"handle"`
+}
+
+type UpdatePrivacyRequest struct {
+	Discoverability string `json:"discoverability,omitempty"`
+	LastSeen        string `json:"last_seen,omitempty"`
+	Online          string `json:"online,omitempty"`
+}
+
+type UpdateQuietHoursRequest struct {
+	AllowMentions *bool  `json:"allow_mentions,omitempty"`
+	Enabled       *bool  `json:"enabled,omitempty"`
+	End           string `json:"end,omitempty"`
+	Start         string `json:"start,omitempty"`
+	Summary       *bool  `json:"summary,omitempty"`
+}
+
+type UpdateSettingsRequest struct {
+	IsDigestEnabled            *bool                    `json:"is_digest_enabled,omitempty"`
+	IsEmailNotificationEnabled *bool                    `json:"is_email_notification_enabled,omitempty"`
+	IsNotificationEnabled      *bool                    `json:"is_notification_enabled,omitempty"`
+	IsSoundEnabled             *bool                    `json:"is_sound_enabled,omitempty"`
+	Language                   string                   `json:"language,omitempty"`
+	Privacy                    *UpdatePrivacyRequest    `json:"privacy,omitempty"`
+	QuietHours                 *UpdateQuietHoursRequest `json:"quiet_hours,omitempty"`
+	// from the next sign-in, each one ends the others
+	SingleSession *bool  `json:"single_session,omitempty"`
+	Theme         string `json:"theme,omitempty"`
+	Timezone      string `json:"timezone,omitempty"`
+}
+
+type UpdateStatusRequest struct {
+	Emoji string `json:"emoji,omitempty"`
+	// omit to keep the status until cleared
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Message   string     `json:"message,omitempty"`
+}
+
+// UploadResponse is returned after a successful file upload
+type UploadResponse struct {
+	// SHA-256 of the file content
+	ContentHash string `json:"content_hash,omitempty"`
+	// true if an identical file was already stored
+	Deduplicated bool   `json:"deduplicated,omitempty"`
+	FileName     string `json:"file_name,omitempty"`
+	FileSize     int64  `json:"file_size,omitempty"`
+	MimeType     string `json:"mime_type,omitempty"`
+	URL          string `json:"url,omitempty"`
+}
+
+// UsagePoint is a metric's value on a day
+type UsagePoint struct {
+	Day   string `json:"day,omitempty"`
+	Value int64  `json:"value,omitempty"`
+}
+
+// UsageSeries is a metric's daily values, one per day of the range
+type UsageSeries struct {
+	Metric string       `json:"metric,omitempty"`
+	Points []UsagePoint `json:"points,omitempty"`
+}
+
+// UsageStatsResponse holds the daily series of the requested metrics
+type UsageStatsResponse struct {
+	From   string        `json:"from,omitempty"`
+	Series []UsageSeries `json:"series,omitempty"`
+	To     string        `json:"to,omitempty"`
+}
+
+// User represents a registered user with multi-provider authentication
+type User struct {
+	AuthProvider string    `json:"auth_provider,omitempty"`
+	Avatar       string    `json:"avatar,omitempty"`
+	Bio          string    `json:"bio,omitempty"`
+	CreatedAt    time.Time `json:"created_at,omitzero"`
+	// shown to other users instead of Name when set
+	DisplayName string `json:"display_name,omitempty"`
+	Email       string `json:"email,omitempty"`
+	// NULL = not verified
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	// unique @handle used for mentions
+	Handle string    `json:"handle,omitempty"`
+	ID     uuid.UUID `json:"id,omitzero"`
+	// weekly unread digest email
+	IsDigestEnabled bool `json:"is_digest_enabled,omitempty"`
+	// message emails while offline, answerable by reply
+	IsEmailNotificationEnabled bool       `json:"is_email_notification_enabled,omitempty"`
+	IsNotificationEnabled      bool       `json:"is_notification_enabled,omitempty"`
+	IsOnline                   bool       `json:"is_online,omitempty"`
+	IsSoundEnabled             bool       `json:"is_sound_enabled,omitempty"`
+	Language                   string     `json:"language,omitempty"`
+	LastSeen                   *time.Time `json:"last_seen,omitempty"`
+	// remote Matrix user this account stands in for (bridge)
+	MatrixID *string `json:"matrix_id,omitempty"`
+	Name     string  `json:"name,omitempty"`
+	// @mentions still notify
+	QuietHoursAllowMentions bool `json:"quiet_hours_allow_mentions,omitempty"`
+	QuietHoursEnabled       bool `json:"quiet_hours_enabled,omitempty"`
+	// HH:MM
+	QuietHoursEnd string `json:"quiet_hours_end,omitempty"`
+	// HH:MM
+	QuietHoursStart string `json:"quiet_hours_start,omitempty"`
+	// push a summary when the window ends
+	QuietHoursSummary bool `json:"quiet_hours_summary,omitempty"`
+	// signing in ends the user's other sessions
+	SingleSession bool   `json:"single_session,omitempty"`
+	StatusEmoji   string `json:"status_emoji,omitempty"`
+	// NULL = until cleared
+	StatusExpiresAt *time.Time `json:"status_expires_at,omitempty"`
+	// custom status ("In a meeting")
+	StatusMessage string    `json:"status_message,omitempty"`
+	Theme         string    `json:"theme,omitempty"`
+	Timezone      string    `json:"timezone,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at,omitzero"`
+}
+
+// UserInfo is the OpenID Connect userinfo response; fields depend on the
+// granted scopes
+type UserInfo struct {
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	Name          string `json:"name,omitempty"`
+	Picture       string `json:"picture,omitempty"`
+	Sub           string `json:"sub,omitempty"`
+}
+
+// UserProfileResponse is another user's profile page
+type UserProfileResponse struct {
+	AuthProvider               string      `json:"auth_provider,omitempty"`
+	Avatar                     string      `json:"avatar,omitempty"`
+	Bio                        string      `json:"bio,omitempty"`
+	DisplayName                string      `json:"display_name,omitempty"`
+	Email                      string      `json:"email,omitempty"`
+	EmailVerified              bool        `json:"email_verified,omitempty"`
+	Handle                     string      `json:"handle,omitempty"`
+	ID                         uuid.UUID   `json:"id,omitzero"`
+	IsContact                  bool        `json:"is_contact,omitempty"`
+	IsDigestEnabled            bool        `json:"is_digest_enabled,omitempty"`
+	IsEmailNotificationEnabled bool        `json:"is_email_notification_enabled,omitempty"`
+	IsNotificationEnabled      bool        `json:"is_notification_enabled,omitempty"`
+	IsOnline                   bool        `json:"is_online,omitempty"`
+	IsSoundEnabled             bool        `json:"is_sound_enabled,omitempty"`
+	Language                   string      `json:"language,omitempty"`
+	LastSeen                   *time.Time  `json:"last_seen,omitempty"`
+	Name                       string      `json:"name,omitempty"`
+	Privacy                    *Privacy    `json:"privacy,omitempty"`
+	QuietHours                 *QuietHours `json:"quiet_hours,omitempty"`
+	SingleSession              bool        `json:"single_session,omitempty"`
+	Status                     *UserStatus `json:"status,omitempty"`
+	Theme                      string      `json:"theme,omitempty"`
+	Timezone                   string      `json:"timezone,omitempty"`
+	UpdatedAt                  time.Time   `json:"updated_at,omitzero"`
+}
+
+// UserResponse is the safe version of User for API responses
+type UserResponse struct {
+	AuthProvider               string      `json:"auth_provider,omitempty"`
+	Avatar                     string      `json:"avatar,omitempty"`
+	Bio                        string      `json:"bio,omitempty"`
+	DisplayName                string      `json:"display_name,omitempty"`
+	Email                      string      `json:"email,omitempty"`
+	EmailVerified              bool        `json:"email_verified,omitempty"`
+	Handle                     string      `json:"handle,omitempty"`
+	ID                         uuid.UUID   `json:"id,omitzero"`
+	IsDigestEnabled            bool        `json:"is_digest_enabled,omitempty"`
+	IsEmailNotificationEnabled bool        `json:"is_email_notification_enabled,omitempty"`
+	IsNotificationEnabled      bool        `json:"is_notification_enabled,omitempty"`
+	IsOnline                   bool        `json:"is_online,omitempty"`
+	IsSoundEnabled             bool        `json:"is_sound_enabled,omitempty"`
+	Language                   string      `json:"language,omitempty"`
+	LastSeen                   *time.Time  `json:"last_seen,omitempty"`
+	Name                       string      `json:"name,omitempty"`
+	Privacy                    *Privacy    `json:"privacy,omitempty"`
+	QuietHours                 *QuietHours `json:"quiet_hours,omitempty"`
+	SingleSession              bool        `json:"single_session,omitempty"`
+	Status                     *UserStatus `json:"status,omitempty"`
+	Theme                      string      `json:"theme,omitempty"`
+	Timezone                   string      `json:"timezone,omitempty"`
+	UpdatedAt                  time.Time   `json:"updated_at,omitzero"`
+}
+
+// UserStatus is a custom status shown next to the user's name
+type UserStatus struct {
+	Emoji     string     `json:"emoji,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Message   string     `json:"message,omitempty"`
+}
+
+// VerifyMagicLinkRequest uses a sign-in link, as JSON or a form post
+type VerifyMagicLinkRequest struct {
+	Token string `json:"token"`
+}
+
+type VerifyOTPRequest struct {
+	Code  string `json:"code"`
+	Email string `json:"email"`
+}
+
+// WSEventStats instruments one event type on one instance since startup.
+// Published counts events this instance sent through Redis to every instance;
+// Delivered and Dropped count copies queued for, or discarded from the full
+// queues of, local connections. FanOut is how many local connections each
+// delivery reached. Invalid counts deliveries whose event didn't match its
+// schema in internal/ws/events.schema.json, when WS_VALIDATE_EVENTS is on.
+type WSEventStats struct {
+	Delivered int64      `json:"delivered,omitempty"`
+	Dropped   int64      `json:"dropped,omitempty"`
+	FanOut    *Histogram `json:"fan_out,omitempty"`
+	Invalid   int64      `json:"invalid,omitempty"`
+	Published int64      `json:"published,omitempty"`
+}
+
+// WSStats describes the WebSocket clients on one instance. Dropped counts
+// ephemeral events (typing, presence) discarded for full outbound queues;
+// SlowDisconnects counts clients dropped because a chat message didn't fit.
+// Compressed* cover clients that negotiated permessage-deflate; bytes are
+// measured before compression. UnstableWarnings counts connection_unstable
+// events sent to connections that went silent. Publish* cover the events this
+// instance published to Redis for the other instances, and Events breaks the
+// traffic down by event type.
+type WSStats struct {
+	BufferDiscarded   int64 `json:"buffer_discarded,omitempty"`
+	Buffered          int   `json:"buffered,omitempty"`
+	Clients           int   `json:"clients,omitempty"`
+	CompressedBytes   int64 `json:"compressed_bytes,omitempty"`
+	CompressedClients int   `json:"compressed_clients,omitempty"`
+	CompressedFrames  int64 `json:"compressed_frames,omitempty"`
+	Degraded          bool  `json:"degraded,omitempty"`
+	Dropped           int64 `json:"dropped,omitempty"`
+	// by event type
+	Events           map[string]WSEventStats `json:"events,omitempty"`
+	MaxDepth         int                     `json:"max_depth,omitempty"`
+	PublishErrors    int64                   `json:"publish_errors,omitempty"`
+	PublishLatencyMs *Histogram              `json:"publish_latency_ms,omitempty"`
+	QueueSize        int                     `json:"queue_size,omitempty"`
+	Queued           int                     `json:"queued,omitempty"`
+	RedisOutages     int64                   `json:"redis_outages,omitempty"`
+	SlowDisconnects  int64                   `json:"slow_disconnects,omitempty"`
+	UnstableWarnings int64                   `json:"unstable_warnings,omitempty"`
+}
+
+// WebPushKeyResponse carries the VAPID public key used as applicationServerKey
+type WebPushKeyResponse struct {
+	PublicKey string `json:"public_key,omitempty"`
+}
+
+// WebPushSubscribeRequest mirrors the browser's PushSubscription.toJSON()
+type WebPushSubscribeRequest struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		Auth   string `json:"auth"`
+		P256dh string `json:"p256dh"`
+	} `json:"keys"`
+}
+
+type WebPushUnsubscribeRequest struct {
+	Endpoint string `json:"endpoint"`
+}
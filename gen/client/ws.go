@@ -0,0 +1,111 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Event is a frame received over the WebSocket
+type Event struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Decode decodes the payload into the type its event type carries, e.g. a
+// *Message for EventNewMessage. Payloads of event types newer than the client
+// are returned as they came, as a json.RawMessage.
+func (e Event) Decode() (any, error) {
+	newPayload, ok := eventPayloads[e.Type]
+	if !ok {
+		return e.Payload, nil
+	}
+	payload := newPayload()
+	if err := json.Unmarshal(e.Payload, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// Conn is a WebSocket connection to the server, receiving the user's events
+type Conn struct {
+	conn    *websocket.Conn
+	pending []Event // events of the last frame not returned yet
+
+	writeMu sync.Mutex
+}
+
+// Connect opens the WebSocket with the client's token
+func (c *Client) Connect(ctx context.Context) (*Conn, error) {
+	u, err := url.Parse(c.cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	default:
+		u.Scheme = "ws"
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + "/ws"
+	u.RawQuery = url.Values{"token": {c.cfg.Token}}.Encode()
+
+	conn, resp, err := websocket.DefaultDialer.DialContext(ctx, u.String(), nil)
+	if err != nil {
+		if resp != nil && resp.StatusCode >= 300 {
+			defer resp.Body.Close()
+			return nil, responseError(resp)
+		}
+		return nil, err
+	}
+	return &Conn{conn: conn}, nil
+}
+
+// Next waits for the next event. It must not be called concurrently.
+func (c *Conn) Next() (Event, error) {
+	for len(c.pending) == 0 {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return Event{}, err
+		}
+		// The server batches events into one frame, one per line
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var event Event
+			if err := dec.Decode(&event); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return Event{}, err
+			}
+			c.pending = append(c.pending, event)
+		}
+	}
+	event := c.pending[0]
+	c.pending = c.pending[1:]
+	return event, nil
+}
+
+// Send sends an event, e.g. EventTyping with a conversation
+func (c *Conn) Send(eventType string, payload any) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	return c.conn.WriteJSON(struct {
+		Type    string `json:"type"`
+		Payload any    `json:"payload"`
+	}{eventType, payload})
+}
+
+// Close closes the connection
+func (c *Conn) Close() error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	return c.conn.Close()
+}
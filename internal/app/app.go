@@ -0,0 +1,137 @@
+// Package app builds the API server's object graph, from the configuration
+// through the repositories, services and handlers to the router, and the
+// lifecycle that starts and stops it. cmd/server connects the dependencies and
+// serves the router; integration tests and other binaries can build the same
+// graph against their own database.
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quocanhngo/gotalk/internal/config"
+	"github.com/quocanhngo/gotalk/internal/ws"
+	"github.com/quocanhngo/gotalk/pkg/auth"
+	"github.com/quocanhngo/gotalk/pkg/errreport"
+	"github.com/quocanhngo/gotalk/pkg/mailer"
+	"github.com/quocanhngo/gotalk/pkg/storage"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// App is the wired API server
+type App struct {
+	Config   *config.Config
+	DB       *gorm.DB
+	Redis    *redis.Client
+	Reporter *errreport.Reporter
+	Mailer   *mailer.Mailer
+	Storage  *storage.MinIOStorage // nil when MinIO isn't available
+	JWT      *auth.JWTManager
+	Hub      *ws.Hub
+
+	Repositories *Repositories
+	Services     *Services
+	Handlers     *Handlers
+	Router       *gin.Engine
+
+	Lifecycle *Lifecycle
+}
+
+// New builds the object graph on a connected database and Redis client. Its
+// background workers don't run until Start.
+func New(cfg *config.Config, db *gorm.DB, rdb *redis.Client) (*App, error) {
+	a := &App{
+		Config:    cfg,
+		DB:        db,
+		Redis:     rdb,
+		JWT:       auth.NewJWTManager(cfg.JWT.Secret, cfg.JWT.Expiry),
+		Lifecycle: &Lifecycle{},
+	}
+
+	reporter, err := errreport.New(errreport.Config{
+		SentryDSN:    cfg.Errors.SentryDSN,
+		RollbarToken: cfg.Errors.RollbarToken,
+		Environment:  cfg.App.Env,
+		Release:      cfg.App.Release,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reporting: %w", err)
+	}
+	if backends := reporter.Backends(); len(backends) > 0 {
+		log.Printf("🔥 Reporting panics to %s", strings.Join(backends, ", "))
+	}
+	a.Reporter = reporter
+
+	if err := a.newMailer(); err != nil {
+		return nil, fmt.Errorf("mailer: %w", err)
+	}
+	a.Repositories = newRepositories(db)
+	if err := a.newServices(); err != nil {
+		return nil, err
+	}
+	a.newHandlers()
+	a.newRouter()
+
+	// Tell WebSocket clients to reconnect elsewhere before the workers stop
+	a.Lifecycle.Append(Hook{Name: "websocket", OnStop: func(ctx context.Context) error {
+		a.Hub.Drain(ctx)
+		return nil
+	}})
+	return a, nil
+}
+
+// Start runs the lifecycle's hooks and background workers
+func (a *App) Start(ctx context.Context) error {
+	return a.Lifecycle.Start(ctx)
+}
+
+// Stop stops what Start started, within ctx
+func (a *App) Stop(ctx context.Context) error {
+	return a.Lifecycle.Stop(ctx)
+}
+
+func (a *App) newMailer() error {
+	cfg := a.Config
+	mailClient, err := mailer.New(mailer.Config{
+		Provider: cfg.Mail.Provider,
+		From:     cfg.SMTP.From,
+		FromName: cfg.SMTP.FromName,
+		SMTP: mailer.SMTPConfig{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			TLSMode:  cfg.SMTP.TLSMode,
+			Timeout:  cfg.SMTP.Timeout,
+			PoolSize: cfg.SMTP.PoolSize,
+		},
+		SES: mailer.SESConfig{
+			Region:          cfg.Mail.SESRegion,
+			AccessKeyID:     cfg.Mail.SESAccessKeyID,
+			SecretAccessKey: cfg.Mail.SESSecretAccessKey,
+		},
+		SendGrid: mailer.SendGridConfig{
+			APIKey: cfg.Mail.SendGridAPIKey,
+		},
+		Mailgun: mailer.MailgunConfig{
+			Domain:  cfg.Mail.MailgunDomain,
+			APIKey:  cfg.Mail.MailgunAPIKey,
+			BaseURL: cfg.Mail.MailgunBaseURL,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if mailClient.ProviderName() == mailer.ProviderSMTP {
+		log.Printf("📧 SMTP configured: %s:%s (tls: %s)", cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.TLSMode)
+	} else {
+		log.Printf("📧 Mail provider configured: %s", mailClient.ProviderName())
+	}
+	a.Mailer = mailClient
+	return nil
+}
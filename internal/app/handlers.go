@@ -0,0 +1,87 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/quocanhngo/gotalk/internal/handler"
+	"github.com/quocanhngo/gotalk/internal/middleware"
+)
+
+// Handlers are the HTTP layer: the versioned API, and the endpoints mounted
+// outside it
+type Handlers struct {
+	API         handler.Handlers
+	WS          *handler.WSHandler
+	SCIM        *handler.SCIMHandler
+	InboundMail *handler.InboundMailHandler
+
+	SessionCookies *middleware.SessionCookies // nil without AUTH_COOKIE_ENABLED
+}
+
+func (a *App) newHandlers() {
+	cfg, s := a.Config, a.Services
+
+	wsHandler := handler.NewWSHandler(
+		a.Hub,
+		s.Chat,
+		s.Presence,
+		s.NotificationCenter,
+		s.Call,
+		s.Analytics,
+		a.JWT,
+		a.Redis,
+		cfg.WebSocket.AuthCheckInterval,
+	)
+	a.Lifecycle.Go("websocket credential checks", wsHandler.RunCredentialChecks)
+	wsHandler.UsePush(s.Push)
+	if s.MatrixBridge.Enabled() {
+		wsHandler.UseRelay(s.MatrixBridge)
+	}
+
+	a.Handlers = &Handlers{
+		API: handler.Handlers{
+			Auth:         handler.NewAuthHandler(s.Auth, a.Storage, cfg.VAPID.PublicKey),
+			Chat:         handler.NewChatHandler(s.Chat, s.Export, s.Insights),
+			Upload:       handler.NewUploadHandler(a.Storage, s.Blob),
+			Image:        handler.NewImageHandler(a.Storage, a.Redis),
+			Admin:        handler.NewAdminHandler(s.MailQueue, s.NotificationCenter, s.Flag, a.Hub, s.MembershipCache, s.Auth, s.Signup, s.LDAP, s.Analytics),
+			Notification: handler.NewNotificationHandler(s.NotificationCenter),
+			Profile:      handler.NewProfileHandler(s.Profile, s.Presence),
+			SSO:          handler.NewSSOHandler(s.SSO, cfg.SSO.FrontendURL),
+			Import:       handler.NewImportHandler(s.Import, int64(cfg.Import.MaxSizeMB)<<20),
+			Matrix:       handler.NewMatrixHandler(s.MatrixBridge),
+			OAuth:        handler.NewOAuthHandler(s.OAuth),
+			Token:        handler.NewTokenHandler(s.PersonalToken),
+			Compliance:   handler.NewComplianceHandler(s.Retention, s.Compliance, s.Audit),
+			Search:       handler.NewSearchHandler(s.Search),
+			Call:         handler.NewCallHandler(s.Call),
+			Template:     handler.NewTemplateHandler(s.Template),
+			Onboarding:   handler.NewOnboardingHandler(s.Onboarding),
+			Attachment:   handler.NewAttachmentHandler(s.Chat, a.Storage),
+		},
+		WS:          wsHandler,
+		SCIM:        handler.NewSCIMHandler(s.SCIM),
+		InboundMail: handler.NewInboundMailHandler(s.ReplyMail),
+	}
+
+	// Cookie-based auth mode for browser clients
+	if cfg.AuthCookie.Enabled {
+		cookies := &middleware.SessionCookies{
+			Name:     cfg.AuthCookie.Name,
+			Domain:   cfg.AuthCookie.Domain,
+			Secure:   cfg.AuthCookie.Secure,
+			SameSite: sameSiteModes[cfg.AuthCookie.SameSite],
+			MaxAge:   a.JWT.Expiry(),
+		}
+		a.Handlers.SessionCookies = cookies
+		a.Handlers.API.Auth.UseSessionCookies(cookies)
+		a.Handlers.API.SSO.UseSessionCookies(cookies)
+		wsHandler.UseSessionCookies(cookies, cfg.CORS.Origins)
+	}
+}
+
+var sameSiteModes = map[string]http.SameSite{
+	"lax":    http.SameSiteLaxMode,
+	"strict": http.SameSiteStrictMode,
+	"none":   http.SameSiteNoneMode,
+}
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/quocanhngo/gotalk/internal/config"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/pkg/dbresolver"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// WaitFunc waits until a dependency answers ping, e.g. retrying until a
+// startup timeout. Nil pings once.
+type WaitFunc func(name string, ping func(ctx context.Context) error) error
+
+func (wait WaitFunc) call(name string, ping func(ctx context.Context) error) error {
+	if wait == nil {
+		return ping(context.Background())
+	}
+	return wait(name, ping)
+}
+
+// OpenDatabase connects to PostgreSQL and its read replicas, which serve the
+// read-heavy queries marked with dbresolver.Replica
+func OpenDatabase(cfg *config.Config, wait WaitFunc) (*gorm.DB, error) {
+	gormLogger := logger.Default.LogMode(logger.Info)
+	if cfg.App.Env == "production" {
+		gormLogger = logger.Default.LogMode(logger.Warn)
+	}
+
+	db, err := gorm.Open(postgres.Open(cfg.DB.DSN()), &gorm.Config{
+		Logger:               gormLogger,
+		TranslateError:       true, // surface unique violations as gorm.ErrDuplicatedKey
+		DisableAutomaticPing: true, // waited for below
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	configurePool := func(sqlDB *sql.DB) {
+		sqlDB.SetMaxOpenConns(cfg.DB.MaxOpenConns)
+		sqlDB.SetMaxIdleConns(cfg.DB.MaxIdleConns)
+		sqlDB.SetConnMaxLifetime(cfg.DB.ConnMaxLifetime)
+		sqlDB.SetConnMaxIdleTime(cfg.DB.ConnMaxIdleTime)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	configurePool(sqlDB)
+	if err := wait.call("PostgreSQL", sqlDB.PingContext); err != nil {
+		return nil, err
+	}
+
+	var replicas []gorm.ConnPool
+	for i, dsn := range cfg.DB.ReplicaDSNs {
+		name := fmt.Sprintf("read replica #%d", i+1)
+		replica, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: gormLogger, DisableAutomaticPing: true})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		sqlReplica, err := replica.DB()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		configurePool(sqlReplica)
+		if err := wait.call(name, sqlReplica.PingContext); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		replicas = append(replicas, sqlReplica)
+	}
+	if err := db.Use(dbresolver.New(replicas...)); err != nil {
+		return nil, fmt.Errorf("read replicas: %w", err)
+	}
+	return db, nil
+}
+
+// OpenRedis returns a client for the configured Redis; it connects lazily
+func OpenRedis(cfg *config.Config) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr(),
+		Password: cfg.Redis.Password,
+		DB:       0,
+	})
+}
+
+// AutoMigrate creates the schema from the models, for when the migration
+// files can't be applied outside production, and for throwaway test databases
+func AutoMigrate(db *gorm.DB) error {
+	return db.AutoMigrate(
+		&model.User{},
+		&model.OTPCode{},
+		&model.Conversation{},
+		&model.ConversationMember{},
+		&model.Message{},
+		&model.MessageAttachment{},
+		&model.ReadReceipt{},
+		&model.DeliveryReceipt{},
+		&model.FileBlob{},
+		&model.WebPushSubscription{},
+		&model.Notification{},
+		&model.OutboxEvent{},
+		&model.MatrixRoomLink{},
+		&model.LoginEvent{},
+		&model.Invitation{},
+		&model.OAuthClient{},
+		&model.OAuthGrant{},
+		&model.OAuthRefreshToken{},
+		&model.PersonalAccessToken{},
+		&model.ConversationRole{},
+		&model.RetentionPolicy{},
+		&model.AuditEvent{},
+		&model.LegalHold{},
+		&model.UsageStat{},
+		&model.ConversationDailyStat{},
+		&model.ConversationMemberDailyStat{},
+		&model.Call{},
+		&model.CallFeedback{},
+		&model.CallStatsSample{},
+		&model.ConversationTemplate{},
+		&model.OnboardingRule{},
+		&model.PinnedMessage{},
+	)
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Hook is run when the application starts and stops
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error // may be nil
+	OnStop  func(ctx context.Context) error // may be nil
+}
+
+// Lifecycle starts and stops what the object graph needs running: hooks, in
+// the order they were added on start and in reverse on stop, and background
+// workers, which run from Start until every stop hook is done.
+type Lifecycle struct {
+	hooks   []Hook
+	workers []worker
+
+	started int // hooks whose OnStart succeeded
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[string]int // workers that haven't returned yet, by name
+}
+
+type worker struct {
+	name string
+	run  func(ctx context.Context)
+}
+
+// Append adds a hook; hooks added after Start aren't started
+func (l *Lifecycle) Append(hook Hook) {
+	l.hooks = append(l.hooks, hook)
+}
+
+// Go adds a background worker, run until its context is cancelled
+func (l *Lifecycle) Go(name string, run func(ctx context.Context)) {
+	l.workers = append(l.workers, worker{name: name, run: run})
+}
+
+// Start runs the start hooks, then the workers. When a hook fails, the hooks
+// already started are stopped and its error is returned.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	for _, hook := range l.hooks {
+		if hook.OnStart != nil {
+			if err := hook.OnStart(ctx); err != nil {
+				l.stopHooks(ctx)
+				return fmt.Errorf("start %s: %w", hook.Name, err)
+			}
+		}
+		l.started++
+	}
+
+	workerCtx, cancel := context.WithCancel(context.Background())
+	l.cancel = cancel
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.running = map[string]int{}
+	for _, w := range l.workers {
+		l.running[w.name]++
+		l.wg.Add(1)
+		go func() {
+			defer l.wg.Done()
+			w.run(workerCtx)
+			l.mu.Lock()
+			if l.running[w.name]--; l.running[w.name] == 0 {
+				delete(l.running, w.name)
+			}
+			l.mu.Unlock()
+		}()
+	}
+	return nil
+}
+
+// Stop runs the stop hooks of the started hooks in reverse, then cancels the
+// workers and waits for them until ctx ends. Every hook is run even when one
+// fails; their errors are returned together.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	err := l.stopHooks(ctx)
+	if l.cancel == nil {
+		return err
+	}
+	l.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		l.mu.Lock()
+		names := make([]string, 0, len(l.running))
+		for name := range l.running {
+			names = append(names, name)
+		}
+		l.mu.Unlock()
+		sort.Strings(names)
+		log.Printf("⚠️  Background workers still running at shutdown: %s", strings.Join(names, ", "))
+	}
+	return err
+}
+
+func (l *Lifecycle) stopHooks(ctx context.Context) error {
+	var errs []error
+	for ; l.started > 0; l.started-- {
+		hook := l.hooks[l.started-1]
+		if hook.OnStop != nil {
+			if err := hook.OnStop(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("stop %s: %w", hook.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
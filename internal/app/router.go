@@ -0,0 +1,116 @@
+package app
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/handler"
+	"github.com/quocanhngo/gotalk/internal/middleware"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+)
+
+func (a *App) newRouter() {
+	cfg, s, h := a.Config, a.Services, a.Handlers
+
+	if cfg.App.Env == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router := gin.New()
+	router.Use(gin.Logger(), middleware.Recovery(a.Reporter))
+	router.Use(middleware.SecurityHeaders(cfg.Security.HSTSMaxAge, cfg.Security.HSTSIncludeSubdomains, cfg.Security.ReferrerPolicy, "/swagger/"))
+
+	// API docs: the OpenAPI 3 spec is generated by cmd/genapi (go generate ./cmd/server)
+	// Serve it at /docs/openapi.json to avoid conflict with /swagger/* wildcard
+	router.StaticFile("/docs/openapi.json", "./docs/openapi.json")
+
+	// Swagger UI handling
+	url := ginSwagger.URL("/docs/openapi.json") // Point to the relative path
+	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, url))
+
+	// Global middleware
+	router.Use(middleware.CORSMiddleware(cfg.CORS.Origins))
+	if cfg.Compression.Enabled {
+		// Before ErrorHandler so error bodies are compressed too
+		router.Use(middleware.Compression(cfg.Compression.Level, cfg.Compression.MinSize, cfg.Compression.ContentTypes))
+	}
+	router.Use(middleware.ErrorHandler(func(userID uuid.UUID) string {
+		// Validation errors are localized to the user's language setting
+		user, err := a.Repositories.User.FindByID(userID)
+		if err != nil {
+			return ""
+		}
+		return user.Language
+	}))
+	if h.SessionCookies != nil {
+		// After ErrorHandler, which renders its refusals
+		router.Use(middleware.CSRF(h.SessionCookies))
+	}
+	router.Use(middleware.FeatureFlags(s.Flag.Get))
+	router.Use(middleware.TrackActivity(s.Analytics.Active))
+	router.Use(middleware.TrackActivity(s.Presence.Active))
+
+	// Health check
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "ok",
+			"service": "gotalk-api",
+			"time":    time.Now().Format(time.RFC3339),
+		})
+	})
+
+	// Readiness: the database answers. Without Redis the instance stays ready but
+	// degraded, delivering WebSocket events to its own connections only.
+	router.GET("/health/ready", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+		defer cancel()
+		sqlDB, err := a.DB.DB()
+		if err == nil {
+			err = sqlDB.PingContext(ctx)
+		}
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "database": err.Error()})
+			return
+		}
+		status := "ready"
+		if a.Hub.Degraded() {
+			status = "degraded"
+		}
+		c.JSON(http.StatusOK, gin.H{"status": status, "degraded": a.Hub.Degraded()})
+	})
+
+	// ==================== API Routes ====================
+	// Every version (/api/v1, /api/v2) is served by the same handlers
+	handler.RegisterVersions(router, h.API, middleware.AuthMiddleware(a.JWT, a.Redis, s.PersonalToken.Authenticate, h.SessionCookies), middleware.AdminMiddleware(cfg.App.AdminEmails), middleware.Idempotency(a.Redis), middleware.Transfer(cfg.HTTP.TransferTimeout))
+
+	// OpenID Connect discovery, for apps signing users in with GoTalk
+	router.GET("/.well-known/openid-configuration", h.API.OAuth.Discovery)
+
+	// WebSocket endpoint (auth via query parameter)
+	router.GET("/ws", h.WS.HandleWebSocket)
+
+	// SCIM 2.0 provisioning (only with a provisioning token configured)
+	if cfg.SCIM.Token != "" {
+		handler.RegisterSCIMRoutes(router, h.SCIM, middleware.SCIMErrorHandler(), middleware.SCIMAuth(cfg.SCIM.Token))
+		log.Printf("👥 SCIM provisioning enabled at %s", handler.SCIMBasePath)
+	}
+
+	// Matrix application service API, called by the homeserver
+	if s.MatrixBridge.Enabled() {
+		handler.RegisterMatrixRoutes(router, h.API.Matrix, middleware.MatrixAuth(cfg.Matrix.HSToken))
+		log.Printf("🌉 Matrix bridge enabled with %s", cfg.Matrix.HomeserverURL)
+	}
+
+	// Inbound mail webhook, called by the mail provider with replies to notification emails
+	if s.ReplyMail.Enabled() {
+		handler.RegisterInboundMailRoutes(router, h.InboundMail, middleware.InboundMailAuth(cfg.ReplyMail.InboundToken))
+		log.Printf("📬 Reply by email enabled for reply+…@%s at %s", cfg.ReplyMail.Domain, handler.InboundMailPath)
+	}
+
+	a.Router = router
+}
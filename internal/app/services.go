@@ -0,0 +1,469 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/internal/ws"
+	"github.com/quocanhngo/gotalk/pkg/geoip"
+	"github.com/quocanhngo/gotalk/pkg/mailer"
+	"github.com/quocanhngo/gotalk/pkg/matrix"
+	"github.com/quocanhngo/gotalk/pkg/notification"
+	"github.com/quocanhngo/gotalk/pkg/oidc"
+	"github.com/quocanhngo/gotalk/pkg/opensearch"
+	"github.com/quocanhngo/gotalk/pkg/storage"
+	"github.com/quocanhngo/gotalk/pkg/transcoder"
+	"gorm.io/gorm"
+)
+
+// Repositories are the data access layer
+type Repositories struct {
+	User          *repository.UserRepository
+	OTP           *repository.OTPRepository
+	Conversation  *repository.ConversationRepository
+	Message       *repository.MessageRepository
+	Blob          *repository.BlobRepository
+	Notification  *repository.NotificationRepository
+	Outbox        *repository.OutboxRepository
+	Matrix        *repository.MatrixRepository
+	Invitation    *repository.InvitationRepository
+	OAuth         *repository.OAuthRepository
+	PersonalToken *repository.PersonalTokenRepository
+	Retention     *repository.RetentionRepository
+	Audit         *repository.AuditRepository
+	LegalHold     *repository.LegalHoldRepository
+	Analytics     *repository.AnalyticsRepository
+	Insights      *repository.InsightsRepository
+	Call          *repository.CallRepository
+	Template      *repository.TemplateRepository
+	Onboarding    *repository.OnboardingRepository
+	Pin           *repository.PinRepository
+}
+
+func newRepositories(db *gorm.DB) *Repositories {
+	return &Repositories{
+		User:          repository.NewUserRepository(db),
+		OTP:           repository.NewOTPRepository(db),
+		Conversation:  repository.NewConversationRepository(db),
+		Message:       repository.NewMessageRepository(db),
+		Blob:          repository.NewBlobRepository(db),
+		Notification:  repository.NewNotificationRepository(db),
+		Outbox:        repository.NewOutboxRepository(db),
+		Matrix:        repository.NewMatrixRepository(db),
+		Invitation:    repository.NewInvitationRepository(db),
+		OAuth:         repository.NewOAuthRepository(db),
+		PersonalToken: repository.NewPersonalTokenRepository(db),
+		Retention:     repository.NewRetentionRepository(db),
+		Audit:         repository.NewAuditRepository(db),
+		LegalHold:     repository.NewLegalHoldRepository(db),
+		Analytics:     repository.NewAnalyticsRepository(db),
+		Insights:      repository.NewInsightsRepository(db),
+		Call:          repository.NewCallRepository(db),
+		Template:      repository.NewTemplateRepository(db),
+		Onboarding:    repository.NewOnboardingRepository(db),
+		Pin:           repository.NewPinRepository(db),
+	}
+}
+
+// Services are the business logic layer; the optional ones are disabled
+// rather than nil when not configured, except MessageIndex
+type Services struct {
+	MailQueue          *mailer.Queue
+	Signup             *service.SignupService
+	Auth               *service.AuthService
+	Push               *notification.NotificationService
+	Presence           *service.PresenceService
+	Media              *service.MediaService
+	Analytics          *service.AnalyticsService
+	Blob               *service.BlobService
+	NotificationCenter *service.NotificationCenterService
+	MembershipCache    *service.MembershipCache
+	Outbox             *service.OutboxService
+	Chat               *service.ChatService
+	Insights           *service.InsightsService
+	MatrixBridge       *service.MatrixBridgeService
+	Onboarding         *service.OnboardingService
+	ReplyMail          *service.ReplyMailService
+	Search             *service.SearchService
+	MessageIndex       *service.MessageIndex // nil without OpenSearch
+	Export             *service.ExportService
+	Import             *service.ImportService
+	Profile            *service.ProfileService
+	Digest             *service.DigestService
+	Flag               *service.FlagService
+	SCIM               *service.SCIMService
+	LDAP               *service.LDAPService
+	SSO                *service.SSOService
+	OAuth              *service.OAuthService
+	PersonalToken      *service.PersonalTokenService
+	Audit              *service.AuditService
+	Retention          *service.RetentionService
+	Compliance         *service.ComplianceService
+	Call               *service.CallService
+	Template           *service.TemplateService
+}
+
+// newServices builds the services and the WebSocket hub, and adds their
+// background workers to the lifecycle
+func (a *App) newServices() error {
+	cfg, rdb, repos, lc := a.Config, a.Redis, a.Repositories, a.Lifecycle
+	s := &Services{}
+	a.Services = s
+
+	// All emails go through the Redis queue (retries + dead-letter)
+	s.MailQueue = mailer.NewQueue(rdb, a.Mailer)
+	a.Mailer.UseQueue(s.MailQueue)
+	lc.Go("mail queue", s.MailQueue.Run)
+
+	s.Signup = service.NewSignupService(repos.Invitation, service.SignupPolicy{
+		AllowedDomains:  cfg.Signup.AllowedDomains,
+		BlockedDomains:  cfg.Signup.BlockedDomains,
+		BlockDisposable: cfg.Signup.BlockDisposable,
+		InviteOnly:      cfg.Signup.InviteOnly,
+	})
+	otpSecret := cfg.OTP.Secret
+	if otpSecret == "" {
+		otpSecret = cfg.JWT.Secret
+	}
+	s.Auth = service.NewAuthService(repos.User, repos.OTP, service.OTPPolicy{
+		Secret:           []byte(otpSecret),
+		MaxAttempts:      cfg.OTP.MaxAttempts,
+		VerifyRateLimit:  cfg.OTP.VerifyRateLimit,
+		VerifyRateWindow: cfg.OTP.VerifyRateWindow,
+	}, a.JWT, a.Mailer, rdb, s.Signup, cfg.Google.ClientID, cfg.JWT.SingleSession)
+	s.Auth.UseMagicLinks(cfg.MagicLink.URL, cfg.MagicLink.TTL)
+
+	// Prune push devices that stopped re-registering
+	lc.Go("device prune", func(ctx context.Context) { s.Auth.RunDevicePrune(ctx, 24*time.Hour) })
+
+	// Notification Service
+	var err error
+	s.Push, err = notification.NewNotificationService(notification.Config{
+		FirebaseCredentialsFile: cfg.Firebase.CredentialsFile,
+		APNs: notification.APNsConfig{
+			KeyFile:  cfg.APNs.KeyFile,
+			KeyID:    cfg.APNs.KeyID,
+			TeamID:   cfg.APNs.TeamID,
+			BundleID: cfg.APNs.BundleID,
+			Sandbox:  cfg.APNs.Sandbox,
+		},
+		VAPID: notification.VAPIDConfig{
+			PublicKey:  cfg.VAPID.PublicKey,
+			PrivateKey: cfg.VAPID.PrivateKey,
+			Subject:    cfg.VAPID.Subject,
+		},
+	}, repos.User, rdb)
+	if err != nil {
+		log.Printf("⚠️ Notification service error: %v", err)
+	}
+
+	// Send quiet hours summaries when users' do-not-disturb windows end
+	lc.Go("quiet hours summaries", s.Push.RunQuietHoursSummary)
+
+	a.newHub()
+
+	// Pick up rotated secrets: the JWT signing key is swapped in place, other
+	// settings need a restart
+	lc.Go("secret watcher", func(ctx context.Context) {
+		cfg.WatchSecrets(ctx, func(name, value string) {
+			if name == "JWT.Secret" {
+				a.JWT.SetSecret(value)
+				log.Println("🔐 JWT signing key rotated")
+				return
+			}
+			log.Printf("🔐 Secret for %s was rotated; restart to apply it", name)
+		})
+	})
+
+	// MinIO Storage
+	minioStorage, err := storage.NewMinIO(storage.Config{
+		Endpoint:  cfg.MinIO.Endpoint,
+		PublicURL: cfg.MinIO.PublicURL,
+		AccessKey: cfg.MinIO.AccessKey,
+		SecretKey: cfg.MinIO.SecretKey,
+		Bucket:    cfg.MinIO.Bucket,
+		UseSSL:    cfg.MinIO.UseSSL,
+	})
+	if err != nil {
+		log.Printf("⚠️  MinIO not available: %v (file upload disabled)", err)
+	}
+	if minioStorage != nil {
+		log.Println("✅ Connected to MinIO")
+	}
+	a.Storage = minioStorage
+
+	// Video Transcoding (ffmpeg)
+	var videoTranscoder *transcoder.Transcoder
+	if cfg.Transcode.Enabled {
+		videoTranscoder, err = transcoder.New(transcoder.Config{
+			FFmpegPath:  cfg.Transcode.FFmpegPath,
+			FFprobePath: cfg.Transcode.FFprobePath,
+		})
+		if err != nil {
+			log.Printf("⚠️  Video transcoding disabled: %v", err)
+		}
+	}
+
+	s.Media = service.NewMediaService(repos.Message, minioStorage, videoTranscoder, rdb, cfg.Transcode.Workers,
+		func(att *model.MessageAttachment, conversationID uuid.UUID) {
+			// Callback: let conversation members swap in the processed rendition
+			memberIDs, err := repos.Conversation.GetMemberIDs(conversationID)
+			if err != nil {
+				return
+			}
+			a.Hub.SendToUsers(memberIDs, &model.WSEvent{
+				Type:    model.WSEventAttachmentProcessed,
+				Payload: att,
+			})
+		})
+	lc.Go("media", s.Media.Run)
+
+	// Anonymous usage statistics, buffered and flushed into daily aggregates
+	s.Analytics = service.NewAnalyticsService(repos.Analytics, rdb)
+	lc.Go("analytics", func(ctx context.Context) { s.Analytics.Run(ctx, cfg.Analytics.FlushInterval) })
+	// Keep the usage counted since the last flush
+	lc.Append(Hook{Name: "analytics", OnStop: func(ctx context.Context) error {
+		s.Analytics.Flush(ctx)
+		return nil
+	}})
+
+	// Deduplicated file storage (content-addressed by SHA-256)
+	s.Blob = service.NewBlobService(repos.Blob, minioStorage, s.Analytics)
+	lc.Go("blob purge", func(ctx context.Context) { s.Blob.RunPurge(ctx, time.Hour) })
+
+	// Notification center (bell icon), delivered live over WebSocket
+	s.NotificationCenter = service.NewNotificationCenterService(repos.Notification, repos.User, rdb, func(n *model.Notification) {
+		a.Hub.SendToUser(n.UserID, &model.WSEvent{
+			Type:    model.WSEventNotification,
+			Payload: n,
+		})
+	})
+
+	// Login history and new-device alerts, with sign-ins located through MaxMind when configured
+	geo := geoip.New(geoip.Config{
+		AccountID:  cfg.GeoIP.AccountID,
+		LicenseKey: cfg.GeoIP.LicenseKey,
+		Host:       cfg.GeoIP.Host,
+	})
+	s.Auth.UseLoginAlerts(geo, s.NotificationCenter, cfg.LoginAlert.RevokeURL, cfg.LoginAlert.RevokeTTL)
+
+	// Outbox worker: publishes the WebSocket broadcast and push notifications saved with
+	// each message, retrying until they go out
+	// Conversation members cached in Redis for the per-message, typing and read receipt checks
+	s.MembershipCache = service.NewMembershipCache(repos.Conversation, rdb)
+
+	s.Outbox = service.NewOutboxService(repos.Outbox, repos.Message, repos.Conversation, repos.User, s.MembershipCache, a.Hub, s.Push, s.NotificationCenter)
+
+	s.Chat = service.NewChatService(repos.Conversation, s.MembershipCache, repos.Message, repos.Pin, repos.User, s.NotificationCenter, s.Media, s.Blob, s.Outbox, s.Analytics, a.Hub, cfg.Conversation.Retention, service.ChatLimits{
+		GroupMembers:  cfg.Limits.GroupMembers,
+		GroupsPerUser: cfg.Limits.GroupsPerUser,
+		DirectsPerDay: cfg.Limits.DirectsPerDay,
+		MessageLength: cfg.Limits.MessageLength,
+		Pins:          cfg.Limits.Pins,
+	})
+
+	// Deleted conversations past the retention are purged hourly
+	lc.Go("conversation purge", s.Chat.RunPurge)
+
+	// Group activity is rolled up into daily stats hourly for insights
+	s.Insights = service.NewInsightsService(repos.Insights, repos.Conversation, repos.User, s.Chat)
+	lc.Go("insights", func(ctx context.Context) { s.Insights.Run(ctx, time.Hour) })
+
+	// Matrix bridge: group conversations linked to Matrix rooms (disabled without a homeserver)
+	var matrixClient *matrix.Client
+	if cfg.Matrix.HomeserverURL != "" {
+		matrixClient = matrix.New(matrix.Config{
+			HomeserverURL: cfg.Matrix.HomeserverURL,
+			ServerName:    cfg.Matrix.ServerName,
+			ASToken:       cfg.Matrix.ASToken,
+			UserPrefix:    cfg.Matrix.UserPrefix,
+			BotLocalpart:  cfg.Matrix.BotLocalpart,
+		})
+	}
+	s.MatrixBridge = service.NewMatrixBridgeService(matrixClient, repos.Matrix, repos.Conversation, s.MembershipCache, repos.User, s.Chat, a.Hub, rdb)
+	if s.MatrixBridge.Enabled() {
+		s.Outbox.UseRelay(s.MatrixBridge)
+		lc.Go("matrix bridge", s.MatrixBridge.Run)
+	}
+
+	// Onboarding rules: groups' automatic actions for new members, run by the outbox worker
+	s.Onboarding = service.NewOnboardingService(repos.Onboarding, repos.Conversation, repos.Outbox, s.Chat)
+	s.Outbox.UseOnboarding(s.Onboarding)
+
+	// Auto-replies: away messages answering direct messages, sent by the outbox worker
+	s.Outbox.UseAutoReply(service.NewAutoReplyService(s.Chat, rdb))
+
+	// Reply by email: offline members are emailed new messages and can answer them (disabled without a domain)
+	s.ReplyMail = service.NewReplyMailService(repos.User, s.Chat, a.Mailer, a.Hub, rdb,
+		cfg.ReplyMail.Domain, cfg.ReplyMail.AddressTTL, cfg.ReplyMail.Throttle)
+	if s.ReplyMail.Enabled() {
+		s.Outbox.UseReplyMail(s.ReplyMail)
+	}
+
+	// Message search: Postgres full-text search, or an OpenSearch index fed by the outbox when configured
+	s.Search = service.NewSearchService(repos.Message, repos.Conversation, repos.User)
+	if cfg.OpenSearch.URL != "" {
+		s.MessageIndex = service.NewMessageIndex(opensearch.New(opensearch.Config{
+			URL:      cfg.OpenSearch.URL,
+			Index:    cfg.OpenSearch.Index,
+			Username: cfg.OpenSearch.Username,
+			Password: cfg.OpenSearch.Password,
+		}), repos.Message)
+		lc.Append(Hook{Name: "opensearch", OnStart: func(ctx context.Context) error {
+			if err := s.MessageIndex.EnsureIndex(ctx); err != nil {
+				log.Printf("⚠️  OpenSearch index unavailable, it's created on the next start: %v", err)
+			}
+			return nil
+		}})
+		s.Outbox.UseSearchIndex(s.MessageIndex)
+		s.Search.UseIndex(s.MessageIndex)
+		log.Printf("🔎 Message search uses OpenSearch index %s", cfg.OpenSearch.Index)
+	}
+	lc.Go("outbox", s.Outbox.Run)
+
+	// Conversation exports (JSON / HTML / CSV), built in the background and downloaded via signed links
+	s.Export = service.NewExportService(repos.Conversation, repos.Message, minioStorage, s.NotificationCenter, rdb, cfg.Export.LinkExpiry, cfg.Export.Retention)
+	lc.Go("exports", s.Export.Run)
+
+	// Chat imports from WhatsApp / Telegram exports
+	s.Import = service.NewImportService(repos.Conversation, repos.Message, s.Blob, minioStorage, s.NotificationCenter, rdb)
+	if s.MessageIndex != nil {
+		s.Import.UseSearchIndex(s.MessageIndex)
+	}
+	lc.Go("imports", s.Import.Run)
+
+	// Profiles and custom statuses; status changes go live to contacts and the user's other devices
+	s.Profile = service.NewProfileService(repos.User, func(userID uuid.UUID, status *model.UserStatus) {
+		contactIDs, err := repos.User.GetContactIDs(userID)
+		if err != nil {
+			return
+		}
+		a.Hub.SendToUsers(append(contactIDs, userID), &model.WSEvent{
+			Type:    model.WSEventStatusChanged,
+			Payload: model.StatusChangedEvent{UserID: userID, Status: status},
+		})
+	})
+	lc.Go("custom status expiry", func(ctx context.Context) { s.Profile.Run(ctx, time.Minute) })
+
+	// Weekly unread digest emails (opt-in via user settings)
+	s.Digest = service.NewDigestService(repos.User, repos.Conversation, repos.Message, a.Mailer, rdb)
+	lc.Go("digests", s.Digest.Run)
+
+	// Runtime feature flags, changed through the admin API
+	s.Flag = service.NewFlagService(rdb)
+
+	// SCIM directory sync: identity providers provision users and group conversations
+	s.SCIM = service.NewSCIMService(repos.User, repos.Conversation, s.MembershipCache, s.NotificationCenter, rdb, a.Hub, a.JWT.Expiry())
+
+	// LDAP / Active Directory: password sign-in against the directory and a nightly sync (disabled without a URL)
+	s.LDAP = service.NewLDAPService(service.LDAPSettings{
+		URL:             cfg.LDAP.URL,
+		StartTLS:        cfg.LDAP.StartTLS,
+		BindDN:          cfg.LDAP.BindDN,
+		BindPassword:    cfg.LDAP.BindPassword,
+		BaseDN:          cfg.LDAP.BaseDN,
+		UserFilter:      cfg.LDAP.UserFilter,
+		AttrID:          cfg.LDAP.AttrID,
+		AttrEmail:       cfg.LDAP.AttrEmail,
+		AttrName:        cfg.LDAP.AttrName,
+		AttrDisplayName: cfg.LDAP.AttrDisplayName,
+		GroupFilter:     cfg.LDAP.GroupFilter,
+		GroupMemberAttr: cfg.LDAP.GroupMemberAttr,
+		SyncHour:        cfg.LDAP.SyncHour,
+	}, repos.User, repos.Conversation, s.SCIM, rdb)
+	if s.LDAP.Enabled() {
+		s.Auth.UseLDAP(s.LDAP)
+		lc.Go("ldap sync", s.LDAP.Run)
+		log.Printf("📇 LDAP sign-in enabled with %s", cfg.LDAP.URL)
+	}
+
+	// Enterprise single sign-on through an OpenID Connect provider (disabled without an issuer)
+	var ssoProvider *oidc.Provider
+	if cfg.SSO.Issuer != "" {
+		ssoProvider = oidc.New(oidc.Config{
+			Issuer:       cfg.SSO.Issuer,
+			ClientID:     cfg.SSO.ClientID,
+			ClientSecret: cfg.SSO.ClientSecret,
+			RedirectURL:  cfg.SSO.RedirectURL,
+			Scopes:       cfg.SSO.Scopes,
+		})
+		log.Printf("🔑 Single sign-on enabled with %s", cfg.SSO.Issuer)
+	}
+	s.SSO = service.NewSSOService(repos.User, s.Auth, a.JWT, rdb, ssoProvider, cfg.SSO.AllowedDomains)
+
+	// OAuth 2.0 / OpenID Connect provider for third-party apps (disabled without an issuer)
+	s.OAuth, err = service.NewOAuthService(service.OAuthSettings{
+		Issuer:          cfg.OAuth.Issuer,
+		AuthorizeURL:    cfg.OAuth.AuthorizeURL,
+		SigningKey:      cfg.OAuth.SigningKey,
+		AccessTokenTTL:  cfg.OAuth.AccessTokenTTL,
+		RefreshTokenTTL: cfg.OAuth.RefreshTokenTTL,
+	}, repos.OAuth, repos.User, a.JWT, rdb)
+	if err != nil {
+		return fmt.Errorf("OAuth provider: %w", err)
+	}
+	if s.OAuth.Enabled() {
+		lc.Go("oauth cleanup", s.OAuth.Run)
+		log.Printf("🔑 OAuth provider enabled as %s", cfg.OAuth.Issuer)
+	}
+	s.PersonalToken = service.NewPersonalTokenService(repos.PersonalToken, repos.User)
+
+	// Message retention policies, purged in the background and audited
+	s.Audit = service.NewAuditService(repos.Audit)
+	s.Retention = service.NewRetentionService(repos.Retention, repos.Conversation, repos.Blob, minioStorage, s.Audit)
+	lc.Go("retention", func(ctx context.Context) { s.Retention.Run(ctx, cfg.Retention.PurgeInterval) })
+	s.Compliance = service.NewComplianceService(repos.LegalHold, repos.Message, repos.Conversation, repos.User, s.Audit)
+	s.Call = service.NewCallService(repos.Call, repos.Conversation, s.MembershipCache, rdb)
+	s.Template = service.NewTemplateService(repos.Template, s.Chat)
+	s.Call.UseGeoIP(geo)
+	return nil
+}
+
+// newHub builds the WebSocket hub, with Redis Pub/Sub for horizontal scaling,
+// and the presence service, which persists status changes and emits
+// privacy-aware online/offline events
+func (a *App) newHub() {
+	cfg, s := a.Config, a.Services
+	a.Hub = ws.NewHub(a.Redis, cfg.WebSocket.SendQueueSize, func(userID uuid.UUID, online bool) {
+		s.Presence.StatusChanged(userID, online)
+	})
+	s.Presence = service.NewPresenceService(a.Repositories.User, a.Hub, a.Redis, cfg.WebSocket.IdleTimeout)
+	s.Auth.UseHub(a.Hub)
+	a.Hub.UseErrorReporter(a.Reporter)
+	a.Hub.UseCompression(ws.Compression{
+		Enabled:   cfg.WebSocket.CompressionEnabled,
+		Level:     cfg.WebSocket.CompressionLevel,
+		Threshold: cfg.WebSocket.CompressionThreshold,
+	})
+	a.Hub.UseKeepalive(ws.Keepalive{
+		PingInterval:   cfg.WebSocket.PingInterval,
+		PongTimeout:    cfg.WebSocket.PongTimeout,
+		MaxMessageSize: int64(cfg.WebSocket.MaxMessageSize),
+	})
+	a.Hub.UseConnectionLimit(cfg.WebSocket.MaxConnectionsPerUser)
+	a.Hub.UseRedisFallback(ws.RedisFallback{
+		SingleInstance: cfg.WebSocket.SingleInstance,
+		BufferSize:     cfg.WebSocket.RedisBufferSize,
+		BufferMaxAge:   cfg.WebSocket.RedisBufferMaxAge,
+	})
+	if cfg.WebSocket.ValidateEvents {
+		validator, err := ws.NewEventValidator()
+		if err != nil {
+			log.Printf("⚠️  WS event validation disabled: %v", err)
+		} else {
+			a.Hub.UseEventValidator(validator)
+			log.Println("✅ Validating outgoing WS events against events.schema.json")
+		}
+	}
+
+	a.Lifecycle.Go("websocket hub", a.Hub.Run)
+
+	// Reset online flags left behind by crashed instances, then keep them in sync
+	a.Lifecycle.Go("presence", func(ctx context.Context) { s.Presence.Run(ctx, time.Minute) })
+}
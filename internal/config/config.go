@@ -1,8 +1,12 @@
 package config
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,29 +15,74 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	App      AppConfig
-	DB       DBConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
-	MinIO    MinIOConfig
-	CORS     CORSConfig
-	SMTP     SMTPConfig
-	Google   GoogleConfig
-	Firebase FirebaseConfig
+	App          AppConfig
+	DB           DBConfig
+	Redis        RedisConfig
+	Startup      StartupConfig
+	HTTP         HTTPConfig
+	TLS          TLSConfig
+	JWT          JWTConfig
+	MinIO        MinIOConfig
+	CORS         CORSConfig
+	Security     SecurityConfig
+	AuthCookie   AuthCookieConfig
+	SMTP         SMTPConfig
+	Mail         MailConfig
+	Google       GoogleConfig
+	Firebase     FirebaseConfig
+	APNs         APNsConfig
+	VAPID        VAPIDConfig
+	Transcode    TranscodeConfig
+	Compression  CompressionConfig
+	SCIM         SCIMConfig
+	SSO          SSOConfig
+	LDAP         LDAPConfig
+	Secrets      SecretsConfig
+	Errors       ErrorReportingConfig
+	WebSocket    WebSocketConfig
+	Export       ExportConfig
+	Conversation ConversationConfig
+	Retention    RetentionConfig
+	Analytics    AnalyticsConfig
+	Limits       LimitsConfig
+	Import       ImportConfig
+	Matrix       MatrixConfig
+	ReplyMail    ReplyMailConfig
+	OpenSearch   OpenSearchConfig
+	GeoIP        GeoIPConfig
+	LoginAlert   LoginAlertConfig
+	Signup       SignupConfig
+	OTP          OTPConfig
+	MagicLink    MagicLinkConfig
+	OAuth        OAuthConfig
+
+	secrets *secretRefs // settings read from the secrets backend, for rotation
 }
 
 type AppConfig struct {
-	Env  string
-	Port string
+	Env         string
+	Port        string
+	AdminEmails []string // users allowed to access /admin endpoints
+	Release     string   // version or commit reported with errors
 }
 
 type DBConfig struct {
 	Host     string
 	Port     string
 	User     string
-	Password string
+	Password string `config:"secret"`
 	Name     string
 	SSLMode  string
+
+	// Connection pool, applied to the primary and every replica
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// ReplicaDSNs are read replicas for read-heavy queries (message history,
+	// conversation lists, search); writes always go to the primary
+	ReplicaDSNs []string `config:"secret"`
 }
 
 // DSN returns the PostgreSQL connection string
@@ -57,7 +106,7 @@ func (d DBConfig) URL() string {
 type RedisConfig struct {
 	Host     string
 	Port     string
-	Password string
+	Password string `config:"secret"`
 }
 
 // Addr returns the Redis address
@@ -65,16 +114,64 @@ func (r RedisConfig) Addr() string {
 	return r.Host + ":" + r.Port
 }
 
+// StartupConfig controls how the server waits for PostgreSQL and Redis at
+// startup instead of exiting when they aren't up yet
+type StartupConfig struct {
+	Timeout        time.Duration // give up (and exit) after waiting this long; 0 waits forever
+	AttemptTimeout time.Duration // per connection attempt
+	RetryInitial   time.Duration // wait after the first failed attempt, doubling from there
+	RetryMax       time.Duration
+
+	ServeHealth   bool // answer /health (and 503 elsewhere) on APP_PORT while waiting
+	RedisOptional bool // start without Redis after Timeout, with the WebSocket hub degraded
+}
+
+// HTTPConfig limits how long and how much clients may take, so slow or
+// abusive ones (slowloris and the like) can't hold connections open
+type HTTPConfig struct {
+	ReadHeaderTimeout time.Duration // to send the request headers
+	ReadTimeout       time.Duration // to send the whole request; 0 = no limit
+	WriteTimeout      time.Duration // from the end of the headers to the end of the response; 0 = no limit
+	IdleTimeout       time.Duration // between requests on a kept-alive connection
+	TransferTimeout   time.Duration // replaces the read and write timeouts for uploads, imports and downloads
+	MaxHeaderBytes    int
+
+	HTTP2 bool // HTTP/2 over TLS
+	H2C   bool // HTTP/2 without TLS (prior knowledge), e.g. behind a proxy speaking h2c
+}
+
+// TLSConfig serves HTTPS on APP_PORT, from certificate files or with
+// certificates obtained from Let's Encrypt. Without either the server speaks
+// plain HTTP, e.g. behind a TLS-terminating proxy.
+type TLSConfig struct {
+	CertFile string // PEM certificate chain, reloaded when it changes
+	KeyFile  string
+
+	AutocertDomains  []string // obtain certificates for these host names from Let's Encrypt
+	AutocertEmail    string   // contact for the ACME account, told about expiry problems
+	AutocertCacheDir string   // where certificates are kept across restarts
+
+	HTTPPort string // also listen here with plain HTTP, redirecting to HTTPS and answering ACME challenges; empty = off
+}
+
+// Enabled reports whether the server speaks HTTPS
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || len(c.AutocertDomains) > 0
+}
+
 type JWTConfig struct {
-	Secret string
+	Secret string `config:"secret"`
 	Expiry time.Duration
+
+	// Every sign-in ends the user's other sessions (users can also opt in one by one)
+	SingleSession bool
 }
 
 type MinIOConfig struct {
 	Endpoint  string
 	PublicURL string
 	AccessKey string
-	SecretKey string
+	SecretKey string `config:"secret"`
 	Bucket    string
 	UseSSL    bool
 }
@@ -83,40 +180,329 @@ type CORSConfig struct {
 	Origins []string
 }
 
+// SecurityConfig sets the security headers sent with every response
+type SecurityConfig struct {
+	HSTSMaxAge            time.Duration // Strict-Transport-Security on HTTPS requests; 0 = not sent
+	HSTSIncludeSubdomains bool
+	ReferrerPolicy        string
+}
+
+// AuthCookieConfig is the optional cookie-based auth mode: browser clients
+// signing in with X-Session-Mode: cookie keep the token in an HttpOnly cookie
+// instead of localStorage, with double-submit CSRF protection
+type AuthCookieConfig struct {
+	Enabled  bool
+	Name     string // of the session cookie; the CSRF cookie is Name + "_csrf"
+	Domain   string // e.g. example.com to share the CSRF cookie with chat.example.com; empty = the API's host only
+	Secure   bool
+	SameSite string // lax, strict or none
+}
+
 type SMTPConfig struct {
 	Host     string
 	Port     string
 	Username string
-	Password string
+	Password string `config:"secret"`
 	From     string
 	FromName string
+	TLSMode  string // auto, none, starttls, tls
+	Timeout  time.Duration
+	PoolSize int
+}
+
+// MailConfig selects the email provider and holds API-based provider credentials
+// (SMTP settings live in SMTPConfig)
+type MailConfig struct {
+	Provider string // smtp, ses, sendgrid, mailgun
+
+	SESRegion          string
+	SESAccessKeyID     string
+	SESSecretAccessKey string `config:"secret"`
+
+	SendGridAPIKey string `config:"secret"`
+
+	MailgunDomain  string
+	MailgunAPIKey  string `config:"secret"`
+	MailgunBaseURL string
 }
 
 type GoogleConfig struct {
 	ClientID     string
-	ClientSecret string
+	ClientSecret string `config:"secret"`
 }
 
 type FirebaseConfig struct {
 	CredentialsFile string
 }
 
-// Load reads configuration from .env file and environment variables
-func Load() *Config {
+// VAPIDConfig holds Web Push application server keys (generate with `go run ./cmd/vapid`)
+type VAPIDConfig struct {
+	PublicKey  string
+	PrivateKey string `config:"secret"`
+	Subject    string
+}
+
+// APNsConfig enables direct APNs delivery for iOS devices (token-based .p8 auth)
+type APNsConfig struct {
+	KeyFile  string
+	KeyID    string
+	TeamID   string
+	BundleID string
+	Sandbox  bool
+}
+
+type TranscodeConfig struct {
+	Enabled     bool
+	FFmpegPath  string
+	FFprobePath string
+	Workers     int
+}
+
+// CompressionConfig controls gzip/deflate compression of API responses
+type CompressionConfig struct {
+	Enabled      bool
+	Level        int      // 1 (fastest) to 9 (smallest); -1 is the gzip default
+	MinSize      int      // smaller bodies are sent uncompressed
+	ContentTypes []string // e.g. application/json, text/*
+}
+
+// SCIMConfig enables the SCIM 2.0 provisioning API for an identity provider
+type SCIMConfig struct {
+	Token string `config:"secret"` // long-lived bearer token given to the identity provider; empty disables SCIM
+}
+
+// SSOConfig enables enterprise single sign-on through an OpenID Connect provider
+type SSOConfig struct {
+	Issuer         string // e.g. https://login.microsoftonline.com/<tenant>/v2.0; empty disables SSO
+	ClientID       string
+	ClientSecret   string   `config:"secret"`
+	RedirectURL    string   // the API's callback, e.g. https://api.example.com/api/v1/auth/sso/callback
+	Scopes         []string // in addition to openid
+	AllowedDomains []string // email domains allowed to sign in; empty allows any
+	FrontendURL    string   // where the callback sends the browser with a one-time code
+}
+
+// LDAPConfig verifies passwords against an LDAP or Active Directory server
+// and syncs its users and groups nightly
+type LDAPConfig struct {
+	URL          string // ldap://host or ldaps://host; empty disables LDAP
+	StartTLS     bool   // upgrade an ldap:// connection with StartTLS
+	BindDN       string // service account that searches the directory
+	BindPassword string `config:"secret"`
+	BaseDN       string // where users and groups are searched
+	UserFilter   string // which entries are users, e.g. (objectClass=person)
+
+	// Attributes mapped to user fields
+	AttrID          string // stable ID: entryUUID, or objectGUID on Active Directory
+	AttrEmail       string
+	AttrName        string
+	AttrDisplayName string
+
+	GroupFilter     string // which groups become group conversations; empty maps none
+	GroupMemberAttr string // group attribute listing member DNs
+	SyncHour        int    // hour of the nightly sync, UTC
+}
+
+// WebSocketConfig tunes WebSocket connections
+type WebSocketConfig struct {
+	SendQueueSize int // outbound events buffered per connection before the overflow policies apply
+
+	// permessage-deflate, for clients that offer it
+	CompressionEnabled   bool
+	CompressionLevel     int // 1 (fastest) to 9 (smallest)
+	CompressionThreshold int // frames smaller than this many bytes are sent uncompressed
+
+	// Heartbeat: connections silent for a PingInterval are warned, for PongTimeout closed
+	PingInterval   time.Duration
+	PongTimeout    time.Duration
+	MaxMessageSize int // bytes accepted per inbound message
+
+	MaxConnectionsPerUser int // per instance; more close the oldest. 0 means unlimited
+
+	// How often live connections' tokens are checked for expiry and revocation
+	AuthCheckInterval time.Duration
+
+	// Connected users without activity for this long show as away
+	IdleTimeout time.Duration
+
+	// Check outgoing events against internal/ws/events.schema.json (development)
+	ValidateEvents bool
+
+	// While Redis is unreachable events reach this instance's connections at
+	// once and are buffered for the others, unless this is the only instance
+	SingleInstance    bool
+	RedisBufferSize   int           // events buffered for other instances
+	RedisBufferMaxAge time.Duration // older buffered events are discarded when Redis is back
+}
+
+// ExportConfig controls conversation exports
+type ExportConfig struct {
+	LinkExpiry time.Duration // lifetime of a signed download link (at most 7 days)
+	Retention  time.Duration // exports and their files are deleted after this
+}
+
+// ConversationConfig controls what happens to deleted conversations
+type ConversationConfig struct {
+	Retention time.Duration // deleted conversations can be restored this long, then they're purged
+}
+
+// RetentionConfig controls the purge of messages past their retention policy;
+// the policies themselves are set through the admin API
+type RetentionConfig struct {
+	PurgeInterval time.Duration
+}
+
+// AnalyticsConfig controls the usage statistics behind GET /admin/stats
+type AnalyticsConfig struct {
+	FlushInterval time.Duration // counts are buffered in memory this long before they're written
+}
+
+// LimitsConfig caps what a single user can create, against spam groups and
+// mass messaging; 0 means unlimited
+type LimitsConfig struct {
+	GroupMembers  int // members of a group, including its creator
+	GroupsPerUser int // groups a user has created and not deleted
+	DirectsPerDay int // new direct conversations a user can start in 24 hours
+	MessageLength int // characters of a message's text
+	Pins          int // pinned messages of a conversation
+}
+
+// ImportConfig controls chat imports from other apps
+type ImportConfig struct {
+	MaxSizeMB int // largest export archive accepted
+}
+
+// MatrixConfig bridges conversations to Matrix rooms as an application service.
+// The values must match the registration file installed on the homeserver.
+type MatrixConfig struct {
+	HomeserverURL string // client-server API, e.g. https://matrix.example.com; empty disables the bridge
+	ServerName    string // homeserver's domain in user IDs, e.g. example.com
+	ASToken       string `config:"secret"` // as_token: the bridge authenticates to the homeserver with it
+	HSToken       string `config:"secret"` // hs_token: the homeserver authenticates to the bridge with it
+	UserPrefix    string // localpart prefix of the members' virtual users
+	BotLocalpart  string // sender_localpart of the registration
+}
+
+// OpenSearchConfig moves message search to an OpenSearch (or Elasticsearch)
+// index, for deployments too large for Postgres full-text search
+type OpenSearchConfig struct {
+	URL      string // e.g. https://search.example.com:9200; empty searches Postgres
+	Index    string
+	Username string // basic auth; empty for none
+	Password string `config:"secret"`
+}
+
+// ReplyMailConfig enables reply by email: message notification emails get a
+// reply-to address on Domain, whose mail the provider forwards to the inbound webhook
+type ReplyMailConfig struct {
+	Domain       string        // e.g. reply.example.com; empty disables message emails
+	InboundToken string        `config:"secret"` // shared with the mail provider's inbound webhook
+	AddressTTL   time.Duration // how long a reply address keeps working
+	Throttle     time.Duration // at most one email per member and conversation in this window
+}
+
+// GeoIPConfig locates sign-in IP addresses with MaxMind's GeoIP2 / GeoLite2
+// web service for login history and new-device alerts
+type GeoIPConfig struct {
+	AccountID  string // empty disables lookups
+	LicenseKey string `config:"secret"`
+	Host       string // geolite.info for GeoLite2, geoip.maxmind.com for GeoIP2
+}
+
+// LoginAlertConfig tunes the alerts sent on sign-ins from new devices
+type LoginAlertConfig struct {
+	RevokeURL string        // app page that posts ?token=... to /auth/login-alerts/revoke; empty leaves the link out
+	RevokeTTL time.Duration // how long the "this wasn't me" link keeps working
+}
+
+// SignupConfig restricts who may register with email/password or Google;
+// SSO, SCIM and LDAP accounts are not affected
+type SignupConfig struct {
+	AllowedDomains  []string // only these email domains may sign up; empty allows any
+	BlockedDomains  []string
+	BlockDisposable bool // reject throwaway email domains (pkg/disposable)
+	InviteOnly      bool // require an invitation code issued at /admin/invitations
+}
+
+// OTPConfig hardens the email verification and password reset codes
+type OTPConfig struct {
+	Secret           string `config:"secret"` // HMAC key of the stored code hashes; JWT_SECRET when empty
+	MaxAttempts      int    // wrong guesses before a code stops working
+	VerifyRateLimit  int    // code verifications per IP per VerifyRateWindow; 0 disables the limit
+	VerifyRateWindow time.Duration
+}
+
+// MagicLinkConfig enables passwordless sign-in links
+type MagicLinkConfig struct {
+	URL string        // app page that posts ?token=... to /auth/magic-link/verify; empty disables links
+	TTL time.Duration // how long a link works
+}
+
+// OAuthConfig lets GoTalk act as an OAuth 2.0 / OpenID Connect provider for
+// third-party apps
+type OAuthConfig struct {
+	Issuer          string        // public base URL of the API, e.g. https://api.example.com; empty disables it
+	AuthorizeURL    string        // the frontend's consent page that apps send users to
+	SigningKey      string        `config:"secret"` // PEM RSA private key that signs ID tokens
+	AccessTokenTTL  time.Duration // at most JWT_EXPIRY
+	RefreshTokenTTL time.Duration
+}
+
+// ErrorReportingConfig sends recovered panics to Sentry and/or Rollbar; with
+// neither set they are only logged
+type ErrorReportingConfig struct {
+	SentryDSN    string `config:"secret"`
+	RollbarToken string `config:"secret"`
+}
+
+// Load reads configuration from environment variables, the .env file and the
+// YAML file named by CONFIG_FILE, in that order of precedence, resolves secret
+// references (see SecretsConfig), then validates it.
+// In production any problem is returned as an error so the server refuses to
+// start; elsewhere problems are logged and the defaults are used.
+func Load() (*Config, error) {
 	// Load .env file (ignore error if not exists - e.g. in Docker)
 	if err := godotenv.Load(); err != nil {
 		log.Println("⚠️  No .env file found, reading from environment variables")
 	}
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadFile(path); err != nil {
+			return nil, err
+		}
+		log.Printf("📄 Loaded configuration from %s", path)
+	}
 
-	jwtExpiry, err := time.ParseDuration(getEnv("JWT_EXPIRY", "24h"))
-	if err != nil {
-		jwtExpiry = 24 * time.Hour
+	l := &loader{}
+	cfg := l.load()
+	if err := cfg.resolveSecrets(context.Background()); err != nil {
+		return nil, err
 	}
 
+	problems := append(l.problems, cfg.Validate()...)
+	if len(problems) == 0 {
+		return cfg, nil
+	}
+	if cfg.App.Env == "production" {
+		return nil, errors.Join(problems...)
+	}
+	for _, p := range problems {
+		log.Printf("⚠️  Config: %v", p)
+	}
+	return cfg, nil
+}
+
+// loader reads typed values from the environment, recording malformed ones
+type loader struct {
+	problems []error
+}
+
+func (l *loader) load() *Config {
 	return &Config{
 		App: AppConfig{
-			Env:  getEnv("APP_ENV", "development"),
-			Port: getEnv("APP_PORT", "8080"),
+			Env:         getEnv("APP_ENV", "development"),
+			Port:        getEnv("APP_PORT", "8080"),
+			AdminEmails: strings.Split(getEnv("ADMIN_EMAILS", ""), ","),
+			Release:     getEnv("APP_RELEASE", ""),
 		},
 		DB: DBConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -125,15 +511,49 @@ func Load() *Config {
 			Password: getEnv("DB_PASSWORD", "gotalk"),
 			Name:     getEnv("DB_NAME", "gotalk"),
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
+
+			MaxOpenConns:    l.int("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    l.int("DB_MAX_IDLE_CONNS", 10),
+			ConnMaxLifetime: l.duration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+			ConnMaxIdleTime: l.duration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+			ReplicaDSNs:     getList("DB_REPLICA_DSNS"),
 		},
 		Redis: RedisConfig{
 			Host:     getEnv("REDIS_HOST", "localhost"),
 			Port:     getEnv("REDIS_PORT", "6379"),
 			Password: getEnv("REDIS_PASSWORD", ""),
 		},
+		Startup: StartupConfig{
+			Timeout:        l.duration("STARTUP_TIMEOUT", 2*time.Minute),
+			AttemptTimeout: l.duration("STARTUP_ATTEMPT_TIMEOUT", 5*time.Second),
+			RetryInitial:   l.duration("STARTUP_RETRY_INITIAL", 500*time.Millisecond),
+			RetryMax:       l.duration("STARTUP_RETRY_MAX", 15*time.Second),
+			ServeHealth:    l.bool("STARTUP_SERVE_HEALTH", true),
+			RedisOptional:  l.bool("STARTUP_REDIS_OPTIONAL", false),
+		},
+		HTTP: HTTPConfig{
+			ReadHeaderTimeout: l.duration("HTTP_READ_HEADER_TIMEOUT", 10*time.Second),
+			ReadTimeout:       l.duration("HTTP_READ_TIMEOUT", time.Minute),
+			WriteTimeout:      l.duration("HTTP_WRITE_TIMEOUT", time.Minute),
+			IdleTimeout:       l.duration("HTTP_IDLE_TIMEOUT", 2*time.Minute),
+			TransferTimeout:   l.duration("HTTP_TRANSFER_TIMEOUT", 30*time.Minute),
+			MaxHeaderBytes:    l.int("HTTP_MAX_HEADER_BYTES", 64<<10),
+			HTTP2:             l.bool("HTTP_HTTP2", true),
+			H2C:               l.bool("HTTP_H2C", false),
+		},
+		TLS: TLSConfig{
+			CertFile:         getEnv("TLS_CERT_FILE", ""),
+			KeyFile:          getEnv("TLS_KEY_FILE", ""),
+			AutocertDomains:  getList("TLS_AUTOCERT_DOMAINS"),
+			AutocertEmail:    getEnv("TLS_AUTOCERT_EMAIL", ""),
+			AutocertCacheDir: getEnv("TLS_AUTOCERT_CACHE_DIR", "certs"),
+			HTTPPort:         getEnv("TLS_HTTP_PORT", ""),
+		},
 		JWT: JWTConfig{
 			Secret: getEnv("JWT_SECRET", "default-secret"),
-			Expiry: jwtExpiry,
+			Expiry: l.duration("JWT_EXPIRY", 24*time.Hour),
+
+			SingleSession: l.bool("JWT_SINGLE_SESSION", false),
 		},
 		MinIO: MinIOConfig{
 			Endpoint:  getEnv("MINIO_ENDPOINT", "localhost:9000"),
@@ -141,11 +561,23 @@ func Load() *Config {
 			AccessKey: getEnv("MINIO_ACCESS_KEY", "minioadmin"),
 			SecretKey: getEnv("MINIO_SECRET_KEY", "minioadmin"),
 			Bucket:    getEnv("MINIO_BUCKET", "gotalk-media"),
-			UseSSL:    getEnv("MINIO_USE_SSL", "false") == "true",
+			UseSSL:    l.bool("MINIO_USE_SSL", false),
 		},
 		CORS: CORSConfig{
 			Origins: strings.Split(getEnv("CORS_ORIGINS", "http://localhost:3000"), ","),
 		},
+		Security: SecurityConfig{
+			HSTSMaxAge:            l.duration("SECURITY_HSTS_MAX_AGE", 365*24*time.Hour),
+			HSTSIncludeSubdomains: l.bool("SECURITY_HSTS_INCLUDE_SUBDOMAINS", false),
+			ReferrerPolicy:        getEnv("SECURITY_REFERRER_POLICY", "no-referrer"),
+		},
+		AuthCookie: AuthCookieConfig{
+			Enabled:  l.bool("AUTH_COOKIE_ENABLED", false),
+			Name:     getEnv("AUTH_COOKIE_NAME", "gotalk_session"),
+			Domain:   getEnv("AUTH_COOKIE_DOMAIN", ""),
+			Secure:   l.bool("AUTH_COOKIE_SECURE", true),
+			SameSite: getEnv("AUTH_COOKIE_SAMESITE", "lax"),
+		},
 		SMTP: SMTPConfig{
 			Host:     getEnv("SMTP_HOST", "mailpit"),
 			Port:     getEnv("SMTP_PORT", "1025"),
@@ -153,6 +585,19 @@ func Load() *Config {
 			Password: getEnv("SMTP_PASSWORD", ""),
 			From:     getEnv("SMTP_FROM", "noreply@gotalk.local"),
 			FromName: getEnv("SMTP_FROM_NAME", "GoTalk"),
+			TLSMode:  getEnv("SMTP_TLS_MODE", "auto"),
+			Timeout:  l.duration("SMTP_TIMEOUT", 10*time.Second),
+			PoolSize: l.int("SMTP_POOL_SIZE", 2),
+		},
+		Mail: MailConfig{
+			Provider:           getEnv("MAIL_PROVIDER", "smtp"),
+			SESRegion:          getEnv("SES_REGION", ""),
+			SESAccessKeyID:     getEnv("SES_ACCESS_KEY_ID", ""),
+			SESSecretAccessKey: getEnv("SES_SECRET_ACCESS_KEY", ""),
+			SendGridAPIKey:     getEnv("SENDGRID_API_KEY", ""),
+			MailgunDomain:      getEnv("MAILGUN_DOMAIN", ""),
+			MailgunAPIKey:      getEnv("MAILGUN_API_KEY", ""),
+			MailgunBaseURL:     getEnv("MAILGUN_BASE_URL", ""),
 		},
 		Google: GoogleConfig{
 			ClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
@@ -161,6 +606,166 @@ func Load() *Config {
 		Firebase: FirebaseConfig{
 			CredentialsFile: getEnv("FIREBASE_CREDENTIALS_FILE", "firebase-adminsdk.json"),
 		},
+		APNs: APNsConfig{
+			KeyFile:  getEnv("APNS_KEY_FILE", ""),
+			KeyID:    getEnv("APNS_KEY_ID", ""),
+			TeamID:   getEnv("APNS_TEAM_ID", ""),
+			BundleID: getEnv("APNS_BUNDLE_ID", ""),
+			Sandbox:  l.bool("APNS_SANDBOX", false),
+		},
+		VAPID: VAPIDConfig{
+			PublicKey:  getEnv("VAPID_PUBLIC_KEY", ""),
+			PrivateKey: getEnv("VAPID_PRIVATE_KEY", ""),
+			Subject:    getEnv("VAPID_SUBJECT", "mailto:admin@gotalk.local"),
+		},
+		Transcode: TranscodeConfig{
+			Enabled:     l.bool("TRANSCODE_ENABLED", true),
+			FFmpegPath:  getEnv("FFMPEG_PATH", "ffmpeg"),
+			FFprobePath: getEnv("FFPROBE_PATH", "ffprobe"),
+			Workers:     l.int("TRANSCODE_WORKERS", 2),
+		},
+		Compression: CompressionConfig{
+			Enabled:      l.bool("COMPRESSION_ENABLED", true),
+			Level:        l.int("COMPRESSION_LEVEL", -1),
+			MinSize:      l.int("COMPRESSION_MIN_SIZE", 1024),
+			ContentTypes: strings.Split(getEnv("COMPRESSION_TYPES", "application/json,text/*,application/javascript"), ","),
+		},
+		SCIM: SCIMConfig{
+			Token: getEnv("SCIM_TOKEN", ""),
+		},
+		SSO: SSOConfig{
+			Issuer:         getEnv("SSO_ISSUER", ""),
+			ClientID:       getEnv("SSO_CLIENT_ID", ""),
+			ClientSecret:   getEnv("SSO_CLIENT_SECRET", ""),
+			RedirectURL:    getEnv("SSO_REDIRECT_URL", ""),
+			Scopes:         strings.Split(getEnv("SSO_SCOPES", "email,profile"), ","),
+			AllowedDomains: strings.Split(getEnv("SSO_ALLOWED_DOMAINS", ""), ","),
+			FrontendURL:    getEnv("SSO_FRONTEND_URL", ""),
+		},
+		LDAP: LDAPConfig{
+			URL:          getEnv("LDAP_URL", ""),
+			StartTLS:     l.bool("LDAP_START_TLS", false),
+			BindDN:       getEnv("LDAP_BIND_DN", ""),
+			BindPassword: getEnv("LDAP_BIND_PASSWORD", ""),
+			BaseDN:       getEnv("LDAP_BASE_DN", ""),
+			UserFilter:   getEnv("LDAP_USER_FILTER", "(objectClass=person)"),
+
+			AttrID:          getEnv("LDAP_ATTR_ID", "entryUUID"),
+			AttrEmail:       getEnv("LDAP_ATTR_EMAIL", "mail"),
+			AttrName:        getEnv("LDAP_ATTR_NAME", "cn"),
+			AttrDisplayName: getEnv("LDAP_ATTR_DISPLAY_NAME", "displayName"),
+
+			GroupFilter:     getEnv("LDAP_GROUP_FILTER", ""),
+			GroupMemberAttr: getEnv("LDAP_GROUP_MEMBER_ATTR", "member"),
+			SyncHour:        l.int("LDAP_SYNC_HOUR", 2),
+		},
+		Secrets: SecretsConfig{
+			VaultAddr:          getEnv("VAULT_ADDR", ""),
+			VaultToken:         getEnv("VAULT_TOKEN", ""),
+			VaultNamespace:     getEnv("VAULT_NAMESPACE", ""),
+			AWSRegion:          getEnv("AWS_REGION", ""),
+			AWSAccessKeyID:     getEnv("AWS_ACCESS_KEY_ID", ""),
+			AWSSecretAccessKey: getEnv("AWS_SECRET_ACCESS_KEY", ""),
+			AWSSessionToken:    getEnv("AWS_SESSION_TOKEN", ""),
+			RefreshInterval:    l.duration("SECRETS_REFRESH_INTERVAL", 0),
+		},
+		WebSocket: WebSocketConfig{
+			SendQueueSize:        l.int("WS_SEND_QUEUE_SIZE", 256),
+			CompressionEnabled:   l.bool("WS_COMPRESSION_ENABLED", true),
+			CompressionLevel:     l.int("WS_COMPRESSION_LEVEL", 1),
+			CompressionThreshold: l.int("WS_COMPRESSION_THRESHOLD", 512),
+			PingInterval:         l.duration("WS_PING_INTERVAL", 20*time.Second),
+			PongTimeout:          l.duration("WS_PONG_TIMEOUT", 45*time.Second),
+			MaxMessageSize:       l.int("WS_MAX_MESSAGE_SIZE", 512*1024),
+
+			MaxConnectionsPerUser: l.int("WS_MAX_CONNECTIONS_PER_USER", 10),
+			AuthCheckInterval:     l.duration("WS_AUTH_CHECK_INTERVAL", 30*time.Second),
+			IdleTimeout:           l.duration("WS_IDLE_TIMEOUT", 5*time.Minute),
+			ValidateEvents:        l.bool("WS_VALIDATE_EVENTS", getEnv("APP_ENV", "development") == "development"),
+			SingleInstance:        l.bool("WS_SINGLE_INSTANCE", false),
+			RedisBufferSize:       l.int("WS_REDIS_BUFFER_SIZE", 10000),
+			RedisBufferMaxAge:     l.duration("WS_REDIS_BUFFER_MAX_AGE", 2*time.Minute),
+		},
+		Export: ExportConfig{
+			LinkExpiry: l.duration("EXPORT_LINK_EXPIRY", time.Hour),
+			Retention:  l.duration("EXPORT_RETENTION", 24*time.Hour),
+		},
+		Conversation: ConversationConfig{
+			Retention: l.duration("CONVERSATION_RETENTION", 30*24*time.Hour),
+		},
+		Retention: RetentionConfig{
+			PurgeInterval: l.duration("RETENTION_PURGE_INTERVAL", time.Hour),
+		},
+		Analytics: AnalyticsConfig{
+			FlushInterval: l.duration("ANALYTICS_FLUSH_INTERVAL", 30*time.Second),
+		},
+		Limits: LimitsConfig{
+			GroupMembers:  l.int("LIMITS_GROUP_MEMBERS", 1000),
+			GroupsPerUser: l.int("LIMITS_GROUPS_PER_USER", 100),
+			DirectsPerDay: l.int("LIMITS_DIRECTS_PER_DAY", 100),
+			MessageLength: l.int("LIMITS_MESSAGE_LENGTH", 4000),
+			Pins:          l.int("LIMITS_PINS", 50),
+		},
+		Import: ImportConfig{
+			MaxSizeMB: l.int("IMPORT_MAX_SIZE_MB", 500),
+		},
+		Matrix: MatrixConfig{
+			HomeserverURL: getEnv("MATRIX_HOMESERVER_URL", ""),
+			ServerName:    getEnv("MATRIX_SERVER_NAME", ""),
+			ASToken:       getEnv("MATRIX_AS_TOKEN", ""),
+			HSToken:       getEnv("MATRIX_HS_TOKEN", ""),
+			UserPrefix:    getEnv("MATRIX_USER_PREFIX", "gotalk_"),
+			BotLocalpart:  getEnv("MATRIX_BOT_LOCALPART", "gotalkbot"),
+		},
+		OpenSearch: OpenSearchConfig{
+			URL:      getEnv("OPENSEARCH_URL", ""),
+			Index:    getEnv("OPENSEARCH_INDEX", "gotalk-messages"),
+			Username: getEnv("OPENSEARCH_USERNAME", ""),
+			Password: getEnv("OPENSEARCH_PASSWORD", ""),
+		},
+		ReplyMail: ReplyMailConfig{
+			Domain:       getEnv("REPLY_MAIL_DOMAIN", ""),
+			InboundToken: getEnv("REPLY_MAIL_INBOUND_TOKEN", ""),
+			AddressTTL:   l.duration("REPLY_MAIL_ADDRESS_TTL", 30*24*time.Hour),
+			Throttle:     l.duration("REPLY_MAIL_THROTTLE", 15*time.Minute),
+		},
+		GeoIP: GeoIPConfig{
+			AccountID:  getEnv("GEOIP_ACCOUNT_ID", ""),
+			LicenseKey: getEnv("GEOIP_LICENSE_KEY", ""),
+			Host:       getEnv("GEOIP_HOST", "geolite.info"),
+		},
+		LoginAlert: LoginAlertConfig{
+			RevokeURL: getEnv("LOGIN_ALERT_REVOKE_URL", ""),
+			RevokeTTL: l.duration("LOGIN_ALERT_REVOKE_TTL", 7*24*time.Hour),
+		},
+		Signup: SignupConfig{
+			AllowedDomains:  strings.Split(getEnv("SIGNUP_ALLOWED_DOMAINS", ""), ","),
+			BlockedDomains:  strings.Split(getEnv("SIGNUP_BLOCKED_DOMAINS", ""), ","),
+			BlockDisposable: l.bool("SIGNUP_BLOCK_DISPOSABLE", false),
+			InviteOnly:      l.bool("SIGNUP_INVITE_ONLY", false),
+		},
+		OTP: OTPConfig{
+			Secret:           getEnv("OTP_SECRET", ""),
+			MaxAttempts:      l.int("OTP_MAX_ATTEMPTS", 5),
+			VerifyRateLimit:  l.int("OTP_VERIFY_RATE_LIMIT", 20),
+			VerifyRateWindow: l.duration("OTP_VERIFY_RATE_WINDOW", 15*time.Minute),
+		},
+		MagicLink: MagicLinkConfig{
+			URL: getEnv("MAGIC_LINK_URL", ""),
+			TTL: l.duration("MAGIC_LINK_TTL", 15*time.Minute),
+		},
+		OAuth: OAuthConfig{
+			Issuer:          getEnv("OAUTH_ISSUER", ""),
+			AuthorizeURL:    getEnv("OAUTH_AUTHORIZE_URL", ""),
+			SigningKey:      getEnv("OAUTH_SIGNING_KEY", ""),
+			AccessTokenTTL:  l.duration("OAUTH_ACCESS_TOKEN_TTL", time.Hour),
+			RefreshTokenTTL: l.duration("OAUTH_REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		},
+
+		Errors: ErrorReportingConfig{
+			SentryDSN:    getEnv("SENTRY_DSN", ""),
+			RollbarToken: getEnv("ROLLBAR_ACCESS_TOKEN", ""),
+		},
 	}
 }
 
@@ -170,3 +775,53 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getList splits a comma-separated variable, dropping empty entries
+func getList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func (l *loader) int(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		l.problems = append(l.problems, fmt.Errorf("%s: %q is not a number", key, value))
+		return fallback
+	}
+	return n
+}
+
+func (l *loader) bool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		l.problems = append(l.problems, fmt.Errorf("%s: %q is not true or false", key, value))
+		return fallback
+	}
+	return b
+}
+
+func (l *loader) duration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		l.problems = append(l.problems, fmt.Errorf("%s: %q is not a duration (e.g. 30s, 24h)", key, value))
+		return fallback
+	}
+	return d
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/quocanhngo/gotalk/pkg/secrets"
+)
+
+// SecretsConfig configures the backends that settings can reference instead of
+// holding a value, e.g. JWT_SECRET=vault://secret/data/gotalk#jwt_secret or
+// DB_PASSWORD=awssm://prod/gotalk#db_password
+type SecretsConfig struct {
+	VaultAddr      string
+	VaultToken     string `config:"secret"`
+	VaultNamespace string
+
+	AWSRegion          string
+	AWSAccessKeyID     string
+	AWSSecretAccessKey string `config:"secret"`
+	AWSSessionToken    string `config:"secret"`
+
+	RefreshInterval time.Duration // how often references are re-read for rotation; 0 disables
+}
+
+// secretRefs remembers which settings came from a secrets backend
+type secretRefs struct {
+	refs   map[string]string // setting name (e.g. JWT.Secret) -> reference
+	values map[string]string // setting name -> last resolved value
+}
+
+// resolver returns a resolver with the configured backends
+func (s SecretsConfig) resolver() *secrets.Resolver {
+	backends := map[string]secrets.Backend{}
+	if s.VaultAddr != "" {
+		backends[secrets.SchemeVault] = secrets.NewVault(secrets.VaultConfig{
+			Addr:      s.VaultAddr,
+			Token:     s.VaultToken,
+			Namespace: s.VaultNamespace,
+		})
+	}
+	if s.AWSRegion != "" {
+		backends[secrets.SchemeAWSSecretsManager] = secrets.NewAWSSecretsManager(secrets.AWSConfig{
+			Region:          s.AWSRegion,
+			AccessKeyID:     s.AWSAccessKeyID,
+			SecretAccessKey: s.AWSSecretAccessKey,
+			SessionToken:    s.AWSSessionToken,
+		})
+	}
+	return secrets.NewResolver(backends)
+}
+
+// resolveSecrets replaces every setting that holds a secret reference with the
+// referenced value
+func (c *Config) resolveSecrets(ctx context.Context) error {
+	refs := map[string]string{}
+	c.eachSetting(func(name string, v reflect.Value) {
+		if v.Kind() == reflect.String && secrets.IsRef(v.String()) {
+			refs[name] = v.String()
+		}
+	})
+	if len(refs) == 0 {
+		return nil
+	}
+
+	values, err := c.Secrets.resolver().ResolveAll(ctx, refs)
+	if err != nil {
+		return err
+	}
+	c.eachSetting(func(name string, v reflect.Value) {
+		if value, ok := values[name]; ok {
+			v.SetString(value)
+		}
+	})
+	c.secrets = &secretRefs{refs: refs, values: values}
+	log.Printf("🔐 Resolved %d settings from the secrets backend", len(refs))
+	return nil
+}
+
+// WatchSecrets re-reads the secret references every Secrets.RefreshInterval
+// until ctx is done and calls onChange with the setting's name (e.g.
+// "JWT.Secret") and new value when a secret was rotated
+func (c *Config) WatchSecrets(ctx context.Context, onChange func(name, value string)) {
+	if c.secrets == nil || c.Secrets.RefreshInterval <= 0 {
+		return
+	}
+	resolver := c.Secrets.resolver()
+	current := make(map[string]string, len(c.secrets.values))
+	for name, value := range c.secrets.values {
+		current[name] = value
+	}
+
+	ticker := time.NewTicker(c.Secrets.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			values, err := resolver.ResolveAll(ctx, c.secrets.refs)
+			if err != nil {
+				log.Printf("⚠️  Failed to refresh secrets: %v", err)
+				continue
+			}
+			names := make([]string, 0, len(values))
+			for name := range values {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if values[name] != current[name] {
+					current[name] = values[name]
+					onChange(name, values[name])
+				}
+			}
+		}
+	}
+}
+
+// eachSetting calls fn with every setting's name (Section.Field) and settable
+// value, skipping the secrets backend settings themselves
+func (c *Config) eachSetting(fn func(name string, v reflect.Value)) {
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		section := v.Type().Field(i)
+		if !section.IsExported() || section.Name == "Secrets" {
+			continue
+		}
+		for j := 0; j < v.Field(i).NumField(); j++ {
+			fn(section.Name+"."+section.Type.Field(j).Name, v.Field(i).Field(j))
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minSecretLength is the shortest JWT secret or provisioning token accepted in production
+const minSecretLength = 32
+
+// weakSecrets are the defaults and examples shipped with the repository
+var weakSecrets = map[string]bool{
+	"default-secret":            true,
+	"change-this-in-production": true,
+	"your_google_client_secret": true,
+	"gotalk":                    true,
+	"minioadmin":                true,
+	"password":                  true,
+	"secret":                    true,
+	"changeme":                  true,
+}
+
+// Validate reports settings the server can't run with: malformed origins,
+// ports, URLs and durations, and in production missing or weak secrets
+func (c *Config) Validate() []error {
+	var problems []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(validPort(c.App.Port), "APP_PORT: %q is not a port number", c.App.Port)
+	check(validPort(c.DB.Port), "DB_PORT: %q is not a port number", c.DB.Port)
+	check(validPort(c.Redis.Port), "REDIS_PORT: %q is not a port number", c.Redis.Port)
+	check(c.DB.MaxOpenConns >= 0, "DB_MAX_OPEN_CONNS: must not be negative (0 = unlimited), got %d", c.DB.MaxOpenConns)
+	check(c.DB.MaxIdleConns >= 0, "DB_MAX_IDLE_CONNS: must not be negative, got %d", c.DB.MaxIdleConns)
+	if c.DB.MaxOpenConns > 0 {
+		check(c.DB.MaxIdleConns <= c.DB.MaxOpenConns, "DB_MAX_IDLE_CONNS: %d is more than DB_MAX_OPEN_CONNS (%d)", c.DB.MaxIdleConns, c.DB.MaxOpenConns)
+	}
+	check(c.DB.ConnMaxLifetime >= 0, "DB_CONN_MAX_LIFETIME: must not be negative, got %s", c.DB.ConnMaxLifetime)
+	check(c.DB.ConnMaxIdleTime >= 0, "DB_CONN_MAX_IDLE_TIME: must not be negative, got %s", c.DB.ConnMaxIdleTime)
+	check(c.Startup.Timeout >= 0, "STARTUP_TIMEOUT: must not be negative (0 = wait forever), got %s", c.Startup.Timeout)
+	check(c.Startup.AttemptTimeout > 0, "STARTUP_ATTEMPT_TIMEOUT: must be positive, got %s", c.Startup.AttemptTimeout)
+	check(c.Startup.RetryInitial > 0, "STARTUP_RETRY_INITIAL: must be positive, got %s", c.Startup.RetryInitial)
+	check(c.Startup.RetryMax >= c.Startup.RetryInitial, "STARTUP_RETRY_MAX: must be at least STARTUP_RETRY_INITIAL (%s), got %s", c.Startup.RetryInitial, c.Startup.RetryMax)
+	check(!c.Startup.RedisOptional || c.Startup.Timeout > 0, "STARTUP_REDIS_OPTIONAL: needs a STARTUP_TIMEOUT to give up waiting for Redis after")
+	check(c.HTTP.ReadHeaderTimeout > 0, "HTTP_READ_HEADER_TIMEOUT: must be positive, got %s", c.HTTP.ReadHeaderTimeout)
+	check(c.HTTP.ReadTimeout >= 0, "HTTP_READ_TIMEOUT: must not be negative (0 = no limit), got %s", c.HTTP.ReadTimeout)
+	check(c.HTTP.WriteTimeout >= 0, "HTTP_WRITE_TIMEOUT: must not be negative (0 = no limit), got %s", c.HTTP.WriteTimeout)
+	check(c.HTTP.IdleTimeout > 0, "HTTP_IDLE_TIMEOUT: must be positive, got %s", c.HTTP.IdleTimeout)
+	check(c.HTTP.TransferTimeout >= 0, "HTTP_TRANSFER_TIMEOUT: must not be negative (0 = no limit), got %s", c.HTTP.TransferTimeout)
+	check(c.HTTP.MaxHeaderBytes >= 4<<10, "HTTP_MAX_HEADER_BYTES: must be at least 4096, got %d", c.HTTP.MaxHeaderBytes)
+	check((c.TLS.CertFile == "") == (c.TLS.KeyFile == ""), "TLS_CERT_FILE, TLS_KEY_FILE: set both or neither")
+	check(c.TLS.CertFile == "" || len(c.TLS.AutocertDomains) == 0, "TLS_AUTOCERT_DOMAINS: can't be combined with TLS_CERT_FILE")
+	check(len(c.TLS.AutocertDomains) == 0 || c.TLS.AutocertCacheDir != "", "TLS_AUTOCERT_CACHE_DIR: is required with TLS_AUTOCERT_DOMAINS")
+	if c.TLS.HTTPPort != "" {
+		check(validPort(c.TLS.HTTPPort), "TLS_HTTP_PORT: %q is not a port number", c.TLS.HTTPPort)
+		check(c.TLS.Enabled(), "TLS_HTTP_PORT: needs TLS_CERT_FILE or TLS_AUTOCERT_DOMAINS")
+		check(c.TLS.HTTPPort != c.App.Port, "TLS_HTTP_PORT: must differ from APP_PORT (%s)", c.App.Port)
+	}
+	check(c.JWT.Expiry > 0, "JWT_EXPIRY: must be positive, got %s", c.JWT.Expiry)
+	check(c.SMTP.Timeout > 0, "SMTP_TIMEOUT: must be positive, got %s", c.SMTP.Timeout)
+	check(c.WebSocket.SendQueueSize > 0, "WS_SEND_QUEUE_SIZE: must be positive, got %d", c.WebSocket.SendQueueSize)
+	check(c.WebSocket.CompressionLevel >= 1 && c.WebSocket.CompressionLevel <= 9, "WS_COMPRESSION_LEVEL: must be between 1 and 9, got %d", c.WebSocket.CompressionLevel)
+	check(c.WebSocket.CompressionThreshold >= 0, "WS_COMPRESSION_THRESHOLD: must not be negative, got %d", c.WebSocket.CompressionThreshold)
+	check(c.WebSocket.PingInterval > 0, "WS_PING_INTERVAL: must be positive, got %s", c.WebSocket.PingInterval)
+	check(c.WebSocket.PongTimeout > c.WebSocket.PingInterval, "WS_PONG_TIMEOUT: must be longer than WS_PING_INTERVAL (%s), got %s", c.WebSocket.PingInterval, c.WebSocket.PongTimeout)
+	check(c.WebSocket.MaxMessageSize > 0, "WS_MAX_MESSAGE_SIZE: must be positive, got %d", c.WebSocket.MaxMessageSize)
+	check(c.WebSocket.AuthCheckInterval > 0, "WS_AUTH_CHECK_INTERVAL: must be positive, got %s", c.WebSocket.AuthCheckInterval)
+	check(c.WebSocket.IdleTimeout >= time.Minute, "WS_IDLE_TIMEOUT: must be at least 1m, got %s", c.WebSocket.IdleTimeout)
+	check(c.WebSocket.RedisBufferSize >= 0, "WS_REDIS_BUFFER_SIZE: must not be negative, got %d", c.WebSocket.RedisBufferSize)
+	check(c.WebSocket.RedisBufferMaxAge > 0, "WS_REDIS_BUFFER_MAX_AGE: must be positive, got %s", c.WebSocket.RedisBufferMaxAge)
+	check(c.WebSocket.MaxConnectionsPerUser >= 0, "WS_MAX_CONNECTIONS_PER_USER: must not be negative (0 = unlimited), got %d", c.WebSocket.MaxConnectionsPerUser)
+	check(c.Export.LinkExpiry > 0 && c.Export.LinkExpiry <= 7*24*time.Hour, "EXPORT_LINK_EXPIRY: must be positive and at most 168h, got %s", c.Export.LinkExpiry)
+	check(c.Export.Retention > 0, "EXPORT_RETENTION: must be positive, got %s", c.Export.Retention)
+	check(c.Conversation.Retention > 0, "CONVERSATION_RETENTION: must be positive, got %s", c.Conversation.Retention)
+	check(c.Retention.PurgeInterval > 0, "RETENTION_PURGE_INTERVAL: must be positive, got %s", c.Retention.PurgeInterval)
+	check(c.Analytics.FlushInterval > 0, "ANALYTICS_FLUSH_INTERVAL: must be positive, got %s", c.Analytics.FlushInterval)
+	check(c.Limits.GroupMembers >= 0, "LIMITS_GROUP_MEMBERS: must not be negative (0 = unlimited), got %d", c.Limits.GroupMembers)
+	check(c.Limits.GroupsPerUser >= 0, "LIMITS_GROUPS_PER_USER: must not be negative (0 = unlimited), got %d", c.Limits.GroupsPerUser)
+	check(c.Limits.DirectsPerDay >= 0, "LIMITS_DIRECTS_PER_DAY: must not be negative (0 = unlimited), got %d", c.Limits.DirectsPerDay)
+	check(c.Limits.MessageLength >= 0, "LIMITS_MESSAGE_LENGTH: must not be negative (0 = unlimited), got %d", c.Limits.MessageLength)
+	check(c.Limits.Pins >= 0, "LIMITS_PINS: must not be negative (0 = unlimited), got %d", c.Limits.Pins)
+	check(c.Import.MaxSizeMB > 0, "IMPORT_MAX_SIZE_MB: must be positive, got %d", c.Import.MaxSizeMB)
+	check(c.Compression.Level >= -1 && c.Compression.Level <= 9, "COMPRESSION_LEVEL: must be between -1 and 9, got %d", c.Compression.Level)
+	for _, origin := range c.CORS.Origins {
+		check(validOrigin(origin), "CORS_ORIGINS: %q is not an origin like https://chat.example.com", origin)
+	}
+	check(c.Security.HSTSMaxAge >= 0, "SECURITY_HSTS_MAX_AGE: must not be negative (0 = no HSTS), got %s", c.Security.HSTSMaxAge)
+	check(referrerPolicies[c.Security.ReferrerPolicy], "SECURITY_REFERRER_POLICY: %q is not a referrer policy", c.Security.ReferrerPolicy)
+	if c.AuthCookie.Enabled {
+		check(validCookieName(c.AuthCookie.Name), "AUTH_COOKIE_NAME: %q is not a cookie name", c.AuthCookie.Name)
+		check(c.AuthCookie.SameSite == "lax" || c.AuthCookie.SameSite == "strict" || c.AuthCookie.SameSite == "none",
+			"AUTH_COOKIE_SAMESITE: must be lax, strict or none, got %q", c.AuthCookie.SameSite)
+		check(c.AuthCookie.SameSite != "none" || c.AuthCookie.Secure, "AUTH_COOKIE_SAMESITE: none needs AUTH_COOKIE_SECURE=true")
+		if c.App.Env == "production" {
+			check(c.AuthCookie.Secure, "AUTH_COOKIE_SECURE: must be true in production")
+		}
+	}
+	if c.MinIO.PublicURL != "" {
+		check(validURL(c.MinIO.PublicURL), "MINIO_PUBLIC_URL: %q is not an http(s) URL", c.MinIO.PublicURL)
+	}
+	if c.Secrets.VaultAddr != "" {
+		check(validURL(c.Secrets.VaultAddr), "VAULT_ADDR: %q is not an http(s) URL", c.Secrets.VaultAddr)
+	}
+	check(c.Secrets.RefreshInterval >= 0, "SECRETS_REFRESH_INTERVAL: must not be negative, got %s", c.Secrets.RefreshInterval)
+	if c.Errors.SentryDSN != "" {
+		check(validSentryDSN(c.Errors.SentryDSN), "SENTRY_DSN: not a DSN like https://<key>@o0.ingest.sentry.io/<project>")
+	}
+	if c.SSO.Issuer != "" {
+		check(validURL(c.SSO.Issuer), "SSO_ISSUER: %q is not an http(s) URL", c.SSO.Issuer)
+		check(c.SSO.ClientID != "", "SSO_CLIENT_ID: required when SSO_ISSUER is set")
+		check(validURL(c.SSO.RedirectURL), "SSO_REDIRECT_URL: %q is not an http(s) URL", c.SSO.RedirectURL)
+		if c.SSO.FrontendURL != "" {
+			check(validURL(c.SSO.FrontendURL), "SSO_FRONTEND_URL: %q is not an http(s) URL", c.SSO.FrontendURL)
+		}
+	}
+	if c.LDAP.URL != "" {
+		check(validLDAPURL(c.LDAP.URL), "LDAP_URL: %q is not an ldap:// or ldaps:// URL", c.LDAP.URL)
+		check(!c.LDAP.StartTLS || strings.HasPrefix(c.LDAP.URL, "ldap://"), "LDAP_START_TLS: only applies to ldap:// URLs")
+		check(c.LDAP.BaseDN != "", "LDAP_BASE_DN: required when LDAP_URL is set")
+		check(c.LDAP.UserFilter != "", "LDAP_USER_FILTER: required when LDAP_URL is set")
+		check(c.LDAP.AttrID != "" && c.LDAP.AttrEmail != "" && c.LDAP.AttrName != "", "LDAP_ATTR_ID, LDAP_ATTR_EMAIL, LDAP_ATTR_NAME: required when LDAP_URL is set")
+		if c.LDAP.GroupFilter != "" {
+			check(c.LDAP.GroupMemberAttr != "", "LDAP_GROUP_MEMBER_ATTR: required when LDAP_GROUP_FILTER is set")
+		}
+		check(c.LDAP.SyncHour >= 0 && c.LDAP.SyncHour <= 23, "LDAP_SYNC_HOUR: must be between 0 and 23, got %d", c.LDAP.SyncHour)
+	}
+
+	if c.OpenSearch.URL != "" {
+		check(validURL(c.OpenSearch.URL), "OPENSEARCH_URL: %q is not an http(s) URL", c.OpenSearch.URL)
+		check(c.OpenSearch.Index != "", "OPENSEARCH_INDEX: required when OPENSEARCH_URL is set")
+	}
+
+	if c.Matrix.HomeserverURL != "" {
+		check(validURL(c.Matrix.HomeserverURL), "MATRIX_HOMESERVER_URL: %q is not an http(s) URL", c.Matrix.HomeserverURL)
+		check(c.Matrix.ServerName != "", "MATRIX_SERVER_NAME: required when MATRIX_HOMESERVER_URL is set")
+		check(c.Matrix.ASToken != "", "MATRIX_AS_TOKEN: required when MATRIX_HOMESERVER_URL is set")
+		check(len(c.Matrix.HSToken) >= minSecretLength, "MATRIX_HS_TOKEN: must be at least %d characters", minSecretLength)
+		check(c.Matrix.UserPrefix != "", "MATRIX_USER_PREFIX: required when MATRIX_HOMESERVER_URL is set")
+		check(c.Matrix.BotLocalpart != "", "MATRIX_BOT_LOCALPART: required when MATRIX_HOMESERVER_URL is set")
+	}
+
+	if c.ReplyMail.Domain != "" {
+		check(len(c.ReplyMail.InboundToken) >= minSecretLength, "REPLY_MAIL_INBOUND_TOKEN: must be at least %d characters when REPLY_MAIL_DOMAIN is set", minSecretLength)
+		check(c.ReplyMail.AddressTTL > 0, "REPLY_MAIL_ADDRESS_TTL: must be positive, got %s", c.ReplyMail.AddressTTL)
+		check(c.ReplyMail.Throttle > 0, "REPLY_MAIL_THROTTLE: must be positive, got %s", c.ReplyMail.Throttle)
+	}
+
+	if c.GeoIP.AccountID != "" {
+		check(c.GeoIP.LicenseKey != "", "GEOIP_LICENSE_KEY: required when GEOIP_ACCOUNT_ID is set")
+		check(c.GeoIP.Host != "", "GEOIP_HOST: required when GEOIP_ACCOUNT_ID is set")
+	}
+	if c.LoginAlert.RevokeURL != "" {
+		check(validURL(c.LoginAlert.RevokeURL), "LOGIN_ALERT_REVOKE_URL: %q is not an http(s) URL", c.LoginAlert.RevokeURL)
+	}
+	check(c.OTP.MaxAttempts > 0, "OTP_MAX_ATTEMPTS: must be positive, got %d", c.OTP.MaxAttempts)
+	check(c.OTP.VerifyRateLimit >= 0, "OTP_VERIFY_RATE_LIMIT: must not be negative, got %d", c.OTP.VerifyRateLimit)
+	if c.OTP.VerifyRateLimit > 0 {
+		check(c.OTP.VerifyRateWindow > 0, "OTP_VERIFY_RATE_WINDOW: must be positive, got %s", c.OTP.VerifyRateWindow)
+	}
+	if c.MagicLink.URL != "" {
+		check(validURL(c.MagicLink.URL), "MAGIC_LINK_URL: %q is not an http(s) URL", c.MagicLink.URL)
+		check(c.MagicLink.TTL >= time.Minute, "MAGIC_LINK_TTL: must be at least 1m, got %s", c.MagicLink.TTL)
+	}
+	if c.OAuth.Issuer != "" {
+		check(validURL(c.OAuth.Issuer), "OAUTH_ISSUER: %q is not an http(s) URL", c.OAuth.Issuer)
+		check(validURL(c.OAuth.AuthorizeURL), "OAUTH_AUTHORIZE_URL: %q is not an http(s) URL", c.OAuth.AuthorizeURL)
+		// App tokens are signed with JWT_SECRET, whose rotation only honours tokens for JWT_EXPIRY
+		check(c.OAuth.AccessTokenTTL > 0 && c.OAuth.AccessTokenTTL <= c.JWT.Expiry,
+			"OAUTH_ACCESS_TOKEN_TTL: must be positive and at most JWT_EXPIRY (%s), got %s", c.JWT.Expiry, c.OAuth.AccessTokenTTL)
+		check(c.OAuth.RefreshTokenTTL > 0, "OAUTH_REFRESH_TOKEN_TTL: must be positive, got %s", c.OAuth.RefreshTokenTTL)
+	}
+	check(c.LoginAlert.RevokeTTL > 0, "LOGIN_ALERT_REVOKE_TTL: must be positive, got %s", c.LoginAlert.RevokeTTL)
+
+	if c.App.Env == "production" {
+		check(len(c.JWT.Secret) >= minSecretLength && !weakSecrets[c.JWT.Secret],
+			"JWT_SECRET: must be at least %d characters and not a default (generate with: openssl rand -hex 32)", minSecretLength)
+		check(!weakSecrets[c.DB.Password], "DB_PASSWORD: must be set and not a default")
+		check(!weakSecrets[c.MinIO.SecretKey], "MINIO_SECRET_KEY: must be set and not a default")
+		if c.SCIM.Token != "" {
+			check(len(c.SCIM.Token) >= minSecretLength, "SCIM_TOKEN: must be at least %d characters", minSecretLength)
+		}
+		if c.SSO.Issuer != "" {
+			check(c.SSO.ClientSecret != "", "SSO_CLIENT_SECRET: required when SSO_ISSUER is set")
+		}
+		if c.LDAP.URL != "" {
+			// Passwords are sent to the server in the bind request
+			check(strings.HasPrefix(c.LDAP.URL, "ldaps://") || c.LDAP.StartTLS, "LDAP_URL: must be ldaps:// or use LDAP_START_TLS")
+		}
+		if c.OAuth.Issuer != "" {
+			// Every instance must sign ID tokens with the same key
+			check(c.OAuth.SigningKey != "", "OAUTH_SIGNING_KEY: required when OAUTH_ISSUER is set (generate with: openssl genrsa 2048)")
+		}
+		if c.OTP.Secret != "" {
+			check(len(c.OTP.Secret) >= minSecretLength, "OTP_SECRET: must be at least %d characters", minSecretLength)
+		}
+	}
+	return problems
+}
+
+// Summary lists the effective settings, one per line, with secrets redacted
+func (c *Config) Summary() string {
+	var b strings.Builder
+	v := reflect.ValueOf(*c)
+	for i := 0; i < v.NumField(); i++ {
+		section := v.Type().Field(i)
+		if !section.IsExported() {
+			continue
+		}
+		for j := 0; j < v.Field(i).NumField(); j++ {
+			field := section.Type.Field(j)
+			value := v.Field(i).Field(j)
+			fmt.Fprintf(&b, "  %s.%s = %s\n", section.Name, field.Name, summaryValue(value, field.Tag.Get("config") == "secret"))
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func summaryValue(v reflect.Value, secret bool) string {
+	if secret {
+		if v.Len() == 0 {
+			return `""`
+		}
+		return "[redacted]"
+	}
+	switch x := v.Interface().(type) {
+	case []string:
+		return "[" + strings.Join(x, ", ") + "]"
+	case time.Duration:
+		return x.String()
+	case string:
+		return strconv.Quote(x)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// referrerPolicies are the values browsers accept in Referrer-Policy
+var referrerPolicies = map[string]bool{
+	"no-referrer":                     true,
+	"no-referrer-when-downgrade":      true,
+	"origin":                          true,
+	"origin-when-cross-origin":        true,
+	"same-origin":                     true,
+	"strict-origin":                   true,
+	"strict-origin-when-cross-origin": true,
+	"unsafe-url":                      true,
+}
+
+// validCookieName accepts the token characters allowed in cookie names
+func validCookieName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune(`()<>@,;:\"/[]?={}`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n < 65536
+}
+
+// validOrigin accepts "*" or scheme://host[:port] with no path
+func validOrigin(origin string) bool {
+	if origin == "*" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.Path == "" && u.RawQuery == ""
+}
+
+func validURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func validLDAPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "ldap" || u.Scheme == "ldaps") && u.Host != ""
+}
+
+func validSentryDSN(dsn string) bool {
+	u, err := url.Parse(dsn)
+	return err == nil && validURL(dsn) && u.User != nil && u.User.Username() != "" &&
+		strings.Trim(u.Path, "/") != ""
+}
@@ -0,0 +1,349 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/internal/ws"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+	"github.com/quocanhngo/gotalk/pkg/mailer"
+)
+
+// AdminHandler handles operator endpoints
+type AdminHandler struct {
+	mailQueue   *mailer.Queue
+	notifCenter *service.NotificationCenterService
+	flagService *service.FlagService
+	hub         *ws.Hub
+	members     *service.MembershipCache
+	authService *service.AuthService
+	signup      *service.SignupService
+	ldap        *service.LDAPService
+	stats       *service.AnalyticsService
+}
+
+func NewAdminHandler(mailQueue *mailer.Queue, notifCenter *service.NotificationCenterService, flagService *service.FlagService, hub *ws.Hub, members *service.MembershipCache, authService *service.AuthService, signup *service.SignupService, ldap *service.LDAPService, stats *service.AnalyticsService) *AdminHandler {
+	return &AdminHandler{mailQueue: mailQueue, notifCenter: notifCenter, flagService: flagService, hub: hub, members: members, authService: authService, signup: signup, ldap: ldap, stats: stats}
+}
+
+// GetFailedEmails godoc
+// @Summary List dead-lettered emails
+// @Description Emails that exhausted their retries or failed permanently, plus queue stats
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.FailedEmailsResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /admin/emails/failed [get]
+func (h *AdminHandler) GetFailedEmails(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	jobs, err := h.mailQueue.DeadLetters(ctx)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	stats, err := h.mailQueue.Stats(ctx)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.FailedEmailsResponse{Stats: *stats, Jobs: jobs})
+}
+
+// ResendFailedEmail godoc
+// @Summary Resend a dead-lettered email
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Email job ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /admin/emails/failed/{id}/resend [post]
+func (h *AdminHandler) ResendFailedEmail(c *gin.Context) {
+	if err := h.mailQueue.Resend(c.Request.Context(), c.Param("id")); err != nil {
+		if errors.Is(err, mailer.ErrJobNotFound) {
+			c.Error(apperror.ErrNotFound.Wrap(err))
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Email re-queued"})
+}
+
+// DiscardFailedEmail godoc
+// @Summary Discard a dead-lettered email
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Email job ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /admin/emails/failed/{id} [delete]
+func (h *AdminHandler) DiscardFailedEmail(c *gin.Context) {
+	if err := h.mailQueue.Discard(c.Request.Context(), c.Param("id")); err != nil {
+		if errors.Is(err, mailer.ErrJobNotFound) {
+			c.Error(apperror.ErrNotFound.Wrap(err))
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Email discarded"})
+}
+
+// SendNotice godoc
+// @Summary Send an admin notice to the notification center
+// @Description Delivered to the listed users, or to every verified user when user_ids is empty
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.AdminNoticeRequest true "Notice"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /admin/notices [post]
+func (h *AdminHandler) SendNotice(c *gin.Context) {
+	var req model.AdminNoticeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	sent, err := h.notifCenter.BroadcastNotice(req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Notice sent", Data: gin.H{"recipients": sent}})
+}
+
+// GetFeatureFlags godoc
+// @Summary Get the runtime feature flags
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.FeatureFlags
+// @Failure 403 {object} model.ErrorResponse
+// @Router /admin/flags [get]
+func (h *AdminHandler) GetFeatureFlags(c *gin.Context) {
+	respond(c, http.StatusOK, h.flagService.Get(c.Request.Context()))
+}
+
+// UpdateFeatureFlags godoc
+// @Summary Change runtime feature flags
+// @Description Omitted flags keep their value. Changes reach every instance within a few seconds.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.UpdateFeatureFlagsRequest true "Flags to change"
+// @Success 200 {object} model.FeatureFlags
+// @Failure 403 {object} model.ErrorResponse
+// @Router /admin/flags [patch]
+func (h *AdminHandler) UpdateFeatureFlags(c *gin.Context) {
+	var req model.UpdateFeatureFlagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	flags, err := h.flagService.Update(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	log.Printf("🚩 Feature flags changed by %s: %+v", c.GetString("email"), flags)
+
+	respond(c, http.StatusOK, flags)
+}
+
+// GetWebSocketStats godoc
+// @Summary Get WebSocket connection stats
+// @Description Per-instance view: connected clients, queued events, the deepest queue, dropped ephemeral events, slow clients disconnected and compression use.
+// @Description Since startup, per event type: events published to Redis, copies delivered to and dropped from local connections,
+// @Description and a histogram of connections reached per delivery (fan-out); plus a histogram of Redis publish latency.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.WSStats
+// @Failure 403 {object} model.ErrorResponse
+// @Router /admin/ws/stats [get]
+func (h *AdminHandler) GetWebSocketStats(c *gin.Context) {
+	respond(c, http.StatusOK, h.hub.Stats())
+}
+
+// GetMembershipCacheStats godoc
+// @Summary Get conversation membership cache stats
+// @Description Per-instance view since startup: membership lookups answered from Redis (hits) or loaded from the database (misses), Redis failures, and the average latency of each
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.MembershipCacheStats
+// @Failure 403 {object} model.ErrorResponse
+// @Router /admin/membership/stats [get]
+func (h *AdminHandler) GetMembershipCacheStats(c *gin.Context) {
+	respond(c, http.StatusOK, h.members.Stats())
+}
+
+// GetUsageStats godoc
+// @Summary Get usage statistics
+// @Description Daily series, one point per UTC day, of messages sent, uploads and their bytes, call minutes, and daily
+// @Description and trailing 30-day active users. Counts reach the stats within ANALYTICS_FLUSH_INTERVAL.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param metric query string false "Only this metric" Enums(messages_sent, uploads, upload_bytes, call_minutes, active_users, monthly_active_users)
+// @Param from query string false "First day, YYYY-MM-DD (default: 29 days before to)"
+// @Param to query string false "Last day, YYYY-MM-DD (default: today)"
+// @Success 200 {object} model.UsageStatsResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /admin/stats [get]
+func (h *AdminHandler) GetUsageStats(c *gin.Context) {
+	var req model.UsageStatsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	stats, err := h.stats.Stats(req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, stats)
+}
+
+// LogoutUser godoc
+// @Summary Sign a user out on all devices
+// @Description Revokes every token issued to the user so far and closes their WebSocket connections on all instances. The user can sign in again.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /admin/users/{id}/logout [post]
+func (h *AdminHandler) LogoutUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid user ID"))
+		return
+	}
+
+	if err := h.authService.LogoutEverywhere(userID); err != nil {
+		c.Error(err)
+		return
+	}
+	log.Printf("🔒 User %s signed out everywhere by %s", userID, c.GetString("email"))
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "User signed out on all devices"})
+}
+
+// CreateInvitation godoc
+// @Summary Issue an invitation code
+// @Description Lets people register while SIGNUP_INVITE_ONLY is on. Single-use unless max_uses says otherwise (0 = unlimited); optionally limited to one email address and an expiry.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.CreateInvitationRequest true "Invitation"
+// @Success 201 {object} model.Invitation
+// @Failure 403 {object} model.ErrorResponse
+// @Router /admin/invitations [post]
+func (h *AdminHandler) CreateInvitation(c *gin.Context) {
+	var req model.CreateInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	adminID := c.MustGet("user_id").(uuid.UUID)
+	invitation, err := h.signup.CreateInvitation(adminID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusCreated, invitation)
+}
+
+// ListInvitations godoc
+// @Summary List invitation codes
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.Invitation
+// @Failure 403 {object} model.ErrorResponse
+// @Router /admin/invitations [get]
+func (h *AdminHandler) ListInvitations(c *gin.Context) {
+	invitations, err := h.signup.ListInvitations()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respondList(c, http.StatusOK, invitations, model.PageMeta{Count: len(invitations)})
+}
+
+// DeleteInvitation godoc
+// @Summary Revoke an invitation code
+// @Description Accounts already created with it are kept
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Invitation ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /admin/invitations/{id} [delete]
+func (h *AdminHandler) DeleteInvitation(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid invitation ID"))
+		return
+	}
+
+	if err := h.signup.DeleteInvitation(id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Invitation revoked"})
+}
+
+// SyncLDAP godoc
+// @Summary Sync the LDAP directory now
+// @Description Runs the nightly LDAP / Active Directory sync right away: creates and updates users, deactivates LDAP users gone from the directory and updates the group conversations mapped to directory groups.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.LDAPSyncResult
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 503 {object} model.ErrorResponse
+// @Router /admin/ldap/sync [post]
+func (h *AdminHandler) SyncLDAP(c *gin.Context) {
+	result, err := h.ldap.Sync(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	log.Printf("📇 LDAP sync run by %s", c.GetString("email"))
+
+	respond(c, http.StatusOK, result)
+}
@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+	"github.com/quocanhngo/gotalk/pkg/storage"
+)
+
+// Attachments of these types may be shown inline; anything else is always
+// downloaded, so a stored HTML or SVG file can't run in the API's origin
+var inlineAttachmentTypes = []string{"image/", "video/", "audio/"}
+
+// AttachmentHandler streams message attachments from storage to members of
+// their conversation, for deployments that don't expose MinIO publicly
+type AttachmentHandler struct {
+	chatService *service.ChatService
+	storage     *storage.MinIOStorage
+}
+
+// NewAttachmentHandler creates a new attachment handler
+func NewAttachmentHandler(chatService *service.ChatService, storage *storage.MinIOStorage) *AttachmentHandler {
+	return &AttachmentHandler{chatService: chatService, storage: storage}
+}
+
+// GetAttachment godoc
+// @Summary Download a message attachment
+// @Description Streams an attachment of a message you can see. Range requests are supported (206), so videos can be seeked; responses carry ETag and Last-Modified, and If-None-Match / If-Modified-Since get 304. Images, videos and audio are shown inline unless download=true; other files are always downloaded.
+// @Tags Upload
+// @Produce application/octet-stream
+// @Security BearerAuth
+// @Param id path string true "Attachment ID"
+// @Param rendition query string false "original (default), processed (the transcoded video) or poster (the video's poster image)" Enums(original, processed, poster)
+// @Param download query bool false "Save the file (Content-Disposition: attachment) instead of showing it"
+// @Param Range header string false "Byte range, e.g. bytes=0-1048575"
+// @Success 200 {file} binary
+// @Success 206 {file} binary
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 416 {object} model.ErrorResponse
+// @Router /attachments/{id} [get]
+func (h *AttachmentHandler) GetAttachment(c *gin.Context) {
+	if h.storage == nil {
+		c.Error(apperror.ErrUnavailable.WithMessage("File storage unavailable"))
+		return
+	}
+
+	attachmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid attachment ID"))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	att, err := h.chatService.GetAttachment(attachmentID, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	url, fileName := att.URL, att.FileName
+	switch c.DefaultQuery("rendition", "original") {
+	case "original":
+	case "processed":
+		url = att.ProcessedURL
+	case "poster":
+		url, fileName = att.PosterURL, ""
+	default:
+		c.Error(apperror.ErrInvalidRequest.WithMessage("rendition must be one of: original, processed, poster"))
+		return
+	}
+	// Attachments linking elsewhere aren't ours to serve
+	key, ok := h.storage.KeyFromURL(url)
+	if !ok {
+		c.Error(service.ErrAttachmentNotFound)
+		return
+	}
+
+	obj, info, err := h.storage.OpenObject(c.Request.Context(), key)
+	if err != nil {
+		c.Error(service.ErrAttachmentNotFound.Wrap(err))
+		return
+	}
+	defer obj.Close()
+
+	contentType := info.ContentType
+	if url == att.URL && att.MimeType != "" {
+		contentType = att.MimeType
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	disposition := "attachment"
+	if c.Query("download") != "true" && isInlineType(contentType) {
+		disposition = "inline"
+	}
+	if fileName == "" {
+		fileName = key[strings.LastIndex(key, "/")+1:]
+	}
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", contentType)
+	if value := mime.FormatMediaType(disposition, map[string]string{"filename": fileName}); value != "" {
+		header.Set("Content-Disposition", value)
+	} else {
+		header.Set("Content-Disposition", disposition)
+	}
+	header.Set("ETag", info.ETag)
+	// Object keys are never reused, but access is checked on every request
+	header.Set("Cache-Control", "private, no-cache")
+
+	// Handles Range, If-Range, If-None-Match and If-Modified-Since
+	http.ServeContent(c.Writer, c.Request, "", info.LastModified, obj)
+}
+
+// isInlineType reports whether a Content-Type is safe to show inline
+func isInlineType(contentType string) bool {
+	if strings.HasPrefix(contentType, "image/svg") {
+		return false
+	}
+	for _, prefix := range inlineAttachmentTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,29 +1,41 @@
 package handler
 
 import (
+	"bytes"
+	"errors"
+	"html/template"
 	"net/http"
-	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/middleware"
 	"github.com/quocanhngo/gotalk/internal/model"
 	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
 	"github.com/quocanhngo/gotalk/pkg/storage"
 )
 
 // AuthHandler handles authentication endpoints
 type AuthHandler struct {
-	authService *service.AuthService
-	storage     storage.Storage
+	authService    *service.AuthService
+	storage        storage.Storage
+	vapidPublicKey string
+	cookies        *middleware.SessionCookies // nil without the cookie-based auth mode
 }
 
-func NewAuthHandler(authService *service.AuthService, storage storage.Storage) *AuthHandler {
+func NewAuthHandler(authService *service.AuthService, storage storage.Storage, vapidPublicKey string) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
-		storage:     storage,
+		authService:    authService,
+		storage:        storage,
+		vapidPublicKey: vapidPublicKey,
 	}
 }
 
+// UseSessionCookies lets browser clients sign in to a cookie session
+func (h *AuthHandler) UseSessionCookies(cookies *middleware.SessionCookies) {
+	h.cookies = cookies
+}
+
 // Register godoc
 // @Summary Register a new user (sends OTP for verification)
 // @Tags Auth
@@ -32,21 +44,27 @@ func NewAuthHandler(authService *service.AuthService, storage storage.Storage) *
 // @Param body body model.RegisterRequest true "Register request"
 // @Success 201 {object} model.OTPSentResponse
 // @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
 // @Router /auth/register [post]
 func (h *AuthHandler) Register(c *gin.Context) {
+	if !featureFlags(c).RegistrationOpen {
+		c.Error(service.ErrRegistrationClosed)
+		return
+	}
+
 	var req model.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request", Message: err.Error()})
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
 	resp, err := h.authService.Register(req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, resp)
+	respond(c, http.StatusCreated, resp)
 }
 
 // VerifyOTP godoc
@@ -55,23 +73,23 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param body body model.VerifyOTPRequest true "Verify OTP request"
-// @Success 200 {object} model.AuthResponse
+// @Success 200 {object} model.LoginResponse
 // @Failure 400 {object} model.ErrorResponse
 // @Router /auth/verify-otp [post]
 func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 	var req model.VerifyOTPRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request", Message: err.Error()})
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
-	resp, err := h.authService.VerifyOTP(req)
+	resp, err := h.authService.VerifyOTP(req, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, resp)
+	respondSession(c, h.cookies, resp)
 }
 
 // ResendOTP godoc
@@ -86,17 +104,17 @@ func (h *AuthHandler) VerifyOTP(c *gin.Context) {
 func (h *AuthHandler) ResendOTP(c *gin.Context) {
 	var req model.ResendOTPRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request", Message: err.Error()})
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
 	resp, err := h.authService.ResendOTP(req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, resp)
+	respond(c, http.StatusOK, resp)
 }
 
 // Login godoc
@@ -105,23 +123,23 @@ func (h *AuthHandler) ResendOTP(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param body body model.LoginRequest true "Login request"
-// @Success 200 {object} model.AuthResponse
+// @Success 200 {object} model.LoginResponse
 // @Failure 401 {object} model.ErrorResponse
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req model.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request", Message: err.Error()})
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
-	resp, err := h.authService.Login(req)
+	resp, err := h.authService.Login(req, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, resp)
+	respondSession(c, h.cookies, resp)
 }
 
 // GoogleLogin godoc
@@ -136,17 +154,124 @@ func (h *AuthHandler) Login(c *gin.Context) {
 func (h *AuthHandler) GoogleLogin(c *gin.Context) {
 	var req model.GoogleLoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request", Message: err.Error()})
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	resp, err := h.authService.LoginWithGoogle(req, clientInfo(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respondSession(c, h.cookies, resp)
+}
+
+// SendMagicLink godoc
+// @Summary Email a one-time sign-in link
+// @Description An alternative to codes: the email links to the app's MAGIC_LINK_URL with a token, which the app posts to /auth/magic-link/verify once the user confirms. The response is the same whether or not the email has an account that can get a link.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body model.MagicLinkRequest true "Email to send the link to"
+// @Success 200 {object} model.OTPSentResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /auth/magic-link [post]
+func (h *AuthHandler) SendMagicLink(c *gin.Context) {
+	var req model.MagicLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	resp, err := h.authService.SendMagicLink(req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, resp)
+}
+
+// MagicLinkPage godoc
+// @Summary Confirm a magic link sign-in
+// @Description Shows a page asking to confirm the sign-in, whose button posts the token to /auth/magic-link/verify. Following the link doesn't use it up, so mail scanners and link previews can't.
+// @Tags Auth
+// @Produce text/html
+// @Param token query string true "Token from the sign-in link"
+// @Success 200 {string} string "Confirmation page"
+// @Failure 400 {string} string "Page saying the link is invalid or expired"
+// @Failure 429 {string} string "Page saying to try again later"
+// @Router /auth/magic-link/verify [get]
+func (h *AuthHandler) MagicLinkPage(c *gin.Context) {
+	token := c.Query("token")
+	status, problem := http.StatusOK, ""
+	if err := h.authService.CheckMagicLink(token, clientInfo(c)); err != nil {
+		// Only the link's own problems (invalid, expired, rate limited) are shown on the page
+		var appErr *apperror.Error
+		if !errors.As(err, &appErr) {
+			c.Error(err)
+			return
+		}
+		status, problem = appErr.Status(), appErr.Message
+	}
+
+	var page bytes.Buffer
+	if err := magicLinkPage.Execute(&page, gin.H{"Token": token, "Problem": problem}); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	// The page has inline styles and a form posting back here
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; frame-ancestors 'none'; base-uri 'none'")
+	c.Data(status, "text/html; charset=utf-8", page.Bytes())
+}
+
+// magicLinkPage confirms a sign-in before the link is used up
+var magicLinkPage = template.Must(template.New("magic-link").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Sign in to GoTalk</title>
+<style>body{font-family:system-ui,sans-serif;max-width:28rem;margin:4rem auto;padding:0 1rem;text-align:center}button{font-size:1rem;padding:.6rem 1.5rem}</style>
+</head>
+<body>
+{{if not .Problem}}<h1>Sign in to GoTalk</h1>
+<p>Continue to sign in with this link. It works once.</p>
+<form method="post"><input type="hidden" name="token" value="{{.Token}}"><button type="submit">Sign in</button></form>
+{{else}}<h1>Can't sign in</h1>
+<p>{{.Problem}}. Request a new link from the app.</p>
+{{end}}</body>
+</html>
+`))
+
+// VerifyMagicLink godoc
+// @Summary Sign in with a magic link
+// @Description Exchanges the token of a sign-in link for a session. Each link works once. Apps post the token from their MAGIC_LINK_URL page once the user confirms; form posts (token=...) work too.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body model.VerifyMagicLinkRequest true "Token from the sign-in link"
+// @Success 200 {object} model.LoginResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse
+// @Router /auth/magic-link/verify [post]
+func (h *AuthHandler) VerifyMagicLink(c *gin.Context) {
+	var req model.VerifyMagicLinkRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("token is required").Wrap(err))
 		return
 	}
 
-	resp, err := h.authService.LoginWithGoogle(req)
+	resp, err := h.authService.VerifyMagicLink(req.Token, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, resp)
+	respondSession(c, h.cookies, resp)
 }
 
 // ForgotPassword godoc
@@ -160,17 +285,17 @@ func (h *AuthHandler) GoogleLogin(c *gin.Context) {
 func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 	var req model.ForgotPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request", Message: err.Error()})
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
 	resp, err := h.authService.ForgotPassword(req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, resp)
+	respond(c, http.StatusOK, resp)
 }
 
 // ResetPassword godoc
@@ -185,16 +310,41 @@ func (h *AuthHandler) ForgotPassword(c *gin.Context) {
 func (h *AuthHandler) ResetPassword(c *gin.Context) {
 	var req model.ResetPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request", Message: err.Error()})
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	if err := h.authService.ResetPassword(req, clientInfo(c)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Password reset successfully"})
+}
+
+// RevokeLoginAlert godoc
+// @Summary Sign out everywhere from a new sign-in alert
+// @Description The "this wasn't me" link of a new sign-in alert: revokes every token of the account and closes its WebSocket connections. Each link works once.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body model.LoginAlertRevokeRequest true "Token from the alert link"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /auth/login-alerts/revoke [post]
+func (h *AuthHandler) RevokeLoginAlert(c *gin.Context) {
+	var req model.LoginAlertRevokeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
-	if err := h.authService.ResetPassword(req); err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+	if err := h.authService.RevokeFromLoginAlert(req.Token); err != nil {
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, model.SuccessResponse{Message: "Password reset successfully"})
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Signed out on all devices. Please reset your password"})
 }
 
 // GetProfile godoc
@@ -202,6 +352,8 @@ func (h *AuthHandler) ResetPassword(c *gin.Context) {
 // @Tags Auth
 // @Produce json
 // @Security BearerAuth
+// @Param If-None-Match header string false "ETag of the cached copy; 304 Not Modified if unchanged"
+// @Param If-Modified-Since header string false "Last-Modified of the cached copy (HTTP date); 304 Not Modified if unchanged"
 // @Success 200 {object} model.UserResponse
 // @Router /auth/profile [get]
 func (h *AuthHandler) GetProfile(c *gin.Context) {
@@ -209,11 +361,91 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 
 	profile, err := h.authService.GetProfile(userID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, model.ErrorResponse{Error: err.Error()})
+		c.Error(err)
+		return
+	}
+
+	cache := newCacheValidator()
+	cache.addUser(profile.ID, profile.UpdatedAt, profile.Status)
+	if cache.notModified(c) {
 		return
 	}
 
-	c.JSON(http.StatusOK, profile)
+	respond(c, http.StatusOK, profile)
+}
+
+// CheckHandle godoc
+// @Summary Check whether a handle is available
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param handle query string true "Handle to check (with or without @)"
+// @Success 200 {object} model.HandleAvailabilityResponse
+// @Router /users/handle-availability [get]
+func (h *AuthHandler) CheckHandle(c *gin.Context) {
+	handle := c.Query("handle")
+	if handle == "" {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Handle is required"))
+		return
+	}
+
+	resp, err := h.authService.CheckHandle(handle)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, resp)
+}
+
+// UpdateHandle godoc
+// @Summary Change the current user's @handle
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.UpdateHandleRequest true "New handle"
+// @Success 200 {object} model.UserResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /auth/handle [put]
+func (h *AuthHandler) UpdateHandle(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	var req model.UpdateHandleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	user, err := h.authService.UpdateHandle(userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, user)
+}
+
+// GetUserByHandle godoc
+// @Summary Get a user by @handle
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param handle path string true "User handle"
+// @Success 200 {object} model.UserResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /users/by-handle/{handle} [get]
+func (h *AuthHandler) GetUserByHandle(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+
+	user, err := h.authService.GetUserByHandle(c.Param("handle"), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, user)
 }
 
 // SearchUsers godoc
@@ -227,18 +459,18 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 func (h *AuthHandler) SearchUsers(c *gin.Context) {
 	query := c.Query("q")
 	if query == "" {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Search query is required"})
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Search query is required"))
 		return
 	}
 
 	userID := c.MustGet("user_id").(uuid.UUID)
 	users, err := h.authService.SearchUsers(query, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to search users"})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, users)
+	respondList(c, http.StatusOK, users, model.PageMeta{Count: len(users)})
 }
 
 // Logout godoc
@@ -252,25 +484,52 @@ func (h *AuthHandler) SearchUsers(c *gin.Context) {
 // @Router /auth/logout [post]
 func (h *AuthHandler) Logout(c *gin.Context) {
 	userID := c.MustGet("user_id").(uuid.UUID)
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Token required"})
+	if err := h.authService.Logout(userID, c.GetString("token")); err != nil {
+		c.Error(err)
 		return
 	}
+	h.cookies.End(c)
 
-	parts := strings.SplitN(authHeader, " ", 2)
-	if len(parts) != 2 {
-		c.JSON(http.StatusUnauthorized, model.ErrorResponse{Error: "Invalid token format"})
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Logged out successfully"})
+}
+
+// LogoutAll godoc
+// @Summary Log out on all devices
+// @Description Revoke every token issued so far, this one included, and close all WebSocket connections
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.SuccessResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Router /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	if err := h.authService.LogoutEverywhere(userID); err != nil {
+		c.Error(err)
 		return
 	}
-	tokenString := parts[1]
+	h.cookies.End(c)
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Logged out on all devices"})
+}
 
-	if err := h.authService.Logout(userID, tokenString); err != nil {
-		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+// GetLoginHistory godoc
+// @Summary List recent sign-ins
+// @Description The account's 50 most recent sign-ins with device, IP and location, newest first
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.LoginEvent
+// @Router /auth/logins [get]
+func (h *AuthHandler) GetLoginHistory(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	events, err := h.authService.LoginHistory(userID)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, model.SuccessResponse{Message: "Logged out successfully"})
+	respondList(c, http.StatusOK, events, model.PageMeta{Count: len(events)})
 }
 
 // UpdateProfile godoc
@@ -280,6 +539,8 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param name formData string false "User name"
+// @Param display_name formData string false "Public display name (empty clears it)"
+// @Param bio formData string false "Short bio (empty clears it)"
 // @Param avatar formData file false "Avatar image file"
 // @Success 200 {object} model.UserResponse
 // @Router /auth/profile [put]
@@ -289,7 +550,7 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 	// Parse multipart form
 	form, err := c.MultipartForm()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid form data", Message: err.Error()})
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid form data").Wrap(err))
 		return
 	}
 
@@ -299,6 +560,20 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 	if names := form.Value["name"]; len(names) > 0 {
 		req.Name = names[0]
 	}
+	if displayNames := form.Value["display_name"]; len(displayNames) > 0 {
+		if len([]rune(displayNames[0])) > 100 {
+			c.Error(apperror.ErrInvalidRequest.WithMessage("Display name must be at most 100 characters"))
+			return
+		}
+		req.DisplayName = &displayNames[0]
+	}
+	if bios := form.Value["bio"]; len(bios) > 0 {
+		if len([]rune(bios[0])) > 500 {
+			c.Error(apperror.ErrInvalidRequest.WithMessage("Bio must be at most 500 characters"))
+			return
+		}
+		req.Bio = &bios[0]
+	}
 
 	// Handle avatar file upload
 	if files := form.File["avatar"]; len(files) > 0 {
@@ -307,32 +582,38 @@ func (h *AuthHandler) UpdateProfile(c *gin.Context) {
 		// Open the file
 		file, err := fileHeader.Open()
 		if err != nil {
-			c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Failed to read file", Message: err.Error()})
+			c.Error(apperror.ErrInvalidRequest.WithMessage("Failed to read file").Wrap(err))
 			return
 		}
 		defer file.Close()
 
+		// Avatars must actually be images, regardless of the declared Content-Type
+		if folder, err := sniffUpload(file, fileHeader); err != nil || folder != "images" {
+			c.Error(apperror.ErrUnsupportedMediaType.WithMessage("Avatar must be a jpg, png, gif or webp image"))
+			return
+		}
+
 		// Upload to MinIO
 		if h.storage != nil {
 			result, err := h.storage.Upload(c.Request.Context(), file, fileHeader, "avatars")
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to upload avatar", Message: err.Error()})
+				c.Error(err)
 				return
 			}
 			req.Avatar = result.URL
 		} else {
-			c.JSON(http.StatusServiceUnavailable, model.ErrorResponse{Error: "File upload service unavailable"})
+			c.Error(apperror.ErrUnavailable.WithMessage("File upload service unavailable"))
 			return
 		}
 	}
 
 	user, err := h.authService.UpdateProfile(userID, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	respond(c, http.StatusOK, user)
 }
 
 // UpdateSettings godoc
@@ -348,17 +629,17 @@ func (h *AuthHandler) UpdateSettings(c *gin.Context) {
 	userID := c.MustGet("user_id").(uuid.UUID)
 	var req model.UpdateSettingsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request", Message: err.Error()})
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
 	user, err := h.authService.UpdateSettings(userID, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	respond(c, http.StatusOK, user)
 }
 
 // GetSettings godoc
@@ -366,6 +647,8 @@ func (h *AuthHandler) UpdateSettings(c *gin.Context) {
 // @Tags Users
 // @Produce json
 // @Security BearerAuth
+// @Param If-None-Match header string false "ETag of the cached copy; 304 Not Modified if unchanged"
+// @Param If-Modified-Since header string false "Last-Modified of the cached copy (HTTP date); 304 Not Modified if unchanged"
 // @Success 200 {object} model.UserResponse
 // @Router /auth/settings [get]
 func (h *AuthHandler) GetSettings(c *gin.Context) {
@@ -374,6 +657,8 @@ func (h *AuthHandler) GetSettings(c *gin.Context) {
 
 // RegisterDevice godoc
 // @Summary Register device for push notifications
+// @Description `device_type` is android, ios, web, or ios_voip for an iOS app's PushKit token, which
+// @Description only rings incoming calls.
 // @Tags Users
 // @Accept json
 // @Produce json
@@ -385,14 +670,106 @@ func (h *AuthHandler) RegisterDevice(c *gin.Context) {
 	userID := c.MustGet("user_id").(uuid.UUID)
 	var req model.RegisterDeviceRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request", Message: err.Error()})
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
 	if err := h.authService.RegisterDevice(userID, req); err != nil {
-		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, model.SuccessResponse{Message: "Device registered successfully"})
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Device registered successfully"})
+}
+
+// GetWebPushKey godoc
+// @Summary Get the VAPID public key for browser push subscriptions
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.WebPushKeyResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /auth/device/webpush/key [get]
+func (h *AuthHandler) GetWebPushKey(c *gin.Context) {
+	if h.vapidPublicKey == "" {
+		c.Error(apperror.ErrNotFound.WithMessage("Web push is not configured"))
+		return
+	}
+	respond(c, http.StatusOK, model.WebPushKeyResponse{PublicKey: h.vapidPublicKey})
+}
+
+// RegisterWebPush godoc
+// @Summary Subscribe this browser to Web Push notifications
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.WebPushSubscribeRequest true "Push subscription"
+// @Success 200 {object} model.SuccessResponse
+// @Router /auth/device/webpush [post]
+func (h *AuthHandler) RegisterWebPush(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	var req model.WebPushSubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	if err := h.authService.RegisterWebPush(userID, req, c.Request.UserAgent()); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Web push subscription registered"})
+}
+
+// UnregisterWebPush godoc
+// @Summary Remove this browser's Web Push subscription
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.WebPushUnsubscribeRequest true "Push subscription endpoint"
+// @Success 200 {object} model.SuccessResponse
+// @Router /auth/device/webpush [delete]
+func (h *AuthHandler) UnregisterWebPush(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	var req model.WebPushUnsubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	if err := h.authService.UnregisterWebPush(userID, req.Endpoint); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Web push subscription removed"})
+}
+
+// clientInfo extracts the caller's IP and user agent
+func clientInfo(c *gin.Context) model.ClientInfo {
+	return model.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// featureFlags returns the flags middleware.FeatureFlags put in the context,
+// or the defaults when it didn't run
+func featureFlags(c *gin.Context) model.FeatureFlags {
+	if flags, ok := c.Get("feature_flags"); ok {
+		return flags.(model.FeatureFlags)
+	}
+	return model.DefaultFeatureFlags()
+}
+
+// respondSession answers a sign-in. A browser that asked for a cookie session
+// gets the token in an HttpOnly cookie instead of the body.
+func respondSession(c *gin.Context, cookies *middleware.SessionCookies, resp *model.LoginResponse) {
+	if cookies.Requested(c) {
+		cookies.Start(c, resp.Token)
+		session := *resp
+		session.Token = ""
+		resp = &session
+	}
+	respond(c, http.StatusOK, resp)
 }
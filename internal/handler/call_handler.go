@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+)
+
+// CallHandler serves call feedback, stats reports and the call quality report
+type CallHandler struct {
+	callService *service.CallService
+}
+
+func NewCallHandler(callService *service.CallService) *CallHandler {
+	return &CallHandler{callService: callService}
+}
+
+// SubmitFeedback godoc
+// @Summary Rate a call
+// @Description One to five stars and the issues you noticed, by either participant; sending it again replaces it.
+// @Tags Calls
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Call ID (call_id of its signaling)"
+// @Param body body model.CallFeedbackRequest true "Feedback"
+// @Success 200 {object} model.CallFeedback
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /calls/{id}/feedback [post]
+func (h *CallHandler) SubmitFeedback(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid call ID"))
+		return
+	}
+	var req model.CallFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	feedback, err := h.callService.SubmitFeedback(id, userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, feedback)
+}
+
+// ReportStats godoc
+// @Summary Report WebRTC stats during a call
+// @Description Send every 10 seconds or so while the call is going on, from RTCPeerConnection.getStats(), and once
+// @Description more right after it ends. Reports are located by the caller's IP for the quality report by region.
+// @Tags Calls
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Call ID (call_id of its signaling)"
+// @Param body body model.CallStatsRequest true "Stats since the last report"
+// @Success 202 {object} model.SuccessResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /calls/{id}/stats [post]
+func (h *CallHandler) ReportStats(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid call ID"))
+		return
+	}
+	var req model.CallStatsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	if err := h.callService.ReportStats(c.Request.Context(), id, userID, c.ClientIP(), req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusAccepted, model.SuccessResponse{Message: "Stats recorded"})
+}
+
+// GetCallQuality godoc
+// @Summary Get call quality by network and region
+// @Description Packet loss, round-trip time and jitter from the stats reported during calls, and the ratings
+// @Description participants gave, by network type and by region (country of the reporter's IP, with GEOIP_*
+// @Description credentials; empty otherwise). Each participant's call counts once however long it lasted.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "First day, YYYY-MM-DD (default: 29 days before to)"
+// @Param to query string false "Last day, YYYY-MM-DD (default: today)"
+// @Success 200 {object} model.CallQualityReport
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /admin/calls/quality [get]
+func (h *CallHandler) GetCallQuality(c *gin.Context) {
+	var req model.CallQualityRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	report, err := h.callService.QualityReport(req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, report)
+}
@@ -1,23 +1,29 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/quocanhngo/gotalk/internal/model"
 	"github.com/quocanhngo/gotalk/internal/service"
-	"github.com/quocanhngo/gotalk/internal/ws"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
 )
 
 // ChatHandler handles chat-related HTTP endpoints
 type ChatHandler struct {
-	chatService *service.ChatService
-	hub         *ws.Hub
+	chatService     *service.ChatService
+	exportService   *service.ExportService
+	insightsService *service.InsightsService
 }
 
-func NewChatHandler(chatService *service.ChatService, hub *ws.Hub) *ChatHandler {
-	return &ChatHandler{chatService: chatService, hub: hub}
+func NewChatHandler(chatService *service.ChatService, exportService *service.ExportService, insightsService *service.InsightsService) *ChatHandler {
+	return &ChatHandler{chatService: chatService, exportService: exportService, insightsService: insightsService}
 }
 
 // GetOrCreateDirect godoc
@@ -30,22 +36,23 @@ func NewChatHandler(chatService *service.ChatService, hub *ws.Hub) *ChatHandler
 // @Param body body model.DirectConversationRequest true "Partner ID"
 // @Success 200 {object} model.DirectConversationResponse
 // @Failure 400 {object} model.ErrorResponse
+// @Failure 429 {object} model.ErrorResponse "direct_limit_reached"
 // @Router /conversations/direct [post]
 func (h *ChatHandler) GetOrCreateDirect(c *gin.Context) {
 	var req model.DirectConversationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request", Message: err.Error()})
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
 	userID := c.MustGet("user_id").(uuid.UUID)
 	resp, err := h.chatService.GetOrCreateDirect(userID, req.ReceiverID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, resp)
+	respond(c, http.StatusOK, resp)
 }
 
 // CreateConversation godoc
@@ -56,22 +63,29 @@ func (h *ChatHandler) GetOrCreateDirect(c *gin.Context) {
 // @Security BearerAuth
 // @Param body body model.CreateConversationRequest true "Create conversation request"
 // @Success 201 {object} model.Conversation
+// @Failure 400 {object} model.ErrorResponse "group_too_large, invalid_members"
+// @Failure 403 {object} model.ErrorResponse "group_limit_reached"
+// @Failure 429 {object} model.ErrorResponse "direct_limit_reached"
 // @Router /conversations [post]
 func (h *ChatHandler) CreateConversation(c *gin.Context) {
 	var req model.CreateConversationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request", Message: err.Error()})
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
 		return
 	}
 
 	userID := c.MustGet("user_id").(uuid.UUID)
+	if limit := featureFlags(c).MaxGroupSize; req.Type == model.ConversationTypeGroup && limit > 0 && groupSize(userID, req.MemberIDs) > limit {
+		c.Error(service.ErrGroupTooLarge.WithMessage(fmt.Sprintf("a group can have at most %d members", limit)))
+		return
+	}
 	conv, err := h.chatService.CreateConversation(userID, req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, conv)
+	respond(c, http.StatusCreated, conv)
 }
 
 // GetConversations godoc
@@ -79,6 +93,8 @@ func (h *ChatHandler) CreateConversation(c *gin.Context) {
 // @Tags Chat
 // @Produce json
 // @Security BearerAuth
+// @Param If-None-Match header string false "ETag of the cached copy; 304 Not Modified if unchanged"
+// @Param If-Modified-Since header string false "Last-Modified of the cached copy (HTTP date); 304 Not Modified if unchanged"
 // @Success 200 {array} model.ConversationResponse
 // @Router /conversations [get]
 func (h *ChatHandler) GetConversations(c *gin.Context) {
@@ -86,11 +102,20 @@ func (h *ChatHandler) GetConversations(c *gin.Context) {
 
 	conversations, err := h.chatService.GetConversations(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to get conversations"})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, conversations)
+	cache := newCacheValidator()
+	for i := range conversations {
+		cache.addConversation(&conversations[i].Conversation)
+		cache.add("unread:"+strconv.Itoa(conversations[i].UnreadCount), time.Time{})
+	}
+	if cache.notModified(c) {
+		return
+	}
+
+	respondList(c, http.StatusOK, conversations, model.PageMeta{Count: len(conversations)})
 }
 
 // GetConversation godoc
@@ -99,122 +124,366 @@ func (h *ChatHandler) GetConversations(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Conversation ID"
+// @Param If-None-Match header string false "ETag of the cached copy; 304 Not Modified if unchanged"
+// @Param If-Modified-Since header string false "Last-Modified of the cached copy (HTTP date); 304 Not Modified if unchanged"
 // @Success 200 {object} model.Conversation
 // @Router /conversations/{id} [get]
 func (h *ChatHandler) GetConversation(c *gin.Context) {
 	convID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid conversation ID"})
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
 		return
 	}
 
 	userID := c.MustGet("user_id").(uuid.UUID)
 	conv, err := h.chatService.GetConversation(convID, userID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, model.ErrorResponse{Error: err.Error()})
+		c.Error(err)
+		return
+	}
+
+	cache := newCacheValidator()
+	cache.addConversation(conv)
+	if cache.notModified(c) {
+		return
+	}
+
+	respond(c, http.StatusOK, conv)
+}
+
+// DeleteConversation godoc
+// @Summary Delete a conversation for everyone
+// @Description Either participant can delete a direct conversation; only admins can delete a group.
+// @Description It can be restored for 30 days (`CONVERSATION_RETENTION`), after which its messages
+// @Description and attachments are deleted for good.
+// @Tags Chat
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /conversations/{id} [delete]
+func (h *ChatHandler) DeleteConversation(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	if err := h.chatService.DeleteConversation(convID, userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Conversation deleted"})
+}
+
+// RestoreConversation godoc
+// @Summary Restore a deleted conversation
+// @Description Brings back a conversation deleted within the retention, with its history, for all
+// @Description of its members. The same members who can delete it can restore it.
+// @Tags Chat
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Success 200 {object} model.Conversation
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /conversations/{id}/restore [post]
+func (h *ChatHandler) RestoreConversation(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	conv, err := h.chatService.RestoreConversation(convID, userID)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, conv)
+	respond(c, http.StatusOK, conv)
+}
+
+// AddMembers godoc
+// @Summary Add people to a group
+// @Description Needs add_members. Members, including the new ones, get a `member_added` event and the new
+// @Description members a notification; the group's onboarding rules then run for each of them.
+// @Tags Chat
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Param body body model.AddMembersRequest true "Users to add"
+// @Success 200 {object} model.Conversation
+// @Failure 400 {object} model.ErrorResponse "group_too_large"
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /conversations/{id}/members [post]
+func (h *ChatHandler) AddMembers(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+	var req model.AddMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	conv, err := h.chatService.AddMembers(convID, userID, req.MemberIDs)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, conv)
+}
+
+// ListMembers godoc
+// @Summary List the members of a conversation
+// @Description Conversation payloads carry `member_count` and only the first members to join (plus
+// @Description you); this pages through all of them in join order.
+// @Tags Chat
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Param after query string false "Cursor: user ID of the last member of the previous page"
+// @Param q query string false "Only members whose name, display name or handle contains this"
+// @Param limit query int false "Number of members to return (default: 50, max: 200)"
+// @Success 200 {array} model.ConversationMember
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /conversations/{id}/members [get]
+func (h *ChatHandler) ListMembers(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+
+	var req model.MemberListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	var after *uuid.UUID
+	if req.After != "" {
+		parsed, err := uuid.Parse(req.After)
+		if err != nil {
+			c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid cursor"))
+			return
+		}
+		after = &parsed
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	members, err := h.chatService.ListMembers(convID, userID, req.Query, after, req.Limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	meta := model.PageMeta{Count: len(members), HasMore: len(members) == service.MemberPageLimit(req.Limit)}
+	if meta.HasMore {
+		meta.NextCursor = members[len(members)-1].UserID.String()
+	}
+	respondList(c, http.StatusOK, members, meta)
 }
 
 // SendMessage godoc
 // @Summary Send a message to a conversation
+// @Description The text is normalized (Unicode NFC, LF line breaks, control characters removed) and may be
+// @Description at most LIMITS_MESSAGE_LENGTH characters. Formatting goes in `entities`, spans over the
+// @Description normalized text counted in characters (code points); markup in the text is kept as is.
+// @Description Code snippets are sent with type `code` and a `language` (see the README for the list).
 // @Tags Chat
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Conversation ID"
 // @Param body body model.SendMessageRequest true "Send message request"
+// @Param Idempotency-Key header string false "Unique key per logical request; retries with the same key replay the first response for 24h"
 // @Success 201 {object} model.Message
+// @Failure 403 {object} model.ErrorResponse "not_member, not_permitted, or conversation_frozen in a frozen group"
+// @Failure 422 {object} model.ErrorResponse "message_too_long"
 // @Router /conversations/{id}/messages [post]
 func (h *ChatHandler) SendMessage(c *gin.Context) {
 	convID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid conversation ID"})
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
 		return
 	}
 
 	var req model.SendMessageRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request", Message: err.Error()})
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+	if serializerOf(c).Version() >= APIv2 && req.FileURL != "" {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("file_url is not supported since API v2; send attachments instead"))
 		return
 	}
 
 	userID := c.MustGet("user_id").(uuid.UUID)
 	msg, err := h.chatService.SendMessage(userID, convID, req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
-	// Broadcast to recipients via WebSocket (send only to others)
-	go func() {
-		memberIDs, err := h.chatService.GetConversationMemberIDs(convID)
-		if err == nil {
-			var recipientIDs []uuid.UUID
-			for _, id := range memberIDs {
-				if id != userID {
-					recipientIDs = append(recipientIDs, id)
-				}
-			}
-
-			if len(recipientIDs) > 0 {
-				broadcastEvent := &model.WSEvent{
-					Type:    model.WSEventNewMessage,
-					Payload: msg,
-				}
-				h.hub.SendToUsers(recipientIDs, broadcastEvent)
-			}
-		}
-	}()
-
-	c.JSON(http.StatusCreated, msg)
+	// Recipients get it over WebSocket through the outbox
+	respond(c, http.StatusCreated, msg)
 }
 
 // GetMessages godoc
 // @Summary Get messages for a conversation
+// @Description Pages newest first. By default it starts from the latest message and `before` pages
+// @Description backwards; `after` pages forwards. `around` (a message ID) and `date` return a window
+// @Description centered on a message, for deep links to search results or pinned messages. Use at most
+// @Description one of them. `meta.next_cursor` continues with older messages as `before`, and
+// @Description `meta.prev_cursor` with newer ones as `after`.
 // @Tags Chat
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Conversation ID"
 // @Param before query string false "Cursor: message ID to get messages before"
+// @Param after query string false "Cursor: message ID to get messages after"
+// @Param around query string false "Message ID to center the page on"
+// @Param date query string false "RFC 3339 time or YYYY-MM-DD date to center the page on"
 // @Param limit query int false "Number of messages to return (default: 50)"
 // @Success 200 {array} model.Message
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
 // @Router /conversations/{id}/messages [get]
 func (h *ChatHandler) GetMessages(c *gin.Context) {
 	convID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid conversation ID"})
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
 		return
 	}
 
 	var req model.MessageListRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid request"})
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	query, err := messageQuery(req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	page, err := h.chatService.GetMessages(convID, userID, query, req.Limit)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	var before *uuid.UUID
+	messages := page.Messages
+	meta := model.PageMeta{Count: len(messages), HasMore: page.HasOlder}
+	if len(messages) > 0 && page.HasOlder {
+		meta.NextCursor = messages[len(messages)-1].ID.String()
+	}
+	if len(messages) > 0 && page.HasNewer {
+		meta.PrevCursor = messages[0].ID.String()
+	}
+	respondList(c, http.StatusOK, messages, meta)
+}
+
+// messageQuery reads the page selection of GetMessages. An unparsable
+// `before` is ignored, as it always has been.
+func messageQuery(req model.MessageListRequest) (service.MessageQuery, error) {
+	var q service.MessageQuery
 	if req.Before != "" {
-		parsed, err := uuid.Parse(req.Before)
-		if err == nil {
-			before = &parsed
+		if parsed, err := uuid.Parse(req.Before); err == nil {
+			q.Before = &parsed
+		}
+	}
+	var err error
+	if q.After, err = messageIDParam("after", req.After); err != nil {
+		return q, err
+	}
+	if q.Around, err = messageIDParam("around", req.Around); err != nil {
+		return q, err
+	}
+	if req.Date != "" {
+		date, err := time.Parse(time.RFC3339, req.Date)
+		if err != nil {
+			if date, err = time.Parse(time.DateOnly, req.Date); err != nil {
+				return q, apperror.ErrInvalidRequest.WithMessage("date must be an RFC 3339 time or a YYYY-MM-DD date")
+			}
+		}
+		q.Date = &date
+	}
+
+	set := 0
+	for _, given := range []bool{req.Before != "", q.After != nil, q.Around != nil, q.Date != nil} {
+		if given {
+			set++
 		}
 	}
+	if set > 1 {
+		return q, apperror.ErrInvalidRequest.WithMessage("Use only one of before, after, around and date")
+	}
+	return q, nil
+}
+
+// messageIDParam parses an optional message ID query parameter
+func messageIDParam(name, value string) (*uuid.UUID, error) {
+	if value == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, apperror.ErrInvalidRequest.WithMessage("Invalid " + name + " message ID")
+	}
+	return &id, nil
+}
+
+// GetMessageInfo godoc
+// @Summary Get delivery and read times of a message
+// @Description For the message's sender only: when each recipient's device received the message and
+// @Description when they read it. Recipients are the members who were in the conversation when it was
+// @Description sent. A message counts as delivered and read once a later one is.
+// @Tags Chat
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Message ID"
+// @Success 200 {object} model.MessageInfo
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /messages/{id}/info [get]
+func (h *ChatHandler) GetMessageInfo(c *gin.Context) {
+	messageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid message ID"))
+		return
+	}
 
 	userID := c.MustGet("user_id").(uuid.UUID)
-	messages, err := h.chatService.GetMessages(convID, userID, before, req.Limit)
+	info, err := h.chatService.GetMessageInfo(messageID, userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, messages)
+	respond(c, http.StatusOK, info)
 }
 
 // MarkAsRead godoc
 // @Summary Mark all messages in a conversation as read
+// @Description The other members and your other devices get a `message_read` event; on your devices
+// @Description it carries the conversation's new `unread_count`.
 // @Tags Chat
 // @Produce json
 // @Security BearerAuth
@@ -224,15 +493,512 @@ func (h *ChatHandler) GetMessages(c *gin.Context) {
 func (h *ChatHandler) MarkAsRead(c *gin.Context) {
 	convID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid conversation ID"})
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	if err := h.chatService.ReadConversation(convID, userID, uuid.Nil, uuid.Nil); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Messages marked as read"})
+}
+
+// FreezeConversation godoc
+// @Summary Freeze a group so only members who manage it can post
+// @Description Announcement mode: members without manage_settings get `conversation_frozen` when they try to post.
+// @Description Needs manage_settings; members get a `conversation_frozen` event.
+// @Tags Chat
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Success 200 {object} model.Conversation
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /conversations/{id}/freeze [post]
+func (h *ChatHandler) FreezeConversation(c *gin.Context) {
+	h.setFrozen(c, true)
+}
+
+// UnfreezeConversation godoc
+// @Summary Let every member of a frozen group post again
+// @Tags Chat
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Success 200 {object} model.Conversation
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /conversations/{id}/freeze [delete]
+func (h *ChatHandler) UnfreezeConversation(c *gin.Context) {
+	h.setFrozen(c, false)
+}
+
+func (h *ChatHandler) setFrozen(c *gin.Context, frozen bool) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	conv, err := h.chatService.SetFrozen(convID, userID, frozen)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, conv)
+}
+
+// GetInsights godoc
+// @Summary Get a group's activity insights
+// @Description Needs manage_settings. Messages, joins and leaves per day, messages by hour of the day (UTC) and the
+// @Description most active members over a range of days, from daily rollups refreshed hourly.
+// @Tags Chat
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Param from query string false "First day, YYYY-MM-DD (default: 29 days before to)"
+// @Param to query string false "Last day, YYYY-MM-DD (default: today)"
+// @Success 200 {object} model.ConversationInsights
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /conversations/{id}/insights [get]
+func (h *ChatHandler) GetInsights(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+
+	var req model.InsightsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	insights, err := h.insightsService.Insights(convID, userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, insights)
+}
+
+// ListPins godoc
+// @Summary List a conversation's pinned messages
+// @Description Top first. The announcement, if any, is also the conversation's `announcement` banner.
+// @Tags Chat
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Success 200 {array} model.PinnedMessage
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /conversations/{id}/pins [get]
+func (h *ChatHandler) ListPins(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	pins, err := h.chatService.ListPins(convID, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respondList(c, http.StatusOK, pins, model.PageMeta{Count: len(pins)})
+}
+
+// PinMessage godoc
+// @Summary Pin a message
+// @Description Needs the pin permission; new pins go on top and a conversation has at most LIMITS_PINS. Pinning
+// @Description with `announcement: true` makes it the conversation's banner, replacing the current one, which
+// @Description needs manage_settings too. Pinning a pinned message again changes only whether it's the
+// @Description announcement. Members get a `pins_changed` event.
+// @Tags Chat
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Param body body model.PinMessageRequest true "Message to pin"
+// @Success 200 {object} model.PinnedMessage
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /conversations/{id}/pins [post]
+func (h *ChatHandler) PinMessage(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+	var req model.PinMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	pin, err := h.chatService.PinMessage(convID, userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, pin)
+}
+
+// ReorderPins godoc
+// @Summary Reorder a conversation's pins
+// @Description Needs the pin permission. List every pinned message once, top first.
+// @Tags Chat
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Param body body model.ReorderPinsRequest true "Pinned messages in their new order"
+// @Success 200 {array} model.PinnedMessage
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /conversations/{id}/pins/order [put]
+func (h *ChatHandler) ReorderPins(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+	var req model.ReorderPinsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	pins, err := h.chatService.ReorderPins(convID, userID, req.MessageIDs)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respondList(c, http.StatusOK, pins, model.PageMeta{Count: len(pins)})
+}
+
+// UnpinMessage godoc
+// @Summary Unpin a message
+// @Description Needs the pin permission, and manage_settings for the announcement. Members get a `pins_changed` event.
+// @Tags Chat
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Param message_id path string true "Message ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /conversations/{id}/pins/{message_id} [delete]
+func (h *ChatHandler) UnpinMessage(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+	messageID, err := uuid.Parse(c.Param("message_id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid message ID"))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	if err := h.chatService.UnpinMessage(convID, userID, messageID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Message unpinned"})
+}
+
+// ListRoles godoc
+// @Summary List a group's roles and their permissions
+// @Description admin (every permission), member and the group's custom roles.
+// @Tags Chat
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Success 200 {array} model.ConversationRole
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /conversations/{id}/roles [get]
+func (h *ChatHandler) ListRoles(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	roles, err := h.chatService.ListRoles(convID, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respondList(c, http.StatusOK, roles, model.PageMeta{Count: len(roles)})
+}
+
+// SaveRole godoc
+// @Summary Create a custom role or change a role's permissions
+// @Description Needs manage_settings. The admin role always has every permission; a group has at most 20 custom roles.
+// @Description Members get a `roles_changed` event.
+// @Tags Chat
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Param role path string true "Role name: 2-20 lowercase letters, digits or underscores"
+// @Param body body model.SaveRoleRequest true "Permissions"
+// @Success 200 {object} model.ConversationRole
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /conversations/{id}/roles/{role} [put]
+func (h *ChatHandler) SaveRole(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+	var req model.SaveRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	role, err := h.chatService.SaveRole(convID, userID, model.MemberRole(c.Param("role")), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, role)
+}
+
+// DeleteRole godoc
+// @Summary Delete a custom role
+// @Description Its members go back to the member role. Deleting the member role resets its permissions.
+// @Description Needs manage_settings; members get a `roles_changed` event.
+// @Tags Chat
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Param role path string true "Role name"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /conversations/{id}/roles/{role} [delete]
+func (h *ChatHandler) DeleteRole(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	if err := h.chatService.DeleteRole(convID, userID, model.MemberRole(c.Param("role"))); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Role deleted"})
+}
+
+// SetMemberRole godoc
+// @Summary Give a member of a group another role
+// @Description Needs manage_settings; only admins can make someone an admin or change an admin's role,
+// @Description and a group keeps at least one admin. Members get a `member_role_changed` event.
+// @Tags Chat
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Param user_id path string true "Member's user ID"
+// @Param body body model.SetMemberRoleRequest true "Role"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /conversations/{id}/members/{user_id}/role [put]
+func (h *ChatHandler) SetMemberRole(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+	memberID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid user ID"))
+		return
+	}
+	var req model.SetMemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	if err := h.chatService.SetMemberRole(convID, userID, memberID, req.Role); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Role changed"})
+}
+
+// ClearHistory godoc
+// @Summary Clear a conversation's history for yourself
+// @Description Hides every message sent so far from you only; the other members keep their history.
+// @Description Your other devices get a `history_cleared` event.
+// @Tags Chat
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /conversations/{id}/clear [post]
+func (h *ChatHandler) ClearHistory(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
 		return
 	}
 
 	userID := c.MustGet("user_id").(uuid.UUID)
-	if err := h.chatService.MarkMessagesAsRead(convID, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to mark as read"})
+	if err := h.chatService.ClearHistory(convID, userID); err != nil {
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, model.SuccessResponse{Message: "Messages marked as read"})
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "History cleared"})
+}
+
+// MarkAllAsRead godoc
+// @Summary Mark several or all conversations as read
+// @Description Marks the listed conversations read in one call, or every conversation when the body
+// @Description is omitted or lists none. Conversations you aren't in are ignored. Your other devices and
+// @Description the members of the affected conversations get one `conversations_read` event each.
+// @Tags Chat
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.MarkReadRequest false "Conversations to mark read"
+// @Success 200 {object} model.MarkReadResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Router /conversations/read-all [post]
+func (h *ChatHandler) MarkAllAsRead(c *gin.Context) {
+	var req model.MarkReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	readIDs, err := h.chatService.MarkAllAsRead(userID, req.ConversationIDs)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.MarkReadResponse{ConversationIDs: readIDs})
+}
+
+// ExportConversation godoc
+// @Summary Export a conversation
+// @Description Queues a downloadable export of the conversation history (messages, sender names,
+// @Description timestamps and attachment links) as JSON, HTML or CSV. Poll the returned export, or wait
+// @Description for the export_ready notification, then download it from its signed link.
+// @Tags Chat
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Param body body model.ExportConversationRequest true "Export format"
+// @Success 202 {object} model.ConversationExport
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 503 {object} model.ErrorResponse
+// @Router /conversations/{id}/export [post]
+func (h *ChatHandler) ExportConversation(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+
+	var req model.ExportConversationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	export, err := h.exportService.Request(convID, userID, req.Format)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusAccepted, export)
+}
+
+// GetExport godoc
+// @Summary Get a conversation export
+// @Description Returns the export's status; once completed it includes a short-lived signed download link.
+// @Tags Chat
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Param export_id path string true "Export ID"
+// @Success 200 {object} model.ConversationExport
+// @Failure 404 {object} model.ErrorResponse
+// @Router /conversations/{id}/exports/{export_id} [get]
+func (h *ChatHandler) GetExport(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+	exportID, err := uuid.Parse(c.Param("export_id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid export ID"))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	export, err := h.exportService.Get(convID, exportID, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, export)
+}
+
+// groupSize counts the members a new group would have: the creator plus the
+// distinct other users
+func groupSize(creatorID uuid.UUID, memberIDs []uuid.UUID) int {
+	members := map[uuid.UUID]bool{creatorID: true}
+	for _, id := range memberIDs {
+		members[id] = true
+	}
+	return len(members)
 }
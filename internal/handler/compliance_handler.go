@@ -0,0 +1,315 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+)
+
+// ComplianceHandler serves the admin endpoints for message retention, legal
+// holds, compliance exports and the audit log
+type ComplianceHandler struct {
+	retention  *service.RetentionService
+	compliance *service.ComplianceService
+	audit      *service.AuditService
+}
+
+func NewComplianceHandler(retention *service.RetentionService, compliance *service.ComplianceService, audit *service.AuditService) *ComplianceHandler {
+	return &ComplianceHandler{retention: retention, compliance: compliance, audit: audit}
+}
+
+// GetRetention godoc
+// @Summary List the message retention policies
+// @Description The deployment's policy and the conversations that override it.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.RetentionPoliciesResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /admin/retention [get]
+func (h *ComplianceHandler) GetRetention(c *gin.Context) {
+	policies, err := h.retention.Policies()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, policies)
+}
+
+// SetRetention godoc
+// @Summary Set the deployment's message retention
+// @Description Messages older than max_age_days are deleted for good, with their attachments, by a background job
+// @Description (0 keeps them). Conversations with their own policy are not affected. The change is audited.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.SetRetentionPolicyRequest true "Policy"
+// @Success 200 {object} model.RetentionPolicy
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /admin/retention [put]
+func (h *ComplianceHandler) SetRetention(c *gin.Context) {
+	h.setPolicy(c, nil)
+}
+
+// DeleteRetention godoc
+// @Summary Remove the deployment's message retention
+// @Description Messages are kept again, except in conversations with their own policy.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.SuccessResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /admin/retention [delete]
+func (h *ComplianceHandler) DeleteRetention(c *gin.Context) {
+	h.removePolicy(c, nil)
+}
+
+// SetConversationRetention godoc
+// @Summary Set a conversation's message retention
+// @Description Overrides the deployment's policy for this conversation; 0 keeps its messages. The change is audited.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Param body body model.SetRetentionPolicyRequest true "Policy"
+// @Success 200 {object} model.RetentionPolicy
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /admin/conversations/{id}/retention [put]
+func (h *ComplianceHandler) SetConversationRetention(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+	h.setPolicy(c, &convID)
+}
+
+// DeleteConversationRetention godoc
+// @Summary Remove a conversation's message retention
+// @Description The deployment's policy applies to it again.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /admin/conversations/{id}/retention [delete]
+func (h *ComplianceHandler) DeleteConversationRetention(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+	h.removePolicy(c, &convID)
+}
+
+func (h *ComplianceHandler) setPolicy(c *gin.Context, convID *uuid.UUID) {
+	var req model.SetRetentionPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	adminID := c.MustGet("user_id").(uuid.UUID)
+	policy, err := h.retention.SetPolicy(adminID, convID, *req.MaxAgeDays)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, policy)
+}
+
+func (h *ComplianceHandler) removePolicy(c *gin.Context, convID *uuid.UUID) {
+	adminID := c.MustGet("user_id").(uuid.UUID)
+	if err := h.retention.RemovePolicy(adminID, convID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Retention policy removed"})
+}
+
+// ListLegalHolds godoc
+// @Summary List the legal holds
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.LegalHold
+// @Failure 403 {object} model.ErrorResponse
+// @Router /admin/legal-holds [get]
+func (h *ComplianceHandler) ListLegalHolds(c *gin.Context) {
+	holds, err := h.compliance.ListHolds()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, holds)
+}
+
+// PlaceLegalHold godoc
+// @Summary Place a legal hold
+// @Description Holds a conversation, or every conversation a user is or was in. Held messages are kept past any
+// @Description retention policy and held conversations are not purged after deletion, until the hold is released.
+// @Description The change is audited.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.CreateLegalHoldRequest true "Hold"
+// @Success 201 {object} model.LegalHold
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /admin/legal-holds [post]
+func (h *ComplianceHandler) PlaceLegalHold(c *gin.Context) {
+	var req model.CreateLegalHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	adminID := c.MustGet("user_id").(uuid.UUID)
+	hold, err := h.compliance.PlaceHold(adminID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusCreated, hold)
+}
+
+// ReleaseLegalHold godoc
+// @Summary Release a legal hold
+// @Description Retention and purges apply again to what the hold covered. The change is audited.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Legal hold ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /admin/legal-holds/{id} [delete]
+func (h *ComplianceHandler) ReleaseLegalHold(c *gin.Context) {
+	holdID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid legal hold ID"))
+		return
+	}
+
+	adminID := c.MustGet("user_id").(uuid.UUID)
+	if err := h.compliance.ReleaseHold(adminID, holdID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Legal hold released"})
+}
+
+// ExportMessages godoc
+// @Summary Export messages for compliance review
+// @Description Streams the matching messages, deleted ones included, oldest first as newline-delimited JSON.
+// @Description user_id matches the messages the user sent and every message in conversations they are or were in.
+// @Description The export is audited.
+// @Tags Admin
+// @Produce application/x-ndjson
+// @Security BearerAuth
+// @Param user_id query string false "Messages sent by or to this user"
+// @Param conversation_id query string false "Messages in this conversation"
+// @Param from query string false "Sent at or after (RFC 3339)"
+// @Param to query string false "Sent before (RFC 3339)"
+// @Success 200 {object} model.ComplianceRecord "One message per line"
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /admin/compliance/export [get]
+func (h *ComplianceHandler) ExportMessages(c *gin.Context) {
+	var req model.ComplianceExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	adminID := c.MustGet("user_id").(uuid.UUID)
+	enc := json.NewEncoder(c.Writer)
+	started := false
+	count, err := h.compliance.Export(c.Request.Context(), adminID, req, func(record *model.ComplianceRecord) error {
+		if !started {
+			started = true
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Header("Content-Disposition", `attachment; filename="gotalk-export-`+time.Now().UTC().Format("20060102T150405Z")+`.ndjson"`)
+			c.Status(http.StatusOK)
+		}
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		if !started {
+			c.Error(err)
+			return
+		}
+		// The response is already streaming; all that's left is to cut it short
+		log.Printf("⚠️  Compliance export by %s stopped after %d messages: %v", c.GetString("email"), count, err)
+		return
+	}
+	if !started {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+	}
+	log.Printf("📤 Compliance export by %s: %d messages", c.GetString("email"), count)
+}
+
+// ListAuditEvents godoc
+// @Summary List the audit log
+// @Description Newest first: retention policy changes and purges, legal holds and compliance exports. Paginate with the created_at of the last item as `before`.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param action query string false "Only this action, e.g. retention.purge"
+// @Param conversation_id query string false "Only events about this conversation"
+// @Param before query string false "Cursor: RFC 3339 timestamp"
+// @Param limit query int false "Number of events to return (default: 50, max: 200)"
+// @Success 200 {array} model.AuditEvent
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /admin/audit [get]
+func (h *ComplianceHandler) ListAuditEvents(c *gin.Context) {
+	var req model.AuditListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	events, err := h.audit.List(req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	meta := model.PageMeta{
+		Count:   len(events),
+		HasMore: len(events) == service.AuditPageLimit(req.Limit),
+	}
+	if meta.HasMore {
+		meta.NextCursor = events[len(events)-1].CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	respondList(c, http.StatusOK, events, meta)
+}
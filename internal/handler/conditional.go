@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+)
+
+// cacheValidator builds the ETag and Last-Modified of a response from the
+// updated_at (and similar) timestamps of what it contains, so polling clients
+// can revalidate without downloading an unchanged body
+type cacheValidator struct {
+	hash         hash.Hash
+	lastModified time.Time
+}
+
+func newCacheValidator() *cacheValidator {
+	return &cacheValidator{hash: sha256.New()}
+}
+
+// add records one part of the response and the time it last changed
+func (v *cacheValidator) add(part string, t time.Time) {
+	v.hash.Write([]byte(part))
+	v.hash.Write([]byte{0})
+	v.hash.Write([]byte(strconv.FormatInt(t.UnixNano(), 10)))
+	v.hash.Write([]byte{0})
+	// Future times (e.g. muted_until) are not modifications
+	if t.After(v.lastModified) && !t.After(time.Now()) {
+		v.lastModified = t
+	}
+}
+
+// addOptional records a nullable timestamp (e.g. last_read_at)
+func (v *cacheValidator) addOptional(part string, t *time.Time) {
+	if t == nil {
+		v.add(part, time.Time{})
+		return
+	}
+	v.add(part, *t)
+}
+
+// addUser records a user; an expiring custom status disappears without an update
+func (v *cacheValidator) addUser(id uuid.UUID, updatedAt time.Time, status *model.UserStatus) {
+	v.add("user:"+id.String(), updatedAt)
+	v.add("status:"+strconv.FormatBool(status != nil), time.Time{})
+}
+
+func (v *cacheValidator) addConversation(conv *model.Conversation) {
+	v.add("conversation:"+conv.ID.String(), conv.UpdatedAt)
+	v.add("members:"+strconv.FormatInt(conv.MemberCount, 10), time.Time{})
+	for i := range conv.Members {
+		m := &conv.Members[i]
+		v.addOptional("read:"+m.UserID.String(), m.LastReadAt)
+		v.addOptional("muted", m.MutedUntil)
+		// Role changes only update the member
+		v.add("role:"+string(m.Role), time.Time{})
+		v.addUser(m.User.ID, m.User.UpdatedAt, m.User.ActiveStatus(time.Now()))
+	}
+	if conv.LastMessage != nil {
+		v.add("message:"+conv.LastMessage.ID.String(), conv.LastMessage.UpdatedAt)
+	}
+	// Pinning doesn't touch the conversation, whose updated_at orders the list
+	if conv.Announcement != nil {
+		v.add("announcement:"+conv.Announcement.ID.String(), conv.Announcement.UpdatedAt)
+	} else {
+		v.add("announcement", time.Time{})
+	}
+}
+
+// etag returns a weak ETag: equal tags mean equivalent, not byte-identical, bodies
+func (v *cacheValidator) etag(c *gin.Context) string {
+	// Versions render the same data differently
+	v.hash.Write([]byte(strconv.Itoa(int(serializerOf(c).Version()))))
+	return `W/"` + hex.EncodeToString(v.hash.Sum(nil))[:32] + `"`
+}
+
+// notModified sets the ETag and Last-Modified headers and, when the client's
+// copy (If-None-Match, or else If-Modified-Since) is current, writes 304 and
+// returns true
+func (v *cacheValidator) notModified(c *gin.Context) bool {
+	etag := v.etag(c)
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if !v.lastModified.IsZero() {
+		c.Header("Last-Modified", v.lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		if !etagMatches(match, etag) {
+			return false
+		}
+	} else if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err != nil ||
+		v.lastModified.IsZero() || v.lastModified.Truncate(time.Second).After(since) {
+		return false
+	}
+
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// etagMatches does the weak comparison of If-None-Match
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+	"github.com/quocanhngo/gotalk/pkg/imaging"
+	"github.com/quocanhngo/gotalk/pkg/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// Largest width/height a client may request
+	maxImageDimension = 2048
+
+	// Resized variants larger than this are served but not cached
+	maxCachedVariantSize = 1 << 20 // 1MB
+
+	imageCacheTTL    = 7 * 24 * time.Hour
+	imageCachePrefix = "gotalk:img:"
+)
+
+// Only objects in these folders are served through the resizing proxy
+var resizableFolders = []string{"images/", "avatars/"}
+
+var errImageNotFound = errors.New("image not found")
+
+// ImageHandler serves resized, cached variants of stored images
+type ImageHandler struct {
+	storage *storage.MinIOStorage
+	rdb     *redis.Client
+}
+
+// NewImageHandler creates a new image handler
+func NewImageHandler(storage *storage.MinIOStorage, rdb *redis.Client) *ImageHandler {
+	return &ImageHandler{storage: storage, rdb: rdb}
+}
+
+// GetImage godoc
+// @Summary Get a resized image
+// @Description Serves a resized variant of a stored image. Variants are cached in Redis. Without w/h the request is redirected to the original.
+// @Tags Upload
+// @Produce image/jpeg,image/png
+// @Param key path string true "Object key (e.g. images/2026/01/02/<uuid>.jpg)"
+// @Param w query int false "Target width (max 2048)"
+// @Param h query int false "Target height (max 2048)"
+// @Param fit query string false "Fit mode" Enums(contain, cover, fill)
+// @Success 200 {file} binary
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /images/{key} [get]
+func (h *ImageHandler) GetImage(c *gin.Context) {
+	if h.storage == nil {
+		c.Error(apperror.ErrUnavailable.WithMessage("File storage unavailable"))
+		return
+	}
+
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if !isResizableKey(key) {
+		c.Error(apperror.ErrNotFound.WithMessage("Image not found"))
+		return
+	}
+
+	width, errW := parseDimension(c.Query("w"))
+	height, errH := parseDimension(c.Query("h"))
+	if errW != nil || errH != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage(fmt.Sprintf("w and h must be between 1 and %d", maxImageDimension)))
+		return
+	}
+	fit, ok := imaging.ParseFit(c.Query("fit"))
+	if !ok {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("fit must be one of: contain, cover, fill"))
+		return
+	}
+
+	// Nothing to resize - let the client fetch the original directly
+	if width == 0 && height == 0 {
+		c.Redirect(http.StatusFound, h.storage.GetPublicURL(key))
+		return
+	}
+
+	ctx := c.Request.Context()
+	cacheKey := fmt.Sprintf("%s%s:%dx%d:%s", imageCachePrefix, key, width, height, fit)
+	etag := fmt.Sprintf(`"%dx%d-%s"`, width, height, fit)
+
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	// Serve from cache if available
+	if cached, err := h.rdb.HGetAll(ctx, cacheKey).Result(); err == nil && cached["data"] != "" {
+		h.writeImage(c, cached["type"], []byte(cached["data"]), etag)
+		return
+	}
+
+	data, contentType, err := h.resize(ctx, key, width, height, fit)
+	if err != nil {
+		if errors.Is(err, imaging.ErrUnsupportedFormat) {
+			// e.g. webp - serve the original rather than failing
+			c.Redirect(http.StatusFound, h.storage.GetPublicURL(key))
+			return
+		}
+		if errors.Is(err, errImageNotFound) {
+			c.Error(apperror.ErrNotFound.WithMessage("Image not found"))
+			return
+		}
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Failed to resize image").Wrap(err))
+		return
+	}
+
+	if len(data) <= maxCachedVariantSize {
+		pipe := h.rdb.TxPipeline()
+		pipe.HSet(ctx, cacheKey, "type", contentType, "data", data)
+		pipe.Expire(ctx, cacheKey, imageCacheTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			log.Printf("⚠️  Failed to cache image variant %s: %v", cacheKey, err)
+		}
+	}
+
+	h.writeImage(c, contentType, data, etag)
+}
+
+// resize loads the original from storage and produces the encoded variant
+func (h *ImageHandler) resize(ctx context.Context, key string, width, height int, fit imaging.Fit) ([]byte, string, error) {
+	obj, err := h.storage.Open(ctx, key)
+	if err != nil {
+		return nil, "", errImageNotFound
+	}
+	defer obj.Close()
+
+	img, format, err := imaging.Decode(obj)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var buf bytes.Buffer
+	contentType, err := imaging.Encode(&buf, imaging.Resize(img, width, height, fit), format)
+	if err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), contentType, nil
+}
+
+// writeImage writes image bytes with long-lived cache headers (object keys are immutable)
+func (h *ImageHandler) writeImage(c *gin.Context, contentType string, data []byte, etag string) {
+	c.Header("Cache-Control", "public, max-age=604800, immutable")
+	c.Header("ETag", etag)
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// isResizableKey rejects path traversal and objects outside image folders
+func isResizableKey(key string) bool {
+	if key == "" || strings.Contains(key, "..") {
+		return false
+	}
+	for _, folder := range resizableFolders {
+		if strings.HasPrefix(key, folder) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDimension parses an optional w/h query value
+func parseDimension(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > maxImageDimension {
+		return 0, errors.New("invalid dimension")
+	}
+	return n, nil
+}
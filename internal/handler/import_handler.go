@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+	"github.com/quocanhngo/gotalk/pkg/storage"
+)
+
+// ImportHandler handles chat imports from other apps
+type ImportHandler struct {
+	importService *service.ImportService
+	maxSize       int64 // largest archive accepted, in bytes
+}
+
+func NewImportHandler(importService *service.ImportService, maxSize int64) *ImportHandler {
+	return &ImportHandler{importService: importService, maxSize: maxSize}
+}
+
+// CreateImport godoc
+// @Summary Import a chat from another app
+// @Description Upload a WhatsApp ("Export chat", with or without media) or Telegram Desktop (single chat,
+// @Description JSON) export as a zip. It is imported in the background into a new group conversation:
+// @Description messages keep their original timestamps and are flagged `imported`, with the original
+// @Description sender's name in `imported_sender`. Poll the returned import for progress.
+// @Tags Imports
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "Export archive (.zip)"
+// @Param source formData string true "App the chat was exported from" Enums(whatsapp, telegram)
+// @Param timezone formData string false "IANA time zone of the exporting phone, for WhatsApp timestamps (default UTC)"
+// @Success 202 {object} model.ChatImport
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 413 {object} model.ErrorResponse
+// @Failure 415 {object} model.ErrorResponse
+// @Router /imports [post]
+func (h *ImportHandler) CreateImport(c *gin.Context) {
+	if !featureFlags(c).UploadsEnabled {
+		c.Error(service.ErrUploadsDisabled)
+		return
+	}
+
+	// Leave room for the other form fields
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxSize+1<<20)
+
+	var req model.ImportRequest
+	if err := c.ShouldBind(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.Error(apperror.ErrPayloadTooLarge.WithMessage(fmt.Sprintf("Archive too large (max %dMB)", h.maxSize>>20)))
+			return
+		}
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("File is required").Wrap(err))
+		return
+	}
+	defer file.Close()
+
+	if header.Size > h.maxSize {
+		c.Error(apperror.ErrPayloadTooLarge.WithMessage(fmt.Sprintf("Archive too large (max %dMB)", h.maxSize>>20)))
+		return
+	}
+	if sniffed, err := storage.SniffContentType(file); err != nil || sniffed != "application/zip" {
+		c.Error(apperror.ErrUnsupportedMediaType.WithMessage("The export must be a zip archive"))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	imp, err := h.importService.Start(c.Request.Context(), userID, req, file, header.Size)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusAccepted, imp)
+}
+
+// GetImport godoc
+// @Summary Get a chat import
+// @Description Returns the import's status and progress; `conversation_id` is set once the conversation exists.
+// @Tags Imports
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Import ID"
+// @Success 200 {object} model.ChatImport
+// @Failure 404 {object} model.ErrorResponse
+// @Router /imports/{id} [get]
+func (h *ImportHandler) GetImport(c *gin.Context) {
+	importID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid import ID"))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	imp, err := h.importService.Get(importID, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, imp)
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+)
+
+// InboundMailPath is where mail providers deliver replies to notification emails
+const InboundMailPath = "/inbound/email"
+
+// maxInboundMailSize bounds an inbound email, attachments included (they're ignored)
+const maxInboundMailSize = 25 << 20
+
+// InboundMailHandler receives emailed replies from the mail provider's inbound
+// webhook. It's called by the provider rather than clients, so it is mounted
+// outside /api and not in the OpenAPI spec.
+type InboundMailHandler struct {
+	replyMail *service.ReplyMailService
+}
+
+func NewInboundMailHandler(replyMail *service.ReplyMailService) *InboundMailHandler {
+	return &InboundMailHandler{replyMail: replyMail}
+}
+
+// RegisterInboundMailRoutes mounts the inbound mail webhook; authMiddleware checks the shared token
+func RegisterInboundMailRoutes(router gin.IRouter, h *InboundMailHandler, authMiddleware gin.HandlerFunc) {
+	router.POST(InboundMailPath, authMiddleware, h.ReceiveReply)
+}
+
+// ReceiveReply posts an emailed reply to its conversation. The body is the raw
+// email (message/rfc822), or a form with the raw email in the "email" field
+// (SendGrid Inbound Parse with raw MIME) or "body-mime" (Mailgun routes).
+// Replies that can never be posted are acknowledged with 200 so the provider
+// doesn't retry them; other failures return an error and are retried.
+// POST /inbound/email
+func (h *InboundMailHandler) ReceiveReply(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxInboundMailSize)
+
+	raw, err := inboundEmail(c)
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Raw email is required").Wrap(err))
+		return
+	}
+
+	if err := h.replyMail.HandleInbound(c.Request.Context(), raw); err != nil {
+		var appErr *apperror.Error
+		if errors.As(err, &appErr) && appErr.Status() < http.StatusInternalServerError {
+			log.Printf("📭 Emailed reply dropped: %v", err)
+			respond(c, http.StatusOK, model.SuccessResponse{Message: "Reply dropped: " + appErr.Message})
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Reply posted"})
+}
+
+// inboundEmail returns the raw email from the request body or form
+func inboundEmail(c *gin.Context) (io.Reader, error) {
+	mediaType, _, _ := mime.ParseMediaType(c.ContentType())
+	if mediaType != "multipart/form-data" && mediaType != "application/x-www-form-urlencoded" {
+		return c.Request.Body, nil
+	}
+
+	for _, field := range []string{"email", "body-mime"} {
+		if value := c.PostForm(field); value != "" {
+			return strings.NewReader(value), nil
+		}
+	}
+	return nil, errors.New("no email or body-mime field")
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+	"github.com/quocanhngo/gotalk/pkg/matrix"
+)
+
+// MatrixAppServicePath is where the homeserver reaches the application service API
+const MatrixAppServicePath = "/_matrix/app/v1"
+
+// MatrixHandler serves the Matrix bridge: the application service API the
+// homeserver pushes events to (outside /api, not in the OpenAPI spec) and the
+// admin endpoints that link conversations to rooms
+type MatrixHandler struct {
+	bridgeService *service.MatrixBridgeService
+}
+
+func NewMatrixHandler(bridgeService *service.MatrixBridgeService) *MatrixHandler {
+	return &MatrixHandler{bridgeService: bridgeService}
+}
+
+// RegisterMatrixRoutes mounts the application service API at /_matrix/app/v1;
+// authMiddleware checks the homeserver token
+func RegisterMatrixRoutes(router gin.IRouter, h *MatrixHandler, authMiddleware gin.HandlerFunc) {
+	as := router.Group(MatrixAppServicePath, authMiddleware)
+	{
+		as.PUT("/transactions/:txnId", h.PutTransaction)
+		as.GET("/users/:userId", h.QueryUser)
+		as.GET("/rooms/:alias", h.QueryRoom)
+	}
+}
+
+// matrixError writes an error in the Matrix {"errcode","error"} format
+func matrixError(c *gin.Context, status int, errcode, message string) {
+	c.AbortWithStatusJSON(status, gin.H{"errcode": errcode, "error": message})
+}
+
+// PutTransaction receives a batch of room events from the homeserver
+// PUT /_matrix/app/v1/transactions/:txnId
+func (h *MatrixHandler) PutTransaction(c *gin.Context) {
+	var txn matrix.Transaction
+	if err := c.ShouldBindJSON(&txn); err != nil {
+		matrixError(c, http.StatusBadRequest, "M_NOT_JSON", "Invalid transaction")
+		return
+	}
+
+	if err := h.bridgeService.HandleTransaction(c.Request.Context(), c.Param("txnId"), &txn); err != nil {
+		// The homeserver retries the transaction until it gets a 200
+		log.Printf("❌ Matrix transaction %s failed: %v", c.Param("txnId"), err)
+		matrixError(c, http.StatusInternalServerError, "M_UNKNOWN", "Failed to process transaction")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// QueryUser tells the homeserver whether a user in our namespace exists.
+// Virtual users are registered as the bridge needs them, never on demand.
+// GET /_matrix/app/v1/users/:userId
+func (h *MatrixHandler) QueryUser(c *gin.Context) {
+	matrixError(c, http.StatusNotFound, "M_NOT_FOUND", "No such user")
+}
+
+// QueryRoom tells the homeserver whether a room alias in our namespace exists.
+// Rooms are linked by admins, never created on demand.
+// GET /_matrix/app/v1/rooms/:alias
+func (h *MatrixHandler) QueryRoom(c *gin.Context) {
+	matrixError(c, http.StatusNotFound, "M_NOT_FOUND", "No such room")
+}
+
+// ListLinks godoc
+// @Summary List conversations bridged to Matrix
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.MatrixRoomLink
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 503 {object} model.ErrorResponse
+// @Router /admin/matrix/links [get]
+func (h *MatrixHandler) ListLinks(c *gin.Context) {
+	links, err := h.bridgeService.ListLinks()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respondList(c, http.StatusOK, links, model.PageMeta{Count: len(links)})
+}
+
+// LinkRoom godoc
+// @Summary Bridge a group conversation to a Matrix room
+// @Description The bridge bot joins the room (invite it first unless the room is public), then the
+// @Description members' virtual users. From then on messages, membership and typing are relayed both
+// @Description ways; remote Matrix users appear as members with a `matrix_id`. A conversation already
+// @Description linked elsewhere is moved to the new room.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.MatrixLinkRequest true "Conversation and room ID or alias"
+// @Success 201 {object} model.MatrixRoomLink
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Failure 503 {object} model.ErrorResponse
+// @Router /admin/matrix/links [post]
+func (h *MatrixHandler) LinkRoom(c *gin.Context) {
+	var req model.MatrixLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	link, err := h.bridgeService.Link(c.Request.Context(), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	log.Printf("🌉 Conversation %s bridged to %s by %s", link.ConversationID, link.RoomID, c.GetString("email"))
+
+	respond(c, http.StatusCreated, link)
+}
+
+// UnlinkRoom godoc
+// @Summary Stop bridging a conversation to Matrix
+// @Description The bridge's users leave the room; ghost members stay in the conversation.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param conversation_id path string true "Conversation ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 503 {object} model.ErrorResponse
+// @Router /admin/matrix/links/{conversation_id} [delete]
+func (h *MatrixHandler) UnlinkRoom(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("conversation_id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+
+	if err := h.bridgeService.Unlink(c.Request.Context(), convID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Conversation unlinked"})
+}
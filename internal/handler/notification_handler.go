@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+)
+
+// NotificationHandler handles notification center endpoints
+type NotificationHandler struct {
+	notifCenter *service.NotificationCenterService
+}
+
+func NewNotificationHandler(notifCenter *service.NotificationCenterService) *NotificationHandler {
+	return &NotificationHandler{notifCenter: notifCenter}
+}
+
+// GetNotifications godoc
+// @Summary List notifications
+// @Description Newest first. Paginate with the created_at of the last item as `before`.
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Param before query string false "Cursor: RFC 3339 timestamp"
+// @Param unread query bool false "Only unread notifications"
+// @Param limit query int false "Number of notifications to return (default: 30, max: 100)"
+// @Success 200 {object} model.NotificationListResponse
+// @Router /notifications [get]
+func (h *NotificationHandler) GetNotifications(c *gin.Context) {
+	var req model.NotificationListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	resp, err := h.notifCenter.List(userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	if serializerOf(c).Version() == APIv1 {
+		respond(c, http.StatusOK, resp)
+		return
+	}
+	meta := model.PageMeta{
+		Count:       len(resp.Notifications),
+		HasMore:     len(resp.Notifications) == service.NotificationPageLimit(req.Limit),
+		UnreadCount: &resp.UnreadCount,
+	}
+	if meta.HasMore {
+		meta.NextCursor = resp.Notifications[len(resp.Notifications)-1].CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	respondList(c, http.StatusOK, resp.Notifications, meta)
+}
+
+// MarkAsRead godoc
+// @Summary Mark a notification as read
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Notification ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /notifications/{id}/read [post]
+func (h *NotificationHandler) MarkAsRead(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid notification ID"))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	if err := h.notifCenter.MarkRead(id, userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Notification marked as read"})
+}
+
+// MarkAllAsRead godoc
+// @Summary Mark all notifications as read
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.SuccessResponse
+// @Router /notifications/read-all [post]
+func (h *NotificationHandler) MarkAllAsRead(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	if err := h.notifCenter.MarkAllRead(userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "All notifications marked as read"})
+}
@@ -0,0 +1,375 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+)
+
+// OAuthHandler lets third-party apps act for users through OAuth 2.0 and
+// OpenID Connect. The token, revocation, key and discovery endpoints answer in
+// the standard formats OAuth libraries expect, in every API version.
+type OAuthHandler struct {
+	oauthService *service.OAuthService
+}
+
+func NewOAuthHandler(oauthService *service.OAuthService) *OAuthHandler {
+	return &OAuthHandler{oauthService: oauthService}
+}
+
+// ==================== App registration ====================
+
+// RegisterClient godoc
+// @Summary Register an OAuth app
+// @Description Registers a third-party app that users can give scoped access to their account.
+// @Description The client secret is only returned here. Public apps (single-page or mobile) get no secret and must use PKCE.
+// @Tags OAuth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.CreateOAuthClientRequest true "App"
+// @Success 201 {object} model.OAuthClientResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /oauth/clients [post]
+func (h *OAuthHandler) RegisterClient(c *gin.Context) {
+	var req model.CreateOAuthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	client, err := h.oauthService.RegisterClient(userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusCreated, client)
+}
+
+// ListClients godoc
+// @Summary List my OAuth apps
+// @Tags OAuth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.OAuthClientResponse
+// @Router /oauth/clients [get]
+func (h *OAuthHandler) ListClients(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	clients, err := h.oauthService.ListClients(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respondList(c, http.StatusOK, clients, model.PageMeta{Count: len(clients)})
+}
+
+// DeleteClient godoc
+// @Summary Delete one of my OAuth apps
+// @Description Every token issued to the app stops working at once.
+// @Tags OAuth
+// @Produce json
+// @Security BearerAuth
+// @Param client_id path string true "Client ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /oauth/clients/{client_id} [delete]
+func (h *OAuthHandler) DeleteClient(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	if err := h.oauthService.DeleteClient(c.Request.Context(), userID, c.Param("client_id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "App deleted"})
+}
+
+// ==================== Consent ====================
+
+// GetAuthorization godoc
+// @Summary Check an authorization request
+// @Description Apps send the browser to OAUTH_AUTHORIZE_URL, the frontend's consent page, with the
+// @Description standard authorization request parameters. The page passes its query string here to
+// @Description learn what to show, then answers with POST /oauth/authorize.
+// @Tags OAuth
+// @Produce json
+// @Security BearerAuth
+// @Param response_type query string true "Must be code"
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string false "One of the app's redirect URIs; optional when it registered only one"
+// @Param scope query string true "Space-separated scopes, e.g. openid read:messages"
+// @Param state query string false "Returned to the app unchanged"
+// @Param code_challenge query string false "PKCE code challenge; required for public apps"
+// @Param code_challenge_method query string false "Must be S256"
+// @Param nonce query string false "Echoed in the ID token"
+// @Success 200 {object} model.OAuthConsent
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /oauth/authorize [get]
+func (h *OAuthHandler) GetAuthorization(c *gin.Context) {
+	var req model.OAuthAuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	consent, err := h.oauthService.Authorize(userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, consent)
+}
+
+// Authorize godoc
+// @Summary Approve or deny an authorization request
+// @Description Takes the same query parameters as GET /oauth/authorize. Returns the app's redirect
+// @Description URI to send the browser to, with ?code= when approved or ?error=access_denied.
+// @Tags OAuth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param response_type query string true "Must be code"
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string false "One of the app's redirect URIs; optional when it registered only one"
+// @Param scope query string true "Space-separated scopes"
+// @Param state query string false "Returned to the app unchanged"
+// @Param code_challenge query string false "PKCE code challenge; required for public apps"
+// @Param code_challenge_method query string false "Must be S256"
+// @Param nonce query string false "Echoed in the ID token"
+// @Param body body model.OAuthDecisionRequest true "The user's answer"
+// @Success 200 {object} model.OAuthRedirect
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /oauth/authorize [post]
+func (h *OAuthHandler) Authorize(c *gin.Context) {
+	var req model.OAuthAuthorizeRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+	var decision model.OAuthDecisionRequest
+	if err := c.ShouldBindJSON(&decision); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	redirectTo, err := h.oauthService.Decide(c.Request.Context(), userID, req, *decision.Approve)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.OAuthRedirect{RedirectTo: redirectTo})
+}
+
+// ==================== Tokens ====================
+
+// Token godoc
+// @Summary Get OAuth tokens
+// @Description Redeems an authorization code (with the PKCE code_verifier) or a refresh token. Refresh
+// @Description tokens are single-use: each response carries a new one. Confidential apps authenticate
+// @Description with HTTP Basic or client_id and client_secret. Errors use the OAuth format.
+// @Tags OAuth
+// @Accept application/x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code or refresh_token" Enums(authorization_code, refresh_token)
+// @Param code formData string false "Authorization code"
+// @Param redirect_uri formData string false "Required when the authorization request had one"
+// @Param code_verifier formData string false "PKCE code verifier"
+// @Param refresh_token formData string false "Refresh token"
+// @Param scope formData string false "Narrower scopes for a refresh"
+// @Param client_id formData string false "Client ID, unless sent with HTTP Basic"
+// @Param client_secret formData string false "Client secret of confidential apps, unless sent with HTTP Basic"
+// @Success 200 {object} model.OAuthTokenResponse
+// @Failure 400 {object} model.OAuthErrorResponse
+// @Failure 401 {object} model.OAuthErrorResponse
+// @Router /oauth/token [post]
+func (h *OAuthHandler) Token(c *gin.Context) {
+	var req model.OAuthTokenRequest
+	if err := c.ShouldBindWith(&req, binding.Form); err != nil {
+		oauthError(c, &service.OAuthError{Code: "invalid_request", Description: "the request must be form-encoded"})
+		return
+	}
+
+	clientID, secret := basicCredentials(c)
+	tokens, err := h.oauthService.Token(c.Request.Context(), req, clientID, secret)
+	if err != nil {
+		oauthError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Revoke godoc
+// @Summary Revoke an OAuth token
+// @Description Revokes one of the app's access or refresh tokens (RFC 7009). Unknown tokens succeed too.
+// @Tags OAuth
+// @Accept application/x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Access or refresh token"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Param client_id formData string false "Client ID, unless sent with HTTP Basic"
+// @Param client_secret formData string false "Client secret of confidential apps, unless sent with HTTP Basic"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 401 {object} model.OAuthErrorResponse
+// @Router /oauth/revoke [post]
+func (h *OAuthHandler) Revoke(c *gin.Context) {
+	var req model.OAuthRevokeRequest
+	if err := c.ShouldBindWith(&req, binding.Form); err != nil || req.Token == "" {
+		oauthError(c, &service.OAuthError{Code: "invalid_request", Description: "token is required"})
+		return
+	}
+
+	clientID, secret := basicCredentials(c)
+	if err := h.oauthService.Revoke(c.Request.Context(), req, clientID, secret); err != nil {
+		oauthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, model.SuccessResponse{Message: "Token revoked"})
+}
+
+// JWKS godoc
+// @Summary Get the ID token signing keys
+// @Description The JSON Web Key Set that verifies ID tokens, as listed in the discovery document.
+// @Tags OAuth
+// @Produce json
+// @Success 200 {object} model.JSONWebKeySet
+// @Failure 404 {object} model.ErrorResponse
+// @Router /oauth/jwks [get]
+func (h *OAuthHandler) JWKS(c *gin.Context) {
+	keys, err := h.oauthService.JWKS()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// UserInfo godoc
+// @Summary Get the signed-in user's OpenID claims
+// @Description For apps with the openid scope; name and picture need profile, email needs email.
+// @Tags OAuth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.UserInfo
+// @Failure 403 {object} model.ErrorResponse
+// @Router /oauth/userinfo [get]
+func (h *OAuthHandler) UserInfo(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	var scopes []string
+	if granted, ok := c.Get("scopes"); ok {
+		scopes = granted.([]string)
+	}
+	info, err := h.oauthService.UserInfo(userID, scopes)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// Discovery serves the OpenID Connect discovery document at
+// /.well-known/openid-configuration, outside the versioned API
+func (h *OAuthHandler) Discovery(c *gin.Context) {
+	doc, err := h.oauthService.Discovery()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, doc)
+}
+
+// ==================== Connected apps ====================
+
+// ListApps godoc
+// @Summary List apps with access to my account
+// @Tags OAuth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.ConnectedApp
+// @Router /auth/apps [get]
+func (h *OAuthHandler) ListApps(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	apps, err := h.oauthService.ConnectedApps(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respondList(c, http.StatusOK, apps, model.PageMeta{Count: len(apps)})
+}
+
+// DisconnectApp godoc
+// @Summary Take an app's access to my account away
+// @Description The app's tokens stop working at once; it has to ask for consent again.
+// @Tags OAuth
+// @Produce json
+// @Security BearerAuth
+// @Param client_id path string true "Client ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /auth/apps/{client_id} [delete]
+func (h *OAuthHandler) DisconnectApp(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	if err := h.oauthService.DisconnectApp(c.Request.Context(), userID, c.Param("client_id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "App disconnected"})
+}
+
+// ==================== Helpers ====================
+
+// oauthError writes a token or revocation endpoint failure in the OAuth error
+// format; other errors go to the error handler as usual
+func oauthError(c *gin.Context, err error) {
+	var oauthErr *service.OAuthError
+	if !errors.As(err, &oauthErr) {
+		c.Error(err)
+		return
+	}
+	status := http.StatusBadRequest
+	if oauthErr.Code == "invalid_client" {
+		status = http.StatusUnauthorized
+		c.Header("WWW-Authenticate", `Basic realm="gotalk"`)
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, model.OAuthErrorResponse{Error: oauthErr.Code, Description: oauthErr.Description})
+}
+
+// basicCredentials returns the client ID and secret of HTTP Basic
+// authentication, which are form-encoded first (RFC 6749 section 2.3.1)
+func basicCredentials(c *gin.Context) (string, string) {
+	id, secret, ok := c.Request.BasicAuth()
+	if !ok {
+		return "", ""
+	}
+	if unescaped, err := url.QueryUnescape(id); err == nil {
+		id = unescaped
+	}
+	if unescaped, err := url.QueryUnescape(secret); err == nil {
+		secret = unescaped
+	}
+	return id, secret
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+)
+
+// OnboardingHandler serves groups' onboarding rules
+type OnboardingHandler struct {
+	onboardingService *service.OnboardingService
+}
+
+func NewOnboardingHandler(onboardingService *service.OnboardingService) *OnboardingHandler {
+	return &OnboardingHandler{onboardingService: onboardingService}
+}
+
+// ListRules godoc
+// @Summary List a group's onboarding rules
+// @Description Needs manage_settings. Rules run in this order for each member added to the group.
+// @Tags Chat
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Success 200 {array} model.OnboardingRule
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /conversations/{id}/onboarding-rules [get]
+func (h *OnboardingHandler) ListRules(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	rules, err := h.onboardingService.ListRules(convID, userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respondList(c, http.StatusOK, rules, model.PageMeta{Count: len(rules)})
+}
+
+// CreateRule godoc
+// @Summary Add an onboarding rule to a group
+// @Description Needs manage_settings; a group has at most 10 rules. When someone is added, `welcome_dm` sends
+// @Description them content as a system message in a direct conversation with you, `post_rules` posts content
+// @Description to the group and `assign_role` gives them a custom role after_days after joining. Rules act on
+// @Description your behalf and are skipped once you no longer manage the group.
+// @Tags Chat
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Param body body model.OnboardingRuleRequest true "Rule"
+// @Success 201 {object} model.OnboardingRule
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /conversations/{id}/onboarding-rules [post]
+func (h *OnboardingHandler) CreateRule(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+	var req model.OnboardingRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	rule, err := h.onboardingService.CreateRule(convID, userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusCreated, rule)
+}
+
+// DeleteRule godoc
+// @Summary Delete an onboarding rule
+// @Description Needs manage_settings. Role assignments the rule already scheduled are dropped.
+// @Tags Chat
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Conversation ID"
+// @Param rule_id path string true "Rule ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /conversations/{id}/onboarding-rules/{rule_id} [delete]
+func (h *OnboardingHandler) DeleteRule(c *gin.Context) {
+	convID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid conversation ID"))
+		return
+	}
+	ruleID, err := uuid.Parse(c.Param("rule_id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid rule ID"))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	if err := h.onboardingService.DeleteRule(convID, userID, ruleID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Rule deleted"})
+}
@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+)
+
+// ProfileHandler handles profile page, custom status and presence endpoints
+type ProfileHandler struct {
+	profileService *service.ProfileService
+	presence       *service.PresenceService
+}
+
+func NewProfileHandler(profileService *service.ProfileService, presence *service.PresenceService) *ProfileHandler {
+	return &ProfileHandler{profileService: profileService, presence: presence}
+}
+
+// GetProfile godoc
+// @Summary Get a user's profile page
+// @Description Bio, custom status and presence, as the current user is allowed to see them
+// @Tags Users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} model.UserProfileResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /users/{id}/profile [get]
+func (h *ProfileHandler) GetProfile(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid user ID"))
+		return
+	}
+
+	viewerID := c.MustGet("user_id").(uuid.UUID)
+	profile, err := h.profileService.GetProfile(id, viewerID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, profile)
+}
+
+// UpdateStatus godoc
+// @Summary Set the current user's custom status
+// @Description Empty message and emoji clear the status. Contacts receive a status_changed WebSocket event.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.UpdateStatusRequest true "Status"
+// @Success 200 {object} model.UserStatus
+// @Failure 400 {object} model.ErrorResponse
+// @Router /auth/status [put]
+func (h *ProfileHandler) UpdateStatus(c *gin.Context) {
+	var req model.UpdateStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	status, err := h.profileService.UpdateStatus(userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, status)
+}
+
+// ClearStatus godoc
+// @Summary Clear the current user's custom status
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.SuccessResponse
+// @Router /auth/status [delete]
+func (h *ProfileHandler) ClearStatus(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	if err := h.profileService.ClearStatus(userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Status cleared"})
+}
+
+// GetAutoReply godoc
+// @Summary Get the current user's auto-reply
+// @Description `data` is null when no auto-reply is set; `active` tells whether it's answering now.
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.AutoReply
+// @Router /auth/auto-reply [get]
+func (h *ProfileHandler) GetAutoReply(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	autoReply, err := h.profileService.GetAutoReply(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, autoReply)
+}
+
+// SetAutoReply godoc
+// @Summary Set the current user's auto-reply (away message)
+// @Description Between starts_at and ends_at, direct messages you receive are answered with the text, at most
+// @Description once a day per conversation. The reply is sent as you with `auto_reply: true`; messages that are
+// @Description auto-replies themselves are never answered.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.SetAutoReplyRequest true "Auto-reply"
+// @Success 200 {object} model.AutoReply
+// @Failure 400 {object} model.ErrorResponse
+// @Router /auth/auto-reply [put]
+func (h *ProfileHandler) SetAutoReply(c *gin.Context) {
+	var req model.SetAutoReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	autoReply, err := h.profileService.SetAutoReply(userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, autoReply)
+}
+
+// ClearAutoReply godoc
+// @Summary Turn the current user's auto-reply off
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.SuccessResponse
+// @Router /auth/auto-reply [delete]
+func (h *ProfileHandler) ClearAutoReply(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	if err := h.profileService.ClearAutoReply(userID); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Auto-reply turned off"})
+}
+
+// GetPresence godoc
+// @Summary Get the current user's presence
+// @Description `state` is what you chose; `current` is how you show now: away after WS_IDLE_TIMEOUT
+// @Description without activity, invisible (offline to others), or offline when not connected.
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} model.PresenceResponse
+// @Router /auth/presence [get]
+func (h *ProfileHandler) GetPresence(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	presence, err := h.presence.Presence(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, presence)
+}
+
+// SetPresence godoc
+// @Summary Set the current user's presence
+// @Description online (away when idle), away, busy (do not disturb) or invisible (offline to others).
+// @Description Those who may see your online status get an online/offline event with the new state;
+// @Description your devices get a presence_changed event. Also settable with the set_presence WebSocket event.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.SetPresenceRequest true "Presence"
+// @Success 200 {object} model.PresenceResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Router /auth/presence [put]
+func (h *ProfileHandler) SetPresence(c *gin.Context) {
+	var req model.SetPresenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	presence, err := h.presence.SetPresence(c.Request.Context(), userID, req.State)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, presence)
+}
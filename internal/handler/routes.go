@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/quocanhngo/gotalk/internal/middleware"
+	"github.com/quocanhngo/gotalk/internal/model"
+)
+
+// Handlers holds every handler mounted under the API base path
+type Handlers struct {
+	Auth         *AuthHandler
+	Chat         *ChatHandler
+	Upload       *UploadHandler
+	Image        *ImageHandler
+	Admin        *AdminHandler
+	Notification *NotificationHandler
+	Profile      *ProfileHandler
+	SSO          *SSOHandler
+	Import       *ImportHandler
+	Matrix       *MatrixHandler
+	OAuth        *OAuthHandler
+	Token        *TokenHandler
+	Compliance   *ComplianceHandler
+	Search       *SearchHandler
+	Call         *CallHandler
+	Template     *TemplateHandler
+	Onboarding   *OnboardingHandler
+	Attachment   *AttachmentHandler
+}
+
+// appScopes lists the endpoints third-party apps (OAuth access tokens) and
+// personal access tokens may call and the scope each needs; such tokens are
+// refused elsewhere
+var appScopes = map[string]string{
+	"GET /conversations":               model.ScopeReadMessages,
+	"GET /conversations/:id":           model.ScopeReadMessages,
+	"GET /conversations/:id/members":   model.ScopeReadMessages,
+	"GET /conversations/:id/messages":  model.ScopeReadMessages,
+	"GET /attachments/:id":             model.ScopeReadMessages,
+	"POST /conversations/:id/messages": model.ScopeWriteMessages,
+	"POST /conversations/:id/read":     model.ScopeWriteMessages,
+	"GET /oauth/userinfo":              model.ScopeOpenID,
+}
+
+// RegisterRoutes mounts the REST API on the given group (e.g. /api/v1).
+// cmd/genapi mounts the same routes to generate and check the OpenAPI spec,
+// so every handler registered here needs a godoc @Router annotation.
+//
+// idempotencyMiddleware guards the non-idempotent POSTs that clients retry
+// (message send, uploads, imports) against duplicates. transferMiddleware
+// gives the routes moving large bodies (uploads, imports, attachments,
+// streamed exports) longer than the server's read and write timeouts.
+func RegisterRoutes(api *gin.RouterGroup, h Handlers, authMiddleware, adminMiddleware, idempotencyMiddleware, transferMiddleware gin.HandlerFunc) {
+	// Auth routes (public)
+	authGroup := api.Group("/auth")
+	{
+		authGroup.POST("/register", h.Auth.Register)
+		authGroup.POST("/verify-otp", h.Auth.VerifyOTP)
+		authGroup.POST("/resend-otp", h.Auth.ResendOTP)
+		authGroup.POST("/login", h.Auth.Login)
+		authGroup.POST("/google", h.Auth.GoogleLogin)
+		authGroup.POST("/magic-link", h.Auth.SendMagicLink)
+		authGroup.GET("/magic-link/verify", h.Auth.MagicLinkPage)
+		authGroup.POST("/magic-link/verify", h.Auth.VerifyMagicLink)
+		authGroup.GET("/sso/login", h.SSO.Login)
+		authGroup.GET("/sso/callback", h.SSO.Callback)
+		authGroup.POST("/sso/token", h.SSO.Token)
+		authGroup.POST("/forgot-password", h.Auth.ForgotPassword)
+		authGroup.POST("/reset-password", h.Auth.ResetPassword)
+		authGroup.POST("/login-alerts/revoke", h.Auth.RevokeLoginAlert)
+	}
+
+	// OAuth endpoints apps call directly (public; apps authenticate themselves)
+	oauthGroup := api.Group("/oauth")
+	{
+		oauthGroup.POST("/token", h.OAuth.Token)
+		oauthGroup.POST("/revoke", h.OAuth.Revoke)
+		oauthGroup.GET("/jwks", h.OAuth.JWKS)
+	}
+
+	// Resized images (public, so they can be used directly in <img> tags)
+	api.GET("/images/*key", h.Image.GetImage)
+
+	// Protected routes
+	protected := api.Group("")
+	protected.Use(authMiddleware, middleware.RequireScopes(api.BasePath(), appScopes))
+	{
+		// Auth
+		protected.POST("/auth/logout", h.Auth.Logout)
+		protected.POST("/auth/logout-all", h.Auth.LogoutAll)
+		protected.GET("/auth/logins", h.Auth.GetLoginHistory)
+		protected.GET("/auth/profile", h.Auth.GetProfile)
+		protected.PUT("/auth/profile", h.Auth.UpdateProfile)
+		protected.GET("/auth/settings", h.Auth.GetSettings)
+		protected.PUT("/auth/settings", h.Auth.UpdateSettings)
+		protected.POST("/auth/device", h.Auth.RegisterDevice)
+		protected.GET("/auth/device/webpush/key", h.Auth.GetWebPushKey)
+		protected.POST("/auth/device/webpush", h.Auth.RegisterWebPush)
+		protected.DELETE("/auth/device/webpush", h.Auth.UnregisterWebPush)
+		protected.PUT("/auth/handle", h.Auth.UpdateHandle)
+		protected.PUT("/auth/status", h.Profile.UpdateStatus)
+		protected.DELETE("/auth/status", h.Profile.ClearStatus)
+		protected.GET("/auth/auto-reply", h.Profile.GetAutoReply)
+		protected.PUT("/auth/auto-reply", h.Profile.SetAutoReply)
+		protected.DELETE("/auth/auto-reply", h.Profile.ClearAutoReply)
+		protected.GET("/auth/presence", h.Profile.GetPresence)
+		protected.PUT("/auth/presence", h.Profile.SetPresence)
+		protected.GET("/users/search", h.Auth.SearchUsers)
+		protected.GET("/search", h.Search.Search)
+		protected.GET("/users/handle-availability", h.Auth.CheckHandle)
+		protected.GET("/users/by-handle/:handle", h.Auth.GetUserByHandle)
+		protected.GET("/users/:id/profile", h.Profile.GetProfile)
+
+		// Third-party apps (OAuth)
+		protected.GET("/auth/apps", h.OAuth.ListApps)
+		protected.DELETE("/auth/apps/:client_id", h.OAuth.DisconnectApp)
+		protected.POST("/oauth/clients", h.OAuth.RegisterClient)
+		protected.GET("/oauth/clients", h.OAuth.ListClients)
+		protected.DELETE("/oauth/clients/:client_id", h.OAuth.DeleteClient)
+		protected.GET("/oauth/authorize", h.OAuth.GetAuthorization)
+		protected.POST("/oauth/authorize", h.OAuth.Authorize)
+		protected.GET("/oauth/userinfo", h.OAuth.UserInfo)
+
+		// Personal access tokens
+		protected.POST("/auth/tokens", h.Token.CreateToken)
+		protected.GET("/auth/tokens", h.Token.ListTokens)
+		protected.DELETE("/auth/tokens/:id", h.Token.RevokeToken)
+
+		// Conversations
+		protected.GET("/conversations", h.Chat.GetConversations)
+		protected.POST("/conversations", h.Chat.CreateConversation)
+		protected.POST("/conversations/direct", h.Chat.GetOrCreateDirect)
+		protected.POST("/conversations/from-template", h.Template.CreateFromTemplate)
+		protected.GET("/conversation-templates", h.Template.ListTemplates)
+		protected.POST("/conversations/read-all", h.Chat.MarkAllAsRead)
+		protected.GET("/conversations/:id", h.Chat.GetConversation)
+		protected.DELETE("/conversations/:id", h.Chat.DeleteConversation)
+		protected.POST("/conversations/:id/restore", h.Chat.RestoreConversation)
+		protected.POST("/conversations/:id/freeze", h.Chat.FreezeConversation)
+		protected.DELETE("/conversations/:id/freeze", h.Chat.UnfreezeConversation)
+		protected.GET("/conversations/:id/members", h.Chat.ListMembers)
+		protected.POST("/conversations/:id/members", h.Chat.AddMembers)
+		protected.GET("/conversations/:id/onboarding-rules", h.Onboarding.ListRules)
+		protected.POST("/conversations/:id/onboarding-rules", h.Onboarding.CreateRule)
+		protected.DELETE("/conversations/:id/onboarding-rules/:rule_id", h.Onboarding.DeleteRule)
+		protected.PUT("/conversations/:id/members/:user_id/role", h.Chat.SetMemberRole)
+		protected.GET("/conversations/:id/pins", h.Chat.ListPins)
+		protected.POST("/conversations/:id/pins", h.Chat.PinMessage)
+		protected.PUT("/conversations/:id/pins/order", h.Chat.ReorderPins)
+		protected.DELETE("/conversations/:id/pins/:message_id", h.Chat.UnpinMessage)
+		protected.GET("/conversations/:id/roles", h.Chat.ListRoles)
+		protected.GET("/conversations/:id/insights", h.Chat.GetInsights)
+		protected.PUT("/conversations/:id/roles/:role", h.Chat.SaveRole)
+		protected.DELETE("/conversations/:id/roles/:role", h.Chat.DeleteRole)
+
+		// Messages
+		protected.GET("/conversations/:id/messages", h.Chat.GetMessages)
+		protected.POST("/conversations/:id/messages", idempotencyMiddleware, h.Chat.SendMessage)
+		protected.POST("/conversations/:id/read", h.Chat.MarkAsRead)
+		protected.POST("/conversations/:id/clear", h.Chat.ClearHistory)
+		protected.GET("/messages/:id/info", h.Chat.GetMessageInfo)
+
+		// Calls
+		protected.POST("/calls/:id/feedback", h.Call.SubmitFeedback)
+		protected.POST("/calls/:id/stats", h.Call.ReportStats)
+
+		// Exports
+		protected.POST("/conversations/:id/export", h.Chat.ExportConversation)
+		protected.GET("/conversations/:id/exports/:export_id", h.Chat.GetExport)
+
+		// Imports from other chat apps
+		protected.POST("/imports", transferMiddleware, idempotencyMiddleware, h.Import.CreateImport)
+		protected.GET("/imports/:id", h.Import.GetImport)
+
+		// Upload
+		protected.POST("/upload", transferMiddleware, idempotencyMiddleware, h.Upload.UploadFile)
+		protected.POST("/upload/multiple", transferMiddleware, idempotencyMiddleware, h.Upload.UploadMultiple)
+		protected.GET("/attachments/:id", transferMiddleware, h.Attachment.GetAttachment)
+
+		// Notification center
+		protected.GET("/notifications", h.Notification.GetNotifications)
+		protected.POST("/notifications/read-all", h.Notification.MarkAllAsRead)
+		protected.POST("/notifications/:id/read", h.Notification.MarkAsRead)
+
+		// Admin
+		admin := protected.Group("/admin")
+		admin.Use(adminMiddleware)
+		{
+			admin.GET("/emails/failed", h.Admin.GetFailedEmails)
+			admin.POST("/emails/failed/:id/resend", h.Admin.ResendFailedEmail)
+			admin.DELETE("/emails/failed/:id", h.Admin.DiscardFailedEmail)
+			admin.POST("/notices", h.Admin.SendNotice)
+			admin.GET("/flags", h.Admin.GetFeatureFlags)
+			admin.PATCH("/flags", h.Admin.UpdateFeatureFlags)
+			admin.GET("/ws/stats", h.Admin.GetWebSocketStats)
+			admin.GET("/membership/stats", h.Admin.GetMembershipCacheStats)
+			admin.GET("/stats", h.Admin.GetUsageStats)
+			admin.GET("/calls/quality", h.Call.GetCallQuality)
+			admin.POST("/conversation-templates", h.Template.CreateTemplate)
+			admin.PUT("/conversation-templates/:id", h.Template.UpdateTemplate)
+			admin.DELETE("/conversation-templates/:id", h.Template.DeleteTemplate)
+			admin.POST("/users/:id/logout", h.Admin.LogoutUser)
+			admin.GET("/invitations", h.Admin.ListInvitations)
+			admin.POST("/invitations", h.Admin.CreateInvitation)
+			admin.DELETE("/invitations/:id", h.Admin.DeleteInvitation)
+			admin.POST("/ldap/sync", h.Admin.SyncLDAP)
+			admin.GET("/matrix/links", h.Matrix.ListLinks)
+			admin.POST("/matrix/links", h.Matrix.LinkRoom)
+			admin.DELETE("/matrix/links/:conversation_id", h.Matrix.UnlinkRoom)
+			admin.GET("/retention", h.Compliance.GetRetention)
+			admin.PUT("/retention", h.Compliance.SetRetention)
+			admin.DELETE("/retention", h.Compliance.DeleteRetention)
+			admin.PUT("/conversations/:id/retention", h.Compliance.SetConversationRetention)
+			admin.DELETE("/conversations/:id/retention", h.Compliance.DeleteConversationRetention)
+			admin.GET("/legal-holds", h.Compliance.ListLegalHolds)
+			admin.POST("/legal-holds", h.Compliance.PlaceLegalHold)
+			admin.DELETE("/legal-holds/:id", h.Compliance.ReleaseLegalHold)
+			admin.GET("/compliance/export", transferMiddleware, h.Compliance.ExportMessages)
+			admin.GET("/audit", h.Compliance.ListAuditEvents)
+		}
+	}
+}
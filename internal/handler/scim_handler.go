@@ -0,0 +1,354 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+)
+
+// SCIMBasePath is where the SCIM 2.0 provisioning API is mounted
+const SCIMBasePath = "/scim/v2"
+
+// SCIMHandler serves the SCIM 2.0 provisioning API used by identity providers
+// (Okta, Entra ID, ...) to sync users and groups. It speaks SCIM rather than the
+// REST API's conventions, so it is mounted outside /api and not in the OpenAPI spec.
+type SCIMHandler struct {
+	scimService *service.SCIMService
+}
+
+func NewSCIMHandler(scimService *service.SCIMService) *SCIMHandler {
+	return &SCIMHandler{scimService: scimService}
+}
+
+// RegisterSCIMRoutes mounts the SCIM API at /scim/v2. errorMiddleware renders
+// SCIM errors and must come first; authMiddleware checks the provisioning token.
+func RegisterSCIMRoutes(router gin.IRouter, h *SCIMHandler, errorMiddleware, authMiddleware gin.HandlerFunc) {
+	scim := router.Group(SCIMBasePath, errorMiddleware, authMiddleware)
+	{
+		scim.GET("/ServiceProviderConfig", h.GetServiceProviderConfig)
+
+		scim.GET("/Users", h.ListUsers)
+		scim.POST("/Users", h.CreateUser)
+		scim.GET("/Users/:id", h.GetUser)
+		scim.PUT("/Users/:id", h.ReplaceUser)
+		scim.PATCH("/Users/:id", h.PatchUser)
+		scim.DELETE("/Users/:id", h.DeleteUser)
+
+		scim.GET("/Groups", h.ListGroups)
+		scim.POST("/Groups", h.CreateGroup)
+		scim.GET("/Groups/:id", h.GetGroup)
+		scim.PUT("/Groups/:id", h.ReplaceGroup)
+		scim.PATCH("/Groups/:id", h.PatchGroup)
+		scim.DELETE("/Groups/:id", h.DeleteGroup)
+	}
+}
+
+// GetServiceProviderConfig describes the supported SCIM features
+// GET /scim/v2/ServiceProviderConfig
+func (h *SCIMHandler) GetServiceProviderConfig(c *gin.Context) {
+	supported := func(ok bool) gin.H { return gin.H{"supported": ok} }
+	scimJSON(c, http.StatusOK, gin.H{
+		"schemas":        []string{model.SCIMSchemaConfig},
+		"patch":          supported(true),
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         gin.H{"supported": true, "maxResults": 200},
+		"changePassword": supported(false),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "Provisioning token",
+			"description": "Authorization: Bearer <SCIM_TOKEN>",
+		}},
+	})
+}
+
+// ==================== Users ====================
+
+// ListUsers lists users, optionally filtered (e.g. filter=userName eq "an@example.com")
+// GET /scim/v2/Users
+func (h *SCIMHandler) ListUsers(c *gin.Context) {
+	startIndex, count := scimPage(c)
+	users, total, err := h.scimService.ListUsers(c.Query("filter"), startIndex, count)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	for i := range users {
+		users[i].Meta.Location = scimLocation(c, "Users", users[i].ID)
+	}
+	scimList(c, users, len(users), total, startIndex)
+}
+
+// GetUser returns one user
+// GET /scim/v2/Users/:id
+func (h *SCIMHandler) GetUser(c *gin.Context) {
+	id, ok := scimID(c, service.ErrUserNotFound)
+	if !ok {
+		return
+	}
+	user, err := h.scimService.GetUser(id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	h.respondUser(c, http.StatusOK, user)
+}
+
+// CreateUser provisions a user
+// POST /scim/v2/Users
+func (h *SCIMHandler) CreateUser(c *gin.Context) {
+	var req model.SCIMUser
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+	user, err := h.scimService.CreateUser(req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	h.respondUser(c, http.StatusCreated, user)
+}
+
+// ReplaceUser replaces a user's attributes
+// PUT /scim/v2/Users/:id
+func (h *SCIMHandler) ReplaceUser(c *gin.Context) {
+	id, ok := scimID(c, service.ErrUserNotFound)
+	if !ok {
+		return
+	}
+	var req model.SCIMUser
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+	user, err := h.scimService.ReplaceUser(id, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	h.respondUser(c, http.StatusOK, user)
+}
+
+// PatchUser updates a user, e.g. {"op": "replace", "path": "active", "value": false} to deactivate
+// PATCH /scim/v2/Users/:id
+func (h *SCIMHandler) PatchUser(c *gin.Context) {
+	id, ok := scimID(c, service.ErrUserNotFound)
+	if !ok {
+		return
+	}
+	var req model.SCIMPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+	user, err := h.scimService.PatchUser(id, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	h.respondUser(c, http.StatusOK, user)
+}
+
+// DeleteUser deprovisions a user
+// DELETE /scim/v2/Users/:id
+func (h *SCIMHandler) DeleteUser(c *gin.Context) {
+	id, ok := scimID(c, service.ErrUserNotFound)
+	if !ok {
+		return
+	}
+	if err := h.scimService.DeleteUser(id); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SCIMHandler) respondUser(c *gin.Context, status int, user *model.SCIMUser) {
+	user.Meta.Location = scimLocation(c, "Users", user.ID)
+	if status == http.StatusCreated {
+		c.Header("Location", user.Meta.Location)
+	}
+	scimJSON(c, status, user)
+}
+
+// ==================== Groups ====================
+
+// ListGroups lists provisioned groups, optionally filtered (e.g. filter=displayName eq "Sales").
+// excludedAttributes=members leaves out the member lists.
+// GET /scim/v2/Groups
+func (h *SCIMHandler) ListGroups(c *gin.Context) {
+	startIndex, count := scimPage(c)
+	groups, total, err := h.scimService.ListGroups(c.Query("filter"), startIndex, count, scimWithMembers(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	for i := range groups {
+		groups[i].Meta.Location = scimLocation(c, "Groups", groups[i].ID)
+	}
+	scimList(c, groups, len(groups), total, startIndex)
+}
+
+// GetGroup returns one provisioned group
+// GET /scim/v2/Groups/:id
+func (h *SCIMHandler) GetGroup(c *gin.Context) {
+	id, ok := scimID(c, service.ErrGroupNotFound)
+	if !ok {
+		return
+	}
+	group, err := h.scimService.GetGroup(id, scimWithMembers(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	h.respondGroup(c, http.StatusOK, group)
+}
+
+// CreateGroup creates a group conversation with the given members
+// POST /scim/v2/Groups
+func (h *SCIMHandler) CreateGroup(c *gin.Context) {
+	var req model.SCIMGroup
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+	group, err := h.scimService.CreateGroup(req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	h.respondGroup(c, http.StatusCreated, group)
+}
+
+// ReplaceGroup replaces a group's name and members
+// PUT /scim/v2/Groups/:id
+func (h *SCIMHandler) ReplaceGroup(c *gin.Context) {
+	id, ok := scimID(c, service.ErrGroupNotFound)
+	if !ok {
+		return
+	}
+	var req model.SCIMGroup
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+	group, err := h.scimService.ReplaceGroup(id, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	h.respondGroup(c, http.StatusOK, group)
+}
+
+// PatchGroup renames a group or adds and removes members
+// PATCH /scim/v2/Groups/:id
+func (h *SCIMHandler) PatchGroup(c *gin.Context) {
+	id, ok := scimID(c, service.ErrGroupNotFound)
+	if !ok {
+		return
+	}
+	var req model.SCIMPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+	group, err := h.scimService.PatchGroup(id, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	h.respondGroup(c, http.StatusOK, group)
+}
+
+// DeleteGroup deletes a provisioned group conversation
+// DELETE /scim/v2/Groups/:id
+func (h *SCIMHandler) DeleteGroup(c *gin.Context) {
+	id, ok := scimID(c, service.ErrGroupNotFound)
+	if !ok {
+		return
+	}
+	if err := h.scimService.DeleteGroup(id); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SCIMHandler) respondGroup(c *gin.Context, status int, group *model.SCIMGroup) {
+	group.Meta.Location = scimLocation(c, "Groups", group.ID)
+	if status == http.StatusCreated {
+		c.Header("Location", group.Meta.Location)
+	}
+	scimJSON(c, status, group)
+}
+
+// ==================== Helpers ====================
+
+// scimJSON writes a SCIM response body
+func scimJSON(c *gin.Context, status int, body interface{}) {
+	c.Header("Content-Type", model.SCIMContentType)
+	c.JSON(status, body)
+}
+
+func scimList(c *gin.Context, resources interface{}, n int, total int64, startIndex int) {
+	scimJSON(c, http.StatusOK, model.SCIMListResponse{
+		Schemas:      []string{model.SCIMSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: n,
+		Resources:    resources,
+	})
+}
+
+// scimPage reads the 1-based startIndex and count query parameters
+func scimPage(c *gin.Context) (int, int) {
+	startIndex, err := strconv.Atoi(c.DefaultQuery("startIndex", "1"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(c.DefaultQuery("count", "-1"))
+	if err != nil {
+		count = -1
+	}
+	return startIndex, count
+}
+
+// scimID parses the :id path parameter; IDs that aren't UUIDs can't exist
+func scimID(c *gin.Context, notFound error) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(notFound)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// scimWithMembers is false when the provider asks to leave out group members
+func scimWithMembers(c *gin.Context) bool {
+	for _, attr := range strings.Split(c.Query("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(attr), "members") {
+			return false
+		}
+	}
+	return true
+}
+
+// scimLocation returns the absolute URL of a resource
+func scimLocation(c *gin.Context, resourceType, id string) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host + SCIMBasePath + "/" + resourceType + "/" + id
+}
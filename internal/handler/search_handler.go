@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+)
+
+// SearchHandler serves the universal search endpoint
+type SearchHandler struct {
+	searchService *service.SearchService
+}
+
+func NewSearchHandler(searchService *service.SearchService) *SearchHandler {
+	return &SearchHandler{searchService: searchService}
+}
+
+// Search godoc
+// @Summary Search messages, groups, people and files
+// @Description One query across the messages you can read (full-text: "quoted phrases", -excluded words, or),
+// @Description the groups you're in, the people you can find and the files shared with you, mixed into one list
+// @Description best first. Names score by whole, leading or partial match, so people and groups named like the
+// @Description query come before messages. With conversation_id only that conversation's messages and files are searched.
+// @Tags Search
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "Search query (2-200 characters)"
+// @Param types query string false "Comma-separated: message, conversation, user, file (default: all)"
+// @Param conversation_id query string false "Only messages and files of this conversation"
+// @Param limit query int false "Number of results to return (default: 20, max: 50)"
+// @Success 200 {object} model.SearchResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Router /search [get]
+func (h *SearchHandler) Search(c *gin.Context) {
+	var req model.SearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	results, err := h.searchService.Search(c.Request.Context(), userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, results)
+}
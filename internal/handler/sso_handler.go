@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quocanhngo/gotalk/internal/middleware"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+)
+
+// SSOHandler handles enterprise single sign-on (OpenID Connect)
+type SSOHandler struct {
+	ssoService  *service.SSOService
+	frontendURL string
+	cookies     *middleware.SessionCookies // nil without the cookie-based auth mode
+}
+
+func NewSSOHandler(ssoService *service.SSOService, frontendURL string) *SSOHandler {
+	return &SSOHandler{ssoService: ssoService, frontendURL: frontendURL}
+}
+
+// UseSessionCookies lets browser clients exchange the SSO code for a cookie session
+func (h *SSOHandler) UseSessionCookies(cookies *middleware.SessionCookies) {
+	h.cookies = cookies
+}
+
+// Login godoc
+// @Summary Start single sign-on
+// @Description Redirects the browser to the organization's identity provider.
+// @Tags Auth
+// @Produce json
+// @Success 302 {object} string "Redirect to the identity provider"
+// @Failure 404 {object} model.ErrorResponse
+// @Router /auth/sso/login [get]
+func (h *SSOHandler) Login(c *gin.Context) {
+	loginURL, err := h.ssoService.LoginURL(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Redirect(http.StatusFound, loginURL)
+}
+
+// Callback godoc
+// @Summary Finish single sign-on
+// @Description The identity provider redirects here. The browser is sent on to SSO_FRONTEND_URL
+// @Description with ?code= (a one-time code for POST /auth/sso/token) or ?error= (an error code).
+// @Description Without a frontend URL the code is returned as JSON.
+// @Tags Auth
+// @Produce json
+// @Param code query string false "Authorization code from the identity provider"
+// @Param state query string false "State from the login redirect"
+// @Success 200 {object} model.SSOCodeResponse
+// @Success 302 {object} string "Redirect to the frontend"
+// @Failure 401 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Router /auth/sso/callback [get]
+func (h *SSOHandler) Callback(c *gin.Context) {
+	var code string
+	var err error
+	if providerErr := c.Query("error"); providerErr != "" {
+		// e.g. access_denied when the user cancels at the provider
+		err = service.ErrSSOFailed.WithMessage("the identity provider declined the sign-in: " + providerErr)
+	} else {
+		code, err = h.ssoService.Callback(c.Request.Context(), c.Query("code"), c.Query("state"), clientInfo(c))
+	}
+
+	if h.frontendURL == "" {
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		respond(c, http.StatusOK, model.SSOCodeResponse{Code: code})
+		return
+	}
+
+	query := url.Values{}
+	if err != nil {
+		appErr := apperror.From(err)
+		if appErr.Status() >= http.StatusInternalServerError {
+			log.Printf("❌ %s %s: %v", c.Request.Method, c.FullPath(), appErr)
+		}
+		query.Set("error", string(appErr.Code))
+	} else {
+		query.Set("code", code)
+	}
+	c.Redirect(http.StatusFound, h.frontendURL+"?"+query.Encode())
+}
+
+// Token godoc
+// @Summary Exchange a single sign-on code for a token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param body body model.SSOTokenRequest true "One-time code from the SSO callback"
+// @Success 200 {object} model.LoginResponse
+// @Failure 401 {object} model.ErrorResponse
+// @Router /auth/sso/token [post]
+func (h *SSOHandler) Token(c *gin.Context) {
+	var req model.SSOTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	resp, err := h.ssoService.ExchangeCode(c.Request.Context(), req.Code)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respondSession(c, h.cookies, resp)
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+)
+
+// TemplateHandler serves conversation templates and creating groups from them
+type TemplateHandler struct {
+	templateService *service.TemplateService
+}
+
+func NewTemplateHandler(templateService *service.TemplateService) *TemplateHandler {
+	return &TemplateHandler{templateService: templateService}
+}
+
+// ListTemplates godoc
+// @Summary List conversation templates
+// @Description The templates groups can be created from; the project and support templates are predefined.
+// @Tags Chat
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.ConversationTemplate
+// @Router /conversation-templates [get]
+func (h *TemplateHandler) ListTemplates(c *gin.Context) {
+	templates, err := h.templateService.List()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respondList(c, http.StatusOK, templates, model.PageMeta{Count: len(templates)})
+}
+
+// CreateFromTemplate godoc
+// @Summary Create a group from a template
+// @Description Creates a group like POST /conversations, then posts the template's messages to it as system
+// @Description messages (type "system") sent by you, e.g. the project checklist or the support welcome.
+// @Tags Chat
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.CreateFromTemplateRequest true "Template and members"
+// @Success 201 {object} model.Conversation
+// @Failure 400 {object} model.ErrorResponse "group_too_large"
+// @Failure 403 {object} model.ErrorResponse "group_limit_reached"
+// @Failure 404 {object} model.ErrorResponse
+// @Router /conversations/from-template [post]
+func (h *TemplateHandler) CreateFromTemplate(c *gin.Context) {
+	var req model.CreateFromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	if limit := featureFlags(c).MaxGroupSize; limit > 0 && groupSize(userID, req.MemberIDs) > limit {
+		c.Error(service.ErrGroupTooLarge.WithMessage(fmt.Sprintf("a group can have at most %d members", limit)))
+		return
+	}
+	conv, err := h.templateService.CreateConversation(userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusCreated, conv)
+}
+
+// CreateTemplate godoc
+// @Summary Create a conversation template
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.ConversationTemplateRequest true "Template"
+// @Success 201 {object} model.ConversationTemplate
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /admin/conversation-templates [post]
+func (h *TemplateHandler) CreateTemplate(c *gin.Context) {
+	var req model.ConversationTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	template, err := h.templateService.Create(req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusCreated, template)
+}
+
+// UpdateTemplate godoc
+// @Summary Replace a conversation template
+// @Description Groups already created from it are unaffected
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Template ID"
+// @Param body body model.ConversationTemplateRequest true "Template"
+// @Success 200 {object} model.ConversationTemplate
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /admin/conversation-templates/{id} [put]
+func (h *TemplateHandler) UpdateTemplate(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid template ID"))
+		return
+	}
+	var req model.ConversationTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	template, err := h.templateService.Update(id, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, template)
+}
+
+// DeleteTemplate godoc
+// @Summary Delete a conversation template
+// @Description Groups already created from it are unaffected
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Template ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 403 {object} model.ErrorResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /admin/conversation-templates/{id} [delete]
+func (h *TemplateHandler) DeleteTemplate(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid template ID"))
+		return
+	}
+
+	if err := h.templateService.Delete(id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Template deleted"})
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+)
+
+// TokenHandler manages the user's personal access tokens
+type TokenHandler struct {
+	tokenService *service.PersonalTokenService
+}
+
+func NewTokenHandler(tokenService *service.PersonalTokenService) *TokenHandler {
+	return &TokenHandler{tokenService: tokenService}
+}
+
+// CreateToken godoc
+// @Summary Create a personal access token
+// @Description Creates a long-lived token for scripts and integrations, sent as "Authorization: Bearer gtk_...".
+// @Description It can only reach the endpoints its scopes allow, the same ones as OAuth apps. The token is only returned here.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body model.CreatePersonalTokenRequest true "Token"
+// @Success 201 {object} model.CreatePersonalTokenResponse
+// @Failure 400 {object} model.ErrorResponse
+// @Failure 409 {object} model.ErrorResponse
+// @Router /auth/tokens [post]
+func (h *TokenHandler) CreateToken(c *gin.Context) {
+	var req model.CreatePersonalTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(apperror.ErrInvalidRequest.Wrap(err))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	token, err := h.tokenService.Create(userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusCreated, token)
+}
+
+// ListTokens godoc
+// @Summary List my personal access tokens
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} model.PersonalAccessToken
+// @Router /auth/tokens [get]
+func (h *TokenHandler) ListTokens(c *gin.Context) {
+	userID := c.MustGet("user_id").(uuid.UUID)
+	tokens, err := h.tokenService.List(userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	respondList(c, http.StatusOK, tokens, model.PageMeta{Count: len(tokens)})
+}
+
+// RevokeToken godoc
+// @Summary Revoke a personal access token
+// @Description The token stops working at once.
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Token ID"
+// @Success 200 {object} model.SuccessResponse
+// @Failure 404 {object} model.ErrorResponse
+// @Router /auth/tokens/{id} [delete]
+func (h *TokenHandler) RevokeToken(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid token ID"))
+		return
+	}
+
+	userID := c.MustGet("user_id").(uuid.UUID)
+	if err := h.tokenService.Revoke(userID, id); err != nil {
+		c.Error(err)
+		return
+	}
+
+	respond(c, http.StatusOK, model.SuccessResponse{Message: "Token revoked"})
+}
@@ -1,11 +1,15 @@
 package handler
 
 import (
+	"errors"
+	"mime/multipart"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/service"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
 	"github.com/quocanhngo/gotalk/pkg/storage"
 )
 
@@ -27,22 +31,28 @@ var allowedVideoTypes = map[string]bool{
 }
 
 var allowedFileTypes = map[string]bool{
-	"application/pdf":    true,
-	"application/msword": true,
-	"application/zip":    true,
-	"audio/mpeg":         true,
-	"audio/ogg":          true,
-	"audio/wav":          true,
+	"application/pdf":               true,
+	"application/msword":            true,
+	"application/vnd.ms-excel":      true,
+	"application/vnd.ms-powerpoint": true,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   true,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         true,
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": true,
+	"application/zip": true,
+	"audio/mpeg":      true,
+	"audio/ogg":       true,
+	"audio/wav":       true,
 }
 
 // UploadHandler handles file upload endpoints
 type UploadHandler struct {
-	storage *storage.MinIOStorage
+	storage     *storage.MinIOStorage
+	blobService *service.BlobService
 }
 
 // NewUploadHandler creates a new upload handler
-func NewUploadHandler(storage *storage.MinIOStorage) *UploadHandler {
-	return &UploadHandler{storage: storage}
+func NewUploadHandler(storage *storage.MinIOStorage, blobService *service.BlobService) *UploadHandler {
+	return &UploadHandler{storage: storage, blobService: blobService}
 }
 
 // UploadFile godoc
@@ -52,57 +62,51 @@ func NewUploadHandler(storage *storage.MinIOStorage) *UploadHandler {
 // @Accept multipart/form-data
 // @Produce json
 // @Security BearerAuth
-// @Param file formance file true "File to upload"
+// @Param file formData file true "File to upload"
 // @Param type formData string false "File type hint: image, video, file" Enums(image, video, file)
+// @Param Idempotency-Key header string false "Unique key per logical request; retries with the same key replay the first response for 24h"
 // @Success 200 {object} model.UploadResponse
 // @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
 // @Failure 413 {object} model.ErrorResponse
 // @Router /upload [post]
 func (h *UploadHandler) UploadFile(c *gin.Context) {
+	if !featureFlags(c).UploadsEnabled {
+		c.Error(service.ErrUploadsDisabled)
+		return
+	}
+
 	// Limit request body size
 	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadSize)
 
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
 		if err.Error() == "http: request body too large" {
-			c.JSON(http.StatusRequestEntityTooLarge, model.ErrorResponse{Error: "File too large (max 50MB)"})
+			c.Error(apperror.ErrPayloadTooLarge.WithMessage("File too large (max 50MB)"))
 			return
 		}
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "File is required", Message: err.Error()})
+		c.Error(apperror.ErrInvalidRequest.WithMessage("File is required").Wrap(err))
 		return
 	}
 	defer file.Close()
 
-	// Detect and validate content type
-	contentType := header.Header.Get("Content-Type")
-	if contentType == "" {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Unable to detect file type"})
-		return
-	}
-
-	// Determine folder based on content type
-	folder := determineFolder(contentType)
-	if folder == "" {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{
-			Error:   "Unsupported file type",
-			Message: "Allowed: jpg, png, gif, webp, mp4, webm, mov, pdf, doc, zip, mp3, ogg, wav",
-		})
+	// Detect content type from the file's bytes and validate against the allow-list
+	folder, err := sniffUpload(file, header)
+	if err != nil {
+		c.Error(apperror.ErrUnsupportedMediaType.WithMessage(err.Error()).WithDetails(gin.H{
+			"allowed": "jpg, png, gif, webp, mp4, webm, mov, pdf, doc, zip, mp3, ogg, wav",
+		}))
 		return
 	}
 
-	// Upload to MinIO
-	result, err := h.storage.Upload(c.Request.Context(), file, header, folder)
+	// Upload to MinIO (identical content is stored once and shared)
+	result, err := h.blobService.Store(c.Request.Context(), file, header, folder)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, model.ErrorResponse{Error: "Failed to upload file", Message: err.Error()})
+		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, model.UploadResponse{
-		URL:      result.URL,
-		FileName: result.FileName,
-		FileSize: result.FileSize,
-		MimeType: result.MimeType,
-	})
+	respond(c, http.StatusOK, toUploadResponse(result))
 }
 
 // UploadMultiple godoc
@@ -112,27 +116,34 @@ func (h *UploadHandler) UploadFile(c *gin.Context) {
 // @Accept multipart/form-data
 // @Produce json
 // @Security BearerAuth
-// @Param files formData file true "Files to upload (max 10)"
+// @Param files formData []file true "Files to upload (max 10)"
+// @Param Idempotency-Key header string false "Unique key per logical request; retries with the same key replay the first response for 24h"
 // @Success 200 {array} model.UploadResponse
 // @Failure 400 {object} model.ErrorResponse
+// @Failure 403 {object} model.ErrorResponse
 // @Router /upload/multiple [post]
 func (h *UploadHandler) UploadMultiple(c *gin.Context) {
+	if !featureFlags(c).UploadsEnabled {
+		c.Error(service.ErrUploadsDisabled)
+		return
+	}
+
 	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadSize)
 
 	form, err := c.MultipartForm()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Invalid form data", Message: err.Error()})
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Invalid form data").Wrap(err))
 		return
 	}
 
 	files := form.File["files"]
 	if len(files) == 0 {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "No files provided"})
+		c.Error(apperror.ErrInvalidRequest.WithMessage("No files provided"))
 		return
 	}
 
 	if len(files) > 10 {
-		c.JSON(http.StatusBadRequest, model.ErrorResponse{Error: "Maximum 10 files allowed"})
+		c.Error(apperror.ErrInvalidRequest.WithMessage("Maximum 10 files allowed"))
 		return
 	}
 
@@ -143,28 +154,62 @@ func (h *UploadHandler) UploadMultiple(c *gin.Context) {
 			continue
 		}
 
-		contentType := header.Header.Get("Content-Type")
-		folder := determineFolder(contentType)
-		if folder == "" {
+		folder, err := sniffUpload(file, header)
+		if err != nil {
 			file.Close()
-			continue // Skip unsupported files
+			continue // Skip unsupported or mismatched files
 		}
 
-		result, err := h.storage.Upload(c.Request.Context(), file, header, folder)
+		result, err := h.blobService.Store(c.Request.Context(), file, header, folder)
 		file.Close()
 		if err != nil {
 			continue // Skip failed uploads
 		}
 
-		results = append(results, model.UploadResponse{
-			URL:      result.URL,
-			FileName: result.FileName,
-			FileSize: result.FileSize,
-			MimeType: result.MimeType,
-		})
+		results = append(results, toUploadResponse(result))
+	}
+
+	respondList(c, http.StatusOK, results, model.PageMeta{Count: len(results)})
+}
+
+// toUploadResponse converts a blob store result to the API response
+func toUploadResponse(result *service.StoreResult) model.UploadResponse {
+	return model.UploadResponse{
+		URL:          result.URL,
+		FileName:     result.FileName,
+		FileSize:     result.FileSize,
+		MimeType:     result.MimeType,
+		ContentHash:  result.Hash,
+		Deduplicated: result.Deduplicated,
+	}
+}
+
+// sniffUpload detects the real content type of an uploaded file, rejects it if
+// it is not allowed or does not match the type declared by the client, and
+// returns the storage folder. On success the header's Content-Type is replaced
+// with the sniffed type so storage never persists a client-supplied value.
+func sniffUpload(file multipart.File, header *multipart.FileHeader) (string, error) {
+	sniffed, err := storage.SniffContentType(file)
+	if err != nil {
+		return "", errors.New("Unable to detect file type")
+	}
+
+	folder := determineFolder(sniffed)
+	if folder == "" {
+		return "", errors.New("Unsupported file type")
+	}
+
+	declared := storage.NormalizeMimeType(header.Header.Get("Content-Type"))
+	if declared != "" && declared != "application/octet-stream" {
+		if !storage.MatchesSniffedType(declared, sniffed) {
+			return "", errors.New("File content does not match its declared type")
+		}
+		// Keep the more specific type, e.g. a .xlsx over the ZIP it sniffed as
+		sniffed = declared
 	}
 
-	c.JSON(http.StatusOK, results)
+	header.Header.Set("Content-Type", sniffed)
+	return folder, nil
 }
 
 // determineFolder returns the storage folder based on content type
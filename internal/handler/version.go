@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quocanhngo/gotalk/internal/model"
+)
+
+// APIVersion is a major version of the REST API
+type APIVersion int
+
+const (
+	APIv1 APIVersion = 1
+	APIv2 APIVersion = 2
+)
+
+// Serializer shapes response bodies for one API version, so the same handlers
+// serve every version
+type Serializer interface {
+	Version() APIVersion
+	// Object renders a single resource or any other non-list body
+	Object(body interface{}) interface{}
+	// List renders a list of items described by meta
+	List(items interface{}, meta model.PageMeta) interface{}
+}
+
+// Versions lists the mounted API versions, oldest first
+var Versions = []struct {
+	Prefix     string
+	Serializer Serializer
+}{
+	{"/api/v1", v1Serializer{}},
+	{"/api/v2", v2Serializer{}},
+}
+
+// RegisterVersions mounts the REST API once per version, e.g. /api/v1 and /api/v2
+func RegisterVersions(router gin.IRouter, h Handlers, authMiddleware, adminMiddleware, idempotencyMiddleware, transferMiddleware gin.HandlerFunc) {
+	for _, v := range Versions {
+		RegisterRoutes(router.Group(v.Prefix, useSerializer(v.Serializer)), h, authMiddleware, adminMiddleware, idempotencyMiddleware, transferMiddleware)
+	}
+}
+
+const serializerKey = "api_serializer"
+
+func useSerializer(s Serializer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(serializerKey, s)
+		c.Next()
+	}
+}
+
+// serializerOf returns the serializer of the request's API version (v1 by default)
+func serializerOf(c *gin.Context) Serializer {
+	if s, ok := c.Get(serializerKey); ok {
+		return s.(Serializer)
+	}
+	return v1Serializer{}
+}
+
+// respond writes a non-list body in the request's API version
+func respond(c *gin.Context, status int, body interface{}) {
+	c.JSON(status, serializerOf(c).Object(body))
+}
+
+// respondList writes a list in the request's API version
+func respondList(c *gin.Context, status int, items interface{}, meta model.PageMeta) {
+	c.JSON(status, serializerOf(c).List(items, meta))
+}
+
+// v1Serializer keeps the original response shapes: bodies as-is, lists as bare arrays
+type v1Serializer struct{}
+
+func (v1Serializer) Version() APIVersion { return APIv1 }
+
+func (v1Serializer) Object(body interface{}) interface{} { return body }
+
+func (v1Serializer) List(items interface{}, _ model.PageMeta) interface{} { return items }
+
+// v2Serializer wraps lists in {data, meta}, writes timestamps as UTC ISO 8601
+// with millisecond precision and drops the legacy single-file message fields
+type v2Serializer struct{}
+
+func (v2Serializer) Version() APIVersion { return APIv2 }
+
+func (v2Serializer) Object(body interface{}) interface{} { return normalizeV2(body) }
+
+func (v2Serializer) List(items interface{}, meta model.PageMeta) interface{} {
+	return model.ListResponse{Data: normalizeV2(items), Meta: meta}
+}
+
+const v2TimeFormat = "2006-01-02T15:04:05.000Z"
+
+// legacyMessageFields were replaced by attachments and are not part of v2
+var legacyMessageFields = []string{"file_url", "file_name", "file_size"}
+
+// normalizeV2 rewrites the JSON form of body. It works on the JSON tree so
+// the shared models don't need per-version struct types.
+func normalizeV2(body interface{}) interface{} {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return body
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree interface{}
+	if err := dec.Decode(&tree); err != nil {
+		return body
+	}
+	return normalizeNode(tree)
+}
+
+func normalizeNode(node interface{}) interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		_, hasSender := n["sender_id"]
+		_, hasConversation := n["conversation_id"]
+		if hasSender && hasConversation {
+			for _, key := range legacyMessageFields {
+				delete(n, key)
+			}
+		}
+		for key, value := range n {
+			if s, ok := value.(string); ok && isTimestampKey(key) {
+				n[key] = normalizeTimestamp(s)
+				continue
+			}
+			n[key] = normalizeNode(value)
+		}
+	case []interface{}:
+		for i, value := range n {
+			n[i] = normalizeNode(value)
+		}
+	}
+	return node
+}
+
+// isTimestampKey matches the names of time fields (created_at, muted_until, last_seen, ...)
+func isTimestampKey(key string) bool {
+	return strings.HasSuffix(key, "_at") || strings.HasSuffix(key, "_until") || key == "last_seen"
+}
+
+func normalizeTimestamp(s string) string {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return s
+	}
+	return t.UTC().Format(v2TimeFormat)
+}
@@ -1,39 +1,100 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
+	"slices"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/quocanhngo/gotalk/internal/middleware"
 	"github.com/quocanhngo/gotalk/internal/model"
 	"github.com/quocanhngo/gotalk/internal/service"
 	"github.com/quocanhngo/gotalk/internal/ws"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
 	"github.com/quocanhngo/gotalk/pkg/auth"
+	"github.com/quocanhngo/gotalk/pkg/notification"
+	"github.com/redis/go-redis/v9"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  4096,
-	WriteBufferSize: 4096,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // In production, validate origin
-	},
-}
-
 // WSHandler handles WebSocket connections
 type WSHandler struct {
 	hub         *ws.Hub
 	chatService *service.ChatService
+	presence    *service.PresenceService
+	notifCenter *service.NotificationCenterService
+	calls       *service.CallService
+	stats       *service.AnalyticsService
 	jwtManager  *auth.JWTManager
+	rdb         *redis.Client
+	relay       service.Relay                     // optional
+	push        *notification.NotificationService // optional
+	upgrader    websocket.Upgrader
+
+	cookies       *middleware.SessionCookies // optional
+	cookieOrigins map[string]bool            // pages allowed to connect with the session cookie
+
+	// Live connections' tokens are checked for expiry and revocation this often
+	authCheckInterval time.Duration
 }
 
-func NewWSHandler(hub *ws.Hub, chatService *service.ChatService, jwtManager *auth.JWTManager) *WSHandler {
+func NewWSHandler(
+	hub *ws.Hub,
+	chatService *service.ChatService,
+	presence *service.PresenceService,
+	notifCenter *service.NotificationCenterService,
+	calls *service.CallService,
+	stats *service.AnalyticsService,
+	jwtManager *auth.JWTManager,
+	rdb *redis.Client,
+	authCheckInterval time.Duration,
+) *WSHandler {
 	return &WSHandler{
-		hub:         hub,
-		chatService: chatService,
-		jwtManager:  jwtManager,
+		hub:               hub,
+		chatService:       chatService,
+		presence:          presence,
+		notifCenter:       notifCenter,
+		calls:             calls,
+		stats:             stats,
+		jwtManager:        jwtManager,
+		rdb:               rdb,
+		authCheckInterval: authCheckInterval,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  4096,
+			WriteBufferSize: 4096,
+			// Clients opt into MessagePack with Sec-WebSocket-Protocol: gotalk.msgpack.v1
+			Subprotocols: ws.Subprotocols,
+			// permessage-deflate for clients that offer it
+			EnableCompression: hub.Compression().Enabled,
+			CheckOrigin: func(r *http.Request) bool {
+				return true // In production, validate origin
+			},
+		},
+	}
+}
+
+// UseRelay also forwards typing indicators to a bridged network
+func (h *WSHandler) UseRelay(relay service.Relay) {
+	h.relay = relay
+}
+
+// UsePush rings callees who aren't connected with a VoIP push
+func (h *WSHandler) UsePush(push *notification.NotificationService) {
+	h.push = push
+}
+
+// UseSessionCookies also authenticates connections by the session cookie of
+// the cookie-based auth mode, when opened from one of origins: browsers send
+// cookies with cross-site WebSocket handshakes, and those can't carry a CSRF token.
+func (h *WSHandler) UseSessionCookies(cookies *middleware.SessionCookies, origins []string) {
+	h.cookies = cookies
+	h.cookieOrigins = map[string]bool{}
+	for _, origin := range origins {
+		h.cookieOrigins[origin] = true
 	}
 }
 
@@ -43,18 +104,25 @@ func (h *WSHandler) HandleWebSocket(c *gin.Context) {
 	// Authenticate via query parameter (WebSocket can't use Authorization header)
 	tokenString := c.Query("token")
 	if tokenString == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token required"})
+		tokenString = h.cookies.Token(c)
+		if tokenString != "" && !h.cookieOrigins[c.GetHeader("Origin")] {
+			c.Error(apperror.ErrForbidden.WithMessage("Cookie sessions can only connect from an allowed origin"))
+			return
+		}
+	}
+	if tokenString == "" {
+		c.Error(apperror.ErrUnauthorized.WithMessage("Token required"))
 		return
 	}
 
-	claims, err := h.jwtManager.ValidateToken(tokenString)
+	claims, err := h.authenticate(c.Request.Context(), tokenString)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Error(err)
 		return
 	}
 
 	// Upgrade HTTP to WebSocket
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
 		return
@@ -63,18 +131,178 @@ func (h *WSHandler) HandleWebSocket(c *gin.Context) {
 	// Create client and register with hub
 	// Use Name from claims
 	client := ws.NewClient(h.hub, conn, claims.UserID, claims.Name)
+	client.SetCredentials(credentialsOf(tokenString, claims))
+	if h.upgrader.EnableCompression && ws.OffersCompression(c.Request.Header) {
+		client.UseCompression()
+	}
 	h.hub.Register(client)
 
-	log.Printf("✅ WS Connected: UserID=%s Name=%s", claims.UserID, claims.Name)
+	log.Printf("✅ WS Connected: UserID=%s Name=%s Encoding=%s Compressed=%v", claims.UserID, claims.Name, client.Codec().Name(), client.Compressed())
 
 	// Start read/write pumps in goroutines
 	go client.WritePump()
 	go client.ReadPump(h.handleWSMessage)
+
+	go h.sendBootstrap(client)
+}
+
+// authenticate validates a token and checks it wasn't revoked by a logout or
+// the user's deactivation, like the HTTP auth middleware. Tokens issued to
+// OAuth apps are refused.
+func (h *WSHandler) authenticate(ctx context.Context, tokenString string) (*auth.Claims, error) {
+	claims, err := h.jwtManager.ValidateToken(tokenString)
+	if err != nil {
+		return nil, apperror.ErrUnauthorized.WithMessage("Invalid token")
+	}
+	if claims.ClientID != "" {
+		// OAuth apps only reach the REST endpoints their scopes allow
+		return nil, apperror.New(apperror.CodeInsufficientScope, "apps can't open a WebSocket")
+	}
+	values, err := h.rdb.MGet(ctx, auth.RevocationKeys(tokenString, claims.UserID)...).Result()
+	if err != nil {
+		return nil, apperror.ErrInternal.WithMessage("Auth server error").Wrap(err)
+	}
+	if auth.Revoked(values, tokenString, credentialsOf(tokenString, claims).IssuedAt) {
+		return nil, apperror.ErrUnauthorized.WithMessage("Token has been revoked")
+	}
+	return claims, nil
+}
+
+// credentialsOf returns what a connection keeps of the token it authenticated with
+func credentialsOf(tokenString string, claims *auth.Claims) ws.Credentials {
+	credentials := ws.Credentials{Token: tokenString}
+	if claims.IssuedAt != nil {
+		credentials.IssuedAt = claims.IssuedAt.Time
+	}
+	if claims.ExpiresAt != nil {
+		credentials.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return credentials
+}
+
+// RunCredentialChecks closes, every authCheckInterval, the connections whose
+// token expired or was revoked, and warns those about to expire so they send
+// refresh_token, until ctx is cancelled
+func (h *WSHandler) RunCredentialChecks(ctx context.Context) {
+	ticker := time.NewTicker(h.authCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			h.checkCredentials(ctx, now)
+		}
+	}
+}
+
+// checkCredentials checks the tokens of every connection on this instance
+// with one Redis round trip. When Redis fails only expiry is checked.
+func (h *WSHandler) checkCredentials(ctx context.Context, now time.Time) {
+	clients := h.hub.Clients()
+	if len(clients) == 0 {
+		return
+	}
+
+	credentials := make([]ws.Credentials, len(clients))
+	checks := make([]*redis.SliceCmd, len(clients))
+	_, err := h.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, client := range clients {
+			credentials[i] = client.Credentials()
+			checks[i] = pipe.MGet(ctx, auth.RevocationKeys(credentials[i].Token, client.UserID)...)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("⚠️  Failed to check WebSocket tokens for revocation: %v", err)
+	}
+
+	closed := 0
+	for i, client := range clients {
+		expiresAt := credentials[i].ExpiresAt
+		revoked := err == nil && auth.Revoked(checks[i].Val(), credentials[i].Token, credentials[i].IssuedAt)
+		if revoked || (!expiresAt.IsZero() && !now.Before(expiresAt)) {
+			client.Close(ws.CloseAuthExpired)
+			closed++
+			continue
+		}
+		// Two intervals ahead, so the warning can't be skipped
+		if !expiresAt.IsZero() && expiresAt.Sub(now) <= 2*h.authCheckInterval && client.WarnExpiryOnce() {
+			client.Send(&model.WSEvent{
+				Type:    model.WSEventTokenExpiring,
+				Payload: model.TokenExpiryEvent{ExpiresAt: expiresAt},
+			})
+		}
+	}
+	if closed > 0 {
+		log.Printf("🔒 Closed %d WebSocket connections with expired or revoked tokens", closed)
+	}
+}
+
+// handleRefreshToken swaps the connection's token for a newer one of the same
+// user, so it outlives the token it connected with
+func (h *WSHandler) handleRefreshToken(client *ws.Client, event model.WSEvent) {
+	payloadBytes, _ := json.Marshal(event.Payload)
+	var payload model.RefreshTokenRequest
+	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		return
+	}
+
+	claims, err := h.authenticate(context.Background(), payload.Token)
+	if err == nil && claims.UserID != client.UserID {
+		err = apperror.ErrUnauthorized.WithMessage("Token belongs to another user")
+	}
+	if err != nil {
+		appErr := apperror.From(err)
+		client.Send(&model.WSEvent{
+			Type:    model.WSEventError,
+			Payload: model.ErrorResponse{Code: appErr.Code, Error: appErr.Message},
+		})
+		return
+	}
+
+	credentials := credentialsOf(payload.Token, claims)
+	client.SetCredentials(credentials)
+	client.Send(&model.WSEvent{
+		Type:    model.WSEventTokenRefreshed,
+		Payload: model.TokenExpiryEvent{ExpiresAt: credentials.ExpiresAt},
+	})
+}
+
+// sendBootstrap pushes the initial state snapshot to a new connection
+func (h *WSHandler) sendBootstrap(client *ws.Client) {
+	snapshot, err := h.chatService.Bootstrap(client.UserID)
+	if err != nil {
+		log.Printf("⚠️  Failed to build bootstrap for %s: %v", client.UserID, err)
+		return
+	}
+	if err := client.Send(&model.WSEvent{Type: model.WSEventBootstrap, Payload: snapshot}); err != nil {
+		log.Printf("⚠️  Failed to send bootstrap to %s: %v", client.UserID, err)
+	}
+}
+
+// activityEvents are the client events that show the user is at the device,
+// unlike those clients send on their own (delivery receipts, subscriptions, tokens)
+var activityEvents = map[string]bool{
+	model.WSEventNewMessage:  true,
+	model.WSEventTyping:      true,
+	model.WSEventMessageRead: true,
+	model.WSEventCallOffer:   true,
+	model.WSEventCallAnswer:  true,
+
+	model.WSEventCallScreenShare: true,
+	model.WSEventCallTrackState:  true,
+	model.WSEventCallChat:        true,
+	model.WSEventCallReaction:    true,
 }
 
 // handleWSMessage processes incoming WebSocket messages from clients
 func (h *WSHandler) handleWSMessage(client *ws.Client, event model.WSEvent) {
 	log.Printf("📩 WS Received from %s (%s): %s", client.Name, client.UserID, event.Type)
+	if activityEvents[event.Type] {
+		h.presence.Active(client.UserID)
+	}
 
 	switch event.Type {
 	case model.WSEventNewMessage:
@@ -89,6 +317,21 @@ func (h *WSHandler) handleWSMessage(client *ws.Client, event model.WSEvent) {
 	case model.WSEventMessageRead:
 		h.handleMessageRead(client, event)
 
+	case model.WSEventMessageDelivered:
+		h.handleMessageDelivered(client, event)
+
+	case model.WSEventPresenceSubscribe:
+		h.handlePresenceSubscribe(client, event)
+
+	case model.WSEventPresenceUnsubscribe:
+		h.handlePresenceUnsubscribe(client, event)
+
+	case model.WSEventRefreshToken:
+		h.handleRefreshToken(client, event)
+
+	case model.WSEventSetPresence:
+		h.handleSetPresence(client, event)
+
 	// WebRTC Signaling events
 	case model.WSEventCallOffer:
 		h.handleCallSignaling(client, event)
@@ -102,6 +345,10 @@ func (h *WSHandler) handleWSMessage(client *ws.Client, event model.WSEvent) {
 	case model.WSEventCallHangup:
 		h.handleCallSignaling(client, event)
 
+	case model.WSEventCallRenegotiate, model.WSEventCallScreenShare, model.WSEventCallTrackState,
+		model.WSEventCallChat, model.WSEventCallReaction:
+		h.handleCallSignaling(client, event)
+
 	default:
 		log.Printf("Unknown WebSocket event type: %s", event.Type)
 	}
@@ -112,9 +359,11 @@ func (h *WSHandler) handleNewMessage(client *ws.Client, event model.WSEvent) {
 	// Parse the payload
 	payloadBytes, _ := json.Marshal(event.Payload)
 	var payload struct {
-		ConversationID uuid.UUID `json:"conversation_id"`
-		Content        string    `json:"content"`
-		Type           string    `json:"type"`
+		ConversationID uuid.UUID             `json:"conversation_id"`
+		Content        string                `json:"content"`
+		Entities       model.MessageEntities `json:"entities"`
+		Type           string                `json:"type"`
+		Language       string                `json:"language"`
 	}
 	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
 		log.Printf("Error parsing new_message payload: %v", err)
@@ -128,29 +377,30 @@ func (h *WSHandler) handleNewMessage(client *ws.Client, event model.WSEvent) {
 	}
 
 	msg, err := h.chatService.SendMessage(client.UserID, payload.ConversationID, model.SendMessageRequest{
-		Content: payload.Content,
-		Type:    msgType,
+		Content:  payload.Content,
+		Entities: payload.Entities,
+		Type:     msgType,
+		Language: payload.Language,
 	})
 	if err != nil {
 		log.Printf("Error saving message: %v", err)
+		// Tell the sender why, e.g. conversation_frozen; internal errors stay in the log
+		appErr := apperror.From(err)
+		if appErr.Status() < http.StatusInternalServerError {
+			h.hub.SendToUser(client.UserID, &model.WSEvent{
+				Type:    model.WSEventError,
+				Payload: model.ErrorResponse{Code: appErr.Code, Error: appErr.Message},
+			})
+		}
 		return
 	}
 
-	// Get all members of the conversation
-	memberIDs, err := h.chatService.GetConversationMemberIDs(payload.ConversationID)
-	if err != nil {
-		log.Printf("Error getting member IDs: %v", err)
-		return
-	}
-
-	// Broadcast new message to all conversation members
-	broadcastEvent := &model.WSEvent{
+	// The other members get it through the outbox; echo it to the sender's own
+	// connections, which sent it without getting a response
+	h.hub.SendToUser(client.UserID, &model.WSEvent{
 		Type:    model.WSEventNewMessage,
 		Payload: msg,
-	}
-
-	log.Printf("📢 Broadcasting 'new_message' to %d members of conv %s", len(memberIDs), payload.ConversationID)
-	h.hub.SendToUsers(memberIDs, broadcastEvent)
+	})
 }
 
 // handleTyping broadcasts typing indicator to conversation members
@@ -164,6 +414,10 @@ func (h *WSHandler) handleTyping(client *ws.Client, event model.WSEvent) {
 	}
 
 	memberIDs, _ := h.chatService.GetConversationMemberIDs(payload.ConversationID)
+	if len(memberIDs) > ws.TypingSummaryThreshold {
+		h.updateTyping(client, payload.ConversationID, memberIDs, true)
+		return
+	}
 
 	typingEvent := &model.WSEvent{
 		Type: model.WSEventTyping,
@@ -180,6 +434,7 @@ func (h *WSHandler) handleTyping(client *ws.Client, event model.WSEvent) {
 			h.hub.SendToUser(memberID, typingEvent)
 		}
 	}
+	h.relayTyping(client, payload.ConversationID, memberIDs, true)
 }
 
 // handleStopTyping broadcasts stop typing indicator
@@ -193,6 +448,10 @@ func (h *WSHandler) handleStopTyping(client *ws.Client, event model.WSEvent) {
 	}
 
 	memberIDs, _ := h.chatService.GetConversationMemberIDs(payload.ConversationID)
+	if len(memberIDs) > ws.TypingSummaryThreshold {
+		h.updateTyping(client, payload.ConversationID, memberIDs, false)
+		return
+	}
 
 	stopEvent := &model.WSEvent{
 		Type: model.WSEventStopTyping,
@@ -208,6 +467,25 @@ func (h *WSHandler) handleStopTyping(client *ws.Client, event model.WSEvent) {
 			h.hub.SendToUser(memberID, stopEvent)
 		}
 	}
+	h.relayTyping(client, payload.ConversationID, memberIDs, false)
+}
+
+// updateTyping feeds the typing summaries of a large conversation, which are
+// sent once a second instead of an event per member and keystroke
+func (h *WSHandler) updateTyping(client *ws.Client, convID uuid.UUID, memberIDs []uuid.UUID, typing bool) {
+	if !slices.Contains(memberIDs, client.UserID) {
+		return
+	}
+	h.hub.UpdateTyping(convID, memberIDs, client.UserID, client.Name, typing)
+	h.relayTyping(client, convID, memberIDs, typing)
+}
+
+// relayTyping forwards a member's typing indicator to the bridged network, if any
+func (h *WSHandler) relayTyping(client *ws.Client, convID uuid.UUID, memberIDs []uuid.UUID, typing bool) {
+	if h.relay == nil || !slices.Contains(memberIDs, client.UserID) {
+		return
+	}
+	h.relay.RelayTyping(convID, client.UserID, typing)
 }
 
 // handleMessageRead processes read receipt events
@@ -221,41 +499,188 @@ func (h *WSHandler) handleMessageRead(client *ws.Client, event model.WSEvent) {
 		return
 	}
 
-	// Mark messages as read in DB
-	_ = h.chatService.MarkMessagesAsRead(payload.ConversationID, client.UserID)
+	// Other members get the receipt; the user's other devices the new unread count
+	if err := h.chatService.ReadConversation(payload.ConversationID, client.UserID, payload.MessageID, client.ID); err != nil {
+		log.Printf("⚠️  Failed to mark conversation %s read: %v", payload.ConversationID, err)
+	}
+}
 
-	// Notify other members about read receipt
-	memberIDs, _ := h.chatService.GetConversationMemberIDs(payload.ConversationID)
+// handleMessageDelivered records that a message reached the client; the
+// sender is told the first time one of the user's devices gets it
+func (h *WSHandler) handleMessageDelivered(client *ws.Client, event model.WSEvent) {
+	payloadBytes, _ := json.Marshal(event.Payload)
+	var payload model.MessageDeliveredEvent
+	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		return
+	}
 
-	readEvent := &model.WSEvent{
-		Type: model.WSEventMessageRead,
-		Payload: model.MessageReadEvent{
-			ConversationID: payload.ConversationID,
-			MessageID:      payload.MessageID,
-			UserID:         client.UserID,
-		},
+	if err := h.chatService.MarkDelivered(payload.ConversationID, client.UserID, payload.MessageID); err != nil {
+		log.Printf("⚠️  Failed to record delivery of message %s: %v", payload.MessageID, err)
 	}
+}
 
-	for _, memberID := range memberIDs {
-		if memberID != client.UserID {
-			h.hub.SendToUser(memberID, readEvent)
-		}
+// handlePresenceSubscribe starts the online/offline events of some users for
+// this connection and answers with which of them are online now
+func (h *WSHandler) handlePresenceSubscribe(client *ws.Client, event model.WSEvent) {
+	payloadBytes, _ := json.Marshal(event.Payload)
+	var payload model.PresenceSubscription
+	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		return
+	}
+
+	watched := h.hub.SubscribePresence(client, payload.UserIDs)
+	if len(watched) == 0 {
+		return
+	}
+	state, err := h.presence.StateAmong(context.Background(), client.UserID, watched)
+	if err != nil {
+		log.Printf("⚠️  Failed to load presence for %s: %v", client.UserID, err)
+		return
+	}
+	if err := client.Send(&model.WSEvent{
+		Type:    model.WSEventPresenceState,
+		Payload: state,
+	}); err != nil {
+		log.Printf("⚠️  Failed to send presence state to %s: %v", client.UserID, err)
+	}
+}
+
+// handlePresenceUnsubscribe stops the online/offline events of some users for this connection
+func (h *WSHandler) handlePresenceUnsubscribe(client *ws.Client, event model.WSEvent) {
+	payloadBytes, _ := json.Marshal(event.Payload)
+	var payload model.PresenceSubscription
+	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		return
 	}
+	h.hub.UnsubscribePresence(client, payload.UserIDs)
 }
 
-// handleCallSignaling forwards WebRTC signaling events to the target user
+// handleSetPresence changes how the user shows to others; errors go back to
+// the connection, the new presence to all the user's devices
+func (h *WSHandler) handleSetPresence(client *ws.Client, event model.WSEvent) {
+	payloadBytes, _ := json.Marshal(event.Payload)
+	var payload model.SetPresenceRequest
+	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		return
+	}
+
+	if _, err := h.presence.SetPresence(context.Background(), client.UserID, payload.State); err != nil {
+		appErr := apperror.From(err)
+		client.Send(&model.WSEvent{
+			Type:    model.WSEventError,
+			Payload: model.ErrorResponse{Code: appErr.Code, Error: appErr.Message},
+		})
+	}
+}
+
+// handleCallSignaling forwards WebRTC signaling events to the target user,
+// once the sender may reach them. Refused events go back as an error.
 func (h *WSHandler) handleCallSignaling(client *ws.Client, event model.WSEvent) {
 	log.Printf("📡 Signal: %s -> %s", event.Type, client.UserID)
 
 	payloadBytes, _ := json.Marshal(event.Payload)
-	var payload struct {
-		To uuid.UUID `json:"to"`
+	var signal model.CallSignal
+	var fields map[string]interface{}
+	if err := json.Unmarshal(payloadBytes, &signal); err != nil {
+		log.Printf("❌ Error parsing signal payload: %v", err)
+		return
 	}
-	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+	if err := json.Unmarshal(payloadBytes, &fields); err != nil {
 		log.Printf("❌ Error parsing signal payload: %v", err)
 		return
 	}
 
-	// Forward the event as-is to the target user
-	h.hub.SendToUser(payload.To, &event)
+	var err error
+	if signal.From != uuid.Nil && signal.From != client.UserID {
+		err = service.ErrCallSpoofed
+	} else {
+		err = h.calls.AuthorizeSignal(context.Background(), event.Type, client.UserID, &signal)
+	}
+	if err == nil && event.Type == model.WSEventCallChat {
+		err = h.keepCallChat(client, &signal, fields)
+	}
+	if err != nil {
+		log.Printf("⚠️  Dropped %s from %s to %s: %v", event.Type, client.UserID, signal.To, err)
+		appErr := apperror.From(err)
+		client.Send(&model.WSEvent{
+			Type:    model.WSEventError,
+			Payload: model.ErrorResponse{Code: appErr.Code, Error: appErr.Message},
+		})
+		return
+	}
+
+	if err := h.calls.Record(event.Type, client.UserID, &signal); err != nil {
+		log.Printf("⚠️  Failed to record %s of call %s: %v", event.Type, signal.CallID, err)
+	}
+
+	// The callee learns who is calling from the server, not the caller. Offers
+	// get an ID, for CallKit and ConnectionService, and later events the ID of
+	// their call.
+	fields["from"] = client.UserID
+	if signal.CallID != uuid.Nil {
+		fields["call_id"] = signal.CallID
+	}
+	event.Payload = fields
+
+	// Track ringing calls so an unanswered one shows up as a missed call, and
+	// time answered ones for the usage stats
+	switch event.Type {
+	case model.WSEventCallOffer:
+		h.notifCenter.CallOffered(client.UserID, signal.To)
+		h.ringOffline(client, notification.Call{
+			ID:             signal.CallID,
+			CallerID:       client.UserID,
+			CallerName:     client.Name,
+			ConversationID: signal.ConversationID,
+			CallType:       signal.CallType,
+		}, signal.To)
+	case model.WSEventCallAnswer:
+		h.notifCenter.CallAnswered(client.UserID, signal.To)
+		h.stats.CallAnswered(client.UserID, signal.To)
+	case model.WSEventCallHangup:
+		h.notifCenter.CallEnded(client.UserID, signal.To, client.Name)
+		h.stats.CallEnded(client.UserID, signal.To)
+	}
+
+	h.hub.SendToUser(signal.To, &event)
+}
+
+// keepCallChat forwards the cleaned text of an in-call chat message and, when
+// the sender flagged it, also posts it to the conversation
+func (h *WSHandler) keepCallChat(client *ws.Client, signal *model.CallSignal, fields map[string]interface{}) error {
+	fields["text"] = signal.Text
+	if !signal.Persist {
+		return nil
+	}
+	msg, err := h.chatService.SendMessage(client.UserID, signal.ConversationID, model.SendMessageRequest{Content: signal.Text})
+	if err != nil {
+		return err
+	}
+	fields["message_id"] = msg.ID
+	// The conversation's other members get it through the outbox, as any message
+	h.hub.SendToUser(client.UserID, &model.WSEvent{
+		Type:    model.WSEventNewMessage,
+		Payload: msg,
+	})
+	return nil
+}
+
+// ringOffline rings the callee with a push when they have no live connection
+// anywhere
+func (h *WSHandler) ringOffline(client *ws.Client, call notification.Call, calleeID uuid.UUID) {
+	if h.push == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		present, err := h.hub.IsUserPresent(ctx, calleeID)
+		if err != nil || present {
+			return
+		}
+		if err := h.push.SendCallNotification(ctx, calleeID, call); err != nil {
+			log.Printf("⚠️  Failed to ring %s for %s: %v", calleeID, client.UserID, err)
+		}
+	}()
 }
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TrackActivity reports the user behind each authenticated request to track,
+// e.g. to count daily active users. It runs after the rest of the chain, so it
+// can be mounted before AuthMiddleware (which sets "user_id").
+func TrackActivity(track func(userID uuid.UUID)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if userID, ok := c.Get("user_id"); ok {
+			track(userID.(uuid.UUID))
+		}
+	}
+}
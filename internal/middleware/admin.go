@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+)
+
+// AdminMiddleware restricts a route group to the configured admin emails.
+// Must run after AuthMiddleware (which sets "email" in the context).
+func AdminMiddleware(adminEmails []string) gin.HandlerFunc {
+	admins := make(map[string]bool, len(adminEmails))
+	for _, email := range adminEmails {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			admins[email] = true
+		}
+	}
+
+	return func(c *gin.Context) {
+		email := strings.ToLower(c.GetString("email"))
+		if !admins[email] {
+			c.Error(apperror.ErrForbidden.WithMessage("Admin access required"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -2,53 +2,101 @@ package middleware
 
 import (
 	"context"
-	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
 	"github.com/quocanhngo/gotalk/pkg/auth"
 	"github.com/redis/go-redis/v9"
 )
 
-// AuthMiddleware validates JWT tokens and injects user claims into context
-func AuthMiddleware(jwtManager *auth.JWTManager, rdb *redis.Client) gin.HandlerFunc {
+// PersonalTokenAuthenticator resolves a personal access token presented from
+// ip to its owner's claims, with Scope set to the token's scopes
+type PersonalTokenAuthenticator func(token, ip string) (*auth.Claims, error)
+
+// AuthMiddleware validates JWT tokens and personal access tokens and injects
+// user claims into context. With cookies, requests without an Authorization
+// header may present the token in the session cookie instead (see CSRF).
+func AuthMiddleware(jwtManager *auth.JWTManager, rdb *redis.Client, personalTokens PersonalTokenAuthenticator, cookies *SessionCookies) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
-			return
+		tokenString := cookies.Token(c)
+		if tokenString == "" {
+			authHeader := c.GetHeader("Authorization")
+			if authHeader == "" {
+				c.Error(apperror.ErrUnauthorized.WithMessage("Authorization header required"))
+				c.Abort()
+				return
+			}
+
+			parts := strings.SplitN(authHeader, " ", 2)
+			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+				c.Error(apperror.ErrUnauthorized.WithMessage("Invalid authorization format. Use: Bearer <token>"))
+				c.Abort()
+				return
+			}
+			tokenString = parts[1]
 		}
+		c.Set("token", tokenString)
 
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization format. Use: Bearer <token>"})
+		if auth.IsPersonalToken(tokenString) {
+			claims, err := personalTokens(tokenString, c.ClientIP())
+			if err != nil {
+				c.Error(err)
+				c.Abort()
+				return
+			}
+			// Personal access token; RequireScopes limits what it reaches
+			c.Set("user_id", claims.UserID)
+			c.Set("email", claims.Email)
+			c.Set("scopes", strings.Fields(claims.Scope))
+			c.Next()
 			return
 		}
 
-		tokenString := parts[1]
+		claims, err := jwtManager.ValidateToken(tokenString)
+		if err != nil {
+			c.Error(apperror.ErrUnauthorized.WithMessage("Invalid or expired token"))
+			c.Abort()
+			return
+		}
 
-		// Check blacklist
+		// Check blacklist (logged-out tokens, deactivated users, logouts on all devices,
+		// disconnected OAuth apps)
+		keys := auth.RevocationKeys(tokenString, claims.UserID)
+		if claims.ClientID != "" {
+			keys = auth.AppRevocationKeys(tokenString, claims.UserID, claims.ClientID)
+		}
 		ctx := context.Background()
-		exists, err := rdb.Exists(ctx, "blacklist:"+tokenString).Result()
+		values, err := rdb.MGet(ctx, keys...).Result()
 		if err != nil {
 			// Redis error, fail safe or fail closed? Fail closed for security.
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Auth server error"})
+			c.Error(apperror.ErrInternal.WithMessage("Auth server error").Wrap(err))
+			c.Abort()
 			return
 		}
-		if exists > 0 {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
-			return
+		var issuedAt time.Time
+		if claims.IssuedAt != nil {
+			issuedAt = claims.IssuedAt.Time
 		}
-
-		claims, err := jwtManager.ValidateToken(tokenString)
-		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		revoked := auth.Revoked(values, tokenString, issuedAt)
+		if claims.ClientID != "" {
+			revoked = auth.AppRevoked(values, issuedAt)
+		}
+		if revoked {
+			c.Error(apperror.ErrUnauthorized.WithMessage("Token has been revoked"))
+			c.Abort()
 			return
 		}
 
 		// Store user info in context for downstream handlers
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
+		if claims.ClientID != "" {
+			// Token of an OAuth app acting for the user; RequireScopes limits what it reaches
+			c.Set("client_id", claims.ClientID)
+			c.Set("scopes", strings.Fields(claims.Scope))
+		}
 
 		c.Next()
 	}
@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Compression gzip- or deflate-encodes responses whose Content-Type is in
+// contentTypes (entries like "text/*" match a whole family) and whose body
+// reaches minSize bytes, for clients that accept it. WebSocket upgrades and
+// responses that are already encoded are left alone, and file streams are
+// excluded by not listing their types. level is a compress/flate level.
+func Compression(level, minSize int, contentTypes []string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(contentTypes))
+	for _, t := range contentTypes {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			allowed[t] = true
+		}
+	}
+	if level < flate.HuffmanOnly || level > flate.BestCompression {
+		level = flate.DefaultCompression
+	}
+
+	return func(c *gin.Context) {
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead ||
+			strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{
+			ResponseWriter: c.Writer,
+			encoding:       encoding,
+			level:          level,
+			minSize:        minSize,
+			allowed:        allowed,
+		}
+		c.Writer = w
+		c.Header("Vary", "Accept-Encoding")
+		c.Next()
+
+		if err := w.finish(); err != nil {
+			log.Printf("⚠️  Failed to compress response for %s: %v", c.Request.URL.Path, err)
+		}
+	}
+}
+
+// negotiateEncoding picks gzip, then deflate, from an Accept-Encoding header
+func negotiateEncoding(header string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		accepted[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+	switch {
+	case accepted["gzip"] || accepted["*"]:
+		return "gzip"
+	case accepted["deflate"]:
+		return "deflate"
+	}
+	return ""
+}
+
+// compressWriter buffers the body until it knows whether to compress it: the
+// Content-Type must be allowed and the body must reach minSize
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	level    int
+	minSize  int
+	allowed  map[string]bool
+
+	decided    bool
+	buf        bytes.Buffer
+	compressor io.WriteCloser
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		if !w.compressible() {
+			w.decided = true
+		} else {
+			w.buf.Write(b)
+			if w.buf.Len() < w.minSize {
+				return len(b), nil
+			}
+			if err := w.start(); err != nil {
+				return 0, err
+			}
+			return len(b), nil
+		}
+	}
+	if w.compressor != nil {
+		return w.compressor.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written reports true once the handler has written a body, even if it is still buffered
+func (w *compressWriter) Written() bool {
+	return w.ResponseWriter.Written() || w.buf.Len() > 0
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided && w.buf.Len() > 0 {
+		// Streaming: don't wait for minSize
+		if err := w.start(); err != nil {
+			return
+		}
+	}
+	if f, ok := w.compressor.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressWriter) compressible() bool {
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	switch w.Status() {
+	case http.StatusNoContent, http.StatusNotModified, http.StatusPartialContent:
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	family, _, _ := strings.Cut(mediaType, "/")
+	return w.allowed[mediaType] || w.allowed[family+"/*"]
+}
+
+// start switches to compressed output and writes what was buffered
+func (w *compressWriter) start() error {
+	w.decided = true
+	header := w.Header()
+	header.Set("Content-Encoding", w.encoding)
+	header.Del("Content-Length")
+	header.Del("Accept-Ranges")
+
+	var err error
+	if w.encoding == "gzip" {
+		w.compressor, err = gzip.NewWriterLevel(w.ResponseWriter, w.level)
+	} else {
+		w.compressor, err = flate.NewWriter(w.ResponseWriter, w.level)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.compressor.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// finish writes a body that stayed below minSize as-is, or ends the compressed stream
+func (w *compressWriter) finish() error {
+	if w.compressor != nil {
+		return w.compressor.Close()
+	}
+	if w.buf.Len() > 0 {
+		_, err := w.ResponseWriter.Write(w.buf.Bytes())
+		return err
+	}
+	return nil
+}
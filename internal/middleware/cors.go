@@ -12,8 +12,8 @@ func CORSMiddleware(origins []string) gin.HandlerFunc {
 	return cors.New(cors.Config{
 		AllowOrigins:     origins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", IdempotencyKeyHeader, "If-None-Match", "If-Modified-Since", SessionModeHeader, CSRFHeader},
+		ExposeHeaders:    []string{"Content-Length", IdempotentReplayedHeader, "ETag", "Last-Modified", CSRFHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	})
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+)
+
+const (
+	// SessionModeHeader set to "cookie" on a sign-in asks for the session in
+	// cookies instead of the token in the response body
+	SessionModeHeader = "X-Session-Mode"
+	// CSRFHeader carries the CSRF cookie's value on requests authenticated by
+	// the session cookie. Such responses also carry it, for clients on
+	// another host that can't read the cookie.
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// SessionCookies is the cookie-based auth mode: browser clients keep the
+// access token in an HttpOnly cookie, out of reach of scripts, instead of in
+// localStorage. Requests authenticated by it must repeat the CSRF cookie in
+// the X-CSRF-Token header (double submit), which other sites can't read.
+// A nil *SessionCookies means the mode is off.
+type SessionCookies struct {
+	Name     string // of the session cookie; the CSRF cookie is Name + "_csrf"
+	Domain   string // empty = the API's host only
+	Secure   bool
+	SameSite http.SameSite
+	MaxAge   time.Duration // the access token's lifetime
+}
+
+func (s *SessionCookies) csrfName() string {
+	return s.Name + "_csrf"
+}
+
+// Requested reports whether a sign-in asked for a cookie session
+func (s *SessionCookies) Requested(c *gin.Context) bool {
+	return s != nil && strings.EqualFold(c.GetHeader(SessionModeHeader), "cookie")
+}
+
+// Start sets the session and a fresh CSRF cookie
+func (s *SessionCookies) Start(c *gin.Context, token string) {
+	csrfToken := rand.Text()
+	s.set(c, s.Name, token, true, s.MaxAge)
+	s.set(c, s.csrfName(), csrfToken, false, s.MaxAge)
+	c.Header(CSRFHeader, csrfToken)
+}
+
+// End clears the session cookies
+func (s *SessionCookies) End(c *gin.Context) {
+	if s == nil {
+		return
+	}
+	s.set(c, s.Name, "", true, -1)
+	s.set(c, s.csrfName(), "", false, -1)
+}
+
+func (s *SessionCookies) set(c *gin.Context, name, value string, httpOnly bool, maxAge time.Duration) {
+	seconds := int(maxAge / time.Second)
+	if maxAge < 0 {
+		seconds = -1
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   s.Domain,
+		MaxAge:   seconds,
+		Secure:   s.Secure,
+		HttpOnly: httpOnly,
+		SameSite: s.SameSite,
+	})
+}
+
+// Token returns the access token of a request authenticated by the session
+// cookie rather than an Authorization header, or ""
+func (s *SessionCookies) Token(c *gin.Context) string {
+	if s == nil || c.GetHeader("Authorization") != "" {
+		return ""
+	}
+	token, err := c.Cookie(s.Name)
+	if err != nil {
+		return ""
+	}
+	return token
+}
+
+// CSRF refuses state-changing requests authenticated by the session cookie
+// unless their X-CSRF-Token header matches the CSRF cookie. Requests with an
+// Authorization header aren't affected: browsers never add one on their own.
+func CSRF(cookies *SessionCookies) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cookies.Token(c) == "" {
+			c.Next()
+			return
+		}
+
+		csrfToken, _ := c.Cookie(cookies.csrfName())
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			header := c.GetHeader(CSRFHeader)
+			if csrfToken == "" || subtle.ConstantTimeCompare([]byte(header), []byte(csrfToken)) != 1 {
+				c.Error(apperror.ErrForbidden.WithMessage("Missing or invalid " + CSRFHeader + " header"))
+				c.Abort()
+				return
+			}
+		}
+		if csrfToken != "" {
+			c.Header(CSRFHeader, csrfToken)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/validation"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+	"gorm.io/gorm"
+)
+
+// ErrorHandler renders errors attached with c.Error as a model.ErrorResponse.
+// Handlers and middlewares report failures with c.Error(err) and return (or
+// abort); the last error decides the response. Errors that aren't an
+// *apperror.Error become internal_error and their message is only logged.
+// Binding failures are reported per field in details, in the language given by
+// userLanguage for signed-in users or by Accept-Language otherwise.
+func ErrorHandler(userLanguage func(userID uuid.UUID) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		appErr := toAppError(c.Errors.Last().Err)
+		status := appErr.Status()
+		if status >= http.StatusInternalServerError {
+			log.Printf("❌ %s %s: %v", c.Request.Method, c.FullPath(), appErr)
+		}
+
+		resp := model.ErrorResponse{Code: appErr.Code, Error: appErr.Message, Details: appErr.Details}
+		// Causes of client errors (e.g. validation messages) are useful to the caller
+		if cause := appErr.Cause(); cause != nil && status < http.StatusInternalServerError {
+			lang := requestLanguage(c, userLanguage)
+			if fields, ok := validation.Fields(cause, lang); ok {
+				resp.Message = validation.Summary(lang)
+				resp.Details = fields
+			} else {
+				resp.Message = cause.Error()
+			}
+		}
+		c.AbortWithStatusJSON(status, resp)
+	}
+}
+
+// requestLanguage returns the language to report validation errors in
+func requestLanguage(c *gin.Context, userLanguage func(uuid.UUID) string) string {
+	var lang string
+	if userID, ok := c.Get("user_id"); ok && userLanguage != nil {
+		lang = userLanguage(userID.(uuid.UUID))
+	}
+	return validation.Language(lang, c.GetHeader("Accept-Language"))
+}
+
+// toAppError maps well-known library errors to API errors
+func toAppError(err error) *apperror.Error {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return apperror.ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return apperror.ErrConflict
+	case errors.As(err, &maxBytesErr):
+		return apperror.ErrPayloadTooLarge
+	}
+	return apperror.From(err)
+}
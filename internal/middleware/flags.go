@@ -0,0 +1,17 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quocanhngo/gotalk/internal/model"
+)
+
+// FeatureFlags puts the current feature flags in the context as "feature_flags",
+// so a request sees one consistent set of flags
+func FeatureFlags(flags func(ctx context.Context) model.FeatureFlags) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("feature_flags", flags(c.Request.Context()))
+		c.Next()
+	}
+}
@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed" // set to "true" on replayed responses
+	idempotencyKeyPrefix     = "gotalk:idempotency:" // user + route + key -> stored response
+	idempotencyTTL           = 24 * time.Hour
+	idempotencyLockTTL       = time.Minute // how long a request may hold a key before finishing
+	maxIdempotencyKeyLength  = 255
+	idempotencyPending       = "pending"
+)
+
+// storedResponse is a completed response saved for replay
+type storedResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// Idempotency makes a route safe to retry: when the client sends an
+// Idempotency-Key header, the first successful response is stored for 24h and
+// replayed for later requests with the same key, instead of running the
+// handler again. Keys are scoped to the user and route, so it must run after
+// AuthMiddleware. Failed requests are not stored and can be retried.
+func Idempotency(rdb *redis.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.Error(apperror.ErrInvalidRequest.WithMessage("Idempotency-Key must be at most 255 characters"))
+			c.Abort()
+			return
+		}
+
+		ctx := context.Background()
+		sum := sha256.Sum256([]byte(c.Request.Method + " " + c.Request.URL.Path + " " + key))
+		userID := c.MustGet("user_id").(uuid.UUID)
+		redisKey := idempotencyKeyPrefix + userID.String() + ":" + hex.EncodeToString(sum[:])
+
+		// Claim the key; if it is already taken, replay or report the request in flight
+		claimed, err := rdb.SetNX(ctx, redisKey, idempotencyPending, idempotencyLockTTL).Result()
+		if err != nil {
+			log.Printf("⚠️  Idempotency check failed, handling request without it: %v", err)
+			c.Next()
+			return
+		}
+		if !claimed {
+			stored, err := rdb.Get(ctx, redisKey).Result()
+			if err != nil || stored == idempotencyPending {
+				c.Error(apperror.ErrConflict.WithMessage("A request with this Idempotency-Key is still in progress"))
+				c.Abort()
+				return
+			}
+			var resp storedResponse
+			if err := json.Unmarshal([]byte(stored), &resp); err != nil {
+				c.Error(apperror.ErrInternal.Wrap(err))
+				c.Abort()
+				return
+			}
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(resp.Status, resp.ContentType, resp.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := c.Writer.Status()
+		if len(c.Errors) > 0 || status < 200 || status >= 300 {
+			// Let the client retry failed requests
+			rdb.Del(ctx, redisKey)
+			return
+		}
+		data, err := json.Marshal(storedResponse{
+			Status:      status,
+			ContentType: c.Writer.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+		if err == nil {
+			err = rdb.Set(ctx, redisKey, data, idempotencyTTL).Err()
+		}
+		if err != nil {
+			log.Printf("⚠️  Failed to store idempotent response: %v", err)
+			rdb.Del(ctx, redisKey)
+		}
+	}
+}
+
+// bodyRecorder copies the response body while writing it
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController
+func (w *bodyRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+)
+
+// InboundMailAuth protects the inbound mail webhook with a shared token. Mail
+// providers can't always set headers, so besides Authorization: Bearer <token>
+// it accepts the token as the basic auth password in the webhook URL
+// (https://any:<token>@api.example.com/inbound/email) or as ?token=.
+func InboundMailAuth(token string) gin.HandlerFunc {
+	expected := []byte(token)
+	return func(c *gin.Context) {
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok {
+			if _, password, hasBasic := c.Request.BasicAuth(); hasBasic {
+				given = password
+			} else {
+				given = c.Query("token")
+			}
+		}
+		if len(expected) == 0 || subtle.ConstantTimeCompare([]byte(given), expected) != 1 {
+			c.Error(apperror.ErrUnauthorized.WithMessage("Invalid inbound mail token"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MatrixAuth protects the application service API with the homeserver token
+// (hs_token) from the registration. Homeservers send it as a Bearer token;
+// older ones use the access_token query parameter. Errors use the Matrix
+// {"errcode","error"} format the homeserver expects.
+func MatrixAuth(hsToken string) gin.HandlerFunc {
+	expected := []byte(hsToken)
+	return func(c *gin.Context) {
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok {
+			given = c.Query("access_token")
+		}
+		if given == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"errcode": "M_UNAUTHORIZED", "error": "Missing homeserver token"})
+			return
+		}
+		if len(expected) == 0 || subtle.ConstantTimeCompare([]byte(given), expected) != 1 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"errcode": "M_FORBIDDEN", "error": "Invalid homeserver token"})
+			return
+		}
+		c.Next()
+	}
+}
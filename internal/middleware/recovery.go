@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+	"github.com/quocanhngo/gotalk/pkg/errreport"
+)
+
+// IncidentHeader carries the incident ID of a recovered panic
+const IncidentHeader = "X-Incident-ID"
+
+// redactedHeaders and redactedParams never leave the server with an error report
+var (
+	redactedHeaders = map[string]bool{"Authorization": true, "Cookie": true, "Proxy-Authorization": true}
+	redactedParams  = []string{"token", "code", "state"}
+)
+
+// Recovery replaces gin's recovery: a panicking handler is reported with its
+// stack trace, the request and the signed-in user, and the client gets the
+// usual internal_error body with the incident ID in details.incident_id (and
+// the X-Incident-ID header) to quote to support.
+func Recovery(reporter *errreport.Reporter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// http.ErrAbortHandler is how handlers abort a response on purpose
+			if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(recovered)
+			}
+			if brokenPipe(recovered) {
+				// The client went away mid-response; nothing to report or send
+				c.Abort()
+				return
+			}
+
+			event := reporter.PanicEvent(recovered)
+			event.Request = &errreport.Request{
+				Method:     c.Request.Method,
+				URL:        requestURL(c.Request),
+				Route:      c.FullPath(),
+				RemoteAddr: c.ClientIP(),
+				Headers:    requestHeaders(c.Request.Header),
+			}
+			if userID, ok := c.Get("user_id"); ok {
+				event.UserID = userID.(uuid.UUID).String()
+			}
+			reporter.Report(event)
+
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			c.Header(IncidentHeader, event.IncidentID())
+			c.AbortWithStatusJSON(http.StatusInternalServerError, model.ErrorResponse{
+				Code:    apperror.ErrInternal.Code,
+				Error:   apperror.ErrInternal.Message,
+				Details: map[string]string{"incident_id": event.IncidentID()},
+			})
+		}()
+		c.Next()
+	}
+}
+
+// brokenPipe reports whether the panic came from writing to a closed connection
+func brokenPipe(recovered interface{}) bool {
+	err, ok := recovered.(error)
+	if !ok {
+		return false
+	}
+	var opErr *net.OpError
+	var sysErr *os.SyscallError
+	return errors.As(err, &opErr) && errors.As(opErr, &sysErr) &&
+		(errors.Is(sysErr.Err, syscall.EPIPE) || errors.Is(sysErr.Err, syscall.ECONNRESET))
+}
+
+// requestURL returns the request URL with credentials in the query string redacted
+func requestURL(r *http.Request) string {
+	query := r.URL.Query()
+	for _, param := range redactedParams {
+		if query.Has(param) {
+			query.Set(param, "[redacted]")
+		}
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	u := url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawQuery: query.Encode()}
+	return u.String()
+}
+
+func requestHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for name, values := range header {
+		if redactedHeaders[name] {
+			headers[name] = "[redacted]"
+			continue
+		}
+		headers[name] = strings.Join(values, ", ")
+	}
+	return headers
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+)
+
+// SCIMAuth protects the SCIM endpoints with the long-lived provisioning token
+// configured for the identity provider (Authorization: Bearer <token>)
+func SCIMAuth(token string) gin.HandlerFunc {
+	expected := []byte(token)
+	return func(c *gin.Context) {
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || len(expected) == 0 || subtle.ConstantTimeCompare([]byte(given), expected) != 1 {
+			c.Error(apperror.ErrUnauthorized.WithMessage("Invalid provisioning token"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// SCIMErrorHandler renders errors attached with c.Error as SCIM errors
+// (RFC 7644 §3.12) instead of model.ErrorResponse. It must run inside
+// ErrorHandler, which then finds the response already written.
+func SCIMErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		appErr := toAppError(c.Errors.Last().Err)
+		status := appErr.Status()
+		detail := appErr.Message
+		if status >= http.StatusInternalServerError {
+			log.Printf("❌ SCIM %s %s: %v", c.Request.Method, c.FullPath(), appErr)
+		} else if cause := appErr.Cause(); cause != nil {
+			detail += ": " + cause.Error()
+		}
+
+		c.Header("Content-Type", model.SCIMContentType)
+		c.AbortWithStatusJSON(status, model.SCIMError{
+			Schemas:  []string{model.SCIMSchemaError},
+			Status:   strconv.Itoa(status),
+			ScimType: scimType(appErr.Code),
+			Detail:   detail,
+		})
+	}
+}
+
+// scimType maps error codes to the SCIM error types identity providers act on
+func scimType(code apperror.Code) string {
+	switch code {
+	case apperror.CodeConflict, apperror.CodeEmailTaken:
+		return "uniqueness"
+	case apperror.CodeInvalidFilter:
+		return "invalidFilter"
+	case apperror.CodeInvalidRequest:
+		return "invalidValue"
+	}
+	return ""
+}
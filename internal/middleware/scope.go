@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+)
+
+// RequireScopes limits tokens issued to OAuth apps and personal access tokens
+// to the routes listed in scopes, each needing the given scope. Routes are
+// keyed by method and path relative to basePath, e.g. "GET /conversations/:id".
+// The user's own session tokens pass through. Must run after AuthMiddleware
+// (which sets "scopes" for scoped tokens).
+func RequireScopes(basePath string, scopes map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted, ok := c.Get("scopes")
+		if !ok {
+			c.Next()
+			return
+		}
+
+		route := c.Request.Method + " " + strings.TrimPrefix(c.FullPath(), basePath)
+		scope, listed := scopes[route]
+		if !listed {
+			c.Error(apperror.New(apperror.CodeInsufficientScope, "this endpoint can't be called with a scoped token"))
+			c.Abort()
+			return
+		}
+		if !slices.Contains(granted.([]string), scope) {
+			c.Error(apperror.New(apperror.CodeInsufficientScope, "the access token lacks the "+scope+" scope"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
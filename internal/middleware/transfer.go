@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Transfer gives a route that moves a lot of data, like an upload or a
+// streamed download, timeout to read the request and write the response,
+// instead of the server's HTTP_READ_TIMEOUT and HTTP_WRITE_TIMEOUT. 0 lifts
+// the limits.
+func Transfer(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var deadline time.Time
+		if timeout > 0 {
+			deadline = time.Now().Add(timeout)
+		}
+		// Fails only where deadlines aren't supported, e.g. in tests
+		rc := http.NewResponseController(c.Writer)
+		_ = rc.SetReadDeadline(deadline)
+		_ = rc.SetWriteDeadline(deadline)
+		c.Next()
+	}
+}
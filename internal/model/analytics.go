@@ -0,0 +1,59 @@
+package model
+
+import "time"
+
+// UsageMetric names a usage statistic
+type UsageMetric string
+
+const (
+	MetricMessagesSent       UsageMetric = "messages_sent"
+	MetricUploads            UsageMetric = "uploads"
+	MetricUploadBytes        UsageMetric = "upload_bytes"
+	MetricCallMinutes        UsageMetric = "call_minutes"         // of answered calls, each rounded up to the minute
+	MetricActiveUsers        UsageMetric = "active_users"         // distinct users with an authenticated request that day
+	MetricMonthlyActiveUsers UsageMetric = "monthly_active_users" // distinct users in the 30 days up to that day
+)
+
+// UsageMetrics lists every metric, in the order they are reported
+var UsageMetrics = []UsageMetric{
+	MetricMessagesSent,
+	MetricUploads,
+	MetricUploadBytes,
+	MetricCallMinutes,
+	MetricActiveUsers,
+	MetricMonthlyActiveUsers,
+}
+
+// UsageStat is one metric's value for one UTC day. Only aggregates are
+// stored, never who did what.
+type UsageStat struct {
+	Day    time.Time   `json:"day" gorm:"type:date;primaryKey"`
+	Metric UsageMetric `json:"metric" gorm:"type:varchar(40);primaryKey"`
+	Value  int64       `json:"value" gorm:"not null;default:0"`
+}
+
+// UsageStatsRequest selects the days and metric of GET /admin/stats
+type UsageStatsRequest struct {
+	Metric string `form:"metric"`                                       // all metrics if empty
+	From   string `form:"from" binding:"omitempty,datetime=2006-01-02"` // default: 29 days before to
+	To     string `form:"to" binding:"omitempty,datetime=2006-01-02"`   // default: today (UTC)
+}
+
+// UsagePoint is a metric's value on a day
+type UsagePoint struct {
+	Day   string `json:"day" example:"2026-10-18"`
+	Value int64  `json:"value"`
+}
+
+// UsageSeries is a metric's daily values, one per day of the range
+type UsageSeries struct {
+	Metric UsageMetric  `json:"metric"`
+	Points []UsagePoint `json:"points"`
+}
+
+// UsageStatsResponse holds the daily series of the requested metrics
+type UsageStatsResponse struct {
+	From   string        `json:"from" example:"2026-09-19"`
+	To     string        `json:"to" example:"2026-10-18"`
+	Series []UsageSeries `json:"series"`
+}
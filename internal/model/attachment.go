@@ -17,6 +17,17 @@ const (
 	AttachmentTypeAudio AttachmentType = "audio"
 )
 
+// ProcessingStatus tracks asynchronous media processing (e.g. video transcoding)
+type ProcessingStatus string
+
+const (
+	ProcessingStatusNone       ProcessingStatus = ""
+	ProcessingStatusPending    ProcessingStatus = "pending"
+	ProcessingStatusProcessing ProcessingStatus = "processing"
+	ProcessingStatusReady      ProcessingStatus = "ready"
+	ProcessingStatusFailed     ProcessingStatus = "failed"
+)
+
 // MessageAttachment represents a file attached to a message
 type MessageAttachment struct {
 	ID        uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
@@ -29,8 +40,13 @@ type MessageAttachment struct {
 	Width     int            `json:"width,omitempty"`    // for images/videos
 	Height    int            `json:"height,omitempty"`   // for images/videos
 	Duration  float64        `json:"duration,omitempty"` // for audio/video (seconds)
-	CreatedAt time.Time      `json:"created_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	// Streaming-friendly renditions produced by the transcoding worker (videos only)
+	ProcessedURL     string           `json:"processed_url,omitempty" gorm:"size:1000"`
+	PosterURL        string           `json:"poster_url,omitempty" gorm:"size:1000"`
+	ProcessingStatus ProcessingStatus `json:"processing_status,omitempty" gorm:"type:varchar(20);default:''"`
+	BlobHash         *string          `json:"-" gorm:"type:char(64);index"` // deduplicated content (file_blobs)
+	CreatedAt        time.Time        `json:"created_at"`
+	DeletedAt        gorm.DeletedAt   `json:"-" gorm:"index"`
 
 	// Relations
 	Message Message `json:"-" gorm:"foreignKey:MessageID"`
@@ -38,8 +54,10 @@ type MessageAttachment struct {
 
 // UploadResponse is returned after a successful file upload
 type UploadResponse struct {
-	URL      string `json:"url"`
-	FileName string `json:"file_name"`
-	FileSize int64  `json:"file_size"`
-	MimeType string `json:"mime_type"`
+	URL          string `json:"url"`
+	FileName     string `json:"file_name"`
+	FileSize     int64  `json:"file_size"`
+	MimeType     string `json:"mime_type"`
+	ContentHash  string `json:"content_hash"` // SHA-256 of the file content
+	Deduplicated bool   `json:"deduplicated"` // true if an identical file was already stored
 }
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audit actions
+const (
+	AuditRetentionPolicySet     = "retention.policy_set"
+	AuditRetentionPolicyRemoved = "retention.policy_removed"
+	AuditRetentionPurge         = "retention.purge"
+	AuditLegalHoldPlaced        = "legal_hold.placed"
+	AuditLegalHoldReleased      = "legal_hold.released"
+	AuditComplianceExport       = "compliance.export"
+)
+
+// AuditEvent records an administrative or compliance action, for operators
+// to review. Events outlive the conversations they are about.
+type AuditEvent struct {
+	ID             uuid.UUID              `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Action         string                 `json:"action" gorm:"size:50;not null;index"`
+	ActorID        *uuid.UUID             `json:"actor_id,omitempty" gorm:"type:uuid"` // admin who acted; nil for background jobs
+	ConversationID *uuid.UUID             `json:"conversation_id,omitempty" gorm:"type:uuid;index"`
+	Details        map[string]interface{} `json:"details,omitempty" gorm:"type:jsonb;serializer:json"`
+	CreatedAt      time.Time              `json:"created_at" gorm:"index"`
+}
+
+// AuditListRequest filters the audit log
+type AuditListRequest struct {
+	Action         string     `form:"action"`
+	ConversationID string     `form:"conversation_id" binding:"omitempty,uuid"`
+	Before         *time.Time `form:"before" time_format:"2006-01-02T15:04:05Z07:00"` // cursor: created_at of the last item
+	Limit          int        `form:"limit,default=50"`
+}
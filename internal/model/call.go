@@ -0,0 +1,140 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CallStatus is where a call is in its life
+type CallStatus string
+
+const (
+	CallStatusRinging CallStatus = "ringing"
+	CallStatusOngoing CallStatus = "ongoing"
+	CallStatusEnded   CallStatus = "ended"
+	CallStatusMissed  CallStatus = "missed" // hung up before it was answered
+)
+
+// Call is the record of a one-to-one call, kept from its signaling. Its ID is
+// the call_id of the signaling events.
+type Call struct {
+	ID             uuid.UUID                 `json:"id" gorm:"type:uuid;primaryKey"`
+	ConversationID *uuid.UUID                `json:"conversation_id,omitempty" gorm:"type:uuid;index"`
+	CallerID       uuid.UUID                 `json:"caller_id" gorm:"type:uuid;not null;index"`
+	CalleeID       uuid.UUID                 `json:"callee_id" gorm:"type:uuid;not null;index"`
+	Type           string                    `json:"type" gorm:"size:10"` // audio or video
+	Status         CallStatus                `json:"status" gorm:"size:20;not null;default:'ringing'"`
+	AnsweredAt     *time.Time                `json:"answered_at,omitempty" gorm:"type:timestamptz"`
+	EndedAt        *time.Time                `json:"ended_at,omitempty" gorm:"type:timestamptz"`
+	Renegotiations int                       `json:"renegotiations" gorm:"not null;default:0"`
+	ScreenShares   int                       `json:"screen_shares" gorm:"not null;default:0"`                 // times a screen share started
+	MediaState     map[string]CallMediaState `json:"media_state,omitempty" gorm:"type:jsonb;serializer:json"` // by participant's user ID
+	CreatedAt      time.Time                 `json:"created_at"`
+	UpdatedAt      time.Time                 `json:"updated_at"`
+}
+
+// CallMediaState is what a participant is currently sending
+type CallMediaState struct {
+	AudioMuted    bool `json:"audio_muted"`
+	VideoMuted    bool `json:"video_muted"`
+	ScreenSharing bool `json:"screen_sharing"`
+}
+
+// Media tracks whose mute state is signaled with call_track_state
+const (
+	CallTrackAudio = "audio"
+	CallTrackVideo = "video"
+)
+
+// CallIssue is a problem a participant reports in call feedback
+type CallIssue string
+
+const (
+	CallIssueEcho         CallIssue = "echo"
+	CallIssueNoise        CallIssue = "noise"
+	CallIssueAudioDropped CallIssue = "audio_dropped"
+	CallIssueVideoFrozen  CallIssue = "video_frozen"
+	CallIssueVideoBlurry  CallIssue = "video_blurry"
+	CallIssueDelay        CallIssue = "delay"
+	CallIssueDisconnected CallIssue = "disconnected"
+	CallIssueOther        CallIssue = "other"
+)
+
+// CallIssues lists the issues feedback may carry
+var CallIssues = []CallIssue{
+	CallIssueEcho, CallIssueNoise, CallIssueAudioDropped, CallIssueVideoFrozen,
+	CallIssueVideoBlurry, CallIssueDelay, CallIssueDisconnected, CallIssueOther,
+}
+
+// CallFeedback is a participant's rating of a call, one per participant
+type CallFeedback struct {
+	ID        uuid.UUID   `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	CallID    uuid.UUID   `json:"call_id" gorm:"type:uuid;not null;uniqueIndex:idx_call_feedback_call_user"`
+	UserID    uuid.UUID   `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_call_feedback_call_user"`
+	Rating    int         `json:"rating" gorm:"not null"` // 1-5 stars
+	Issues    []CallIssue `json:"issues" gorm:"type:jsonb;serializer:json"`
+	Comment   string      `json:"comment,omitempty" gorm:"size:1000"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+func (CallFeedback) TableName() string { return "call_feedback" }
+
+// CallStatsSample is one WebRTC stats report of a participant during a call
+type CallStatsSample struct {
+	ID         int64     `json:"-" gorm:"primaryKey;autoIncrement"`
+	CallID     uuid.UUID `json:"call_id" gorm:"type:uuid;not null;index"`
+	UserID     uuid.UUID `json:"user_id" gorm:"type:uuid;not null"`
+	RecordedAt time.Time `json:"recorded_at" gorm:"type:timestamptz;not null;index"`
+	PacketLoss float64   `json:"packet_loss"` // percent of packets lost
+	RTTMs      float64   `json:"rtt_ms"`
+	JitterMs   float64   `json:"jitter_ms"`
+	Network    string    `json:"network" gorm:"size:20"` // wifi, cellular, ethernet or other
+	Region     string    `json:"region" gorm:"size:2"`   // country code of the reporter's IP, if known
+}
+
+func (CallStatsSample) TableName() string { return "call_stats" }
+
+// CallFeedbackRequest rates a call
+type CallFeedbackRequest struct {
+	Rating  int         `json:"rating" binding:"required,min=1,max=5"`
+	Issues  []CallIssue `json:"issues" binding:"max=8"` // see CallIssues
+	Comment string      `json:"comment" binding:"max=1000"`
+}
+
+// CallStatsRequest is a periodic WebRTC stats report, e.g. every 10 seconds
+// from RTCPeerConnection.getStats()
+type CallStatsRequest struct {
+	PacketLoss *float64 `json:"packet_loss" binding:"required,min=0,max=100"` // percent, since the last report
+	RTTMs      *float64 `json:"rtt_ms" binding:"required,min=0"`
+	JitterMs   *float64 `json:"jitter_ms" binding:"required,min=0"`
+	Network    string   `json:"network" binding:"omitempty,oneof=wifi cellular ethernet other"`
+}
+
+// CallQualityRequest selects the days of GET /admin/calls/quality
+type CallQualityRequest struct {
+	From string `form:"from" binding:"omitempty,datetime=2006-01-02"` // default: 29 days before to
+	To   string `form:"to" binding:"omitempty,datetime=2006-01-02"`   // default: today (UTC)
+}
+
+// CallQualityRow is call quality on one kind of network in one region. Stats
+// are averaged per participant of a call first, so long calls don't weigh more.
+type CallQualityRow struct {
+	Network       string   `json:"network"`
+	Region        string   `json:"region"`
+	Calls         int64    `json:"calls"` // participants' calls reporting stats
+	Samples       int64    `json:"samples"`
+	AvgPacketLoss float64  `json:"avg_packet_loss"`
+	AvgRTTMs      float64  `json:"avg_rtt_ms"`
+	AvgJitterMs   float64  `json:"avg_jitter_ms"`
+	Ratings       int64    `json:"ratings"`
+	AvgRating     *float64 `json:"avg_rating"` // null without ratings
+}
+
+// CallQualityReport is call quality by network and region, most packet loss first
+type CallQualityReport struct {
+	From string           `json:"from" example:"2026-09-19"`
+	To   string           `json:"to" example:"2026-10-18"`
+	Rows []CallQualityRow `json:"rows"`
+}
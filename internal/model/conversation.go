@@ -17,18 +17,24 @@ const (
 
 // Conversation represents a chat conversation (1-1 or group)
 type Conversation struct {
-	ID        uuid.UUID        `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	Name      string           `json:"name" gorm:"size:100"`                              // group name, empty for private
-	Type      ConversationType `json:"type" gorm:"type:varchar(20);default:'private'"`
-	Avatar    string           `json:"avatar,omitempty" gorm:"size:500"`                   // group avatar
-	CreatorID *uuid.UUID       `json:"creator_id,omitempty" gorm:"type:uuid"`              // group creator
-	CreatedAt time.Time        `json:"created_at"`
-	UpdatedAt time.Time        `json:"updated_at"`
-	DeletedAt gorm.DeletedAt   `json:"-" gorm:"index"`
+	ID           uuid.UUID        `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name         string           `json:"name" gorm:"size:100"` // group name, empty for private
+	Type         ConversationType `json:"type" gorm:"type:varchar(20);default:'private'"`
+	Avatar       string           `json:"avatar,omitempty" gorm:"size:500"`       // group avatar
+	CreatorID    *uuid.UUID       `json:"creator_id,omitempty" gorm:"type:uuid"`  // group creator
+	ExternalID   *string          `json:"-" gorm:"size:255"`                      // identity provider's group ID (SCIM externalId)
+	Provisioned  bool             `json:"-" gorm:"default:false"`                 // group managed by SCIM directory sync
+	ImportedFrom string           `json:"imported_from,omitempty" gorm:"size:20"` // app the history was imported from (whatsapp, telegram)
+	Frozen       bool             `json:"frozen" gorm:"default:false"`            // only members with manage_settings can post (announcements)
+	CreatedAt    time.Time        `json:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt   `json:"-" gorm:"index"`
 
 	// Relations
-	Members      []ConversationMember `json:"members,omitempty" gorm:"foreignKey:ConversationID"`
-	LastMessage  *Message             `json:"last_message,omitempty" gorm:"-"` // populated manually
+	Members      []ConversationMember `json:"members,omitempty" gorm:"foreignKey:ConversationID"` // in API payloads, a preview: see MemberCount
+	MemberCount  int64                `json:"member_count" gorm:"-"`                              // all members; list them with GET /conversations/:id/members
+	LastMessage  *Message             `json:"last_message,omitempty" gorm:"-"`                    // populated manually
+	Announcement *Message             `json:"announcement,omitempty" gorm:"-"`                    // pinned announcement, shown as a banner
 }
 
 // MemberRole defines the role of a member in a conversation
@@ -48,6 +54,7 @@ type ConversationMember struct {
 	JoinedAt       time.Time      `json:"joined_at"`
 	LastReadAt     *time.Time     `json:"last_read_at,omitempty"`
 	MutedUntil     *time.Time     `json:"muted_until,omitempty"`
+	ClearedBefore  *time.Time     `json:"cleared_before,omitempty"` // messages before this are hidden from this member
 	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relations
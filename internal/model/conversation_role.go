@@ -0,0 +1,86 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Permission is an action in a conversation that a member's role allows or not
+type Permission string
+
+const (
+	PermissionSendMessages   Permission = "send_messages"
+	PermissionAddMembers     Permission = "add_members"
+	PermissionPin            Permission = "pin"
+	PermissionManageSettings Permission = "manage_settings" // freeze, roles, delete and restore the group
+)
+
+// Permissions lists every permission
+var Permissions = []Permission{
+	PermissionSendMessages,
+	PermissionAddMembers,
+	PermissionPin,
+	PermissionManageSettings,
+}
+
+// DefaultMemberPermissions are what the member role allows until a group changes it
+var DefaultMemberPermissions = []Permission{PermissionSendMessages}
+
+// directPermissions are what both participants of a private conversation can do
+var directPermissions = []Permission{PermissionSendMessages, PermissionPin, PermissionManageSettings}
+
+// ConversationRole is a role of a group: a custom one, or the member role
+// once the group changed its permissions. The admin role always has every
+// permission and is never stored.
+type ConversationRole struct {
+	ConversationID uuid.UUID    `json:"-" gorm:"type:uuid;primaryKey"`
+	Name           MemberRole   `json:"name" gorm:"type:varchar(20);primaryKey"`
+	Permissions    []Permission `json:"permissions" gorm:"type:jsonb;serializer:json;not null"`
+	BuiltIn        bool         `json:"built_in" gorm:"-"` // admin and member, which can't be deleted
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// MemberPermissions is what decides what a member may do in a conversation
+type MemberPermissions struct {
+	ConversationType ConversationType
+	Frozen           bool
+	Role             MemberRole
+	RolePermissions  []Permission `gorm:"serializer:json"` // the role's stored permissions; nil uses its defaults
+}
+
+// Permissions returns what the member's role allows, before freezing
+func (p *MemberPermissions) Permissions() []Permission {
+	switch {
+	case p.ConversationType != ConversationTypeGroup:
+		return directPermissions
+	case p.Role == MemberRoleAdmin:
+		return Permissions
+	case p.RolePermissions != nil:
+		return p.RolePermissions
+	default:
+		return DefaultMemberPermissions
+	}
+}
+
+// Allows tells whether the member may perform the action. In a frozen group
+// only members who manage its settings can post.
+func (p *MemberPermissions) Allows(action Permission) bool {
+	permissions := p.Permissions()
+	if action == PermissionSendMessages && p.Frozen && !slices.Contains(permissions, PermissionManageSettings) {
+		return false
+	}
+	return slices.Contains(permissions, action)
+}
+
+// SaveRoleRequest creates a custom role or changes a role's permissions
+type SaveRoleRequest struct {
+	Permissions []Permission `json:"permissions" binding:"dive,oneof=send_messages add_members pin manage_settings"`
+}
+
+// SetMemberRoleRequest gives a member a role
+type SetMemberRoleRequest struct {
+	Role MemberRole `json:"role" binding:"required"`
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConversationTemplate sets up a new group: the messages are posted to it as
+// system messages when it's created, and pinned if they say so. Admins manage templates; the project and
+// support templates come predefined.
+type ConversationTemplate struct {
+	ID          uuid.UUID         `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Name        string            `json:"name" gorm:"size:50;not null;uniqueIndex"` // what clients pass to create from it, e.g. "project"
+	Title       string            `json:"title" gorm:"size:100;not null"`           // default group name
+	Description string            `json:"description" gorm:"size:500"`
+	Messages    []TemplateMessage `json:"messages" gorm:"type:jsonb;serializer:json"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// TemplateMessage is a message posted to groups created from a template
+type TemplateMessage struct {
+	Content  string          `json:"content" binding:"required"`
+	Entities MessageEntities `json:"entities,omitempty"` // formatting of content
+	Pin      bool            `json:"pin,omitempty"`      // pin it once posted
+}
+
+// ConversationTemplateRequest creates or replaces a template
+type ConversationTemplateRequest struct {
+	Name        string            `json:"name" binding:"required"` // 2-50 lowercase letters, digits, underscores or hyphens
+	Title       string            `json:"title" binding:"required,max=100"`
+	Description string            `json:"description" binding:"max=500"`
+	Messages    []TemplateMessage `json:"messages" binding:"max=10,dive"` // posted in order
+}
+
+// CreateFromTemplateRequest creates a group from a template
+type CreateFromTemplateRequest struct {
+	Template  string      `json:"template" binding:"required"` // template name
+	Name      string      `json:"name"`                        // default: the template's title
+	MemberIDs []uuid.UUID `json:"member_ids" binding:"required,min=1"`
+}
@@ -6,12 +6,20 @@ import (
 	"github.com/google/uuid"
 )
 
+// Device types reported by clients when registering for push
+const (
+	DeviceTypeAndroid = "android"
+	DeviceTypeIOS     = "ios"
+	DeviceTypeIOSVoIP = "ios_voip" // PushKit token, for incoming calls only
+	DeviceTypeWeb     = "web"
+)
+
 // UserDevice represents a user's device for push notifications
 type UserDevice struct {
 	ID           uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
 	UserID       uuid.UUID `json:"user_id" gorm:"not null;index"`
 	FCMToken     string    `json:"fcm_token" gorm:"not null;uniqueIndex:idx_user_token"`
-	DeviceType   string    `json:"device_type" gorm:"size:20;default:'unknown'"` // android, ios, web
+	DeviceType   string    `json:"device_type" gorm:"size:20;default:'unknown'"` // android, ios, ios_voip, web
 	LastActiveAt time.Time `json:"last_active_at"`
 	CreatedAt    time.Time `json:"created_at"`
 }
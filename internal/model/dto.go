@@ -1,13 +1,20 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+	"github.com/quocanhngo/gotalk/pkg/mailer"
+)
 
 // ========== Auth DTOs ==========
 
 type RegisterRequest struct {
-	Name     string `json:"name" binding:"required,min=2,max=100"`
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Name       string `json:"name" binding:"required,min=2,max=100"`
+	Email      string `json:"email" binding:"required,email"`
+	Password   string `json:"password" binding:"required,min=6"`
+	InviteCode string `json:"invite_code,omitempty"` // required when registration is invitation-only
 }
 
 type LoginRequest struct {
@@ -16,11 +23,28 @@ type LoginRequest struct {
 }
 
 type GoogleLoginRequest struct {
-	IDToken string `json:"id_token" binding:"required"` // Google ID token from frontend
+	IDToken    string `json:"id_token" binding:"required"` // Google ID token from frontend
+	InviteCode string `json:"invite_code,omitempty"`       // for a new account when registration is invitation-only
+}
+
+// SSOTokenRequest trades the one-time code from the SSO callback for a session
+type SSOTokenRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// SSOCodeResponse carries the one-time code when no SSO frontend URL is configured
+type SSOCodeResponse struct {
+	Code string `json:"code"`
+}
+
+// ClientInfo identifies the client a request came from (used for new-device login alerts)
+type ClientInfo struct {
+	IP        string
+	UserAgent string
 }
 
 type LoginResponse struct {
-	Token string       `json:"token"`
+	Token string       `json:"token"` // empty when the sign-in asked for a cookie session (X-Session-Mode: cookie)
 	User  UserResponse `json:"user"`
 }
 
@@ -41,6 +65,16 @@ type OTPSentResponse struct {
 	ExpiresIn int    `json:"expires_in"` // seconds until code expires
 }
 
+// MagicLinkRequest asks for a one-time sign-in link by email
+type MagicLinkRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// VerifyMagicLinkRequest uses a sign-in link, as JSON or a form post
+type VerifyMagicLinkRequest struct {
+	Token string `json:"token" form:"token" binding:"required"`
+}
+
 type ForgotPasswordRequest struct {
 	Email string `json:"email" binding:"required,email"`
 }
@@ -51,6 +85,11 @@ type ResetPasswordRequest struct {
 	NewPassword string `json:"new_password" binding:"required,min=6"`
 }
 
+// LoginAlertRevokeRequest carries the token of a new sign-in alert's "this wasn't me" link
+type LoginAlertRevokeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
 // ========== Google OAuth DTOs ==========
 
 type GoogleUserInfo struct {
@@ -62,20 +101,89 @@ type GoogleUserInfo struct {
 }
 
 type UpdateProfileRequest struct {
-	Name   string `json:"name" binding:"max=100"`
-	Avatar string `json:"avatar" binding:"max=500"`
+	Name        string  `json:"name" binding:"max=100"`
+	DisplayName *string `json:"display_name" binding:"omitempty,max=100"` // empty string clears it
+	Bio         *string `json:"bio" binding:"omitempty,max=500"`          // empty string clears it
+	Avatar      string  `json:"avatar" binding:"max=500"`
+}
+
+type UpdateHandleRequest struct {
+	Handle string `json:"handle" binding:"required,max=31"` // a leading @ is allowed
+}
+
+type HandleAvailabilityResponse struct {
+	Handle    string `json:"handle"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"` // invalid, reserved or taken
+}
+
+type UpdateStatusRequest struct {
+	Message   string     `json:"message" binding:"max=100"`
+	Emoji     string     `json:"emoji" binding:"max=16"`
+	ExpiresAt *time.Time `json:"expires_at"` // omit to keep the status until cleared
+}
+
+// SetAutoReplyRequest sets the current user's auto-reply
+type SetAutoReplyRequest struct {
+	Text     string     `json:"text" binding:"required,max=1000"`
+	StartsAt *time.Time `json:"starts_at"` // omit to start now
+	EndsAt   *time.Time `json:"ends_at"`   // omit to keep it until cleared
+}
+
+// UserProfileResponse is another user's profile page
+type UserProfileResponse struct {
+	UserResponse
+	IsContact bool `json:"is_contact"`
 }
 
 type UpdateSettingsRequest struct {
-	Theme                 string `json:"theme" binding:"omitempty,oneof=light dark system"`
-	IsNotificationEnabled *bool  `json:"is_notification_enabled"`
-	IsSoundEnabled        *bool  `json:"is_sound_enabled"`
-	Language              string `json:"language" binding:"omitempty,len=2"`
+	Theme                 string                   `json:"theme" binding:"omitempty,oneof=light dark system"`
+	IsNotificationEnabled *bool                    `json:"is_notification_enabled"`
+	IsSoundEnabled        *bool                    `json:"is_sound_enabled"`
+	IsDigestEnabled       *bool                    `json:"is_digest_enabled"`
+	IsEmailNotifEnabled   *bool                    `json:"is_email_notification_enabled"`
+	Language              string                   `json:"language" binding:"omitempty,len=2"`
+	Timezone              string                   `json:"timezone" binding:"omitempty,timezone"`
+	SingleSession         *bool                    `json:"single_session"` // from the next sign-in, each one ends the others
+	QuietHours            *UpdateQuietHoursRequest `json:"quiet_hours"`
+	Privacy               *UpdatePrivacyRequest    `json:"privacy"`
+}
+
+type UpdatePrivacyRequest struct {
+	LastSeen        PrivacyLevel `json:"last_seen" binding:"omitempty,oneof=everyone contacts nobody"`
+	Online          PrivacyLevel `json:"online" binding:"omitempty,oneof=everyone contacts nobody"`
+	Discoverability PrivacyLevel `json:"discoverability" binding:"omitempty,oneof=everyone contacts nobody"`
+}
+
+type UpdateQuietHoursRequest struct {
+	Enabled       *bool  `json:"enabled"`
+	Start         string `json:"start" binding:"omitempty,datetime=15:04"`
+	End           string `json:"end" binding:"omitempty,datetime=15:04"`
+	Summary       *bool  `json:"summary"`
+	AllowMentions *bool  `json:"allow_mentions"`
 }
 
 type RegisterDeviceRequest struct {
 	FCMToken   string `json:"fcm_token" binding:"required"`
-	DeviceType string `json:"device_type" binding:"required"`
+	DeviceType string `json:"device_type" binding:"required"` // android, ios, ios_voip (PushKit token) or web
+}
+
+// WebPushSubscribeRequest mirrors the browser's PushSubscription.toJSON()
+type WebPushSubscribeRequest struct {
+	Endpoint string `json:"endpoint" binding:"required,url"`
+	Keys     struct {
+		P256dh string `json:"p256dh" binding:"required"`
+		Auth   string `json:"auth" binding:"required"`
+	} `json:"keys" binding:"required"`
+}
+
+type WebPushUnsubscribeRequest struct {
+	Endpoint string `json:"endpoint" binding:"required"`
+}
+
+// WebPushKeyResponse carries the VAPID public key used as applicationServerKey
+type WebPushKeyResponse struct {
+	PublicKey string `json:"public_key"`
 }
 
 // ========== Conversation DTOs ==========
@@ -105,7 +213,9 @@ type ConversationResponse struct {
 
 type SendMessageRequest struct {
 	Content     string            `json:"content" binding:"required_without_all=Attachments FileURL"`
+	Entities    MessageEntities   `json:"entities,omitempty"` // bold, italic, code, links... over content
 	Type        MessageType       `json:"type"`
+	Language    string            `json:"language,omitempty"` // code messages: one of model.CodeLanguages, plaintext by default
 	ReplyToID   *uuid.UUID        `json:"reply_to_id"`
 	Attachments []AttachmentInput `json:"attachments,omitempty"`
 	// Legacy single-file fields (backward compatible)
@@ -123,11 +233,74 @@ type AttachmentInput struct {
 	MimeType string         `json:"mime_type"`
 }
 
+// MessageInfo is the delivery and read breakdown of a message, for its sender
+type MessageInfo struct {
+	MessageID      uuid.UUID              `json:"message_id"`
+	Recipients     []MessageRecipientInfo `json:"recipients"`
+	DeliveredCount int                    `json:"delivered_count"`
+	ReadCount      int                    `json:"read_count"`
+}
+
+// MessageRecipientInfo is when a message reached and was read by one member.
+// Reading or receiving a later message counts for the earlier ones too.
+type MessageRecipientInfo struct {
+	User        User       `json:"user"`
+	DeliveredAt *time.Time `json:"delivered_at"` // nil = not delivered yet
+	ReadAt      *time.Time `json:"read_at"`      // nil = not read yet
+}
+
 type MessageListRequest struct {
 	Before string `form:"before"` // cursor for pagination (message ID)
+	After  string `form:"after"`  // cursor for newer messages (message ID)
+	Around string `form:"around"` // message ID to center the page on
+	Date   string `form:"date"`   // RFC 3339 time or YYYY-MM-DD date to center the page on
 	Limit  int    `form:"limit,default=50"`
 }
 
+// MarkReadRequest is the optional body of POST /conversations/read-all
+type MarkReadRequest struct {
+	ConversationIDs []uuid.UUID `json:"conversation_ids" binding:"max=500"` // empty = every conversation
+}
+
+type MarkReadResponse struct {
+	ConversationIDs []uuid.UUID `json:"conversation_ids"` // the conversations that had unread messages
+}
+
+type MemberListRequest struct {
+	After string `form:"after"` // cursor for pagination (user ID of the last member)
+	Query string `form:"q" binding:"max=100"`
+	Limit int    `form:"limit,default=50"`
+}
+
+// ========== Notification Center DTOs ==========
+
+type NotificationListRequest struct {
+	Before     *time.Time `form:"before" time_format:"2006-01-02T15:04:05Z07:00"` // cursor: created_at of the last item
+	UnreadOnly bool       `form:"unread"`
+	Limit      int        `form:"limit,default=30"`
+}
+
+type NotificationListResponse struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int64          `json:"unread_count"`
+}
+
+type AdminNoticeRequest struct {
+	Title   string      `json:"title" binding:"required,max=255"`
+	Body    string      `json:"body" binding:"required"`
+	UserIDs []uuid.UUID `json:"user_ids"` // empty = all verified users
+}
+
+type ExportConversationRequest struct {
+	Format ExportFormat `json:"format" binding:"required,oneof=json html csv"`
+}
+
+// ImportRequest accompanies the archive uploaded to POST /imports
+type ImportRequest struct {
+	Source   string `form:"source" binding:"required,oneof=whatsapp telegram"`
+	Timezone string `form:"timezone" binding:"omitempty,timezone"` // IANA zone of the exporting phone, for WhatsApp timestamps; default UTC
+}
+
 // ========== WebSocket Event DTOs ==========
 
 type WSEvent struct {
@@ -135,40 +308,313 @@ type WSEvent struct {
 	Payload interface{} `json:"payload"`
 }
 
-// WebSocket event types
+// WebSocket event types. The "payload:" comments name the payload type and are
+// read by cmd/genapi to document the events in the OpenAPI spec.
 const (
-	WSEventNewMessage  = "new_message"
-	WSEventTyping      = "typing"
-	WSEventStopTyping  = "stop_typing"
-	WSEventOnline      = "online"
-	WSEventOffline     = "offline"
-	WSEventMessageRead = "message_read"
-	WSEventCallOffer   = "call_offer"
-	WSEventCallAnswer  = "call_answer"
-	WSEventCallICE     = "call_ice_candidate"
-	WSEventCallHangup  = "call_hangup"
+	WSEventNewMessage      = "new_message"        // payload: Message
+	WSEventTyping          = "typing"             // payload: TypingEvent
+	WSEventStopTyping      = "stop_typing"        // payload: TypingEvent
+	WSEventOnline          = "online"             // payload: OnlineEvent
+	WSEventOffline         = "offline"            // payload: OnlineEvent
+	WSEventMessageRead     = "message_read"       // payload: MessageReadEvent
+	WSEventCallOffer       = "call_offer"         // payload: CallOfferEvent
+	WSEventCallAnswer      = "call_answer"        // payload: CallAnswerEvent
+	WSEventCallICE         = "call_ice_candidate" // payload: ICECandidateEvent
+	WSEventCallHangup      = "call_hangup"        // payload: CallHangupEvent
+	WSEventCallRenegotiate = "call_renegotiate"   // payload: CallRenegotiateEvent
+	WSEventCallScreenShare = "call_screen_share"  // payload: CallScreenShareEvent
+	WSEventCallTrackState  = "call_track_state"   // payload: CallTrackStateEvent
+	WSEventCallChat        = "call_chat"          // payload: CallChatEvent
+	WSEventCallReaction    = "call_reaction"      // payload: CallReactionEvent
+
+	WSEventAttachmentProcessed  = "attachment_processed"  // payload: MessageAttachment
+	WSEventNotification         = "notification"          // payload: Notification
+	WSEventStatusChanged        = "status_changed"        // payload: StatusChangedEvent
+	WSEventBootstrap            = "bootstrap"             // payload: BootstrapEvent
+	WSEventConversationsRead    = "conversations_read"    // payload: ConversationsReadEvent
+	WSEventMessageDelivered     = "message_delivered"     // payload: MessageDeliveredEvent
+	WSEventConversationDeleted  = "conversation_deleted"  // payload: ConversationChangeEvent
+	WSEventConversationRestored = "conversation_restored" // payload: ConversationChangeEvent
+	WSEventHistoryCleared       = "history_cleared"       // payload: HistoryClearedEvent
+	WSEventConversationFrozen   = "conversation_frozen"   // payload: ConversationFrozenEvent
+	WSEventRolesChanged         = "roles_changed"         // payload: RolesChangedEvent
+	WSEventMemberRoleChanged    = "member_role_changed"   // payload: MemberRoleChangedEvent
+	WSEventMemberAdded          = "member_added"          // payload: MemberAddedEvent
+	WSEventPinsChanged          = "pins_changed"          // payload: PinsChangedEvent
+	WSEventError                = "error"                 // payload: ErrorResponse
+	WSEventTypingSummary        = "typing_summary"        // payload: TypingSummaryEvent
+	WSEventPresenceSubscribe    = "presence_subscribe"    // payload: PresenceSubscription
+	WSEventPresenceUnsubscribe  = "presence_unsubscribe"  // payload: PresenceSubscription
+	WSEventPresenceState        = "presence_state"        // payload: PresenceStateEvent
+	WSEventConnectionUnstable   = "connection_unstable"   // payload: ConnectionUnstableEvent
+	WSEventDisconnect           = "disconnect"            // payload: DisconnectEvent
+	WSEventRefreshToken         = "refresh_token"         // payload: RefreshTokenRequest
+	WSEventTokenExpiring        = "token_expiring"        // payload: TokenExpiryEvent
+	WSEventTokenRefreshed       = "token_refreshed"       // payload: TokenExpiryEvent
+	WSEventSetPresence          = "set_presence"          // payload: SetPresenceRequest
+	WSEventPresenceChanged      = "presence_changed"      // payload: PresenceResponse
 )
 
+// WSStats describes the WebSocket clients on one instance. Dropped counts
+// ephemeral events (typing, presence) discarded for full outbound queues;
+// SlowDisconnects counts clients dropped because a chat message didn't fit.
+// Compressed* cover clients that negotiated permessage-deflate; bytes are
+// measured before compression. UnstableWarnings counts connection_unstable
+// events sent to connections that went silent. Publish* cover the events this
+// instance published to Redis for the other instances, and Events breaks the
+// traffic down by event type.
+type WSStats struct {
+	Clients         int   `json:"clients"`
+	QueueSize       int   `json:"queue_size"`
+	Queued          int   `json:"queued"`
+	MaxDepth        int   `json:"max_depth"`
+	Dropped         int64 `json:"dropped"`
+	SlowDisconnects int64 `json:"slow_disconnects"`
+
+	CompressedClients int   `json:"compressed_clients"`
+	CompressedFrames  int64 `json:"compressed_frames"`
+	CompressedBytes   int64 `json:"compressed_bytes"`
+
+	UnstableWarnings int64 `json:"unstable_warnings"`
+
+	// Redis outages: degraded while it's unreachable, with events for other
+	// instances buffered and those over the size or age limit discarded
+	Degraded        bool  `json:"degraded"`
+	RedisOutages    int64 `json:"redis_outages"`
+	Buffered        int   `json:"buffered"`
+	BufferDiscarded int64 `json:"buffer_discarded"`
+
+	PublishErrors    int64                   `json:"publish_errors"`
+	PublishLatencyMs Histogram               `json:"publish_latency_ms"`
+	Events           map[string]WSEventStats `json:"events"` // by event type
+}
+
+// WSEventStats instruments one event type on one instance since startup.
+// Published counts events this instance sent through Redis to every
+// instance; Delivered and Dropped count copies queued for, or discarded from
+// the full queues of, local connections. FanOut is how many local
+// connections each delivery reached. Invalid counts deliveries whose event
+// didn't match its schema in internal/ws/events.schema.json, when
+// WS_VALIDATE_EVENTS is on.
+type WSEventStats struct {
+	Published int64     `json:"published"`
+	Delivered int64     `json:"delivered"`
+	Dropped   int64     `json:"dropped"`
+	Invalid   int64     `json:"invalid"`
+	FanOut    Histogram `json:"fan_out"`
+}
+
+// Histogram is a distribution of observations; each bucket counts those up
+// to its upper bound, the last ("+Inf") all of them
+type Histogram struct {
+	Count   int64             `json:"count"`
+	Sum     float64           `json:"sum"`
+	Buckets []HistogramBucket `json:"buckets"`
+}
+
+type HistogramBucket struct {
+	Le    string `json:"le"`
+	Count int64  `json:"count"`
+}
+
+// MembershipCacheStats describes the conversation membership cache on one
+// instance: lookups answered from Redis (hits) against those loaded from the
+// database (misses). Errors counts Redis failures answered from the database.
+type MembershipCacheStats struct {
+	Hits      int64   `json:"hits"`
+	Misses    int64   `json:"misses"`
+	Errors    int64   `json:"errors"`
+	HitRate   float64 `json:"hit_rate"`
+	AvgHitMs  float64 `json:"avg_hit_ms"`
+	AvgMissMs float64 `json:"avg_miss_ms"`
+}
+
+// BootstrapEvent is sent once right after a WebSocket connects, so clients can
+// render without a burst of REST calls
+type BootstrapEvent struct {
+	Conversations       []ConversationResponse `json:"conversations"`
+	OnlineContactIDs    []uuid.UUID            `json:"online_contact_ids"`
+	UnreadNotifications int64                  `json:"unread_notifications"`
+}
+
 type TypingEvent struct {
 	ConversationID uuid.UUID `json:"conversation_id"`
 	UserID         uuid.UUID `json:"user_id"`
 	Name           string    `json:"name"`
 }
 
+// TypingSummaryEvent says who is typing in a large conversation, replacing
+// typing/stop_typing there. It lists up to 3 names in the order they started;
+// Count is everyone typing, so "Ann, Bob and 4 others" is Count - len(Names)
+// others. A Count of 0 means nobody is typing anymore.
+type TypingSummaryEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	Names          []string  `json:"names"`
+	Count          int       `json:"count"`
+}
+
+// ConnectionUnstableEvent warns that the server heard nothing from the
+// connection, not even a pong, for SilentSeconds and closes it in
+// ClosesInSeconds. Sending any event proves it alive; clients that get no
+// further traffic should reconnect.
+type ConnectionUnstableEvent struct {
+	SilentSeconds   int `json:"silent_seconds"`
+	ClosesInSeconds int `json:"closes_in_seconds"`
+}
+
+// RefreshTokenRequest is sent by clients to swap the token a live connection
+// is authenticated with for a newer one of the same user
+type RefreshTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// TokenExpiryEvent tells a connection when its token expires: token_expiring
+// warns once shortly before, token_refreshed confirms a refresh_token. At
+// expiry the connection is closed with auth_expired.
+type TokenExpiryEvent struct {
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// What a client should do after the server closed its connection
+const (
+	ReconnectRetry          = "retry"          // reconnect, after RetryAfterSeconds if set, with backoff
+	ReconnectReauthenticate = "reauthenticate" // get a new token (sign in again) before reconnecting
+	ReconnectNever          = "none"           // don't reconnect automatically
+)
+
+// DisconnectEvent is the last event before the server closes a connection.
+// Code is also the close frame's code (4000-4999, see the README) and
+// Reconnect tells the client whether to sign in again, retry or give up.
+type DisconnectEvent struct {
+	Code              int    `json:"code"`
+	Reason            string `json:"reason"`
+	Reconnect         string `json:"reconnect"`
+	RetryAfterSeconds int    `json:"retry_after_seconds,omitempty"`
+}
+
+// PresenceSubscription is sent by clients to start or stop getting the
+// online/offline events of some users, e.g. their contacts and the members of
+// the open conversation. A connection watches up to 1000 users.
+type PresenceSubscription struct {
+	UserIDs []uuid.UUID `json:"user_ids"`
+}
+
+// PresenceStateEvent answers presence_subscribe with which of the newly
+// watched users are online now; online/offline events follow
+type PresenceStateEvent struct {
+	OnlineUserIDs []uuid.UUID                 `json:"online_user_ids"`
+	States        map[uuid.UUID]PresenceState `json:"states"` // online, away or busy, for each online user
+}
+
+// OnlineEvent announces that a user came online or went offline, or changed
+// state (online, away, busy) while connected
 type OnlineEvent struct {
-	UserID   uuid.UUID `json:"user_id"`
-	IsOnline bool      `json:"is_online"`
+	UserID   uuid.UUID     `json:"user_id"`
+	IsOnline bool          `json:"is_online"`
+	State    PresenceState `json:"state"`
 }
 
+// SetPresenceRequest is the body of PUT /auth/presence and the payload of the
+// set_presence WebSocket event
+type SetPresenceRequest struct {
+	State PresenceState `json:"state" binding:"required,oneof=online away busy invisible"`
+}
+
+// PresenceResponse is the current user's chosen presence and how they show
+// now (away when idle, offline when not connected)
+type PresenceResponse struct {
+	State   PresenceState `json:"state"`
+	Current PresenceState `json:"current"`
+}
+
+type StatusChangedEvent struct {
+	UserID uuid.UUID   `json:"user_id"`
+	Status *UserStatus `json:"status"` // nil = cleared or expired
+}
+
+// MessageReadEvent tells members that user_id read the conversation up to
+// message_id (nil when read without one). The reader's own other devices get
+// it too, with unread_count set to what's left unread for them.
 type MessageReadEvent struct {
 	ConversationID uuid.UUID `json:"conversation_id"`
 	MessageID      uuid.UUID `json:"message_id"`
 	UserID         uuid.UUID `json:"user_id"`
+	UnreadCount    *int64    `json:"unread_count,omitempty"`
+}
+
+// MessageDeliveredEvent is sent by a client when a message reached it (user_id
+// is filled in by the server) and forwarded to the message's sender
+type MessageDeliveredEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	MessageID      uuid.UUID `json:"message_id"`
+	UserID         uuid.UUID `json:"user_id"`
+}
+
+// ConversationChangeEvent tells the members who deleted or restored a conversation
+type ConversationChangeEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`
+}
+
+// ConversationFrozenEvent tells the members someone froze the conversation, so
+// only members who manage it can post, or unfroze it
+type ConversationFrozenEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	Frozen         bool      `json:"frozen"`
+	UserID         uuid.UUID `json:"user_id"` // who froze or unfroze it
+}
+
+// RolesChangedEvent tells the members a role of the conversation was created,
+// changed or deleted; clients reload them with GET /conversations/:id/roles
+type RolesChangedEvent struct {
+	ConversationID uuid.UUID  `json:"conversation_id"`
+	Role           MemberRole `json:"role"`
+	UserID         uuid.UUID  `json:"user_id"` // who changed it
+}
+
+// MemberRoleChangedEvent tells the members someone was given another role
+type MemberRoleChangedEvent struct {
+	ConversationID uuid.UUID  `json:"conversation_id"`
+	MemberID       uuid.UUID  `json:"member_id"`
+	Role           MemberRole `json:"role"`
+	UserID         uuid.UUID  `json:"user_id"` // who changed it
+}
+
+// MemberAddedEvent tells the members of a group, including the new ones, that
+// people were added
+type MemberAddedEvent struct {
+	ConversationID uuid.UUID   `json:"conversation_id"`
+	MemberIDs      []uuid.UUID `json:"member_ids"`
+	UserID         uuid.UUID   `json:"user_id"` // who added them
+}
+
+// PinsChangedEvent tells the members of a conversation that its pins changed
+type PinsChangedEvent struct {
+	ConversationID uuid.UUID   `json:"conversation_id"`
+	MessageIDs     []uuid.UUID `json:"message_ids"`               // pinned messages, top first
+	AnnouncementID *uuid.UUID  `json:"announcement_id,omitempty"` // the banner, if any
+	UserID         uuid.UUID   `json:"user_id"`                   // who changed them
+}
+
+// HistoryClearedEvent tells a user's devices that they cleared a conversation's
+// history: messages sent before cleared_before are no longer shown
+type HistoryClearedEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	ClearedBefore  time.Time `json:"cleared_before"`
+}
+
+// ConversationsReadEvent tells members that a user caught up on several
+// conversations at once; each recipient gets only the conversations it's in
+type ConversationsReadEvent struct {
+	UserID          uuid.UUID   `json:"user_id"`
+	ConversationIDs []uuid.UUID `json:"conversation_ids"`
+	ReadAt          time.Time   `json:"read_at"`
 }
 
 // ========== WebRTC Signaling DTOs ==========
 
 type CallOfferEvent struct {
+	CallID         uuid.UUID   `json:"call_id"` // set by the server when the caller leaves it out
 	From           uuid.UUID   `json:"from"`
 	To             uuid.UUID   `json:"to"`
 	ConversationID uuid.UUID   `json:"conversation_id"`
@@ -177,6 +623,7 @@ type CallOfferEvent struct {
 }
 
 type CallAnswerEvent struct {
+	CallID         uuid.UUID   `json:"call_id"`
 	From           uuid.UUID   `json:"from"`
 	To             uuid.UUID   `json:"to"`
 	ConversationID uuid.UUID   `json:"conversation_id"`
@@ -184,17 +631,136 @@ type CallAnswerEvent struct {
 }
 
 type ICECandidateEvent struct {
+	CallID         uuid.UUID   `json:"call_id"`
 	From           uuid.UUID   `json:"from"`
 	To             uuid.UUID   `json:"to"`
 	ConversationID uuid.UUID   `json:"conversation_id"`
 	Candidate      interface{} `json:"candidate"`
 }
 
+type CallHangupEvent struct {
+	CallID         uuid.UUID `json:"call_id"`
+	From           uuid.UUID `json:"from"`
+	To             uuid.UUID `json:"to"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+}
+
+// CallRenegotiateEvent carries a new SDP offer or answer mid-call, e.g. when a
+// track is added
+type CallRenegotiateEvent struct {
+	CallID         uuid.UUID   `json:"call_id"`
+	From           uuid.UUID   `json:"from"`
+	To             uuid.UUID   `json:"to"`
+	ConversationID uuid.UUID   `json:"conversation_id"`
+	SDP            interface{} `json:"sdp"`
+}
+
+// CallScreenShareEvent starts or stops sharing the sender's screen
+type CallScreenShareEvent struct {
+	CallID         uuid.UUID `json:"call_id"`
+	From           uuid.UUID `json:"from"`
+	To             uuid.UUID `json:"to"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	Sharing        bool      `json:"sharing"`
+}
+
+// CallTrackStateEvent mutes or unmutes one of the sender's tracks
+type CallTrackStateEvent struct {
+	CallID         uuid.UUID `json:"call_id"`
+	From           uuid.UUID `json:"from"`
+	To             uuid.UUID `json:"to"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	Track          string    `json:"track"` // audio or video
+	Muted          bool      `json:"muted"`
+}
+
+// CallChatEvent is a text message within a call. It only goes to the other
+// participant, unless Persist also posts it to the conversation.
+type CallChatEvent struct {
+	CallID         uuid.UUID  `json:"call_id"`
+	From           uuid.UUID  `json:"from"`
+	To             uuid.UUID  `json:"to"`
+	ConversationID uuid.UUID  `json:"conversation_id"`
+	Text           string     `json:"text"`
+	Persist        bool       `json:"persist,omitempty"`
+	MessageID      *uuid.UUID `json:"message_id,omitempty"` // set by the server when persisted
+}
+
+// CallReactionEvent is an emoji reaction within a call; never persisted
+type CallReactionEvent struct {
+	CallID         uuid.UUID `json:"call_id"`
+	From           uuid.UUID `json:"from"`
+	To             uuid.UUID `json:"to"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	Emoji          string    `json:"emoji"`
+}
+
+// CallSignal is what the server reads of any call signaling payload; the rest
+// is forwarded untouched
+type CallSignal struct {
+	CallID         uuid.UUID `json:"call_id"`
+	From           uuid.UUID `json:"from"`
+	To             uuid.UUID `json:"to"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	CallType       string    `json:"call_type"` // call_offer
+	Sharing        *bool     `json:"sharing"`   // call_screen_share
+	Track          string    `json:"track"`     // call_track_state
+	Muted          *bool     `json:"muted"`     // call_track_state
+	Text           string    `json:"text"`      // call_chat
+	Persist        bool      `json:"persist"`   // call_chat
+	Emoji          string    `json:"emoji"`     // call_reaction
+}
+
+// ========== Admin DTOs ==========
+
+type FailedEmailsResponse struct {
+	Stats mailer.QueueStats `json:"stats"`
+	Jobs  []mailer.Job      `json:"jobs"`
+}
+
+// LDAPSyncResult counts what a directory sync changed
+type LDAPSyncResult struct {
+	Users       int `json:"users"` // directory entries that map to users
+	Created     int `json:"created"`
+	Updated     int `json:"updated"`
+	Deactivated int `json:"deactivated"`
+	Reactivated int `json:"reactivated"`
+	Groups      int `json:"groups"`  // group conversations created or brought in line with the directory
+	Skipped     int `json:"skipped"` // entries without an email or ID
+}
+
 // ========== Common ==========
 
+// ErrorResponse is the body of every error response. Code is stable and meant
+// for programs; Error and Message are for humans (see docs/errors.md)
 type ErrorResponse struct {
-	Error   string `json:"error"`
-	Message string `json:"message,omitempty"`
+	Code    apperror.Code `json:"code"`
+	Error   string        `json:"error"`
+	Message string        `json:"message,omitempty"`
+	Details interface{}   `json:"details,omitempty"`
+}
+
+// FieldError describes one invalid request field; invalid_request responses
+// carry a list of them in details
+type FieldError struct {
+	Field   string `json:"field"`   // JSON path, e.g. "quiet_hours.start"
+	Rule    string `json:"rule"`    // failed rule, e.g. "required", "max"
+	Message string `json:"message"` // localized to the user's language
+}
+
+// PageMeta describes a list in API v2 responses
+type PageMeta struct {
+	Count       int    `json:"count"`
+	HasMore     bool   `json:"has_more"`               // paginated lists only
+	NextCursor  string `json:"next_cursor,omitempty"`  // pass as `before` (`after` for members) to get the next page
+	PrevCursor  string `json:"prev_cursor,omitempty"`  // messages only: pass as `after` to get newer messages
+	UnreadCount *int64 `json:"unread_count,omitempty"` // notifications only
+}
+
+// ListResponse is the API v2 envelope for lists
+type ListResponse struct {
+	Data interface{} `json:"data"`
+	Meta PageMeta    `json:"meta"`
 }
 
 type SuccessResponse struct {
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExportFormat is the file format of a conversation export
+type ExportFormat string
+
+const (
+	ExportFormatJSON ExportFormat = "json"
+	ExportFormatHTML ExportFormat = "html"
+	ExportFormatCSV  ExportFormat = "csv"
+)
+
+// ExportStatus tracks a conversation export through the background worker
+type ExportStatus string
+
+const (
+	ExportStatusPending    ExportStatus = "pending"
+	ExportStatusProcessing ExportStatus = "processing"
+	ExportStatusCompleted  ExportStatus = "completed"
+	ExportStatusFailed     ExportStatus = "failed"
+)
+
+// ConversationExport is a requested export of a conversation's history.
+// Jobs live in Redis and expire together with the exported file.
+type ConversationExport struct {
+	ID             uuid.UUID    `json:"id"`
+	ConversationID uuid.UUID    `json:"conversation_id"`
+	UserID         uuid.UUID    `json:"user_id"`
+	Format         ExportFormat `json:"format"`
+	Status         ExportStatus `json:"status"`
+	MessageCount   int          `json:"message_count,omitempty"`
+	FileSize       int64        `json:"file_size,omitempty"`
+	ObjectKey      string       `json:"-"`
+	DownloadURL    string       `json:"download_url,omitempty"` // presigned on each read, valid until URLExpiresAt
+	URLExpiresAt   *time.Time   `json:"url_expires_at,omitempty"`
+	Error          string       `json:"error,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+	CompletedAt    *time.Time   `json:"completed_at,omitempty"`
+	ExpiresAt      time.Time    `json:"expires_at"` // the export and its file are deleted after this
+}
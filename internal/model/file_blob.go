@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// FileBlob is a deduplicated stored object, keyed by the SHA-256 of its content.
+// RefCount tracks how many message attachments reference it; unreferenced blobs
+// are purged from storage.
+type FileBlob struct {
+	Hash      string    `json:"hash" gorm:"type:char(64);primaryKey"`
+	ObjectKey string    `json:"object_key" gorm:"size:500;not null"`
+	URL       string    `json:"url" gorm:"size:1000;uniqueIndex;not null"`
+	MimeType  string    `json:"mime_type" gorm:"size:100"`
+	Size      int64     `json:"size"`
+	RefCount  int       `json:"ref_count" gorm:"not null;default:0"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,25 @@
+package model
+
+// FeatureFlags are runtime switches operators change through the admin API,
+// without a redeploy
+type FeatureFlags struct {
+	UploadsEnabled   bool `json:"uploads_enabled"`
+	RegistrationOpen bool `json:"registration_open"` // email sign-up; existing accounts can still sign in
+	MaxGroupSize     int  `json:"max_group_size"`    // members of a new group including its creator; 0 = unlimited
+}
+
+// DefaultFeatureFlags are used for flags that were never set
+func DefaultFeatureFlags() FeatureFlags {
+	return FeatureFlags{
+		UploadsEnabled:   true,
+		RegistrationOpen: true,
+		MaxGroupSize:     0,
+	}
+}
+
+// UpdateFeatureFlagsRequest changes some flags; omitted flags keep their value
+type UpdateFeatureFlagsRequest struct {
+	UploadsEnabled   *bool `json:"uploads_enabled"`
+	RegistrationOpen *bool `json:"registration_open"`
+	MaxGroupSize     *int  `json:"max_group_size" binding:"omitempty,min=0"`
+}
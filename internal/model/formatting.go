@@ -0,0 +1,126 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"unicode/utf8"
+)
+
+// EntityType is a kind of formatting applied to a span of message text
+type EntityType string
+
+const (
+	EntityBold          EntityType = "bold"
+	EntityItalic        EntityType = "italic"
+	EntityStrikethrough EntityType = "strikethrough"
+	EntityCode          EntityType = "code" // inline code
+	EntityPre           EntityType = "pre"  // code block, with an optional language
+	EntityLink          EntityType = "link" // text linking to url
+)
+
+// EntityTypes lists the formatting clients may send
+var EntityTypes = []EntityType{EntityBold, EntityItalic, EntityStrikethrough, EntityCode, EntityPre, EntityLink}
+
+// MaxMessageEntities caps the formatting spans of one message
+const MaxMessageEntities = 100
+
+// entityLanguagePattern matches the language of a code block, e.g. "go" or "c++"
+var entityLanguagePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9+#._-]{0,19}$`)
+
+// MessageEntity formats a span of a message's text, Telegram style: the text
+// stays plain and clients render the spans, so formatting can't carry markup
+// or scripts. Offset and Length count characters (Unicode code points) of the
+// text as stored, i.e. after normalization.
+type MessageEntity struct {
+	Type     EntityType `json:"type"`
+	Offset   int        `json:"offset"`
+	Length   int        `json:"length"`
+	URL      string     `json:"url,omitempty"`      // link only; http, https or mailto
+	Language string     `json:"language,omitempty"` // pre only
+}
+
+// End is the offset just past the span
+func (e MessageEntity) End() int {
+	return e.Offset + e.Length
+}
+
+// MessageEntities are the formatting spans of one message
+type MessageEntities []MessageEntity
+
+// Normalize checks the spans against the text and sorts them by offset, outer
+// spans first. Spans must lie within the text and either nest or not touch;
+// code and code blocks can't contain or sit inside other spans, and a span
+// can't sit inside one of its own type.
+func (e MessageEntities) Normalize(content string) error {
+	if len(e) > MaxMessageEntities {
+		return fmt.Errorf("at most %d entities are allowed", MaxMessageEntities)
+	}
+	textLength := utf8.RuneCountInString(content)
+	for i, entity := range e {
+		if err := entity.validate(textLength); err != nil {
+			return fmt.Errorf("entities[%d]: %w", i, err)
+		}
+	}
+
+	slices.SortStableFunc(e, func(a, b MessageEntity) int {
+		if a.Offset != b.Offset {
+			return a.Offset - b.Offset
+		}
+		return b.Length - a.Length
+	})
+	var open []MessageEntity
+	for _, entity := range e {
+		for len(open) > 0 && open[len(open)-1].End() <= entity.Offset {
+			open = open[:len(open)-1]
+		}
+		if len(open) > 0 {
+			outer := open[len(open)-1]
+			if entity.End() > outer.End() {
+				return fmt.Errorf("%s at %d overlaps %s at %d without nesting", entity.Type, entity.Offset, outer.Type, outer.Offset)
+			}
+			if outer.Type == EntityCode || outer.Type == EntityPre || entity.Type == EntityCode || entity.Type == EntityPre {
+				return fmt.Errorf("%s at %d can't be combined with %s at %d", entity.Type, entity.Offset, outer.Type, outer.Offset)
+			}
+			if slices.ContainsFunc(open, func(o MessageEntity) bool { return o.Type == entity.Type }) {
+				return fmt.Errorf("%s at %d sits inside another %s", entity.Type, entity.Offset, entity.Type)
+			}
+		}
+		open = append(open, entity)
+	}
+	return nil
+}
+
+// validate checks one span on its own
+func (e MessageEntity) validate(textLength int) error {
+	if !slices.Contains(EntityTypes, e.Type) {
+		return fmt.Errorf("unknown type %q", e.Type)
+	}
+	if e.Offset < 0 || e.Length <= 0 || e.End() > textLength {
+		return fmt.Errorf("span %d+%d is outside the %d-character text", e.Offset, e.Length, textLength)
+	}
+
+	if e.Type == EntityLink {
+		u, err := url.Parse(e.URL)
+		if err != nil || len(e.URL) > 2048 {
+			return fmt.Errorf("invalid url")
+		}
+		switch u.Scheme {
+		case "http", "https":
+			if u.Host == "" {
+				return fmt.Errorf("invalid url")
+			}
+		case "mailto":
+		default:
+			return fmt.Errorf("url must be http, https or mailto")
+		}
+	} else if e.URL != "" {
+		return fmt.Errorf("only links have a url")
+	}
+
+	if e.Language != "" && (e.Type != EntityPre || !entityLanguagePattern.MatchString(e.Language)) {
+		return fmt.Errorf("only code blocks have a language, of up to 20 lowercase letters, digits or +#._-")
+	}
+	return nil
+}
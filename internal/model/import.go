@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImportStatus tracks a chat import through the background worker
+type ImportStatus string
+
+const (
+	ImportStatusPending    ImportStatus = "pending"
+	ImportStatusProcessing ImportStatus = "processing"
+	ImportStatusCompleted  ImportStatus = "completed"
+	ImportStatusFailed     ImportStatus = "failed"
+)
+
+// ChatImport is an uploaded chat export from another app being turned into a
+// GoTalk conversation. Jobs live in Redis for a week.
+type ChatImport struct {
+	ID                 uuid.UUID    `json:"id"`
+	UserID             uuid.UUID    `json:"user_id"`
+	Source             string       `json:"source"` // whatsapp, telegram
+	Status             ImportStatus `json:"status"`
+	ConversationID     *uuid.UUID   `json:"conversation_id,omitempty"` // set once the conversation is created
+	TotalMessages      int          `json:"total_messages"`
+	ImportedMessages   int          `json:"imported_messages"`
+	SkippedAttachments int          `json:"skipped_attachments"` // missing from the archive or of an unsupported type
+	Error              string       `json:"error,omitempty"`
+	CreatedAt          time.Time    `json:"created_at"`
+	CompletedAt        *time.Time   `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConversationDailyStat is a group's activity on one UTC day, rolled up from
+// its messages and members
+type ConversationDailyStat struct {
+	ConversationID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Day            time.Time `gorm:"type:date;primaryKey"`
+	Messages       int64     `gorm:"not null;default:0"`
+	Joins          int64     `gorm:"not null;default:0"`
+	Leaves         int64     `gorm:"not null;default:0"`
+	Hours          []int64   `gorm:"type:jsonb;serializer:json;not null"` // messages sent in each UTC hour, 24 entries
+}
+
+// ConversationMemberDailyStat is how many messages a member sent to a group on
+// one UTC day
+type ConversationMemberDailyStat struct {
+	ConversationID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Day            time.Time `gorm:"type:date;primaryKey"`
+	UserID         uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Messages       int64     `gorm:"not null;default:0"`
+}
+
+// InsightsRequest selects the days of GET /conversations/:id/insights
+type InsightsRequest struct {
+	From string `form:"from" binding:"omitempty,datetime=2006-01-02"` // default: 29 days before to
+	To   string `form:"to" binding:"omitempty,datetime=2006-01-02"`   // default: today (UTC)
+}
+
+// InsightsDay is a group's activity on a day
+type InsightsDay struct {
+	Day      string `json:"day" example:"2026-10-18"`
+	Messages int64  `json:"messages"`
+	Joins    int64  `json:"joins"`
+	Leaves   int64  `json:"leaves"`
+}
+
+// MemberActivity is how many messages a member, current or former, sent
+type MemberActivity struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Name     string    `json:"name"`
+	Messages int64     `json:"messages"`
+}
+
+// ConversationInsights summarizes a group's activity over a range of days
+type ConversationInsights struct {
+	From     string           `json:"from" example:"2026-09-19"`
+	To       string           `json:"to" example:"2026-10-18"`
+	Messages int64            `json:"messages"`
+	Joins    int64            `json:"joins"`
+	Leaves   int64            `json:"leaves"`
+	Days     []InsightsDay    `json:"days"`    // one per day of the range
+	Hours    []int64          `json:"hours"`   // messages sent in each UTC hour of the day, 24 entries
+	Members  []MemberActivity `json:"members"` // most active first, at most 50
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Invitation is a code issued by an admin that lets people sign up when
+// registration is invitation-only
+type Invitation struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Code      string     `json:"code" gorm:"size:32;not null;uniqueIndex"`
+	Email     string     `json:"email,omitempty" gorm:"size:255"` // only this address may use it; empty allows anyone
+	MaxUses   int        `json:"max_uses" gorm:"not null"`        // 0 means unlimited
+	Uses      int        `json:"uses" gorm:"not null;default:0"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedBy uuid.UUID  `json:"created_by" gorm:"type:uuid;not null"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// CreateInvitationRequest issues an invitation code
+type CreateInvitationRequest struct {
+	Email     string     `json:"email" binding:"omitempty,email"`    // restrict the code to this address
+	MaxUses   *int       `json:"max_uses" binding:"omitempty,min=0"` // default 1; 0 means unlimited
+	ExpiresAt *time.Time `json:"expires_at"`
+}
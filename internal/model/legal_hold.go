@@ -0,0 +1,77 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LegalHold keeps a user's or a conversation's messages from being purged by
+// retention policies or conversation deletion while it's in place. A hold on
+// a user covers every conversation they belong or belonged to.
+type LegalHold struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID         *uuid.UUID `json:"user_id,omitempty" gorm:"type:uuid;index"`
+	ConversationID *uuid.UUID `json:"conversation_id,omitempty" gorm:"type:uuid;index"`
+	Reason         string     `json:"reason" gorm:"size:500;not null"` // e.g. the matter or case reference
+	CreatedBy      uuid.UUID  `json:"created_by" gorm:"type:uuid;not null"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// CreateLegalHoldRequest places a legal hold on a user or a conversation
+type CreateLegalHoldRequest struct {
+	UserID         *uuid.UUID `json:"user_id"`
+	ConversationID *uuid.UUID `json:"conversation_id"`
+	Reason         string     `json:"reason" binding:"required,max=500"`
+}
+
+// ComplianceExportRequest selects the messages of a compliance export
+type ComplianceExportRequest struct {
+	UserID         string     `form:"user_id" binding:"omitempty,uuid"` // sent by the user or in conversations they belong or belonged to
+	ConversationID string     `form:"conversation_id" binding:"omitempty,uuid"`
+	From           *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"` // sent at or after
+	To             *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`   // sent before
+}
+
+// ComplianceRecord is a message in a compliance export, one per NDJSON line.
+// Deleted messages are included, with deleted_at.
+type ComplianceRecord struct {
+	ID             uuid.UUID           `json:"id"`
+	ConversationID uuid.UUID           `json:"conversation_id"`
+	SenderID       uuid.UUID           `json:"sender_id"`
+	SenderEmail    string              `json:"sender_email,omitempty"`
+	ImportedSender string              `json:"imported_sender,omitempty"`
+	Type           MessageType         `json:"type"`
+	Content        string              `json:"content"`
+	FileURL        string              `json:"file_url,omitempty"`
+	FileName       string              `json:"file_name,omitempty"`
+	ReplyToID      *uuid.UUID          `json:"reply_to_id,omitempty"`
+	Attachments    []MessageAttachment `json:"attachments,omitempty"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+	DeletedAt      *time.Time          `json:"deleted_at,omitempty"`
+}
+
+// ToComplianceRecord converts a message, loaded with its sender and
+// attachments, for a compliance export
+func (m *Message) ToComplianceRecord() ComplianceRecord {
+	record := ComplianceRecord{
+		ID:             m.ID,
+		ConversationID: m.ConversationID,
+		SenderID:       m.SenderID,
+		SenderEmail:    m.Sender.Email,
+		ImportedSender: m.ImportedSender,
+		Type:           m.Type,
+		Content:        m.Content,
+		FileURL:        m.FileURL,
+		FileName:       m.FileName,
+		ReplyToID:      m.ReplyToID,
+		Attachments:    m.Attachments,
+		CreatedAt:      m.CreatedAt,
+		UpdatedAt:      m.UpdatedAt,
+	}
+	if m.DeletedAt.Valid {
+		record.DeletedAt = &m.DeletedAt.Time
+	}
+	return record
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginEvent is one sign-in in a user's login history
+type LoginEvent struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID      uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	Fingerprint string    `json:"-" gorm:"size:64;not null"` // SHA-256 of the user agent
+	Device      string    `json:"device" gorm:"size:100"`    // e.g. "Chrome on Windows"
+	UserAgent   string    `json:"user_agent" gorm:"size:500"`
+	IP          string    `json:"ip" gorm:"size:45"`
+	Location    string    `json:"location,omitempty" gorm:"size:255"` // "City, Country", when known
+	CountryCode string    `json:"country_code,omitempty" gorm:"size:2"`
+	NewDevice   bool      `json:"new_device" gorm:"not null;default:false"` // the user was alerted
+	CreatedAt   time.Time `json:"created_at"`
+}
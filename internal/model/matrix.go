@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MatrixRoomLink bridges a conversation to a Matrix room
+type MatrixRoomLink struct {
+	ConversationID uuid.UUID `json:"conversation_id" gorm:"type:uuid;primaryKey"`
+	RoomID         string    `json:"room_id" gorm:"size:255;not null;uniqueIndex"` // !opaque:example.org
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// MatrixLinkRequest links a conversation to a Matrix room, given by ID or alias
+type MatrixLinkRequest struct {
+	ConversationID uuid.UUID `json:"conversation_id" binding:"required"`
+	Room           string    `json:"room" binding:"required"` // !roomid:example.org or #alias:example.org
+}
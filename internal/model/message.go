@@ -1,9 +1,12 @@
 package model
 
 import (
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
+	"golang.org/x/text/unicode/norm"
 	"gorm.io/gorm"
 )
 
@@ -11,11 +14,13 @@ import (
 type MessageType string
 
 const (
-	MessageTypeText  MessageType = "text"
-	MessageTypeImage MessageType = "image"
-	MessageTypeVideo MessageType = "video"
-	MessageTypeFile  MessageType = "file"
-	MessageTypeAudio MessageType = "audio"
+	MessageTypeText   MessageType = "text"
+	MessageTypeImage  MessageType = "image"
+	MessageTypeVideo  MessageType = "video"
+	MessageTypeFile   MessageType = "file"
+	MessageTypeAudio  MessageType = "audio"
+	MessageTypeCode   MessageType = "code"   // content is source code in Language
+	MessageTypeSystem MessageType = "system" // posted by the server, e.g. from a conversation template
 )
 
 // MessageStatus defines the delivery status of a message
@@ -29,19 +34,25 @@ const (
 
 // Message represents a chat message
 type Message struct {
-	ID             uuid.UUID      `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	ConversationID uuid.UUID      `json:"conversation_id" gorm:"type:uuid;index;not null"`
-	SenderID       uuid.UUID      `json:"sender_id" gorm:"type:uuid;index;not null"`
-	Content        string         `json:"content" gorm:"type:text"`
-	Type           MessageType    `json:"type" gorm:"type:varchar(20);default:'text'"`
-	Status         MessageStatus  `json:"status" gorm:"type:varchar(20);default:'sent'"`
-	FileURL        string         `json:"file_url,omitempty" gorm:"size:500"`
-	FileName       string         `json:"file_name,omitempty" gorm:"size:255"`
-	FileSize       int64          `json:"file_size,omitempty"`
-	ReplyToID      *uuid.UUID     `json:"reply_to_id,omitempty" gorm:"type:uuid"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
+	ID             uuid.UUID       `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ConversationID uuid.UUID       `json:"conversation_id" gorm:"type:uuid;index;not null"`
+	SenderID       uuid.UUID       `json:"sender_id" gorm:"type:uuid;index;not null"`
+	Content        string          `json:"content" gorm:"type:text"`
+	Entities       MessageEntities `json:"entities,omitempty" gorm:"type:jsonb;serializer:json"` // formatting of content
+	Type           MessageType     `json:"type" gorm:"type:varchar(20);default:'text'"`
+	Language       string          `json:"language,omitempty" gorm:"size:20"` // code messages, for highlighting; see CodeLanguages
+	Collapsed      bool            `json:"collapsed,omitempty" gorm:"-"`      // content is a preview, see Collapse
+	Status         MessageStatus   `json:"status" gorm:"type:varchar(20);default:'sent'"`
+	FileURL        string          `json:"file_url,omitempty" gorm:"size:500"`
+	FileName       string          `json:"file_name,omitempty" gorm:"size:255"`
+	FileSize       int64           `json:"file_size,omitempty"`
+	ReplyToID      *uuid.UUID      `json:"reply_to_id,omitempty" gorm:"type:uuid"`
+	Imported       bool            `json:"imported,omitempty" gorm:"default:false"`   // copied from another app's chat export
+	ImportedSender string          `json:"imported_sender,omitempty" gorm:"size:100"` // sender's name in the export
+	AutoReply      bool            `json:"auto_reply,omitempty" gorm:"default:false"` // sent by the server from the sender's auto-reply
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt  `json:"-" gorm:"index"`
 
 	// Relations
 	Sender       User                `json:"sender" gorm:"foreignKey:SenderID"`
@@ -62,3 +73,59 @@ type ReadReceipt struct {
 	Message Message `json:"-" gorm:"foreignKey:MessageID"`
 	User    User    `json:"user" gorm:"foreignKey:UserID"`
 }
+
+// DeliveryReceipt tracks when a message reached one of the user's devices
+type DeliveryReceipt struct {
+	ID          uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	MessageID   uuid.UUID `json:"message_id" gorm:"type:uuid;uniqueIndex:idx_delivery_receipts_message_user;not null"`
+	UserID      uuid.UUID `json:"user_id" gorm:"type:uuid;uniqueIndex:idx_delivery_receipts_message_user;index;not null"`
+	DeliveredAt time.Time `json:"delivered_at" gorm:"not null"`
+}
+
+// CleanContent normalizes message text the same way on every send path:
+// Unicode NFC (so "é" is one character however it was typed), CRLF line
+// breaks turned into LF, and control characters other than newline and tab
+// removed.
+func CleanContent(content string) string {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return -1
+		}
+		return r
+	}, norm.NFC.String(content))
+}
+
+// CodeLanguages are the languages code messages may be highlighted as, by
+// their highlight.js / Prism name
+var CodeLanguages = []string{
+	"plaintext", "bash", "c", "cpp", "csharp", "css", "dart", "diff", "dockerfile", "go",
+	"graphql", "html", "java", "javascript", "json", "kotlin", "lua", "markdown", "php",
+	"python", "ruby", "rust", "scala", "shell", "sql", "swift", "toml", "typescript", "xml", "yaml",
+}
+
+// Code message previews keep this many lines and characters
+const (
+	CodePreviewLines  = 10
+	CodePreviewLength = 500
+)
+
+// Collapse cuts a code message down to its first lines for list payloads such
+// as a conversation's last message, marking it Collapsed. The full message
+// comes with the conversation's history.
+func (m *Message) Collapse() {
+	if m.Type != MessageTypeCode {
+		return
+	}
+	preview := m.Content
+	if lines := strings.SplitAfterN(preview, "\n", CodePreviewLines+1); len(lines) > CodePreviewLines {
+		preview = strings.TrimSuffix(strings.Join(lines[:CodePreviewLines], ""), "\n")
+	}
+	if runes := []rune(preview); len(runes) > CodePreviewLength {
+		preview = string(runes[:CodePreviewLength])
+	}
+	if preview != m.Content {
+		m.Content = preview
+		m.Collapsed = true
+	}
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationType categorizes notification center entries
+type NotificationType string
+
+const (
+	NotificationTypeMention     NotificationType = "mention"
+	NotificationTypeGroupInvite NotificationType = "group_invite"
+	NotificationTypeMissedCall  NotificationType = "missed_call"
+	NotificationTypeAdminNotice NotificationType = "admin_notice"
+	NotificationTypeExportReady NotificationType = "export_ready"
+	NotificationTypeImportDone  NotificationType = "import_completed"
+	NotificationTypeNewLogin    NotificationType = "new_login"
+)
+
+// Notification is a persisted notification center entry
+type Notification struct {
+	ID        uuid.UUID         `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    uuid.UUID         `json:"user_id" gorm:"type:uuid;not null;index"`
+	Type      NotificationType  `json:"type" gorm:"size:30;not null"`
+	Title     string            `json:"title" gorm:"size:255;not null"`
+	Body      string            `json:"body" gorm:"type:text"`
+	Data      map[string]string `json:"data,omitempty" gorm:"type:jsonb;serializer:json"` // e.g. conversation_id, caller_id
+	ReadAt    *time.Time        `json:"read_at" gorm:"type:timestamptz"`                  // NULL = unread
+	CreatedAt time.Time         `json:"created_at"`
+}
@@ -0,0 +1,227 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scopes third-party apps can request
+const (
+	ScopeOpenID        = "openid" // sign in with GoTalk: an ID token and /oauth/userinfo
+	ScopeProfile       = "profile"
+	ScopeEmail         = "email"
+	ScopeReadMessages  = "read:messages"
+	ScopeWriteMessages = "write:messages"
+)
+
+// OAuthScope describes a scope on the consent screen
+type OAuthScope struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// OAuthScopes lists every scope, in the order consent screens show them
+var OAuthScopes = []OAuthScope{
+	{ScopeOpenID, "Sign you in with your GoTalk account"},
+	{ScopeProfile, "See your name and profile picture"},
+	{ScopeEmail, "See your email address"},
+	{ScopeReadMessages, "Read your conversations and messages"},
+	{ScopeWriteMessages, "Send messages and mark conversations read for you"},
+}
+
+// OAuthClient is a third-party app registered to act for GoTalk users
+type OAuthClient struct {
+	ID           string    `gorm:"size:64;primaryKey"` // the client_id
+	Name         string    `gorm:"size:100;not null"`
+	RedirectURIs []string  `gorm:"type:jsonb;serializer:json;not null"`
+	Scopes       []string  `gorm:"type:jsonb;serializer:json;not null"` // the most the app may ask for
+	SecretHash   string    `gorm:"size:64"`                             // SHA-256 of the secret; empty for public clients
+	OwnerID      uuid.UUID `gorm:"type:uuid;not null;index"`
+	CreatedAt    time.Time
+}
+
+func (OAuthClient) TableName() string { return "oauth_clients" }
+
+// Public tells whether the app can't keep a secret (single-page and mobile
+// apps); it must then use PKCE
+func (c *OAuthClient) Public() bool {
+	return c.SecretHash == ""
+}
+
+// OAuthClientResponse describes a registered app to its owner
+type OAuthClientResponse struct {
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret,omitempty"` // only in the registration response
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Public       bool      `json:"public"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (c *OAuthClient) ToResponse() OAuthClientResponse {
+	return OAuthClientResponse{
+		ClientID:     c.ID,
+		Name:         c.Name,
+		RedirectURIs: c.RedirectURIs,
+		Scopes:       c.Scopes,
+		Public:       c.Public(),
+		CreatedAt:    c.CreatedAt,
+	}
+}
+
+// OAuthGrant records the scopes a user agreed to give an app
+type OAuthGrant struct {
+	UserID    uuid.UUID   `gorm:"type:uuid;primaryKey"`
+	ClientID  string      `gorm:"size:64;primaryKey"`
+	Scopes    []string    `gorm:"type:jsonb;serializer:json;not null"`
+	Client    OAuthClient `gorm:"foreignKey:ClientID"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (OAuthGrant) TableName() string { return "oauth_grants" }
+
+// OAuthRefreshToken lets an app get new access tokens without the user. It is
+// replaced by a new one on every use.
+type OAuthRefreshToken struct {
+	TokenHash string    `gorm:"size:64;primaryKey"` // SHA-256; the token itself is never stored
+	ClientID  string    `gorm:"size:64;not null;index"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	Scopes    []string  `gorm:"type:jsonb;serializer:json;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+func (OAuthRefreshToken) TableName() string { return "oauth_refresh_tokens" }
+
+// CreateOAuthClientRequest registers an app
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required,min=1,max=10,dive,required,max=2000"`
+	Scopes       []string `json:"scopes" binding:"required,min=1,dive,oneof=openid profile email read:messages write:messages"`
+	Public       bool     `json:"public"` // no client secret; the app must use PKCE
+}
+
+// OAuthAuthorizeRequest is the authorization request an app sent the user's
+// browser to the consent page with; the page passes its query string on
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `form:"response_type" binding:"required,eq=code"`
+	ClientID            string `form:"client_id" binding:"required"`
+	RedirectURI         string `form:"redirect_uri"` // may be left out when the app registered only one
+	Scope               string `form:"scope" binding:"required"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge"` // PKCE; required for public clients
+	CodeChallengeMethod string `form:"code_challenge_method" binding:"omitempty,eq=S256"`
+	Nonce               string `form:"nonce"` // echoed in the ID token
+}
+
+// OAuthConsent is what the consent screen shows
+type OAuthConsent struct {
+	ClientID    string       `json:"client_id"`
+	ClientName  string       `json:"client_name"`
+	Scopes      []OAuthScope `json:"scopes"`
+	RedirectURI string       `json:"redirect_uri"`
+	Granted     bool         `json:"granted"` // the user already agreed to all of these scopes
+}
+
+// OAuthDecisionRequest answers an authorization request
+type OAuthDecisionRequest struct {
+	Approve *bool `json:"approve" binding:"required"`
+}
+
+// OAuthRedirect is where the consent page sends the browser back to the app
+type OAuthRedirect struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// OAuthTokenRequest is a form-encoded token request (RFC 6749 section 4.1.3
+// and 6). Confidential clients authenticate with HTTP Basic or the client_id
+// and client_secret fields.
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"` // narrows a refresh; defaults to the original scopes
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// OAuthTokenResponse carries the tokens issued to an app
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // seconds
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+	IDToken      string `json:"id_token,omitempty"` // with the openid scope
+}
+
+// OAuthRevokeRequest is a form-encoded token revocation (RFC 7009)
+type OAuthRevokeRequest struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// OAuthErrorResponse is the error body of the token and revocation
+// endpoints, in the form OAuth client libraries expect (RFC 6749 section 5.2)
+type OAuthErrorResponse struct {
+	Error       string `json:"error"` // e.g. invalid_grant
+	Description string `json:"error_description,omitempty"`
+}
+
+// ConnectedApp is an app the user has given access to their account
+type ConnectedApp struct {
+	ClientID     string    `json:"client_id"`
+	Name         string    `json:"name"`
+	Scopes       []string  `json:"scopes"`
+	AuthorizedAt time.Time `json:"authorized_at"`
+	UpdatedAt    time.Time `json:"updated_at"` // last time the user agreed to more scopes
+}
+
+// UserInfo is the OpenID Connect userinfo response; fields depend on the
+// granted scopes
+type UserInfo struct {
+	Subject       string `json:"sub"`
+	Name          string `json:"name,omitempty"`
+	Picture       string `json:"picture,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+}
+
+// OpenIDConfiguration is the OpenID Connect discovery document
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+}
+
+// JSONWebKeySet publishes the keys that verify ID tokens (RFC 7517)
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JSONWebKey is a public RSA signing key
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OnboardingAction is what an onboarding rule does when someone joins a group
+type OnboardingAction string
+
+const (
+	OnboardingWelcomeDM  OnboardingAction = "welcome_dm"  // message the new member directly
+	OnboardingPostRules  OnboardingAction = "post_rules"  // post the group's rules to the group
+	OnboardingAssignRole OnboardingAction = "assign_role" // give the new member Role after AfterDays
+)
+
+// OnboardingActions lists the actions rules may take
+var OnboardingActions = []OnboardingAction{OnboardingWelcomeDM, OnboardingPostRules, OnboardingAssignRole}
+
+// MaxOnboardingRules caps the rules of one group
+const MaxOnboardingRules = 10
+
+// OnboardingRule is an action a group takes automatically when a member is
+// added. It runs on behalf of the admin who created it, and is skipped once
+// they no longer manage the group.
+type OnboardingRule struct {
+	ID             uuid.UUID        `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ConversationID uuid.UUID        `json:"conversation_id" gorm:"type:uuid;not null;index"`
+	Action         OnboardingAction `json:"action" gorm:"type:varchar(20);not null"`
+	Content        string           `json:"content,omitempty" gorm:"type:text"`     // welcome_dm and post_rules
+	Role           MemberRole       `json:"role,omitempty" gorm:"type:varchar(20)"` // assign_role
+	AfterDays      int              `json:"after_days" gorm:"not null;default:0"`   // assign_role: days after joining; 0 is right away
+	CreatedBy      uuid.UUID        `json:"created_by" gorm:"type:uuid;not null"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+// OnboardingRuleRequest adds an onboarding rule to a group
+type OnboardingRuleRequest struct {
+	Action    OnboardingAction `json:"action" binding:"required"`
+	Content   string           `json:"content" binding:"max=4000"`         // welcome_dm and post_rules
+	Role      MemberRole       `json:"role"`                               // assign_role: a custom role of the group
+	AfterDays int              `json:"after_days" binding:"min=0,max=365"` // assign_role
+}
+
+// AddMembersRequest adds users to a group
+type AddMembersRequest struct {
+	MemberIDs []uuid.UUID `json:"member_ids" binding:"required,min=1,max=100"`
+}
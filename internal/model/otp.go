@@ -12,16 +12,18 @@ type OTPPurpose string
 const (
 	OTPPurposeEmailVerification OTPPurpose = "email_verification"
 	OTPPurposePasswordReset     OTPPurpose = "password_reset"
+	OTPPurposeMagicLink         OTPPurpose = "magic_link" // sign-in link; the "code" is the link's secret
 )
 
 // OTPCode represents a one-time password for email verification or password reset
 type OTPCode struct {
 	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
 	UserID    uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
-	Code      string     `json:"-" gorm:"size:6;not null"`         // 6-digit numeric code
+	CodeHash  string     `json:"-" gorm:"size:64;not null"`   // HMAC-SHA256 of the code (hex); the code itself is not stored
+	Attempts  int        `json:"-" gorm:"not null;default:0"` // Wrong guesses so far
 	Purpose   OTPPurpose `json:"purpose" gorm:"type:otp_purpose;default:'email_verification'"`
-	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`       // When the code becomes invalid
-	UsedAt    *time.Time `json:"used_at"`                          // NULL = not yet used
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"` // When the code becomes invalid
+	UsedAt    *time.Time `json:"used_at"`                    // NULL = not yet used
 	CreatedAt time.Time  `json:"created_at"`
 
 	// Relations
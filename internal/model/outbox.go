@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxEventType says what a worker does when it publishes an outbox event
+type OutboxEventType string
+
+const (
+	OutboxMessageBroadcast OutboxEventType = "message.broadcast" // new_message over WebSocket to the other members
+	OutboxMessageNotify    OutboxEventType = "message.notify"    // push notifications and mention entries
+	OutboxMessageRelay     OutboxEventType = "message.relay"     // copy to a bridged network (Matrix)
+	OutboxMessageIndex     OutboxEventType = "message.index"     // add to the search index (OpenSearch)
+	OutboxMemberAdded      OutboxEventType = "member.added"      // run the group's onboarding rules for a new member
+	OutboxOnboardingRole   OutboxEventType = "onboarding.role"   // an onboarding rule's delayed role assignment
+)
+
+// OutboxEvent is fan-out work saved in the same transaction as the change it
+// announces, so a crash can't lose it. Rows are deleted once published.
+type OutboxEvent struct {
+	ID          uuid.UUID         `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Type        OutboxEventType   `json:"type" gorm:"size:50;not null"`
+	Payload     map[string]string `json:"payload" gorm:"type:jsonb;serializer:json;not null"` // e.g. message_id or user_id
+	Attempts    int               `json:"attempts" gorm:"not null;default:0"`
+	LastError   string            `json:"last_error,omitempty" gorm:"type:text"`
+	AvailableAt time.Time         `json:"available_at" gorm:"type:timestamptz;not null;default:now();index"` // not picked up before this
+	CreatedAt   time.Time         `json:"created_at"`
+}
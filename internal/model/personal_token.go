@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PersonalAccessToken is a long-lived, scoped API token a user creates for
+// scripts and integrations that can't sign in interactively. Only its hash
+// is stored.
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID     uuid.UUID  `json:"-" gorm:"type:uuid;not null;index"`
+	Name       string     `json:"name" gorm:"size:100;not null"`
+	TokenHash  string     `json:"-" gorm:"size:64;not null;uniqueIndex"` // SHA-256 of the token
+	Hint       string     `json:"hint" gorm:"size:16;not null"`          // the token's first characters, to tell tokens apart
+	Scopes     []string   `json:"scopes" gorm:"type:jsonb;serializer:json;not null"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // nil never expires
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty" gorm:"size:45"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatePersonalTokenRequest creates a personal access token
+type CreatePersonalTokenRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,oneof=openid profile email read:messages write:messages"`
+	ExpiresAt *time.Time `json:"expires_at"` // empty never expires
+}
+
+// CreatePersonalTokenResponse is a new personal access token. The token
+// itself is only returned here.
+type CreatePersonalTokenResponse struct {
+	PersonalAccessToken
+	Token string `json:"token"`
+}
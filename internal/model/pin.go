@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PinnedMessage is a message pinned to the top of a conversation. Pins are
+// ordered by Position, new ones on top; at most one is the announcement,
+// which conversation payloads carry as a banner.
+type PinnedMessage struct {
+	ConversationID uuid.UUID `json:"conversation_id" gorm:"type:uuid;primaryKey"`
+	MessageID      uuid.UUID `json:"message_id" gorm:"type:uuid;primaryKey"`
+	Position       int       `json:"position" gorm:"not null;default:0"` // 0 is the top
+	Announcement   bool      `json:"announcement" gorm:"not null;default:false"`
+	PinnedBy       uuid.UUID `json:"pinned_by" gorm:"type:uuid;not null"`
+	PinnedAt       time.Time `json:"pinned_at" gorm:"autoCreateTime"`
+
+	// Relations
+	Message Message `json:"message" gorm:"foreignKey:MessageID"`
+}
+
+// PinMessageRequest pins a message, or changes whether a pinned one is the announcement
+type PinMessageRequest struct {
+	MessageID    uuid.UUID `json:"message_id" binding:"required"`
+	Announcement bool      `json:"announcement"` // show it as the conversation's banner, replacing the current one
+}
+
+// ReorderPinsRequest orders a conversation's pins, top first
+type ReorderPinsRequest struct {
+	MessageIDs []uuid.UUID `json:"message_ids" binding:"required,min=1"` // every pinned message, once
+}
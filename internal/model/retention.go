@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RetentionPolicy limits how long messages are kept, across the deployment
+// or for one conversation, overriding the deployment's
+type RetentionPolicy struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	ConversationID *uuid.UUID `json:"conversation_id,omitempty" gorm:"type:uuid;uniqueIndex"` // nil for the deployment
+	MaxAgeDays     int        `json:"max_age_days" gorm:"not null"`                           // older messages are purged; 0 keeps them
+	UpdatedBy      uuid.UUID  `json:"updated_by" gorm:"type:uuid"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// SetRetentionPolicyRequest sets a retention policy
+type SetRetentionPolicyRequest struct {
+	MaxAgeDays *int `json:"max_age_days" binding:"required,min=0,max=36500"` // 0 keeps messages forever
+}
+
+// RetentionPoliciesResponse lists the retention policies in force
+type RetentionPoliciesResponse struct {
+	Deployment    *RetentionPolicy  `json:"deployment"` // null keeps messages forever
+	Conversations []RetentionPolicy `json:"conversations"`
+}
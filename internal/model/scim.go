@@ -0,0 +1,141 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SCIM 2.0 (RFC 7643/7644) resources used for directory sync with identity providers.
+// Attribute names follow the SCIM schema (camelCase), not the API's snake_case.
+const (
+	SCIMContentType = "application/scim+json"
+
+	SCIMSchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMSchemaGroup        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIMSchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMSchemaPatchOp      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMSchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SCIMSchemaConfig       = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+)
+
+// SCIMMeta describes a SCIM resource
+type SCIMMeta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location,omitempty"`
+}
+
+type SCIMName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type SCIMEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// SCIMUser is a GoTalk user as a SCIM User: userName is the email address
+type SCIMUser struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	ExternalID  string      `json:"externalId,omitempty"`
+	UserName    string      `json:"userName" binding:"required,max=255"`
+	Name        *SCIMName   `json:"name,omitempty"`
+	DisplayName string      `json:"displayName,omitempty" binding:"max=100"`
+	Emails      []SCIMEmail `json:"emails,omitempty"`
+	Active      *bool       `json:"active,omitempty"` // defaults to true on create
+	Meta        *SCIMMeta   `json:"meta,omitempty"`
+}
+
+// SCIMMember references a user in a group
+type SCIMMember struct {
+	Value   string `json:"value" binding:"required"`
+	Display string `json:"display,omitempty"`
+}
+
+// SCIMGroup is a provisioned group conversation as a SCIM Group
+type SCIMGroup struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	DisplayName string       `json:"displayName" binding:"required,max=100"`
+	Members     []SCIMMember `json:"members,omitempty" binding:"dive"`
+	Meta        *SCIMMeta    `json:"meta,omitempty"`
+}
+
+// SCIMListResponse is a page of SCIM resources (startIndex is 1-based)
+type SCIMListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int64       `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// SCIMPatchRequest is a SCIM PATCH body
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations" binding:"required,min=1,dive"`
+}
+
+// SCIMPatchOperation adds, replaces or removes the attribute at path. Without
+// a path, value is an object of attributes to set.
+type SCIMPatchOperation struct {
+	Op    string          `json:"op" binding:"required"` // add, replace, remove (any case)
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// SCIMError is the SCIM error body
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// ToSCIM converts User to a SCIM User
+func (u *User) ToSCIM() SCIMUser {
+	active := u.IsActive()
+	var externalID string
+	if u.ExternalID != nil {
+		externalID = *u.ExternalID
+	}
+	return SCIMUser{
+		Schemas:     []string{SCIMSchemaUser},
+		ID:          u.ID.String(),
+		ExternalID:  externalID,
+		UserName:    u.Email,
+		Name:        &SCIMName{Formatted: u.Name},
+		DisplayName: u.PublicName(),
+		Emails:      []SCIMEmail{{Value: u.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta:        &SCIMMeta{ResourceType: "User", Created: u.CreatedAt, LastModified: u.UpdatedAt},
+	}
+}
+
+// ToSCIMGroup converts a provisioned group conversation to a SCIM Group.
+// Members are left out when the identity provider excludes them (large groups).
+func (c *Conversation) ToSCIMGroup(withMembers bool) SCIMGroup {
+	var externalID string
+	if c.ExternalID != nil {
+		externalID = *c.ExternalID
+	}
+	group := SCIMGroup{
+		Schemas:     []string{SCIMSchemaGroup},
+		ID:          c.ID.String(),
+		ExternalID:  externalID,
+		DisplayName: c.Name,
+		Meta:        &SCIMMeta{ResourceType: "Group", Created: c.CreatedAt, LastModified: c.UpdatedAt},
+	}
+	if withMembers {
+		for _, m := range c.Members {
+			group.Members = append(group.Members, SCIMMember{Value: m.UserID.String(), Display: m.User.PublicName()})
+		}
+	}
+	return group
+}
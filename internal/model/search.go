@@ -0,0 +1,64 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SearchResultType is what a search result is
+type SearchResultType string
+
+const (
+	SearchResultMessage      SearchResultType = "message"
+	SearchResultConversation SearchResultType = "conversation"
+	SearchResultUser         SearchResultType = "user"
+	SearchResultFile         SearchResultType = "file"
+)
+
+// SearchResultTypes lists every result type
+var SearchResultTypes = []SearchResultType{
+	SearchResultMessage,
+	SearchResultConversation,
+	SearchResultUser,
+	SearchResultFile,
+}
+
+// SearchRequest is a query of GET /search
+type SearchRequest struct {
+	Q              string `form:"q" binding:"required,min=2,max=200"`
+	Types          string `form:"types"`                                    // comma-separated result types; all if empty
+	ConversationID string `form:"conversation_id" binding:"omitempty,uuid"` // only messages and files of this conversation
+	Limit          int    `form:"limit" binding:"omitempty,min=1,max=50"`   // default 20
+}
+
+// FileSearchHit is an attachment whose file name matches a search
+type FileSearchHit struct {
+	AttachmentID   uuid.UUID      `json:"attachment_id"`
+	MessageID      uuid.UUID      `json:"message_id"`
+	ConversationID uuid.UUID      `json:"conversation_id"`
+	SenderID       uuid.UUID      `json:"sender_id"`
+	Type           AttachmentType `json:"type"`
+	URL            string         `json:"url"`
+	FileName       string         `json:"file_name"`
+	FileSize       int64          `json:"file_size"`
+	MimeType       string         `json:"mime_type"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// SearchResult is one hit of a search; the field named by Type is set
+type SearchResult struct {
+	Type         SearchResultType `json:"type"`
+	Score        float64          `json:"score"` // 0-1, higher first
+	Message      *Message         `json:"message,omitempty"`
+	Conversation *Conversation    `json:"conversation,omitempty"`
+	User         *UserResponse    `json:"user,omitempty"`
+	File         *FileSearchHit   `json:"file,omitempty"`
+}
+
+// SearchResponse mixes the hits of every searched type, best first
+type SearchResponse struct {
+	Query   string                   `json:"query"`
+	Results []SearchResult           `json:"results"`
+	Counts  map[SearchResultType]int `json:"counts"` // results per type
+}
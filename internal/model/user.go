@@ -1,35 +1,91 @@
 package model
 
 import (
+	"regexp"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
+	"golang.org/x/text/unicode/norm"
 	"gorm.io/gorm"
 )
 
+// PrivacyLevel controls who can see a piece of user information
+type PrivacyLevel string
+
+const (
+	PrivacyEveryone PrivacyLevel = "everyone"
+	PrivacyContacts PrivacyLevel = "contacts" // users who share a private conversation
+	PrivacyNobody   PrivacyLevel = "nobody"
+)
+
+// Allows reports whether a viewer may see the information
+func (p PrivacyLevel) Allows(isContact bool) bool {
+	switch p {
+	case PrivacyNobody:
+		return false
+	case PrivacyContacts:
+		return isContact
+	default:
+		return true
+	}
+}
+
 // AuthProvider defines how the user authenticates
 type AuthProvider string
 
 const (
 	AuthProviderEmail  AuthProvider = "email"
 	AuthProviderGoogle AuthProvider = "google"
+	AuthProviderSSO    AuthProvider = "sso"    // enterprise single sign-on (OpenID Connect)
+	AuthProviderMatrix AuthProvider = "matrix" // remote Matrix user posting through the bridge; can't sign in
+	AuthProviderLDAP   AuthProvider = "ldap"   // password checked against the LDAP / Active Directory server
 )
 
 // User represents a registered user with multi-provider authentication
 type User struct {
 	ID              uuid.UUID    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
 	Name            string       `json:"name" gorm:"size:100;not null"`
+	DisplayName     string       `json:"display_name" gorm:"size:100;default:''"`    // shown to other users instead of Name when set
+	Handle          string       `json:"handle" gorm:"uniqueIndex;size:30;not null"` // unique @handle used for mentions
 	Email           string       `json:"email" gorm:"uniqueIndex;not null;size:255"`
 	Password        string       `json:"-" gorm:"size:255"` // NULL for Google OAuth users
 	Avatar          string       `json:"avatar" gorm:"size:500;default:''"`
+	Bio             string       `json:"bio" gorm:"size:500;default:''"`
+	StatusMessage   string       `json:"status_message" gorm:"size:100;default:''"` // custom status ("In a meeting")
+	StatusEmoji     string       `json:"status_emoji" gorm:"size:16;default:''"`
+	StatusExpiresAt *time.Time   `json:"status_expires_at" gorm:"type:timestamptz"` // NULL = until cleared
 	AuthProvider    AuthProvider `json:"auth_provider" gorm:"type:auth_provider;default:'email'"`
 	GoogleID        *string      `json:"-" gorm:"uniqueIndex;size:255"`             // Google's unique ID
+	SSOSubject      *string      `json:"-" gorm:"size:255"`                         // SSO provider's subject (sub) for linked accounts
 	EmailVerifiedAt *time.Time   `json:"email_verified_at" gorm:"type:timestamptz"` // NULL = not verified
+	ExternalID      *string      `json:"-" gorm:"size:255"`                         // identity provider's ID (SCIM externalId)
+	DeactivatedAt   *time.Time   `json:"-" gorm:"type:timestamptz"`                 // set by SCIM; deactivated users can't sign in
+	MatrixID        *string      `json:"matrix_id,omitempty" gorm:"size:255"`       // remote Matrix user this account stands in for (bridge)
 	// User Settings
 	Theme                 string `json:"theme" gorm:"size:20;default:'system'"`
 	IsNotificationEnabled bool   `json:"is_notification_enabled" gorm:"default:true"`
 	IsSoundEnabled        bool   `json:"is_sound_enabled" gorm:"default:true"`
+	IsDigestEnabled       bool   `json:"is_digest_enabled" gorm:"default:false"`                                                  // weekly unread digest email
+	IsEmailNotifEnabled   bool   `json:"is_email_notification_enabled" gorm:"column:is_email_notification_enabled;default:false"` // message emails while offline, answerable by reply
 	Language              string `json:"language" gorm:"size:10;default:'vi'"`
+	Timezone              string `json:"timezone" gorm:"size:64;default:'UTC'"`
+	SingleSession         bool   `json:"single_session" gorm:"default:false"` // signing in ends the user's other sessions
+	// Privacy
+	LastSeenPrivacy PrivacyLevel `json:"-" gorm:"size:20;default:'everyone'"`
+	OnlinePrivacy   PrivacyLevel `json:"-" gorm:"size:20;default:'everyone'"`
+	Discoverability PrivacyLevel `json:"-" gorm:"size:20;default:'everyone'"` // who can find the user in search
+	// Quiet hours (do not disturb): pushes are suppressed between start and end in the user's timezone
+	QuietHoursEnabled       bool   `json:"quiet_hours_enabled" gorm:"default:false"`
+	QuietHoursStart         string `json:"quiet_hours_start" gorm:"size:5;default:'22:00'"` // HH:MM
+	QuietHoursEnd           string `json:"quiet_hours_end" gorm:"size:5;default:'07:00'"`   // HH:MM
+	QuietHoursSummary       bool   `json:"quiet_hours_summary" gorm:"default:true"`         // push a summary when the window ends
+	QuietHoursAllowMentions bool   `json:"quiet_hours_allow_mentions" gorm:"default:true"`  // @mentions still notify
+	// Auto-reply (away message): sent once a day per conversation to people who message the user directly
+	AutoReplyText     string     `json:"-" gorm:"size:1000;default:''"`
+	AutoReplyStartsAt *time.Time `json:"-" gorm:"type:timestamptz"` // NULL = from when it was set
+	AutoReplyEndsAt   *time.Time `json:"-" gorm:"type:timestamptz"` // NULL = until cleared
 
 	IsOnline  bool           `json:"is_online" gorm:"default:false"`
 	LastSeen  *time.Time     `json:"last_seen"`
@@ -43,20 +99,219 @@ func (u *User) IsEmailVerified() bool {
 	return u.EmailVerifiedAt != nil
 }
 
+// IsActive reports whether the user may sign in (not deactivated by directory sync)
+func (u *User) IsActive() bool {
+	return u.DeactivatedAt == nil
+}
+
+// QuietHours is the do-not-disturb schedule exposed in user settings
+type QuietHours struct {
+	Enabled       bool   `json:"enabled"`
+	Start         string `json:"start"`
+	End           string `json:"end"`
+	Summary       bool   `json:"summary"`
+	AllowMentions bool   `json:"allow_mentions"`
+}
+
+// Location returns the user's timezone, falling back to UTC when unset or invalid
+func (u *User) Location() *time.Location {
+	if u.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// QuietHoursWindow reports whether t falls inside the user's quiet hours and,
+// if so, when the current window ends. Windows may wrap past midnight (22:00-07:00).
+func (u *User) QuietHoursWindow(t time.Time) (bool, time.Time) {
+	if !u.QuietHoursEnabled {
+		return false, time.Time{}
+	}
+	start, err1 := time.Parse("15:04", u.QuietHoursStart)
+	end, err2 := time.Parse("15:04", u.QuietHoursEnd)
+	if err1 != nil || err2 != nil {
+		return false, time.Time{}
+	}
+
+	local := t.In(u.Location())
+	now := local.Hour()*60 + local.Minute()
+	from := start.Hour()*60 + start.Minute()
+	to := end.Hour()*60 + end.Minute()
+	if from == to {
+		return false, time.Time{}
+	}
+
+	var inside bool
+	if from < to {
+		inside = now >= from && now < to
+	} else {
+		inside = now >= from || now < to
+	}
+	if !inside {
+		return false, time.Time{}
+	}
+
+	endsAt := time.Date(local.Year(), local.Month(), local.Day(), end.Hour(), end.Minute(), 0, 0, local.Location())
+	if !endsAt.After(local) {
+		endsAt = endsAt.AddDate(0, 0, 1)
+	}
+	return true, endsAt
+}
+
+// Handles are 3-30 lowercase letters, digits or underscores
+const (
+	HandleMinLength = 3
+	HandleMaxLength = 30
+)
+
+var handlePattern = regexp.MustCompile(`^[a-z0-9_]{3,30}$`)
+
+// reservedHandles can't be claimed: they clash with group mentions or look official
+var reservedHandles = map[string]bool{
+	"all": true, "everyone": true, "here": true,
+	"admin": true, "support": true, "gotalk": true,
+}
+
+// NormalizeHandle lowercases a handle and strips a leading @
+func NormalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+}
+
+// IsValidHandle reports whether a normalized handle may be claimed
+func IsValidHandle(handle string) bool {
+	return handlePattern.MatchString(handle) && !reservedHandles[handle]
+}
+
+// IsReservedHandle reports whether a handle is kept back from users
+func IsReservedHandle(handle string) bool {
+	return reservedHandles[handle]
+}
+
+// HandleBase derives a handle candidate from a display name
+// ("Nguyễn Văn An" -> "nguyenvanan"); it may still be taken, short or reserved.
+func HandleBase(name string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(name)) {
+		switch {
+		case r == 'đ':
+			b.WriteRune('d')
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'):
+			b.WriteRune(r)
+		}
+		if b.Len() >= 20 {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return "user"
+	}
+	return b.String()
+}
+
+// UserStatus is a custom status shown next to the user's name
+type UserStatus struct {
+	Message   string     `json:"message"`
+	Emoji     string     `json:"emoji"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// ActiveStatus returns the user's custom status, or nil if none is set or it has expired
+func (u *User) ActiveStatus(now time.Time) *UserStatus {
+	if u.StatusMessage == "" && u.StatusEmoji == "" {
+		return nil
+	}
+	if u.StatusExpiresAt != nil && !u.StatusExpiresAt.After(now) {
+		return nil
+	}
+	return &UserStatus{
+		Message:   u.StatusMessage,
+		Emoji:     u.StatusEmoji,
+		ExpiresAt: u.StatusExpiresAt,
+	}
+}
+
+// AutoReply is the away message the server answers direct messages with
+type AutoReply struct {
+	Text     string     `json:"text"`
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+	Active   bool       `json:"active"` // now within the window
+}
+
+// AutoReply returns the user's auto-reply, or nil if none is set
+func (u *User) AutoReply(now time.Time) *AutoReply {
+	if u.AutoReplyText == "" {
+		return nil
+	}
+	return &AutoReply{
+		Text:     u.AutoReplyText,
+		StartsAt: u.AutoReplyStartsAt,
+		EndsAt:   u.AutoReplyEndsAt,
+		Active: (u.AutoReplyStartsAt == nil || !u.AutoReplyStartsAt.After(now)) &&
+			(u.AutoReplyEndsAt == nil || u.AutoReplyEndsAt.After(now)),
+	}
+}
+
+// PresenceState is how a connected user shows to others: online, away (idle
+// or chosen) or busy (do not disturb). Invisible users show as offline.
+type PresenceState string
+
+const (
+	PresenceOnline    PresenceState = "online"
+	PresenceAway      PresenceState = "away"
+	PresenceBusy      PresenceState = "busy"
+	PresenceInvisible PresenceState = "invisible"
+	PresenceOffline   PresenceState = "offline"
+)
+
+// PresenceChoices are the states a user can choose; online lets idle
+// detection switch them to away and back
+var PresenceChoices = []PresenceState{PresenceOnline, PresenceAway, PresenceBusy, PresenceInvisible}
+
+// PublicName returns the name other users see
+func (u *User) PublicName() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	return u.Name
+}
+
+// Privacy holds the user's visibility settings
+type Privacy struct {
+	LastSeen        PrivacyLevel `json:"last_seen"`
+	Online          PrivacyLevel `json:"online"`
+	Discoverability PrivacyLevel `json:"discoverability"`
+}
+
 // UserResponse is the safe version of User for API responses
 type UserResponse struct {
 	ID                    uuid.UUID    `json:"id"`
 	Name                  string       `json:"name"`
+	DisplayName           string       `json:"display_name,omitempty"`
+	Handle                string       `json:"handle"`
 	Email                 string       `json:"email"`
 	Avatar                string       `json:"avatar"`
+	Bio                   string       `json:"bio"`
+	Status                *UserStatus  `json:"status"`
 	AuthProvider          AuthProvider `json:"auth_provider"`
 	EmailVerified         bool         `json:"email_verified"`
 	IsOnline              bool         `json:"is_online"`
 	Theme                 string       `json:"theme"`
 	IsNotificationEnabled bool         `json:"is_notification_enabled"`
 	IsSoundEnabled        bool         `json:"is_sound_enabled"`
+	IsDigestEnabled       bool         `json:"is_digest_enabled"`
+	IsEmailNotifEnabled   bool         `json:"is_email_notification_enabled"`
 	Language              string       `json:"language"`
+	Timezone              string       `json:"timezone"`
+	SingleSession         bool         `json:"single_session"`
+	QuietHours            QuietHours   `json:"quiet_hours"`
+	Privacy               Privacy      `json:"privacy"`
 	LastSeen              *time.Time   `json:"last_seen"`
+	UpdatedAt             time.Time    `json:"updated_at"`
 }
 
 // ToResponse converts User to safe UserResponse
@@ -64,15 +319,80 @@ func (u *User) ToResponse() UserResponse {
 	return UserResponse{
 		ID:                    u.ID,
 		Name:                  u.Name,
+		DisplayName:           u.DisplayName,
+		Handle:                u.Handle,
 		Email:                 u.Email,
 		Avatar:                u.Avatar,
+		Bio:                   u.Bio,
+		Status:                u.ActiveStatus(time.Now()),
 		AuthProvider:          u.AuthProvider,
 		EmailVerified:         u.IsEmailVerified(),
 		IsOnline:              u.IsOnline,
 		Theme:                 u.Theme,
 		IsNotificationEnabled: u.IsNotificationEnabled,
 		IsSoundEnabled:        u.IsSoundEnabled,
+		IsDigestEnabled:       u.IsDigestEnabled,
+		IsEmailNotifEnabled:   u.IsEmailNotifEnabled,
 		Language:              u.Language,
-		LastSeen:              u.LastSeen,
+		Timezone:              u.Timezone,
+		SingleSession:         u.SingleSession,
+		QuietHours: QuietHours{
+			Enabled:       u.QuietHoursEnabled,
+			Start:         u.QuietHoursStart,
+			End:           u.QuietHoursEnd,
+			Summary:       u.QuietHoursSummary,
+			AllowMentions: u.QuietHoursAllowMentions,
+		},
+		Privacy: Privacy{
+			LastSeen:        u.LastSeenPrivacy,
+			Online:          u.OnlinePrivacy,
+			Discoverability: u.Discoverability,
+		},
+		LastSeen:  u.LastSeen,
+		UpdatedAt: u.UpdatedAt,
+	}
+}
+
+// ApplyPrivacy prepares the user to be shown to another user: the public name
+// replaces the account name, email is hidden from non-contacts, and online status
+// and last seen follow the privacy settings. A user always sees their own details.
+// An expired custom status is dropped for everyone.
+func (u *User) ApplyPrivacy(viewerID uuid.UUID, isContact bool) {
+	if u.ActiveStatus(time.Now()) == nil {
+		u.StatusMessage, u.StatusEmoji, u.StatusExpiresAt = "", "", nil
+	}
+	if u.ID == viewerID {
+		return
+	}
+	u.Name = u.PublicName()
+	u.DisplayName = ""
+	if !isContact {
+		u.Email = ""
+	}
+	if !u.OnlinePrivacy.Allows(isContact) {
+		u.IsOnline = false
+	}
+	if !u.LastSeenPrivacy.Allows(isContact) {
+		u.LastSeen = nil
+	}
+}
+
+// ToPublicResponse converts User to UserResponse as seen by another user:
+// privacy settings are applied and private settings are left out
+func (u *User) ToPublicResponse(viewerID uuid.UUID, isContact bool) UserResponse {
+	viewed := *u
+	viewed.ApplyPrivacy(viewerID, isContact)
+	return UserResponse{
+		ID:            viewed.ID,
+		Name:          viewed.Name,
+		Handle:        viewed.Handle,
+		Email:         viewed.Email,
+		Avatar:        viewed.Avatar,
+		Bio:           viewed.Bio,
+		Status:        viewed.ActiveStatus(time.Now()),
+		AuthProvider:  viewed.AuthProvider,
+		EmailVerified: viewed.IsEmailVerified(),
+		IsOnline:      viewed.IsOnline,
+		LastSeen:      viewed.LastSeen,
 	}
 }
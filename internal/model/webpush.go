@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebPushSubscription is a browser's Push API subscription (endpoint + encryption keys)
+type WebPushSubscription struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:uuid;not null;index"`
+	Endpoint  string    `json:"endpoint" gorm:"type:text;not null;uniqueIndex"`
+	P256dh    string    `json:"p256dh" gorm:"size:255;not null"`
+	Auth      string    `json:"auth" gorm:"size:255;not null"`
+	UserAgent string    `json:"user_agent" gorm:"size:500"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/quocanhngo/gotalk/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AnalyticsRepository handles database operations for usage statistics
+type AnalyticsRepository struct {
+	db *gorm.DB
+}
+
+func NewAnalyticsRepository(db *gorm.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// AddStats adds counts to the day's running totals
+func (r *AnalyticsRepository) AddStats(stats []model.UsageStat) error {
+	if len(stats) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "day"}, {Name: "metric"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"value": gorm.Expr("usage_stats.value + excluded.value")}),
+	}).Create(&stats).Error
+}
+
+// SetStats replaces values, for metrics that aren't running totals
+func (r *AnalyticsRepository) SetStats(stats []model.UsageStat) error {
+	if len(stats) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "day"}, {Name: "metric"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value"}),
+	}).Create(&stats).Error
+}
+
+// ListStats returns the stats from one day to another, inclusive, optionally
+// of one metric
+func (r *AnalyticsRepository) ListStats(metric model.UsageMetric, from, to time.Time) ([]model.UsageStat, error) {
+	stats := []model.UsageStat{}
+	query := r.db.Where("day BETWEEN ? AND ?", from, to)
+	if metric != "" {
+		query = query.Where("metric = ?", metric)
+	}
+	err := query.Order("day").Find(&stats).Error
+	return stats, err
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"gorm.io/gorm"
+)
+
+// AuditRepository handles database operations for the audit log
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Create records an event
+func (r *AuditRepository) Create(event *model.AuditEvent) error {
+	return r.db.Create(event).Error
+}
+
+// List returns events, newest first, optionally of one action or conversation
+// and older than before
+func (r *AuditRepository) List(action string, conversationID *uuid.UUID, before *time.Time, limit int) ([]model.AuditEvent, error) {
+	events := []model.AuditEvent{}
+	query := r.db
+	if action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if conversationID != nil {
+		query = query.Where("conversation_id = ?", *conversationID)
+	}
+	if before != nil {
+		query = query.Where("created_at < ?", *before)
+	}
+	err := query.Order("created_at DESC").Limit(limit).Find(&events).Error
+	return events, err
+}
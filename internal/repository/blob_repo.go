@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/quocanhngo/gotalk/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BlobRepository handles database operations for deduplicated file blobs
+type BlobRepository struct {
+	db *gorm.DB
+}
+
+func NewBlobRepository(db *gorm.DB) *BlobRepository {
+	return &BlobRepository{db: db}
+}
+
+// FindByHash finds a blob by its content hash
+func (r *BlobRepository) FindByHash(hash string) (*model.FileBlob, error) {
+	var blob model.FileBlob
+	err := r.db.Where("hash = ?", hash).First(&blob).Error
+	if err != nil {
+		return nil, err
+	}
+	return &blob, nil
+}
+
+// FindByURL finds a blob by its public URL
+func (r *BlobRepository) FindByURL(url string) (*model.FileBlob, error) {
+	var blob model.FileBlob
+	err := r.db.Where("url = ?", url).First(&blob).Error
+	if err != nil {
+		return nil, err
+	}
+	return &blob, nil
+}
+
+// CreateIfNotExists inserts a blob unless one with the same hash already exists.
+// Returns false if another upload won the race.
+func (r *BlobRepository) CreateIfNotExists(blob *model.FileBlob) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(blob)
+	return result.RowsAffected > 0, result.Error
+}
+
+// IncrementRef adds a reference to a blob
+func (r *BlobRepository) IncrementRef(hash string) error {
+	return r.db.Model(&model.FileBlob{}).
+		Where("hash = ?", hash).
+		Updates(map[string]interface{}{
+			"ref_count":  gorm.Expr("ref_count + 1"),
+			"updated_at": gorm.Expr("NOW()"),
+		}).Error
+}
+
+// DecrementRef removes a reference from a blob (never below zero)
+func (r *BlobRepository) DecrementRef(hash string) error {
+	return r.db.Model(&model.FileBlob{}).
+		Where("hash = ? AND ref_count > 0", hash).
+		Updates(map[string]interface{}{
+			"ref_count":  gorm.Expr("ref_count - 1"),
+			"updated_at": gorm.Expr("NOW()"),
+		}).Error
+}
+
+// FindUnreferenced returns blobs with no references that haven't been touched since the cutoff
+func (r *BlobRepository) FindUnreferenced(before time.Time, limit int) ([]model.FileBlob, error) {
+	var blobs []model.FileBlob
+	err := r.db.
+		Where("ref_count = 0 AND updated_at < ?", before).
+		Limit(limit).
+		Find(&blobs).Error
+	return blobs, err
+}
+
+// Touch bumps updated_at so a freshly re-uploaded blob isn't purged before it gets referenced
+func (r *BlobRepository) Touch(hash string) error {
+	return r.db.Model(&model.FileBlob{}).
+		Where("hash = ?", hash).
+		Update("updated_at", gorm.Expr("NOW()")).Error
+}
+
+// DeleteIfUnreferenced deletes a blob row only if it still has no references and
+// hasn't been touched since the cutoff. Returns false if it was reused in the meantime.
+func (r *BlobRepository) DeleteIfUnreferenced(hash string, before time.Time) (bool, error) {
+	result := r.db.Where("hash = ? AND ref_count = 0 AND updated_at < ?", hash, before).Delete(&model.FileBlob{})
+	return result.RowsAffected > 0, result.Error
+}
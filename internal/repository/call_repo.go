@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CallRepository handles database operations for call records
+type CallRepository struct {
+	db *gorm.DB
+}
+
+func NewCallRepository(db *gorm.DB) *CallRepository {
+	return &CallRepository{db: db}
+}
+
+// Create inserts a call record; a resent offer for the same call is ignored
+func (r *CallRepository) Create(call *model.Call) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(call).Error
+}
+
+// FindByID finds a call record by its call ID
+func (r *CallRepository) FindByID(id uuid.UUID) (*model.Call, error) {
+	var call model.Call
+	if err := r.db.First(&call, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &call, nil
+}
+
+// FindCurrent finds the latest call between two users that hasn't ended
+func (r *CallRepository) FindCurrent(userID1, userID2 uuid.UUID) (*model.Call, error) {
+	var call model.Call
+	err := r.db.
+		Where("(caller_id = ? AND callee_id = ?) OR (caller_id = ? AND callee_id = ?)", userID1, userID2, userID2, userID1).
+		Where("status IN ?", []model.CallStatus{model.CallStatusRinging, model.CallStatusOngoing}).
+		Order("created_at DESC").
+		First(&call).Error
+	if err != nil {
+		return nil, err
+	}
+	return &call, nil
+}
+
+// Answer marks a ringing call as picked up
+func (r *CallRepository) Answer(id uuid.UUID, at time.Time) error {
+	return r.db.Model(&model.Call{}).
+		Where("id = ? AND status = ?", id, model.CallStatusRinging).
+		Updates(map[string]interface{}{"status": model.CallStatusOngoing, "answered_at": at}).Error
+}
+
+// End closes a call: ended if it was answered, missed otherwise
+func (r *CallRepository) End(id uuid.UUID, at time.Time) error {
+	return r.db.Model(&model.Call{}).
+		Where("id = ? AND status IN ?", id, []model.CallStatus{model.CallStatusRinging, model.CallStatusOngoing}).
+		Updates(map[string]interface{}{
+			"status":   gorm.Expr("CASE WHEN answered_at IS NULL THEN ? ELSE ? END", model.CallStatusMissed, model.CallStatusEnded),
+			"ended_at": at,
+		}).Error
+}
+
+// CountRenegotiation counts a mid-call renegotiation
+func (r *CallRepository) CountRenegotiation(id uuid.UUID) error {
+	return r.db.Model(&model.Call{}).Where("id = ?", id).
+		Update("renegotiations", gorm.Expr("renegotiations + 1")).Error
+}
+
+// SetMediaState sets one flag of a participant's media state (audio_muted,
+// video_muted or screen_sharing) in place, so both sides can update theirs at
+// once. A screen share starting is counted.
+func (r *CallRepository) SetMediaState(id, userID uuid.UUID, flag string, value bool) error {
+	updates := map[string]interface{}{
+		"media_state": gorm.Expr(
+			"COALESCE(media_state, '{}'::jsonb) || jsonb_build_object(?::text, COALESCE(media_state -> ?::text, '{}'::jsonb) || jsonb_build_object(?::text, ?::boolean))",
+			userID.String(), userID.String(), flag, value,
+		),
+	}
+	if flag == "screen_sharing" && value {
+		updates["screen_shares"] = gorm.Expr("screen_shares + 1")
+	}
+	return r.db.Model(&model.Call{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// SaveFeedback stores a participant's feedback, replacing what they sent before
+func (r *CallRepository) SaveFeedback(feedback *model.CallFeedback) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "call_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rating", "issues", "comment", "updated_at"}),
+	}).Create(feedback).Error
+}
+
+// AddStatsSample appends a WebRTC stats report
+func (r *CallRepository) AddStatsSample(sample *model.CallStatsSample) error {
+	return r.db.Create(sample).Error
+}
+
+// QualityByNetworkRegion aggregates the stats reported in [from, to) by
+// network and region, averaging each participant's call first, with the
+// ratings those participants gave
+func (r *CallRepository) QualityByNetworkRegion(from, to time.Time) ([]model.CallQualityRow, error) {
+	perCall := r.db.Model(&model.CallStatsSample{}).
+		Select("call_id, user_id, network, region, COUNT(*) AS samples, AVG(packet_loss) AS packet_loss, AVG(rtt_ms) AS rtt_ms, AVG(jitter_ms) AS jitter_ms").
+		Where("recorded_at >= ? AND recorded_at < ?", from, to).
+		Group("call_id, user_id, network, region")
+
+	var rows []model.CallQualityRow
+	err := r.db.Table("(?) AS per_call", perCall).
+		Select(`per_call.network, per_call.region, COUNT(*) AS calls, SUM(per_call.samples) AS samples,
+			AVG(per_call.packet_loss) AS avg_packet_loss, AVG(per_call.rtt_ms) AS avg_rtt_ms, AVG(per_call.jitter_ms) AS avg_jitter_ms,
+			COUNT(call_feedback.rating) AS ratings, AVG(call_feedback.rating) AS avg_rating`).
+		Joins("LEFT JOIN call_feedback ON call_feedback.call_id = per_call.call_id AND call_feedback.user_id = per_call.user_id").
+		Group("per_call.network, per_call.region").
+		Order("avg_packet_loss DESC").
+		Scan(&rows).Error
+	return rows, err
+}
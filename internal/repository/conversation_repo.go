@@ -1,9 +1,13 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/pkg/dbresolver"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ConversationRepository handles database operations for Conversation
@@ -20,7 +24,17 @@ func (r *ConversationRepository) Create(conv *model.Conversation) error {
 	return r.db.Create(conv).Error
 }
 
-// FindByID finds a conversation by ID with members
+// CountCreated counts the conversations of a type the user created since the
+// given time (zero for all), leaving out deleted ones
+func (r *ConversationRepository) CountCreated(creatorID uuid.UUID, convType model.ConversationType, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Conversation{}).
+		Where("creator_id = ? AND type = ? AND created_at >= ?", creatorID, convType, since).
+		Count(&count).Error
+	return count, err
+}
+
+// FindByID finds a conversation by ID with all of its members
 func (r *ConversationRepository) FindByID(id uuid.UUID) (*model.Conversation, error) {
 	var conv model.Conversation
 	err := r.db.
@@ -30,9 +44,24 @@ func (r *ConversationRepository) FindByID(id uuid.UUID) (*model.Conversation, er
 	if err != nil {
 		return nil, err
 	}
+	conv.MemberCount = int64(len(conv.Members))
 	return &conv, nil
 }
 
+// FindByIDWithPreview finds a conversation by ID with its member count and a
+// preview of its members: the first `preview` to join, plus the viewer
+func (r *ConversationRepository) FindByIDWithPreview(id, viewerID uuid.UUID, preview int) (*model.Conversation, error) {
+	var conv model.Conversation
+	if err := r.db.Where("id = ?", id).First(&conv).Error; err != nil {
+		return nil, err
+	}
+	conversations := []model.Conversation{conv}
+	if err := r.loadMemberPreviews(r.db, conversations, viewerID, preview); err != nil {
+		return nil, err
+	}
+	return &conversations[0], nil
+}
+
 // FindPrivateConversation finds an existing private conversation between two users
 func (r *ConversationRepository) FindPrivateConversation(userID1, userID2 uuid.UUID) (*model.Conversation, error) {
 	var conv model.Conversation
@@ -48,18 +77,120 @@ func (r *ConversationRepository) FindPrivateConversation(userID1, userID2 uuid.U
 	if err != nil {
 		return nil, err
 	}
+	conv.MemberCount = int64(len(conv.Members))
 	return &conv, nil
 }
 
-// GetUserConversations returns all conversations for a user, ordered by latest activity
-func (r *ConversationRepository) GetUserConversations(userID uuid.UUID) ([]model.Conversation, error) {
+// GetUserConversations returns all conversations for a user, ordered by latest activity,
+// each with a member preview (see FindByIDWithPreview). Served from a read
+// replica when one is configured.
+func (r *ConversationRepository) GetUserConversations(userID uuid.UUID, preview int) ([]model.Conversation, error) {
 	var conversations []model.Conversation
-	err := r.db.
+	db := dbresolver.Replica(r.db)
+	err := db.
 		Joins("JOIN conversation_members ON conversation_members.conversation_id = conversations.id").
 		Where("conversation_members.user_id = ? AND conversation_members.deleted_at IS NULL", userID).
-		Preload("Members.User").
 		Order("conversations.updated_at DESC").
 		Find(&conversations).Error
+	if err != nil {
+		return nil, err
+	}
+	return conversations, r.loadMemberPreviews(db, conversations, userID, preview)
+}
+
+// loadMemberPreviews sets the member count of each conversation and loads, in
+// join order, its first `preview` members plus the viewer's own membership
+func (r *ConversationRepository) loadMemberPreviews(db *gorm.DB, conversations []model.Conversation, viewerID uuid.UUID, preview int) error {
+	if len(conversations) == 0 {
+		return nil
+	}
+	index := make(map[uuid.UUID]int, len(conversations))
+	ids := make([]uuid.UUID, len(conversations))
+	for i := range conversations {
+		index[conversations[i].ID] = i
+		ids[i] = conversations[i].ID
+	}
+
+	var counts []struct {
+		ConversationID uuid.UUID
+		Count          int64
+	}
+	err := db.Model(&model.ConversationMember{}).
+		Select("conversation_id, COUNT(*) AS count").
+		Where("conversation_id IN ?", ids).
+		Group("conversation_id").
+		Scan(&counts).Error
+	if err != nil {
+		return err
+	}
+	for _, c := range counts {
+		conversations[index[c.ConversationID]].MemberCount = c.Count
+	}
+
+	ranked := db.Model(&model.ConversationMember{}).
+		Select("id, user_id, ROW_NUMBER() OVER (PARTITION BY conversation_id ORDER BY joined_at, id) AS position").
+		Where("conversation_id IN ?", ids)
+	var members []model.ConversationMember
+	err = db.
+		Preload("User").
+		Where("id IN (?)", db.Table("(?) AS ranked", ranked).Select("id").Where("position <= ? OR user_id = ?", preview, viewerID)).
+		Order("joined_at, id").
+		Find(&members).Error
+	if err != nil {
+		return err
+	}
+	for _, m := range members {
+		conv := &conversations[index[m.ConversationID]]
+		conv.Members = append(conv.Members, m)
+	}
+	return nil
+}
+
+// ListMembers returns a page of a conversation's members with their users, in
+// join order after the member with user ID `after`. A non-empty query keeps the
+// members whose name, display name or handle contains it.
+func (r *ConversationRepository) ListMembers(conversationID uuid.UUID, query string, after *uuid.UUID, limit int) ([]model.ConversationMember, error) {
+	members := []model.ConversationMember{}
+	q := dbresolver.Replica(r.db).
+		Preload("User").
+		Where("conversation_members.conversation_id = ?", conversationID)
+	if query != "" {
+		pattern := containsPattern(query)
+		q = q.Joins("JOIN users ON users.id = conversation_members.user_id").
+			Where("users.name ILIKE ? ESCAPE '\\' OR users.display_name ILIKE ? ESCAPE '\\' OR users.handle LIKE ? ESCAPE '\\'",
+				pattern, pattern, containsPattern(model.NormalizeHandle(query)))
+	}
+	if after != nil {
+		q = q.Where("(conversation_members.joined_at, conversation_members.id) > "+
+			"(SELECT joined_at, id FROM conversation_members WHERE conversation_id = ? AND user_id = ?)", conversationID, *after)
+	}
+	err := q.
+		Order("conversation_members.joined_at, conversation_members.id").
+		Limit(limit).
+		Find(&members).Error
+	return members, err
+}
+
+// MemberConversationIDs returns the IDs of the conversations the user is in
+func (r *ConversationRepository) MemberConversationIDs(userID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := dbresolver.Replica(r.db).Model(&model.ConversationMember{}).
+		Joins("JOIN conversations ON conversations.id = conversation_members.conversation_id AND conversations.deleted_at IS NULL").
+		Where("conversation_members.user_id = ?", userID).
+		Pluck("conversation_members.conversation_id", &ids).Error
+	return ids, err
+}
+
+// SearchGroups finds the groups the user is in whose name contains the
+// query, most recently active first
+func (r *ConversationRepository) SearchGroups(userID uuid.UUID, query string, limit int) ([]model.Conversation, error) {
+	conversations := []model.Conversation{}
+	err := dbresolver.Replica(r.db).
+		Where("type = ? AND name ILIKE ? ESCAPE '\\'", model.ConversationTypeGroup, containsPattern(query)).
+		Where("id IN (?)", r.db.Model(&model.ConversationMember{}).Select("conversation_id").Where("user_id = ?", userID)).
+		Order("updated_at DESC").
+		Limit(limit).
+		Find(&conversations).Error
 	return conversations, err
 }
 
@@ -75,24 +206,54 @@ func (r *ConversationRepository) RemoveMember(conversationID, userID uuid.UUID)
 		Delete(&model.ConversationMember{}).Error
 }
 
-// IsMember checks if a user is a member of a conversation
+// IsMember checks if a user is a member of a conversation. A deleted
+// conversation has no members.
 func (r *ConversationRepository) IsMember(conversationID, userID uuid.UUID) (bool, error) {
 	var count int64
 	err := r.db.Model(&model.ConversationMember{}).
-		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Joins("JOIN conversations ON conversations.id = conversation_members.conversation_id AND conversations.deleted_at IS NULL").
+		Where("conversation_members.conversation_id = ? AND conversation_members.user_id = ?", conversationID, userID).
 		Count(&count).Error
 	return count > 0, err
 }
 
-// GetMemberIDs returns all member user IDs for a conversation
+// ShareConversation reports whether two users are both current members of
+// some conversation that isn't deleted
+func (r *ConversationRepository) ShareConversation(userID1, userID2 uuid.UUID) (bool, error) {
+	var count int64
+	// A raw table skips the soft-delete scope: members who left or were removed
+	// keep their row with deleted_at set
+	err := r.db.Table("conversation_members cm1").
+		Joins("JOIN conversation_members cm2 ON cm2.conversation_id = cm1.conversation_id AND cm2.deleted_at IS NULL").
+		Joins("JOIN conversations ON conversations.id = cm1.conversation_id AND conversations.deleted_at IS NULL").
+		Where("cm1.user_id = ? AND cm1.deleted_at IS NULL AND cm2.user_id = ?", userID1, userID2).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetMemberIDs returns all member user IDs for a conversation; none for a
+// deleted conversation
 func (r *ConversationRepository) GetMemberIDs(conversationID uuid.UUID) ([]uuid.UUID, error) {
 	var memberIDs []uuid.UUID
 	err := r.db.Model(&model.ConversationMember{}).
-		Where("conversation_id = ?", conversationID).
-		Pluck("user_id", &memberIDs).Error
+		Joins("JOIN conversations ON conversations.id = conversation_members.conversation_id AND conversations.deleted_at IS NULL").
+		Where("conversation_members.conversation_id = ?", conversationID).
+		Pluck("conversation_members.user_id", &memberIDs).Error
 	return memberIDs, err
 }
 
+// GetMember returns a user's membership of a conversation, deleted or not
+func (r *ConversationRepository) GetMember(conversationID, userID uuid.UUID) (*model.ConversationMember, error) {
+	var member model.ConversationMember
+	err := r.db.
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		First(&member).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
 // TouchUpdatedAt bumps the updated_at timestamp (to sort by latest activity)
 func (r *ConversationRepository) TouchUpdatedAt(conversationID uuid.UUID) error {
 	return r.db.Model(&model.Conversation{}).
@@ -106,3 +267,266 @@ func (r *ConversationRepository) UpdateLastRead(conversationID, userID uuid.UUID
 		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
 		Update("last_read_at", gorm.Expr("NOW()")).Error
 }
+
+// SetFrozen freezes or unfreezes a conversation
+func (r *ConversationRepository) SetFrozen(id uuid.UUID, frozen bool) error {
+	return r.db.Model(&model.Conversation{}).Where("id = ?", id).Update("frozen", frozen).Error
+}
+
+// GetMemberPermissions returns what decides a member's permissions: the
+// conversation's type and freeze, the member's role and the role's stored
+// permissions. The conversation may be deleted.
+func (r *ConversationRepository) GetMemberPermissions(conversationID, userID uuid.UUID) (*model.MemberPermissions, error) {
+	var permissions []model.MemberPermissions
+	err := r.db.Model(&model.ConversationMember{}).
+		Select("conversations.type AS conversation_type, conversations.frozen, conversation_members.role, conversation_roles.permissions AS role_permissions").
+		Joins("JOIN conversations ON conversations.id = conversation_members.conversation_id").
+		Joins("LEFT JOIN conversation_roles ON conversation_roles.conversation_id = conversation_members.conversation_id AND conversation_roles.name = conversation_members.role").
+		Where("conversation_members.conversation_id = ? AND conversation_members.user_id = ?", conversationID, userID).
+		Limit(1).
+		Scan(&permissions).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(permissions) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &permissions[0], nil
+}
+
+// SetMemberRole gives a member another role
+func (r *ConversationRepository) SetMemberRole(conversationID, userID uuid.UUID, role model.MemberRole) error {
+	result := r.db.Model(&model.ConversationMember{}).
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CountMembersWithRole returns how many members have the role
+func (r *ConversationRepository) CountMembersWithRole(conversationID uuid.UUID, role model.MemberRole) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.ConversationMember{}).
+		Where("conversation_id = ? AND role = ?", conversationID, role).
+		Count(&count).Error
+	return count, err
+}
+
+// ListRoles returns the conversation's stored roles, oldest first
+func (r *ConversationRepository) ListRoles(conversationID uuid.UUID) ([]model.ConversationRole, error) {
+	roles := []model.ConversationRole{}
+	err := r.db.Where("conversation_id = ?", conversationID).Order("created_at").Find(&roles).Error
+	return roles, err
+}
+
+// FindRole finds a stored role of the conversation
+func (r *ConversationRepository) FindRole(conversationID uuid.UUID, name model.MemberRole) (*model.ConversationRole, error) {
+	var role model.ConversationRole
+	err := r.db.Where("conversation_id = ? AND name = ?", conversationID, name).First(&role).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// SaveRole creates a role or replaces its permissions
+func (r *ConversationRepository) SaveRole(role *model.ConversationRole) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "conversation_id"}, {Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"permissions", "updated_at"}),
+	}).Create(role).Error
+}
+
+// DeleteRole deletes a stored role; its members go back to the member role
+func (r *ConversationRepository) DeleteRole(conversationID uuid.UUID, name model.MemberRole) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("conversation_id = ? AND name = ?", conversationID, name).Delete(&model.ConversationRole{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Unscoped().Model(&model.ConversationMember{}).
+			Where("conversation_id = ? AND role = ?", conversationID, name).
+			Update("role", model.MemberRoleMember).Error
+	})
+}
+
+// ClearHistory hides the conversation's messages sent until now from the
+// member, who has read them all as well
+func (r *ConversationRepository) ClearHistory(conversationID, userID uuid.UUID, at time.Time) error {
+	return r.db.Model(&model.ConversationMember{}).
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Updates(map[string]interface{}{"cleared_before": at, "last_read_at": at}).Error
+}
+
+// MarkAllRead sets last_read_at on the user's memberships that have unread
+// messages, in the given conversations or (empty) all of them, and returns
+// the memberships it changed
+func (r *ConversationRepository) MarkAllRead(userID uuid.UUID, conversationIDs []uuid.UUID) ([]model.ConversationMember, error) {
+	unread := r.db.Table("messages").Select("1").
+		Where("messages.conversation_id = conversation_members.conversation_id AND messages.sender_id != ? AND messages.deleted_at IS NULL", userID).
+		Where("messages.created_at > COALESCE(conversation_members.last_read_at, '0001-01-01')")
+
+	members := []model.ConversationMember{}
+	query := r.db.Model(&members).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "conversation_id"}, {Name: "last_read_at"}}}).
+		Where("user_id = ? AND EXISTS (?)", userID, unread)
+	if len(conversationIDs) > 0 {
+		query = query.Where("conversation_id IN ?", conversationIDs)
+	}
+	err := query.Update("last_read_at", gorm.Expr("NOW()")).Error
+	return members, err
+}
+
+// ListProvisioned returns a page of directory-managed group conversations (with
+// members) in creation order and the total number matching the filter
+func (r *ConversationRepository) ListProvisioned(filter DirectoryFilter, offset, limit int) ([]model.Conversation, int64, error) {
+	query := r.db.Model(&model.Conversation{}).Where("provisioned = ?", true)
+	if filter.ID != nil {
+		query = query.Where("id = ?", *filter.ID)
+	}
+	if filter.Name != "" {
+		query = query.Where("name = ?", filter.Name)
+	}
+	if filter.ExternalID != "" {
+		query = query.Where("external_id = ?", filter.ExternalID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	conversations := []model.Conversation{}
+	err := query.
+		Preload("Members.User").
+		Order("created_at, id").
+		Offset(offset).
+		Limit(limit).
+		Find(&conversations).Error
+	return conversations, total, err
+}
+
+// FindProvisioned finds a directory-managed group conversation with members
+func (r *ConversationRepository) FindProvisioned(id uuid.UUID) (*model.Conversation, error) {
+	var conv model.Conversation
+	err := r.db.
+		Preload("Members.User").
+		Where("id = ? AND provisioned = ?", id, true).
+		First(&conv).Error
+	if err != nil {
+		return nil, err
+	}
+	return &conv, nil
+}
+
+// UpdateDirectoryAttributes sets the name and external ID of a directory-managed group
+func (r *ConversationRepository) UpdateDirectoryAttributes(id uuid.UUID, name string, externalID *string) error {
+	return r.db.Model(&model.Conversation{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"name":        name,
+		"external_id": externalID,
+	}).Error
+}
+
+// RestoreOrAddMember adds a user to a conversation, bringing back a member who left
+func (r *ConversationRepository) RestoreOrAddMember(conversationID, userID uuid.UUID, role model.MemberRole) error {
+	return restoreOrAddMember(r.db, conversationID, userID, role)
+}
+
+func restoreOrAddMember(db *gorm.DB, conversationID, userID uuid.UUID, role model.MemberRole) error {
+	member := model.ConversationMember{
+		ConversationID: conversationID,
+		UserID:         userID,
+		Role:           role,
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "conversation_id"}, {Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"deleted_at": nil,
+			"joined_at":  gorm.Expr("NOW()"),
+		}),
+	}).Create(&member).Error
+}
+
+// AddMembersWithOutbox adds users to a conversation like RestoreOrAddMember,
+// saving the outbox events that announce them in the same transaction
+func (r *ConversationRepository) AddMembersWithOutbox(conversationID uuid.UUID, userIDs []uuid.UUID, role model.MemberRole, events []model.OutboxEvent) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, userID := range userIDs {
+			if err := restoreOrAddMember(tx, conversationID, userID, role); err != nil {
+				return err
+			}
+		}
+		if len(events) > 0 {
+			return tx.Create(&events).Error
+		}
+		return nil
+	})
+}
+
+// Delete soft-deletes a conversation
+func (r *ConversationRepository) Delete(id uuid.UUID) error {
+	return r.db.Where("id = ?", id).Delete(&model.Conversation{}).Error
+}
+
+// FindDeleted finds a soft-deleted conversation by ID
+func (r *ConversationRepository) FindDeleted(id uuid.UUID) (*model.Conversation, error) {
+	var conv model.Conversation
+	err := r.db.Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		First(&conv).Error
+	if err != nil {
+		return nil, err
+	}
+	return &conv, nil
+}
+
+// Restore brings back a conversation deleted after the cutoff. Returns false
+// if it isn't deleted or was deleted before the cutoff.
+func (r *ConversationRepository) Restore(id uuid.UUID, deletedAfter time.Time) (bool, error) {
+	result := r.db.Unscoped().Model(&model.Conversation{}).
+		Where("id = ? AND deleted_at > ?", id, deletedAfter).
+		Updates(map[string]interface{}{"deleted_at": nil, "updated_at": gorm.Expr("NOW()")})
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindDeletedBefore returns the IDs of conversations deleted before the
+// cutoff, except those under legal hold
+func (r *ConversationRepository) FindDeletedBefore(before time.Time, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Unscoped().Model(&model.Conversation{}).
+		Where("deleted_at < ?", before).
+		Where("id NOT IN ("+heldConversations+")").
+		Order("deleted_at").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// Purge permanently deletes a soft-deleted conversation. Its members, messages,
+// attachments and receipts go with it (ON DELETE CASCADE), and the
+// attachments' references to their file blobs are dropped so the blob purge
+// can remove the files.
+func (r *ConversationRepository) Purge(id uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			UPDATE file_blobs SET ref_count = GREATEST(file_blobs.ref_count - refs.n, 0), updated_at = NOW()
+			FROM (
+				SELECT a.blob_hash, COUNT(*) AS n FROM message_attachments a
+				JOIN messages m ON m.id = a.message_id
+				WHERE m.conversation_id = ? AND a.blob_hash IS NOT NULL AND a.deleted_at IS NULL
+				GROUP BY a.blob_hash
+			) refs
+			WHERE file_blobs.hash = refs.blob_hash`, id).Error
+		if err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).Delete(&model.Conversation{}).Error
+	})
+}
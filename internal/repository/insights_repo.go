@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// InsightsRepository handles database operations for the daily rollups of
+// group activity
+type InsightsRepository struct {
+	db *gorm.DB
+}
+
+func NewInsightsRepository(db *gorm.DB) *InsightsRepository {
+	return &InsightsRepository{db: db}
+}
+
+// RollUp computes every group's activity on a UTC day from its messages,
+// deleted ones included, and members, replacing earlier rollups of the day
+func (r *InsightsRepository) RollUp(day time.Time) error {
+	next := day.AddDate(0, 0, 1)
+	groups := r.db.Model(&model.Conversation{}).Select("id").Where("type = ?", model.ConversationTypeGroup)
+
+	var hourly []struct {
+		ConversationID uuid.UUID
+		Hour           int
+		Messages       int64
+	}
+	err := r.db.Unscoped().Model(&model.Message{}).
+		Select("conversation_id, EXTRACT(HOUR FROM created_at AT TIME ZONE 'UTC')::int AS hour, COUNT(*) AS messages").
+		Where("conversation_id IN (?) AND created_at >= ? AND created_at < ?", groups, day, next).
+		Group("conversation_id, hour").
+		Scan(&hourly).Error
+	if err != nil {
+		return err
+	}
+
+	var members []model.ConversationMemberDailyStat
+	err = r.db.Unscoped().Model(&model.Message{}).
+		Select("conversation_id, ? AS day, sender_id AS user_id, COUNT(*) AS messages", day).
+		Where("conversation_id IN (?) AND created_at >= ? AND created_at < ?", groups, day, next).
+		Group("conversation_id, sender_id").
+		Scan(&members).Error
+	if err != nil {
+		return err
+	}
+
+	// Members who since left still count as joins; a rejoin replaces the
+	// earlier join and leave
+	var changes []struct {
+		ConversationID uuid.UUID
+		Joins          int64
+		Leaves         int64
+	}
+	err = r.db.Unscoped().Model(&model.ConversationMember{}).
+		Select(`conversation_id,
+			COUNT(*) FILTER (WHERE joined_at >= @day AND joined_at < @next) AS joins,
+			COUNT(*) FILTER (WHERE deleted_at >= @day AND deleted_at < @next) AS leaves`,
+			map[string]interface{}{"day": day, "next": next}).
+		Where("conversation_id IN (?)", groups).
+		Where("(joined_at >= ? AND joined_at < ?) OR (deleted_at >= ? AND deleted_at < ?)", day, next, day, next).
+		Group("conversation_id").
+		Scan(&changes).Error
+	if err != nil {
+		return err
+	}
+
+	stats := make(map[uuid.UUID]*model.ConversationDailyStat)
+	stat := func(convID uuid.UUID) *model.ConversationDailyStat {
+		if s, ok := stats[convID]; ok {
+			return s
+		}
+		s := &model.ConversationDailyStat{ConversationID: convID, Day: day, Hours: make([]int64, 24)}
+		stats[convID] = s
+		return s
+	}
+	for _, h := range hourly {
+		s := stat(h.ConversationID)
+		s.Messages += h.Messages
+		s.Hours[h.Hour] = h.Messages
+	}
+	for _, c := range changes {
+		s := stat(c.ConversationID)
+		s.Joins, s.Leaves = c.Joins, c.Leaves
+	}
+	rows := make([]model.ConversationDailyStat, 0, len(stats))
+	for _, s := range stats {
+		rows = append(rows, *s)
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if len(rows) > 0 {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "conversation_id"}, {Name: "day"}},
+				DoUpdates: clause.AssignmentColumns([]string{"messages", "joins", "leaves", "hours"}),
+			}).CreateInBatches(&rows, 500).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(members) > 0 {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "conversation_id"}, {Name: "day"}, {Name: "user_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"messages"}),
+			}).CreateInBatches(&members, 500).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// LatestDay returns the last day rolled up, or nil before the first rollup
+func (r *InsightsRepository) LatestDay() (*time.Time, error) {
+	var day sql.NullTime
+	if err := r.db.Model(&model.ConversationDailyStat{}).Select("MAX(day)").Row().Scan(&day); err != nil {
+		return nil, err
+	}
+	if !day.Valid {
+		return nil, nil
+	}
+	return &day.Time, nil
+}
+
+// ListDays returns a group's rollups from one day to another, inclusive
+func (r *InsightsRepository) ListDays(convID uuid.UUID, from, to time.Time) ([]model.ConversationDailyStat, error) {
+	stats := []model.ConversationDailyStat{}
+	err := r.db.Where("conversation_id = ? AND day BETWEEN ? AND ?", convID, from, to).
+		Order("day").Find(&stats).Error
+	return stats, err
+}
+
+// TopMembers returns the members who sent the most messages to a group from
+// one day to another, inclusive, most active first
+func (r *InsightsRepository) TopMembers(convID uuid.UUID, from, to time.Time, limit int) ([]model.ConversationMemberDailyStat, error) {
+	stats := []model.ConversationMemberDailyStat{}
+	err := r.db.Model(&model.ConversationMemberDailyStat{}).
+		Select("user_id, SUM(messages) AS messages").
+		Where("conversation_id = ? AND day BETWEEN ? AND ?", convID, from, to).
+		Group("user_id").
+		Order("messages DESC, user_id").
+		Limit(limit).
+		Scan(&stats).Error
+	return stats, err
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"gorm.io/gorm"
+)
+
+// InvitationRepository handles database operations for invitation codes
+type InvitationRepository struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) *InvitationRepository {
+	return &InvitationRepository{db: db}
+}
+
+// Create stores a new invitation
+func (r *InvitationRepository) Create(invitation *model.Invitation) error {
+	return r.db.Create(invitation).Error
+}
+
+// List returns every invitation, newest first
+func (r *InvitationRepository) List() ([]model.Invitation, error) {
+	invitations := []model.Invitation{}
+	err := r.db.Order("created_at DESC").Find(&invitations).Error
+	return invitations, err
+}
+
+// Delete revokes an invitation
+func (r *InvitationRepository) Delete(id uuid.UUID) error {
+	result := r.db.Where("id = ?", id).Delete(&model.Invitation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Redeem uses up one use of a code for the email. It reports false when the
+// code doesn't exist, expired, is used up or belongs to another address.
+func (r *InvitationRepository) Redeem(code, email string, now time.Time) (bool, error) {
+	result := r.db.Model(&model.Invitation{}).
+		Where("code = ?", code).
+		Where("max_uses = 0 OR uses < max_uses").
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Where("email IS NULL OR email = '' OR LOWER(email) = LOWER(?)", email).
+		UpdateColumn("uses", gorm.Expr("uses + 1"))
+	return result.RowsAffected > 0, result.Error
+}
+
+// Release gives back a use taken by Redeem, when the signup failed afterwards
+func (r *InvitationRepository) Release(code string) error {
+	return r.db.Model(&model.Invitation{}).
+		Where("code = ? AND uses > 0", code).
+		UpdateColumn("uses", gorm.Expr("uses - 1")).Error
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// heldConversations selects the IDs of conversations under legal hold, on
+// their own or through a held current or former member
+const heldConversations = `SELECT legal_holds.conversation_id FROM legal_holds WHERE legal_holds.conversation_id IS NOT NULL
+	UNION SELECT conversation_members.conversation_id FROM conversation_members JOIN legal_holds ON legal_holds.user_id = conversation_members.user_id`
+
+// LegalHoldRepository handles database operations for legal holds
+type LegalHoldRepository struct {
+	db *gorm.DB
+}
+
+func NewLegalHoldRepository(db *gorm.DB) *LegalHoldRepository {
+	return &LegalHoldRepository{db: db}
+}
+
+// Create places a hold
+func (r *LegalHoldRepository) Create(hold *model.LegalHold) error {
+	return r.db.Create(hold).Error
+}
+
+// List returns the holds in place, newest first
+func (r *LegalHoldRepository) List() ([]model.LegalHold, error) {
+	holds := []model.LegalHold{}
+	err := r.db.Order("created_at DESC").Find(&holds).Error
+	return holds, err
+}
+
+// CoversUser reports whether a legal hold covers any of a user's messages: the
+// user is held, or belongs or belonged to a held conversation
+func (r *LegalHoldRepository) CoversUser(userID uuid.UUID) (bool, error) {
+	var held bool
+	err := r.db.Raw(`SELECT EXISTS (SELECT 1 FROM legal_holds WHERE legal_holds.user_id = @user)
+		OR EXISTS (SELECT 1 FROM conversation_members WHERE conversation_members.user_id = @user
+			AND conversation_members.conversation_id IN (`+heldConversations+`))`,
+		map[string]interface{}{"user": userID},
+	).Scan(&held).Error
+	return held, err
+}
+
+// Delete releases a hold and returns it
+func (r *LegalHoldRepository) Delete(id uuid.UUID) (*model.LegalHold, error) {
+	var hold model.LegalHold
+	result := r.db.Clauses(clause.Returning{}).Where("id = ?", id).Delete(&hold)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &hold, nil
+}
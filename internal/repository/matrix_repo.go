@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"gorm.io/gorm"
+)
+
+// MatrixRepository handles database operations for Matrix bridge room links
+type MatrixRepository struct {
+	db *gorm.DB
+}
+
+func NewMatrixRepository(db *gorm.DB) *MatrixRepository {
+	return &MatrixRepository{db: db}
+}
+
+// Link bridges a conversation to a room, replacing the conversation's previous link
+func (r *MatrixRepository) Link(link *model.MatrixRoomLink) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("conversation_id = ?", link.ConversationID).Delete(&model.MatrixRoomLink{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(link).Error
+	})
+}
+
+// Unlink removes a conversation's link
+func (r *MatrixRepository) Unlink(conversationID uuid.UUID) error {
+	result := r.db.Where("conversation_id = ?", conversationID).Delete(&model.MatrixRoomLink{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindByConversation returns the link of a conversation
+func (r *MatrixRepository) FindByConversation(conversationID uuid.UUID) (*model.MatrixRoomLink, error) {
+	var link model.MatrixRoomLink
+	if err := r.db.Where("conversation_id = ?", conversationID).First(&link).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// FindByRoom returns the link of a room
+func (r *MatrixRepository) FindByRoom(roomID string) (*model.MatrixRoomLink, error) {
+	var link model.MatrixRoomLink
+	if err := r.db.Where("room_id = ?", roomID).First(&link).Error; err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// List returns every link, oldest first
+func (r *MatrixRepository) List() ([]model.MatrixRoomLink, error) {
+	links := []model.MatrixRoomLink{}
+	err := r.db.Order("created_at").Find(&links).Error
+	return links, err
+}
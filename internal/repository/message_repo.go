@@ -1,9 +1,13 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/pkg/dbresolver"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // MessageRepository handles database operations for Message
@@ -20,6 +24,34 @@ func (r *MessageRepository) Create(msg *model.Message) error {
 	return r.db.Create(msg).Error
 }
 
+// CreateWithOutbox inserts a message, its attachments and the outbox events
+// announcing it in one transaction
+func (r *MessageRepository) CreateWithOutbox(msg *model.Message, attachments []model.MessageAttachment, events []model.OutboxEvent) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(msg).Error; err != nil {
+			return err
+		}
+		if len(attachments) > 0 {
+			if err := tx.Create(&attachments).Error; err != nil {
+				return err
+			}
+		}
+		if len(events) > 0 {
+			return tx.Create(&events).Error
+		}
+		return nil
+	})
+}
+
+// CreateImported inserts a batch of imported messages with their attachments.
+// Imported history isn't announced, so no outbox events are written.
+func (r *MessageRepository) CreateImported(messages []model.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	return r.db.Create(&messages).Error
+}
+
 // FindByID finds a message by ID
 func (r *MessageRepository) FindByID(id uuid.UUID) (*model.Message, error) {
 	var msg model.Message
@@ -34,13 +66,23 @@ func (r *MessageRepository) FindByID(id uuid.UUID) (*model.Message, error) {
 	return &msg, nil
 }
 
+// notClearedBy hides the messages a member cleared from their own history
+func notClearedBy(viewerID uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(`messages.created_at > COALESCE((SELECT cm.cleared_before FROM conversation_members cm
+			WHERE cm.conversation_id = messages.conversation_id AND cm.user_id = ?), '0001-01-01')`, viewerID)
+	}
+}
+
 // GetConversationMessages returns paginated messages for a conversation (cursor-based)
-func (r *MessageRepository) GetConversationMessages(conversationID uuid.UUID, before *uuid.UUID, limit int) ([]model.Message, error) {
+// as the viewer sees them, served from a read replica when one is configured
+func (r *MessageRepository) GetConversationMessages(conversationID, viewerID uuid.UUID, before *uuid.UUID, limit int) ([]model.Message, error) {
 	messages := []model.Message{}
-	query := r.db.
+	query := dbresolver.Replica(r.db).
 		Preload("Sender").
 		Preload("Attachments").
 		Where("conversation_id = ?", conversationID).
+		Scopes(notClearedBy(viewerID)).
 		Order("created_at DESC").
 		Limit(limit)
 
@@ -57,12 +99,271 @@ func (r *MessageRepository) GetConversationMessages(conversationID uuid.UUID, be
 	return messages, err
 }
 
-// GetLastMessage returns the most recent message in a conversation
-func (r *MessageRepository) GetLastMessage(conversationID uuid.UUID) (*model.Message, error) {
+// GetMessagesOlder returns up to limit messages of a conversation the viewer
+// sees that were sent before `until`, newest first
+func (r *MessageRepository) GetMessagesOlder(conversationID, viewerID uuid.UUID, until time.Time, limit int) ([]model.Message, error) {
+	messages := []model.Message{}
+	err := dbresolver.Replica(r.db).
+		Preload("Sender").
+		Preload("Attachments").
+		Where("conversation_id = ? AND created_at < ?", conversationID, until).
+		Scopes(notClearedBy(viewerID)).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
+// GetMessagesNewer returns up to limit messages of a conversation the viewer
+// sees that were sent after `since` (or at it, when inclusive), oldest first
+func (r *MessageRepository) GetMessagesNewer(conversationID, viewerID uuid.UUID, since time.Time, inclusive bool, limit int) ([]model.Message, error) {
+	op := ">"
+	if inclusive {
+		op = ">="
+	}
+	messages := []model.Message{}
+	err := dbresolver.Replica(r.db).
+		Preload("Sender").
+		Preload("Attachments").
+		Where("conversation_id = ? AND created_at "+op+" ?", conversationID, since).
+		Scopes(notClearedBy(viewerID)).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
+// FindCreatedAt returns when a message of the conversation the viewer sees was sent
+func (r *MessageRepository) FindCreatedAt(conversationID, viewerID, messageID uuid.UUID) (time.Time, error) {
+	var msg model.Message
+	err := r.db.Select("created_at").
+		Where("id = ? AND conversation_id = ?", messageID, conversationID).
+		Scopes(notClearedBy(viewerID)).
+		First(&msg).Error
+	return msg.CreatedAt, err
+}
+
+// GetMessagesAfter returns a batch of a conversation's messages the viewer sees
+// in chronological order, starting after the given message (nil = from the
+// beginning). Used to walk a whole conversation for exports.
+func (r *MessageRepository) GetMessagesAfter(conversationID, viewerID uuid.UUID, after *model.Message, limit int) ([]model.Message, error) {
+	messages := []model.Message{}
+	query := dbresolver.Replica(r.db).
+		Preload("Sender").
+		Preload("Attachments").
+		Where("conversation_id = ?", conversationID).
+		Scopes(notClearedBy(viewerID)).
+		Order("created_at ASC, id ASC").
+		Limit(limit)
+	if after != nil {
+		query = query.Where("(created_at, id) > (?, ?)", after.CreatedAt, after.ID)
+	}
+	err := query.Find(&messages).Error
+	return messages, err
+}
+
+// memberConversations selects the conversations the user is in
+func memberConversations(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+	return db.Table("conversation_members AS cm").
+		Select("cm.conversation_id").
+		Joins("JOIN conversations AS c ON c.id = cm.conversation_id AND c.deleted_at IS NULL").
+		Where("cm.user_id = ? AND cm.deleted_at IS NULL", userID)
+}
+
+// MessageSearchHit is a message matching a search, with its text rank
+type MessageSearchHit struct {
+	Message model.Message
+	Rank    float64 // 0-1
+}
+
+// Search finds the messages the user sees whose content matches a web-style
+// query ("quoted phrases", -excluded words, or), best match first, optionally
+// in one conversation. Served from a read replica when one is configured.
+func (r *MessageRepository) Search(userID uuid.UUID, query string, conversationID *uuid.UUID, limit int) ([]MessageSearchHit, error) {
+	db := dbresolver.Replica(r.db)
+	var ranked []struct {
+		ID   uuid.UUID
+		Rank float64
+	}
+	q := db.Model(&model.Message{}).
+		Select("messages.id, ts_rank(to_tsvector('simple', content), websearch_to_tsquery('simple', ?), 32) AS rank", query).
+		Where("to_tsvector('simple', content) @@ websearch_to_tsquery('simple', ?)", query).
+		Where("conversation_id IN (?)", memberConversations(db, userID)).
+		Scopes(notClearedBy(userID))
+	if conversationID != nil {
+		q = q.Where("conversation_id = ?", *conversationID)
+	}
+	if err := q.Order("rank DESC, created_at DESC").Limit(limit).Scan(&ranked).Error; err != nil {
+		return nil, err
+	}
+	if len(ranked) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uuid.UUID, len(ranked))
+	for i, hit := range ranked {
+		ids[i] = hit.ID
+	}
+	byID, err := r.FindVisible(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]MessageSearchHit, 0, len(ranked))
+	for _, hit := range ranked {
+		if msg, ok := byID[hit.ID]; ok {
+			hits = append(hits, MessageSearchHit{Message: msg, Rank: hit.Rank})
+		}
+	}
+	return hits, nil
+}
+
+// FindVisible loads those of the messages the user sees, with their senders
+// and attachments, by ID
+func (r *MessageRepository) FindVisible(userID uuid.UUID, ids []uuid.UUID) (map[uuid.UUID]model.Message, error) {
+	db := dbresolver.Replica(r.db)
+	var messages []model.Message
+	err := db.Preload("Sender").Preload("Attachments").
+		Where("id IN ?", ids).
+		Where("conversation_id IN (?)", memberConversations(db, userID)).
+		Scopes(notClearedBy(userID)).
+		Find(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]model.Message, len(messages))
+	for _, msg := range messages {
+		byID[msg.ID] = msg
+	}
+	return byID, nil
+}
+
+// ListAll returns a batch of every message in chronological order, starting
+// after the given message (nil = from the beginning). Used to rebuild the
+// search index.
+func (r *MessageRepository) ListAll(after *model.Message, limit int) ([]model.Message, error) {
+	messages := []model.Message{}
+	query := r.db.Order("created_at ASC, id ASC").Limit(limit)
+	if after != nil {
+		query = query.Where("(created_at, id) > (?, ?)", after.CreatedAt, after.ID)
+	}
+	err := query.Find(&messages).Error
+	return messages, err
+}
+
+// SearchFiles finds the attachments the user sees whose file name contains
+// the query, newest first, optionally in one conversation
+func (r *MessageRepository) SearchFiles(userID uuid.UUID, query string, conversationID *uuid.UUID, limit int) ([]model.FileSearchHit, error) {
+	db := dbresolver.Replica(r.db)
+	hits := []model.FileSearchHit{}
+	q := db.Model(&model.MessageAttachment{}).
+		Select(`message_attachments.id AS attachment_id, message_attachments.message_id, messages.conversation_id,
+			messages.sender_id, message_attachments.type, message_attachments.url, message_attachments.file_name,
+			message_attachments.file_size, message_attachments.mime_type, messages.created_at`).
+		Joins("JOIN messages ON messages.id = message_attachments.message_id AND messages.deleted_at IS NULL").
+		Where("message_attachments.file_name ILIKE ? ESCAPE '\\'", containsPattern(query)).
+		Where("messages.conversation_id IN (?)", memberConversations(db, userID)).
+		Scopes(notClearedBy(userID))
+	if conversationID != nil {
+		q = q.Where("messages.conversation_id = ?", *conversationID)
+	}
+	err := q.Order("messages.created_at DESC").Limit(limit).Scan(&hits).Error
+	return hits, err
+}
+
+// ComplianceFilter selects messages for a compliance export; the zero value
+// matches everything
+type ComplianceFilter struct {
+	UserID         *uuid.UUID // sent by the user or in conversations they belong or belonged to
+	ConversationID *uuid.UUID
+	From           *time.Time // sent at or after
+	To             *time.Time // sent before
+}
+
+// ListForCompliance returns a batch of the messages matching the filter,
+// deleted ones included, with their senders and attachments, in chronological
+// order starting after the given message (nil = from the beginning)
+func (r *MessageRepository) ListForCompliance(filter ComplianceFilter, after *model.Message, limit int) ([]model.Message, error) {
+	messages := []model.Message{}
+	unscoped := func(db *gorm.DB) *gorm.DB { return db.Unscoped() }
+	query := dbresolver.Replica(r.db).Unscoped().
+		Preload("Sender", unscoped).
+		Preload("Attachments", unscoped).
+		Order("created_at ASC, id ASC").
+		Limit(limit)
+	if filter.UserID != nil {
+		query = query.Where("(sender_id = ? OR conversation_id IN (SELECT conversation_id FROM conversation_members WHERE user_id = ?))", *filter.UserID, *filter.UserID)
+	}
+	if filter.ConversationID != nil {
+		query = query.Where("conversation_id = ?", *filter.ConversationID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if after != nil {
+		query = query.Where("(created_at, id) > (?, ?)", after.CreatedAt, after.ID)
+	}
+	err := query.Find(&messages).Error
+	return messages, err
+}
+
+// RecordDelivered saves a delivery receipt; a message is delivered to a user once
+func (r *MessageRepository) RecordDelivered(messageID, userID uuid.UUID) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.DeliveryReceipt{
+		MessageID:   messageID,
+		UserID:      userID,
+		DeliveredAt: time.Now(),
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+// RecordRead saves a read receipt; a message is read by a user once
+func (r *MessageRepository) RecordRead(messageID, userID uuid.UUID) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.ReadReceipt{
+		MessageID: messageID,
+		UserID:    userID,
+		ReadAt:    time.Now(),
+	}).Error
+}
+
+// RecipientReceipts is a member's delivery and read state for a message
+type RecipientReceipts struct {
+	UserID      uuid.UUID
+	DeliveredAt *time.Time // earliest delivery receipt for the message or a later one
+	ReadAt      *time.Time // earliest read receipt for the message or a later one
+	LastReadAt  *time.Time // when the member last marked the conversation read
+}
+
+// GetRecipientReceipts returns the receipts of the members a message was sent
+// to: everyone but the sender who had joined the conversation by then. Clients
+// acknowledge the newest message they got or read, so a receipt for a later
+// message counts for this one too.
+func (r *MessageRepository) GetRecipientReceipts(msg *model.Message) ([]RecipientReceipts, error) {
+	receipts := []RecipientReceipts{}
+	err := dbresolver.Replica(r.db).Raw(`
+		SELECT cm.user_id, cm.last_read_at,
+			(SELECT MIN(dr.delivered_at) FROM delivery_receipts dr JOIN messages m ON m.id = dr.message_id
+				WHERE dr.user_id = cm.user_id AND m.conversation_id = @conversation AND m.created_at >= @sent_at) AS delivered_at,
+			(SELECT MIN(rr.read_at) FROM read_receipts rr JOIN messages m ON m.id = rr.message_id
+				WHERE rr.user_id = cm.user_id AND m.conversation_id = @conversation AND m.created_at >= @sent_at) AS read_at
+		FROM conversation_members cm
+		WHERE cm.conversation_id = @conversation AND cm.user_id != @sender
+			AND cm.deleted_at IS NULL AND cm.joined_at <= @sent_at
+		ORDER BY cm.joined_at, cm.id`,
+		map[string]interface{}{"conversation": msg.ConversationID, "sender": msg.SenderID, "sent_at": msg.CreatedAt},
+	).Scan(&receipts).Error
+	return receipts, err
+}
+
+// GetLastMessage returns the most recent message in a conversation the viewer sees
+func (r *MessageRepository) GetLastMessage(conversationID, viewerID uuid.UUID) (*model.Message, error) {
 	var msg model.Message
 	err := r.db.
 		Preload("Sender").
 		Where("conversation_id = ?", conversationID).
+		Scopes(notClearedBy(viewerID)).
 		Order("created_at DESC").
 		First(&msg).Error
 	if err != nil {
@@ -107,3 +408,18 @@ func (r *MessageRepository) CountUnread(conversationID, userID uuid.UUID) (int64
 func (r *MessageRepository) CreateAttachment(att *model.MessageAttachment) error {
 	return r.db.Create(att).Error
 }
+
+// FindAttachmentByID finds a message attachment by ID
+func (r *MessageRepository) FindAttachmentByID(id uuid.UUID) (*model.MessageAttachment, error) {
+	var att model.MessageAttachment
+	err := r.db.Where("id = ?", id).First(&att).Error
+	if err != nil {
+		return nil, err
+	}
+	return &att, nil
+}
+
+// UpdateAttachment applies partial updates to a message attachment
+func (r *MessageRepository) UpdateAttachment(id uuid.UUID, updates map[string]interface{}) error {
+	return r.db.Model(&model.MessageAttachment{}).Where("id = ?", id).Updates(updates).Error
+}
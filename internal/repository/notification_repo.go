@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"gorm.io/gorm"
+)
+
+// NotificationRepository handles database operations for notification center entries
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// Create inserts notifications (in batches for broadcasts)
+func (r *NotificationRepository) Create(notifications []model.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	return r.db.CreateInBatches(notifications, 500).Error
+}
+
+// ListByUser returns a user's notifications, newest first, before an optional cursor
+func (r *NotificationRepository) ListByUser(userID uuid.UUID, before *time.Time, unreadOnly bool, limit int) ([]model.Notification, error) {
+	var notifications []model.Notification
+	query := r.db.Where("user_id = ?", userID)
+	if before != nil {
+		query = query.Where("created_at < ?", *before)
+	}
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	err := query.Order("created_at DESC").Limit(limit).Find(&notifications).Error
+	return notifications, err
+}
+
+// CountUnread counts a user's unread notifications
+func (r *NotificationRepository) CountUnread(userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkRead marks one of the user's notifications as read
+func (r *NotificationRepository) MarkRead(id, userID uuid.UUID) error {
+	result := r.db.Model(&model.Notification{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("read_at", gorm.Expr("COALESCE(read_at, NOW())"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// MarkAllRead marks all of a user's notifications as read
+func (r *NotificationRepository) MarkAllRead(userID uuid.UUID) error {
+	return r.db.Model(&model.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", gorm.Expr("NOW()")).Error
+}
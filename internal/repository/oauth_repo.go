@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OAuthRepository handles database operations for third-party apps, the
+// access users granted them and their refresh tokens
+type OAuthRepository struct {
+	db *gorm.DB
+}
+
+func NewOAuthRepository(db *gorm.DB) *OAuthRepository {
+	return &OAuthRepository{db: db}
+}
+
+// CreateClient registers an app
+func (r *OAuthRepository) CreateClient(client *model.OAuthClient) error {
+	return r.db.Create(client).Error
+}
+
+// FindClient finds an app by client ID
+func (r *OAuthRepository) FindClient(id string) (*model.OAuthClient, error) {
+	var client model.OAuthClient
+	err := r.db.Where("id = ?", id).First(&client).Error
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// ListClients returns the apps a user registered, newest first
+func (r *OAuthRepository) ListClients(ownerID uuid.UUID) ([]model.OAuthClient, error) {
+	clients := []model.OAuthClient{}
+	err := r.db.Where("owner_id = ?", ownerID).Order("created_at DESC").Find(&clients).Error
+	return clients, err
+}
+
+// DeleteClient deletes an app of the owner, with its grants and refresh tokens
+func (r *OAuthRepository) DeleteClient(id string, ownerID uuid.UUID) error {
+	result := r.db.Where("id = ? AND owner_id = ?", id, ownerID).Delete(&model.OAuthClient{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindGrant returns the scopes the user gave the app
+func (r *OAuthRepository) FindGrant(userID uuid.UUID, clientID string) (*model.OAuthGrant, error) {
+	var grant model.OAuthGrant
+	err := r.db.Where("user_id = ? AND client_id = ?", userID, clientID).First(&grant).Error
+	if err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// SaveGrant creates or replaces the scopes the user gave the app
+func (r *OAuthRepository) SaveGrant(grant *model.OAuthGrant) error {
+	return r.db.Omit("Client").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"scopes", "updated_at"}),
+	}).Create(grant).Error
+}
+
+// ListGrants returns the apps the user gave access to (with the app), most
+// recently authorized first
+func (r *OAuthRepository) ListGrants(userID uuid.UUID) ([]model.OAuthGrant, error) {
+	grants := []model.OAuthGrant{}
+	err := r.db.Preload("Client").Where("user_id = ?", userID).Order("updated_at DESC").Find(&grants).Error
+	return grants, err
+}
+
+// DeleteGrant takes the app's access away from the user, with its refresh tokens
+func (r *OAuthRepository) DeleteGrant(userID uuid.UUID, clientID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND client_id = ?", userID, clientID).Delete(&model.OAuthGrant{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Where("user_id = ? AND client_id = ?", userID, clientID).Delete(&model.OAuthRefreshToken{}).Error
+	})
+}
+
+// CreateRefreshToken stores a refresh token
+func (r *OAuthRepository) CreateRefreshToken(token *model.OAuthRefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+// ConsumeRefreshToken deletes and returns an unexpired refresh token, so each
+// one is used at most once even when requests race
+func (r *OAuthRepository) ConsumeRefreshToken(tokenHash string, now time.Time) (*model.OAuthRefreshToken, error) {
+	var token model.OAuthRefreshToken
+	result := r.db.Clauses(clause.Returning{}).
+		Where("token_hash = ? AND expires_at > ?", tokenHash, now).
+		Delete(&token)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &token, nil
+}
+
+// DeleteRefreshToken revokes one of the app's refresh tokens
+func (r *OAuthRepository) DeleteRefreshToken(tokenHash, clientID string) error {
+	return r.db.Where("token_hash = ? AND client_id = ?", tokenHash, clientID).Delete(&model.OAuthRefreshToken{}).Error
+}
+
+// DeleteExpiredRefreshTokens removes refresh tokens that expired before now
+// and returns how many were removed
+func (r *OAuthRepository) DeleteExpiredRefreshTokens(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now).Delete(&model.OAuthRefreshToken{})
+	return result.RowsAffected, result.Error
+}
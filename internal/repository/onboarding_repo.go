@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"gorm.io/gorm"
+)
+
+// OnboardingRepository handles database operations for groups' onboarding rules
+type OnboardingRepository struct {
+	db *gorm.DB
+}
+
+func NewOnboardingRepository(db *gorm.DB) *OnboardingRepository {
+	return &OnboardingRepository{db: db}
+}
+
+// Create stores a new rule
+func (r *OnboardingRepository) Create(rule *model.OnboardingRule) error {
+	return r.db.Create(rule).Error
+}
+
+// FindByID returns a rule
+func (r *OnboardingRepository) FindByID(id uuid.UUID) (*model.OnboardingRule, error) {
+	var rule model.OnboardingRule
+	if err := r.db.Where("id = ?", id).First(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// ListByConversation returns a group's rules, oldest first, which is the order they run in
+func (r *OnboardingRepository) ListByConversation(conversationID uuid.UUID) ([]model.OnboardingRule, error) {
+	rules := []model.OnboardingRule{}
+	err := r.db.Where("conversation_id = ?", conversationID).Order("created_at").Find(&rules).Error
+	return rules, err
+}
+
+// CountByConversation counts a group's rules
+func (r *OnboardingRepository) CountByConversation(conversationID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.OnboardingRule{}).Where("conversation_id = ?", conversationID).Count(&count).Error
+	return count, err
+}
+
+// Delete removes a rule of a group
+func (r *OnboardingRepository) Delete(conversationID, id uuid.UUID) error {
+	result := r.db.Where("id = ? AND conversation_id = ?", id, conversationID).Delete(&model.OnboardingRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
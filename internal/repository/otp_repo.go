@@ -22,12 +22,12 @@ func (r *OTPRepository) Create(otp *model.OTPCode) error {
 	return r.db.Create(otp).Error
 }
 
-// FindValidOTP finds an unused, non-expired OTP code for a user and purpose
-func (r *OTPRepository) FindValidOTP(userID uuid.UUID, code string, purpose model.OTPPurpose) (*model.OTPCode, error) {
+// FindPending finds the latest unused, non-expired OTP code for a user and purpose
+func (r *OTPRepository) FindPending(userID uuid.UUID, purpose model.OTPPurpose) (*model.OTPCode, error) {
 	var otp model.OTPCode
 	err := r.db.
-		Where("user_id = ? AND code = ? AND purpose = ? AND expires_at > ? AND used_at IS NULL",
-			userID, code, purpose, time.Now()).
+		Where("user_id = ? AND purpose = ? AND expires_at > ? AND used_at IS NULL",
+			userID, purpose, time.Now()).
 		Order("created_at DESC").
 		First(&otp).Error
 	if err != nil {
@@ -36,12 +36,46 @@ func (r *OTPRepository) FindValidOTP(userID uuid.UUID, code string, purpose mode
 	return &otp, nil
 }
 
-// MarkAsUsed marks an OTP code as used
+// FindPendingByID finds an unused, non-expired OTP code by ID and purpose
+func (r *OTPRepository) FindPendingByID(otpID uuid.UUID, purpose model.OTPPurpose) (*model.OTPCode, error) {
+	var otp model.OTPCode
+	err := r.db.
+		Where("id = ? AND purpose = ? AND expires_at > ? AND used_at IS NULL", otpID, purpose, time.Now()).
+		First(&otp).Error
+	if err != nil {
+		return nil, err
+	}
+	return &otp, nil
+}
+
+// RecordFailedAttempt counts a wrong guess against a code and uses the code
+// up once it reaches maxAttempts. It reports whether the code is used up.
+func (r *OTPRepository) RecordFailedAttempt(otpID uuid.UUID, maxAttempts int) (bool, error) {
+	var updated []struct{ UsedAt *time.Time }
+	err := r.db.Raw(`
+		UPDATE otp_codes SET attempts = attempts + 1,
+			used_at = CASE WHEN attempts + 1 >= ? THEN NOW() ELSE NULL END
+		WHERE id = ? AND used_at IS NULL
+		RETURNING used_at`, maxAttempts, otpID).Scan(&updated).Error
+	if err != nil {
+		return false, err
+	}
+	return len(updated) == 0 || updated[0].UsedAt != nil, nil
+}
+
+// MarkAsUsed marks an OTP code as used. It fails with gorm.ErrRecordNotFound
+// when the code was used meanwhile, so each code works once.
 func (r *OTPRepository) MarkAsUsed(otpID uuid.UUID) error {
-	now := time.Now()
-	return r.db.Model(&model.OTPCode{}).
-		Where("id = ?", otpID).
-		Update("used_at", now).Error
+	result := r.db.Model(&model.OTPCode{}).
+		Where("id = ? AND used_at IS NULL", otpID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
 // InvalidateAllForUser invalidates all pending OTPs for a user and purpose
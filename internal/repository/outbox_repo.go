@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OutboxRepository handles database operations for outbox events
+type OutboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// Claim leases up to limit due events, oldest first. Other workers (on any
+// instance) skip them until the lease ends, so an event whose worker crashed
+// is picked up again once its lease runs out.
+func (r *OutboxRepository) Claim(limit int, lease time.Duration) ([]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("available_at <= ?", time.Now()).
+			Order("available_at").
+			Limit(limit).
+			Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(events))
+		for i := range events {
+			ids[i] = events[i].ID
+			events[i].Attempts++
+		}
+		return tx.Model(&model.OutboxEvent{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"available_at": time.Now().Add(lease),
+			"attempts":     gorm.Expr("attempts + 1"),
+		}).Error
+	})
+	return events, err
+}
+
+// Create saves events on their own, e.g. work scheduled for later by a worker
+func (r *OutboxRepository) Create(events []model.OutboxEvent) error {
+	return r.db.Create(&events).Error
+}
+
+// Delete removes a published event
+func (r *OutboxRepository) Delete(id uuid.UUID) error {
+	return r.db.Where("id = ?", id).Delete(&model.OutboxEvent{}).Error
+}
+
+// Retry makes a failed event due again at the given time
+func (r *OutboxRepository) Retry(id uuid.UUID, at time.Time, lastError string) error {
+	return r.db.Model(&model.OutboxEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"available_at": at,
+		"last_error":   lastError,
+	}).Error
+}
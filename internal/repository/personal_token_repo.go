@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"gorm.io/gorm"
+)
+
+// PersonalTokenRepository handles database operations for personal access tokens
+type PersonalTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewPersonalTokenRepository(db *gorm.DB) *PersonalTokenRepository {
+	return &PersonalTokenRepository{db: db}
+}
+
+// Create stores a new token
+func (r *PersonalTokenRepository) Create(token *model.PersonalAccessToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindByHash finds a token by the hash of its value
+func (r *PersonalTokenRepository) FindByHash(tokenHash string) (*model.PersonalAccessToken, error) {
+	var token model.PersonalAccessToken
+	err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// List returns the user's tokens, newest first
+func (r *PersonalTokenRepository) List(userID uuid.UUID) ([]model.PersonalAccessToken, error) {
+	tokens := []model.PersonalAccessToken{}
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// Count returns how many tokens the user has
+func (r *PersonalTokenRepository) Count(userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.PersonalAccessToken{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// Delete revokes one of the user's tokens
+func (r *PersonalTokenRepository) Delete(id, userID uuid.UUID) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&model.PersonalAccessToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Touch records a use of the token
+func (r *PersonalTokenRepository) Touch(id uuid.UUID, usedAt time.Time, ip string) error {
+	return r.db.Model(&model.PersonalAccessToken{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"last_used_at": usedAt, "last_used_ip": ip}).Error
+}
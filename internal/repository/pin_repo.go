@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrPinLimit is returned by Pin when the conversation already has its limit of pins
+var ErrPinLimit = errors.New("pin limit reached")
+
+// PinRepository handles database operations for pinned messages
+type PinRepository struct {
+	db *gorm.DB
+}
+
+func NewPinRepository(db *gorm.DB) *PinRepository {
+	return &PinRepository{db: db}
+}
+
+// List returns a conversation's pins, top first, with their messages
+func (r *PinRepository) List(conversationID uuid.UUID) ([]model.PinnedMessage, error) {
+	pins := []model.PinnedMessage{}
+	err := r.db.
+		Preload("Message.Sender").
+		Preload("Message.Attachments").
+		Where("conversation_id = ?", conversationID).
+		Order("position, pinned_at DESC").
+		Find(&pins).Error
+	return pins, err
+}
+
+// Find returns the pin of a message
+func (r *PinRepository) Find(conversationID, messageID uuid.UUID) (*model.PinnedMessage, error) {
+	var pin model.PinnedMessage
+	if err := r.db.Where("conversation_id = ? AND message_id = ?", conversationID, messageID).First(&pin).Error; err != nil {
+		return nil, err
+	}
+	return &pin, nil
+}
+
+// Pin puts a message on top of the conversation's pins, unless it has limit
+// pins already (0 for no limit). An announcement replaces the conversation's
+// current one.
+func (r *PinRepository) Pin(pin *model.PinnedMessage, limit int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Locking the conversation serializes concurrent pins, so they can't all pass the limit
+		var conv model.Conversation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			Where("id = ?", pin.ConversationID).
+			First(&conv).Error; err != nil {
+			return err
+		}
+		if limit > 0 {
+			var count int64
+			if err := tx.Model(&model.PinnedMessage{}).
+				Where("conversation_id = ?", pin.ConversationID).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count >= int64(limit) {
+				return ErrPinLimit
+			}
+		}
+
+		if err := tx.Model(&model.PinnedMessage{}).
+			Where("conversation_id = ?", pin.ConversationID).
+			UpdateColumn("position", gorm.Expr("position + 1")).Error; err != nil {
+			return err
+		}
+		if pin.Announcement {
+			if err := clearAnnouncement(tx, pin.ConversationID); err != nil {
+				return err
+			}
+		}
+		pin.Position = 0
+		return tx.Omit(clause.Associations).Create(pin).Error
+	})
+}
+
+// SetAnnouncement makes a pinned message the conversation's announcement, or
+// no longer one
+func (r *PinRepository) SetAnnouncement(conversationID, messageID uuid.UUID, announcement bool) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if announcement {
+			if err := clearAnnouncement(tx, conversationID); err != nil {
+				return err
+			}
+		}
+		return tx.Model(&model.PinnedMessage{}).
+			Where("conversation_id = ? AND message_id = ?", conversationID, messageID).
+			UpdateColumn("announcement", announcement).Error
+	})
+}
+
+func clearAnnouncement(db *gorm.DB, conversationID uuid.UUID) error {
+	return db.Model(&model.PinnedMessage{}).
+		Where("conversation_id = ? AND announcement", conversationID).
+		UpdateColumn("announcement", false).Error
+}
+
+// Unpin removes a message's pin
+func (r *PinRepository) Unpin(conversationID, messageID uuid.UUID) error {
+	result := r.db.Where("conversation_id = ? AND message_id = ?", conversationID, messageID).Delete(&model.PinnedMessage{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Reorder sets the pins' positions to their order in messageIDs
+func (r *PinRepository) Reorder(conversationID uuid.UUID, messageIDs []uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i, messageID := range messageIDs {
+			if err := tx.Model(&model.PinnedMessage{}).
+				Where("conversation_id = ? AND message_id = ?", conversationID, messageID).
+				UpdateColumn("position", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FindAnnouncements returns the announcement messages of conversations, by conversation
+func (r *PinRepository) FindAnnouncements(conversationIDs []uuid.UUID) (map[uuid.UUID]*model.Message, error) {
+	announcements := make(map[uuid.UUID]*model.Message, len(conversationIDs))
+	if len(conversationIDs) == 0 {
+		return announcements, nil
+	}
+	var pins []model.PinnedMessage
+	if err := r.db.
+		Preload("Message.Sender").
+		Where("conversation_id IN ? AND announcement", conversationIDs).
+		Find(&pins).Error; err != nil {
+		return nil, err
+	}
+	for i := range pins {
+		if pins[i].Message.ID != uuid.Nil {
+			announcements[pins[i].ConversationID] = &pins[i].Message
+		}
+	}
+	return announcements, nil
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"gorm.io/gorm"
+)
+
+// RetentionRepository handles database operations for retention policies
+// and the messages they expire
+type RetentionRepository struct {
+	db *gorm.DB
+}
+
+func NewRetentionRepository(db *gorm.DB) *RetentionRepository {
+	return &RetentionRepository{db: db}
+}
+
+// ListPolicies returns every policy, the deployment's first
+func (r *RetentionRepository) ListPolicies() ([]model.RetentionPolicy, error) {
+	policies := []model.RetentionPolicy{}
+	err := r.db.Order("conversation_id NULLS FIRST, created_at").Find(&policies).Error
+	return policies, err
+}
+
+// SavePolicy creates the deployment's or a conversation's policy, or replaces it
+func (r *RetentionRepository) SavePolicy(policy *model.RetentionPolicy) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing model.RetentionPolicy
+		err := policyScope(tx, policy.ConversationID).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(policy).Error
+		}
+		if err != nil {
+			return err
+		}
+		policy.ID, policy.CreatedAt = existing.ID, existing.CreatedAt
+		return tx.Save(policy).Error
+	})
+}
+
+// DeletePolicy removes the deployment's or a conversation's policy
+func (r *RetentionRepository) DeletePolicy(conversationID *uuid.UUID) error {
+	result := policyScope(r.db, conversationID).Delete(&model.RetentionPolicy{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindExpiredMessages returns up to limit messages, deleted or not and with
+// their attachments, sent longer ago than their conversation's policy (or
+// else the deployment's, deploymentDays) allows, oldest first. Conversations
+// under legal hold are skipped.
+func (r *RetentionRepository) FindExpiredMessages(deploymentDays int, now time.Time, limit int) ([]model.Message, error) {
+	messages := []model.Message{}
+	err := r.db.Unscoped().
+		Preload("Attachments", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Joins("LEFT JOIN retention_policies ON retention_policies.conversation_id = messages.conversation_id").
+		Where("COALESCE(retention_policies.max_age_days, ?) > 0", deploymentDays).
+		Where("messages.created_at < ?::timestamptz - COALESCE(retention_policies.max_age_days, ?) * INTERVAL '1 day'", now, deploymentDays).
+		Where("messages.conversation_id NOT IN (" + heldConversations + ")").
+		Order("messages.created_at").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
+// PurgeMessages permanently deletes messages. Their attachments and receipts
+// go with them (ON DELETE CASCADE), replies to them lose the reference, and
+// the attachments' references to their file blobs are dropped so the blob
+// purge can remove the files.
+func (r *RetentionRepository) PurgeMessages(ids []uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`
+			UPDATE file_blobs SET ref_count = GREATEST(file_blobs.ref_count - refs.n, 0), updated_at = NOW()
+			FROM (
+				SELECT blob_hash, COUNT(*) AS n FROM message_attachments
+				WHERE message_id IN ? AND blob_hash IS NOT NULL AND deleted_at IS NULL
+				GROUP BY blob_hash
+			) refs
+			WHERE file_blobs.hash = refs.blob_hash`, ids).Error
+		if err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id IN ?", ids).Delete(&model.Message{}).Error
+	})
+}
+
+func policyScope(db *gorm.DB, conversationID *uuid.UUID) *gorm.DB {
+	if conversationID == nil {
+		return db.Where("conversation_id IS NULL")
+	}
+	return db.Where("conversation_id = ?", *conversationID)
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"gorm.io/gorm"
+)
+
+// TemplateRepository handles database operations for conversation templates
+type TemplateRepository struct {
+	db *gorm.DB
+}
+
+func NewTemplateRepository(db *gorm.DB) *TemplateRepository {
+	return &TemplateRepository{db: db}
+}
+
+// Create stores a new template
+func (r *TemplateRepository) Create(template *model.ConversationTemplate) error {
+	return r.db.Create(template).Error
+}
+
+// Update saves a changed template
+func (r *TemplateRepository) Update(template *model.ConversationTemplate) error {
+	return r.db.Save(template).Error
+}
+
+// FindByID returns a template
+func (r *TemplateRepository) FindByID(id uuid.UUID) (*model.ConversationTemplate, error) {
+	var template model.ConversationTemplate
+	if err := r.db.Where("id = ?", id).First(&template).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// FindByName returns the template with a name
+func (r *TemplateRepository) FindByName(name string) (*model.ConversationTemplate, error) {
+	var template model.ConversationTemplate
+	if err := r.db.Where("name = ?", name).First(&template).Error; err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// List returns every template by name
+func (r *TemplateRepository) List() ([]model.ConversationTemplate, error) {
+	templates := []model.ConversationTemplate{}
+	err := r.db.Order("name").Find(&templates).Error
+	return templates, err
+}
+
+// Delete removes a template; groups created from it are unaffected
+func (r *TemplateRepository) Delete(id uuid.UUID) error {
+	result := r.db.Where("id = ?", id).Delete(&model.ConversationTemplate{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
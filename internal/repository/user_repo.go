@@ -1,10 +1,14 @@
 package repository
 
 import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/pkg/dbresolver"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -18,11 +22,61 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-// Create inserts a new user
+// Create inserts a new user, deriving a handle from the name if none is set
 func (r *UserRepository) Create(user *model.User) error {
+	if user.Handle == "" {
+		handle, err := r.GenerateHandle(user.Name)
+		if err != nil {
+			return err
+		}
+		user.Handle = handle
+	}
 	return r.db.Create(user).Error
 }
 
+// GenerateHandle returns a free handle based on the name, adding a numeric suffix when needed
+func (r *UserRepository) GenerateHandle(name string) (string, error) {
+	base := model.HandleBase(name)
+	candidate := base
+	for attempt := 0; attempt < 10; attempt++ {
+		if model.IsValidHandle(candidate) {
+			exists, err := r.HandleExists(candidate)
+			if err != nil {
+				return "", err
+			}
+			if !exists {
+				return candidate, nil
+			}
+		}
+		candidate = fmt.Sprintf("%s_%04d", base, rand.IntN(10000))
+	}
+	return "", fmt.Errorf("no free handle for %q", name)
+}
+
+// HandleExists checks whether a handle is already taken (including by deleted users)
+func (r *UserRepository) HandleExists(handle string) (bool, error) {
+	var count int64
+	err := r.db.Unscoped().Model(&model.User{}).Where("handle = ?", handle).Count(&count).Error
+	return count > 0, err
+}
+
+// FindByHandle finds a user by their @handle
+func (r *UserRepository) FindByHandle(handle string) (*model.User, error) {
+	var user model.User
+	err := r.db.Where("handle = ?", handle).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateHandle changes a user's handle
+func (r *UserRepository) UpdateHandle(userID uuid.UUID, handle string) error {
+	return r.db.Model(&model.User{}).
+		Where("id = ?", userID).
+		Update("handle", handle).Error
+}
+
 // FindByID finds a user by UUID
 func (r *UserRepository) FindByID(id uuid.UUID) (*model.User, error) {
 	var user model.User
@@ -53,11 +107,73 @@ func (r *UserRepository) FindByGoogleID(googleID string) (*model.User, error) {
 	return &user, nil
 }
 
-// SearchUsers searches users by name or email (partial match)
+// FindBySSOSubject finds a user linked to the SSO provider's subject
+func (r *UserRepository) FindBySSOSubject(subject string) (*model.User, error) {
+	var user model.User
+	err := r.db.Where("sso_subject = ?", subject).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindByExternalID finds a user by their identity provider's ID
+func (r *UserRepository) FindByExternalID(externalID string) (*model.User, error) {
+	var user model.User
+	err := r.db.Where("external_id = ?", externalID).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindByAuthProvider returns every user who signs in with the given provider
+func (r *UserRepository) FindByAuthProvider(provider model.AuthProvider) ([]model.User, error) {
+	var users []model.User
+	err := r.db.Where("auth_provider = ?", provider).Find(&users).Error
+	return users, err
+}
+
+// LinkSSOSubject links an existing account to the SSO provider's subject and
+// marks its email verified (the provider vouched for it)
+func (r *UserRepository) LinkSSOSubject(userID uuid.UUID, subject string) error {
+	return r.db.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"sso_subject":       subject,
+		"email_verified_at": gorm.Expr("COALESCE(email_verified_at, NOW())"),
+	}).Error
+}
+
+// FindByMatrixID finds the local stand-in of a remote Matrix user
+func (r *UserRepository) FindByMatrixID(matrixID string) (*model.User, error) {
+	var user model.User
+	err := r.db.Where("matrix_id = ?", matrixID).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns, used with ESCAPE '\'
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern returns a LIKE pattern matching values that contain s as typed
+func containsPattern(s string) string {
+	return "%" + likeEscaper.Replace(s) + "%"
+}
+
+// SearchUsers searches users by name, display name, handle or email (partial match),
+// skipping deactivated users and users who don't want to be found by the searcher,
+// served from a read replica when one is configured
 func (r *UserRepository) SearchUsers(query string, excludeUserID uuid.UUID, limit int) ([]model.User, error) {
 	var users []model.User
-	err := r.db.
-		Where("(name ILIKE ? OR email ILIKE ?) AND id != ?", "%"+query+"%", "%"+query+"%", excludeUserID).
+	pattern := containsPattern(query)
+	handlePattern := containsPattern(model.NormalizeHandle(query))
+	err := dbresolver.Replica(r.db).
+		Where("(name ILIKE ? ESCAPE '\\' OR display_name ILIKE ? ESCAPE '\\' OR handle LIKE ? ESCAPE '\\' OR email ILIKE ? ESCAPE '\\') AND id != ?",
+			pattern, pattern, handlePattern, pattern, excludeUserID).
+		Where("discoverability = ? OR (discoverability = ? AND id IN (?))",
+			model.PrivacyEveryone, model.PrivacyContacts, r.contactIDsQuery(excludeUserID)).
+		Where("deactivated_at IS NULL").
 		Limit(limit).
 		Find(&users).Error
 	return users, err
@@ -74,6 +190,55 @@ func (r *UserRepository) UpdateOnlineStatus(id uuid.UUID, isOnline bool) error {
 	return r.db.Model(&model.User{}).Where("id = ?", id).Updates(updates).Error
 }
 
+// GetContactIDs returns the IDs of users who share a private conversation with the user
+func (r *UserRepository) GetContactIDs(userID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.contactIDsQuery(userID).Pluck("cm.user_id", &ids).Error
+	return ids, err
+}
+
+// FindOnlineContactIDs returns the user's contacts who are online and let contacts see it
+func (r *UserRepository) FindOnlineContactIDs(userID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Model(&model.User{}).
+		Where("id IN (?)", r.contactIDsQuery(userID)).
+		Where("is_online = ? AND online_privacy != ?", true, model.PrivacyNobody).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// contactIDsQuery selects the user IDs of the user's contacts (usable as a subquery)
+func (r *UserRepository) contactIDsQuery(userID uuid.UUID) *gorm.DB {
+	return r.db.Table("conversation_members AS cm").
+		Select("DISTINCT cm.user_id").
+		Joins("JOIN conversation_members AS mine ON mine.conversation_id = cm.conversation_id").
+		Joins("JOIN conversations AS c ON c.id = cm.conversation_id").
+		Where("c.type = ? AND c.deleted_at IS NULL", model.ConversationTypePrivate).
+		Where("mine.user_id = ? AND mine.deleted_at IS NULL", userID).
+		Where("cm.user_id != ? AND cm.deleted_at IS NULL", userID)
+}
+
+// FindOnlineIDs returns the IDs of users currently flagged online
+func (r *UserRepository) FindOnlineIDs() ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Model(&model.User{}).Where("is_online = ?", true).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// SetOnlineStatusBulk sets the online status of many users at once
+func (r *UserRepository) SetOnlineStatusBulk(ids []uuid.UUID, isOnline bool) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	updates := map[string]interface{}{
+		"is_online": isOnline,
+	}
+	if !isOnline {
+		updates["last_seen"] = gorm.Expr("NOW()")
+	}
+	return r.db.Model(&model.User{}).Where("id IN ?", ids).Updates(updates).Error
+}
+
 // VerifyEmail marks user's email as verified
 func (r *UserRepository) VerifyEmail(userID uuid.UUID) error {
 	now := time.Now()
@@ -96,32 +261,121 @@ func (r *UserRepository) UpdateAvatar(userID uuid.UUID, avatarURL string) error
 		Update("avatar", avatarURL).Error
 }
 
-// UpdateProfile updates user's name and/or avatar
-func (r *UserRepository) UpdateProfile(userID uuid.UUID, name, avatar string) error {
+// UpdateProfile updates user's name, display name and/or avatar
+func (r *UserRepository) UpdateProfile(userID uuid.UUID, req model.UpdateProfileRequest) error {
 	updates := map[string]interface{}{}
-	if name != "" {
-		updates["name"] = name
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.DisplayName != nil {
+		updates["display_name"] = *req.DisplayName
+	}
+	if req.Bio != nil {
+		updates["bio"] = *req.Bio
 	}
-	if avatar != "" {
-		updates["avatar"] = avatar
+	if req.Avatar != "" {
+		updates["avatar"] = req.Avatar
+	}
+	if len(updates) == 0 {
+		return nil
 	}
 	return r.db.Model(&model.User{}).Where("id = ?", userID).Updates(updates).Error
 }
 
-// UpdateSettings updates user settings
-func (r *UserRepository) UpdateSettings(userID uuid.UUID, theme string, notifEnabled *bool, soundEnabled *bool, lang string) error {
+// UpdateStatus sets (or, with empty values, clears) a user's custom status
+func (r *UserRepository) UpdateStatus(userID uuid.UUID, message, emoji string, expiresAt *time.Time) error {
+	return r.db.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"status_message":    message,
+		"status_emoji":      emoji,
+		"status_expires_at": expiresAt,
+	}).Error
+}
+
+// UpdateAutoReply sets or, with empty text, clears a user's auto-reply
+func (r *UserRepository) UpdateAutoReply(userID uuid.UUID, text string, startsAt, endsAt *time.Time) error {
+	return r.db.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"auto_reply_text":      text,
+		"auto_reply_starts_at": startsAt,
+		"auto_reply_ends_at":   endsAt,
+	}).Error
+}
+
+// ClearExpiredStatuses clears custom statuses that expired before now and returns the affected users
+func (r *UserRepository) ClearExpiredStatuses(now time.Time) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := r.db.Model(&model.User{}).
+		Where("status_expires_at <= ?", now).
+		Pluck("id", &ids).Error; err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	err := r.db.Model(&model.User{}).
+		Where("id IN ? AND status_expires_at <= ?", ids, now).
+		Updates(map[string]interface{}{
+			"status_message":    "",
+			"status_emoji":      "",
+			"status_expires_at": nil,
+		}).Error
+	return ids, err
+}
+
+// UpdateSettings updates user settings (only fields present in the request)
+func (r *UserRepository) UpdateSettings(userID uuid.UUID, req model.UpdateSettingsRequest) error {
 	updates := map[string]interface{}{}
-	if theme != "" {
-		updates["theme"] = theme
+	if req.Theme != "" {
+		updates["theme"] = req.Theme
+	}
+	if req.IsNotificationEnabled != nil {
+		updates["is_notification_enabled"] = *req.IsNotificationEnabled
+	}
+	if req.IsSoundEnabled != nil {
+		updates["is_sound_enabled"] = *req.IsSoundEnabled
+	}
+	if req.IsDigestEnabled != nil {
+		updates["is_digest_enabled"] = *req.IsDigestEnabled
+	}
+	if req.IsEmailNotifEnabled != nil {
+		updates["is_email_notification_enabled"] = *req.IsEmailNotifEnabled
 	}
-	if notifEnabled != nil {
-		updates["is_notification_enabled"] = *notifEnabled
+	if req.Language != "" {
+		updates["language"] = req.Language
 	}
-	if soundEnabled != nil {
-		updates["is_sound_enabled"] = *soundEnabled
+	if req.Timezone != "" {
+		updates["timezone"] = req.Timezone
 	}
-	if lang != "" {
-		updates["language"] = lang
+	if req.SingleSession != nil {
+		updates["single_session"] = *req.SingleSession
+	}
+	if q := req.QuietHours; q != nil {
+		if q.Enabled != nil {
+			updates["quiet_hours_enabled"] = *q.Enabled
+		}
+		if q.Start != "" {
+			updates["quiet_hours_start"] = q.Start
+		}
+		if q.End != "" {
+			updates["quiet_hours_end"] = q.End
+		}
+		if q.Summary != nil {
+			updates["quiet_hours_summary"] = *q.Summary
+		}
+		if q.AllowMentions != nil {
+			updates["quiet_hours_allow_mentions"] = *q.AllowMentions
+		}
+	}
+	if p := req.Privacy; p != nil {
+		if p.LastSeen != "" {
+			updates["last_seen_privacy"] = p.LastSeen
+		}
+		if p.Online != "" {
+			updates["online_privacy"] = p.Online
+		}
+		if p.Discoverability != "" {
+			updates["discoverability"] = p.Discoverability
+		}
+	}
+	if len(updates) == 0 {
+		return nil
 	}
 	return r.db.Model(&model.User{}).Where("id = ?", userID).Updates(updates).Error
 }
@@ -151,6 +405,158 @@ func (r *UserRepository) GetUserDevices(userID uuid.UUID) ([]model.UserDevice, e
 	return devices, err
 }
 
+// DeleteDevicesByToken removes devices whose push tokens were rejected by the provider
+func (r *UserRepository) DeleteDevicesByToken(tokens []string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	return r.db.Where("fcm_token IN ?", tokens).Delete(&model.UserDevice{}).Error
+}
+
+// DeleteInactiveDevices removes devices that haven't re-registered since the cutoff
+func (r *UserRepository) DeleteInactiveDevices(before time.Time) (int64, error) {
+	result := r.db.Where("last_active_at < ?", before).Delete(&model.UserDevice{})
+	return result.RowsAffected, result.Error
+}
+
+// AddLoginEvent records a sign-in
+func (r *UserRepository) AddLoginEvent(event *model.LoginEvent) error {
+	return r.db.Create(event).Error
+}
+
+// KnownLoginDevice reports whether the user signed in before at all, and
+// whether they did from the device with this fingerprint
+func (r *UserRepository) KnownLoginDevice(userID uuid.UUID, fingerprint string) (signedInBefore, known bool, err error) {
+	var seen struct {
+		SignedIn bool
+		Known    bool
+	}
+	err = r.db.Model(&model.LoginEvent{}).
+		Select("COUNT(*) > 0 AS signed_in, COALESCE(BOOL_OR(fingerprint = ?), FALSE) AS known", fingerprint).
+		Where("user_id = ?", userID).
+		Scan(&seen).Error
+	return seen.SignedIn, seen.Known, err
+}
+
+// GetLoginEvents returns the user's most recent sign-ins, newest first
+func (r *UserRepository) GetLoginEvents(userID uuid.UUID, limit int) ([]model.LoginEvent, error) {
+	var events []model.LoginEvent
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// AddWebPushSubscription stores a browser push subscription, re-assigning the endpoint if it already exists
+func (r *UserRepository) AddWebPushSubscription(sub *model.WebPushSubscription) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "endpoint"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"user_id":    sub.UserID,
+			"p256dh":     sub.P256dh,
+			"auth":       sub.Auth,
+			"user_agent": sub.UserAgent,
+			"updated_at": time.Now(),
+		}),
+	}).Create(sub).Error
+}
+
+// GetWebPushSubscriptions gets all browser push subscriptions for a user
+func (r *UserRepository) GetWebPushSubscriptions(userID uuid.UUID) ([]model.WebPushSubscription, error) {
+	var subs []model.WebPushSubscription
+	err := r.db.Where("user_id = ?", userID).Find(&subs).Error
+	return subs, err
+}
+
+// DeleteWebPushSubscription removes a browser push subscription by endpoint
+func (r *UserRepository) DeleteWebPushSubscription(endpoint string) error {
+	return r.db.Where("endpoint = ?", endpoint).Delete(&model.WebPushSubscription{}).Error
+}
+
+// DeleteUserWebPushSubscription removes a user's own browser push subscription
+func (r *UserRepository) DeleteUserWebPushSubscription(userID uuid.UUID, endpoint string) error {
+	return r.db.Where("user_id = ? AND endpoint = ?", userID, endpoint).Delete(&model.WebPushSubscription{}).Error
+}
+
+// FindVerifiedIDs returns the IDs of all verified users
+func (r *UserRepository) FindVerifiedIDs() ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Model(&model.User{}).
+		Where("email_verified_at IS NOT NULL").
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// FindDigestRecipients returns verified users who opted into the weekly unread digest
+func (r *UserRepository) FindDigestRecipients() ([]model.User, error) {
+	var users []model.User
+	err := r.db.
+		Where("is_digest_enabled = ? AND email_verified_at IS NOT NULL", true).
+		Find(&users).Error
+	return users, err
+}
+
+// DirectoryFilter selects users or groups by one directory (SCIM) attribute;
+// the zero value matches everything
+type DirectoryFilter struct {
+	ID         *uuid.UUID
+	Email      string // users, case-insensitive
+	Name       string // groups
+	ExternalID string
+}
+
+// ListDirectory returns a page of users in creation order and the total number matching the filter
+func (r *UserRepository) ListDirectory(filter DirectoryFilter, offset, limit int) ([]model.User, int64, error) {
+	query := r.db.Model(&model.User{})
+	if filter.ID != nil {
+		query = query.Where("id = ?", *filter.ID)
+	}
+	if filter.Email != "" {
+		query = query.Where("LOWER(email) = LOWER(?)", filter.Email)
+	}
+	if filter.ExternalID != "" {
+		query = query.Where("external_id = ?", filter.ExternalID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	users := []model.User{}
+	err := query.Order("created_at, id").Offset(offset).Limit(limit).Find(&users).Error
+	return users, total, err
+}
+
+// FindByIDs returns the users with the given IDs (missing IDs are skipped)
+func (r *UserRepository) FindByIDs(ids []uuid.UUID) ([]model.User, error) {
+	var users []model.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.Where("id IN ?", ids).Find(&users).Error
+	return users, err
+}
+
+// UpdateDirectoryProfile sets the attributes an identity provider manages
+func (r *UserRepository) UpdateDirectoryProfile(userID uuid.UUID, name, displayName, email string, externalID *string) error {
+	return r.db.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"name":         name,
+		"display_name": displayName,
+		"email":        email,
+		"external_id":  externalID,
+	}).Error
+}
+
+// SetDeactivated deactivates (or, with nil, reactivates) a user
+func (r *UserRepository) SetDeactivated(userID uuid.UUID, deactivatedAt *time.Time) error {
+	return r.db.Model(&model.User{}).
+		Where("id = ?", userID).
+		Update("deactivated_at", deactivatedAt).Error
+}
+
+// Delete soft-deletes a user
+func (r *UserRepository) Delete(userID uuid.UUID) error {
+	return r.db.Where("id = ?", userID).Delete(&model.User{}).Error
+}
+
 // GetOrCreateGoogleUser finds a user by email/google_id or creates a new one
 func (r *UserRepository) GetOrCreateGoogleUser(userInfo model.GoogleUserInfo) (*model.User, error) {
 	var user model.User
@@ -211,7 +617,7 @@ func (r *UserRepository) GetOrCreateGoogleUser(userInfo model.GoogleUserInfo) (*
 		Language:              "vi",
 	}
 
-	if err := r.db.Create(&newUser).Error; err != nil {
+	if err := r.Create(&newUser); err != nil {
 		return nil, err
 	}
 
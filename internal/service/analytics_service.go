@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	activeUsersKeyPrefix = "gotalk:stats:active:" // + day: HyperLogLog of the users active that day
+	activeUsersTTL       = 32 * 24 * time.Hour    // long enough to count monthly active users
+	activeCallKeyPrefix  = "gotalk:stats:call:"   // + both user IDs, sorted: unix time the call was answered
+	activeCallTTL        = 12 * time.Hour
+
+	statsDayFormat = "2006-01-02"
+	maxStatsDays   = 366
+)
+
+type usageKey struct {
+	day    string
+	metric model.UsageMetric
+}
+
+// AnalyticsService counts anonymous usage (messages, uploads, call minutes,
+// active users) in memory and adds it to the daily aggregates every flush.
+// Active users are counted in Redis HyperLogLogs, shared by every instance,
+// so the aggregates hold how many users were active but never who.
+type AnalyticsService struct {
+	repo *repository.AnalyticsRepository
+	rdb  *redis.Client
+
+	mu     sync.Mutex
+	counts map[usageKey]int64
+	active map[string]map[uuid.UUID]struct{} // day -> users seen since the last flush
+}
+
+func NewAnalyticsService(repo *repository.AnalyticsRepository, rdb *redis.Client) *AnalyticsService {
+	return &AnalyticsService{
+		repo:   repo,
+		rdb:    rdb,
+		counts: make(map[usageKey]int64),
+		active: make(map[string]map[uuid.UUID]struct{}),
+	}
+}
+
+// Record adds n to today's count of a metric
+func (s *AnalyticsService) Record(metric model.UsageMetric, n int64) {
+	if s == nil || n == 0 {
+		return
+	}
+	key := usageKey{day: time.Now().UTC().Format(statsDayFormat), metric: metric}
+	s.mu.Lock()
+	s.counts[key] += n
+	s.mu.Unlock()
+}
+
+// Active counts the user as active today
+func (s *AnalyticsService) Active(userID uuid.UUID) {
+	if s == nil {
+		return
+	}
+	day := time.Now().UTC().Format(statsDayFormat)
+	s.mu.Lock()
+	users, ok := s.active[day]
+	if !ok {
+		users = make(map[uuid.UUID]struct{})
+		s.active[day] = users
+	}
+	users[userID] = struct{}{}
+	s.mu.Unlock()
+}
+
+// CallAnswered starts timing a call between two users
+func (s *AnalyticsService) CallAnswered(a, b uuid.UUID) {
+	if s == nil {
+		return
+	}
+	s.rdb.Set(context.Background(), activeCallKey(a, b), time.Now().Unix(), activeCallTTL)
+}
+
+// CallEnded adds an answered call's minutes once either side hangs up
+func (s *AnalyticsService) CallEnded(a, b uuid.UUID) {
+	if s == nil {
+		return
+	}
+	started, err := s.rdb.GetDel(context.Background(), activeCallKey(a, b)).Int64()
+	if err != nil {
+		return
+	}
+	seconds := time.Now().Unix() - started
+	s.Record(model.MetricCallMinutes, (seconds+59)/60)
+}
+
+// Run flushes the buffered counts every interval until ctx is cancelled
+func (s *AnalyticsService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Flush(ctx)
+		}
+	}
+}
+
+// Flush writes the buffered counts to the aggregates. Counts that fail to
+// write are kept for the next flush.
+func (s *AnalyticsService) Flush(ctx context.Context) {
+	s.mu.Lock()
+	counts, active := s.counts, s.active
+	s.counts = make(map[usageKey]int64)
+	s.active = make(map[string]map[uuid.UUID]struct{})
+	s.mu.Unlock()
+
+	stats := make([]model.UsageStat, 0, len(counts))
+	for key, n := range counts {
+		day, _ := time.Parse(statsDayFormat, key.day)
+		stats = append(stats, model.UsageStat{Day: day, Metric: key.metric, Value: n})
+	}
+	if err := s.repo.AddStats(stats); err != nil {
+		log.Printf("⚠️  Failed to flush usage stats: %v", err)
+		s.mu.Lock()
+		for key, n := range counts {
+			s.counts[key] += n
+		}
+		s.mu.Unlock()
+	}
+
+	for day, users := range active {
+		if err := s.flushActive(ctx, day, users); err != nil {
+			log.Printf("⚠️  Failed to flush active users for %s: %v", day, err)
+		}
+	}
+}
+
+// flushActive adds the users to the day's HyperLogLog, then stores the day's
+// and the trailing 30 days' distinct counts
+func (s *AnalyticsService) flushActive(ctx context.Context, day string, users map[uuid.UUID]struct{}) error {
+	members := make([]interface{}, 0, len(users))
+	for id := range users {
+		members = append(members, id.String())
+	}
+	key := activeUsersKeyPrefix + day
+	pipe := s.rdb.TxPipeline()
+	pipe.PFAdd(ctx, key, members...)
+	pipe.Expire(ctx, key, activeUsersTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	date, _ := time.Parse(statsDayFormat, day)
+	monthKeys := make([]string, 0, 30)
+	for i := range 30 {
+		monthKeys = append(monthKeys, activeUsersKeyPrefix+date.AddDate(0, 0, -i).Format(statsDayFormat))
+	}
+	daily, err := s.rdb.PFCount(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	monthly, err := s.rdb.PFCount(ctx, monthKeys...).Result()
+	if err != nil {
+		return err
+	}
+	return s.repo.SetStats([]model.UsageStat{
+		{Day: date, Metric: model.MetricActiveUsers, Value: daily},
+		{Day: date, Metric: model.MetricMonthlyActiveUsers, Value: monthly},
+	})
+}
+
+// Stats returns the daily series of the requested metrics, with a point for
+// every day of the range
+func (s *AnalyticsService) Stats(req model.UsageStatsRequest) (*model.UsageStatsResponse, error) {
+	metrics := model.UsageMetrics
+	if req.Metric != "" {
+		if !slices.Contains(model.UsageMetrics, model.UsageMetric(req.Metric)) {
+			return nil, ErrUnknownMetric
+		}
+		metrics = []model.UsageMetric{model.UsageMetric(req.Metric)}
+	}
+
+	from, to, days, err := statsRange(req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.repo.ListStats(model.UsageMetric(req.Metric), from, to)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[usageKey]int64, len(stats))
+	for _, stat := range stats {
+		values[usageKey{day: stat.Day.Format(statsDayFormat), metric: stat.Metric}] = stat.Value
+	}
+
+	resp := &model.UsageStatsResponse{
+		From:   from.Format(statsDayFormat),
+		To:     to.Format(statsDayFormat),
+		Series: make([]model.UsageSeries, 0, len(metrics)),
+	}
+	for _, metric := range metrics {
+		points := make([]model.UsagePoint, 0, days)
+		for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+			key := usageKey{day: day.Format(statsDayFormat), metric: metric}
+			points = append(points, model.UsagePoint{Day: key.day, Value: values[key]})
+		}
+		resp.Series = append(resp.Series, model.UsageSeries{Metric: metric, Points: points})
+	}
+	return resp, nil
+}
+
+// statsRange parses a range of YYYY-MM-DD days, by default the 30 days up to
+// today, and returns how many days it spans
+func statsRange(fromDay, toDay string) (from, to time.Time, days int, err error) {
+	to = time.Now().UTC().Truncate(24 * time.Hour)
+	if toDay != "" {
+		to, _ = time.Parse(statsDayFormat, toDay)
+	}
+	from = to.AddDate(0, 0, -29)
+	if fromDay != "" {
+		from, _ = time.Parse(statsDayFormat, fromDay)
+	}
+	days = int(to.Sub(from).Hours()/24) + 1
+	if days < 1 || days > maxStatsDays {
+		return from, to, 0, ErrStatsRange
+	}
+	return from, to, days, nil
+}
+
+func activeCallKey(a, b uuid.UUID) string {
+	ids := []string{a.String(), b.String()}
+	slices.Sort(ids)
+	return activeCallKeyPrefix + ids[0] + ":" + ids[1]
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 200
+)
+
+// AuditService keeps the audit log of administrative and compliance actions
+type AuditService struct {
+	repo *repository.AuditRepository
+}
+
+func NewAuditService(repo *repository.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Record adds an event to the log. Failures are logged, not returned: the
+// action already happened.
+func (s *AuditService) Record(event model.AuditEvent) {
+	if err := s.repo.Create(&event); err != nil {
+		log.Printf("⚠️  Failed to record audit event %s: %v", event.Action, err)
+	}
+}
+
+// List returns a page of the log, newest first
+func (s *AuditService) List(req model.AuditListRequest) ([]model.AuditEvent, error) {
+	var conversationID *uuid.UUID
+	if req.ConversationID != "" {
+		id, err := uuid.Parse(req.ConversationID)
+		if err != nil {
+			return nil, err
+		}
+		conversationID = &id
+	}
+	return s.repo.List(req.Action, conversationID, req.Before, AuditPageLimit(req.Limit))
+}
+
+// AuditPageLimit clamps the requested page size
+func AuditPageLimit(limit int) int {
+	if limit <= 0 {
+		return defaultAuditLimit
+	}
+	if limit > maxAuditLimit {
+		return maxAuditLimit
+	}
+	return limit
+}
@@ -2,17 +2,26 @@ package service
 
 import (
 	"context"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"log"
 	"math/big"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/quocanhngo/gotalk/internal/model"
 	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/internal/ws"
 	"github.com/quocanhngo/gotalk/pkg/auth"
+	"github.com/quocanhngo/gotalk/pkg/geoip"
 	"github.com/quocanhngo/gotalk/pkg/mailer"
+	"github.com/quocanhngo/gotalk/pkg/useragent"
 	"github.com/redis/go-redis/v9"
 	"golang.org/x/crypto/bcrypt"
 	"google.golang.org/api/idtoken"
@@ -24,33 +33,68 @@ const (
 	otpExpiryMinutes = 5
 	otpRateLimit     = 3 // max OTPs per hour
 	googleTokenURL   = "https://oauth2.googleapis.com/tokeninfo?id_token="
+
+	otpVerifyKeyPrefix  = "gotalk:otp:verify:"       // + IP: verification attempts in the current window
+	loginAlertKeyPrefix = "gotalk:auth:login-alert:" // + token: user ID, for the "this wasn't me" link
+	loginHistoryLimit   = 50
+
+	deviceMaxInactivity = 90 * 24 * time.Hour // push devices not seen for this long are pruned
 )
 
+// OTPPolicy hardens one-time codes against guessing
+type OTPPolicy struct {
+	Secret           []byte // HMAC key of the stored code hashes
+	MaxAttempts      int    // wrong guesses before a code stops working
+	VerifyRateLimit  int    // verifications per IP per VerifyRateWindow; 0 disables the limit
+	VerifyRateWindow time.Duration
+}
+
 // AuthService handles authentication business logic
 type AuthService struct {
 	userRepo       *repository.UserRepository
 	otpRepo        *repository.OTPRepository
+	otpPolicy      OTPPolicy
 	jwtManager     *auth.JWTManager
 	mailer         *mailer.Mailer
 	rdb            *redis.Client
+	signup         *SignupService
 	googleClientID string
+	singleSession  bool         // every sign-in ends the user's other sessions
+	hub            *ws.Hub      // optional: closes live connections of revoked tokens
+	ldap           *LDAPService // optional: checks passwords of directory accounts
+
+	// Magic sign-in links, see UseMagicLinks
+	magicLinkURL string
+	magicLinkTTL time.Duration
+
+	// New-device alerts, see UseLoginAlerts
+	geo            *geoip.Locator
+	notifCenter    *NotificationCenterService
+	alertRevokeURL string
+	alertRevokeTTL time.Duration
 }
 
 func NewAuthService(
 	userRepo *repository.UserRepository,
 	otpRepo *repository.OTPRepository,
+	otpPolicy OTPPolicy,
 	jwtManager *auth.JWTManager,
 	mailer *mailer.Mailer,
 	rdb *redis.Client,
+	signup *SignupService,
 	googleClientID string,
+	singleSession bool,
 ) *AuthService {
 	return &AuthService{
 		userRepo:       userRepo,
 		otpRepo:        otpRepo,
+		otpPolicy:      otpPolicy,
 		jwtManager:     jwtManager,
 		mailer:         mailer,
 		rdb:            rdb,
+		signup:         signup,
 		googleClientID: googleClientID,
+		singleSession:  singleSession,
 	}
 }
 
@@ -63,15 +107,20 @@ func (s *AuthService) Register(req model.RegisterRequest) (*model.OTPSentRespons
 	if err == nil {
 		// Email exists
 		if existingUser.IsEmailVerified() {
-			return nil, errors.New("email already registered")
+			return nil, ErrEmailTaken
 		}
 		// User registered but never verified - resend OTP
 		return s.sendOTP(existingUser, model.OTPPurposeEmailVerification)
 	}
 
+	if err := s.signup.Admit(req.Email, req.InviteCode); err != nil {
+		return nil, err
+	}
+
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
+		s.signup.Release(req.InviteCode)
 		return nil, errors.New("failed to hash password")
 	}
 
@@ -83,6 +132,7 @@ func (s *AuthService) Register(req model.RegisterRequest) (*model.OTPSentRespons
 	}
 
 	if err := s.userRepo.Create(user); err != nil {
+		s.signup.Release(req.InviteCode)
 		return nil, errors.New("failed to create user")
 	}
 
@@ -91,21 +141,21 @@ func (s *AuthService) Register(req model.RegisterRequest) (*model.OTPSentRespons
 }
 
 // VerifyOTP verifies an OTP code and activates the account
-func (s *AuthService) VerifyOTP(req model.VerifyOTPRequest) (*model.LoginResponse, error) {
+func (s *AuthService) VerifyOTP(req model.VerifyOTPRequest, client model.ClientInfo) (*model.LoginResponse, error) {
+	if err := s.allowOTPVerify(client.IP); err != nil {
+		return nil, err
+	}
 	user, err := s.userRepo.FindByEmail(req.Email)
 	if err != nil {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
 
-	// Find valid OTP
-	otp, err := s.otpRepo.FindValidOTP(user.ID, req.Code, model.OTPPurposeEmailVerification)
-	if err != nil {
-		return nil, errors.New("invalid or expired OTP code")
+	// Check and use up the code
+	if err := s.useOTP(user.ID, model.OTPPurposeEmailVerification, req.Code); err != nil {
+		return nil, err
 	}
-
-	// Mark OTP as used
-	if err := s.otpRepo.MarkAsUsed(otp.ID); err != nil {
-		return nil, errors.New("failed to verify OTP")
+	if !user.IsActive() {
+		return nil, ErrAccountDeactivated
 	}
 
 	// Verify user's email
@@ -118,10 +168,17 @@ func (s *AuthService) VerifyOTP(req model.VerifyOTPRequest) (*model.LoginRespons
 	if err != nil {
 		return nil, errors.New("failed to generate token")
 	}
+	if err := s.StartSession(user, token); err != nil {
+		return nil, err
+	}
 
 	// Refresh user data
 	user, _ = s.userRepo.FindByID(user.ID)
 
+	if err := s.mailer.SendWelcome(user.Email, user.Name); err != nil {
+		log.Printf("⚠️  Failed to queue welcome email for %s: %v", user.Email, err)
+	}
+
 	return &model.LoginResponse{
 		Token: token,
 		User:  user.ToResponse(),
@@ -132,11 +189,11 @@ func (s *AuthService) VerifyOTP(req model.VerifyOTPRequest) (*model.LoginRespons
 func (s *AuthService) ResendOTP(req model.ResendOTPRequest) (*model.OTPSentResponse, error) {
 	user, err := s.userRepo.FindByEmail(req.Email)
 	if err != nil {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
 
 	if user.IsEmailVerified() {
-		return nil, errors.New("email already verified")
+		return nil, ErrEmailAlreadyVerified
 	}
 
 	return s.sendOTP(user, model.OTPPurposeEmailVerification)
@@ -144,29 +201,53 @@ func (s *AuthService) ResendOTP(req model.ResendOTPRequest) (*model.OTPSentRespo
 
 // ==================== Login (Email/Password) ====================
 
-// Login authenticates a user and returns a JWT token
-func (s *AuthService) Login(req model.LoginRequest) (*model.LoginResponse, error) {
+// Login authenticates a user and returns a JWT token. With LDAP enabled,
+// unknown emails and LDAP accounts are checked against the directory.
+func (s *AuthService) Login(req model.LoginRequest, client model.ClientInfo) (*model.LoginResponse, error) {
 	user, err := s.userRepo.FindByEmail(req.Email)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("invalid email or password")
-		}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, errors.New("failed to find user")
 	}
+	if s.ldap != nil && (user == nil || user.AuthProvider == model.AuthProviderLDAP) {
+		if user, err = s.ldap.Authenticate(context.Background(), req.Email, req.Password); err != nil {
+			return nil, err
+		}
+		return s.finishLogin(user, client)
+	}
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
 
 	// Check if user registered with Google (no password set)
 	if user.AuthProvider == model.AuthProviderGoogle {
-		return nil, errors.New("this account uses Google login. Please sign in with Google")
+		return nil, ErrGoogleAccount
+	}
+	if user.AuthProvider == model.AuthProviderSSO {
+		return nil, ErrSSOAccount
+	}
+	if user.AuthProvider == model.AuthProviderMatrix {
+		return nil, ErrInvalidCredentials // bridged Matrix users have no GoTalk login
+	}
+	if user.AuthProvider == model.AuthProviderLDAP {
+		return nil, ErrLDAPUnavailable // LDAP has been switched off
 	}
 
 	// Check if email is verified
 	if !user.IsEmailVerified() {
-		return nil, errors.New("email not verified. Please check your inbox for the verification code")
+		return nil, ErrEmailNotVerified
 	}
 
 	// Compare password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return nil, errors.New("invalid email or password")
+		return nil, ErrInvalidCredentials
+	}
+	return s.finishLogin(user, client)
+}
+
+// finishLogin starts a session for a user whose password checked out
+func (s *AuthService) finishLogin(user *model.User, client model.ClientInfo) (*model.LoginResponse, error) {
+	if !user.IsActive() {
+		return nil, ErrAccountDeactivated
 	}
 
 	// Generate JWT token
@@ -174,6 +255,11 @@ func (s *AuthService) Login(req model.LoginRequest) (*model.LoginResponse, error
 	if err != nil {
 		return nil, errors.New("failed to generate token")
 	}
+	if err := s.StartSession(user, token); err != nil {
+		return nil, err
+	}
+
+	go s.recordLogin(user, client)
 
 	return &model.LoginResponse{
 		Token: token,
@@ -198,28 +284,42 @@ func (s *AuthService) ForgotPassword(req model.ForgotPasswordRequest) (*model.OT
 	}
 
 	if user.AuthProvider == model.AuthProviderGoogle {
-		return nil, errors.New("this account uses Google login. Password reset is not available")
+		return nil, ErrGoogleAccount.WithMessage("this account uses Google login. Password reset is not available")
+	}
+	if user.AuthProvider == model.AuthProviderSSO {
+		return nil, ErrSSOAccount.WithMessage("this account uses single sign-on. Password reset is not available")
+	}
+	if user.AuthProvider == model.AuthProviderLDAP {
+		return nil, ErrLDAPAccount
+	}
+	if user.AuthProvider == model.AuthProviderMatrix {
+		// Bridged Matrix users have no mailbox; answer as if the email didn't exist
+		return &model.OTPSentResponse{
+			Message:   "If the email exists, a reset code has been sent",
+			Email:     req.Email,
+			ExpiresIn: otpExpiryMinutes * 60,
+		}, nil
 	}
 
 	return s.sendOTP(user, model.OTPPurposePasswordReset)
 }
 
 // ResetPassword verifies OTP and sets a new password
-func (s *AuthService) ResetPassword(req model.ResetPasswordRequest) error {
-	user, err := s.userRepo.FindByEmail(req.Email)
-	if err != nil {
-		return errors.New("user not found")
+func (s *AuthService) ResetPassword(req model.ResetPasswordRequest, client model.ClientInfo) error {
+	if err := s.allowOTPVerify(client.IP); err != nil {
+		return err
 	}
-
-	// Find valid OTP
-	otp, err := s.otpRepo.FindValidOTP(user.ID, req.Code, model.OTPPurposePasswordReset)
+	user, err := s.userRepo.FindByEmail(req.Email)
 	if err != nil {
-		return errors.New("invalid or expired reset code")
+		return ErrUserNotFound
 	}
 
-	// Mark OTP as used
-	if err := s.otpRepo.MarkAsUsed(otp.ID); err != nil {
-		return errors.New("failed to process reset code")
+	// Check and use up the code
+	if err := s.useOTP(user.ID, model.OTPPurposePasswordReset, req.Code); err != nil {
+		if errors.Is(err, ErrInvalidOTP) {
+			return ErrInvalidOTP.WithMessage("invalid or expired reset code")
+		}
+		return err
 	}
 
 	// Hash new password
@@ -228,7 +328,146 @@ func (s *AuthService) ResetPassword(req model.ResetPasswordRequest) error {
 		return errors.New("failed to hash password")
 	}
 
-	return s.userRepo.UpdatePassword(user.ID, string(hashedPassword))
+	if err := s.userRepo.UpdatePassword(user.ID, string(hashedPassword)); err != nil {
+		return err
+	}
+
+	if err := s.mailer.SendPasswordChanged(user.Email, user.Name, time.Now()); err != nil {
+		log.Printf("⚠️  Failed to queue password changed email for %s: %v", user.Email, err)
+	}
+	return nil
+}
+
+// ==================== Magic Link ====================
+
+// SendMagicLink emails a one-time sign-in link. It doesn't reveal whether the
+// email has an account: accounts that can't get a link, or got too many, get
+// the same answer as unknown emails.
+func (s *AuthService) SendMagicLink(req model.MagicLinkRequest) (*model.OTPSentResponse, error) {
+	if s.magicLinkURL == "" {
+		return nil, ErrMagicLinkDisabled
+	}
+	sent := &model.OTPSentResponse{
+		Message:   "If the email exists, a sign-in link has been sent",
+		Email:     req.Email,
+		ExpiresIn: int(s.magicLinkTTL / time.Second),
+	}
+
+	user, err := s.userRepo.FindByEmail(req.Email)
+	if err != nil || user.AuthProvider == model.AuthProviderMatrix || !user.IsActive() {
+		return sent, nil
+	}
+	if user.AuthProvider == model.AuthProviderSSO || user.AuthProvider == model.AuthProviderLDAP {
+		log.Printf("⚠️  Magic link not sent to %s: the account signs in with %s", user.Email, user.AuthProvider)
+		return sent, nil
+	}
+
+	count, _ := s.otpRepo.CountRecentOTPs(user.ID, model.OTPPurposeMagicLink, time.Now().Add(-1*time.Hour))
+	if count >= int64(otpRateLimit) {
+		log.Printf("⚠️  Magic link not sent to %s: %d sent in the last hour", user.Email, count)
+		return sent, nil
+	}
+	_ = s.otpRepo.InvalidateAllForUser(user.ID, model.OTPPurposeMagicLink)
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, errors.New("failed to generate sign-in link")
+	}
+	secret := hex.EncodeToString(b)
+	link := &model.OTPCode{
+		UserID:    user.ID,
+		CodeHash:  s.hashOTP(secret),
+		Purpose:   model.OTPPurposeMagicLink,
+		ExpiresAt: time.Now().Add(s.magicLinkTTL),
+	}
+	if err := s.otpRepo.Create(link); err != nil {
+		return nil, errors.New("failed to save sign-in link")
+	}
+
+	// The token names its row, so the secret is checked against one hash only
+	url := s.magicLinkURL + "?token=" + link.ID.String() + "." + secret
+	if err := s.mailer.SendMagicLink(user.Email, user.Name, url, int(s.magicLinkTTL/time.Minute)); err != nil {
+		return nil, ErrOTPDelivery
+	}
+	return sent, nil
+}
+
+// CheckMagicLink reports whether a sign-in link's token is still usable,
+// without using it up
+func (s *AuthService) CheckMagicLink(token string, client model.ClientInfo) error {
+	if err := s.allowOTPVerify(client.IP); err != nil {
+		return err
+	}
+	_, err := s.findMagicLink(token)
+	return err
+}
+
+// findMagicLink returns the pending link a token names, if its secret matches
+func (s *AuthService) findMagicLink(token string) (*model.OTPCode, error) {
+	idPart, secret, _ := strings.Cut(token, ".")
+	linkID, err := uuid.Parse(idPart)
+	if err != nil || secret == "" {
+		return nil, ErrMagicLinkInvalid
+	}
+
+	link, err := s.otpRepo.FindPendingByID(linkID, model.OTPPurposeMagicLink)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMagicLinkInvalid
+		}
+		return nil, err
+	}
+	if !hmac.Equal([]byte(s.hashOTP(secret)), []byte(link.CodeHash)) {
+		return nil, ErrMagicLinkInvalid
+	}
+	return link, nil
+}
+
+// VerifyMagicLink exchanges a sign-in link's token for a session. Following
+// the link proves the user owns the email, so it also verifies it.
+func (s *AuthService) VerifyMagicLink(token string, client model.ClientInfo) (*model.LoginResponse, error) {
+	if err := s.allowOTPVerify(client.IP); err != nil {
+		return nil, err
+	}
+	link, err := s.findMagicLink(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.otpRepo.MarkAsUsed(link.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMagicLinkInvalid
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(link.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.IsActive() {
+		return nil, ErrAccountDeactivated
+	}
+	if !user.IsEmailVerified() {
+		if err := s.userRepo.VerifyEmail(user.ID); err != nil {
+			return nil, errors.New("failed to verify email")
+		}
+		user, _ = s.userRepo.FindByID(user.ID)
+	}
+
+	sessionToken, err := s.jwtManager.GenerateToken(user.ID, user.Email, user.Name)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+	if err := s.StartSession(user, sessionToken); err != nil {
+		return nil, err
+	}
+	_ = s.userRepo.UpdateOnlineStatus(user.ID, true)
+	go s.recordLogin(user, client)
+
+	return &model.LoginResponse{
+		Token: sessionToken,
+		User:  user.ToResponse(),
+	}, nil
 }
 
 // ==================== Profile ====================
@@ -237,7 +476,7 @@ func (s *AuthService) ResetPassword(req model.ResetPasswordRequest) error {
 func (s *AuthService) GetProfile(userID uuid.UUID) (*model.UserResponse, error) {
 	user, err := s.userRepo.FindByID(userID)
 	if err != nil {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
 	resp := user.ToResponse()
 	return &resp, nil
@@ -250,16 +489,101 @@ func (s *AuthService) SearchUsers(query string, excludeUserID uuid.UUID) ([]mode
 		return nil, err
 	}
 
+	contacts, err := contactSet(s.userRepo, excludeUserID)
+	if err != nil {
+		return nil, err
+	}
+
 	var result []model.UserResponse
 	for _, u := range users {
-		result = append(result, u.ToResponse())
+		result = append(result, u.ToPublicResponse(excludeUserID, contacts[u.ID]))
 	}
 	return result, nil
 }
 
+// CheckHandle reports whether a handle can be claimed
+func (s *AuthService) CheckHandle(handle string) (*model.HandleAvailabilityResponse, error) {
+	handle = model.NormalizeHandle(handle)
+	resp := &model.HandleAvailabilityResponse{Handle: handle}
+
+	switch {
+	case model.IsReservedHandle(handle):
+		resp.Reason = "reserved"
+	case !model.IsValidHandle(handle):
+		resp.Reason = "invalid"
+	default:
+		exists, err := s.userRepo.HandleExists(handle)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			resp.Reason = "taken"
+		}
+	}
+	resp.Available = resp.Reason == ""
+	return resp, nil
+}
+
+// UpdateHandle changes the user's @handle
+func (s *AuthService) UpdateHandle(userID uuid.UUID, req model.UpdateHandleRequest) (*model.UserResponse, error) {
+	handle := model.NormalizeHandle(req.Handle)
+	if !model.IsValidHandle(handle) {
+		return nil, ErrInvalidHandle
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.Handle == handle {
+		resp := user.ToResponse()
+		return &resp, nil
+	}
+
+	exists, err := s.userRepo.HandleExists(handle)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, ErrHandleTaken
+	}
+
+	// The unique index still catches a concurrent claim of the same handle
+	if err := s.userRepo.UpdateHandle(userID, handle); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrHandleTaken
+		}
+		return nil, err
+	}
+	return s.GetProfile(userID)
+}
+
+// GetUserByHandle looks up a user by @handle as seen by the viewer.
+// Users who aren't discoverable by the viewer are reported as not found.
+func (s *AuthService) GetUserByHandle(handle string, viewerID uuid.UUID) (*model.UserResponse, error) {
+	user, err := s.userRepo.FindByHandle(model.NormalizeHandle(handle))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	contacts, err := contactSet(s.userRepo, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID != viewerID && !user.Discoverability.Allows(contacts[user.ID]) {
+		return nil, ErrUserNotFound
+	}
+
+	resp := user.ToPublicResponse(viewerID, contacts[user.ID])
+	return &resp, nil
+}
+
 // UpdateProfile updates user's profile
 func (s *AuthService) UpdateProfile(userID uuid.UUID, req model.UpdateProfileRequest) (*model.UserResponse, error) {
-	if err := s.userRepo.UpdateProfile(userID, req.Name, req.Avatar); err != nil {
+	if err := s.userRepo.UpdateProfile(userID, req); err != nil {
 		return nil, err
 	}
 	return s.GetProfile(userID)
@@ -267,9 +591,15 @@ func (s *AuthService) UpdateProfile(userID uuid.UUID, req model.UpdateProfileReq
 
 // UpdateSettings updates user's settings
 func (s *AuthService) UpdateSettings(userID uuid.UUID, req model.UpdateSettingsRequest) (*model.UserResponse, error) {
-	if err := s.userRepo.UpdateSettings(userID, req.Theme, req.IsNotificationEnabled, req.IsSoundEnabled, req.Language); err != nil {
+	if err := s.userRepo.UpdateSettings(userID, req); err != nil {
 		return nil, err
 	}
+	// Turning single-session mode off lets the user's next sign-ins coexist with this one
+	if req.SingleSession != nil && !*req.SingleSession && !s.singleSession {
+		if err := s.rdb.Del(context.Background(), auth.SessionKey(userID)).Err(); err != nil {
+			return nil, err
+		}
+	}
 	return s.GetProfile(userID)
 }
 
@@ -278,6 +608,72 @@ func (s *AuthService) RegisterDevice(userID uuid.UUID, req model.RegisterDeviceR
 	return s.userRepo.AddDevice(userID, req.FCMToken, req.DeviceType)
 }
 
+// RunDevicePrune periodically deletes push devices inactive for 90+ days,
+// blocking until ctx is cancelled
+func (s *AuthService) RunDevicePrune(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.userRepo.DeleteInactiveDevices(time.Now().Add(-deviceMaxInactivity))
+			if err != nil {
+				log.Printf("⚠️  Device prune failed: %v", err)
+			} else if n > 0 {
+				log.Printf("🧹 Pruned %d inactive push devices", n)
+			}
+		}
+	}
+}
+
+// RegisterWebPush stores a browser push subscription for the user
+func (s *AuthService) RegisterWebPush(userID uuid.UUID, req model.WebPushSubscribeRequest, userAgent string) error {
+	return s.userRepo.AddWebPushSubscription(&model.WebPushSubscription{
+		UserID:    userID,
+		Endpoint:  req.Endpoint,
+		P256dh:    req.Keys.P256dh,
+		Auth:      req.Keys.Auth,
+		UserAgent: userAgent,
+	})
+}
+
+// UnregisterWebPush removes a browser push subscription
+func (s *AuthService) UnregisterWebPush(userID uuid.UUID, endpoint string) error {
+	return s.userRepo.DeleteUserWebPushSubscription(userID, endpoint)
+}
+
+// UseHub makes logouts close the WebSocket connections of the revoked tokens
+// right away instead of at the next periodic check
+func (s *AuthService) UseHub(hub *ws.Hub) {
+	s.hub = hub
+}
+
+// UseLDAP checks the passwords of unknown emails and LDAP accounts against the directory
+func (s *AuthService) UseLDAP(ldap *LDAPService) {
+	s.ldap = ldap
+}
+
+// UseMagicLinks enables sign-in links: emails link to url?token=..., an app
+// page that posts the token to /auth/magic-link/verify within ttl
+func (s *AuthService) UseMagicLinks(url string, ttl time.Duration) {
+	s.magicLinkURL = url
+	s.magicLinkTTL = ttl
+}
+
+// UseLoginAlerts locates sign-ins with geo (nil skips lookups) and also alerts
+// new devices in the notification center. Alert emails link to
+// revokeURL?token=..., which signs out everywhere for revokeTTL; an empty
+// revokeURL leaves the link out.
+func (s *AuthService) UseLoginAlerts(geo *geoip.Locator, notifCenter *NotificationCenterService, revokeURL string, revokeTTL time.Duration) {
+	s.geo = geo
+	s.notifCenter = notifCenter
+	s.alertRevokeURL = revokeURL
+	s.alertRevokeTTL = revokeTTL
+}
+
 // Logout invalidates the token and sets user offline
 func (s *AuthService) Logout(userID uuid.UUID, tokenString string) error {
 	// 1. Set offline
@@ -297,7 +693,79 @@ func (s *AuthService) Logout(userID uuid.UUID, tokenString string) error {
 	}
 
 	// 3. Blacklist token
-	return s.rdb.Set(context.Background(), "blacklist:"+tokenString, "revoked", expiresIn).Err()
+	if err := s.rdb.Set(context.Background(), auth.BlacklistKey(tokenString), "revoked", expiresIn).Err(); err != nil {
+		return err
+	}
+	if s.hub != nil {
+		s.hub.ForceLogout(userID, tokenString, ws.CloseLoggedOut)
+	}
+	return nil
+}
+
+// StartSession applies the single-session policy to a token just issued at
+// sign-in. When the deployment or the user wants one session, the token
+// becomes the user's only valid one and their other connections are closed.
+func (s *AuthService) StartSession(user *model.User, token string) error {
+	ctx := context.Background()
+	if !s.singleSession && !user.SingleSession {
+		// The policy may have been on until now
+		return s.rdb.Del(ctx, auth.SessionKey(user.ID)).Err()
+	}
+
+	if err := s.rdb.Set(ctx, auth.SessionKey(user.ID), auth.TokenHash(token), s.jwtManager.Expiry()).Err(); err != nil {
+		return err
+	}
+	if s.hub != nil {
+		s.hub.ForceLogoutExcept(user.ID, token, ws.CloseSessionReplaced)
+	}
+	return nil
+}
+
+// LogoutEverywhere revokes every token issued to the user so far, on all
+// devices, and closes their WebSocket connections. Signing in again works.
+func (s *AuthService) LogoutEverywhere(userID uuid.UUID) error {
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	// Tokens issued in this second are revoked too: sign-ins can't be ordered within it
+	revokedAt := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := s.rdb.Set(context.Background(), auth.SessionsRevokedKey(userID), revokedAt, s.jwtManager.Expiry()).Err(); err != nil {
+		return err
+	}
+	if err := s.userRepo.UpdateOnlineStatus(userID, false); err != nil {
+		return err
+	}
+	if s.hub != nil {
+		s.hub.ForceLogout(userID, "", ws.CloseLoggedOut)
+	}
+	return nil
+}
+
+// RevokeFromLoginAlert signs the user out everywhere from the "this wasn't me"
+// link of a new sign-in alert. Each link works once.
+func (s *AuthService) RevokeFromLoginAlert(token string) error {
+	userID, err := s.rdb.GetDel(context.Background(), loginAlertKeyPrefix+token).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return ErrLoginAlertInvalid
+		}
+		return err
+	}
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return ErrLoginAlertInvalid
+	}
+	log.Printf("🔒 User %s signed out everywhere from a new sign-in alert", id)
+	return s.LogoutEverywhere(id)
+}
+
+// LoginHistory returns the user's most recent sign-ins
+func (s *AuthService) LoginHistory(userID uuid.UUID) ([]model.LoginEvent, error) {
+	return s.userRepo.GetLoginEvents(userID, loginHistoryLimit)
 }
 
 // ==================== Internal Helpers ====================
@@ -308,7 +776,7 @@ func (s *AuthService) sendOTP(user *model.User, purpose model.OTPPurpose) (*mode
 	// Rate limiting: max 3 OTPs per hour
 	count, _ := s.otpRepo.CountRecentOTPs(user.ID, purpose, time.Now().Add(-1*time.Hour))
 	if count >= int64(otpRateLimit) {
-		return nil, errors.New("too many OTP requests. Please try again later")
+		return nil, ErrTooManyOTPs
 	}
 
 	// Invalidate old OTPs
@@ -323,7 +791,7 @@ func (s *AuthService) sendOTP(user *model.User, purpose model.OTPPurpose) (*mode
 	// Save OTP to database
 	otp := &model.OTPCode{
 		UserID:    user.ID,
-		Code:      code,
+		CodeHash:  s.hashOTP(code),
 		Purpose:   purpose,
 		ExpiresAt: time.Now().Add(time.Duration(otpExpiryMinutes) * time.Minute),
 	}
@@ -331,20 +799,18 @@ func (s *AuthService) sendOTP(user *model.User, purpose model.OTPPurpose) (*mode
 		return nil, errors.New("failed to save OTP")
 	}
 
-	// Send email asynchronously
-	go func() {
-		var emailErr error
-		switch purpose {
-		case model.OTPPurposeEmailVerification:
-			// Used Name instead of Username
-			emailErr = s.mailer.SendOTP(user.Email, user.Name, code, otpExpiryMinutes)
-		case model.OTPPurposePasswordReset:
-			emailErr = s.mailer.SendPasswordReset(user.Email, user.Name, code, otpExpiryMinutes)
-		}
-		if emailErr != nil {
-			fmt.Printf("❌ Failed to send email: %v\n", emailErr)
-		}
-	}()
+	// Queue email (delivered by the email worker with retries)
+	var emailErr error
+	switch purpose {
+	case model.OTPPurposeEmailVerification:
+		// Used Name instead of Username
+		emailErr = s.mailer.SendOTP(user.Email, user.Name, code, otpExpiryMinutes)
+	case model.OTPPurposePasswordReset:
+		emailErr = s.mailer.SendPasswordReset(user.Email, user.Name, code, otpExpiryMinutes)
+	}
+	if emailErr != nil {
+		return nil, ErrOTPDelivery
+	}
 
 	return &model.OTPSentResponse{
 		Message:   "Verification code sent to your email",
@@ -353,6 +819,171 @@ func (s *AuthService) sendOTP(user *model.User, purpose model.OTPPurpose) (*mode
 	}, nil
 }
 
+// recordLogin adds the sign-in to the user's login history and, when it comes
+// from a device the user hasn't signed in from before, alerts them by email
+// and in the app. The very first login is not alerted. Call it in a goroutine:
+// it looks up the IP's location.
+func (s *AuthService) recordLogin(user *model.User, client model.ClientInfo) {
+	ctx := context.Background()
+	sum := sha256.Sum256([]byte(client.UserAgent))
+	event := &model.LoginEvent{
+		UserID:      user.ID,
+		Fingerprint: hex.EncodeToString(sum[:]),
+		Device:      useragent.Describe(client.UserAgent),
+		UserAgent:   truncateRunes(client.UserAgent, 500),
+		IP:          client.IP,
+	}
+	location, err := s.geo.Lookup(ctx, client.IP)
+	if err != nil {
+		log.Printf("⚠️  GeoIP lookup for %s failed: %v", client.IP, err)
+	}
+	event.Location = location.String()
+	event.CountryCode = location.CountryCode
+
+	signedInBefore, known, err := s.userRepo.KnownLoginDevice(user.ID, event.Fingerprint)
+	if err != nil {
+		log.Printf("⚠️  Failed to check login devices of %s: %v", user.ID, err)
+		return
+	}
+	event.NewDevice = signedInBefore && !known
+	if err := s.userRepo.AddLoginEvent(event); err != nil {
+		log.Printf("⚠️  Failed to record login of %s: %v", user.ID, err)
+	}
+	if event.NewDevice {
+		s.alertNewLogin(ctx, user, event)
+	}
+}
+
+// alertNewLogin tells the user about a sign-in from a new device, by email and
+// in the notification center, with a link that signs out everywhere
+func (s *AuthService) alertNewLogin(ctx context.Context, user *model.User, event *model.LoginEvent) {
+	token, err := s.loginAlertToken(ctx, user.ID)
+	if err != nil {
+		log.Printf("⚠️  Failed to create sign-in alert link for %s: %v", user.ID, err)
+	}
+
+	alert := mailer.LoginAlert{
+		Device:   event.Device,
+		IP:       event.IP,
+		Location: event.Location,
+		At:       event.CreatedAt,
+	}
+	if token != "" && s.alertRevokeURL != "" {
+		alert.RevokeURL = s.alertRevokeURL + "?token=" + token
+	}
+	if err := s.mailer.SendNewLoginAlert(user.Email, user.Name, alert); err != nil {
+		log.Printf("⚠️  Failed to queue new login alert for %s: %v", user.Email, err)
+	}
+
+	if s.notifCenter == nil {
+		return
+	}
+	body := event.Device
+	if event.Location != "" {
+		body += " · " + event.Location
+	}
+	data := map[string]string{
+		"login_id": event.ID.String(),
+		"ip":       event.IP,
+	}
+	if token != "" {
+		data["revoke_token"] = token
+	}
+	if err := s.notifCenter.Notify([]uuid.UUID{user.ID}, model.NotificationTypeNewLogin, "New sign-in to your account", body, data); err != nil {
+		log.Printf("⚠️  Failed to notify %s of a new sign-in: %v", user.ID, err)
+	}
+}
+
+// loginAlertToken creates the token of a "this wasn't me" link
+func (s *AuthService) loginAlertToken(ctx context.Context, userID uuid.UUID) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	if err := s.rdb.Set(ctx, loginAlertKeyPrefix+token, userID.String(), s.alertRevokeTTL).Err(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// contactSet returns the users sharing a private conversation with userID
+func contactSet(userRepo *repository.UserRepository, userID uuid.UUID) (map[uuid.UUID]bool, error) {
+	ids, err := userRepo.GetContactIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	contacts := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		contacts[id] = true
+	}
+	return contacts, nil
+}
+
+// hashOTP keys the code's hash with the OTP secret, so the database alone
+// isn't enough to try the million possible codes offline
+func (s *AuthService) hashOTP(code string) string {
+	mac := hmac.New(sha256.New, s.otpPolicy.Secret)
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// useOTP checks a code against the user's latest pending code for the purpose
+// and uses it up. Wrong guesses count against the code, which stops working
+// after MaxAttempts of them.
+func (s *AuthService) useOTP(userID uuid.UUID, purpose model.OTPPurpose, code string) error {
+	otp, err := s.otpRepo.FindPending(userID, purpose)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidOTP
+		}
+		return err
+	}
+
+	if !hmac.Equal([]byte(s.hashOTP(code)), []byte(otp.CodeHash)) {
+		usedUp, err := s.otpRepo.RecordFailedAttempt(otp.ID, s.otpPolicy.MaxAttempts)
+		if err != nil {
+			return err
+		}
+		if usedUp {
+			return ErrOTPAttemptsExceeded
+		}
+		return ErrInvalidOTP
+	}
+
+	if err := s.otpRepo.MarkAsUsed(otp.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidOTP // a concurrent request used it
+		}
+		return err
+	}
+	return nil
+}
+
+// allowOTPVerify counts a code verification from the IP and refuses it over
+// the per-IP limit. When Redis is unavailable the limit is not enforced.
+func (s *AuthService) allowOTPVerify(ip string) error {
+	if s.otpPolicy.VerifyRateLimit <= 0 || ip == "" {
+		return nil
+	}
+	ctx := context.Background()
+	key := otpVerifyKeyPrefix + ip
+	var count *redis.IntCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.Incr(ctx, key)
+		pipe.ExpireNX(ctx, key, s.otpPolicy.VerifyRateWindow)
+		return nil
+	})
+	if err != nil {
+		log.Printf("⚠️  OTP verification rate limit unavailable: %v", err)
+		return nil
+	}
+	if count.Val() > int64(s.otpPolicy.VerifyRateLimit) {
+		return ErrTooManyOTPAttempts
+	}
+	return nil
+}
+
 // generateOTPCode generates a cryptographically secure random numeric code
 func generateOTPCode(length int) (string, error) {
 	code := ""
@@ -371,7 +1002,7 @@ func (s *AuthService) verifyGoogleToken(tokenString string) (*model.GoogleUserIn
 	// Using the official Google library to validate the token
 	payload, err := idtoken.Validate(context.Background(), tokenString, s.googleClientID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid google token: %w", err)
+		return nil, ErrInvalidGoogleToken.Wrap(err)
 	}
 
 	// Extract claims
@@ -379,7 +1010,7 @@ func (s *AuthService) verifyGoogleToken(tokenString string) (*model.GoogleUserIn
 
 	email, ok := claims["email"].(string)
 	if !ok {
-		return nil, errors.New("email not found in token")
+		return nil, ErrInvalidGoogleToken.WithMessage("email not found in token")
 	}
 
 	name, _ := claims["name"].(string)
@@ -397,28 +1028,54 @@ func (s *AuthService) verifyGoogleToken(tokenString string) (*model.GoogleUserIn
 }
 
 // LoginWithGoogle handles Google Sign-In logic
-func (s *AuthService) LoginWithGoogle(req model.GoogleLoginRequest) (*model.LoginResponse, error) {
+func (s *AuthService) LoginWithGoogle(req model.GoogleLoginRequest, client model.ClientInfo) (*model.LoginResponse, error) {
 	// 1. Verify ID Token
 	userInfo, err := s.verifyGoogleToken(req.IDToken)
 	if err != nil {
 		return nil, err
 	}
 
-	// 2. Get or create user in DB
+	// 2. Get or create user in DB; new accounts go through the signup policy
+	existing, err := s.userRepo.FindByEmail(userInfo.Email)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+	if existing != nil && existing.AuthProvider == model.AuthProviderLDAP {
+		// Linking Google would take the account out of the directory's hands
+		return nil, ErrLDAPAccount.WithMessage("this account uses your organization's directory password. Please sign in with it")
+	}
+	newAccount := existing == nil
+	if newAccount {
+		if err := s.signup.Admit(userInfo.Email, req.InviteCode); err != nil {
+			return nil, err
+		}
+	}
 	user, err := s.userRepo.GetOrCreateGoogleUser(*userInfo)
 	if err != nil {
+		if newAccount {
+			s.signup.Release(req.InviteCode)
+		}
 		return nil, fmt.Errorf("database error: %w", err)
 	}
+	if !user.IsActive() {
+		return nil, ErrAccountDeactivated
+	}
 
 	// 3. Generate JWT
 	token, err := s.jwtManager.GenerateToken(user.ID, user.Email, user.Name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
+	if err := s.StartSession(user, token); err != nil {
+		return nil, err
+	}
 
 	// 4. Mark user as online
 	_ = s.userRepo.UpdateOnlineStatus(user.ID, true)
 
+	// 5. Alert on sign-in from an unrecognized device
+	go s.recordLogin(user, client)
+
 	return &model.LoginResponse{
 		Token: token,
 		User:  user.ToResponse(),
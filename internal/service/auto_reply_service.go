@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	autoReplySentKeyPrefix  = "gotalk:autoreply:sent:"  // + conversation id:user id, set while a reply counts as recent
+	autoReplyCountKeyPrefix = "gotalk:autoreply:count:" // + user id: auto-replies in the current window
+	autoReplyInterval       = 24 * time.Hour            // a conversation gets a user's auto-reply at most once per interval
+	autoReplyLimit          = 50                        // max auto-replies per user per autoReplyWindow
+	autoReplyWindow         = time.Hour
+)
+
+// AutoReplyService answers direct messages to users whose auto-reply is on,
+// from the outbox worker. Each conversation gets the reply at most once a day
+// and each user's replies are rate limited, so two auto-replies can't answer
+// each other and a flood of new conversations can't make the server spam.
+type AutoReplyService struct {
+	chatService *ChatService
+	rdb         *redis.Client
+}
+
+func NewAutoReplyService(chatService *ChatService, rdb *redis.Client) *AutoReplyService {
+	return &AutoReplyService{chatService: chatService, rdb: rdb}
+}
+
+// Reply answers a direct message to the recipient with their auto-reply, if
+// it's on and the conversation hasn't had it in the last day. Unlike request
+// rate limits it holds back when Redis is unavailable, so an outage can't make
+// it reply to every message.
+func (s *AutoReplyService) Reply(ctx context.Context, recipient *model.User, msg *model.Message) error {
+	if msg.AutoReply || msg.Type == model.MessageTypeSystem || msg.SenderID == recipient.ID {
+		return nil
+	}
+	autoReply := recipient.AutoReply(time.Now())
+	if autoReply == nil || !autoReply.Active {
+		return nil
+	}
+
+	first, err := s.rdb.SetNX(ctx, autoReplySentKeyPrefix+msg.ConversationID.String()+":"+recipient.ID.String(), 1, autoReplyInterval).Result()
+	if err != nil {
+		return fmt.Errorf("auto-reply interval: %w", err)
+	}
+	if !first {
+		return nil
+	}
+	if !s.allow(ctx, recipient.ID) {
+		return nil
+	}
+
+	_, err = s.chatService.SendAutoReply(msg.ConversationID, recipient.ID, autoReply.Text)
+	return err
+}
+
+// allow counts an auto-reply against the user's limit
+func (s *AutoReplyService) allow(ctx context.Context, userID uuid.UUID) bool {
+	key := autoReplyCountKeyPrefix + userID.String()
+	var count *redis.IntCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.Incr(ctx, key)
+		pipe.ExpireNX(ctx, key, autoReplyWindow)
+		return nil
+	})
+	return err == nil && count.Val() <= autoReplyLimit
+}
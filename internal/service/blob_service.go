@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"time"
+
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/pkg/storage"
+	"gorm.io/gorm"
+)
+
+const (
+	// Unreferenced blobs are kept this long so an upload has time to be attached to a message
+	blobGracePeriod = 24 * time.Hour
+	blobPurgeBatch  = 100
+)
+
+// BlobService stores uploads content-addressed by SHA-256 so identical files
+// (e.g. forwarded memes) are stored once and shared between attachments
+type BlobService struct {
+	blobRepo *repository.BlobRepository
+	storage  *storage.MinIOStorage
+	stats    *AnalyticsService
+}
+
+func NewBlobService(blobRepo *repository.BlobRepository, storage *storage.MinIOStorage, stats *AnalyticsService) *BlobService {
+	return &BlobService{blobRepo: blobRepo, storage: storage, stats: stats}
+}
+
+// StoreResult is the outcome of storing an upload in the blob store
+type StoreResult struct {
+	*storage.UploadResult
+	Hash         string
+	Deduplicated bool // true if identical content was already stored
+}
+
+// Store uploads a file unless an identical one already exists, in which case the
+// existing object is returned. The file must be positioned at the start.
+func (s *BlobService) Store(ctx context.Context, file multipart.File, header *multipart.FileHeader, folder string) (*StoreResult, error) {
+	result, err := s.store(ctx, file, header, folder)
+	if err != nil {
+		return nil, err
+	}
+	s.stats.Record(model.MetricUploads, 1)
+	s.stats.Record(model.MetricUploadBytes, header.Size)
+	return result, nil
+}
+
+func (s *BlobService) store(ctx context.Context, file multipart.File, header *multipart.FileHeader, folder string) (*StoreResult, error) {
+	hash, err := hashFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// Identical content already stored - reuse it
+	if blob, err := s.blobRepo.FindByHash(hash); err == nil {
+		_ = s.blobRepo.Touch(hash)
+		return blobResult(blob, header.Filename), nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	result, err := s.storage.Upload(ctx, file, header, folder)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.blobRepo.CreateIfNotExists(&model.FileBlob{
+		Hash:      hash,
+		ObjectKey: result.Key,
+		URL:       result.URL,
+		MimeType:  result.MimeType,
+		Size:      result.FileSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !created {
+		// A concurrent upload of the same content won - drop our copy and use theirs
+		_ = s.storage.Delete(ctx, result.Key)
+		blob, err := s.blobRepo.FindByHash(hash)
+		if err != nil {
+			return nil, err
+		}
+		return blobResult(blob, header.Filename), nil
+	}
+
+	return &StoreResult{UploadResult: result, Hash: hash}, nil
+}
+
+// RetainURL adds a reference to the blob behind an uploaded URL.
+// Returns nil if the URL was not uploaded through the blob store (e.g. external links).
+func (s *BlobService) RetainURL(url string) *string {
+	if s == nil {
+		return nil
+	}
+	blob, err := s.blobRepo.FindByURL(url)
+	if err != nil {
+		return nil
+	}
+	if err := s.blobRepo.IncrementRef(blob.Hash); err != nil {
+		return nil
+	}
+	return &blob.Hash
+}
+
+// ReleaseAttachment drops an attachment's reference to its blob. The stored object
+// is removed by PurgeUnreferenced once no attachment references it anymore.
+func (s *BlobService) ReleaseAttachment(att *model.MessageAttachment) error {
+	if s == nil || att.BlobHash == nil {
+		return nil
+	}
+	return s.blobRepo.DecrementRef(*att.BlobHash)
+}
+
+// PurgeUnreferenced deletes blobs (and their stored objects) that nothing references
+func (s *BlobService) PurgeUnreferenced(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-blobGracePeriod)
+	blobs, err := s.blobRepo.FindUnreferenced(cutoff, blobPurgeBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, blob := range blobs {
+		// Re-check atomically: the blob may have been referenced since we listed it
+		deleted, err := s.blobRepo.DeleteIfUnreferenced(blob.Hash, cutoff)
+		if err != nil || !deleted {
+			continue
+		}
+		if err := s.storage.Delete(ctx, blob.ObjectKey); err != nil {
+			log.Printf("⚠️  Failed to delete blob object %s: %v", blob.ObjectKey, err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// RunPurge periodically purges unreferenced blobs until ctx is cancelled
+func (s *BlobService) RunPurge(ctx context.Context, interval time.Duration) {
+	if s == nil || s.storage == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.PurgeUnreferenced(ctx)
+			if err != nil {
+				log.Printf("⚠️  Blob purge failed: %v", err)
+			} else if n > 0 {
+				log.Printf("🧹 Purged %d unreferenced file blobs", n)
+			}
+		}
+	}
+}
+
+// hashFile returns the hex SHA-256 of a file and rewinds it
+func hashFile(file multipart.File) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to rewind file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// blobResult builds a deduplicated store result for an existing blob
+func blobResult(blob *model.FileBlob, fileName string) *StoreResult {
+	return &StoreResult{
+		UploadResult: &storage.UploadResult{
+			URL:      blob.URL,
+			Key:      blob.ObjectKey,
+			FileName: fileName,
+			FileSize: blob.Size,
+			MimeType: blob.MimeType,
+		},
+		Hash:         blob.Hash,
+		Deduplicated: true,
+	}
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/pkg/geoip"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	callOffersKeyPrefix = "gotalk:call:offers:" // + caller id: offers in the current window
+	callOfferLimit      = 10                    // max call offers per caller per callOfferWindow
+	callOfferWindow     = time.Minute
+
+	callRegionKeyPrefix = "gotalk:call:region:" // + call id:user id -> country code of the reporter's IP
+	callStatsGrace      = 2 * time.Minute       // stats are still taken this long after a call ends
+
+	maxCallChatLength = 1000 // characters of an in-call chat message
+	maxReactionBytes  = 16
+)
+
+// CallService vets WebRTC signaling before the hub forwards it: users may only
+// signal people they share a conversation with, and call offers are rate
+// limited so nobody can ring others in a loop. It keeps a record of each call
+// from its signaling.
+type CallService struct {
+	callRepo *repository.CallRepository
+	convRepo *repository.ConversationRepository
+	members  *MembershipCache
+	rdb      *redis.Client
+	geo      *geoip.Locator // optional, locates stats reports by region
+}
+
+func NewCallService(callRepo *repository.CallRepository, convRepo *repository.ConversationRepository, members *MembershipCache, rdb *redis.Client) *CallService {
+	return &CallService{callRepo: callRepo, convRepo: convRepo, members: members, rdb: rdb}
+}
+
+// UseGeoIP locates call stats reports, for the quality report by region
+func (s *CallService) UseGeoIP(geo *geoip.Locator) {
+	s.geo = geo
+}
+
+// AuthorizeSignal checks a signaling event from one user to another, cleaning
+// the text of in-call chat. With a conversation ID both must be its members,
+// which is answered from the membership cache; without one they must share
+// some conversation. Chat and reactions need a call going on between them.
+func (s *CallService) AuthorizeSignal(ctx context.Context, eventType string, fromID uuid.UUID, signal *model.CallSignal) error {
+	if signal.To == uuid.Nil || signal.To == fromID {
+		return ErrCallTarget
+	}
+	switch eventType {
+	case model.WSEventCallScreenShare:
+		if signal.Sharing == nil {
+			return ErrCallScreenShare
+		}
+	case model.WSEventCallTrackState:
+		if (signal.Track != model.CallTrackAudio && signal.Track != model.CallTrackVideo) || signal.Muted == nil {
+			return ErrCallTrackState
+		}
+	case model.WSEventCallChat:
+		signal.Text = strings.TrimSpace(model.CleanContent(signal.Text))
+		if signal.Text == "" || utf8.RuneCountInString(signal.Text) > maxCallChatLength {
+			return ErrCallChat
+		}
+		if signal.Persist && signal.ConversationID == uuid.Nil {
+			return ErrCallChatPersist
+		}
+	case model.WSEventCallReaction:
+		if signal.Emoji == "" || len(signal.Emoji) > maxReactionBytes || strings.ContainsFunc(signal.Emoji, unicode.IsSpace) {
+			return ErrCallReaction
+		}
+	}
+
+	if err := s.checkShared(ctx, fromID, signal.To, signal.ConversationID); err != nil {
+		return err
+	}
+	switch eventType {
+	case model.WSEventCallOffer:
+		return s.allowOffer(ctx, fromID)
+	case model.WSEventCallChat, model.WSEventCallReaction:
+		call, err := s.currentCall(fromID, *signal)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && call.Status != model.CallStatusOngoing) {
+			return ErrNoOngoingCall
+		}
+		return err
+	}
+	return nil
+}
+
+// Record keeps the call record up to date with an authorized signaling event.
+// An offer starts a record, under a new call ID when the caller didn't pick
+// one; other events fill in the call ID of the call they belong to, when
+// there is one.
+func (s *CallService) Record(eventType string, fromID uuid.UUID, signal *model.CallSignal) error {
+	if eventType == model.WSEventCallOffer {
+		if signal.CallID == uuid.Nil {
+			signal.CallID = uuid.New()
+		}
+		call := &model.Call{
+			ID:       signal.CallID,
+			CallerID: fromID,
+			CalleeID: signal.To,
+			Type:     signal.CallType,
+			Status:   model.CallStatusRinging,
+		}
+		if signal.ConversationID != uuid.Nil {
+			call.ConversationID = &signal.ConversationID
+		}
+		return s.callRepo.Create(call)
+	}
+
+	call, err := s.currentCall(fromID, *signal)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	signal.CallID = call.ID
+
+	switch eventType {
+	case model.WSEventCallAnswer:
+		return s.callRepo.Answer(call.ID, time.Now())
+	case model.WSEventCallHangup:
+		return s.callRepo.End(call.ID, time.Now())
+	case model.WSEventCallRenegotiate:
+		return s.callRepo.CountRenegotiation(call.ID)
+	case model.WSEventCallScreenShare:
+		return s.callRepo.SetMediaState(call.ID, fromID, "screen_sharing", *signal.Sharing)
+	case model.WSEventCallTrackState:
+		return s.callRepo.SetMediaState(call.ID, fromID, signal.Track+"_muted", *signal.Muted)
+	}
+	return nil
+}
+
+// currentCall finds the call a signaling event belongs to: the one it names,
+// if it's between the two users, or else their latest call still going on
+func (s *CallService) currentCall(fromID uuid.UUID, signal model.CallSignal) (*model.Call, error) {
+	if signal.CallID == uuid.Nil {
+		return s.callRepo.FindCurrent(fromID, signal.To)
+	}
+	call, err := s.callRepo.FindByID(signal.CallID)
+	if err != nil {
+		return nil, err
+	}
+	between := (call.CallerID == fromID && call.CalleeID == signal.To) || (call.CallerID == signal.To && call.CalleeID == fromID)
+	if !between {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return call, nil
+}
+
+// checkShared makes sure the two users have a conversation in common
+func (s *CallService) checkShared(ctx context.Context, fromID, toID, convID uuid.UUID) error {
+	if convID == uuid.Nil {
+		shared, err := s.convRepo.ShareConversation(fromID, toID)
+		if err != nil {
+			return err
+		}
+		if !shared {
+			return ErrCallNotAllowed
+		}
+		return nil
+	}
+
+	for _, userID := range []uuid.UUID{fromID, toID} {
+		isMember, err := s.members.IsMember(ctx, convID, userID)
+		if err != nil {
+			return err
+		}
+		if !isMember {
+			return ErrCallNotAllowed
+		}
+	}
+	return nil
+}
+
+// allowOffer counts a call offer from the caller and refuses it over the
+// limit. When Redis is unavailable the limit is not enforced.
+func (s *CallService) allowOffer(ctx context.Context, callerID uuid.UUID) error {
+	key := callOffersKeyPrefix + callerID.String()
+	var count *redis.IntCmd
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		count = pipe.Incr(ctx, key)
+		pipe.ExpireNX(ctx, key, callOfferWindow)
+		return nil
+	})
+	if err != nil {
+		log.Printf("⚠️  Call offer rate limit unavailable: %v", err)
+		return nil
+	}
+	if count.Val() > callOfferLimit {
+		return ErrTooManyCalls
+	}
+	return nil
+}
+
+// participantCall returns a call the user took part in
+func (s *CallService) participantCall(callID, userID uuid.UUID) (*model.Call, error) {
+	call, err := s.callRepo.FindByID(callID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCallNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if call.CallerID != userID && call.CalleeID != userID {
+		return nil, ErrCallNotFound
+	}
+	return call, nil
+}
+
+// SubmitFeedback stores a participant's rating of a call; rating it again
+// replaces the earlier feedback
+func (s *CallService) SubmitFeedback(callID, userID uuid.UUID, req model.CallFeedbackRequest) (*model.CallFeedback, error) {
+	if _, err := s.participantCall(callID, userID); err != nil {
+		return nil, err
+	}
+	issues := []model.CallIssue{}
+	for _, issue := range req.Issues {
+		if !slices.Contains(model.CallIssues, issue) {
+			return nil, ErrCallIssue
+		}
+		if !slices.Contains(issues, issue) {
+			issues = append(issues, issue)
+		}
+	}
+
+	feedback := &model.CallFeedback{
+		CallID:  callID,
+		UserID:  userID,
+		Rating:  req.Rating,
+		Issues:  issues,
+		Comment: strings.TrimSpace(req.Comment),
+	}
+	if err := s.callRepo.SaveFeedback(feedback); err != nil {
+		return nil, err
+	}
+	return feedback, nil
+}
+
+// ReportStats stores a participant's WebRTC stats report, taken while the
+// call is going on and shortly after it ends (the final report)
+func (s *CallService) ReportStats(ctx context.Context, callID, userID uuid.UUID, ip string, req model.CallStatsRequest) error {
+	call, err := s.participantCall(callID, userID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if call.Status == model.CallStatusMissed || (call.EndedAt != nil && now.Sub(*call.EndedAt) > callStatsGrace) {
+		return ErrCallEnded
+	}
+
+	network := req.Network
+	if network == "" {
+		network = "other"
+	}
+	return s.callRepo.AddStatsSample(&model.CallStatsSample{
+		CallID:     callID,
+		UserID:     userID,
+		RecordedAt: now,
+		PacketLoss: *req.PacketLoss,
+		RTTMs:      *req.RTTMs,
+		JitterMs:   *req.JitterMs,
+		Network:    network,
+		Region:     s.region(ctx, callID, userID, ip),
+	})
+}
+
+// region returns the country of the reporter's IP, looked up once per call
+// and participant
+func (s *CallService) region(ctx context.Context, callID, userID uuid.UUID, ip string) string {
+	if s.geo == nil {
+		return ""
+	}
+	key := callRegionKeyPrefix + callID.String() + ":" + userID.String()
+	if region, err := s.rdb.Get(ctx, key).Result(); err == nil {
+		return region
+	}
+
+	location, err := s.geo.Lookup(ctx, ip)
+	if err != nil {
+		log.Printf("⚠️  GeoIP lookup for %s failed: %v", ip, err)
+		return ""
+	}
+	s.rdb.Set(ctx, key, location.CountryCode, time.Hour)
+	return location.CountryCode
+}
+
+// QualityReport returns call quality by network and region over a range of days
+func (s *CallService) QualityReport(req model.CallQualityRequest) (*model.CallQualityReport, error) {
+	from, to, _, err := statsRange(req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.callRepo.QualityByNetworkRegion(from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	if rows == nil {
+		rows = []model.CallQualityRow{}
+	}
+	return &model.CallQualityReport{
+		From: from.Format(statsDayFormat),
+		To:   to.Format(statsDayFormat),
+		Rows: rows,
+	}, nil
+}
@@ -3,33 +3,91 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/quocanhngo/gotalk/internal/model"
 	"github.com/quocanhngo/gotalk/internal/repository"
-	"github.com/quocanhngo/gotalk/pkg/notification"
+	"github.com/quocanhngo/gotalk/internal/ws"
 	"gorm.io/gorm"
 )
 
+// memberPreviewSize is how many members conversation payloads carry (plus the
+// viewer); clients page through the rest with ListMembers
+const memberPreviewSize = 20
+
+const (
+	conversationPurgeInterval = time.Hour
+	conversationPurgeBatch    = 100
+)
+
+// maxCustomRoles caps the roles a group can add to admin and member
+const maxCustomRoles = 20
+
+// roleNamePattern is what role names look like
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,19}$`)
+
+// ChatLimits caps what a single user can create; 0 means unlimited
+type ChatLimits struct {
+	GroupMembers  int // members of a group, including its creator
+	GroupsPerUser int // groups a user has created and not deleted
+	DirectsPerDay int // new direct conversations a user can start in 24 hours
+	MessageLength int // characters of a message's text
+	Pins          int // pinned messages of a conversation
+}
+
 // ChatService handles chat business logic
 type ChatService struct {
 	convRepo     *repository.ConversationRepository
+	members      *MembershipCache
 	msgRepo      *repository.MessageRepository
+	pinRepo      *repository.PinRepository
 	userRepo     *repository.UserRepository
-	notifService *notification.NotificationService
+	notifCenter  *NotificationCenterService
+	mediaService *MediaService
+	blobService  *BlobService
+	outbox       *OutboxService
+	stats        *AnalyticsService
+	hub          *ws.Hub
+	retention    time.Duration // deleted conversations can be restored this long, then they're purged
+	limits       ChatLimits
 }
 
 func NewChatService(
 	convRepo *repository.ConversationRepository,
+	members *MembershipCache,
 	msgRepo *repository.MessageRepository,
+	pinRepo *repository.PinRepository,
 	userRepo *repository.UserRepository,
-	notifService *notification.NotificationService,
+	notifCenter *NotificationCenterService,
+	mediaService *MediaService,
+	blobService *BlobService,
+	outbox *OutboxService,
+	stats *AnalyticsService,
+	hub *ws.Hub,
+	retention time.Duration,
+	limits ChatLimits,
 ) *ChatService {
 	return &ChatService{
 		convRepo:     convRepo,
+		members:      members,
 		msgRepo:      msgRepo,
+		pinRepo:      pinRepo,
 		userRepo:     userRepo,
-		notifService: notifService,
+		notifCenter:  notifCenter,
+		mediaService: mediaService,
+		blobService:  blobService,
+		outbox:       outbox,
+		stats:        stats,
+		hub:          hub,
+		retention:    retention,
+		limits:       limits,
 	}
 }
 
@@ -38,7 +96,7 @@ func (s *ChatService) CreateConversation(creatorID uuid.UUID, req model.CreateCo
 	// For private conversations, check if one already exists
 	if req.Type == model.ConversationTypePrivate {
 		if len(req.MemberIDs) != 1 {
-			return nil, errors.New("private conversation requires exactly 1 other member")
+			return nil, ErrInvalidMembers
 		}
 
 		existingConv, err := s.convRepo.FindPrivateConversation(creatorID, req.MemberIDs[0])
@@ -66,10 +124,12 @@ func (s *ChatService) CreateConversation(creatorID uuid.UUID, req model.CreateCo
 	}
 
 	// Add other members
+	added := map[uuid.UUID]bool{creatorID: true}
 	for _, memberID := range req.MemberIDs {
-		if memberID == creatorID {
-			continue // Skip if creator is in the list
+		if added[memberID] {
+			continue // Skip the creator and duplicates
 		}
+		added[memberID] = true
 		members = append(members, model.ConversationMember{
 			UserID: memberID,
 			Role:   model.MemberRoleMember,
@@ -77,13 +137,79 @@ func (s *ChatService) CreateConversation(creatorID uuid.UUID, req model.CreateCo
 	}
 
 	conv.Members = members
+	if err := s.checkCreateLimits(creatorID, conv); err != nil {
+		return nil, err
+	}
 
 	if err := s.convRepo.Create(conv); err != nil {
 		return nil, errors.New("failed to create conversation")
 	}
 
+	if conv.Type == model.ConversationTypeGroup {
+		go s.notifyGroupInvite(conv, creatorID)
+	}
+
 	// Reload with relations
-	return s.convRepo.FindByID(conv.ID)
+	created, err := s.convRepo.FindByIDWithPreview(conv.ID, creatorID, memberPreviewSize)
+	if err != nil {
+		return nil, err
+	}
+	contacts, _ := contactSet(s.userRepo, creatorID)
+	applyConversationPrivacy(created, creatorID, contacts)
+	return created, nil
+}
+
+// checkCreateLimits enforces the per-user limits on a conversation about to be created
+func (s *ChatService) checkCreateLimits(creatorID uuid.UUID, conv *model.Conversation) error {
+	if conv.Type == model.ConversationTypeGroup {
+		if limit := s.limits.GroupMembers; limit > 0 && len(conv.Members) > limit {
+			return ErrGroupTooLarge.WithMessage(fmt.Sprintf("a group can have at most %d members", limit))
+		}
+		if limit := s.limits.GroupsPerUser; limit > 0 {
+			created, err := s.convRepo.CountCreated(creatorID, model.ConversationTypeGroup, time.Time{})
+			if err != nil {
+				return err
+			}
+			if created >= int64(limit) {
+				return ErrGroupLimitReached
+			}
+		}
+		return nil
+	}
+
+	if limit := s.limits.DirectsPerDay; limit > 0 {
+		created, err := s.convRepo.CountCreated(creatorID, model.ConversationTypePrivate, time.Now().Add(-24*time.Hour))
+		if err != nil {
+			return err
+		}
+		if created >= int64(limit) {
+			return ErrDirectLimitReached
+		}
+	}
+	return nil
+}
+
+// DirectOnBehalf finds or starts userID's private conversation with
+// partnerID for messages the server sends in their name, such as onboarding
+// welcomes. The creation limits are for what users do themselves: they're
+// skipped, and the conversation has no creator so it doesn't count toward them.
+func (s *ChatService) DirectOnBehalf(userID, partnerID uuid.UUID) (*model.Conversation, error) {
+	conv, err := s.convRepo.FindPrivateConversation(userID, partnerID)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return conv, err
+	}
+
+	conv = &model.Conversation{
+		Type: model.ConversationTypePrivate,
+		Members: []model.ConversationMember{
+			{UserID: userID, Role: model.MemberRoleAdmin},
+			{UserID: partnerID, Role: model.MemberRoleMember},
+		},
+	}
+	if err := s.convRepo.Create(conv); err != nil {
+		return nil, err
+	}
+	return conv, nil
 }
 
 // GetOrCreateDirect finds or creates a private conversation
@@ -95,13 +221,21 @@ func (s *ChatService) GetOrCreateDirect(myID, partnerID uuid.UUID) (*model.Direc
 		_ = s.convRepo.UpdateLastRead(conv.ID, myID)
 
 		// Get messages
-		msgs, _ := s.msgRepo.GetConversationMessages(conv.ID, nil, 50)
+		msgs, _ := s.msgRepo.GetConversationMessages(conv.ID, myID, nil, 50)
 
 		// Count unread
 		unreadCount, _ := s.msgRepo.CountUnread(conv.ID, myID)
 
 		// Get last message
-		lastMsg, _ := s.msgRepo.GetLastMessage(conv.ID)
+		lastMsg, _ := s.msgRepo.GetLastMessage(conv.ID, myID)
+		if lastMsg != nil {
+			lastMsg.Collapse()
+		}
+
+		conv.LastMessage = lastMsg
+		contacts, _ := contactSet(s.userRepo, myID)
+		applyConversationPrivacy(conv, myID, contacts)
+		applyMessagesPrivacy(msgs, myID, contacts)
 
 		// Populate name/avatar for private chat
 		if conv.Type == model.ConversationTypePrivate {
@@ -119,7 +253,6 @@ func (s *ChatService) GetOrCreateDirect(myID, partnerID uuid.UUID) (*model.Direc
 			Conversation: *conv,
 			UnreadCount:  int(unreadCount),
 		}
-		convResp.LastMessage = lastMsg
 
 		return &model.DirectConversationResponse{
 			Conversation: convResp,
@@ -154,15 +287,34 @@ func (s *ChatService) GetOrCreateDirect(myID, partnerID uuid.UUID) (*model.Direc
 
 // GetConversations returns all conversations for a user
 func (s *ChatService) GetConversations(userID uuid.UUID) ([]model.ConversationResponse, error) {
-	conversations, err := s.convRepo.GetUserConversations(userID)
+	conversations, err := s.convRepo.GetUserConversations(userID, memberPreviewSize)
+	if err != nil {
+		return nil, err
+	}
+
+	contacts, err := contactSet(s.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	convIDs := make([]uuid.UUID, len(conversations))
+	for i := range conversations {
+		convIDs[i] = conversations[i].ID
+	}
+	announcements, err := s.pinRepo.FindAnnouncements(convIDs)
 	if err != nil {
 		return nil, err
 	}
 
 	result := []model.ConversationResponse{}
 	for i := range conversations {
+		conversations[i].Announcement = announcements[conversations[i].ID]
+
 		// Get last message for each conversation
-		lastMsg, _ := s.msgRepo.GetLastMessage(conversations[i].ID)
+		lastMsg, _ := s.msgRepo.GetLastMessage(conversations[i].ID, userID)
+		if lastMsg != nil {
+			lastMsg.Collapse()
+		}
 		conversations[i].LastMessage = lastMsg
 
 		// Count unread messages
@@ -170,6 +322,7 @@ func (s *ChatService) GetConversations(userID uuid.UUID) ([]model.ConversationRe
 
 		// Populate name/avatar for private chat
 		conv := conversations[i]
+		applyConversationPrivacy(&conv, userID, contacts)
 		if conv.Type == model.ConversationTypePrivate {
 			for _, m := range conv.Members {
 				if m.UserID != userID {
@@ -189,120 +342,1274 @@ func (s *ChatService) GetConversations(userID uuid.UUID) ([]model.ConversationRe
 	return result, nil
 }
 
+// Bootstrap returns what a client needs to render right after connecting: the
+// conversation list with unread counts, online contacts and unread notifications
+func (s *ChatService) Bootstrap(userID uuid.UUID) (*model.BootstrapEvent, error) {
+	conversations, err := s.GetConversations(userID)
+	if err != nil {
+		return nil, err
+	}
+	onlineContactIDs, err := s.userRepo.FindOnlineContactIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	unreadNotifications, err := s.notifCenter.UnreadCount(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if onlineContactIDs == nil {
+		onlineContactIDs = []uuid.UUID{}
+	}
+	return &model.BootstrapEvent{
+		Conversations:       conversations,
+		OnlineContactIDs:    onlineContactIDs,
+		UnreadNotifications: unreadNotifications,
+	}, nil
+}
+
 // GetConversation returns a specific conversation
 func (s *ChatService) GetConversation(convID, userID uuid.UUID) (*model.Conversation, error) {
 	// Check membership
-	isMember, err := s.convRepo.IsMember(convID, userID)
+	isMember, err := s.members.IsMember(context.Background(), convID, userID)
 	if err != nil {
 		return nil, err
 	}
 	if !isMember {
-		return nil, errors.New("you are not a member of this conversation")
+		return nil, ErrNotMember
 	}
 
-	return s.convRepo.FindByID(convID)
+	conv, err := s.convRepo.FindByIDWithPreview(convID, userID, memberPreviewSize)
+	if err != nil {
+		return nil, err
+	}
+	announcements, err := s.pinRepo.FindAnnouncements([]uuid.UUID{convID})
+	if err != nil {
+		return nil, err
+	}
+	conv.Announcement = announcements[convID]
+	contacts, err := contactSet(s.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	applyConversationPrivacy(conv, userID, contacts)
+	return conv, nil
 }
 
-// SendMessage sends a message to a conversation
-func (s *ChatService) SendMessage(senderID, convID uuid.UUID, req model.SendMessageRequest) (*model.Message, error) {
-	// Check membership
-	isMember, err := s.convRepo.IsMember(convID, senderID)
+// DeleteConversation deletes a conversation for all of its members. Either
+// participant can delete a private conversation; only admins can delete a
+// group. It can be restored until it's purged.
+func (s *ChatService) DeleteConversation(convID, userID uuid.UUID) error {
+	conv, err := s.convRepo.FindByIDWithPreview(convID, userID, 0)
+	if err != nil {
+		return err
+	}
+	if err := s.checkCanManage(conv, userID); err != nil {
+		return err
+	}
+
+	memberIDs, err := s.convRepo.GetMemberIDs(convID)
 	if err != nil {
+		return err
+	}
+	if err := s.convRepo.Delete(convID); err != nil {
+		return err
+	}
+	s.members.Invalidate(context.Background(), convID)
+
+	s.hub.SendToUsers(memberIDs, &model.WSEvent{
+		Type:    model.WSEventConversationDeleted,
+		Payload: model.ConversationChangeEvent{ConversationID: convID, UserID: userID},
+	})
+	return nil
+}
+
+// RestoreConversation brings back a deleted conversation, with its history,
+// for everyone. The same members who could delete it can restore it.
+func (s *ChatService) RestoreConversation(convID, userID uuid.UUID) (*model.Conversation, error) {
+	conv, err := s.convRepo.FindDeleted(convID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrConversationGone
+		}
 		return nil, err
 	}
-	if !isMember {
-		return nil, errors.New("you are not a member of this conversation")
+	if err := s.checkCanManage(conv, userID); err != nil {
+		return nil, err
 	}
 
-	msgType := req.Type
-	if msgType == "" {
-		msgType = model.MessageTypeText
-		// Auto-detect type from attachments
-		if len(req.Attachments) > 0 {
-			msgType = model.MessageType(req.Attachments[0].Type)
-		} else if req.FileURL != "" {
-			msgType = model.MessageTypeFile
+	restored, err := s.convRepo.Restore(convID, time.Now().Add(-s.retention))
+	if err != nil {
+		return nil, err
+	}
+	if !restored {
+		return nil, ErrConversationGone
+	}
+	s.members.Invalidate(context.Background(), convID)
+
+	memberIDs, err := s.convRepo.GetMemberIDs(convID)
+	if err != nil {
+		return nil, err
+	}
+	s.hub.SendToUsers(memberIDs, &model.WSEvent{
+		Type:    model.WSEventConversationRestored,
+		Payload: model.ConversationChangeEvent{ConversationID: convID, UserID: userID},
+	})
+	return s.GetConversation(convID, userID)
+}
+
+// SetFrozen freezes a group so only members who manage its settings can post
+// (announcements), or unfreezes it. Doing either needs manage_settings;
+// members get a conversation_frozen event.
+func (s *ChatService) SetFrozen(convID, userID uuid.UUID, frozen bool) (*model.Conversation, error) {
+	conv, err := s.convRepo.FindByIDWithPreview(convID, userID, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Can(userID, convID, model.PermissionManageSettings); err != nil {
+		return nil, err
+	}
+	if conv.Type != model.ConversationTypeGroup {
+		return nil, ErrFreezeGroupOnly
+	}
+
+	if conv.Frozen != frozen {
+		if err := s.convRepo.SetFrozen(convID, frozen); err != nil {
+			return nil, err
+		}
+		memberIDs, err := s.members.MemberIDs(context.Background(), convID)
+		if err != nil {
+			return nil, err
 		}
+		s.hub.SendToUsers(memberIDs, &model.WSEvent{
+			Type:    model.WSEventConversationFrozen,
+			Payload: model.ConversationFrozenEvent{ConversationID: convID, Frozen: frozen, UserID: userID},
+		})
 	}
+	return s.GetConversation(convID, userID)
+}
 
-	msg := &model.Message{
-		ConversationID: convID,
-		SenderID:       senderID,
-		Content:        req.Content,
-		Type:           msgType,
-		Status:         model.MessageStatusSent,
-		FileURL:        req.FileURL,
-		FileName:       req.FileName,
-		FileSize:       req.FileSize,
-		ReplyToID:      req.ReplyToID,
+// checkCanManage allows either participant of a private conversation and the
+// members of a group whose role manages its settings; directory-managed groups
+// are deleted through the directory
+func (s *ChatService) checkCanManage(conv *model.Conversation, userID uuid.UUID) error {
+	if err := s.Can(userID, conv.ID, model.PermissionManageSettings); err != nil {
+		return err
+	}
+	if conv.Type == model.ConversationTypeGroup && conv.Provisioned {
+		return ErrGroupProvisioned
 	}
+	return nil
+}
 
-	if err := s.msgRepo.Create(msg); err != nil {
-		return nil, errors.New("failed to send message")
+// Can checks that the user's role in the conversation allows the action:
+// ErrNotMember if they aren't a member, ErrConversationFrozen if they can't
+// post in a frozen group, ErrNotPermitted otherwise. Every permission check
+// in a conversation goes through it.
+func (s *ChatService) Can(userID, convID uuid.UUID, action model.Permission) error {
+	permissions, err := s.convRepo.GetMemberPermissions(convID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotMember
+		}
+		return err
+	}
+	if permissions.Allows(action) {
+		return nil
+	}
+	if action == model.PermissionSendMessages && permissions.Frozen {
+		return ErrConversationFrozen
+	}
+	return ErrNotPermitted
+}
+
+// ListRoles returns a group's roles: admin, member and its custom roles
+func (s *ChatService) ListRoles(convID, userID uuid.UUID) ([]model.ConversationRole, error) {
+	if _, err := s.groupForRoles(convID, userID); err != nil {
+		return nil, err
+	}
+	stored, err := s.convRepo.ListRoles(convID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := []model.ConversationRole{
+		{Name: model.MemberRoleAdmin, Permissions: model.Permissions, BuiltIn: true},
+		{Name: model.MemberRoleMember, Permissions: model.DefaultMemberPermissions, BuiltIn: true},
+	}
+	for _, role := range stored {
+		if role.Name == model.MemberRoleMember {
+			role.BuiltIn = true
+			roles[1] = role
+			continue
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+// SaveRole creates a custom role of a group or changes a role's permissions.
+// It needs manage_settings; the admin role can't be changed.
+func (s *ChatService) SaveRole(convID, userID uuid.UUID, name model.MemberRole, req model.SaveRoleRequest) (*model.ConversationRole, error) {
+	if err := s.checkCanManageRoles(convID, userID, name); err != nil {
+		return nil, err
 	}
 
-	// Save attachments if any
-	if len(req.Attachments) > 0 {
-		for _, att := range req.Attachments {
-			attachment := model.MessageAttachment{
-				MessageID: msg.ID,
-				Type:      att.Type,
-				URL:       att.URL,
-				FileName:  att.FileName,
-				FileSize:  att.FileSize,
-				MimeType:  att.MimeType,
+	if name != model.MemberRoleMember {
+		if _, err := s.convRepo.FindRole(convID, name); errors.Is(err, gorm.ErrRecordNotFound) {
+			roles, err := s.convRepo.ListRoles(convID)
+			if err != nil {
+				return nil, err
+			}
+			custom := 0
+			for _, role := range roles {
+				if role.Name != model.MemberRoleMember {
+					custom++
+				}
+			}
+			if custom >= maxCustomRoles {
+				return nil, ErrRoleLimitReached
 			}
-			s.msgRepo.CreateAttachment(&attachment)
+		} else if err != nil {
+			return nil, err
 		}
 	}
 
-	// Update conversation's updated_at for sorting
-	_ = s.convRepo.TouchUpdatedAt(convID)
+	role := &model.ConversationRole{
+		ConversationID: convID,
+		Name:           name,
+		Permissions:    normalizePermissions(req.Permissions),
+		BuiltIn:        name == model.MemberRoleMember,
+	}
+	if err := s.convRepo.SaveRole(role); err != nil {
+		return nil, err
+	}
+	s.broadcastRoles(convID, &model.WSEvent{
+		Type:    model.WSEventRolesChanged,
+		Payload: model.RolesChangedEvent{ConversationID: convID, Role: name, UserID: userID},
+	})
+	return role, nil
+}
 
-	// Send Push Notification
-	go func() {
-		ctx := context.Background()
-		sender, err := s.userRepo.FindByID(senderID)
-		if err != nil {
-			return
+// DeleteRole deletes a custom role of a group, whose members go back to the
+// member role, or resets the member role to its default permissions
+func (s *ChatService) DeleteRole(convID, userID uuid.UUID, name model.MemberRole) error {
+	if err := s.checkCanManageRoles(convID, userID, name); err != nil {
+		return err
+	}
+
+	err := s.convRepo.DeleteRole(convID, name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if name == model.MemberRoleMember {
+			return nil // already the defaults
 		}
+		return ErrRoleNotFound
+	}
+	if err != nil {
+		return err
+	}
+	s.broadcastRoles(convID, &model.WSEvent{
+		Type:    model.WSEventRolesChanged,
+		Payload: model.RolesChangedEvent{ConversationID: convID, Role: name, UserID: userID},
+	})
+	return nil
+}
 
-		memberIDs, _ := s.convRepo.GetMemberIDs(convID)
-		for _, memberID := range memberIDs {
-			if memberID != senderID {
-				_ = s.notifService.SendMessageNotification(ctx, memberID, sender.Name, req.Content, convID)
+// SetMemberRole gives a member of a group another role. It needs
+// manage_settings, and only admins can make someone an admin or change an
+// admin's role. A group keeps at least one admin.
+func (s *ChatService) SetMemberRole(convID, userID, memberID uuid.UUID, role model.MemberRole) error {
+	if _, err := s.groupForRoles(convID, userID); err != nil {
+		return err
+	}
+	if err := s.Can(userID, convID, model.PermissionManageSettings); err != nil {
+		return err
+	}
+	member, err := s.convRepo.GetMember(convID, memberID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrMemberNotFound
+		}
+		return err
+	}
+	if role != model.MemberRoleAdmin && role != model.MemberRoleMember {
+		if _, err := s.convRepo.FindRole(convID, role); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRoleNotFound
 			}
+			return err
 		}
-	}()
+	}
+	if member.Role == role {
+		return nil
+	}
 
-	// Reload with sender info and attachments
-	return s.msgRepo.FindByID(msg.ID)
+	if role == model.MemberRoleAdmin || member.Role == model.MemberRoleAdmin {
+		actor, err := s.convRepo.GetMember(convID, userID)
+		if err != nil {
+			return err
+		}
+		if actor.Role != model.MemberRoleAdmin {
+			return ErrNotGroupAdmin
+		}
+	}
+	if member.Role == model.MemberRoleAdmin {
+		admins, err := s.convRepo.CountMembersWithRole(convID, model.MemberRoleAdmin)
+		if err != nil {
+			return err
+		}
+		if admins <= 1 {
+			return ErrLastAdmin
+		}
+	}
+
+	if err := s.convRepo.SetMemberRole(convID, memberID, role); err != nil {
+		return err
+	}
+	s.broadcastRoles(convID, &model.WSEvent{
+		Type:    model.WSEventMemberRoleChanged,
+		Payload: model.MemberRoleChangedEvent{ConversationID: convID, MemberID: memberID, Role: role, UserID: userID},
+	})
+	return nil
 }
 
-// GetMessages returns paginated messages for a conversation
-func (s *ChatService) GetMessages(convID, userID uuid.UUID, before *uuid.UUID, limit int) ([]model.Message, error) {
-	// Check membership
-	isMember, err := s.convRepo.IsMember(convID, userID)
+// AddMembers adds users to a group, which needs add_members. Members get a
+// member_added event, the added users a notification, and the group's
+// onboarding rules run for each of them in the outbox worker.
+func (s *ChatService) AddMembers(convID, userID uuid.UUID, memberIDs []uuid.UUID) (*model.Conversation, error) {
+	conv, err := s.convRepo.FindByIDWithPreview(convID, userID, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Can(userID, convID, model.PermissionAddMembers); err != nil {
+		return nil, err
+	}
+	if conv.Type != model.ConversationTypeGroup {
+		return nil, ErrAddMembersGroupOnly
+	}
+	if conv.Provisioned {
+		return nil, ErrGroupProvisioned
+	}
+
+	current, err := s.convRepo.GetMemberIDs(convID)
+	if err != nil {
+		return nil, err
+	}
+	var added []uuid.UUID
+	for _, memberID := range memberIDs {
+		if !slices.Contains(current, memberID) && !slices.Contains(added, memberID) {
+			added = append(added, memberID)
+		}
+	}
+	if len(added) == 0 {
+		return s.GetConversation(convID, userID)
+	}
+	if limit := s.limits.GroupMembers; limit > 0 && len(current)+len(added) > limit {
+		return nil, ErrGroupTooLarge.WithMessage(fmt.Sprintf("a group can have at most %d members", limit))
+	}
+	users, err := s.userRepo.FindByIDs(added)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) != len(added) {
+		return nil, ErrUserNotFound
+	}
+
+	if err := s.convRepo.AddMembersWithOutbox(convID, added, model.MemberRoleMember, s.outbox.MemberEvents(convID, userID, added)); err != nil {
+		return nil, err
+	}
+	s.outbox.Wake()
+	s.members.Invalidate(context.Background(), convID)
+
+	s.hub.SendToUsers(append(current, added...), &model.WSEvent{
+		Type:    model.WSEventMemberAdded,
+		Payload: model.MemberAddedEvent{ConversationID: convID, MemberIDs: added, UserID: userID},
+	})
+	invited := *conv
+	invited.Members = make([]model.ConversationMember, len(added))
+	for i, memberID := range added {
+		invited.Members[i] = model.ConversationMember{UserID: memberID}
+	}
+	go s.notifyGroupInvite(&invited, userID)
+
+	return s.GetConversation(convID, userID)
+}
+
+// groupForRoles returns the conversation if it's a group the user is a member of
+func (s *ChatService) groupForRoles(convID, userID uuid.UUID) (*model.Conversation, error) {
+	isMember, err := s.members.IsMember(context.Background(), convID, userID)
 	if err != nil {
 		return nil, err
 	}
 	if !isMember {
-		return nil, errors.New("you are not a member of this conversation")
+		return nil, ErrNotMember
+	}
+	conv, err := s.convRepo.FindByID(convID)
+	if err != nil {
+		return nil, err
+	}
+	if conv.Type != model.ConversationTypeGroup {
+		return nil, ErrRolesGroupOnly
 	}
+	return conv, nil
+}
 
-	if limit <= 0 || limit > 100 {
-		limit = 50
+// checkCanManageRoles allows members who manage a group's settings to change
+// any role but admin
+func (s *ChatService) checkCanManageRoles(convID, userID uuid.UUID, name model.MemberRole) error {
+	if _, err := s.groupForRoles(convID, userID); err != nil {
+		return err
 	}
+	if err := s.Can(userID, convID, model.PermissionManageSettings); err != nil {
+		return err
+	}
+	if name == model.MemberRoleAdmin {
+		return ErrAdminRoleFixed
+	}
+	if !roleNamePattern.MatchString(string(name)) {
+		return ErrInvalidRoleName
+	}
+	return nil
+}
 
-	return s.msgRepo.GetConversationMessages(convID, before, limit)
+// broadcastRoles sends a role change to the group's members
+func (s *ChatService) broadcastRoles(convID uuid.UUID, event *model.WSEvent) {
+	memberIDs, err := s.members.MemberIDs(context.Background(), convID)
+	if err != nil {
+		log.Printf("⚠️ Failed to load members of %s for a role change: %v", convID, err)
+		return
+	}
+	s.hub.SendToUsers(memberIDs, event)
 }
 
-// MarkMessagesAsRead updates the last_read_at timestamp
-func (s *ChatService) MarkMessagesAsRead(convID, userID uuid.UUID) error {
-	return s.convRepo.UpdateLastRead(convID, userID)
+// normalizePermissions removes duplicates and orders permissions as documented
+func normalizePermissions(permissions []model.Permission) []model.Permission {
+	normalized := []model.Permission{}
+	for _, permission := range model.Permissions {
+		if slices.Contains(permissions, permission) {
+			normalized = append(normalized, permission)
+		}
+	}
+	return normalized
 }
 
-// GetConversationMemberIDs returns all member IDs for a conversation
-func (s *ChatService) GetConversationMemberIDs(convID uuid.UUID) ([]uuid.UUID, error) {
-	return s.convRepo.GetMemberIDs(convID)
+// ListPins returns a conversation's pinned messages, top first
+func (s *ChatService) ListPins(convID, userID uuid.UUID) ([]model.PinnedMessage, error) {
+	isMember, err := s.members.IsMember(context.Background(), convID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotMember
+	}
+
+	pins, err := s.pinRepo.List(convID)
+	if err != nil {
+		return nil, err
+	}
+	contacts, err := contactSet(s.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	visible := pins[:0]
+	for _, pin := range pins {
+		if pin.Message.ID == uuid.Nil {
+			continue // the message was deleted
+		}
+		pin.Message.Sender.ApplyPrivacy(userID, contacts[pin.Message.SenderID])
+		visible = append(visible, pin)
+	}
+	return visible, nil
+}
+
+// PinMessage pins a message on top of a conversation's pins, which needs the
+// pin permission, or changes whether a pinned message is the announcement.
+// Announcements also need manage_settings. Members get a pins_changed event.
+func (s *ChatService) PinMessage(convID, userID uuid.UUID, req model.PinMessageRequest) (*model.PinnedMessage, error) {
+	if err := s.Can(userID, convID, model.PermissionPin); err != nil {
+		return nil, err
+	}
+	msg, err := s.msgRepo.FindByID(req.MessageID)
+	if err != nil || msg.ConversationID != convID {
+		if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	pin, err := s.pinRepo.Find(convID, req.MessageID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if req.Announcement || (pin != nil && pin.Announcement) {
+		if err := s.Can(userID, convID, model.PermissionManageSettings); err != nil {
+			return nil, err
+		}
+	}
+
+	if pin != nil {
+		if pin.Announcement != req.Announcement {
+			if err := s.pinRepo.SetAnnouncement(convID, req.MessageID, req.Announcement); err != nil {
+				return nil, err
+			}
+			pin.Announcement = req.Announcement
+			s.broadcastPins(convID, userID)
+		}
+	} else {
+		pin = &model.PinnedMessage{
+			ConversationID: convID,
+			MessageID:      req.MessageID,
+			Announcement:   req.Announcement,
+			PinnedBy:       userID,
+		}
+		if err := s.pinRepo.Pin(pin, s.limits.Pins); err != nil {
+			if errors.Is(err, repository.ErrPinLimit) {
+				return nil, ErrPinLimitReached.WithDetails(map[string]int{"max_pins": s.limits.Pins})
+			}
+			return nil, err
+		}
+		s.broadcastPins(convID, userID)
+	}
+
+	msg.Sender.ApplyPrivacy(uuid.Nil, false)
+	pin.Message = *msg
+	return pin, nil
+}
+
+// UnpinMessage removes a message's pin; needs the pin permission, and
+// manage_settings for the announcement
+func (s *ChatService) UnpinMessage(convID, userID, messageID uuid.UUID) error {
+	if err := s.Can(userID, convID, model.PermissionPin); err != nil {
+		return err
+	}
+	pin, err := s.pinRepo.Find(convID, messageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPinNotFound
+		}
+		return err
+	}
+	if pin.Announcement {
+		if err := s.Can(userID, convID, model.PermissionManageSettings); err != nil {
+			return err
+		}
+	}
+
+	if err := s.pinRepo.Unpin(convID, messageID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPinNotFound
+		}
+		return err
+	}
+	s.broadcastPins(convID, userID)
+	return nil
+}
+
+// ReorderPins orders a conversation's pins as given, top first; needs the pin
+// permission. messageIDs must list every pinned message once.
+func (s *ChatService) ReorderPins(convID, userID uuid.UUID, messageIDs []uuid.UUID) ([]model.PinnedMessage, error) {
+	if err := s.Can(userID, convID, model.PermissionPin); err != nil {
+		return nil, err
+	}
+	pins, err := s.ListPins(convID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(messageIDs) != len(pins) {
+		return nil, ErrPinOrder
+	}
+	for _, pin := range pins {
+		if !slices.Contains(messageIDs, pin.MessageID) {
+			return nil, ErrPinOrder
+		}
+	}
+
+	if err := s.pinRepo.Reorder(convID, messageIDs); err != nil {
+		return nil, err
+	}
+	s.broadcastPins(convID, userID)
+	return s.ListPins(convID, userID)
+}
+
+// broadcastPins sends a conversation's pins to its members after a change
+func (s *ChatService) broadcastPins(convID, userID uuid.UUID) {
+	pins, err := s.pinRepo.List(convID)
+	if err != nil {
+		log.Printf("⚠️  Failed to load pins of %s for a pins_changed event: %v", convID, err)
+		return
+	}
+	event := model.PinsChangedEvent{ConversationID: convID, MessageIDs: []uuid.UUID{}, UserID: userID}
+	for _, pin := range pins {
+		if pin.Message.ID == uuid.Nil {
+			continue
+		}
+		event.MessageIDs = append(event.MessageIDs, pin.MessageID)
+		if pin.Announcement {
+			event.AnnouncementID = &pin.MessageID
+		}
+	}
+
+	memberIDs, err := s.members.MemberIDs(context.Background(), convID)
+	if err != nil {
+		log.Printf("⚠️  Failed to load members of %s for a pins_changed event: %v", convID, err)
+		return
+	}
+	s.hub.SendToUsers(memberIDs, &model.WSEvent{Type: model.WSEventPinsChanged, Payload: event})
+}
+
+// RunPurge permanently deletes conversations that were deleted longer ago than
+// the retention, with their messages and attachments, blocking until ctx is
+// cancelled
+func (s *ChatService) RunPurge(ctx context.Context) {
+	ticker := time.NewTicker(conversationPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.purgeDeleted()
+			if err != nil {
+				log.Printf("⚠️  Conversation purge failed: %v", err)
+			} else if n > 0 {
+				log.Printf("🧹 Purged %d deleted conversations", n)
+			}
+		}
+	}
+}
+
+// purgeDeleted purges one batch of expired conversations
+func (s *ChatService) purgeDeleted() (int, error) {
+	ids, err := s.convRepo.FindDeletedBefore(time.Now().Add(-s.retention), conversationPurgeBatch)
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, id := range ids {
+		if err := s.convRepo.Purge(id); err != nil {
+			log.Printf("⚠️  Failed to purge conversation %s: %v", id, err)
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// ListMembers returns a page of a conversation's members in join order, after
+// the member with user ID `after`, optionally filtered by name or handle
+func (s *ChatService) ListMembers(convID, userID uuid.UUID, query string, after *uuid.UUID, limit int) ([]model.ConversationMember, error) {
+	isMember, err := s.members.IsMember(context.Background(), convID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotMember
+	}
+
+	members, err := s.convRepo.ListMembers(convID, strings.TrimSpace(query), after, MemberPageLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	contacts, err := contactSet(s.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range members {
+		members[i].User.ApplyPrivacy(userID, contacts[members[i].UserID])
+	}
+	return members, nil
+}
+
+// SendMessage sends a message to a conversation
+func (s *ChatService) SendMessage(senderID, convID uuid.UUID, req model.SendMessageRequest) (*model.Message, error) {
+	// Check membership
+	isMember, err := s.members.IsMember(context.Background(), convID, senderID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotMember
+	}
+	if err := s.Can(senderID, convID, model.PermissionSendMessages); err != nil {
+		return nil, err
+	}
+
+	content := model.CleanContent(req.Content)
+	if strings.TrimSpace(content) == "" {
+		content = ""
+		if len(req.Attachments) == 0 && req.FileURL == "" {
+			return nil, ErrMessageEmpty
+		}
+	}
+	if limit := s.limits.MessageLength; limit > 0 && utf8.RuneCountInString(content) > limit {
+		return nil, ErrMessageTooLong.
+			WithMessage(fmt.Sprintf("the message is longer than %d characters", limit)).
+			WithDetails(map[string]int{"max_length": limit})
+	}
+	if err := req.Entities.Normalize(content); err != nil {
+		return nil, ErrInvalidEntities.WithMessage(err.Error())
+	}
+
+	if req.Type == model.MessageTypeSystem {
+		return nil, ErrSystemMessage
+	}
+	language := ""
+	if req.Type == model.MessageTypeCode {
+		if len(req.Entities) > 0 || len(req.Attachments) > 0 || req.FileURL != "" {
+			return nil, ErrCodeMessage
+		}
+		language = strings.ToLower(req.Language)
+		if language == "" {
+			language = "plaintext"
+		}
+		if !slices.Contains(model.CodeLanguages, language) {
+			return nil, ErrCodeLanguage.WithDetails(map[string][]string{"languages": model.CodeLanguages})
+		}
+	} else if req.Language != "" {
+		return nil, ErrCodeLanguage.WithMessage("only code messages have a language")
+	}
+
+	msgType := req.Type
+	if msgType == "" {
+		msgType = model.MessageTypeText
+		// Auto-detect type from attachments
+		if len(req.Attachments) > 0 {
+			msgType = model.MessageType(req.Attachments[0].Type)
+		} else if req.FileURL != "" {
+			msgType = model.MessageTypeFile
+		}
+	}
+
+	msg := &model.Message{
+		ID:             uuid.New(),
+		ConversationID: convID,
+		SenderID:       senderID,
+		Content:        content,
+		Entities:       req.Entities,
+		Type:           msgType,
+		Language:       language,
+		Status:         model.MessageStatusSent,
+		FileURL:        req.FileURL,
+		FileName:       req.FileName,
+		FileSize:       req.FileSize,
+		ReplyToID:      req.ReplyToID,
+	}
+
+	attachments := make([]model.MessageAttachment, 0, len(req.Attachments))
+	for _, att := range req.Attachments {
+		attachments = append(attachments, model.MessageAttachment{
+			MessageID: msg.ID,
+			Type:      att.Type,
+			URL:       att.URL,
+			FileName:  att.FileName,
+			FileSize:  att.FileSize,
+			MimeType:  att.MimeType,
+			// Reference the deduplicated blob so it isn't purged while in use
+			BlobHash: s.blobService.RetainURL(att.URL),
+		})
+	}
+
+	return s.saveMessage(msg, attachments)
+}
+
+// PostSystemMessage posts a system message, e.g. from a conversation
+// template, shown as sent by senderID. It skips the checks SendMessage makes
+// of the sender.
+func (s *ChatService) PostSystemMessage(convID, senderID uuid.UUID, content string, entities model.MessageEntities) (*model.Message, error) {
+	content = model.CleanContent(content)
+	if err := entities.Normalize(content); err != nil {
+		return nil, ErrInvalidEntities.WithMessage(err.Error())
+	}
+	return s.saveMessage(&model.Message{
+		ID:             uuid.New(),
+		ConversationID: convID,
+		SenderID:       senderID,
+		Content:        content,
+		Entities:       entities,
+		Type:           model.MessageTypeSystem,
+		Status:         model.MessageStatusSent,
+	}, nil)
+}
+
+// SendAutoReply sends senderID's auto-reply to a direct conversation, marked
+// AutoReply so clients can tell it apart and it never triggers another one
+func (s *ChatService) SendAutoReply(convID, senderID uuid.UUID, content string) (*model.Message, error) {
+	return s.saveMessage(&model.Message{
+		ID:             uuid.New(),
+		ConversationID: convID,
+		SenderID:       senderID,
+		Content:        content,
+		Type:           model.MessageTypeText,
+		Status:         model.MessageStatusSent,
+		AutoReply:      true,
+	}, nil)
+}
+
+// saveMessage stores a new message and returns it as broadcast to members
+func (s *ChatService) saveMessage(msg *model.Message, attachments []model.MessageAttachment) (*model.Message, error) {
+	// The WebSocket broadcast and push notifications are saved with the message
+	// and published by the outbox worker, so a crash can't drop them
+	if err := s.msgRepo.CreateWithOutbox(msg, attachments, s.outbox.MessageEvents(msg)); err != nil {
+		for i := range attachments {
+			_ = s.blobService.ReleaseAttachment(&attachments[i])
+		}
+		return nil, errors.New("failed to send message")
+	}
+	s.outbox.Wake()
+	s.stats.Record(model.MetricMessagesSent, 1)
+
+	// Videos are transcoded in the background into a streaming-friendly MP4
+	for _, att := range attachments {
+		if att.Type == model.AttachmentTypeVideo {
+			_ = s.mediaService.EnqueueTranscode(att.ID)
+		}
+	}
+
+	// Update conversation's updated_at for sorting
+	_ = s.convRepo.TouchUpdatedAt(msg.ConversationID)
+
+	// Reload with sender info and attachments
+	saved, err := s.msgRepo.FindByID(msg.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Broadcast to every member, so show the sender as a non-contact would see them
+	saved.Sender.ApplyPrivacy(uuid.Nil, false)
+	return saved, nil
+}
+
+// notifyGroupInvite tells the added members they were put into a new group
+func (s *ChatService) notifyGroupInvite(conv *model.Conversation, creatorID uuid.UUID) {
+	creator, err := s.userRepo.FindByID(creatorID)
+	if err != nil {
+		return
+	}
+
+	var invited []uuid.UUID
+	for _, m := range conv.Members {
+		if m.UserID != creatorID {
+			invited = append(invited, m.UserID)
+		}
+	}
+	if len(invited) == 0 {
+		return
+	}
+
+	_ = s.notifCenter.Notify(invited, model.NotificationTypeGroupInvite,
+		"New group", fmt.Sprintf("%s added you to %s", creator.PublicName(), conv.Name),
+		map[string]string{"conversation_id": conv.ID.String()})
+}
+
+// MessageQuery selects the messages GetMessages returns; at most one field is set
+type MessageQuery struct {
+	Before *uuid.UUID // older than this message (default: the latest messages)
+	After  *uuid.UUID // newer than this message
+	Around *uuid.UUID // a window centered on this message
+	Date   *time.Time // a window centered on the first message sent at or after this time
+}
+
+// MessagePage is a page of messages, newest first, and whether the
+// conversation has older and newer messages beyond it
+type MessagePage struct {
+	Messages []model.Message
+	HasOlder bool
+	HasNewer bool
+}
+
+// GetMessages returns a page of messages for a conversation: backwards from
+// the latest or a cursor, forwards from a cursor, or a window around a message
+// or a point in time for deep links
+func (s *ChatService) GetMessages(convID, userID uuid.UUID, q MessageQuery, limit int) (*MessagePage, error) {
+	// Check membership
+	isMember, err := s.members.IsMember(context.Background(), convID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotMember
+	}
+
+	limit = MessagePageLimit(limit)
+	var page *MessagePage
+	switch {
+	case q.After != nil:
+		since, err := s.messageTime(convID, userID, *q.After)
+		if err != nil {
+			return nil, err
+		}
+		newer, err := s.msgRepo.GetMessagesNewer(convID, userID, since, false, limit+1)
+		if err != nil {
+			return nil, err
+		}
+		page = &MessagePage{HasOlder: true, HasNewer: len(newer) > limit}
+		page.Messages = reverseMessages(newer[:min(len(newer), limit)])
+	case q.Around != nil || q.Date != nil:
+		at := time.Time{}
+		if q.Date != nil {
+			at = *q.Date
+		} else if at, err = s.messageTime(convID, userID, *q.Around); err != nil {
+			return nil, err
+		}
+		if page, err = s.messageWindow(convID, userID, at, limit); err != nil {
+			return nil, err
+		}
+	default:
+		messages, err := s.msgRepo.GetConversationMessages(convID, userID, q.Before, limit)
+		if err != nil {
+			return nil, err
+		}
+		page = &MessagePage{Messages: messages, HasOlder: len(messages) == limit, HasNewer: q.Before != nil}
+	}
+
+	contacts, err := contactSet(s.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	applyMessagesPrivacy(page.Messages, userID, contacts)
+	return page, nil
+}
+
+// messageWindow returns up to limit messages centered on the first message sent
+// at or after `at`. When one side runs short the other side fills the page.
+func (s *ChatService) messageWindow(convID, userID uuid.UUID, at time.Time, limit int) (*MessagePage, error) {
+	older, err := s.msgRepo.GetMessagesOlder(convID, userID, at, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	newer, err := s.msgRepo.GetMessagesNewer(convID, userID, at, true, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	newerCount := min(len(newer), max(limit-limit/2, limit-len(older)))
+	olderCount := min(len(older), limit-newerCount)
+	return &MessagePage{
+		Messages: append(reverseMessages(newer[:newerCount]), older[:olderCount]...),
+		HasOlder: len(older) > olderCount,
+		HasNewer: len(newer) > newerCount,
+	}, nil
+}
+
+// messageTime returns when a message of the conversation was sent
+func (s *ChatService) messageTime(convID, userID, messageID uuid.UUID) (time.Time, error) {
+	createdAt, err := s.msgRepo.FindCreatedAt(convID, userID, messageID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, ErrMessageNotFound
+	}
+	return createdAt, err
+}
+
+// reverseMessages turns an oldest-first batch newest first, in place
+func reverseMessages(messages []model.Message) []model.Message {
+	slices.Reverse(messages)
+	return messages
+}
+
+// MessagePageLimit returns the page size GetMessages uses for a requested limit
+func MessagePageLimit(limit int) int {
+	if limit <= 0 || limit > 100 {
+		return 50
+	}
+	return limit
+}
+
+// MemberPageLimit returns the page size ListMembers uses for a requested limit
+func MemberPageLimit(limit int) int {
+	if limit <= 0 || limit > 200 {
+		return 50
+	}
+	return limit
+}
+
+// MarkMessagesAsRead updates the last_read_at timestamp
+func (s *ChatService) MarkMessagesAsRead(convID, userID uuid.UUID) error {
+	return s.convRepo.UpdateLastRead(convID, userID)
+}
+
+// ReadConversation marks the conversation read for the user, recording a read
+// receipt for messageID when set. The other members get a message_read event,
+// and so do the user's other connections (all but exceptClientID, uuid.Nil for
+// none) with the new unread count so they clear their badge.
+func (s *ChatService) ReadConversation(convID, userID, messageID, exceptClientID uuid.UUID) error {
+	if err := s.MarkMessagesAsRead(convID, userID); err != nil {
+		return err
+	}
+	if messageID != uuid.Nil {
+		if err := s.RecordRead(convID, userID, messageID); err != nil {
+			log.Printf("⚠️  Failed to record read receipt for message %s: %v", messageID, err)
+		}
+	}
+
+	memberIDs, err := s.members.MemberIDs(context.Background(), convID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(memberIDs, userID) {
+		return nil
+	}
+	for _, memberID := range memberIDs {
+		if memberID != userID {
+			s.hub.SendToUser(memberID, &model.WSEvent{
+				Type:    model.WSEventMessageRead,
+				Payload: model.MessageReadEvent{ConversationID: convID, MessageID: messageID, UserID: userID},
+			})
+		}
+	}
+
+	unread, err := s.msgRepo.CountUnread(convID, userID)
+	if err != nil {
+		return err
+	}
+	s.hub.SendToUserExcept(userID, exceptClientID, &model.WSEvent{
+		Type: model.WSEventMessageRead,
+		Payload: model.MessageReadEvent{
+			ConversationID: convID,
+			MessageID:      messageID,
+			UserID:         userID,
+			UnreadCount:    &unread,
+		},
+	})
+	return nil
+}
+
+// ClearHistory hides every message sent to the conversation so far from the
+// user only; the other members keep their history. The user's other devices
+// are told to clear it too.
+func (s *ChatService) ClearHistory(convID, userID uuid.UUID) error {
+	isMember, err := s.members.IsMember(context.Background(), convID, userID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrNotMember
+	}
+
+	clearedAt := time.Now()
+	if err := s.convRepo.ClearHistory(convID, userID, clearedAt); err != nil {
+		return err
+	}
+	s.hub.SendToUser(userID, &model.WSEvent{
+		Type:    model.WSEventHistoryCleared,
+		Payload: model.HistoryClearedEvent{ConversationID: convID, ClearedBefore: clearedAt},
+	})
+	return nil
+}
+
+// MarkAllAsRead marks the given conversations (empty = all of the user's) as
+// read and returns those that had unread messages. The user's other devices
+// and the members of those conversations get one conversations_read event
+// each, listing the conversations they share.
+func (s *ChatService) MarkAllAsRead(userID uuid.UUID, convIDs []uuid.UUID) ([]uuid.UUID, error) {
+	updated, err := s.convRepo.MarkAllRead(userID, convIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	readIDs := make([]uuid.UUID, 0, len(updated))
+	shared := map[uuid.UUID][]uuid.UUID{}
+	readAt := time.Now()
+	for _, m := range updated {
+		readIDs = append(readIDs, m.ConversationID)
+		if m.LastReadAt != nil {
+			readAt = *m.LastReadAt
+		}
+		memberIDs, err := s.members.MemberIDs(context.Background(), m.ConversationID)
+		if err != nil {
+			continue
+		}
+		for _, memberID := range memberIDs {
+			if memberID != userID {
+				shared[memberID] = append(shared[memberID], m.ConversationID)
+			}
+		}
+	}
+	if len(readIDs) == 0 {
+		return readIDs, nil
+	}
+
+	shared[userID] = readIDs
+	for recipientID, ids := range shared {
+		s.hub.SendToUser(recipientID, &model.WSEvent{
+			Type:    model.WSEventConversationsRead,
+			Payload: model.ConversationsReadEvent{UserID: userID, ConversationIDs: ids, ReadAt: readAt},
+		})
+	}
+	return readIDs, nil
+}
+
+// MarkDelivered records that one of the user's devices received a message and,
+// the first time, tells the sender
+func (s *ChatService) MarkDelivered(convID, userID, messageID uuid.UUID) error {
+	msg, err := s.receivedMessage(convID, userID, messageID)
+	if err != nil || msg == nil {
+		return err
+	}
+	first, err := s.msgRepo.RecordDelivered(msg.ID, userID)
+	if err != nil || !first {
+		return err
+	}
+	s.hub.SendToUser(msg.SenderID, &model.WSEvent{
+		Type: model.WSEventMessageDelivered,
+		Payload: model.MessageDeliveredEvent{
+			ConversationID: convID,
+			MessageID:      msg.ID,
+			UserID:         userID,
+		},
+	})
+	return nil
+}
+
+// RecordRead records that the user read a message (and the ones before it)
+func (s *ChatService) RecordRead(convID, userID, messageID uuid.UUID) error {
+	msg, err := s.receivedMessage(convID, userID, messageID)
+	if err != nil || msg == nil {
+		return err
+	}
+	return s.msgRepo.RecordRead(msg.ID, userID)
+}
+
+// receivedMessage returns a message of the conversation that the user is in,
+// or nil when the user sent it
+func (s *ChatService) receivedMessage(convID, userID, messageID uuid.UUID) (*model.Message, error) {
+	isMember, err := s.members.IsMember(context.Background(), convID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotMember
+	}
+
+	msg, err := s.msgRepo.FindByID(messageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+	if msg.ConversationID != convID {
+		return nil, ErrMessageNotFound
+	}
+	if msg.SenderID == userID {
+		return nil, nil
+	}
+	return msg, nil
+}
+
+// GetMessageInfo returns when each recipient received and read a message; only
+// its sender may see it
+func (s *ChatService) GetMessageInfo(messageID, userID uuid.UUID) (*model.MessageInfo, error) {
+	msg, err := s.msgRepo.FindByID(messageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+	if msg.SenderID != userID {
+		return nil, ErrNotMessageSender
+	}
+
+	receipts, err := s.msgRepo.GetRecipientReceipts(msg)
+	if err != nil {
+		return nil, err
+	}
+	recipientIDs := make([]uuid.UUID, len(receipts))
+	for i := range receipts {
+		recipientIDs[i] = receipts[i].UserID
+	}
+	users, err := s.userRepo.FindByIDs(recipientIDs)
+	if err != nil {
+		return nil, err
+	}
+	contacts, err := contactSet(s.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]model.User, len(users))
+	for _, u := range users {
+		u.ApplyPrivacy(userID, contacts[u.ID])
+		byID[u.ID] = u
+	}
+
+	info := &model.MessageInfo{MessageID: msg.ID, Recipients: make([]model.MessageRecipientInfo, 0, len(receipts))}
+	for _, r := range receipts {
+		recipient := model.MessageRecipientInfo{User: byID[r.UserID], DeliveredAt: r.DeliveredAt, ReadAt: r.ReadAt}
+		// Marking the conversation read covers messages without a read receipt
+		if recipient.ReadAt == nil && r.LastReadAt != nil && !r.LastReadAt.Before(msg.CreatedAt) {
+			recipient.ReadAt = r.LastReadAt
+		}
+		// A message that was read was delivered
+		if recipient.ReadAt != nil && (recipient.DeliveredAt == nil || recipient.ReadAt.Before(*recipient.DeliveredAt)) {
+			recipient.DeliveredAt = recipient.ReadAt
+		}
+		if recipient.DeliveredAt != nil {
+			info.DeliveredCount++
+		}
+		if recipient.ReadAt != nil {
+			info.ReadCount++
+		}
+		info.Recipients = append(info.Recipients, recipient)
+	}
+	return info, nil
+}
+
+// GetAttachment returns an attachment of a message the user can see: a member
+// of its conversation who hasn't cleared the history since it was sent
+func (s *ChatService) GetAttachment(attachmentID, userID uuid.UUID) (*model.MessageAttachment, error) {
+	att, err := s.msgRepo.FindAttachmentByID(attachmentID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+	msg, err := s.msgRepo.FindByID(att.MessageID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+
+	isMember, err := s.members.IsMember(context.Background(), msg.ConversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotMember
+	}
+	if _, err := s.messageTime(msg.ConversationID, userID, msg.ID); err != nil {
+		if errors.Is(err, ErrMessageNotFound) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+	return att, nil
+}
+
+// GetConversationMemberIDs returns all member IDs for a conversation
+func (s *ChatService) GetConversationMemberIDs(convID uuid.UUID) ([]uuid.UUID, error) {
+	return s.members.MemberIDs(context.Background(), convID)
+}
+
+// applyConversationPrivacy shows members as the viewer may see them. Sharing a
+// group doesn't make members contacts, so their email stays hidden.
+func applyConversationPrivacy(conv *model.Conversation, viewerID uuid.UUID, contacts map[uuid.UUID]bool) {
+	for i := range conv.Members {
+		conv.Members[i].User.ApplyPrivacy(viewerID, contacts[conv.Members[i].UserID])
+	}
+	if conv.LastMessage != nil {
+		conv.LastMessage.Sender.ApplyPrivacy(viewerID, contacts[conv.LastMessage.SenderID])
+	}
+	if conv.Announcement != nil {
+		conv.Announcement.Sender.ApplyPrivacy(viewerID, contacts[conv.Announcement.SenderID])
+	}
+}
+
+// applyMessagesPrivacy shows message senders as the viewer may see them
+func applyMessagesPrivacy(messages []model.Message, viewerID uuid.UUID, contacts map[uuid.UUID]bool) {
+	for i := range messages {
+		messages[i].Sender.ApplyPrivacy(viewerID, contacts[messages[i].SenderID])
+	}
 }
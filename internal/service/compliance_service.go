@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"gorm.io/gorm"
+)
+
+const complianceExportBatch = 500
+
+// ComplianceService places legal holds, which keep messages from retention
+// and deletion, and exports messages for review. Every hold change and every
+// export is recorded in the audit log.
+type ComplianceService struct {
+	holdRepo *repository.LegalHoldRepository
+	msgRepo  *repository.MessageRepository
+	convRepo *repository.ConversationRepository
+	userRepo *repository.UserRepository
+	audit    *AuditService
+}
+
+func NewComplianceService(
+	holdRepo *repository.LegalHoldRepository,
+	msgRepo *repository.MessageRepository,
+	convRepo *repository.ConversationRepository,
+	userRepo *repository.UserRepository,
+	audit *AuditService,
+) *ComplianceService {
+	return &ComplianceService{holdRepo: holdRepo, msgRepo: msgRepo, convRepo: convRepo, userRepo: userRepo, audit: audit}
+}
+
+// ListHolds returns the legal holds in place
+func (s *ComplianceService) ListHolds() ([]model.LegalHold, error) {
+	return s.holdRepo.List()
+}
+
+// PlaceHold puts a user or a conversation, deleted or not, under legal hold
+func (s *ComplianceService) PlaceHold(adminID uuid.UUID, req model.CreateLegalHoldRequest) (*model.LegalHold, error) {
+	if (req.UserID == nil) == (req.ConversationID == nil) {
+		return nil, ErrLegalHoldTarget
+	}
+	if req.UserID != nil {
+		if _, err := s.userRepo.FindByID(*req.UserID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrUserNotFound
+			}
+			return nil, err
+		}
+	}
+	if req.ConversationID != nil {
+		if _, err := s.convRepo.FindByID(*req.ConversationID); errors.Is(err, gorm.ErrRecordNotFound) {
+			_, err = s.convRepo.FindDeleted(*req.ConversationID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrConversationNotFound
+			}
+			if err != nil {
+				return nil, err
+			}
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	hold := &model.LegalHold{
+		UserID:         req.UserID,
+		ConversationID: req.ConversationID,
+		Reason:         req.Reason,
+		CreatedBy:      adminID,
+	}
+	if err := s.holdRepo.Create(hold); err != nil {
+		return nil, err
+	}
+	s.audit.Record(model.AuditEvent{
+		Action:         model.AuditLegalHoldPlaced,
+		ActorID:        &adminID,
+		ConversationID: hold.ConversationID,
+		Details:        holdDetails(hold),
+	})
+	return hold, nil
+}
+
+// ReleaseHold lifts a legal hold; retention and purges apply again
+func (s *ComplianceService) ReleaseHold(adminID, holdID uuid.UUID) error {
+	hold, err := s.holdRepo.Delete(holdID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrLegalHoldNotFound
+	}
+	if err != nil {
+		return err
+	}
+	s.audit.Record(model.AuditEvent{
+		Action:         model.AuditLegalHoldReleased,
+		ActorID:        &adminID,
+		ConversationID: hold.ConversationID,
+		Details:        holdDetails(hold),
+	})
+	return nil
+}
+
+// Export records the access in the audit log, then passes every message
+// matching the request to write in chronological order, deleted ones
+// included, and returns how many it wrote
+func (s *ComplianceService) Export(ctx context.Context, adminID uuid.UUID, req model.ComplianceExportRequest, write func(*model.ComplianceRecord) error) (int, error) {
+	filter := repository.ComplianceFilter{From: req.From, To: req.To}
+	details := map[string]interface{}{}
+	if req.UserID != "" {
+		id, err := uuid.Parse(req.UserID)
+		if err != nil {
+			return 0, err
+		}
+		filter.UserID = &id
+		details["user_id"] = id
+	}
+	if req.ConversationID != "" {
+		id, err := uuid.Parse(req.ConversationID)
+		if err != nil {
+			return 0, err
+		}
+		filter.ConversationID = &id
+	}
+	if req.From != nil {
+		details["from"] = req.From
+	}
+	if req.To != nil {
+		details["to"] = req.To
+	}
+	s.audit.Record(model.AuditEvent{
+		Action:         model.AuditComplianceExport,
+		ActorID:        &adminID,
+		ConversationID: filter.ConversationID,
+		Details:        details,
+	})
+
+	written := 0
+	var after *model.Message
+	for ctx.Err() == nil {
+		messages, err := s.msgRepo.ListForCompliance(filter, after, complianceExportBatch)
+		if err != nil {
+			return written, err
+		}
+		for i := range messages {
+			record := messages[i].ToComplianceRecord()
+			if err := write(&record); err != nil {
+				return written, err
+			}
+			written++
+		}
+		if len(messages) < complianceExportBatch {
+			return written, nil
+		}
+		after = &messages[len(messages)-1]
+	}
+	return written, ctx.Err()
+}
+
+func holdDetails(hold *model.LegalHold) map[string]interface{} {
+	details := map[string]interface{}{"hold_id": hold.ID, "reason": hold.Reason}
+	if hold.UserID != nil {
+		details["user_id"] = *hold.UserID
+	}
+	return details
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/pkg/mailer"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	digestLockKeyPrefix  = "gotalk:digest:sent:" // one key per ISO week, so only one instance sends
+	digestCheckInterval  = time.Hour
+	digestMaxConvs       = 5
+	digestSendWeekday    = time.Monday
+	digestSendHourUTC    = 9
+	digestLockExpiration = 8 * 24 * time.Hour
+)
+
+// DigestService sends the weekly unread-messages digest email to opted-in users
+type DigestService struct {
+	userRepo *repository.UserRepository
+	convRepo *repository.ConversationRepository
+	msgRepo  *repository.MessageRepository
+	mailer   *mailer.Mailer
+	rdb      *redis.Client
+}
+
+func NewDigestService(
+	userRepo *repository.UserRepository,
+	convRepo *repository.ConversationRepository,
+	msgRepo *repository.MessageRepository,
+	mailer *mailer.Mailer,
+	rdb *redis.Client,
+) *DigestService {
+	return &DigestService{
+		userRepo: userRepo,
+		convRepo: convRepo,
+		msgRepo:  msgRepo,
+		mailer:   mailer,
+		rdb:      rdb,
+	}
+}
+
+// Run checks hourly and sends the digest once per week (Monday 09:00 UTC),
+// blocking until ctx is cancelled
+func (s *DigestService) Run(ctx context.Context) {
+	ticker := time.NewTicker(digestCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			now = now.UTC()
+			if now.Weekday() != digestSendWeekday || now.Hour() < digestSendHourUTC {
+				continue
+			}
+
+			year, week := now.ISOWeek()
+			lockKey := fmt.Sprintf("%s%d-%02d", digestLockKeyPrefix, year, week)
+			acquired, err := s.rdb.SetNX(ctx, lockKey, "1", digestLockExpiration).Result()
+			if err != nil || !acquired {
+				continue
+			}
+
+			sent, err := s.SendDigests(ctx)
+			if err != nil {
+				log.Printf("⚠️  Weekly digest failed: %v", err)
+				continue
+			}
+			log.Printf("📬 Weekly digest queued for %d users", sent)
+		}
+	}
+}
+
+// SendDigests queues a digest for every opted-in user with unread messages
+func (s *DigestService) SendDigests(ctx context.Context) (int, error) {
+	users, err := s.userRepo.FindDigestRecipients()
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range users {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+		ok, err := s.sendDigest(&users[i])
+		if err != nil {
+			log.Printf("⚠️  Failed to queue digest for %s: %v", users[i].Email, err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// sendDigest queues the digest for one user; it reports false when nothing is unread
+func (s *DigestService) sendDigest(user *model.User) (bool, error) {
+	convs, err := s.convRepo.GetUserConversations(user.ID, memberPreviewSize)
+	if err != nil {
+		return false, err
+	}
+
+	var total int64
+	var lines []mailer.DigestConversation
+	for _, conv := range convs {
+		unread, err := s.msgRepo.CountUnread(conv.ID, user.ID)
+		if err != nil || unread == 0 {
+			continue
+		}
+		total += unread
+
+		name := conv.Name
+		if conv.Type == model.ConversationTypePrivate {
+			for _, m := range conv.Members {
+				if m.UserID != user.ID {
+					name = m.User.Name
+					break
+				}
+			}
+		}
+		lines = append(lines, mailer.DigestConversation{Name: name, UnreadCount: unread})
+	}
+	if total == 0 {
+		return false, nil
+	}
+
+	sort.Slice(lines, func(i, j int) bool { return lines[i].UnreadCount > lines[j].UnreadCount })
+	if len(lines) > digestMaxConvs {
+		lines = lines[:digestMaxConvs]
+	}
+
+	if err := s.mailer.SendUnreadDigest(user.Email, user.Name, total, lines); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,169 @@
+package service
+
+import "github.com/quocanhngo/gotalk/pkg/apperror"
+
+// Errors returned to API clients. Other service errors are treated as internal
+// and their message is not exposed.
+var (
+	// Auth and account
+	ErrEmailTaken           = apperror.New(apperror.CodeEmailTaken, "email already registered")
+	ErrInvalidCredentials   = apperror.New(apperror.CodeInvalidCredentials, "invalid email or password")
+	ErrEmailNotVerified     = apperror.New(apperror.CodeEmailNotVerified, "email not verified. Please check your inbox for the verification code")
+	ErrEmailAlreadyVerified = apperror.New(apperror.CodeEmailAlreadyVerified, "email already verified")
+	ErrGoogleAccount        = apperror.New(apperror.CodeGoogleAccount, "this account uses Google login. Please sign in with Google")
+	ErrInvalidGoogleToken   = apperror.New(apperror.CodeInvalidGoogleToken, "invalid google token")
+	ErrInvalidOTP           = apperror.New(apperror.CodeInvalidOTP, "invalid or expired OTP code")
+	ErrTooManyOTPs          = apperror.ErrRateLimited.WithMessage("too many OTP requests. Please try again later")
+	ErrTooManyOTPAttempts   = apperror.ErrRateLimited.WithMessage("too many verification attempts. Please try again later")
+	ErrOTPAttemptsExceeded  = apperror.New(apperror.CodeOTPAttemptsExceeded, "too many wrong codes. Please request a new code")
+	ErrMagicLinkInvalid     = ErrInvalidOTP.WithMessage("invalid or expired sign-in link")
+	ErrMagicLinkDisabled    = apperror.ErrNotFound.WithMessage("sign-in links are not enabled")
+	ErrOTPDelivery          = apperror.ErrUnavailable.WithMessage("failed to send verification email. Please try again")
+	ErrUserNotFound         = apperror.New(apperror.CodeUserNotFound, "user not found")
+	ErrInvalidHandle        = apperror.New(apperror.CodeHandleInvalid, "handle must be 3-30 characters of lowercase letters, digits or underscores")
+	ErrHandleTaken          = apperror.New(apperror.CodeHandleTaken, "handle is already taken")
+	ErrAccountDeactivated   = apperror.New(apperror.CodeAccountDeactivated, "this account has been deactivated. Please contact your administrator")
+	ErrSSOAccount           = apperror.New(apperror.CodeSSOAccount, "this account uses single sign-on. Please sign in with SSO")
+	ErrSSONotConfigured     = apperror.New(apperror.CodeSSONotConfigured, "single sign-on is not configured")
+	ErrSSOFailed            = apperror.New(apperror.CodeSSOFailed, "single sign-on failed. Please try again")
+	ErrSSODomainNotAllowed  = apperror.New(apperror.CodeSSODomainNotAllowed, "your email domain is not allowed to sign in with single sign-on")
+	ErrLDAPAccount          = apperror.New(apperror.CodeLDAPAccount, "this account uses your organization's directory password. Please contact your administrator to change it")
+	ErrLDAPUnavailable      = apperror.ErrUnavailable.WithMessage("the directory server is unavailable. Please try again later")
+	ErrLDAPNotConfigured    = apperror.ErrNotFound.WithMessage("LDAP is not configured")
+	ErrLoginAlertInvalid    = apperror.ErrNotFound.WithMessage("unknown or expired sign-in alert link")
+	ErrSignupNotAllowed     = apperror.New(apperror.CodeSignupNotAllowed, "sign-ups from this email domain are not allowed")
+	ErrDisposableEmail      = apperror.New(apperror.CodeDisposableEmail, "disposable email addresses cannot be used to sign up")
+	ErrInvitationRequired   = apperror.New(apperror.CodeInvitationRequired, "registration is by invitation only. Please enter your invitation code")
+	ErrInvitationInvalid    = apperror.New(apperror.CodeInvitationInvalid, "invalid or expired invitation code")
+	ErrInvitationNotFound   = apperror.ErrNotFound.WithMessage("invitation not found")
+
+	// Chat
+	ErrNotMember           = apperror.New(apperror.CodeNotMember, "you are not a member of this conversation")
+	ErrInvalidMembers      = apperror.New(apperror.CodeInvalidMembers, "private conversation requires exactly 1 other member")
+	ErrGroupTooLarge       = apperror.New(apperror.CodeGroupTooLarge, "the group has too many members")
+	ErrMessageNotFound     = apperror.ErrNotFound.WithMessage("message not found")
+	ErrAttachmentNotFound  = apperror.ErrNotFound.WithMessage("attachment not found")
+	ErrNotMessageSender    = apperror.ErrForbidden.WithMessage("only the sender can see a message's info")
+	ErrNotGroupAdmin       = apperror.ErrForbidden.WithMessage("only group admins can do this")
+	ErrGroupProvisioned    = apperror.ErrForbidden.WithMessage("this group is managed by your directory")
+	ErrConversationGone    = apperror.ErrNotFound.WithMessage("conversation not found or no longer restorable")
+	ErrConversationFrozen  = apperror.New(apperror.CodeConversationFrozen, "this conversation is frozen; only members who manage it can post")
+	ErrMessageTooLong      = apperror.New(apperror.CodeMessageTooLong, "the message is too long")
+	ErrMessageEmpty        = apperror.ErrInvalidRequest.WithMessage("the message has no text or attachments")
+	ErrInvalidEntities     = apperror.ErrInvalidRequest.WithMessage("invalid formatting entities")
+	ErrCodeLanguage        = apperror.ErrInvalidRequest.WithMessage("unsupported code language")
+	ErrCodeMessage         = apperror.ErrInvalidRequest.WithMessage("code messages carry only text, without formatting or attachments")
+	ErrSystemMessage       = apperror.ErrInvalidRequest.WithMessage("system messages are posted by the server")
+	ErrFreezeGroupOnly     = apperror.ErrInvalidRequest.WithMessage("only group conversations can be frozen")
+	ErrAddMembersGroupOnly = apperror.ErrInvalidRequest.WithMessage("members can only be added to group conversations")
+	ErrPinNotFound         = apperror.ErrNotFound.WithMessage("the message isn't pinned")
+	ErrPinLimitReached     = apperror.ErrConflict.WithMessage("the conversation has the maximum number of pins. Unpin one to pin another")
+	ErrPinOrder            = apperror.ErrInvalidRequest.WithMessage("message_ids must list every pinned message once")
+	ErrGroupLimitReached   = apperror.New(apperror.CodeGroupLimitReached, "you have created the maximum number of groups. Delete one to create another")
+	ErrDirectLimitReached  = apperror.New(apperror.CodeDirectLimitReached, "you have started too many new conversations today. Please try again later")
+	ErrTemplateNotFound    = apperror.ErrNotFound.WithMessage("conversation template not found")
+	ErrTemplateName        = apperror.ErrInvalidRequest.WithMessage("template names are 2-50 lowercase letters, digits, underscores or hyphens")
+	ErrTemplateNameTaken   = apperror.ErrConflict.WithMessage("a template with this name already exists")
+
+	// Calls
+	ErrCallTarget      = apperror.ErrInvalidRequest.WithMessage("to must be another user")
+	ErrCallNotAllowed  = apperror.ErrForbidden.WithMessage("you can only call people you share a conversation with")
+	ErrCallSpoofed     = apperror.ErrForbidden.WithMessage("from must be your own user ID")
+	ErrTooManyCalls    = apperror.ErrRateLimited.WithMessage("too many calls. Please try again later")
+	ErrCallScreenShare = apperror.ErrInvalidRequest.WithMessage("sharing must be true or false")
+	ErrCallTrackState  = apperror.ErrInvalidRequest.WithMessage("track must be audio or video, with muted true or false")
+	ErrCallNotFound    = apperror.ErrNotFound.WithMessage("call not found")
+	ErrCallEnded       = apperror.ErrConflict.WithMessage("the call has ended")
+	ErrNoOngoingCall   = apperror.ErrConflict.WithMessage("there's no call going on with this user")
+	ErrCallChat        = apperror.ErrInvalidRequest.WithMessage("text must be 1-1000 characters")
+	ErrCallChatPersist = apperror.ErrInvalidRequest.WithMessage("conversation_id is needed to keep a message")
+	ErrCallReaction    = apperror.ErrInvalidRequest.WithMessage("emoji must be one emoji of at most 16 bytes")
+	ErrCallIssue       = apperror.ErrInvalidRequest.WithMessage("issues must be echo, noise, audio_dropped, video_frozen, video_blurry, delay, disconnected or other")
+
+	// Conversation roles
+	ErrNotPermitted     = apperror.New(apperror.CodeNotPermitted, "your role in this conversation doesn't allow this")
+	ErrRolesGroupOnly   = apperror.ErrInvalidRequest.WithMessage("only group conversations have roles")
+	ErrInvalidRoleName  = apperror.ErrInvalidRequest.WithMessage("role names are 2-20 lowercase letters, digits or underscores, starting with a letter")
+	ErrAdminRoleFixed   = apperror.ErrInvalidRequest.WithMessage("the admin role always has every permission")
+	ErrRoleNotFound     = apperror.ErrNotFound.WithMessage("role not found")
+	ErrRoleLimitReached = apperror.ErrConflict.WithMessage("the group has the maximum number of roles. Delete one to create another")
+	ErrMemberNotFound   = apperror.ErrNotFound.WithMessage("member not found")
+	ErrLastAdmin        = apperror.ErrInvalidRequest.WithMessage("a group needs at least one admin")
+
+	// Onboarding rules
+	ErrOnboardingGroupOnly    = apperror.ErrInvalidRequest.WithMessage("only group conversations have onboarding rules")
+	ErrOnboardingAction       = apperror.ErrInvalidRequest.WithMessage("action must be welcome_dm, post_rules or assign_role")
+	ErrOnboardingContent      = apperror.ErrInvalidRequest.WithMessage("welcome_dm and post_rules rules need content")
+	ErrOnboardingRole         = apperror.ErrInvalidRequest.WithMessage("assign_role rules give a custom role of the group")
+	ErrOnboardingRuleNotFound = apperror.ErrNotFound.WithMessage("onboarding rule not found")
+	ErrOnboardingRuleLimit    = apperror.ErrConflict.WithMessage("the group has the maximum number of onboarding rules. Delete one to add another")
+
+	// Conversation exports
+	ErrExportNotFound     = apperror.ErrNotFound.WithMessage("export not found")
+	ErrExportsUnavailable = apperror.ErrUnavailable.WithMessage("conversation exports are unavailable")
+
+	// Chat imports
+	ErrImportNotFound     = apperror.ErrNotFound.WithMessage("import not found")
+	ErrImportsUnavailable = apperror.ErrUnavailable.WithMessage("chat imports are unavailable")
+
+	// Matrix bridge
+	ErrMatrixBridgeUnavailable = apperror.ErrUnavailable.WithMessage("the Matrix bridge is not configured")
+	ErrMatrixLinkNotFound      = apperror.ErrNotFound.WithMessage("conversation is not linked to a Matrix room")
+	ErrMatrixGroupOnly         = apperror.ErrInvalidRequest.WithMessage("only group conversations can be linked to a Matrix room")
+	ErrMatrixRoomLinked        = apperror.ErrConflict.WithMessage("the Matrix room is already linked to another conversation")
+	ErrMatrixJoinFailed        = apperror.ErrInvalidRequest.WithMessage("the bridge couldn't join the Matrix room. Invite its bot or make the room public")
+
+	// Reply by email
+	ErrReplyMailUnavailable = apperror.ErrUnavailable.WithMessage("reply by email is not configured")
+	ErrReplyUnreadable      = apperror.ErrInvalidRequest.WithMessage("the email couldn't be read")
+	ErrReplyAddressUnknown  = apperror.ErrNotFound.WithMessage("unknown or expired reply address")
+	ErrReplySenderMismatch  = apperror.ErrForbidden.WithMessage("the reply wasn't sent from the recipient's address")
+	ErrReplyEmpty           = apperror.ErrInvalidRequest.WithMessage("the reply has no text")
+
+	// Profile
+	ErrStatusExpiry    = apperror.ErrInvalidRequest.WithMessage("expires_at must be in the future")
+	ErrPresenceState   = apperror.ErrInvalidRequest.WithMessage("state must be online, away, busy or invisible")
+	ErrAutoReplyEmpty  = apperror.ErrInvalidRequest.WithMessage("the auto-reply has no text")
+	ErrAutoReplyWindow = apperror.ErrInvalidRequest.WithMessage("ends_at must be in the future and after starts_at")
+
+	// Notifications
+	ErrNotificationNotFound = apperror.New(apperror.CodeNotificationNotFound, "notification not found")
+
+	// Directory sync (SCIM)
+	ErrInvalidFilter    = apperror.New(apperror.CodeInvalidFilter, "only filters of the form: attribute eq \"value\" are supported")
+	ErrSCIMUserName     = apperror.ErrInvalidRequest.WithMessage("userName or a primary email must be an email address")
+	ErrSCIMUnknownUser  = apperror.ErrInvalidRequest.WithMessage("group members must be existing users")
+	ErrSCIMInvalidPatch = apperror.ErrInvalidRequest.WithMessage("unsupported patch operation")
+	ErrGroupNotFound    = apperror.ErrNotFound.WithMessage("group not found")
+
+	// OAuth provider
+	ErrOAuthNotConfigured  = apperror.New(apperror.CodeOAuthNotConfigured, "GoTalk is not set up as an OAuth provider")
+	ErrOAuthClientNotFound = apperror.ErrNotFound.WithMessage("app not found")
+	ErrInvalidRedirectURI  = apperror.New(apperror.CodeInvalidRedirectURI, "the redirect URI is not registered for this app")
+	ErrInvalidScope        = apperror.New(apperror.CodeInvalidScope, "the app may not ask for these scopes")
+	ErrPKCERequired        = apperror.ErrInvalidRequest.WithMessage("apps without a client secret must use PKCE (code_challenge with S256)")
+
+	// Personal access tokens
+	ErrPersonalTokenNotFound = apperror.ErrNotFound.WithMessage("token not found")
+	ErrTooManyPersonalTokens = apperror.ErrConflict.WithMessage("you have created the maximum number of tokens. Revoke one to create another")
+	ErrInvalidPersonalToken  = apperror.ErrUnauthorized.WithMessage("Invalid, expired or revoked token")
+	ErrTokenExpiry           = apperror.ErrInvalidRequest.WithMessage("expires_at must be in the future")
+
+	// Retention, legal holds and audit
+	ErrConversationNotFound    = apperror.ErrNotFound.WithMessage("conversation not found")
+	ErrRetentionPolicyNotFound = apperror.ErrNotFound.WithMessage("no retention policy is set")
+	ErrLegalHoldTarget         = apperror.ErrInvalidRequest.WithMessage("a legal hold is on either a user_id or a conversation_id")
+	ErrLegalHoldNotFound       = apperror.ErrNotFound.WithMessage("legal hold not found")
+
+	// Usage statistics and insights
+	ErrUnknownMetric     = apperror.ErrInvalidRequest.WithMessage("unknown metric")
+	ErrStatsRange        = apperror.ErrInvalidRequest.WithMessage("from must not be after to, at most 366 days apart")
+	ErrInsightsGroupOnly = apperror.ErrInvalidRequest.WithMessage("insights are only available for groups")
+
+	// Search
+	ErrInvalidSearchType = apperror.ErrInvalidRequest.WithMessage("types must be a comma-separated list of message, conversation, user and file")
+
+	// Feature flags
+	ErrRegistrationClosed = apperror.New(apperror.CodeFeatureDisabled, "registration is currently closed")
+	ErrUploadsDisabled    = apperror.New(apperror.CodeFeatureDisabled, "uploads are currently disabled")
+)
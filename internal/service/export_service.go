@@ -0,0 +1,668 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/pkg/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	exportQueueKey      = "gotalk:export:queue"
+	exportJobKeyPrefix  = "gotalk:export:job:"    // export ID -> job JSON, expires with the export
+	exportActiveKey     = "gotalk:export:active:" // conversation:user -> export ID while one is queued or running
+	exportFilesKey      = "gotalk:export:files"   // sorted set of object keys scored by expiry, for purging
+	exportTimeout       = 30 * time.Minute
+	exportBatchSize     = 500
+	exportPurgeInterval = time.Hour
+)
+
+// ExportService builds downloadable archives of a conversation's history in the
+// background. Jobs are queued in Redis so any instance can pick them up; the
+// files go to MinIO and are handed out through presigned links.
+type ExportService struct {
+	convRepo    *repository.ConversationRepository
+	msgRepo     *repository.MessageRepository
+	storage     *storage.MinIOStorage
+	notifCenter *NotificationCenterService
+	rdb         *redis.Client
+	linkExpiry  time.Duration // lifetime of a download link
+	retention   time.Duration // how long exports are kept
+}
+
+func NewExportService(
+	convRepo *repository.ConversationRepository,
+	msgRepo *repository.MessageRepository,
+	storage *storage.MinIOStorage,
+	notifCenter *NotificationCenterService,
+	rdb *redis.Client,
+	linkExpiry, retention time.Duration,
+) *ExportService {
+	return &ExportService{
+		convRepo:    convRepo,
+		msgRepo:     msgRepo,
+		storage:     storage,
+		notifCenter: notifCenter,
+		rdb:         rdb,
+		linkExpiry:  linkExpiry,
+		retention:   retention,
+	}
+}
+
+// Enabled reports whether exports can run (storage available)
+func (s *ExportService) Enabled() bool {
+	return s != nil && s.storage != nil
+}
+
+// exportJob is the stored form of an export; it keeps the object key the API hides
+type exportJob struct {
+	model.ConversationExport
+	ObjectKey string `json:"object_key,omitempty"`
+}
+
+// Request queues an export of a conversation for one of its members. While one
+// is still queued or running for the same member and conversation, that one is
+// returned instead of starting another.
+func (s *ExportService) Request(convID, userID uuid.UUID, format model.ExportFormat) (*model.ConversationExport, error) {
+	if !s.Enabled() {
+		return nil, ErrExportsUnavailable
+	}
+
+	isMember, err := s.convRepo.IsMember(convID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotMember
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+	job := &exportJob{ConversationExport: model.ConversationExport{
+		ID:             uuid.New(),
+		ConversationID: convID,
+		UserID:         userID,
+		Format:         format,
+		Status:         model.ExportStatusPending,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(s.retention),
+	}}
+
+	activeKey := exportActiveKey + convID.String() + ":" + userID.String()
+	claimed, err := s.rdb.SetNX(ctx, activeKey, job.ID.String(), exportTimeout).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		if activeID, err := s.rdb.Get(ctx, activeKey).Result(); err == nil {
+			if id, err := uuid.Parse(activeID); err == nil {
+				if active, err := s.load(ctx, id); err == nil {
+					return &active.ConversationExport, nil
+				}
+			}
+		}
+		// The running job vanished; take over the slot
+		if err := s.rdb.Set(ctx, activeKey, job.ID.String(), exportTimeout).Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.save(ctx, job); err != nil {
+		return nil, err
+	}
+	if err := s.rdb.LPush(ctx, exportQueueKey, job.ID.String()).Err(); err != nil {
+		return nil, err
+	}
+	return &job.ConversationExport, nil
+}
+
+// Get returns an export requested by the user, with a fresh download link once
+// it has completed. Members who have since left the conversation lose access.
+func (s *ExportService) Get(convID, exportID, userID uuid.UUID) (*model.ConversationExport, error) {
+	if !s.Enabled() {
+		return nil, ErrExportsUnavailable
+	}
+
+	job, err := s.load(context.Background(), exportID)
+	if errors.Is(err, redis.Nil) || (err == nil && (job.ConversationID != convID || job.UserID != userID)) {
+		return nil, ErrExportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	isMember, err := s.convRepo.IsMember(convID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotMember
+	}
+
+	export := job.ConversationExport
+	if export.Status == model.ExportStatusCompleted {
+		expiry := s.linkExpiry
+		if remaining := time.Until(export.ExpiresAt); remaining < expiry {
+			expiry = remaining
+		}
+		link, err := s.storage.PresignedURL(context.Background(), job.ObjectKey, exportFileName(job), expiry)
+		if err != nil {
+			return nil, err
+		}
+		expiresAt := time.Now().Add(expiry)
+		export.DownloadURL = link
+		export.URLExpiresAt = &expiresAt
+	}
+	return &export, nil
+}
+
+// Run processes queued exports and purges expired files until ctx is cancelled
+func (s *ExportService) Run(ctx context.Context) {
+	if !s.Enabled() {
+		return
+	}
+
+	log.Println("📦 Conversation export worker started")
+	go s.runPurge(ctx)
+
+	for {
+		result, err := s.rdb.BRPop(ctx, 5*time.Second, exportQueueKey).Result()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if !errors.Is(err, redis.Nil) {
+				log.Printf("⚠️  Export queue error: %v", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		// BRPOP returns [key, value]
+		exportID, err := uuid.Parse(result[1])
+		if err != nil {
+			continue
+		}
+		s.process(ctx, exportID)
+	}
+}
+
+// process builds one export, uploads it and tells the requester it's ready
+func (s *ExportService) process(ctx context.Context, exportID uuid.UUID) {
+	job, err := s.load(ctx, exportID)
+	if err != nil {
+		log.Printf("⚠️  Export %s not found: %v", exportID, err)
+		return
+	}
+	defer s.rdb.Del(context.Background(), exportActiveKey+job.ConversationID.String()+":"+job.UserID.String())
+
+	job.Status = model.ExportStatusProcessing
+	_ = s.save(ctx, job)
+
+	ctx, cancel := context.WithTimeout(ctx, exportTimeout)
+	defer cancel()
+
+	if err := s.build(ctx, job); err != nil {
+		log.Printf("❌ Export %s of conversation %s failed: %v", job.ID, job.ConversationID, err)
+		job.Status = model.ExportStatusFailed
+		job.Error = "the export could not be generated. Please try again"
+		_ = s.save(context.Background(), job)
+		return
+	}
+
+	now := time.Now()
+	job.Status = model.ExportStatusCompleted
+	job.CompletedAt = &now
+	if err := s.save(context.Background(), job); err != nil {
+		log.Printf("❌ Failed to save export %s: %v", job.ID, err)
+		return
+	}
+
+	_ = s.notifCenter.Notify([]uuid.UUID{job.UserID}, model.NotificationTypeExportReady,
+		"Your conversation export is ready",
+		fmt.Sprintf("%d messages exported as %s", job.MessageCount, strings.ToUpper(string(job.Format))),
+		map[string]string{
+			"conversation_id": job.ConversationID.String(),
+			"export_id":       job.ID.String(),
+		})
+}
+
+// build writes the export to a temporary file and uploads it
+func (s *ExportService) build(ctx context.Context, job *exportJob) error {
+	conv, err := s.convRepo.FindByID(job.ConversationID)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp("", "gotalk-export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	buf := bufio.NewWriter(tmp)
+	w := newExportWriter(job.Format, buf)
+	if err := w.begin(conv, time.Now()); err != nil {
+		return err
+	}
+
+	var last *model.Message
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		messages, err := s.msgRepo.GetMessagesAfter(conv.ID, job.UserID, last, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		for i := range messages {
+			if err := w.message(&messages[i]); err != nil {
+				return err
+			}
+		}
+		job.MessageCount += len(messages)
+		if len(messages) < exportBatchSize {
+			break
+		}
+		last = &messages[len(messages)-1]
+	}
+
+	if err := w.end(); err != nil {
+		return err
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+
+	objectKey := fmt.Sprintf("%s/%s/%s.%s", storage.ExportsFolder, job.ConversationID, job.ID, job.Format)
+	result, err := s.storage.UploadFromFile(ctx, tmp.Name(), objectKey, exportContentTypes[job.Format])
+	if err != nil {
+		return err
+	}
+	job.ObjectKey = objectKey
+	job.FileSize = result.FileSize
+
+	return s.rdb.ZAdd(context.Background(), exportFilesKey, redis.Z{
+		Score:  float64(job.ExpiresAt.Unix()),
+		Member: objectKey,
+	}).Err()
+}
+
+// runPurge deletes export files once their exports have expired
+func (s *ExportService) runPurge(ctx context.Context) {
+	ticker := time.NewTicker(exportPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.purgeExpired(ctx)
+		}
+	}
+}
+
+func (s *ExportService) purgeExpired(ctx context.Context) {
+	keys, err := s.rdb.ZRangeByScore(ctx, exportFilesKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: fmt.Sprint(time.Now().Unix()),
+	}).Result()
+	if err != nil {
+		log.Printf("⚠️  Export purge failed: %v", err)
+		return
+	}
+
+	for _, key := range keys {
+		if err := s.storage.Delete(ctx, key); err != nil {
+			log.Printf("⚠️  Failed to delete expired export %s: %v", key, err)
+			continue
+		}
+		s.rdb.ZRem(ctx, exportFilesKey, key)
+	}
+	if len(keys) > 0 {
+		log.Printf("🧹 Purged %d expired conversation exports", len(keys))
+	}
+}
+
+func (s *ExportService) load(ctx context.Context, exportID uuid.UUID) (*exportJob, error) {
+	data, err := s.rdb.Get(ctx, exportJobKeyPrefix+exportID.String()).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var job exportJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// save stores a job until its export expires
+func (s *ExportService) save(ctx context.Context, job *exportJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(job.ExpiresAt)
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	return s.rdb.Set(ctx, exportJobKeyPrefix+job.ID.String(), data, ttl).Err()
+}
+
+var exportContentTypes = map[model.ExportFormat]string{
+	model.ExportFormatJSON: "application/json",
+	model.ExportFormatHTML: "text/html; charset=utf-8",
+	model.ExportFormatCSV:  "text/csv; charset=utf-8",
+}
+
+// exportFileName is the name the browser saves a download as
+func exportFileName(job *exportJob) string {
+	return fmt.Sprintf("conversation-%s-%s.%s", job.ConversationID, job.CreatedAt.Format("20060102"), job.Format)
+}
+
+// ========== Export formats ==========
+
+// exportWriter streams a conversation into one of the export formats
+type exportWriter interface {
+	begin(conv *model.Conversation, exportedAt time.Time) error
+	message(msg *model.Message) error
+	end() error
+}
+
+func newExportWriter(format model.ExportFormat, w io.Writer) exportWriter {
+	switch format {
+	case model.ExportFormatHTML:
+		return &htmlExportWriter{w: w}
+	case model.ExportFormatCSV:
+		return &csvExportWriter{w: csv.NewWriter(w)}
+	default:
+		return &jsonExportWriter{w: w}
+	}
+}
+
+// exportedAttachment is an attachment link in an export
+type exportedAttachment struct {
+	FileName string `json:"file_name"`
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type,omitempty"`
+	FileSize int64  `json:"file_size,omitempty"`
+}
+
+// exportedMessage is a message as it appears in an export
+type exportedMessage struct {
+	ID          uuid.UUID             `json:"id"`
+	SenderID    uuid.UUID             `json:"sender_id"`
+	SenderName  string                `json:"sender_name"`
+	Type        model.MessageType     `json:"type"`
+	Content     string                `json:"content"`
+	Entities    model.MessageEntities `json:"entities,omitempty"`
+	Language    string                `json:"language,omitempty"`
+	ReplyToID   *uuid.UUID            `json:"reply_to_id,omitempty"`
+	CreatedAt   time.Time             `json:"created_at"`
+	Attachments []exportedAttachment  `json:"attachments,omitempty"`
+}
+
+func newExportedMessage(msg *model.Message) exportedMessage {
+	out := exportedMessage{
+		ID:         msg.ID,
+		SenderID:   msg.SenderID,
+		SenderName: msg.Sender.PublicName(),
+		Type:       msg.Type,
+		Content:    msg.Content,
+		Entities:   msg.Entities,
+		Language:   msg.Language,
+		ReplyToID:  msg.ReplyToID,
+		CreatedAt:  msg.CreatedAt,
+	}
+	if msg.Imported && msg.ImportedSender != "" {
+		out.SenderName = msg.ImportedSender
+	}
+	// Messages from before multi-attachment support carry a single file
+	if msg.FileURL != "" {
+		out.Attachments = append(out.Attachments, exportedAttachment{
+			FileName: msg.FileName,
+			URL:      msg.FileURL,
+			FileSize: msg.FileSize,
+		})
+	}
+	for _, att := range msg.Attachments {
+		out.Attachments = append(out.Attachments, exportedAttachment{
+			FileName: att.FileName,
+			URL:      att.URL,
+			MimeType: att.MimeType,
+			FileSize: att.FileSize,
+		})
+	}
+	return out
+}
+
+// exportedConversation heads JSON and HTML exports
+type exportedConversation struct {
+	ID         uuid.UUID              `json:"id"`
+	Name       string                 `json:"name,omitempty"`
+	Type       model.ConversationType `json:"type"`
+	ExportedAt time.Time              `json:"exported_at"`
+}
+
+// jsonExportWriter writes {"conversation": {...}, "messages": [...]}
+type jsonExportWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (j *jsonExportWriter) begin(conv *model.Conversation, exportedAt time.Time) error {
+	header, err := json.Marshal(exportedConversation{ID: conv.ID, Name: conv.Name, Type: conv.Type, ExportedAt: exportedAt})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(j.w, "{\"conversation\":%s,\"messages\":[", header)
+	return err
+}
+
+func (j *jsonExportWriter) message(msg *model.Message) error {
+	data, err := json.Marshal(newExportedMessage(msg))
+	if err != nil {
+		return err
+	}
+	sep := "\n"
+	if j.count > 0 {
+		sep = ",\n"
+	}
+	j.count++
+	_, err = fmt.Fprintf(j.w, "%s%s", sep, data)
+	return err
+}
+
+func (j *jsonExportWriter) end() error {
+	_, err := io.WriteString(j.w, "\n]}\n")
+	return err
+}
+
+// csvExportWriter writes one row per message; attachment URLs are space-separated
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvExportWriter) begin(*model.Conversation, time.Time) error {
+	return c.w.Write([]string{"id", "created_at", "sender_id", "sender_name", "type", "content", "attachments"})
+}
+
+func (c *csvExportWriter) message(msg *model.Message) error {
+	m := newExportedMessage(msg)
+	urls := make([]string, len(m.Attachments))
+	for i, att := range m.Attachments {
+		urls[i] = att.URL
+	}
+	return c.w.Write([]string{
+		m.ID.String(),
+		m.CreatedAt.UTC().Format(time.RFC3339),
+		m.SenderID.String(),
+		csvSafe(m.SenderName),
+		string(m.Type),
+		csvSafe(m.Content),
+		strings.Join(urls, " "),
+	})
+}
+
+func (c *csvExportWriter) end() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// csvSafe keeps spreadsheet apps from evaluating user text as a formula
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// htmlExportWriter writes a self-contained page that can be opened offline
+type htmlExportWriter struct {
+	w io.Writer
+}
+
+var exportHTML = template.Must(template.New("export").Funcs(template.FuncMap{
+	"time":      func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04 UTC") },
+	"formatted": formatHTML,
+}).Parse(`
+{{- define "begin" -}}
+<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{if .Name}}{{.Name}}{{else}}Conversation{{end}} - GoTalk export</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; max-width: 760px; margin: 2rem auto; color: #1f2328; }
+header { border-bottom: 1px solid #d0d7de; margin-bottom: 1rem; }
+.message { padding: .5rem 0; }
+.meta { color: #656d76; font-size: .85rem; }
+.sender { font-weight: 600; color: #1f2328; }
+.content { white-space: pre-wrap; margin-top: .25rem; }
+.content pre { background: #f6f8fa; padding: .5rem; overflow-x: auto; }
+.attachments { margin: .25rem 0 0; padding-left: 1.25rem; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{if .Name}}{{.Name}}{{else}}Conversation{{end}}</h1>
+<p class="meta">Exported {{time .ExportedAt}}</p>
+</header>
+{{end -}}
+{{- define "message" -}}
+<div class="message" id="m-{{.ID}}">
+<div class="meta"><span class="sender">{{.SenderName}}</span> &middot; {{time .CreatedAt}}</div>
+{{- if eq .Type "code"}}
+<pre class="content"><code class="language-{{.Language}}">{{.Content}}</code></pre>
+{{- else if .Content}}
+<div class="content">{{formatted .Content .Entities}}</div>
+{{- end}}
+{{- if .Attachments}}
+<ul class="attachments">
+{{- range .Attachments}}
+<li><a href="{{.URL}}">{{if .FileName}}{{.FileName}}{{else}}{{.URL}}{{end}}</a></li>
+{{- end}}
+</ul>
+{{- end}}
+</div>
+{{end -}}
+{{- define "end" -}}
+</body>
+</html>
+{{end -}}
+`))
+
+// formatHTML renders a message's text with its formatting entities. The text
+// is escaped and only the tags of known entity types are written, so nothing
+// in a message can inject markup.
+func formatHTML(content string, entities model.MessageEntities) template.HTML {
+	runes := []rune(content)
+	var b strings.Builder
+	pos := 0
+	flush := func(to int) {
+		b.WriteString(template.HTMLEscapeString(string(runes[pos:to])))
+		pos = to
+	}
+
+	// Entities are stored sorted, outer spans first, and nested
+	var open []model.MessageEntity
+	closeUntil := func(offset int) {
+		for len(open) > 0 && open[len(open)-1].End() <= offset {
+			entity := open[len(open)-1]
+			flush(entity.End())
+			b.WriteString(entityCloseTags[entity.Type])
+			open = open[:len(open)-1]
+		}
+	}
+	for _, entity := range entities {
+		closeUntil(entity.Offset)
+		if entity.Offset < pos || entity.End() > len(runes) || (len(open) > 0 && entity.End() > open[len(open)-1].End()) {
+			continue
+		}
+		if _, ok := entityCloseTags[entity.Type]; !ok {
+			continue
+		}
+		flush(entity.Offset)
+		switch entity.Type {
+		case model.EntityLink:
+			b.WriteString(`<a href="` + template.HTMLEscapeString(entity.URL) + `" rel="noopener nofollow">`)
+		case model.EntityPre:
+			if entity.Language != "" {
+				b.WriteString(`<pre><code class="language-` + template.HTMLEscapeString(entity.Language) + `">`)
+			} else {
+				b.WriteString("<pre><code>")
+			}
+		default:
+			b.WriteString(entityOpenTags[entity.Type])
+		}
+		open = append(open, entity)
+	}
+	closeUntil(len(runes))
+	flush(len(runes))
+	return template.HTML(b.String())
+}
+
+var (
+	entityOpenTags = map[model.EntityType]string{
+		model.EntityBold:          "<strong>",
+		model.EntityItalic:        "<em>",
+		model.EntityStrikethrough: "<s>",
+		model.EntityCode:          "<code>",
+	}
+	entityCloseTags = map[model.EntityType]string{
+		model.EntityBold:          "</strong>",
+		model.EntityItalic:        "</em>",
+		model.EntityStrikethrough: "</s>",
+		model.EntityCode:          "</code>",
+		model.EntityPre:           "</code></pre>",
+		model.EntityLink:          "</a>",
+	}
+)
+
+func (h *htmlExportWriter) begin(conv *model.Conversation, exportedAt time.Time) error {
+	return exportHTML.ExecuteTemplate(h.w, "begin", exportedConversation{ID: conv.ID, Name: conv.Name, Type: conv.Type, ExportedAt: exportedAt})
+}
+
+func (h *htmlExportWriter) message(msg *model.Message) error {
+	return exportHTML.ExecuteTemplate(h.w, "message", newExportedMessage(msg))
+}
+
+func (h *htmlExportWriter) end() error {
+	return exportHTML.ExecuteTemplate(h.w, "end", nil)
+}
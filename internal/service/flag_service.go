@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	featureFlagsKey = "gotalk:flags" // HASH flag name -> value, shared by every instance
+	flagCacheTTL    = 5 * time.Second
+
+	flagUploadsEnabled   = "uploads_enabled"
+	flagRegistrationOpen = "registration_open"
+	flagMaxGroupSize     = "max_group_size"
+)
+
+// FlagService reads and changes the runtime feature flags. Flags live in Redis
+// so a change reaches every instance; each instance caches them for a few seconds.
+type FlagService struct {
+	rdb *redis.Client
+
+	mu        sync.Mutex
+	cached    model.FeatureFlags
+	fetchedAt time.Time
+}
+
+func NewFlagService(rdb *redis.Client) *FlagService {
+	return &FlagService{rdb: rdb, cached: model.DefaultFeatureFlags()}
+}
+
+// Get returns the current flags. If Redis is unreachable the last known flags
+// (or the defaults) are returned, so a Redis outage doesn't switch features off.
+func (s *FlagService) Get(ctx context.Context) model.FeatureFlags {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.fetchedAt) < flagCacheTTL {
+		return s.cached
+	}
+
+	flags, err := s.load(ctx)
+	if err != nil {
+		log.Printf("⚠️  Failed to load feature flags: %v", err)
+	} else {
+		s.cached = flags
+	}
+	s.fetchedAt = time.Now()
+	return s.cached
+}
+
+// Update changes the given flags and returns the resulting set
+func (s *FlagService) Update(ctx context.Context, req model.UpdateFeatureFlagsRequest) (model.FeatureFlags, error) {
+	values := map[string]interface{}{}
+	if req.UploadsEnabled != nil {
+		values[flagUploadsEnabled] = strconv.FormatBool(*req.UploadsEnabled)
+	}
+	if req.RegistrationOpen != nil {
+		values[flagRegistrationOpen] = strconv.FormatBool(*req.RegistrationOpen)
+	}
+	if req.MaxGroupSize != nil {
+		values[flagMaxGroupSize] = strconv.Itoa(*req.MaxGroupSize)
+	}
+	if len(values) > 0 {
+		if err := s.rdb.HSet(ctx, featureFlagsKey, values).Err(); err != nil {
+			return model.FeatureFlags{}, fmt.Errorf("failed to save feature flags: %w", err)
+		}
+	}
+
+	flags, err := s.load(ctx)
+	if err != nil {
+		return model.FeatureFlags{}, err
+	}
+	s.mu.Lock()
+	s.cached, s.fetchedAt = flags, time.Now()
+	s.mu.Unlock()
+	return flags, nil
+}
+
+// load reads the flags from Redis, using the defaults for unset or malformed ones
+func (s *FlagService) load(ctx context.Context) (model.FeatureFlags, error) {
+	values, err := s.rdb.HGetAll(ctx, featureFlagsKey).Result()
+	if err != nil {
+		return model.FeatureFlags{}, err
+	}
+
+	flags := model.DefaultFeatureFlags()
+	if b, err := strconv.ParseBool(values[flagUploadsEnabled]); err == nil {
+		flags.UploadsEnabled = b
+	}
+	if b, err := strconv.ParseBool(values[flagRegistrationOpen]); err == nil {
+		flags.RegistrationOpen = b
+	}
+	if n, err := strconv.Atoi(values[flagMaxGroupSize]); err == nil && n >= 0 {
+		flags.MaxGroupSize = n
+	}
+	return flags, nil
+}
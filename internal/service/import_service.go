@@ -0,0 +1,438 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/pkg/chatimport"
+	"github.com/quocanhngo/gotalk/pkg/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	importQueueKey     = "gotalk:import:queue"
+	importJobKeyPrefix = "gotalk:import:job:" // import ID -> job JSON
+	importJobTTL       = 7 * 24 * time.Hour
+	importTimeout      = time.Hour
+	importBatchSize    = 200
+
+	// Attachments larger than a regular upload are left out
+	maxImportAttachmentSize = 50 << 20
+)
+
+// importFolders maps the attachment types accepted from an archive to their
+// storage folder, matching what regular uploads allow
+var importFolders = map[string]string{
+	"image/jpeg":      "images",
+	"image/png":       "images",
+	"image/gif":       "images",
+	"image/webp":      "images",
+	"video/mp4":       "videos",
+	"video/webm":      "videos",
+	"video/quicktime": "videos",
+	"audio/mpeg":      "audio",
+	"audio/ogg":       "audio",
+	"audio/wav":       "audio",
+	"application/pdf": "files",
+}
+
+var importAttachmentTypes = map[string]model.AttachmentType{
+	"images": model.AttachmentTypeImage,
+	"videos": model.AttachmentTypeVideo,
+	"audio":  model.AttachmentTypeAudio,
+	"files":  model.AttachmentTypeFile,
+}
+
+// ImportService turns chat exports from other apps (WhatsApp, Telegram) into
+// GoTalk conversations. Archives are parked in MinIO and processed by a worker
+// that any instance can run; progress is kept in Redis.
+type ImportService struct {
+	convRepo    *repository.ConversationRepository
+	msgRepo     *repository.MessageRepository
+	blobService *BlobService
+	storage     *storage.MinIOStorage
+	notifCenter *NotificationCenterService
+	rdb         *redis.Client
+	searchIndex *MessageIndex // optional
+}
+
+func NewImportService(
+	convRepo *repository.ConversationRepository,
+	msgRepo *repository.MessageRepository,
+	blobService *BlobService,
+	storage *storage.MinIOStorage,
+	notifCenter *NotificationCenterService,
+	rdb *redis.Client,
+) *ImportService {
+	return &ImportService{
+		convRepo:    convRepo,
+		msgRepo:     msgRepo,
+		blobService: blobService,
+		storage:     storage,
+		notifCenter: notifCenter,
+		rdb:         rdb,
+	}
+}
+
+// UseSearchIndex also adds imported messages to an external search index
+func (s *ImportService) UseSearchIndex(index *MessageIndex) {
+	s.searchIndex = index
+}
+
+// Enabled reports whether imports can run (storage available)
+func (s *ImportService) Enabled() bool {
+	return s != nil && s.storage != nil
+}
+
+// importJob is the stored form of an import, with the details the API hides
+type importJob struct {
+	model.ChatImport
+	ArchiveKey string `json:"archive_key"`
+	Timezone   string `json:"timezone,omitempty"`
+}
+
+// Start parks an uploaded archive and queues it for import
+func (s *ImportService) Start(ctx context.Context, userID uuid.UUID, req model.ImportRequest, archive io.Reader, size int64) (*model.ChatImport, error) {
+	if !s.Enabled() {
+		return nil, ErrImportsUnavailable
+	}
+
+	job := &importJob{
+		ChatImport: model.ChatImport{
+			ID:        uuid.New(),
+			UserID:    userID,
+			Source:    req.Source,
+			Status:    model.ImportStatusPending,
+			CreatedAt: time.Now(),
+		},
+		Timezone: req.Timezone,
+	}
+	job.ArchiveKey = fmt.Sprintf("%s/%s.zip", storage.ImportsFolder, job.ID)
+
+	if _, err := s.storage.UploadFromReader(ctx, archive, size, job.ArchiveKey, "application/zip"); err != nil {
+		return nil, err
+	}
+	if err := s.save(ctx, job); err != nil {
+		return nil, err
+	}
+	if err := s.rdb.LPush(ctx, importQueueKey, job.ID.String()).Err(); err != nil {
+		return nil, err
+	}
+	return &job.ChatImport, nil
+}
+
+// Get returns one of the user's imports
+func (s *ImportService) Get(importID, userID uuid.UUID) (*model.ChatImport, error) {
+	if !s.Enabled() {
+		return nil, ErrImportsUnavailable
+	}
+
+	job, err := s.load(context.Background(), importID)
+	if errors.Is(err, redis.Nil) || (err == nil && job.UserID != userID) {
+		return nil, ErrImportNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job.ChatImport, nil
+}
+
+// Run processes queued imports until ctx is cancelled
+func (s *ImportService) Run(ctx context.Context) {
+	if !s.Enabled() {
+		return
+	}
+
+	log.Println("📥 Chat import worker started")
+	for {
+		result, err := s.rdb.BRPop(ctx, 5*time.Second, importQueueKey).Result()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if !errors.Is(err, redis.Nil) {
+				log.Printf("⚠️  Import queue error: %v", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		// BRPOP returns [key, value]
+		importID, err := uuid.Parse(result[1])
+		if err != nil {
+			continue
+		}
+		s.process(ctx, importID)
+	}
+}
+
+// process imports one archive and deletes it afterwards
+func (s *ImportService) process(ctx context.Context, importID uuid.UUID) {
+	job, err := s.load(ctx, importID)
+	if err != nil {
+		log.Printf("⚠️  Import %s not found: %v", importID, err)
+		return
+	}
+	defer s.storage.Delete(context.Background(), job.ArchiveKey)
+
+	job.Status = model.ImportStatusProcessing
+	_ = s.save(ctx, job)
+
+	ctx, cancel := context.WithTimeout(ctx, importTimeout)
+	defer cancel()
+
+	if err := s.importArchive(ctx, job); err != nil {
+		log.Printf("❌ Import %s failed: %v", job.ID, err)
+		job.Status = model.ImportStatusFailed
+		job.Error = importErrorMessage(err)
+		_ = s.save(context.Background(), job)
+		return
+	}
+
+	now := time.Now()
+	job.Status = model.ImportStatusCompleted
+	job.CompletedAt = &now
+	_ = s.save(context.Background(), job)
+
+	_ = s.notifCenter.Notify([]uuid.UUID{job.UserID}, model.NotificationTypeImportDone,
+		"Your chat import is complete",
+		fmt.Sprintf("%d messages imported", job.ImportedMessages),
+		map[string]string{
+			"conversation_id": job.ConversationID.String(),
+			"import_id":       job.ID.String(),
+		})
+}
+
+// importArchive parses the archive and stores its messages in a new group
+// conversation owned by the importing user
+func (s *ImportService) importArchive(ctx context.Context, job *importJob) error {
+	loc := time.UTC
+	if job.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(job.Timezone); err != nil {
+			return err
+		}
+	}
+
+	tmp, err := os.CreateTemp("", "gotalk-import-*.zip")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := s.storage.DownloadToFile(ctx, job.ArchiveKey, tmp.Name()); err != nil {
+		return err
+	}
+	zr, err := zip.OpenReader(tmp.Name())
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidArchive, err)
+	}
+	defer zr.Close()
+
+	chat, err := chatimport.Parse(chatimport.Source(job.Source), &zr.Reader, chatimport.Options{Location: loc})
+	if err != nil {
+		return err
+	}
+	job.TotalMessages = len(chat.Messages)
+
+	conv := &model.Conversation{
+		Name:         truncateRunes(chat.Name, 100),
+		Type:         model.ConversationTypeGroup,
+		CreatorID:    &job.UserID,
+		ImportedFrom: job.Source,
+		Members:      []model.ConversationMember{{UserID: job.UserID, Role: model.MemberRoleAdmin}},
+	}
+	if err := s.convRepo.Create(conv); err != nil {
+		return err
+	}
+	job.ConversationID = &conv.ID
+	_ = s.save(ctx, job)
+
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[path.Clean(f.Name)] = f
+	}
+
+	batch := make([]model.Message, 0, importBatchSize)
+	for _, m := range chat.Messages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		msg := model.Message{
+			ConversationID: conv.ID,
+			SenderID:       job.UserID,
+			Content:        m.Text,
+			Type:           model.MessageTypeText,
+			Status:         model.MessageStatusRead,
+			Imported:       true,
+			ImportedSender: truncateRunes(m.Sender, 100),
+			CreatedAt:      m.Time,
+			UpdatedAt:      m.Time,
+		}
+		for _, name := range m.Attachments {
+			att, ok := s.storeAttachment(ctx, files[path.Clean(name)])
+			if !ok {
+				job.SkippedAttachments++
+				continue
+			}
+			att.CreatedAt = m.Time
+			msg.Attachments = append(msg.Attachments, *att)
+		}
+		if msg.Content == "" {
+			if len(msg.Attachments) == 0 {
+				continue
+			}
+			msg.Type = model.MessageType(msg.Attachments[0].Type)
+		}
+		batch = append(batch, msg)
+
+		if len(batch) == importBatchSize {
+			if err := s.saveBatch(ctx, batch); err != nil {
+				return err
+			}
+			job.ImportedMessages += len(batch)
+			batch = batch[:0]
+			_ = s.save(ctx, job)
+		}
+	}
+	if len(batch) > 0 {
+		if err := s.saveBatch(ctx, batch); err != nil {
+			return err
+		}
+		job.ImportedMessages += len(batch)
+	}
+
+	// The history is the user's own; don't show it as unread
+	_ = s.convRepo.UpdateLastRead(conv.ID, job.UserID)
+	return s.convRepo.TouchUpdatedAt(conv.ID)
+}
+
+// saveBatch stores a batch of imported messages and indexes them for search.
+// Indexing is best-effort: gotalkctl reindex-messages catches up on misses.
+func (s *ImportService) saveBatch(ctx context.Context, batch []model.Message) error {
+	if err := s.msgRepo.CreateImported(batch); err != nil {
+		return err
+	}
+	if s.searchIndex != nil {
+		if err := s.searchIndex.Index(ctx, batch...); err != nil {
+			log.Printf("⚠️  Failed to index imported messages: %v", err)
+		}
+	}
+	return nil
+}
+
+// storeAttachment uploads a file from the archive through the blob store.
+// Missing, oversized and unsupported files are skipped.
+func (s *ImportService) storeAttachment(ctx context.Context, f *zip.File) (*model.MessageAttachment, bool) {
+	if f == nil || f.UncompressedSize64 > maxImportAttachmentSize || s.blobService == nil {
+		return nil, false
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return nil, false
+	}
+	defer rc.Close()
+
+	tmp, err := os.CreateTemp("", "gotalk-import-file-*")
+	if err != nil {
+		return nil, false
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, io.LimitReader(rc, maxImportAttachmentSize))
+	if err != nil {
+		return nil, false
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, false
+	}
+
+	contentType, err := storage.SniffContentType(tmp)
+	if err != nil {
+		return nil, false
+	}
+	folder, ok := importFolders[contentType]
+	if !ok {
+		return nil, false
+	}
+
+	header := &multipart.FileHeader{
+		Filename: path.Base(f.Name),
+		Size:     size,
+		Header:   textproto.MIMEHeader{"Content-Type": {contentType}},
+	}
+	result, err := s.blobService.Store(ctx, tmp, header, folder)
+	if err != nil {
+		log.Printf("⚠️  Import: failed to store %s: %v", f.Name, err)
+		return nil, false
+	}
+
+	return &model.MessageAttachment{
+		Type:     importAttachmentTypes[folder],
+		URL:      result.URL,
+		FileName: header.Filename,
+		FileSize: result.FileSize,
+		MimeType: contentType,
+		// Reference the deduplicated blob so it isn't purged while in use
+		BlobHash: s.blobService.RetainURL(result.URL),
+	}, true
+}
+
+func (s *ImportService) load(ctx context.Context, importID uuid.UUID) (*importJob, error) {
+	data, err := s.rdb.Get(ctx, importJobKeyPrefix+importID.String()).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var job importJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *ImportService) save(ctx context.Context, job *importJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return s.rdb.Set(ctx, importJobKeyPrefix+job.ID.String(), data, importJobTTL).Err()
+}
+
+var errInvalidArchive = errors.New("the file is not a valid zip archive")
+
+// importErrorMessage explains a failed import to the user; unexpected errors
+// get a generic message
+func importErrorMessage(err error) string {
+	for _, known := range []error{errInvalidArchive, chatimport.ErrNoTranscript, chatimport.ErrNoMessages, chatimport.ErrAccountExport} {
+		if errors.Is(err, known) {
+			return known.Error()
+		}
+	}
+	return "the import failed. Please try again"
+}
+
+// truncateRunes shortens s to at most n characters
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+)
+
+const (
+	insightsBackfillDays = 90 // days rolled up on the first run
+	insightsTopMembers   = 50
+)
+
+// InsightsService rolls group activity up into daily stats in the background
+// and summarizes them for the group's admins, so insights never scan messages
+type InsightsService struct {
+	repo     *repository.InsightsRepository
+	convRepo *repository.ConversationRepository
+	userRepo *repository.UserRepository
+	chat     *ChatService
+}
+
+func NewInsightsService(
+	repo *repository.InsightsRepository,
+	convRepo *repository.ConversationRepository,
+	userRepo *repository.UserRepository,
+	chat *ChatService,
+) *InsightsService {
+	return &InsightsService{repo: repo, convRepo: convRepo, userRepo: userRepo, chat: chat}
+}
+
+// Run rolls up the days since the last rollup, today included, every
+// interval until ctx is cancelled
+func (s *InsightsService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RollUp(ctx); err != nil {
+			log.Printf("⚠️  Conversation insights rollup failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RollUp recomputes the rollups from the last day rolled up, which may have
+// been partial, through today
+func (s *InsightsService) RollUp(ctx context.Context) error {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	day := today.AddDate(0, 0, -insightsBackfillDays)
+	latest, err := s.repo.LatestDay()
+	if err != nil {
+		return err
+	}
+	if latest != nil && latest.After(day) {
+		day = time.Date(latest.Year(), latest.Month(), latest.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	for ; !day.After(today) && ctx.Err() == nil; day = day.AddDate(0, 0, 1) {
+		if err := s.repo.RollUp(day); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Insights summarizes a group's activity over a range of days for members
+// whose role manages its settings
+func (s *InsightsService) Insights(convID, userID uuid.UUID, req model.InsightsRequest) (*model.ConversationInsights, error) {
+	if err := s.chat.Can(userID, convID, model.PermissionManageSettings); err != nil {
+		return nil, err
+	}
+	conv, err := s.convRepo.FindByID(convID)
+	if err != nil {
+		return nil, err
+	}
+	if conv.Type != model.ConversationTypeGroup {
+		return nil, ErrInsightsGroupOnly
+	}
+
+	from, to, days, err := statsRange(req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := s.repo.ListDays(convID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	top, err := s.repo.TopMembers(convID, from, to, insightsTopMembers)
+	if err != nil {
+		return nil, err
+	}
+
+	insights := &model.ConversationInsights{
+		From:    from.Format(statsDayFormat),
+		To:      to.Format(statsDayFormat),
+		Days:    make([]model.InsightsDay, 0, days),
+		Hours:   make([]int64, 24),
+		Members: make([]model.MemberActivity, 0, len(top)),
+	}
+	byDay := make(map[string]*model.ConversationDailyStat, len(stats))
+	for i := range stats {
+		byDay[stats[i].Day.Format(statsDayFormat)] = &stats[i]
+	}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		point := model.InsightsDay{Day: day.Format(statsDayFormat)}
+		if stat, ok := byDay[point.Day]; ok {
+			point.Messages, point.Joins, point.Leaves = stat.Messages, stat.Joins, stat.Leaves
+			for hour, n := range stat.Hours {
+				if hour < len(insights.Hours) {
+					insights.Hours[hour] += n
+				}
+			}
+		}
+		insights.Messages += point.Messages
+		insights.Joins += point.Joins
+		insights.Leaves += point.Leaves
+		insights.Days = append(insights.Days, point)
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(top))
+	for _, m := range top {
+		userIDs = append(userIDs, m.UserID)
+	}
+	users, err := s.userRepo.FindByIDs(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[uuid.UUID]string, len(users))
+	for i := range users {
+		names[users[i].ID] = users[i].PublicName()
+	}
+	for _, m := range top {
+		insights.Members = append(insights.Members, model.MemberActivity{UserID: m.UserID, Name: names[m.UserID], Messages: m.Messages})
+	}
+	return insights, nil
+}
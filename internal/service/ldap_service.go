@@ -0,0 +1,430 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/pkg/ldap"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	ldapSyncLockKeyPrefix  = "gotalk:ldap:sync:" // + date (UTC): only one instance syncs each night
+	ldapSyncLockExpiration = 25 * time.Hour
+	ldapSyncCheckInterval  = time.Hour
+	ldapPageSize           = 500
+	ldapExternalIDPrefix   = "ldap:" // users and groups synced from the directory have external IDs ldap:<id> and ldap:<dn>
+	ldapGroupNameAttr      = "cn"
+)
+
+// LDAPSettings says how to reach the directory and map its entries
+type LDAPSettings struct {
+	URL          string // ldap:// or ldaps://; empty disables LDAP
+	StartTLS     bool
+	BindDN       string // service account used to search; empty searches anonymously
+	BindPassword string
+	BaseDN       string
+	UserFilter   string
+
+	AttrID          string // stable, unique ID of a user entry
+	AttrEmail       string
+	AttrName        string
+	AttrDisplayName string
+
+	GroupFilter     string // empty maps no groups
+	GroupMemberAttr string // lists member DNs
+	SyncHour        int    // UTC
+}
+
+// LDAPService signs users in with their LDAP / Active Directory password and
+// syncs the directory nightly: users are created, updated and deactivated, and
+// groups become directory-managed group conversations (like SCIM groups).
+// Accounts that already exist with another sign-in method are left alone.
+type LDAPService struct {
+	settings LDAPSettings
+	userRepo *repository.UserRepository
+	convRepo *repository.ConversationRepository
+	scim     *SCIMService // deactivation and group membership work as for SCIM
+	rdb      *redis.Client
+}
+
+// ldapUser is a user entry mapped to user fields
+type ldapUser struct {
+	dn          string
+	externalID  string
+	email       string
+	name        string
+	displayName string
+}
+
+func NewLDAPService(
+	settings LDAPSettings,
+	userRepo *repository.UserRepository,
+	convRepo *repository.ConversationRepository,
+	scim *SCIMService,
+	rdb *redis.Client,
+) *LDAPService {
+	return &LDAPService{
+		settings: settings,
+		userRepo: userRepo,
+		convRepo: convRepo,
+		scim:     scim,
+		rdb:      rdb,
+	}
+}
+
+// Enabled reports whether an LDAP server is configured
+func (s *LDAPService) Enabled() bool {
+	return s.settings.URL != ""
+}
+
+// Authenticate checks an email and password against the directory: it finds
+// the user's entry with the service account, then binds as that entry with the
+// password. On success it returns the user's account, created on first sign-in.
+func (s *LDAPService) Authenticate(ctx context.Context, email, password string) (*model.User, error) {
+	conn, err := s.connect(ctx)
+	if err != nil {
+		log.Printf("⚠️  LDAP server unavailable: %v", err)
+		return nil, ErrLDAPUnavailable
+	}
+	defer conn.Close()
+
+	filter := fmt.Sprintf("(&%s(%s=%s))", wrapFilter(s.settings.UserFilter), s.settings.AttrEmail, ldap.EscapeFilter(email))
+	entries, err := conn.Search(ctx, ldap.SearchRequest{
+		BaseDN:     s.settings.BaseDN,
+		Scope:      ldap.ScopeWholeSubtree,
+		Filter:     filter,
+		Attributes: s.userAttributes(),
+	})
+	if err != nil {
+		log.Printf("⚠️  LDAP user search failed: %v", err)
+		return nil, ErrLDAPUnavailable
+	}
+	if len(entries) != 1 {
+		return nil, ErrInvalidCredentials // no entry, or an ambiguous email
+	}
+
+	if err := conn.Bind(ctx, entries[0].DN, password); err != nil {
+		if ldap.IsInvalidCredentials(err) || errors.Is(err, ldap.ErrEmptyPassword) {
+			return nil, ErrInvalidCredentials
+		}
+		log.Printf("⚠️  LDAP bind failed: %v", err)
+		return nil, ErrLDAPUnavailable
+	}
+
+	entry, ok := s.mapUser(entries[0])
+	if !ok {
+		log.Printf("⚠️  LDAP entry %s has no %s", entries[0].DN, s.settings.AttrID)
+		return nil, ErrInvalidCredentials
+	}
+	user, _, _, err := s.upsertUser(entry)
+	if err != nil {
+		return nil, err
+	}
+	if user.AuthProvider != model.AuthProviderLDAP {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// ==================== Sync ====================
+
+// Run syncs the directory once a night at the configured hour (UTC),
+// blocking until ctx is cancelled
+func (s *LDAPService) Run(ctx context.Context) {
+	ticker := time.NewTicker(ldapSyncCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			now = now.UTC()
+			if now.Hour() != s.settings.SyncHour {
+				continue
+			}
+			lockKey := ldapSyncLockKeyPrefix + now.Format("2006-01-02")
+			acquired, err := s.rdb.SetNX(ctx, lockKey, "1", ldapSyncLockExpiration).Result()
+			if err != nil || !acquired {
+				continue
+			}
+			if _, err := s.Sync(ctx); err != nil {
+				log.Printf("⚠️  LDAP sync failed: %v", err)
+			}
+		}
+	}
+}
+
+// Sync brings accounts and group conversations in line with the directory.
+// LDAP accounts whose entries are gone are deactivated, and reactivated if
+// they come back. Groups removed from the directory keep their conversation.
+func (s *LDAPService) Sync(ctx context.Context) (*model.LDAPSyncResult, error) {
+	if !s.Enabled() {
+		return nil, ErrLDAPNotConfigured
+	}
+	conn, err := s.connect(ctx)
+	if err != nil {
+		log.Printf("⚠️  LDAP server unavailable: %v", err)
+		return nil, ErrLDAPUnavailable
+	}
+	defer conn.Close()
+
+	entries, err := conn.Search(ctx, ldap.SearchRequest{
+		BaseDN:     s.settings.BaseDN,
+		Scope:      ldap.ScopeWholeSubtree,
+		Filter:     s.settings.UserFilter,
+		Attributes: s.userAttributes(),
+		PageSize:   ldapPageSize,
+	})
+	if err != nil {
+		log.Printf("⚠️  LDAP user search failed: %v", err)
+		return nil, ErrLDAPUnavailable
+	}
+
+	result := &model.LDAPSyncResult{}
+	seen := make(map[string]bool, len(entries))      // external IDs, including of entries that failed to sync
+	byDN := make(map[string]uuid.UUID, len(entries)) // normalized DN -> user, for group members
+	for _, e := range entries {
+		entry, ok := s.mapUser(e)
+		if !ok {
+			result.Skipped++
+			continue
+		}
+		seen[entry.externalID] = true
+		user, created, updated, err := s.upsertUser(entry)
+		if err != nil {
+			log.Printf("⚠️  LDAP sync of %s failed: %v", entry.email, err)
+			continue
+		}
+		result.Users++
+		if created {
+			result.Created++
+		}
+		if updated {
+			result.Updated++
+		}
+		if user.AuthProvider == model.AuthProviderLDAP && !user.IsActive() {
+			if err := s.scim.setActive(user, true); err != nil {
+				log.Printf("⚠️  Failed to reactivate %s: %v", user.Email, err)
+			} else {
+				result.Reactivated++
+			}
+		}
+		byDN[normalizeDN(entry.dn)] = user.ID
+	}
+
+	// No entries at all is more likely a broken filter than an empty
+	// directory, so it deactivates no one
+	if result.Users > 0 {
+		users, err := s.userRepo.FindByAuthProvider(model.AuthProviderLDAP)
+		if err != nil {
+			return result, err
+		}
+		for i := range users {
+			if (users[i].ExternalID != nil && seen[*users[i].ExternalID]) || !users[i].IsActive() {
+				continue
+			}
+			if err := s.scim.setActive(&users[i], false); err != nil {
+				log.Printf("⚠️  Failed to deactivate %s: %v", users[i].Email, err)
+				continue
+			}
+			result.Deactivated++
+		}
+	}
+
+	if s.settings.GroupFilter != "" {
+		if err := s.syncGroups(ctx, conn, byDN, result); err != nil {
+			return result, err
+		}
+	}
+
+	log.Printf("📇 LDAP sync: %d users (%d created, %d updated, %d deactivated, %d reactivated), %d groups",
+		result.Users, result.Created, result.Updated, result.Deactivated, result.Reactivated, result.Groups)
+	return result, nil
+}
+
+// syncGroups creates or updates a provisioned group conversation for every
+// directory group, with the members found among the synced users
+func (s *LDAPService) syncGroups(ctx context.Context, conn *ldap.Conn, byDN map[string]uuid.UUID, result *model.LDAPSyncResult) error {
+	groups, err := conn.Search(ctx, ldap.SearchRequest{
+		BaseDN:     s.settings.BaseDN,
+		Scope:      ldap.ScopeWholeSubtree,
+		Filter:     s.settings.GroupFilter,
+		Attributes: []string{ldapGroupNameAttr, s.settings.GroupMemberAttr},
+		PageSize:   ldapPageSize,
+	})
+	if err != nil {
+		log.Printf("⚠️  LDAP group search failed: %v", err)
+		return ErrLDAPUnavailable
+	}
+
+	for _, group := range groups {
+		externalID := ldapExternalIDPrefix + normalizeDN(group.DN)
+		if len(externalID) > 255 {
+			log.Printf("⚠️  Skipped LDAP group %s: DN too long", group.DN)
+			continue
+		}
+		name := group.Get(ldapGroupNameAttr)
+		if name == "" {
+			name = group.DN
+		}
+		resource := model.SCIMGroup{DisplayName: name, ExternalID: externalID}
+		for _, memberDN := range group.GetAll(s.settings.GroupMemberAttr) {
+			if id, ok := byDN[normalizeDN(memberDN)]; ok {
+				resource.Members = append(resource.Members, model.SCIMMember{Value: id.String()})
+			}
+		}
+
+		existing, _, err := s.convRepo.ListProvisioned(repository.DirectoryFilter{ExternalID: externalID}, 0, 1)
+		if err != nil {
+			return err
+		}
+		if len(existing) == 0 {
+			_, err = s.scim.CreateGroup(resource)
+		} else {
+			_, err = s.scim.ReplaceGroup(existing[0].ID, resource)
+		}
+		if err != nil {
+			log.Printf("⚠️  LDAP sync of group %s failed: %v", group.DN, err)
+			continue
+		}
+		result.Groups++
+	}
+	return nil
+}
+
+// ==================== Helpers ====================
+
+// connect opens a connection bound as the service account
+func (s *LDAPService) connect(ctx context.Context) (*ldap.Conn, error) {
+	conn, err := ldap.Dial(ctx, s.settings.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.settings.StartTLS {
+		if err := conn.StartTLS(ctx, nil); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if s.settings.BindDN != "" {
+		if err := conn.Bind(ctx, s.settings.BindDN, s.settings.BindPassword); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("service account bind: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+func (s *LDAPService) userAttributes() []string {
+	attrs := []string{s.settings.AttrID, s.settings.AttrEmail, s.settings.AttrName}
+	if s.settings.AttrDisplayName != "" {
+		attrs = append(attrs, s.settings.AttrDisplayName)
+	}
+	return attrs
+}
+
+// mapUser reads the user fields of an entry; entries without an ID or email can't be mapped
+func (s *LDAPService) mapUser(e *ldap.Entry) (ldapUser, bool) {
+	id := e.Get(s.settings.AttrID)
+	email := strings.ToLower(strings.TrimSpace(e.Get(s.settings.AttrEmail)))
+	if id == "" || !strings.Contains(email, "@") {
+		return ldapUser{}, false
+	}
+	entry := ldapUser{
+		dn:          e.DN,
+		externalID:  ldapExternalIDPrefix + printableID(id),
+		email:       email,
+		name:        strings.TrimSpace(e.Get(s.settings.AttrName)),
+		displayName: strings.TrimSpace(e.Get(s.settings.AttrDisplayName)),
+	}
+	if entry.name == "" {
+		entry.name, _, _ = strings.Cut(email, "@")
+	}
+	return entry, true
+}
+
+// upsertUser finds the account of a directory entry, by its ID then by email,
+// and brings an LDAP account's profile in line with the entry; an account that
+// doesn't exist yet is created. It reports whether it created or updated one.
+func (s *LDAPService) upsertUser(entry ldapUser) (*model.User, bool, bool, error) {
+	user, err := s.userRepo.FindByExternalID(entry.externalID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		user, err = s.userRepo.FindByEmail(entry.email)
+	}
+	if err == nil {
+		if user.AuthProvider != model.AuthProviderLDAP {
+			return user, false, false, nil // signs in another way; only its group memberships follow the directory
+		}
+		if user.Email == entry.email && user.Name == entry.name && user.DisplayName == entry.displayName &&
+			user.ExternalID != nil && *user.ExternalID == entry.externalID {
+			return user, false, false, nil
+		}
+		if err := s.userRepo.UpdateDirectoryProfile(user.ID, entry.name, entry.displayName, entry.email, &entry.externalID); err != nil {
+			return nil, false, false, fmt.Errorf("failed to update user: %w", err)
+		}
+		user.Email, user.Name, user.DisplayName, user.ExternalID = entry.email, entry.name, entry.displayName, &entry.externalID
+		return user, false, true, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, false, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	now := time.Now()
+	user = &model.User{
+		Email:                 entry.email,
+		Name:                  entry.name,
+		DisplayName:           entry.displayName,
+		ExternalID:            &entry.externalID,
+		AuthProvider:          model.AuthProviderLDAP,
+		EmailVerifiedAt:       &now,
+		Theme:                 "system",
+		IsNotificationEnabled: true,
+		Language:              "vi",
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, false, false, fmt.Errorf("failed to create user: %w", err)
+	}
+	log.Printf("👤 Created %s from LDAP", entry.email)
+	return user, true, false, nil
+}
+
+// wrapFilter parenthesizes a filter written without the outer parentheses
+func wrapFilter(filter string) string {
+	filter = strings.TrimSpace(filter)
+	if strings.HasPrefix(filter, "(") {
+		return filter
+	}
+	return "(" + filter + ")"
+}
+
+// normalizeDN makes DNs from different attributes comparable
+func normalizeDN(dn string) string {
+	parts := strings.Split(dn, ",")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return strings.ToLower(strings.Join(parts, ","))
+}
+
+// printableID hex-encodes binary IDs such as Active Directory's objectGUID
+func printableID(id string) string {
+	for _, r := range id {
+		if r == utf8.RuneError || !unicode.IsPrint(r) {
+			return hex.EncodeToString([]byte(id))
+		}
+	}
+	return id
+}
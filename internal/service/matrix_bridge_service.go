@@ -0,0 +1,643 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/internal/ws"
+	"github.com/quocanhngo/gotalk/pkg/matrix"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	matrixTxnKeyPrefix    = "gotalk:matrix:txn:"    // transactions already handled
+	matrixJoinedKeyPrefix = "gotalk:matrix:joined:" // + room ID: local users whose virtual user is in the room
+	matrixTypingKeyPrefix = "gotalk:matrix:typing:" // + room ID: remote users typing there
+	matrixUsersKey        = "gotalk:matrix:users"   // local user ID -> display name of its registered virtual user
+
+	matrixTxnTTL        = 24 * time.Hour
+	matrixTypingTTL     = time.Minute
+	matrixTypingTimeout = 30 * time.Second // how long a relayed typing indicator lasts without a refresh
+	matrixSyncInterval  = time.Minute
+	matrixRelayTimeout  = 10 * time.Second
+
+	// matrixGhostDomain is the email domain of the local stand-ins for remote
+	// Matrix users; .invalid can never receive mail
+	matrixGhostDomain = "matrix.invalid"
+)
+
+// MatrixBridgeService bridges group conversations to Matrix rooms as an
+// application service. Local members post through virtual users
+// (@<prefix><user id>:<server>) the bridge joins to the room; remote Matrix
+// users become "ghost" GoTalk accounts (auth provider matrix) that can't sign
+// in. Messages, membership and typing are relayed both ways; attachments are
+// relayed as links.
+type MatrixBridgeService struct {
+	client      *matrix.Client // nil when the bridge isn't configured
+	matrixRepo  *repository.MatrixRepository
+	convRepo    *repository.ConversationRepository
+	members     *MembershipCache
+	userRepo    *repository.UserRepository
+	chatService *ChatService
+	hub         *ws.Hub
+	rdb         *redis.Client
+}
+
+func NewMatrixBridgeService(
+	client *matrix.Client,
+	matrixRepo *repository.MatrixRepository,
+	convRepo *repository.ConversationRepository,
+	members *MembershipCache,
+	userRepo *repository.UserRepository,
+	chatService *ChatService,
+	hub *ws.Hub,
+	rdb *redis.Client,
+) *MatrixBridgeService {
+	return &MatrixBridgeService{
+		client:      client,
+		matrixRepo:  matrixRepo,
+		convRepo:    convRepo,
+		members:     members,
+		userRepo:    userRepo,
+		chatService: chatService,
+		hub:         hub,
+		rdb:         rdb,
+	}
+}
+
+// Enabled reports whether a homeserver is configured
+func (s *MatrixBridgeService) Enabled() bool {
+	return s != nil && s.client != nil
+}
+
+// ==================== Room links ====================
+
+// ListLinks returns every bridged conversation
+func (s *MatrixBridgeService) ListLinks() ([]model.MatrixRoomLink, error) {
+	if !s.Enabled() {
+		return nil, ErrMatrixBridgeUnavailable
+	}
+	return s.matrixRepo.List()
+}
+
+// Link bridges a group conversation to a Matrix room. The bot joins the room
+// (it must be invited or the room public), then the members' virtual users.
+func (s *MatrixBridgeService) Link(ctx context.Context, req model.MatrixLinkRequest) (*model.MatrixRoomLink, error) {
+	if !s.Enabled() {
+		return nil, ErrMatrixBridgeUnavailable
+	}
+
+	conv, err := s.convRepo.FindByID(req.ConversationID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGroupNotFound
+		}
+		return nil, err
+	}
+	if conv.Type != model.ConversationTypeGroup {
+		return nil, ErrMatrixGroupOnly
+	}
+
+	roomID, err := s.client.Join(ctx, s.client.BotUserID(), req.Room)
+	if err != nil {
+		return nil, ErrMatrixJoinFailed.Wrap(err)
+	}
+	existing, err := s.matrixRepo.FindByRoom(roomID)
+	if err == nil && existing.ConversationID != conv.ID {
+		return nil, ErrMatrixRoomLinked
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	previous, err := s.matrixRepo.FindByConversation(conv.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	link := &model.MatrixRoomLink{ConversationID: conv.ID, RoomID: roomID}
+	if err := s.matrixRepo.Link(link); err != nil {
+		return nil, err
+	}
+	if previous != nil && previous.RoomID != roomID {
+		s.leaveRoom(ctx, previous.RoomID)
+	}
+
+	// The periodic sync catches up on any member that fails here
+	s.syncLink(ctx, link)
+	return link, nil
+}
+
+// Unlink stops bridging a conversation; the bridge's users leave the room
+func (s *MatrixBridgeService) Unlink(ctx context.Context, convID uuid.UUID) error {
+	if !s.Enabled() {
+		return ErrMatrixBridgeUnavailable
+	}
+
+	link, err := s.matrixRepo.FindByConversation(convID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrMatrixLinkNotFound
+		}
+		return err
+	}
+	if err := s.matrixRepo.Unlink(convID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrMatrixLinkNotFound
+		}
+		return err
+	}
+
+	s.leaveRoom(ctx, link.RoomID)
+	return nil
+}
+
+// leaveRoom makes the virtual users and the bot leave a room that's no longer bridged
+func (s *MatrixBridgeService) leaveRoom(ctx context.Context, roomID string) {
+	joinedKey := matrixJoinedKeyPrefix + roomID
+	joined, err := s.rdb.SMembers(ctx, joinedKey).Result()
+	if err != nil {
+		log.Printf("⚠️  Matrix: failed to list virtual users in %s: %v", roomID, err)
+	}
+	for _, id := range joined {
+		if err := s.client.Leave(ctx, s.client.VirtualUserID(id), roomID); err != nil {
+			log.Printf("⚠️  Matrix: virtual user %s failed to leave %s: %v", id, roomID, err)
+		}
+	}
+	if err := s.client.Leave(ctx, s.client.BotUserID(), roomID); err != nil {
+		log.Printf("⚠️  Matrix: bot failed to leave %s: %v", roomID, err)
+	}
+	s.rdb.Del(ctx, joinedKey, matrixTypingKeyPrefix+roomID)
+}
+
+// ==================== Membership sync ====================
+
+// Run keeps the virtual users in each bridged room in step with the
+// conversation's members, blocking until ctx is cancelled
+func (s *MatrixBridgeService) Run(ctx context.Context) {
+	if !s.Enabled() {
+		return
+	}
+
+	log.Println("🌉 Matrix bridge started")
+	ticker := time.NewTicker(matrixSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		s.sync(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *MatrixBridgeService) sync(ctx context.Context) {
+	links, err := s.matrixRepo.List()
+	if err != nil {
+		log.Printf("⚠️  Matrix: failed to list room links: %v", err)
+		return
+	}
+	for i := range links {
+		if ctx.Err() != nil {
+			return
+		}
+		s.syncLink(ctx, &links[i])
+	}
+}
+
+// syncLink joins the virtual users of local members to the room and makes
+// those of former members leave. Failures are logged and retried next sync.
+func (s *MatrixBridgeService) syncLink(ctx context.Context, link *model.MatrixRoomLink) {
+	memberIDs, err := s.members.MemberIDs(ctx, link.ConversationID)
+	if err != nil {
+		log.Printf("⚠️  Matrix: failed to load members of %s: %v", link.ConversationID, err)
+		return
+	}
+	members, err := s.userRepo.FindByIDs(memberIDs)
+	if err != nil {
+		log.Printf("⚠️  Matrix: failed to load members of %s: %v", link.ConversationID, err)
+		return
+	}
+
+	local := make(map[string]bool, len(members))
+	for i := range members {
+		member := &members[i]
+		if member.MatrixID != nil || !member.IsActive() {
+			continue
+		}
+		local[member.ID.String()] = true
+		if err := s.ensureJoined(ctx, link.RoomID, member); err != nil {
+			log.Printf("⚠️  Matrix: failed to join %s to %s: %v", member.ID, link.RoomID, err)
+		}
+	}
+
+	joinedKey := matrixJoinedKeyPrefix + link.RoomID
+	joined, err := s.rdb.SMembers(ctx, joinedKey).Result()
+	if err != nil {
+		log.Printf("⚠️  Matrix: failed to list virtual users in %s: %v", link.RoomID, err)
+		return
+	}
+	for _, id := range joined {
+		if local[id] {
+			continue
+		}
+		if err := s.client.Leave(ctx, s.client.VirtualUserID(id), link.RoomID); err != nil && !matrix.IsErrCode(err, "M_FORBIDDEN") {
+			log.Printf("⚠️  Matrix: virtual user %s failed to leave %s: %v", id, link.RoomID, err)
+			continue
+		}
+		s.rdb.SRem(ctx, joinedKey, id)
+	}
+}
+
+// ensureJoined makes sure a local user's virtual user exists, carries their
+// current name and is in the room
+func (s *MatrixBridgeService) ensureJoined(ctx context.Context, roomID string, user *model.User) error {
+	if err := s.ensureVirtualUser(ctx, user); err != nil {
+		return err
+	}
+
+	joinedKey := matrixJoinedKeyPrefix + roomID
+	joined, err := s.rdb.SIsMember(ctx, joinedKey, user.ID.String()).Result()
+	if err != nil {
+		return err
+	}
+	if joined {
+		return nil
+	}
+
+	virtualID := s.client.VirtualUserID(user.ID.String())
+	if err := s.client.Invite(ctx, s.client.BotUserID(), roomID, virtualID); err != nil {
+		return err
+	}
+	if _, err := s.client.Join(ctx, virtualID, roomID); err != nil {
+		return err
+	}
+	return s.rdb.SAdd(ctx, joinedKey, user.ID.String()).Err()
+}
+
+// ensureVirtualUser registers a local user's virtual user on first use and
+// keeps its display name current
+func (s *MatrixBridgeService) ensureVirtualUser(ctx context.Context, user *model.User) error {
+	name := user.PublicName()
+	current, err := s.rdb.HGet(ctx, matrixUsersKey, user.ID.String()).Result()
+	if err == nil && current == name {
+		return nil
+	}
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+
+	if errors.Is(err, redis.Nil) {
+		if err := s.client.Register(ctx, s.client.VirtualLocalpart(user.ID.String())); err != nil {
+			return err
+		}
+	}
+	if err := s.client.SetDisplayName(ctx, s.client.VirtualUserID(user.ID.String()), name); err != nil {
+		return err
+	}
+	return s.rdb.HSet(ctx, matrixUsersKey, user.ID.String(), name).Err()
+}
+
+// ==================== GoTalk -> Matrix ====================
+
+// RelayMessage posts a new message in a bridged conversation to its room,
+// as the sender's virtual user. The message ID is the transaction ID, so a
+// retried relay doesn't post twice.
+func (s *MatrixBridgeService) RelayMessage(ctx context.Context, msg *model.Message) error {
+	if msg.Imported || msg.Sender.MatrixID != nil {
+		return nil // came from Matrix, or history that never happened here
+	}
+	link, err := s.matrixRepo.FindByConversation(msg.ConversationID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	body := outboundText(msg)
+	if body == "" {
+		return nil
+	}
+	if err := s.ensureJoined(ctx, link.RoomID, &msg.Sender); err != nil {
+		return err
+	}
+
+	_, err = s.client.SendMessage(ctx, s.client.VirtualUserID(msg.SenderID.String()), link.RoomID, msg.ID.String(),
+		matrix.MessageContent{MsgType: matrix.MsgText, Body: body})
+	if matrix.IsErrCode(err, "M_FORBIDDEN") {
+		// Kicked from the room behind our back; rejoin on the retry
+		s.rdb.SRem(ctx, matrixJoinedKeyPrefix+link.RoomID, msg.SenderID.String())
+	}
+	return err
+}
+
+// outboundText is the message's text followed by links to its attachments
+func outboundText(msg *model.Message) string {
+	lines := []string{}
+	if msg.Content != "" {
+		lines = append(lines, msg.Content)
+	}
+	for _, att := range msg.Attachments {
+		lines = append(lines, att.URL)
+	}
+	if msg.FileURL != "" && len(msg.Attachments) == 0 {
+		lines = append(lines, msg.FileURL)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RelayTyping forwards a local member's typing indicator to a bridged room.
+// It runs in the background so the WebSocket read loop isn't held up.
+func (s *MatrixBridgeService) RelayTyping(convID, userID uuid.UUID, typing bool) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), matrixRelayTimeout)
+		defer cancel()
+
+		link, err := s.matrixRepo.FindByConversation(convID)
+		if err != nil {
+			return // not bridged
+		}
+		user, err := s.userRepo.FindByID(userID)
+		if err != nil || user.MatrixID != nil {
+			return
+		}
+		if err := s.ensureJoined(ctx, link.RoomID, user); err != nil {
+			log.Printf("⚠️  Matrix: failed to join %s to %s: %v", userID, link.RoomID, err)
+			return
+		}
+		if err := s.client.SetTyping(ctx, s.client.VirtualUserID(userID.String()), link.RoomID, typing, matrixTypingTimeout); err != nil {
+			log.Printf("⚠️  Matrix: failed to relay typing to %s: %v", link.RoomID, err)
+		}
+	}()
+}
+
+// ==================== Matrix -> GoTalk ====================
+
+// HandleTransaction processes a batch of events pushed by the homeserver.
+// The homeserver retries a transaction until it's acknowledged, so handled
+// transaction IDs are remembered. Events that fail are logged and skipped
+// rather than failing the whole transaction, which would block the room.
+func (s *MatrixBridgeService) HandleTransaction(ctx context.Context, txnID string, txn *matrix.Transaction) error {
+	if !s.Enabled() {
+		return ErrMatrixBridgeUnavailable
+	}
+
+	txnKey := matrixTxnKeyPrefix + txnID
+	handled, err := s.rdb.Exists(ctx, txnKey).Result()
+	if err != nil {
+		return err
+	}
+	if handled > 0 {
+		return nil
+	}
+
+	for i := range txn.Events {
+		event := &txn.Events[i]
+		if err := s.handleEvent(ctx, event); err != nil {
+			log.Printf("⚠️  Matrix: failed to handle %s %s in %s: %v", event.Type, event.EventID, event.RoomID, err)
+		}
+	}
+	for _, event := range txn.EphemeralEvents() {
+		if event.Type != matrix.EventTyping {
+			continue
+		}
+		if err := s.handleTyping(ctx, &event); err != nil {
+			log.Printf("⚠️  Matrix: failed to handle typing in %s: %v", event.RoomID, err)
+		}
+	}
+
+	return s.rdb.Set(ctx, txnKey, 1, matrixTxnTTL).Err()
+}
+
+func (s *MatrixBridgeService) handleEvent(ctx context.Context, event *matrix.Event) error {
+	// Our own users' events come back to us; they're already on this side
+	if s.client.IsVirtualUser(event.Sender) {
+		return nil
+	}
+	if event.Type != matrix.EventMessage && event.Type != matrix.EventMember {
+		return nil
+	}
+
+	link, err := s.matrixRepo.FindByRoom(event.RoomID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // a room the bot is in but that isn't bridged
+		}
+		return err
+	}
+
+	if event.Type == matrix.EventMessage {
+		return s.handleMessage(ctx, link, event)
+	}
+	return s.handleMember(ctx, link, event)
+}
+
+// handleMessage posts a remote user's message to the conversation as their ghost
+func (s *MatrixBridgeService) handleMessage(ctx context.Context, link *model.MatrixRoomLink, event *matrix.Event) error {
+	var content matrix.MessageContent
+	if err := json.Unmarshal(event.Content, &content); err != nil {
+		return err
+	}
+	if content.IsEdit() {
+		return nil // edits aren't bridged
+	}
+
+	ghost, err := s.ghost(event.Sender, "")
+	if err != nil {
+		return err
+	}
+	text := s.inboundText(ghost, &content)
+	if text == "" {
+		return nil
+	}
+	if err := s.ensureMember(ctx, link.ConversationID, ghost.ID); err != nil {
+		return err
+	}
+
+	_, err = s.chatService.SendMessage(ghost.ID, link.ConversationID, model.SendMessageRequest{Content: text})
+	return err
+}
+
+// inboundText renders a Matrix message as plain text; media become links
+func (s *MatrixBridgeService) inboundText(ghost *model.User, content *matrix.MessageContent) string {
+	switch content.MsgType {
+	case matrix.MsgEmote:
+		return "* " + ghost.PublicName() + " " + content.Body
+	case "m.image", "m.file", "m.video", "m.audio":
+		if link := s.client.MediaURL(content.URL); link != "" {
+			return strings.TrimSpace(content.Body + "\n" + link)
+		}
+	}
+	return content.Body
+}
+
+// handleMember mirrors remote users joining and leaving the room
+func (s *MatrixBridgeService) handleMember(ctx context.Context, link *model.MatrixRoomLink, event *matrix.Event) error {
+	if event.StateKey == nil || s.client.IsVirtualUser(*event.StateKey) {
+		return nil
+	}
+	var content matrix.MemberContent
+	if err := json.Unmarshal(event.Content, &content); err != nil {
+		return err
+	}
+
+	switch content.Membership {
+	case matrix.MembershipJoin:
+		ghost, err := s.ghost(*event.StateKey, content.DisplayName)
+		if err != nil {
+			return err
+		}
+		return s.ensureMember(ctx, link.ConversationID, ghost.ID)
+	case matrix.MembershipLeave, matrix.MembershipBan:
+		ghost, err := s.userRepo.FindByMatrixID(*event.StateKey)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		if err := s.convRepo.RemoveMember(link.ConversationID, ghost.ID); err != nil {
+			return err
+		}
+		s.members.Invalidate(ctx, link.ConversationID)
+	}
+	return nil
+}
+
+// handleTyping shows remote users' typing indicators to the conversation's
+// members. m.typing carries everyone typing in the room, so the previous set
+// is kept to tell who stopped.
+func (s *MatrixBridgeService) handleTyping(ctx context.Context, event *matrix.Event) error {
+	link, err := s.matrixRepo.FindByRoom(event.RoomID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	var content matrix.TypingContent
+	if err := json.Unmarshal(event.Content, &content); err != nil {
+		return err
+	}
+
+	typing := make(map[string]bool, len(content.UserIDs))
+	for _, id := range content.UserIDs {
+		if !s.client.IsVirtualUser(id) {
+			typing[id] = true
+		}
+	}
+
+	typingKey := matrixTypingKeyPrefix + event.RoomID
+	previous, err := s.rdb.SMembers(ctx, typingKey).Result()
+	if err != nil {
+		return err
+	}
+	wasTyping := make(map[string]bool, len(previous))
+	for _, id := range previous {
+		wasTyping[id] = true
+	}
+
+	memberIDs, err := s.members.MemberIDs(ctx, link.ConversationID)
+	if err != nil {
+		return err
+	}
+	notify := func(matrixID, eventType string) {
+		ghost, err := s.userRepo.FindByMatrixID(matrixID)
+		if err != nil {
+			return // not seen in the room yet
+		}
+		s.hub.SendToUsers(memberIDs, &model.WSEvent{
+			Type: eventType,
+			Payload: model.TypingEvent{
+				ConversationID: link.ConversationID,
+				UserID:         ghost.ID,
+				Name:           ghost.PublicName(),
+			},
+		})
+	}
+	for id := range typing {
+		if !wasTyping[id] {
+			notify(id, model.WSEventTyping)
+		}
+	}
+	for id := range wasTyping {
+		if !typing[id] {
+			notify(id, model.WSEventStopTyping)
+		}
+	}
+
+	pipe := s.rdb.TxPipeline()
+	pipe.Del(ctx, typingKey)
+	if len(typing) > 0 {
+		ids := make([]interface{}, 0, len(typing))
+		for id := range typing {
+			ids = append(ids, id)
+		}
+		pipe.SAdd(ctx, typingKey, ids...)
+		pipe.Expire(ctx, typingKey, matrixTypingTTL)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// ghost returns the local stand-in for a remote Matrix user, creating it on
+// first sight and keeping its display name current
+func (s *MatrixBridgeService) ghost(matrixID, displayName string) (*model.User, error) {
+	displayName = truncateRunes(displayName, 100)
+
+	user, err := s.userRepo.FindByMatrixID(matrixID)
+	if err == nil {
+		if displayName != "" && displayName != user.DisplayName {
+			if err := s.userRepo.UpdateProfile(user.ID, model.UpdateProfileRequest{DisplayName: &displayName}); err != nil {
+				return nil, err
+			}
+			user.DisplayName = displayName
+		}
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	user = &model.User{
+		Name:        truncateRunes(matrixID, 100),
+		DisplayName: displayName,
+		// Unique and undeliverable: ghosts have no mailbox and can't sign in
+		Email:           uuid.NewSHA1(uuid.NameSpaceURL, []byte("matrix:"+matrixID)).String() + "@" + matrixGhostDomain,
+		AuthProvider:    model.AuthProviderMatrix,
+		MatrixID:        &matrixID,
+		Discoverability: model.PrivacyNobody,
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		// Created meanwhile by a concurrent transaction
+		if existing, findErr := s.userRepo.FindByMatrixID(matrixID); findErr == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// ensureMember adds a ghost to the conversation unless it's already a member
+func (s *MatrixBridgeService) ensureMember(ctx context.Context, convID, userID uuid.UUID) error {
+	isMember, err := s.members.IsMember(ctx, convID, userID)
+	if err != nil || isMember {
+		return err
+	}
+	if err := s.convRepo.RestoreOrAddMember(convID, userID, model.MemberRoleMember); err != nil {
+		return err
+	}
+	s.members.Invalidate(ctx, convID)
+	return nil
+}
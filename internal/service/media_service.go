@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/pkg/storage"
+	"github.com/quocanhngo/gotalk/pkg/transcoder"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	transcodeQueueKey = "gotalk:transcode:queue"
+	transcodeTimeout  = 15 * time.Minute
+)
+
+// MediaService runs asynchronous media processing (video transcoding)
+// Jobs are queued in Redis so any instance can pick them up
+type MediaService struct {
+	msgRepo    *repository.MessageRepository
+	storage    *storage.MinIOStorage
+	transcoder *transcoder.Transcoder
+	rdb        *redis.Client
+	workers    int
+
+	// Callback when an attachment finished processing (successfully or not)
+	onProcessed func(att *model.MessageAttachment, conversationID uuid.UUID)
+}
+
+func NewMediaService(
+	msgRepo *repository.MessageRepository,
+	storage *storage.MinIOStorage,
+	transcoder *transcoder.Transcoder,
+	rdb *redis.Client,
+	workers int,
+	onProcessed func(att *model.MessageAttachment, conversationID uuid.UUID),
+) *MediaService {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &MediaService{
+		msgRepo:     msgRepo,
+		storage:     storage,
+		transcoder:  transcoder,
+		rdb:         rdb,
+		workers:     workers,
+		onProcessed: onProcessed,
+	}
+}
+
+// Enabled reports whether transcoding can run (ffmpeg and storage available)
+func (s *MediaService) Enabled() bool {
+	return s != nil && s.transcoder != nil && s.storage != nil
+}
+
+// EnqueueTranscode marks a video attachment as pending and queues it for transcoding
+func (s *MediaService) EnqueueTranscode(attachmentID uuid.UUID) error {
+	if !s.Enabled() {
+		return nil
+	}
+
+	if err := s.msgRepo.UpdateAttachment(attachmentID, map[string]interface{}{
+		"processing_status": model.ProcessingStatusPending,
+	}); err != nil {
+		return err
+	}
+
+	return s.rdb.LPush(context.Background(), transcodeQueueKey, attachmentID.String()).Err()
+}
+
+// Run starts the transcoding workers and blocks until ctx is cancelled
+func (s *MediaService) Run(ctx context.Context) {
+	if !s.Enabled() {
+		return
+	}
+
+	log.Printf("🎬 Transcoding workers started (workers: %d)", s.workers)
+	done := make(chan struct{})
+	for i := 0; i < s.workers; i++ {
+		go func() {
+			s.worker(ctx)
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < s.workers; i++ {
+		<-done
+	}
+}
+
+// worker pops attachment IDs from the queue and processes them one at a time
+func (s *MediaService) worker(ctx context.Context) {
+	for {
+		result, err := s.rdb.BRPop(ctx, 5*time.Second, transcodeQueueKey).Result()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if !errors.Is(err, redis.Nil) {
+				log.Printf("⚠️  Transcode queue error: %v", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		// BRPOP returns [key, value]
+		attachmentID, err := uuid.Parse(result[1])
+		if err != nil {
+			continue
+		}
+		s.processVideo(ctx, attachmentID)
+	}
+}
+
+// processVideo downloads the original, transcodes it, uploads renditions and updates the attachment
+func (s *MediaService) processVideo(ctx context.Context, attachmentID uuid.UUID) {
+	att, err := s.msgRepo.FindAttachmentByID(attachmentID)
+	if err != nil {
+		log.Printf("⚠️  Transcode: attachment %s not found: %v", attachmentID, err)
+		return
+	}
+
+	_ = s.msgRepo.UpdateAttachment(att.ID, map[string]interface{}{
+		"processing_status": model.ProcessingStatusProcessing,
+	})
+
+	updates, err := s.transcode(ctx, att)
+	if err != nil {
+		log.Printf("❌ Transcode failed for attachment %s: %v", att.ID, err)
+		updates = map[string]interface{}{"processing_status": model.ProcessingStatusFailed}
+	}
+
+	if err := s.msgRepo.UpdateAttachment(att.ID, updates); err != nil {
+		log.Printf("❌ Failed to update attachment %s: %v", att.ID, err)
+		return
+	}
+
+	if s.onProcessed != nil {
+		msg, err := s.msgRepo.FindByID(att.MessageID)
+		if err != nil {
+			return
+		}
+		if updated, err := s.msgRepo.FindAttachmentByID(att.ID); err == nil {
+			s.onProcessed(updated, msg.ConversationID)
+		}
+	}
+}
+
+// transcode runs ffmpeg on the attachment and returns the column updates to persist
+func (s *MediaService) transcode(ctx context.Context, att *model.MessageAttachment) (map[string]interface{}, error) {
+	key, ok := s.storage.KeyFromURL(att.URL)
+	if !ok {
+		return nil, errors.New("attachment is not stored in this bucket")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, transcodeTimeout)
+	defer cancel()
+
+	workDir, err := os.MkdirTemp("", "gotalk-transcode-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(workDir)
+
+	inputPath := filepath.Join(workDir, "input"+filepath.Ext(key))
+	if err := s.storage.DownloadToFile(ctx, key, inputPath); err != nil {
+		return nil, err
+	}
+
+	result, err := s.transcoder.Transcode(ctx, inputPath, workDir)
+	if err != nil {
+		return nil, err
+	}
+
+	base := "videos/processed/" + att.ID.String()
+	video, err := s.storage.UploadFromFile(ctx, result.VideoPath, base+".mp4", "video/mp4")
+	if err != nil {
+		return nil, err
+	}
+	poster, err := s.storage.UploadFromFile(ctx, result.PosterPath, base+"_poster.jpg", "image/jpeg")
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("🎬 Transcoded attachment %s (%.1fs, %dx%d)", att.ID, result.Duration, result.Width, result.Height)
+	return map[string]interface{}{
+		"processed_url":     video.URL,
+		"poster_url":        poster.URL,
+		"duration":          result.Duration,
+		"width":             result.Width,
+		"height":            result.Height,
+		"processing_status": model.ProcessingStatusReady,
+	}, nil
+}
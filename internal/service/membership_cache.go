@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	membersKeyPrefix        = "gotalk:members:"         // + conversation id: SET of member user IDs
+	membersVersionKeyPrefix = "gotalk:members:version:" // + conversation id: bumped on every membership change
+	membersCacheTTL         = time.Hour
+
+	// membersLoadedMarker keeps a loaded set non-empty, so a conversation
+	// without members can be told apart from one that isn't cached
+	membersLoadedMarker = "-"
+)
+
+// MembershipCache answers "who is in this conversation" from a Redis set per
+// conversation, loaded from the database on first use. It sits on the hot
+// paths: every message sent, typing event and read receipt. Whoever changes a
+// conversation's members must call Invalidate afterwards. When Redis fails,
+// lookups fall back to the database.
+type MembershipCache struct {
+	convRepo *repository.ConversationRepository
+	rdb      *redis.Client
+
+	hits, misses, failures atomic.Int64
+	hitTime, missTime      atomic.Int64 // nanoseconds spent on hits and on misses
+}
+
+func NewMembershipCache(convRepo *repository.ConversationRepository, rdb *redis.Client) *MembershipCache {
+	return &MembershipCache{convRepo: convRepo, rdb: rdb}
+}
+
+// IsMember reports whether the user is a member of the conversation
+func (c *MembershipCache) IsMember(ctx context.Context, convID, userID uuid.UUID) (bool, error) {
+	start := time.Now()
+	found, err := c.rdb.SMIsMember(ctx, membersKeyPrefix+convID.String(), membersLoadedMarker, userID.String()).Result()
+	if err == nil && found[0] {
+		c.hit(start)
+		return found[1], nil
+	}
+	if err != nil {
+		c.fail(err)
+	}
+
+	memberIDs, err := c.load(ctx, convID, err == nil)
+	c.miss(start)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range memberIDs {
+		if id == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// MemberIDs returns the user IDs of the conversation's members
+func (c *MembershipCache) MemberIDs(ctx context.Context, convID uuid.UUID) ([]uuid.UUID, error) {
+	start := time.Now()
+	members, err := c.rdb.SMembers(ctx, membersKeyPrefix+convID.String()).Result()
+	if err == nil && len(members) > 0 {
+		memberIDs := make([]uuid.UUID, 0, len(members)-1)
+		for _, member := range members {
+			if id, err := uuid.Parse(member); err == nil {
+				memberIDs = append(memberIDs, id)
+			}
+		}
+		c.hit(start)
+		return memberIDs, nil
+	}
+	if err != nil {
+		c.fail(err)
+	}
+
+	memberIDs, err := c.load(ctx, convID, err == nil)
+	c.miss(start)
+	return memberIDs, err
+}
+
+// Invalidate drops the cached members of a conversation after they changed.
+// It also fails loads that read the database before the change, so they
+// can't cache the old members.
+func (c *MembershipCache) Invalidate(ctx context.Context, convID uuid.UUID) {
+	versionKey := membersVersionKeyPrefix + convID.String()
+	_, err := c.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, versionKey)
+		pipe.Expire(ctx, versionKey, membersCacheTTL)
+		pipe.Del(ctx, membersKeyPrefix+convID.String())
+		return nil
+	})
+	if err != nil {
+		log.Printf("⚠️  Failed to invalidate members of conversation %s (stale for up to %s): %v", convID, membersCacheTTL, err)
+	}
+}
+
+// Stats reports the cache's hit rate and latency on this instance; the
+// difference between the average hit and miss is what the cache saves
+func (c *MembershipCache) Stats() model.MembershipCacheStats {
+	stats := model.MembershipCacheStats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Errors: c.failures.Load(),
+	}
+	if stats.Hits > 0 {
+		stats.AvgHitMs = float64(c.hitTime.Load()) / float64(stats.Hits) / float64(time.Millisecond)
+	}
+	if stats.Misses > 0 {
+		stats.AvgMissMs = float64(c.missTime.Load()) / float64(stats.Misses) / float64(time.Millisecond)
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// load reads the members from the database and, when store is set, caches
+// them unless the membership changed meanwhile
+func (c *MembershipCache) load(ctx context.Context, convID uuid.UUID, store bool) ([]uuid.UUID, error) {
+	if !store {
+		return c.convRepo.GetMemberIDs(convID)
+	}
+
+	var memberIDs []uuid.UUID
+	var dbErr error
+	read := false
+	err := c.rdb.Watch(ctx, func(tx *redis.Tx) error {
+		memberIDs, dbErr = c.convRepo.GetMemberIDs(convID)
+		read = true
+		if dbErr != nil {
+			return dbErr
+		}
+
+		members := make([]interface{}, 0, len(memberIDs)+1)
+		members = append(members, membersLoadedMarker)
+		for _, id := range memberIDs {
+			members = append(members, id.String())
+		}
+		key := membersKeyPrefix + convID.String()
+		_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.SAdd(ctx, key, members...)
+			pipe.Expire(ctx, key, membersCacheTTL)
+			return nil
+		})
+		return err
+	}, membersVersionKeyPrefix+convID.String())
+	if dbErr != nil {
+		return nil, dbErr
+	}
+	// If the members changed meanwhile (TxFailedErr) they aren't cached and
+	// the next lookup loads them again
+	if err != nil && !errors.Is(err, redis.TxFailedErr) {
+		c.fail(err)
+		if !read {
+			return c.convRepo.GetMemberIDs(convID)
+		}
+	}
+	return memberIDs, nil
+}
+
+func (c *MembershipCache) hit(start time.Time) {
+	c.hits.Add(1)
+	c.hitTime.Add(int64(time.Since(start)))
+}
+
+func (c *MembershipCache) miss(start time.Time) {
+	c.misses.Add(1)
+	c.missTime.Add(int64(time.Since(start)))
+}
+
+func (c *MembershipCache) fail(err error) {
+	if c.failures.Add(1) == 1 {
+		log.Printf("⚠️  Membership cache unavailable, reading members from the database: %v", err)
+	}
+}
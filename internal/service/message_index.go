@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/pkg/opensearch"
+)
+
+const reindexBatchSize = 1000
+
+// searchOrPattern matches the "or" of the web-style search syntax, which
+// OpenSearch spells "|"
+var searchOrPattern = regexp.MustCompile(`(?i)\s+or\s+`)
+
+// MessageIndex keeps messages in an OpenSearch index for deployments too
+// large for Postgres full-text search. The outbox worker indexes new
+// messages; Rebuild (gotalkctl reindex-messages) indexes the existing ones.
+type MessageIndex struct {
+	client  *opensearch.Client
+	msgRepo *repository.MessageRepository
+}
+
+func NewMessageIndex(client *opensearch.Client, msgRepo *repository.MessageRepository) *MessageIndex {
+	return &MessageIndex{client: client, msgRepo: msgRepo}
+}
+
+// EnsureIndex creates the index unless it exists
+func (i *MessageIndex) EnsureIndex(ctx context.Context) error {
+	return i.client.EnsureIndex(ctx)
+}
+
+// Index adds messages to the index; those without text are skipped
+func (i *MessageIndex) Index(ctx context.Context, messages ...model.Message) error {
+	docs := make([]opensearch.Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Content == "" {
+			continue
+		}
+		docs = append(docs, opensearch.Message{
+			ID:             msg.ID.String(),
+			ConversationID: msg.ConversationID.String(),
+			SenderID:       msg.SenderID.String(),
+			Content:        msg.Content,
+			CreatedAt:      msg.CreatedAt,
+		})
+	}
+	switch len(docs) {
+	case 0:
+		return nil
+	case 1:
+		return i.client.Index(ctx, docs[0])
+	}
+	return i.client.Bulk(ctx, docs)
+}
+
+// Search finds the messages of the given conversations matching a web-style
+// query, best first. Hits may name messages deleted since they were indexed.
+func (i *MessageIndex) Search(ctx context.Context, query string, conversationIDs []uuid.UUID, limit int) ([]opensearch.Hit, error) {
+	ids := make([]string, len(conversationIDs))
+	for n, id := range conversationIDs {
+		ids[n] = id.String()
+	}
+	return i.client.Search(ctx, searchOrPattern.ReplaceAllString(query, " | "), ids, limit)
+}
+
+// Rebuild drops the index and indexes every message again, reporting the
+// running total after each batch
+func (i *MessageIndex) Rebuild(ctx context.Context, progress func(indexed int)) error {
+	if err := i.client.DeleteIndex(ctx); err != nil {
+		return err
+	}
+	if err := i.client.EnsureIndex(ctx); err != nil {
+		return err
+	}
+
+	indexed := 0
+	var after *model.Message
+	for {
+		messages, err := i.msgRepo.ListAll(after, reindexBatchSize)
+		if err != nil {
+			return err
+		}
+		if err := i.Index(ctx, messages...); err != nil {
+			return err
+		}
+		indexed += len(messages)
+		progress(indexed)
+		if len(messages) < reindexBatchSize {
+			return nil
+		}
+		after = &messages[len(messages)-1]
+	}
+}
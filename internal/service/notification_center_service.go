@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	pendingCallKeyPrefix = "gotalk:call:pending:" // caller:callee -> ringing call, cleared on answer
+	pendingCallTTL       = 2 * time.Minute
+
+	defaultNotificationLimit = 30
+	maxNotificationLimit     = 100
+)
+
+// NotificationCenterService persists in-app notifications (the bell icon) and
+// hands each new entry to a callback for real-time delivery
+type NotificationCenterService struct {
+	notifRepo *repository.NotificationRepository
+	userRepo  *repository.UserRepository
+	rdb       *redis.Client
+	onCreated func(n *model.Notification)
+}
+
+func NewNotificationCenterService(
+	notifRepo *repository.NotificationRepository,
+	userRepo *repository.UserRepository,
+	rdb *redis.Client,
+	onCreated func(n *model.Notification),
+) *NotificationCenterService {
+	return &NotificationCenterService{
+		notifRepo: notifRepo,
+		userRepo:  userRepo,
+		rdb:       rdb,
+		onCreated: onCreated,
+	}
+}
+
+// Notify stores a notification for each user and emits it
+func (s *NotificationCenterService) Notify(userIDs []uuid.UUID, notifType model.NotificationType, title, body string, data map[string]string) error {
+	notifications := make([]model.Notification, 0, len(userIDs))
+	for _, userID := range userIDs {
+		notifications = append(notifications, model.Notification{
+			UserID: userID,
+			Type:   notifType,
+			Title:  title,
+			Body:   body,
+			Data:   data,
+		})
+	}
+
+	if err := s.notifRepo.Create(notifications); err != nil {
+		return fmt.Errorf("failed to save notifications: %w", err)
+	}
+
+	if s.onCreated != nil {
+		for i := range notifications {
+			s.onCreated(&notifications[i])
+		}
+	}
+	return nil
+}
+
+// List returns a page of the user's notifications with their unread count
+func (s *NotificationCenterService) List(userID uuid.UUID, req model.NotificationListRequest) (*model.NotificationListResponse, error) {
+	notifications, err := s.notifRepo.ListByUser(userID, req.Before, req.UnreadOnly, NotificationPageLimit(req.Limit))
+	if err != nil {
+		return nil, err
+	}
+	unread, err := s.notifRepo.CountUnread(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.NotificationListResponse{
+		Notifications: notifications,
+		UnreadCount:   unread,
+	}, nil
+}
+
+// UnreadCount returns the number of the user's unread notifications
+func (s *NotificationCenterService) UnreadCount(userID uuid.UUID) (int64, error) {
+	return s.notifRepo.CountUnread(userID)
+}
+
+// NotificationPageLimit returns the page size List uses for a requested limit
+func NotificationPageLimit(limit int) int {
+	if limit <= 0 {
+		return defaultNotificationLimit
+	}
+	if limit > maxNotificationLimit {
+		return maxNotificationLimit
+	}
+	return limit
+}
+
+// MarkRead marks a notification as read
+func (s *NotificationCenterService) MarkRead(id, userID uuid.UUID) error {
+	err := s.notifRepo.MarkRead(id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotificationNotFound
+	}
+	return err
+}
+
+// MarkAllRead marks all of the user's notifications as read
+func (s *NotificationCenterService) MarkAllRead(userID uuid.UUID) error {
+	return s.notifRepo.MarkAllRead(userID)
+}
+
+// BroadcastNotice sends an admin notice to the given users, or to every verified user
+func (s *NotificationCenterService) BroadcastNotice(req model.AdminNoticeRequest) (int, error) {
+	userIDs := req.UserIDs
+	if len(userIDs) == 0 {
+		var err error
+		userIDs, err = s.userRepo.FindVerifiedIDs()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := s.Notify(userIDs, model.NotificationTypeAdminNotice, req.Title, req.Body, nil); err != nil {
+		return 0, err
+	}
+	return len(userIDs), nil
+}
+
+// ==================== Missed Call Tracking ====================
+
+// CallOffered records a ringing call from caller to callee
+func (s *NotificationCenterService) CallOffered(callerID, calleeID uuid.UUID) {
+	s.rdb.Set(context.Background(), pendingCallKey(callerID, calleeID), "1", pendingCallTTL)
+}
+
+// CallAnswered clears the ringing call the callee just picked up
+func (s *NotificationCenterService) CallAnswered(calleeID, callerID uuid.UUID) {
+	s.rdb.Del(context.Background(), pendingCallKey(callerID, calleeID))
+}
+
+// CallEnded records a missed call when the caller hangs up before the callee
+// answered. A callee hanging up on a ringing call is a decline, not a miss.
+func (s *NotificationCenterService) CallEnded(fromID, toID uuid.UUID, callerName string) {
+	ctx := context.Background()
+
+	// Callee declined
+	s.rdb.Del(ctx, pendingCallKey(toID, fromID))
+
+	// Caller gave up while it was still ringing
+	removed, err := s.rdb.Del(ctx, pendingCallKey(fromID, toID)).Result()
+	if err != nil || removed == 0 {
+		return
+	}
+
+	if err := s.Notify([]uuid.UUID{toID}, model.NotificationTypeMissedCall,
+		"Missed call", fmt.Sprintf("You missed a call from %s", callerName),
+		map[string]string{"caller_id": fromID.String()},
+	); err != nil {
+		log.Printf("⚠️ Failed to record missed call for %s: %v", toID, err)
+	}
+}
+
+func pendingCallKey(callerID, calleeID uuid.UUID) string {
+	return pendingCallKeyPrefix + callerID.String() + ":" + calleeID.String()
+}
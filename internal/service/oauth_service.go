@@ -0,0 +1,729 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/pkg/auth"
+	"github.com/quocanhngo/gotalk/pkg/oidc"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	oauthCodeKeyPrefix  = "gotalk:oauth:code:" // authorization codes, by code
+	oauthCodeTTL        = time.Minute          // time for the app to redeem a code
+	oauthPruneInterval  = time.Hour
+	oauthAPIPrefix      = "/api/v1" // where the endpoints in the discovery document live
+	oauthSigningKeyBits = 2048
+)
+
+// OAuthSettings configures GoTalk as an OAuth 2.0 / OpenID Connect provider
+type OAuthSettings struct {
+	Issuer          string // public base URL of the API; empty disables the provider
+	AuthorizeURL    string // the frontend's consent page that apps send users to
+	SigningKey      string // PEM RSA private key for ID tokens; empty uses a temporary key
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+}
+
+// OAuthService lets users give third-party apps scoped access to their
+// account: apps are registered, users consent through the authorization code
+// flow (with PKCE), and apps receive short-lived access tokens, rotating
+// refresh tokens and, with the openid scope, ID tokens.
+type OAuthService struct {
+	settings   OAuthSettings
+	repo       *repository.OAuthRepository
+	userRepo   *repository.UserRepository
+	jwtManager *auth.JWTManager
+	rdb        *redis.Client
+	signingKey *rsa.PrivateKey
+	keyID      string
+}
+
+// oauthCode is what an authorization code stands for
+type oauthCode struct {
+	ClientID      string    `json:"client_id"`
+	UserID        uuid.UUID `json:"user_id"`
+	RedirectURI   string    `json:"redirect_uri"`
+	RedirectGiven bool      `json:"redirect_given"` // the token request must then repeat it
+	Scopes        []string  `json:"scopes"`
+	Challenge     string    `json:"challenge"` // PKCE S256 code challenge
+	Nonce         string    `json:"nonce"`
+}
+
+// OAuthError is a failure of the token or revocation endpoint, reported to
+// the app in RFC 6749 form rather than as an API error
+type OAuthError struct {
+	Code        string // e.g. invalid_grant
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+var (
+	errInvalidClient        = &OAuthError{"invalid_client", "unknown client or wrong client secret"}
+	errInvalidGrant         = &OAuthError{"invalid_grant", "the code or refresh token is invalid, expired or was issued to another client"}
+	errUnsupportedGrantType = &OAuthError{"unsupported_grant_type", "grant_type must be authorization_code or refresh_token"}
+)
+
+func NewOAuthService(
+	settings OAuthSettings,
+	repo *repository.OAuthRepository,
+	userRepo *repository.UserRepository,
+	jwtManager *auth.JWTManager,
+	rdb *redis.Client,
+) (*OAuthService, error) {
+	settings.Issuer = strings.TrimSuffix(settings.Issuer, "/")
+	s := &OAuthService{
+		settings:   settings,
+		repo:       repo,
+		userRepo:   userRepo,
+		jwtManager: jwtManager,
+		rdb:        rdb,
+	}
+	if !s.Enabled() {
+		return s, nil
+	}
+
+	key, err := parseSigningKey(settings.SigningKey)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		log.Println("⚠️  OAUTH_SIGNING_KEY is not set; ID tokens are signed with a temporary key that changes on restart")
+		if key, err = rsa.GenerateKey(rand.Reader, oauthSigningKeyBits); err != nil {
+			return nil, err
+		}
+	}
+	jwk := jose.JSONWebKey{Key: &key.PublicKey}
+	thumbprint, err := jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	s.signingKey = key
+	s.keyID = base64.RawURLEncoding.EncodeToString(thumbprint)
+	return s, nil
+}
+
+// Enabled tells whether GoTalk acts as an OAuth provider
+func (s *OAuthService) Enabled() bool {
+	return s.settings.Issuer != ""
+}
+
+// ==================== App registration ====================
+
+// RegisterClient registers an app owned by the user. The client secret is
+// only returned here.
+func (s *OAuthService) RegisterClient(ownerID uuid.UUID, req model.CreateOAuthClientRequest) (*model.OAuthClientResponse, error) {
+	if !s.Enabled() {
+		return nil, ErrOAuthNotConfigured
+	}
+	for _, uri := range req.RedirectURIs {
+		if !validRedirectURI(uri) {
+			return nil, ErrInvalidRedirectURI.WithMessage("redirect URIs must be absolute, without a fragment, and use https (http only on localhost)")
+		}
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	client := &model.OAuthClient{
+		ID:           hex.EncodeToString(id),
+		Name:         req.Name,
+		RedirectURIs: req.RedirectURIs,
+		Scopes:       normalizeScopes(req.Scopes),
+		OwnerID:      ownerID,
+	}
+	var secret string
+	if !req.Public {
+		var err error
+		if secret, err = oidc.RandomString(); err != nil {
+			return nil, err
+		}
+		client.SecretHash = auth.TokenHash(secret)
+	}
+	if err := s.repo.CreateClient(client); err != nil {
+		return nil, err
+	}
+
+	resp := client.ToResponse()
+	resp.ClientSecret = secret
+	return &resp, nil
+}
+
+// ListClients returns the apps the user registered
+func (s *OAuthService) ListClients(ownerID uuid.UUID) ([]model.OAuthClientResponse, error) {
+	clients, err := s.repo.ListClients(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]model.OAuthClientResponse, len(clients))
+	for i := range clients {
+		resp[i] = clients[i].ToResponse()
+	}
+	return resp, nil
+}
+
+// DeleteClient deletes one of the user's apps; every token issued to it stops
+// working at once
+func (s *OAuthService) DeleteClient(ctx context.Context, ownerID uuid.UUID, clientID string) error {
+	err := s.repo.DeleteClient(clientID, ownerID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrOAuthClientNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.rdb.Set(ctx, auth.ClientRevokedKey(clientID), "deleted", s.settings.AccessTokenTTL).Err()
+}
+
+// ==================== Consent ====================
+
+// Authorize checks an authorization request and returns what the consent
+// screen shows the user
+func (s *OAuthService) Authorize(userID uuid.UUID, req model.OAuthAuthorizeRequest) (*model.OAuthConsent, error) {
+	client, redirectURI, scopes, err := s.checkAuthorizeRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	consent := &model.OAuthConsent{
+		ClientID:    client.ID,
+		ClientName:  client.Name,
+		RedirectURI: redirectURI,
+	}
+	for _, scope := range model.OAuthScopes {
+		if slices.Contains(scopes, scope.Name) {
+			consent.Scopes = append(consent.Scopes, scope)
+		}
+	}
+	if grant, err := s.repo.FindGrant(userID, client.ID); err == nil {
+		consent.Granted = containsAll(grant.Scopes, scopes)
+	}
+	return consent, nil
+}
+
+// Decide records the user's answer to an authorization request and returns
+// the app's redirect URI to send the browser to: with an authorization code
+// when approved, or the access_denied error
+func (s *OAuthService) Decide(ctx context.Context, userID uuid.UUID, req model.OAuthAuthorizeRequest, approve bool) (string, error) {
+	client, redirectURI, scopes, err := s.checkAuthorizeRequest(req)
+	if err != nil {
+		return "", err
+	}
+	params := url.Values{}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	if !approve {
+		params.Set("error", "access_denied")
+		return withQuery(redirectURI, params), nil
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return "", ErrUserNotFound
+	}
+	if !user.IsActive() {
+		return "", ErrAccountDeactivated
+	}
+
+	// The grant keeps every scope the user ever agreed to, for the apps page
+	grant := &model.OAuthGrant{UserID: userID, ClientID: client.ID, Scopes: scopes}
+	if existing, err := s.repo.FindGrant(userID, client.ID); err == nil {
+		grant.Scopes = normalizeScopes(append(existing.Scopes, scopes...))
+	}
+	if err := s.repo.SaveGrant(grant); err != nil {
+		return "", err
+	}
+
+	code, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+	data, _ := json.Marshal(oauthCode{
+		ClientID:      client.ID,
+		UserID:        userID,
+		RedirectURI:   redirectURI,
+		RedirectGiven: req.RedirectURI != "",
+		Scopes:        scopes,
+		Challenge:     req.CodeChallenge,
+		Nonce:         req.Nonce,
+	})
+	if err := s.rdb.Set(ctx, oauthCodeKeyPrefix+code, data, oauthCodeTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store authorization code: %w", err)
+	}
+	params.Set("code", code)
+	return withQuery(redirectURI, params), nil
+}
+
+// checkAuthorizeRequest returns the app, the redirect URI to use and the
+// requested scopes of a valid authorization request
+func (s *OAuthService) checkAuthorizeRequest(req model.OAuthAuthorizeRequest) (*model.OAuthClient, string, []string, error) {
+	if !s.Enabled() {
+		return nil, "", nil, ErrOAuthNotConfigured
+	}
+	client, err := s.repo.FindClient(req.ClientID)
+	if err != nil {
+		return nil, "", nil, ErrOAuthClientNotFound
+	}
+
+	redirectURI := req.RedirectURI
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+	if !slices.Contains(client.RedirectURIs, redirectURI) {
+		return nil, "", nil, ErrInvalidRedirectURI
+	}
+
+	scopes := normalizeScopes(strings.Fields(req.Scope))
+	if len(scopes) == 0 || !containsAll(client.Scopes, scopes) {
+		return nil, "", nil, ErrInvalidScope
+	}
+	if client.Public() && req.CodeChallenge == "" {
+		return nil, "", nil, ErrPKCERequired
+	}
+	if req.CodeChallenge != "" && req.CodeChallengeMethod != "S256" {
+		return nil, "", nil, ErrPKCERequired
+	}
+	return client, redirectURI, scopes, nil
+}
+
+// ==================== Tokens ====================
+
+// Token handles a token request: it redeems an authorization code or a
+// refresh token. Protocol failures are *OAuthError. clientID and secret come
+// from HTTP Basic authentication when the app used it.
+func (s *OAuthService) Token(ctx context.Context, req model.OAuthTokenRequest, clientID, secret string) (*model.OAuthTokenResponse, error) {
+	if !s.Enabled() {
+		return nil, ErrOAuthNotConfigured
+	}
+	client, err := s.authenticateClient(clientID, secret, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.GrantType {
+	case "authorization_code":
+		return s.redeemCode(ctx, client, req)
+	case "refresh_token":
+		return s.refresh(client, req)
+	default:
+		return nil, errUnsupportedGrantType
+	}
+}
+
+func (s *OAuthService) redeemCode(ctx context.Context, client *model.OAuthClient, req model.OAuthTokenRequest) (*model.OAuthTokenResponse, error) {
+	// Codes are single-use
+	data, err := s.rdb.GetDel(ctx, oauthCodeKeyPrefix+req.Code).Bytes()
+	if err != nil {
+		return nil, errInvalidGrant
+	}
+	var code oauthCode
+	if err := json.Unmarshal(data, &code); err != nil || code.ClientID != client.ID {
+		return nil, errInvalidGrant
+	}
+	if (code.RedirectGiven || req.RedirectURI != "") && req.RedirectURI != code.RedirectURI {
+		return nil, &OAuthError{"invalid_grant", "redirect_uri does not match the authorization request"}
+	}
+	if code.Challenge != "" && !verifyPKCE(code.Challenge, req.CodeVerifier) {
+		return nil, &OAuthError{"invalid_grant", "code_verifier does not match the code challenge"}
+	}
+
+	user, err := s.activeUser(code.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return s.issueTokens(user, client.ID, code.Scopes, code.Nonce)
+}
+
+func (s *OAuthService) refresh(client *model.OAuthClient, req model.OAuthTokenRequest) (*model.OAuthTokenResponse, error) {
+	token, err := s.repo.ConsumeRefreshToken(auth.TokenHash(req.RefreshToken), time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errInvalidGrant
+	}
+	if err != nil {
+		return nil, err
+	}
+	if token.ClientID != client.ID {
+		return nil, errInvalidGrant
+	}
+
+	scopes := token.Scopes
+	if req.Scope != "" {
+		scopes = normalizeScopes(strings.Fields(req.Scope))
+		if !containsAll(token.Scopes, scopes) {
+			return nil, &OAuthError{"invalid_scope", "a refresh can't add scopes"}
+		}
+	}
+
+	user, err := s.activeUser(token.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return s.issueTokens(user, client.ID, scopes, "")
+}
+
+// Revoke revokes one of the app's access or refresh tokens (RFC 7009).
+// Unknown tokens aren't an error.
+func (s *OAuthService) Revoke(ctx context.Context, req model.OAuthRevokeRequest, clientID, secret string) error {
+	if !s.Enabled() {
+		return ErrOAuthNotConfigured
+	}
+	client, err := s.authenticateClient(clientID, secret, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	if claims, err := s.jwtManager.ValidateToken(req.Token); err == nil {
+		if claims.ClientID != client.ID || claims.ExpiresAt == nil {
+			return nil
+		}
+		return s.rdb.Set(ctx, auth.BlacklistKey(req.Token), "revoked", time.Until(claims.ExpiresAt.Time)).Err()
+	}
+	return s.repo.DeleteRefreshToken(auth.TokenHash(req.Token), client.ID)
+}
+
+// authenticateClient identifies the app from HTTP Basic credentials or the
+// request's client_id and client_secret. Public apps only give their ID.
+func (s *OAuthService) authenticateClient(basicID, basicSecret, formID, formSecret string) (*model.OAuthClient, error) {
+	clientID, secret := basicID, basicSecret
+	if clientID == "" {
+		clientID, secret = formID, formSecret
+	}
+	if clientID == "" {
+		return nil, errInvalidClient
+	}
+	client, err := s.repo.FindClient(clientID)
+	if err != nil {
+		return nil, errInvalidClient
+	}
+	if !client.Public() && subtle.ConstantTimeCompare([]byte(auth.TokenHash(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, errInvalidClient
+	}
+	return client, nil
+}
+
+func (s *OAuthService) activeUser(userID uuid.UUID) (*model.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil || !user.IsActive() {
+		return nil, &OAuthError{"invalid_grant", "the user no longer exists or was deactivated"}
+	}
+	return user, nil
+}
+
+// issueTokens issues an access token, a refresh token and, with the openid
+// scope, an ID token
+func (s *OAuthService) issueTokens(user *model.User, clientID string, scopes []string, nonce string) (*model.OAuthTokenResponse, error) {
+	scope := strings.Join(scopes, " ")
+	accessToken, err := s.jwtManager.GenerateScopedToken(user.ID, user.Email, user.Name, clientID, scope, s.settings.AccessTokenTTL)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	refreshToken, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateRefreshToken(&model.OAuthRefreshToken{
+		TokenHash: auth.TokenHash(refreshToken),
+		ClientID:  clientID,
+		UserID:    user.ID,
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(s.settings.RefreshTokenTTL),
+	}); err != nil {
+		return nil, err
+	}
+
+	resp := &model.OAuthTokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.settings.AccessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        scope,
+	}
+	if slices.Contains(scopes, model.ScopeOpenID) {
+		if resp.IDToken, err = s.idToken(user, clientID, scopes, nonce); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// idToken signs an OpenID Connect ID token with the claims the scopes allow
+func (s *OAuthService) idToken(user *model.User, clientID string, scopes []string, nonce string) (string, error) {
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: s.signingKey, KeyID: s.keyID}},
+		(&jose.SignerOptions{}).WithType("JWT"),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	registered := jwt.Claims{
+		Issuer:   s.settings.Issuer,
+		Subject:  user.ID.String(),
+		Audience: jwt.Audience{clientID},
+		IssuedAt: jwt.NewNumericDate(now),
+		Expiry:   jwt.NewNumericDate(now.Add(s.settings.AccessTokenTTL)),
+	}
+	extra := struct {
+		Nonce string `json:"nonce,omitempty"`
+		model.UserInfo
+	}{Nonce: nonce, UserInfo: userInfo(user, scopes)}
+	return jwt.Signed(signer).Claims(registered).Claims(extra).Serialize()
+}
+
+// ==================== Users and their apps ====================
+
+// UserInfo returns the OpenID Connect claims about the user the scopes allow.
+// The user's own tokens (scopes nil) see every claim.
+func (s *OAuthService) UserInfo(userID uuid.UUID, scopes []string) (*model.UserInfo, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if scopes == nil {
+		scopes = []string{model.ScopeProfile, model.ScopeEmail}
+	}
+	info := userInfo(user, scopes)
+	return &info, nil
+}
+
+func userInfo(user *model.User, scopes []string) model.UserInfo {
+	info := model.UserInfo{Subject: user.ID.String()}
+	if slices.Contains(scopes, model.ScopeProfile) {
+		info.Name = user.Name
+		if user.DisplayName != "" {
+			info.Name = user.DisplayName
+		}
+		info.Picture = user.Avatar
+	}
+	if slices.Contains(scopes, model.ScopeEmail) {
+		verified := user.IsEmailVerified()
+		info.Email = user.Email
+		info.EmailVerified = &verified
+	}
+	return info
+}
+
+// ConnectedApps returns the apps the user gave access to their account
+func (s *OAuthService) ConnectedApps(userID uuid.UUID) ([]model.ConnectedApp, error) {
+	grants, err := s.repo.ListGrants(userID)
+	if err != nil {
+		return nil, err
+	}
+	apps := make([]model.ConnectedApp, len(grants))
+	for i, g := range grants {
+		apps[i] = model.ConnectedApp{
+			ClientID:     g.ClientID,
+			Name:         g.Client.Name,
+			Scopes:       g.Scopes,
+			AuthorizedAt: g.CreatedAt,
+			UpdatedAt:    g.UpdatedAt,
+		}
+	}
+	return apps, nil
+}
+
+// DisconnectApp takes an app's access away: its refresh tokens are deleted and
+// the access tokens it already has stop working
+func (s *OAuthService) DisconnectApp(ctx context.Context, userID uuid.UUID, clientID string) error {
+	err := s.repo.DeleteGrant(userID, clientID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrOAuthClientNotFound
+	}
+	if err != nil {
+		return err
+	}
+	revokedAt := strconv.FormatInt(time.Now().Unix(), 10)
+	return s.rdb.Set(ctx, auth.AppRevokedKey(userID, clientID), revokedAt, s.settings.AccessTokenTTL).Err()
+}
+
+// ==================== Discovery ====================
+
+// Discovery returns the OpenID Connect discovery document
+func (s *OAuthService) Discovery() (*model.OpenIDConfiguration, error) {
+	if !s.Enabled() {
+		return nil, ErrOAuthNotConfigured
+	}
+	api := s.settings.Issuer + oauthAPIPrefix
+	scopes := make([]string, len(model.OAuthScopes))
+	for i, scope := range model.OAuthScopes {
+		scopes[i] = scope.Name
+	}
+	return &model.OpenIDConfiguration{
+		Issuer:                            s.settings.Issuer,
+		AuthorizationEndpoint:             s.settings.AuthorizeURL,
+		TokenEndpoint:                     api + "/oauth/token",
+		UserinfoEndpoint:                  api + "/oauth/userinfo",
+		RevocationEndpoint:                api + "/oauth/revoke",
+		JWKSURI:                           api + "/oauth/jwks",
+		ScopesSupported:                   scopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{"RS256"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+	}, nil
+}
+
+// JWKS returns the public key that verifies ID tokens
+func (s *OAuthService) JWKS() (*model.JSONWebKeySet, error) {
+	if !s.Enabled() {
+		return nil, ErrOAuthNotConfigured
+	}
+	pub := s.signingKey.PublicKey
+	return &model.JSONWebKeySet{Keys: []model.JSONWebKey{{
+		KeyType:   "RSA",
+		Use:       "sig",
+		Algorithm: "RS256",
+		KeyID:     s.keyID,
+		Modulus:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}}, nil
+}
+
+// Run prunes expired refresh tokens every hour until ctx is cancelled
+func (s *OAuthService) Run(ctx context.Context) {
+	ticker := time.NewTicker(oauthPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.repo.DeleteExpiredRefreshTokens(time.Now())
+			if err != nil {
+				log.Printf("⚠️  OAuth refresh token prune failed: %v", err)
+			} else if n > 0 {
+				log.Printf("🧹 Pruned %d expired OAuth refresh tokens", n)
+			}
+		}
+	}
+}
+
+// ==================== Helpers ====================
+
+// parseSigningKey reads a PEM RSA private key (PKCS #1 or #8). Newlines may
+// be given as \n, as in environment variables. An empty key gives nil.
+func parseSigningKey(data string) (*rsa.PrivateKey, error) {
+	if data == "" {
+		return nil, nil
+	}
+	block, _ := pem.Decode([]byte(strings.ReplaceAll(data, `\n`, "\n")))
+	if block == nil {
+		return nil, errors.New("oauth signing key is not PEM")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("oauth signing key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("oauth signing key is not an RSA key")
+	}
+	return key, nil
+}
+
+// validRedirectURI accepts absolute URIs without a fragment that use https,
+// http on a loopback host (development and native apps), or a private-use
+// scheme of a native app (e.g. com.example.app:/callback)
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Fragment != "" {
+		return false
+	}
+	switch u.Scheme {
+	case "https":
+		return u.Host != ""
+	case "http":
+		host := u.Hostname()
+		ip := net.ParseIP(host)
+		return host == "localhost" || (ip != nil && ip.IsLoopback())
+	case "javascript", "data", "file", "vbscript":
+		return false
+	}
+	return strings.Contains(u.Scheme, ".")
+}
+
+// normalizeScopes orders scopes as model.OAuthScopes does and drops
+// duplicates. An unknown scope makes the whole list invalid (nil).
+func normalizeScopes(scopes []string) []string {
+	normalized := []string{}
+	for _, scope := range model.OAuthScopes {
+		if slices.Contains(scopes, scope.Name) {
+			normalized = append(normalized, scope.Name)
+		}
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(normalized, scope) {
+			return nil
+		}
+	}
+	return normalized
+}
+
+func containsAll(have, want []string) bool {
+	for _, w := range want {
+		if !slices.Contains(have, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// verifyPKCE checks a code verifier against its S256 code challenge
+func verifyPKCE(challenge, verifier string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return verifier != "" && subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// withQuery adds params to a redirect URI's query
+func withQuery(redirectURI string, params url.Values) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	query := u.Query()
+	for k, v := range params {
+		query[k] = v
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
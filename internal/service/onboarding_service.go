@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/pkg/apperror"
+	"gorm.io/gorm"
+)
+
+// OnboardingService manages groups' onboarding rules and runs them for members
+// added to a group, from the outbox worker. Rules act on behalf of the admin
+// who created them: they're skipped once that admin no longer manages the
+// group, and the role they assign is checked like a manual change by them.
+type OnboardingService struct {
+	ruleRepo    *repository.OnboardingRepository
+	convRepo    *repository.ConversationRepository
+	outboxRepo  *repository.OutboxRepository
+	chatService *ChatService
+}
+
+func NewOnboardingService(ruleRepo *repository.OnboardingRepository, convRepo *repository.ConversationRepository, outboxRepo *repository.OutboxRepository, chatService *ChatService) *OnboardingService {
+	return &OnboardingService{ruleRepo: ruleRepo, convRepo: convRepo, outboxRepo: outboxRepo, chatService: chatService}
+}
+
+// ListRules returns a group's rules in the order they run; needs manage_settings
+func (s *OnboardingService) ListRules(convID, userID uuid.UUID) ([]model.OnboardingRule, error) {
+	if err := s.checkCanManage(convID, userID); err != nil {
+		return nil, err
+	}
+	return s.ruleRepo.ListByConversation(convID)
+}
+
+// CreateRule adds a rule to a group; needs manage_settings
+func (s *OnboardingService) CreateRule(convID, userID uuid.UUID, req model.OnboardingRuleRequest) (*model.OnboardingRule, error) {
+	if err := s.checkCanManage(convID, userID); err != nil {
+		return nil, err
+	}
+
+	rule := &model.OnboardingRule{
+		ConversationID: convID,
+		Action:         req.Action,
+		CreatedBy:      userID,
+	}
+	switch req.Action {
+	case model.OnboardingWelcomeDM, model.OnboardingPostRules:
+		rule.Content = model.CleanContent(req.Content)
+		if strings.TrimSpace(rule.Content) == "" {
+			return nil, ErrOnboardingContent
+		}
+		if req.Role != "" || req.AfterDays != 0 {
+			return nil, ErrOnboardingRole.WithMessage("only assign_role rules have a role and after_days")
+		}
+	case model.OnboardingAssignRole:
+		if req.Content != "" {
+			return nil, ErrOnboardingContent.WithMessage("assign_role rules have no content")
+		}
+		if req.Role == model.MemberRoleAdmin || req.Role == model.MemberRoleMember {
+			return nil, ErrOnboardingRole
+		}
+		if _, err := s.convRepo.FindRole(convID, req.Role); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrRoleNotFound
+			}
+			return nil, err
+		}
+		rule.Role = req.Role
+		rule.AfterDays = req.AfterDays
+	default:
+		return nil, ErrOnboardingAction.WithDetails(map[string][]model.OnboardingAction{"actions": model.OnboardingActions})
+	}
+
+	count, err := s.ruleRepo.CountByConversation(convID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= model.MaxOnboardingRules {
+		return nil, ErrOnboardingRuleLimit
+	}
+	if err := s.ruleRepo.Create(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// DeleteRule removes a rule of a group; needs manage_settings. Role
+// assignments it already scheduled are dropped.
+func (s *OnboardingService) DeleteRule(convID, userID, ruleID uuid.UUID) error {
+	if err := s.checkCanManage(convID, userID); err != nil {
+		return err
+	}
+	err := s.ruleRepo.Delete(convID, ruleID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrOnboardingRuleNotFound
+	}
+	return err
+}
+
+// checkCanManage checks that the user manages the settings of the group
+func (s *OnboardingService) checkCanManage(convID, userID uuid.UUID) error {
+	if _, err := s.chatService.groupForRoles(convID, userID); err != nil {
+		if errors.Is(err, ErrRolesGroupOnly) {
+			return ErrOnboardingGroupOnly
+		}
+		return err
+	}
+	return s.chatService.Can(userID, convID, model.PermissionManageSettings)
+}
+
+// MemberAdded runs a group's rules for a member added to it. Only loading the
+// rules is retried on failure; a rule that fails is logged and skipped, so the
+// others don't run twice.
+func (s *OnboardingService) MemberAdded(ctx context.Context, convID, memberID uuid.UUID) error {
+	rules, err := s.ruleRepo.ListByConversation(convID)
+	if err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if rule.CreatedBy == memberID {
+			continue
+		}
+		if err := s.run(&rule, memberID); err != nil {
+			log.Printf("⚠️  Onboarding rule %s (%s) for user %s failed: %v", rule.ID, rule.Action, memberID, err)
+		}
+	}
+	return nil
+}
+
+// run takes one rule's action for a new member
+func (s *OnboardingService) run(rule *model.OnboardingRule, memberID uuid.UUID) error {
+	if err := s.chatService.Can(rule.CreatedBy, rule.ConversationID, model.PermissionManageSettings); err != nil {
+		return err
+	}
+
+	switch rule.Action {
+	case model.OnboardingWelcomeDM:
+		// Not limited like the admin's own: a directory sync may add hundreds of members
+		direct, err := s.chatService.DirectOnBehalf(rule.CreatedBy, memberID)
+		if err != nil {
+			return err
+		}
+		_, err = s.chatService.PostSystemMessage(direct.ID, rule.CreatedBy, rule.Content, nil)
+		return err
+	case model.OnboardingPostRules:
+		_, err := s.chatService.PostSystemMessage(rule.ConversationID, rule.CreatedBy, rule.Content, nil)
+		return err
+	case model.OnboardingAssignRole:
+		if rule.AfterDays == 0 {
+			return s.AssignRole(rule.ID, rule.ConversationID, memberID)
+		}
+		// Scheduled through the outbox, which holds events until they're due
+		return s.outboxRepo.Create([]model.OutboxEvent{{
+			Type: model.OutboxOnboardingRole,
+			Payload: map[string]string{
+				"conversation_id": rule.ConversationID.String(),
+				"user_id":         memberID.String(),
+				"rule_id":         rule.ID.String(),
+			},
+			AvailableAt: time.Now().AddDate(0, 0, rule.AfterDays),
+		}})
+	}
+	return nil
+}
+
+// AssignRole gives a member the role of an assign_role rule, unless the rule
+// was deleted, they left or their role was changed meanwhile
+func (s *OnboardingService) AssignRole(ruleID, convID, memberID uuid.UUID) error {
+	rule, err := s.ruleRepo.FindByID(ruleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	member, err := s.convRepo.GetMember(convID, memberID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if member.Role != model.MemberRoleMember {
+		return nil
+	}
+
+	err = s.chatService.SetMemberRole(convID, rule.CreatedBy, memberID, rule.Role)
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) && appErr.Status() < http.StatusInternalServerError {
+		// e.g. the admin left or the role was deleted: nothing a retry would fix
+		log.Printf("⚠️  Onboarding rule %s can't give user %s the %s role: %v", rule.ID, memberID, rule.Role, err)
+		return nil
+	}
+	return err
+}
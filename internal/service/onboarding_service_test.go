@@ -0,0 +1,116 @@
+package service_test
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/app"
+	"github.com/quocanhngo/gotalk/internal/config"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/migrations"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestApp builds the app on the throwaway database in TEST_DATABASE_URL
+// (a postgres:// URL), migrating it first; tests using it are skipped
+// without one
+func newTestApp(t *testing.T) *app.App {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	if err := migrations.Run(dsn); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		Logger:         logger.Default.LogMode(logger.Silent),
+		TranslateError: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rdb := app.OpenRedis(cfg)
+	t.Cleanup(func() { rdb.Close() })
+
+	a, err := app.New(cfg, db, rdb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func createUser(t *testing.T, a *app.App, name string) *model.User {
+	t.Helper()
+	user := &model.User{
+		Name:         name,
+		Email:        name + "-" + uuid.NewString() + "@example.com",
+		AuthProvider: model.AuthProviderEmail,
+	}
+	if err := a.Repositories.User.Create(user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// TestWelcomeDMsSkipDirectLimit provisions more members than the admin may
+// start direct conversations in a day: each still gets the welcome, and the
+// admin can still start their own
+func TestWelcomeDMsSkipDirectLimit(t *testing.T) {
+	const limit = 3
+	t.Setenv("LIMITS_DIRECTS_PER_DAY", strconv.Itoa(limit))
+	a := newTestApp(t)
+	chat, onboarding := a.Services.Chat, a.Services.Onboarding
+
+	admin := createUser(t, a, "admin")
+	group := &model.Conversation{
+		Name:      "Welcome",
+		Type:      model.ConversationTypeGroup,
+		CreatorID: &admin.ID,
+		Members:   []model.ConversationMember{{UserID: admin.ID, Role: model.MemberRoleAdmin}},
+	}
+	if err := a.Repositories.Conversation.Create(group); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := onboarding.CreateRule(group.ID, admin.ID, model.OnboardingRuleRequest{
+		Action:  model.OnboardingWelcomeDM,
+		Content: "Welcome aboard",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < limit+2; i++ {
+		member := createUser(t, a, "member")
+		if err := onboarding.MemberAdded(context.Background(), group.ID, member.ID); err != nil {
+			t.Fatal(err)
+		}
+		direct, err := a.Repositories.Conversation.FindPrivateConversation(admin.ID, member.ID)
+		if err != nil {
+			t.Fatalf("member %d got no welcome conversation: %v", i+1, err)
+		}
+		messages, err := a.Repositories.Message.GetConversationMessages(direct.ID, member.ID, nil, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(messages) != 1 || messages[0].Content != "Welcome aboard" {
+			t.Fatalf("member %d got %d messages, want the welcome", i+1, len(messages))
+		}
+	}
+
+	friend := createUser(t, a, "friend")
+	if _, err := chat.CreateConversation(admin.ID, model.CreateConversationRequest{
+		Type:      model.ConversationTypePrivate,
+		MemberIDs: []uuid.UUID{friend.ID},
+	}); err != nil {
+		t.Fatalf("welcomes counted toward the admin's own limit: %v", err)
+	}
+}
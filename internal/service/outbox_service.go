@@ -0,0 +1,397 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/internal/ws"
+	"github.com/quocanhngo/gotalk/pkg/notification"
+	"gorm.io/gorm"
+)
+
+const (
+	outboxBatchSize    = 100
+	outboxLease        = 30 * time.Second // a claimed event is retried after this if its worker dies
+	outboxPollInterval = time.Second      // picks up retries and events left by other instances
+	outboxMaxAttempts  = 10
+	outboxMaxBackoff   = 5 * time.Minute
+)
+
+// OutboxService publishes the outbox events saved with new messages to the
+// WebSocket hub and the push pipeline, and runs the onboarding of members
+// added to groups. Delivery is at-least-once: an event is retried until it's
+// published, so clients may receive a message twice and should dedupe by its
+// ID.
+type OutboxService struct {
+	outboxRepo   *repository.OutboxRepository
+	msgRepo      *repository.MessageRepository
+	convRepo     *repository.ConversationRepository
+	members      *MembershipCache
+	userRepo     *repository.UserRepository
+	hub          *ws.Hub
+	notifService *notification.NotificationService
+	notifCenter  *NotificationCenterService
+	relay        Relay              // optional
+	replyMail    *ReplyMailService  // optional
+	searchIndex  *MessageIndex      // optional
+	onboarding   *OnboardingService // optional
+	autoReply    *AutoReplyService  // optional
+
+	wake chan struct{}
+}
+
+// Relay forwards activity in bridged conversations to another network
+type Relay interface {
+	// RelayMessage copies a new message to the network; it's retried on error
+	RelayMessage(ctx context.Context, msg *model.Message) error
+	// RelayTyping forwards a typing indicator, best-effort
+	RelayTyping(conversationID, userID uuid.UUID, typing bool)
+}
+
+func NewOutboxService(
+	outboxRepo *repository.OutboxRepository,
+	msgRepo *repository.MessageRepository,
+	convRepo *repository.ConversationRepository,
+	userRepo *repository.UserRepository,
+	members *MembershipCache,
+	hub *ws.Hub,
+	notifService *notification.NotificationService,
+	notifCenter *NotificationCenterService,
+) *OutboxService {
+	return &OutboxService{
+		outboxRepo:   outboxRepo,
+		msgRepo:      msgRepo,
+		convRepo:     convRepo,
+		members:      members,
+		userRepo:     userRepo,
+		hub:          hub,
+		notifService: notifService,
+		notifCenter:  notifCenter,
+		wake:         make(chan struct{}, 1),
+	}
+}
+
+// UseRelay also publishes new messages to a bridged network
+func (s *OutboxService) UseRelay(relay Relay) {
+	s.relay = relay
+}
+
+// UseReplyMail also emails offline members who opted in about new messages
+func (s *OutboxService) UseReplyMail(replyMail *ReplyMailService) {
+	s.replyMail = replyMail
+}
+
+// UseSearchIndex also adds new messages to an external search index
+func (s *OutboxService) UseSearchIndex(index *MessageIndex) {
+	s.searchIndex = index
+}
+
+// UseOnboarding runs groups' onboarding rules for members added to them
+func (s *OutboxService) UseOnboarding(onboarding *OnboardingService) {
+	s.onboarding = onboarding
+}
+
+// UseAutoReply answers direct messages to users whose auto-reply is on
+func (s *OutboxService) UseAutoReply(autoReply *AutoReplyService) {
+	s.autoReply = autoReply
+}
+
+// MessageEvents returns the events announcing a new message, to be saved in
+// the same transaction as the message
+func (s *OutboxService) MessageEvents(msg *model.Message) []model.OutboxEvent {
+	eventTypes := []model.OutboxEventType{model.OutboxMessageBroadcast, model.OutboxMessageNotify}
+	if s.relay != nil {
+		eventTypes = append(eventTypes, model.OutboxMessageRelay)
+	}
+	if s.searchIndex != nil && msg.Content != "" {
+		eventTypes = append(eventTypes, model.OutboxMessageIndex)
+	}
+	return messageOutboxEvents(msg, eventTypes)
+}
+
+func messageOutboxEvents(msg *model.Message, eventTypes []model.OutboxEventType) []model.OutboxEvent {
+	now := time.Now()
+	events := make([]model.OutboxEvent, 0, len(eventTypes))
+	for _, eventType := range eventTypes {
+		events = append(events, model.OutboxEvent{
+			Type: eventType,
+			Payload: map[string]string{
+				"message_id":      msg.ID.String(),
+				"conversation_id": msg.ConversationID.String(),
+				"sender_id":       msg.SenderID.String(),
+			},
+			AvailableAt: now,
+		})
+	}
+	return events
+}
+
+// MemberEvents returns the events announcing members added to a group by
+// addedBy, to be saved in the same transaction as the memberships
+func (s *OutboxService) MemberEvents(convID, addedBy uuid.UUID, memberIDs []uuid.UUID) []model.OutboxEvent {
+	now := time.Now()
+	events := make([]model.OutboxEvent, 0, len(memberIDs))
+	for _, memberID := range memberIDs {
+		events = append(events, model.OutboxEvent{
+			Type: model.OutboxMemberAdded,
+			Payload: map[string]string{
+				"conversation_id": convID.String(),
+				"user_id":         memberID.String(),
+				"added_by":        addedBy.String(),
+			},
+			AvailableAt: now,
+		})
+	}
+	return events
+}
+
+// Wake makes the worker publish right away instead of at its next poll
+func (s *OutboxService) Wake() {
+	if s == nil {
+		return
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run publishes events as they are woken or polled, blocking until ctx is cancelled
+func (s *OutboxService) Run(ctx context.Context) {
+	log.Println("📤 Outbox worker started")
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.drain(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// drain publishes due events until none are left
+func (s *OutboxService) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		events, err := s.outboxRepo.Claim(outboxBatchSize, outboxLease)
+		if err != nil {
+			log.Printf("⚠️  Failed to claim outbox events: %v", err)
+			return
+		}
+
+		// A large group's push fan-out shouldn't hold up other conversations
+		var wg sync.WaitGroup
+		for i := range events {
+			wg.Add(1)
+			go func(event *model.OutboxEvent) {
+				defer wg.Done()
+				s.process(ctx, event)
+			}(&events[i])
+		}
+		wg.Wait()
+
+		if len(events) < outboxBatchSize {
+			return
+		}
+	}
+}
+
+// process publishes one event, deleting it when done and scheduling a retry
+// with exponential backoff when it failed
+func (s *OutboxService) process(ctx context.Context, event *model.OutboxEvent) {
+	err := s.publish(ctx, event)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil // the message was deleted meanwhile, nothing left to announce
+	}
+	if err == nil || event.Attempts >= outboxMaxAttempts {
+		if err != nil {
+			log.Printf("❌ Dropping outbox event %s (%s) after %d attempts: %v", event.ID, event.Type, event.Attempts, err)
+		}
+		if err := s.outboxRepo.Delete(event.ID); err != nil {
+			log.Printf("⚠️  Failed to delete outbox event %s: %v", event.ID, err)
+		}
+		return
+	}
+
+	backoff := min(time.Second<<event.Attempts, outboxMaxBackoff)
+	log.Printf("⚠️  Outbox event %s (%s) failed, retrying in %s: %v", event.ID, event.Type, backoff, err)
+	if err := s.outboxRepo.Retry(event.ID, time.Now().Add(backoff), err.Error()); err != nil {
+		log.Printf("⚠️  Failed to reschedule outbox event %s: %v", event.ID, err)
+	}
+}
+
+func (s *OutboxService) publish(ctx context.Context, event *model.OutboxEvent) error {
+	switch event.Type {
+	case model.OutboxMemberAdded, model.OutboxOnboardingRole:
+		return s.publishMember(ctx, event)
+	}
+
+	messageID, err := uuid.Parse(event.Payload["message_id"])
+	if err != nil {
+		log.Printf("⚠️  Outbox event %s has no valid message_id, skipping", event.ID)
+		return nil
+	}
+
+	switch event.Type {
+	case model.OutboxMessageBroadcast:
+		return s.broadcastMessage(ctx, messageID)
+	case model.OutboxMessageNotify:
+		return s.notifyMessage(ctx, messageID)
+	case model.OutboxMessageRelay:
+		return s.relayMessage(ctx, messageID)
+	case model.OutboxMessageIndex:
+		return s.indexMessage(ctx, messageID)
+	}
+	log.Printf("⚠️  Outbox event %s has unknown type %q, skipping", event.ID, event.Type)
+	return nil
+}
+
+// publishMember runs the onboarding of a member added to a group. Events
+// saved while onboarding was configured are dropped once it no longer is.
+func (s *OutboxService) publishMember(ctx context.Context, event *model.OutboxEvent) error {
+	if s.onboarding == nil {
+		return nil
+	}
+	convID, err := uuid.Parse(event.Payload["conversation_id"])
+	if err != nil {
+		log.Printf("⚠️  Outbox event %s has no valid conversation_id, skipping", event.ID)
+		return nil
+	}
+	userID, err := uuid.Parse(event.Payload["user_id"])
+	if err != nil {
+		log.Printf("⚠️  Outbox event %s has no valid user_id, skipping", event.ID)
+		return nil
+	}
+
+	if event.Type == model.OutboxOnboardingRole {
+		ruleID, err := uuid.Parse(event.Payload["rule_id"])
+		if err != nil {
+			log.Printf("⚠️  Outbox event %s has no valid rule_id, skipping", event.ID)
+			return nil
+		}
+		return s.onboarding.AssignRole(ruleID, convID, userID)
+	}
+	return s.onboarding.MemberAdded(ctx, convID, userID)
+}
+
+// broadcastMessage sends new_message to every member but the sender
+func (s *OutboxService) broadcastMessage(ctx context.Context, messageID uuid.UUID) error {
+	msg, err := s.msgRepo.FindByID(messageID)
+	if err != nil {
+		return err
+	}
+	// Broadcast to every member, so show the sender as a non-contact would see them
+	msg.Sender.ApplyPrivacy(uuid.Nil, false)
+
+	memberIDs, err := s.members.MemberIDs(ctx, msg.ConversationID)
+	if err != nil {
+		return err
+	}
+	var recipientIDs []uuid.UUID
+	for _, id := range memberIDs {
+		if id != msg.SenderID {
+			recipientIDs = append(recipientIDs, id)
+		}
+	}
+
+	if err := s.hub.Deliver(ctx, recipientIDs, &model.WSEvent{
+		Type:    model.WSEventNewMessage,
+		Payload: msg,
+	}); err != nil {
+		return fmt.Errorf("broadcast: %w", err)
+	}
+	return nil
+}
+
+// notifyMessage sends push notifications to the other members, emails those
+// who asked for it while offline, sends the auto-reply of a direct message's
+// recipient and adds notification center entries for mentions. Failures for
+// single recipients are logged rather than retried, so the others aren't
+// notified twice.
+func (s *OutboxService) notifyMessage(ctx context.Context, messageID uuid.UUID) error {
+	msg, err := s.msgRepo.FindByID(messageID)
+	if err != nil {
+		return err
+	}
+	sender, err := s.userRepo.FindByID(msg.SenderID)
+	if err != nil {
+		return err
+	}
+	conv, err := s.convRepo.FindByID(msg.ConversationID)
+	if err != nil {
+		return err
+	}
+
+	var mentioned []uuid.UUID
+	for _, m := range conv.Members {
+		if m.UserID == msg.SenderID {
+			continue
+		}
+		if err := s.notifService.SendMessageNotification(ctx, m.UserID, sender.PublicName(), msg.Content, conv.ID); err != nil {
+			log.Printf("⚠️  Push for message %s to user %s failed: %v", msg.ID, m.UserID, err)
+		}
+		if conv.Type == model.ConversationTypeGroup && notification.IsMention(msg.Content, m.User.Handle) {
+			mentioned = append(mentioned, m.UserID)
+		}
+	}
+
+	if s.replyMail.Enabled() {
+		s.replyMail.NotifyMessage(ctx, msg, conv, sender)
+	}
+
+	if s.autoReply != nil && conv.Type == model.ConversationTypePrivate {
+		for _, m := range conv.Members {
+			if m.UserID == msg.SenderID {
+				continue
+			}
+			if err := s.autoReply.Reply(ctx, &m.User, msg); err != nil {
+				log.Printf("⚠️  Auto-reply of user %s to message %s failed: %v", m.UserID, msg.ID, err)
+			}
+		}
+	}
+
+	// Mentions also land in the notification center
+	if len(mentioned) > 0 {
+		if err := s.notifCenter.Notify(mentioned, model.NotificationTypeMention,
+			fmt.Sprintf("%s mentioned you in %s", sender.PublicName(), conv.Name), msg.Content,
+			map[string]string{"conversation_id": conv.ID.String(), "message_id": msg.ID.String()}); err != nil {
+			log.Printf("⚠️  Mention notifications for message %s failed: %v", msg.ID, err)
+		}
+	}
+	return nil
+}
+
+// relayMessage copies the message to the bridged network. Events saved while
+// a bridge was configured are dropped once it no longer is.
+func (s *OutboxService) relayMessage(ctx context.Context, messageID uuid.UUID) error {
+	if s.relay == nil {
+		return nil
+	}
+	msg, err := s.msgRepo.FindByID(messageID)
+	if err != nil {
+		return err
+	}
+	return s.relay.RelayMessage(ctx, msg)
+}
+
+// indexMessage adds a new message to the search index
+func (s *OutboxService) indexMessage(ctx context.Context, messageID uuid.UUID) error {
+	if s.searchIndex == nil {
+		return nil
+	}
+	msg, err := s.msgRepo.FindByID(messageID)
+	if err != nil {
+		return err
+	}
+	return s.searchIndex.Index(ctx, *msg)
+}
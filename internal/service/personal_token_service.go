@@ -0,0 +1,120 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/pkg/auth"
+	"github.com/quocanhngo/gotalk/pkg/oidc"
+	"gorm.io/gorm"
+)
+
+const (
+	maxPersonalTokens      = 50
+	personalTokenHintLen   = 8           // prefix plus a few characters, shown in token lists
+	personalTokenTouchStep = time.Minute // last use is recorded at most this often
+)
+
+// PersonalTokenService manages personal access tokens: long-lived, named
+// tokens limited to the scopes their owner picked, for scripts and
+// integrations that can't sign in interactively
+type PersonalTokenService struct {
+	repo     *repository.PersonalTokenRepository
+	userRepo *repository.UserRepository
+}
+
+func NewPersonalTokenService(
+	repo *repository.PersonalTokenRepository,
+	userRepo *repository.UserRepository,
+) *PersonalTokenService {
+	return &PersonalTokenService{repo: repo, userRepo: userRepo}
+}
+
+// Create issues a token. Its value is only returned here.
+func (s *PersonalTokenService) Create(userID uuid.UUID, req model.CreatePersonalTokenRequest) (*model.CreatePersonalTokenResponse, error) {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrTokenExpiry
+	}
+	count, err := s.repo.Count(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxPersonalTokens {
+		return nil, ErrTooManyPersonalTokens
+	}
+
+	secret, err := oidc.RandomString()
+	if err != nil {
+		return nil, err
+	}
+	value := auth.PersonalTokenPrefix + secret
+	token := model.PersonalAccessToken{
+		UserID:    userID,
+		Name:      strings.TrimSpace(req.Name),
+		TokenHash: auth.TokenHash(value),
+		Hint:      value[:personalTokenHintLen],
+		Scopes:    normalizeScopes(req.Scopes),
+		ExpiresAt: req.ExpiresAt,
+	}
+	if token.Scopes == nil {
+		return nil, ErrInvalidScope
+	}
+	if err := s.repo.Create(&token); err != nil {
+		return nil, err
+	}
+	return &model.CreatePersonalTokenResponse{PersonalAccessToken: token, Token: value}, nil
+}
+
+// List returns the user's tokens, without their values
+func (s *PersonalTokenService) List(userID uuid.UUID) ([]model.PersonalAccessToken, error) {
+	return s.repo.List(userID)
+}
+
+// Revoke deletes one of the user's tokens; it stops working immediately
+func (s *PersonalTokenService) Revoke(userID, tokenID uuid.UUID) error {
+	err := s.repo.Delete(tokenID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrPersonalTokenNotFound
+	}
+	return err
+}
+
+// Authenticate resolves a token presented by a client at ip to the claims of
+// its owner, limited to the token's scopes, and records the use
+func (s *PersonalTokenService) Authenticate(value, ip string) (*auth.Claims, error) {
+	token, err := s.repo.FindByHash(auth.TokenHash(value))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidPersonalToken
+		}
+		return nil, err
+	}
+	now := time.Now()
+	if token.ExpiresAt != nil && !token.ExpiresAt.After(now) {
+		return nil, ErrInvalidPersonalToken
+	}
+	user, err := s.userRepo.FindByID(token.UserID)
+	if err != nil || !user.IsActive() {
+		return nil, ErrInvalidPersonalToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= personalTokenTouchStep || token.LastUsedIP != ip {
+		go func() {
+			if err := s.repo.Touch(token.ID, now, ip); err != nil {
+				log.Printf("⚠️ Failed to record use of personal token %s: %v", token.ID, err)
+			}
+		}()
+	}
+
+	return &auth.Claims{
+		UserID: user.ID,
+		Email:  user.Email,
+		Name:   user.Name,
+		Scope:  strings.Join(token.Scopes, " "),
+	}, nil
+}
@@ -0,0 +1,404 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/internal/ws"
+	"github.com/redis/go-redis/v9"
+)
+
+// Presence states live in Redis: the state each user chose (none means
+// online), a key that exists while they're active, and the state last
+// announced to others, so every instance announces a change only once
+const (
+	presenceChosenKeyPrefix = "gotalk:presence:state:"
+	presenceActiveKeyPrefix = "gotalk:presence:active:"
+	presenceShownKeyPrefix  = "gotalk:presence:shown:"
+	presenceActiveRefresh   = 30 * time.Second // activity refreshes the active key at most this often
+)
+
+// PresenceService keeps users.is_online in sync with live WebSocket presence
+// and the state users show (online, away, busy or invisible). Without it,
+// users connected to an instance that crashed stay online forever.
+// users.is_online is false for invisible users, so privacy checks hide them.
+type PresenceService struct {
+	userRepo    *repository.UserRepository
+	hub         *ws.Hub
+	rdb         *redis.Client
+	idleTimeout time.Duration
+
+	// When this instance last refreshed each user's active key
+	mu     sync.Mutex
+	active map[uuid.UUID]time.Time
+}
+
+func NewPresenceService(userRepo *repository.UserRepository, hub *ws.Hub, rdb *redis.Client, idleTimeout time.Duration) *PresenceService {
+	return &PresenceService{
+		userRepo:    userRepo,
+		hub:         hub,
+		rdb:         rdb,
+		idleTimeout: idleTimeout,
+		active:      make(map[uuid.UUID]time.Time),
+	}
+}
+
+// StatusChanged announces a user's first connection or last disconnection on
+// this instance, if it changes how they show
+func (s *PresenceService) StatusChanged(userID uuid.UUID, online bool) {
+	if online {
+		s.Active(userID)
+	} else {
+		s.mu.Lock()
+		delete(s.active, userID)
+		s.mu.Unlock()
+	}
+	s.announce(context.Background(), userID)
+}
+
+// Active records that the user did something (sent an event or called the
+// API), bringing them back from away
+func (s *PresenceService) Active(userID uuid.UUID) {
+	now := time.Now()
+	s.mu.Lock()
+	if last, ok := s.active[userID]; ok && now.Sub(last) < presenceActiveRefresh {
+		s.mu.Unlock()
+		return
+	}
+	s.active[userID] = now
+	s.mu.Unlock()
+
+	ctx := context.Background()
+	err := s.rdb.SetArgs(ctx, presenceActiveKeyPrefix+userID.String(), now.Unix(), redis.SetArgs{TTL: s.idleTimeout, Get: true}).Err()
+	if errors.Is(err, redis.Nil) {
+		// The key had expired: the user was idle
+		s.announce(ctx, userID)
+	} else if err != nil {
+		log.Printf("⚠️  Failed to record activity of %s: %v", userID, err)
+	}
+}
+
+// Presence returns the state the user chose and how they show now
+func (s *PresenceService) Presence(ctx context.Context, userID uuid.UUID) (*model.PresenceResponse, error) {
+	chosen, err := s.chosen(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := s.current(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.PresenceResponse{State: chosen, Current: current}, nil
+}
+
+// SetPresence chooses how the user shows to others. Choosing online counts as
+// activity. The user's devices get a presence_changed event.
+func (s *PresenceService) SetPresence(ctx context.Context, userID uuid.UUID, state model.PresenceState) (*model.PresenceResponse, error) {
+	if !slices.Contains(model.PresenceChoices, state) {
+		return nil, ErrPresenceState
+	}
+
+	key := presenceChosenKeyPrefix + userID.String()
+	if state == model.PresenceOnline {
+		if err := s.rdb.Del(ctx, key).Err(); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		s.active[userID] = time.Now()
+		s.mu.Unlock()
+		if err := s.rdb.Set(ctx, presenceActiveKeyPrefix+userID.String(), time.Now().Unix(), s.idleTimeout).Err(); err != nil {
+			return nil, err
+		}
+	} else if err := s.rdb.Set(ctx, key, string(state), 0).Err(); err != nil {
+		return nil, err
+	}
+	s.announce(ctx, userID)
+
+	presence, err := s.Presence(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.hub.SendToUser(userID, &model.WSEvent{Type: model.WSEventPresenceChanged, Payload: presence})
+	return presence, nil
+}
+
+// chosen returns the state the user chose, online when none
+func (s *PresenceService) chosen(ctx context.Context, userID uuid.UUID) (model.PresenceState, error) {
+	state, err := s.rdb.Get(ctx, presenceChosenKeyPrefix+userID.String()).Result()
+	if errors.Is(err, redis.Nil) {
+		return model.PresenceOnline, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return model.PresenceState(state), nil
+}
+
+// current returns how the user shows now, as they see it themselves: offline
+// when not connected, otherwise their choice, with online turned into away
+// when they've been idle
+func (s *PresenceService) current(ctx context.Context, userID uuid.UUID) (model.PresenceState, error) {
+	present, err := s.hub.IsUserPresent(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if !present {
+		return model.PresenceOffline, nil
+	}
+
+	chosen, err := s.chosen(ctx, userID)
+	if err != nil || chosen != model.PresenceOnline {
+		return chosen, err
+	}
+	active, err := s.rdb.Exists(ctx, presenceActiveKeyPrefix+userID.String()).Result()
+	if err != nil {
+		return "", err
+	}
+	if active == 0 {
+		return model.PresenceAway, nil
+	}
+	return model.PresenceOnline, nil
+}
+
+// announce persists how the user shows and, when that changed, tells the
+// connections subscribed to the user that may see it
+func (s *PresenceService) announce(ctx context.Context, userID uuid.UUID) {
+	state, err := s.current(ctx, userID)
+	if err != nil {
+		log.Printf("⚠️  Failed to load presence of %s: %v", userID, err)
+		return
+	}
+	if state == model.PresenceInvisible {
+		state = model.PresenceOffline
+	}
+
+	key := presenceShownKeyPrefix + userID.String()
+	var previous string
+	if state == model.PresenceOffline {
+		previous, err = s.rdb.GetDel(ctx, key).Result()
+	} else {
+		previous, err = s.rdb.SetArgs(ctx, key, string(state), redis.SetArgs{Get: true}).Result()
+	}
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Printf("⚠️  Failed to save presence of %s: %v", userID, err)
+		return
+	}
+	if previous == "" {
+		previous = string(model.PresenceOffline)
+	}
+	if model.PresenceState(previous) == state {
+		return
+	}
+
+	online := state != model.PresenceOffline
+	if online != (model.PresenceState(previous) != model.PresenceOffline) {
+		_ = s.userRepo.UpdateOnlineStatus(userID, online)
+	}
+	log.Printf("👤 User %s is now %s", userID, strings.ToUpper(string(state)))
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return
+	}
+
+	eventType := model.WSEventOffline
+	if online {
+		eventType = model.WSEventOnline
+	}
+	event := &model.WSEvent{
+		Type:    eventType,
+		Payload: model.OnlineEvent{UserID: userID, IsOnline: online, State: state},
+	}
+
+	switch user.OnlinePrivacy {
+	case model.PrivacyNobody:
+		return
+	case model.PrivacyContacts:
+		contactIDs, err := s.userRepo.GetContactIDs(userID)
+		if err != nil {
+			return
+		}
+		if len(contactIDs) > 0 {
+			s.hub.PublishPresence(userID, event, contactIDs)
+		}
+	default:
+		s.hub.PublishPresence(userID, event, nil)
+	}
+}
+
+// states returns the states stored under prefix for the given users; users
+// without one are left out
+func (s *PresenceService) states(ctx context.Context, prefix string, userIDs []uuid.UUID) (map[uuid.UUID]model.PresenceState, error) {
+	states := make(map[uuid.UUID]model.PresenceState, len(userIDs))
+	if len(userIDs) == 0 {
+		return states, nil
+	}
+	keys := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		keys[i] = prefix + id.String()
+	}
+	values, err := s.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for i, value := range values {
+		if state, ok := value.(string); ok {
+			states[userIDs[i]] = model.PresenceState(state)
+		}
+	}
+	return states, nil
+}
+
+// expireIdle announces the users whose activity on this instance is older
+// than the idle timeout, which shows them as away unless they were active
+// elsewhere
+func (s *PresenceService) expireIdle(ctx context.Context) {
+	cutoff := time.Now().Add(-s.idleTimeout)
+	var idle []uuid.UUID
+	s.mu.Lock()
+	for userID, last := range s.active {
+		if last.Before(cutoff) {
+			idle = append(idle, userID)
+			delete(s.active, userID)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, userID := range idle {
+		s.announce(ctx, userID)
+	}
+}
+
+// StateAmong returns which of the users the viewer may see online right now,
+// and how each of those shows
+func (s *PresenceService) StateAmong(ctx context.Context, viewerID uuid.UUID, userIDs []uuid.UUID) (*model.PresenceStateEvent, error) {
+	users, err := s.userRepo.FindByIDs(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	contacts, err := contactSet(s.userRepo, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	online := []uuid.UUID{}
+	for i := range users {
+		users[i].ApplyPrivacy(viewerID, contacts[users[i].ID])
+		if users[i].IsOnline {
+			online = append(online, users[i].ID)
+		}
+	}
+
+	states, err := s.states(ctx, presenceShownKeyPrefix, online)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range online {
+		if _, ok := states[id]; !ok {
+			states[id] = model.PresenceOnline
+		}
+	}
+	return &model.PresenceStateEvent{OnlineUserIDs: online, States: states}, nil
+}
+
+// Run reconciles once at startup and then every interval, blocking until ctx is cancelled
+func (s *PresenceService) Run(ctx context.Context, interval time.Duration) {
+	// Let the hub register this instance before the first pass
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(time.Second):
+	}
+	s.reconcile(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.expireIdle(ctx)
+			s.reconcile(ctx)
+		}
+	}
+}
+
+// reconcile compares DB online flags with Redis presence and fixes any drift;
+// invisible users count as absent
+func (s *PresenceService) reconcile(ctx context.Context) {
+	present, err := s.hub.PresentUserIDs(ctx)
+	if err != nil {
+		log.Printf("⚠️  Presence reconcile failed: %v", err)
+		return
+	}
+	presentIDs := make([]uuid.UUID, 0, len(present))
+	for id := range present {
+		presentIDs = append(presentIDs, id)
+	}
+	chosen, err := s.states(ctx, presenceChosenKeyPrefix, presentIDs)
+	if err != nil {
+		log.Printf("⚠️  Presence reconcile failed: %v", err)
+		return
+	}
+	for id, state := range chosen {
+		if state == model.PresenceInvisible {
+			delete(present, id)
+		}
+	}
+
+	onlineIDs, err := s.userRepo.FindOnlineIDs()
+	if err != nil {
+		log.Printf("⚠️  Presence reconcile failed: %v", err)
+		return
+	}
+
+	var stale []uuid.UUID
+	markedOnline := make(map[uuid.UUID]bool, len(onlineIDs))
+	for _, id := range onlineIDs {
+		markedOnline[id] = true
+		if !present[id] {
+			stale = append(stale, id)
+		}
+	}
+
+	var missing []uuid.UUID
+	for id := range present {
+		if !markedOnline[id] {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(stale) > 0 {
+		if err := s.userRepo.SetOnlineStatusBulk(stale, false); err != nil {
+			log.Printf("⚠️  Failed to reset stale online users: %v", err)
+		} else {
+			s.forgetShown(ctx, stale)
+			log.Printf("🧹 Marked %d stale users offline", len(stale))
+		}
+	}
+	if len(missing) > 0 {
+		if err := s.userRepo.SetOnlineStatusBulk(missing, true); err != nil {
+			log.Printf("⚠️  Failed to mark present users online: %v", err)
+		}
+	}
+}
+
+// forgetShown drops the announced states of users found offline
+func (s *PresenceService) forgetShown(ctx context.Context, userIDs []uuid.UUID) {
+	keys := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		keys[i] = presenceShownKeyPrefix + id.String()
+	}
+	if err := s.rdb.Del(ctx, keys...).Err(); err != nil {
+		log.Printf("⚠️  Failed to clear presence of stale users: %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ProfileService handles profile pages, custom statuses and auto-replies
+type ProfileService struct {
+	userRepo        *repository.UserRepository
+	onStatusChanged func(userID uuid.UUID, status *model.UserStatus) // tells contacts about the new status
+}
+
+func NewProfileService(userRepo *repository.UserRepository, onStatusChanged func(userID uuid.UUID, status *model.UserStatus)) *ProfileService {
+	return &ProfileService{userRepo: userRepo, onStatusChanged: onStatusChanged}
+}
+
+// GetProfile returns another user's profile as seen by the viewer
+func (s *ProfileService) GetProfile(userID, viewerID uuid.UUID) (*model.UserProfileResponse, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	contacts, err := contactSet(s.userRepo, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.UserProfileResponse{
+		UserResponse: user.ToPublicResponse(viewerID, contacts[userID]),
+		IsContact:    contacts[userID],
+	}, nil
+}
+
+// UpdateStatus sets the user's custom status
+func (s *ProfileService) UpdateStatus(userID uuid.UUID, req model.UpdateStatusRequest) (*model.UserStatus, error) {
+	if req.Message == "" && req.Emoji == "" {
+		return nil, s.ClearStatus(userID)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrStatusExpiry
+	}
+
+	if err := s.userRepo.UpdateStatus(userID, req.Message, req.Emoji, req.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	status := &model.UserStatus{Message: req.Message, Emoji: req.Emoji, ExpiresAt: req.ExpiresAt}
+	s.statusChanged(userID, status)
+	return status, nil
+}
+
+// ClearStatus removes the user's custom status
+func (s *ProfileService) ClearStatus(userID uuid.UUID) error {
+	if err := s.userRepo.UpdateStatus(userID, "", "", nil); err != nil {
+		return err
+	}
+	s.statusChanged(userID, nil)
+	return nil
+}
+
+// GetAutoReply returns the user's auto-reply, or nil if none is set
+func (s *ProfileService) GetAutoReply(userID uuid.UUID) (*model.AutoReply, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return user.AutoReply(time.Now()), nil
+}
+
+// SetAutoReply sets the user's auto-reply, answering direct messages during its window
+func (s *ProfileService) SetAutoReply(userID uuid.UUID, req model.SetAutoReplyRequest) (*model.AutoReply, error) {
+	text := strings.TrimSpace(model.CleanContent(req.Text))
+	if text == "" {
+		return nil, ErrAutoReplyEmpty
+	}
+	if req.EndsAt != nil && (!req.EndsAt.After(time.Now()) || (req.StartsAt != nil && !req.EndsAt.After(*req.StartsAt))) {
+		return nil, ErrAutoReplyWindow
+	}
+
+	if err := s.userRepo.UpdateAutoReply(userID, text, req.StartsAt, req.EndsAt); err != nil {
+		return nil, err
+	}
+	user := model.User{AutoReplyText: text, AutoReplyStartsAt: req.StartsAt, AutoReplyEndsAt: req.EndsAt}
+	return user.AutoReply(time.Now()), nil
+}
+
+// ClearAutoReply turns the user's auto-reply off
+func (s *ProfileService) ClearAutoReply(userID uuid.UUID) error {
+	return s.userRepo.UpdateAutoReply(userID, "", nil, nil)
+}
+
+// Run clears expired statuses every interval so contacts see them disappear,
+// blocking until ctx is cancelled
+func (s *ProfileService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ids, err := s.userRepo.ClearExpiredStatuses(time.Now())
+			if err != nil {
+				log.Printf("⚠️ Failed to clear expired statuses: %v", err)
+				continue
+			}
+			for _, id := range ids {
+				s.statusChanged(id, nil)
+			}
+		}
+	}
+}
+
+func (s *ProfileService) statusChanged(userID uuid.UUID, status *model.UserStatus) {
+	if s.onStatusChanged != nil {
+		s.onStatusChanged(userID, status)
+	}
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/internal/ws"
+	"github.com/quocanhngo/gotalk/pkg/mailer"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	replyMailTokenKeyPrefix    = "gotalk:replymail:token:" // + token: "<user id>:<conversation id>"
+	replyMailThrottleKeyPrefix = "gotalk:replymail:sent:"  // + user id:conversation id, while an email is recent
+	replyMailSeenKeyPrefix     = "gotalk:replymail:seen:"  // + Message-ID of replies already posted
+	replyMailSeenTTL           = 7 * 24 * time.Hour
+
+	// replyAddressPrefix starts the local part of reply addresses: reply+<token>@<domain>
+	replyAddressPrefix = "reply+"
+)
+
+// ReplyMailService emails members about messages that arrive while they're
+// offline and posts their email replies back to the conversation. Each email
+// gets its own reply-to address whose token maps to the recipient and the
+// conversation; a reply is only accepted from the recipient's own address.
+type ReplyMailService struct {
+	userRepo    *repository.UserRepository
+	chatService *ChatService
+	mailer      *mailer.Mailer
+	hub         *ws.Hub
+	rdb         *redis.Client
+	domain      string        // receives reply+<token>@domain; empty disables the service
+	addressTTL  time.Duration // how long a reply address keeps working
+	throttle    time.Duration // at most one email per member and conversation in this window
+}
+
+func NewReplyMailService(
+	userRepo *repository.UserRepository,
+	chatService *ChatService,
+	mailer *mailer.Mailer,
+	hub *ws.Hub,
+	rdb *redis.Client,
+	domain string,
+	addressTTL, throttle time.Duration,
+) *ReplyMailService {
+	return &ReplyMailService{
+		userRepo:    userRepo,
+		chatService: chatService,
+		mailer:      mailer,
+		hub:         hub,
+		rdb:         rdb,
+		domain:      strings.ToLower(domain),
+		addressTTL:  addressTTL,
+		throttle:    throttle,
+	}
+}
+
+// Enabled reports whether a reply domain is configured
+func (s *ReplyMailService) Enabled() bool {
+	return s != nil && s.domain != ""
+}
+
+// NotifyMessage emails the members who opted in and aren't connected anywhere.
+// Failures are logged; a member emailed about the conversation recently isn't
+// emailed again until the throttle window has passed.
+func (s *ReplyMailService) NotifyMessage(ctx context.Context, msg *model.Message, conv *model.Conversation, sender *model.User) {
+	notification := mailer.MessageNotification{
+		SenderName: sender.PublicName(),
+		Content:    msg.Content,
+	}
+	if conv.Type == model.ConversationTypeGroup {
+		notification.ConversationName = conv.Name
+	}
+	if notification.Content == "" {
+		notification.Content = "📎 Attachment"
+	}
+
+	for i := range conv.Members {
+		user := &conv.Members[i].User
+		if user.ID == msg.SenderID || !user.IsEmailNotifEnabled || !user.IsEmailVerified() || !user.IsActive() || user.MatrixID != nil {
+			continue
+		}
+		present, err := s.hub.IsUserPresent(ctx, user.ID)
+		if err != nil || present {
+			continue
+		}
+
+		throttleKey := replyMailThrottleKeyPrefix + user.ID.String() + ":" + conv.ID.String()
+		acquired, err := s.rdb.SetNX(ctx, throttleKey, "1", s.throttle).Result()
+		if err != nil || !acquired {
+			continue
+		}
+
+		replyTo, err := s.replyAddress(ctx, user.ID, conv.ID)
+		if err != nil {
+			log.Printf("⚠️  Failed to create reply address for user %s: %v", user.ID, err)
+			continue
+		}
+		if err := s.mailer.SendMessageNotification(user.Email, user.Name, replyTo, notification); err != nil {
+			log.Printf("⚠️  Message email for message %s to user %s failed: %v", msg.ID, user.ID, err)
+		}
+	}
+}
+
+// replyAddress creates a reply-to address that posts as the user to the conversation
+func (s *ReplyMailService) replyAddress(ctx context.Context, userID, convID uuid.UUID) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	if err := s.rdb.Set(ctx, replyMailTokenKeyPrefix+token, userID.String()+":"+convID.String(), s.addressTTL).Err(); err != nil {
+		return "", err
+	}
+	return replyAddressPrefix + token + "@" + s.domain, nil
+}
+
+// HandleInbound posts an emailed reply (a raw RFC 5322 message) to the
+// conversation its reply address belongs to, as the address's user.
+// Redelivered emails are recognized by their Message-ID and posted once.
+func (s *ReplyMailService) HandleInbound(ctx context.Context, raw io.Reader) error {
+	if !s.Enabled() {
+		return ErrReplyMailUnavailable
+	}
+
+	inbound, err := mailer.ParseInbound(raw)
+	if err != nil {
+		return ErrReplyUnreadable.Wrap(err)
+	}
+
+	token := s.replyToken(inbound.Recipients)
+	if token == "" {
+		return ErrReplyAddressUnknown
+	}
+	target, err := s.rdb.Get(ctx, replyMailTokenKeyPrefix+token).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return ErrReplyAddressUnknown
+		}
+		return err
+	}
+	userPart, convPart, _ := strings.Cut(target, ":")
+	userID, userErr := uuid.Parse(userPart)
+	convID, convErr := uuid.Parse(convPart)
+	if userErr != nil || convErr != nil {
+		return ErrReplyAddressUnknown
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrReplyAddressUnknown
+		}
+		return err
+	}
+	// The reply address alone isn't proof: it may have been forwarded
+	if inbound.From != strings.ToLower(user.Email) {
+		return ErrReplySenderMismatch
+	}
+	if !user.IsActive() {
+		return ErrAccountDeactivated
+	}
+	if inbound.Text == "" {
+		return ErrReplyEmpty
+	}
+
+	seenKey := ""
+	if inbound.MessageID != "" {
+		seenKey = replyMailSeenKeyPrefix + inbound.MessageID
+		first, err := s.rdb.SetNX(ctx, seenKey, "1", replyMailSeenTTL).Result()
+		if err != nil {
+			return err
+		}
+		if !first {
+			return nil
+		}
+	}
+
+	if _, err := s.chatService.SendMessage(userID, convID, model.SendMessageRequest{Content: inbound.Text}); err != nil {
+		if seenKey != "" {
+			s.rdb.Del(ctx, seenKey) // let the provider's retry through
+		}
+		return err
+	}
+	return nil
+}
+
+// replyToken finds our reply address among the recipients and returns its token
+func (s *ReplyMailService) replyToken(recipients []string) string {
+	for _, addr := range recipients {
+		local, domain, ok := strings.Cut(addr, "@")
+		if !ok || domain != s.domain {
+			continue
+		}
+		if token, ok := strings.CutPrefix(local, replyAddressPrefix); ok && token != "" {
+			return token
+		}
+	}
+	return ""
+}
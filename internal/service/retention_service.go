@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/pkg/storage"
+	"gorm.io/gorm"
+)
+
+const (
+	retentionPurgeBatch     = 500
+	retentionBatchesPerPass = 20 // a pass stops after this many batches; the next one goes on
+)
+
+// RetentionService enforces message retention policies: admins set how long
+// messages are kept across the deployment and per conversation, and a
+// background job permanently deletes older messages with their attachments,
+// recording each purge in the audit log
+type RetentionService struct {
+	repo     *repository.RetentionRepository
+	convRepo *repository.ConversationRepository
+	blobRepo *repository.BlobRepository
+	storage  *storage.MinIOStorage
+	audit    *AuditService
+}
+
+func NewRetentionService(
+	repo *repository.RetentionRepository,
+	convRepo *repository.ConversationRepository,
+	blobRepo *repository.BlobRepository,
+	storage *storage.MinIOStorage,
+	audit *AuditService,
+) *RetentionService {
+	return &RetentionService{repo: repo, convRepo: convRepo, blobRepo: blobRepo, storage: storage, audit: audit}
+}
+
+// Policies returns the deployment's policy and the conversations' overrides
+func (s *RetentionService) Policies() (*model.RetentionPoliciesResponse, error) {
+	policies, err := s.repo.ListPolicies()
+	if err != nil {
+		return nil, err
+	}
+	resp := &model.RetentionPoliciesResponse{Conversations: []model.RetentionPolicy{}}
+	for i := range policies {
+		if policies[i].ConversationID == nil {
+			resp.Deployment = &policies[i]
+			continue
+		}
+		resp.Conversations = append(resp.Conversations, policies[i])
+	}
+	return resp, nil
+}
+
+// SetPolicy sets the deployment's policy (conversationID nil) or a
+// conversation's, which overrides it; 0 days keeps messages forever
+func (s *RetentionService) SetPolicy(adminID uuid.UUID, conversationID *uuid.UUID, maxAgeDays int) (*model.RetentionPolicy, error) {
+	if conversationID != nil {
+		if _, err := s.convRepo.FindByID(*conversationID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrConversationNotFound
+			}
+			return nil, err
+		}
+	}
+
+	policy := &model.RetentionPolicy{
+		ConversationID: conversationID,
+		MaxAgeDays:     maxAgeDays,
+		UpdatedBy:      adminID,
+	}
+	if err := s.repo.SavePolicy(policy); err != nil {
+		return nil, err
+	}
+	s.audit.Record(model.AuditEvent{
+		Action:         model.AuditRetentionPolicySet,
+		ActorID:        &adminID,
+		ConversationID: conversationID,
+		Details:        map[string]interface{}{"max_age_days": maxAgeDays},
+	})
+	return policy, nil
+}
+
+// RemovePolicy removes the deployment's policy, so messages are kept, or a
+// conversation's, so the deployment's applies again
+func (s *RetentionService) RemovePolicy(adminID uuid.UUID, conversationID *uuid.UUID) error {
+	err := s.repo.DeletePolicy(conversationID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrRetentionPolicyNotFound
+	}
+	if err != nil {
+		return err
+	}
+	s.audit.Record(model.AuditEvent{
+		Action:         model.AuditRetentionPolicyRemoved,
+		ActorID:        &adminID,
+		ConversationID: conversationID,
+	})
+	return nil
+}
+
+// Run purges expired messages every interval, blocking until ctx is cancelled
+func (s *RetentionService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.Purge(ctx)
+			if err != nil {
+				log.Printf("⚠️  Retention purge failed: %v", err)
+			} else if n > 0 {
+				log.Printf("🧹 Purged %d messages past their retention", n)
+			}
+		}
+	}
+}
+
+// purgeTally is what a pass purged from one conversation
+type purgeTally struct {
+	messages    int
+	attachments int
+	maxAgeDays  int
+	oldest      time.Time
+	newest      time.Time
+}
+
+// Purge permanently deletes messages older than their retention allows, with
+// their attachments, records an audit event per conversation and returns how
+// many messages were deleted
+func (s *RetentionService) Purge(ctx context.Context) (int, error) {
+	policies, err := s.repo.ListPolicies()
+	if err != nil {
+		return 0, err
+	}
+	deploymentDays := 0
+	conversationDays := map[uuid.UUID]int{}
+	for _, policy := range policies {
+		if policy.ConversationID == nil {
+			deploymentDays = policy.MaxAgeDays
+		} else {
+			conversationDays[*policy.ConversationID] = policy.MaxAgeDays
+		}
+	}
+
+	tallies := map[uuid.UUID]*purgeTally{}
+	purged := 0
+	defer func() { s.recordPurges(tallies) }()
+
+	for batch := 0; batch < retentionBatchesPerPass && ctx.Err() == nil; batch++ {
+		messages, err := s.repo.FindExpiredMessages(deploymentDays, time.Now(), retentionPurgeBatch)
+		if err != nil {
+			return purged, err
+		}
+		if len(messages) == 0 {
+			break
+		}
+
+		ids := make([]uuid.UUID, len(messages))
+		for i, msg := range messages {
+			ids[i] = msg.ID
+		}
+		if err := s.repo.PurgeMessages(ids); err != nil {
+			return purged, err
+		}
+		purged += len(messages)
+
+		for i := range messages {
+			msg := &messages[i]
+			tally := tallies[msg.ConversationID]
+			if tally == nil {
+				days, ok := conversationDays[msg.ConversationID]
+				if !ok {
+					days = deploymentDays
+				}
+				tally = &purgeTally{maxAgeDays: days, oldest: msg.CreatedAt}
+				tallies[msg.ConversationID] = tally
+			}
+			tally.messages++
+			tally.attachments += len(msg.Attachments)
+			tally.newest = msg.CreatedAt
+			s.removeFiles(ctx, msg)
+		}
+
+		if len(messages) < retentionPurgeBatch {
+			break
+		}
+	}
+	return purged, nil
+}
+
+// removeFiles deletes the stored files of a purged message that no other
+// message shares. Deduplicated uploads are left to the blob purge, which
+// removes them once nothing references them.
+func (s *RetentionService) removeFiles(ctx context.Context, msg *model.Message) {
+	if s.storage == nil {
+		return
+	}
+	urls := []string{}
+	for _, att := range msg.Attachments {
+		if att.BlobHash == nil {
+			urls = append(urls, att.URL)
+		}
+		urls = append(urls, att.ProcessedURL, att.PosterURL)
+	}
+	if msg.FileURL != "" {
+		if _, err := s.blobRepo.FindByURL(msg.FileURL); errors.Is(err, gorm.ErrRecordNotFound) {
+			urls = append(urls, msg.FileURL)
+		}
+	}
+
+	for _, url := range urls {
+		key, ok := s.storage.KeyFromURL(url)
+		if !ok {
+			continue // empty, or an external link
+		}
+		if err := s.storage.Delete(ctx, key); err != nil {
+			log.Printf("⚠️  Failed to delete %s of purged message %s: %v", key, msg.ID, err)
+		}
+	}
+}
+
+// recordPurges adds an audit event for each conversation a pass purged from
+func (s *RetentionService) recordPurges(tallies map[uuid.UUID]*purgeTally) {
+	for convID, tally := range tallies {
+		s.audit.Record(model.AuditEvent{
+			Action:         model.AuditRetentionPurge,
+			ConversationID: &convID,
+			Details: map[string]interface{}{
+				"messages":     tally.messages,
+				"attachments":  tally.attachments,
+				"max_age_days": tally.maxAgeDays,
+				"oldest_at":    tally.oldest,
+				"newest_at":    tally.newest,
+			},
+		})
+	}
+}
@@ -0,0 +1,667 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/internal/ws"
+	"github.com/quocanhngo/gotalk/pkg/auth"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	scimDefaultCount = 100
+	scimMaxCount     = 200
+)
+
+var (
+	// attribute eq "value", e.g. userName eq "an@example.com" or emails[type eq "work"].value eq "..."
+	scimFilterPattern = regexp.MustCompile(`(?i)^\s*([a-z]+)(?:\[[^\]]*\])?(\.[a-z]+)?\s+eq\s+("(?:[^"\\]|\\.)*")\s*$`)
+	// members[value eq "<user id>"]
+	scimMemberPathPattern = regexp.MustCompile(`(?i)^members\[value eq "([^"]*)"\]$`)
+)
+
+// SCIMService provisions users and group conversations from an organization's
+// identity provider (SCIM 2.0). Deactivating a user blocks sign-in and revokes
+// their tokens; groups map to group conversations the provider manages.
+type SCIMService struct {
+	userRepo    *repository.UserRepository
+	convRepo    *repository.ConversationRepository
+	members     *MembershipCache
+	notifCenter *NotificationCenterService
+	rdb         *redis.Client
+	hub         *ws.Hub
+	tokenExpiry time.Duration // revocations are kept until tokens issued before them expire
+}
+
+func NewSCIMService(
+	userRepo *repository.UserRepository,
+	convRepo *repository.ConversationRepository,
+	members *MembershipCache,
+	notifCenter *NotificationCenterService,
+	rdb *redis.Client,
+	hub *ws.Hub,
+	tokenExpiry time.Duration,
+) *SCIMService {
+	return &SCIMService{
+		userRepo:    userRepo,
+		convRepo:    convRepo,
+		members:     members,
+		notifCenter: notifCenter,
+		rdb:         rdb,
+		hub:         hub,
+		tokenExpiry: tokenExpiry,
+	}
+}
+
+// scimWindow converts a 1-based startIndex and count to an offset and limit
+func scimWindow(startIndex, count int) (int, int) {
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 {
+		count = scimDefaultCount
+	}
+	if count > scimMaxCount {
+		count = scimMaxCount
+	}
+	return startIndex - 1, count
+}
+
+// ==================== Users ====================
+
+// ListUsers returns a page of users matching a SCIM filter and the total number of matches
+func (s *SCIMService) ListUsers(filter string, startIndex, count int) ([]model.SCIMUser, int64, error) {
+	f, matchesNone, err := parseSCIMFilter(filter, false)
+	if err != nil || matchesNone {
+		return []model.SCIMUser{}, 0, err
+	}
+	offset, limit := scimWindow(startIndex, count)
+	users, total, err := s.userRepo.ListDirectory(f, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	result := make([]model.SCIMUser, len(users))
+	for i := range users {
+		result[i] = users[i].ToSCIM()
+	}
+	return result, total, nil
+}
+
+// GetUser returns a user as a SCIM User
+func (s *SCIMService) GetUser(id uuid.UUID) (*model.SCIMUser, error) {
+	user, err := s.findUser(id)
+	if err != nil {
+		return nil, err
+	}
+	resource := user.ToSCIM()
+	return &resource, nil
+}
+
+// CreateUser provisions a new account. Its email is trusted as verified; the
+// user signs in with Google or sets a password through forgot-password.
+func (s *SCIMService) CreateUser(req model.SCIMUser) (*model.SCIMUser, error) {
+	email, err := scimEmail(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, total, err := s.userRepo.ListDirectory(repository.DirectoryFilter{Email: email}, 0, 1); err != nil {
+		return nil, err
+	} else if total > 0 {
+		return nil, ErrEmailTaken
+	}
+
+	now := time.Now()
+	name := scimFullName(req, email)
+	user := &model.User{
+		Name:            name,
+		DisplayName:     scimDisplayName(req.DisplayName, name),
+		Email:           email,
+		AuthProvider:    model.AuthProviderEmail,
+		EmailVerifiedAt: &now,
+		ExternalID:      optionalString(req.ExternalID),
+	}
+	if req.Active != nil && !*req.Active {
+		user.DeactivatedAt = &now
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+	return s.GetUser(user.ID)
+}
+
+// ReplaceUser overwrites the attributes the identity provider manages (PUT)
+func (s *SCIMService) ReplaceUser(id uuid.UUID, req model.SCIMUser) (*model.SCIMUser, error) {
+	user, err := s.findUser(id)
+	if err != nil {
+		return nil, err
+	}
+	email, err := scimEmail(req)
+	if err != nil {
+		return nil, err
+	}
+
+	name := scimFullName(req, email)
+	if err := s.userRepo.UpdateDirectoryProfile(id, name, scimDisplayName(req.DisplayName, name), email, optionalString(req.ExternalID)); err != nil {
+		return nil, err
+	}
+	if req.Active != nil {
+		if err := s.setActive(user, *req.Active); err != nil {
+			return nil, err
+		}
+	}
+	return s.GetUser(id)
+}
+
+// PatchUser applies SCIM PATCH operations to a user
+func (s *SCIMService) PatchUser(id uuid.UUID, req model.SCIMPatchRequest) (*model.SCIMUser, error) {
+	user, err := s.findUser(id)
+	if err != nil {
+		return nil, err
+	}
+	resource := user.ToSCIM()
+	for _, op := range req.Operations {
+		if err := patchSCIMUser(&resource, op); err != nil {
+			return nil, err
+		}
+	}
+	return s.ReplaceUser(id, resource)
+}
+
+// DeleteUser deactivates and then deletes a user
+func (s *SCIMService) DeleteUser(id uuid.UUID) error {
+	user, err := s.findUser(id)
+	if err != nil {
+		return err
+	}
+	if err := s.setActive(user, false); err != nil {
+		return err
+	}
+	return s.userRepo.Delete(id)
+}
+
+func (s *SCIMService) findUser(id uuid.UUID) (*model.User, error) {
+	user, err := s.userRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// setActive deactivates or reactivates a user. Deactivation signs them out
+// everywhere by revoking every token issued so far and closing their
+// WebSocket connections.
+func (s *SCIMService) setActive(user *model.User, active bool) error {
+	if active == user.IsActive() {
+		return nil
+	}
+	ctx := context.Background()
+	if active {
+		if err := s.userRepo.SetDeactivated(user.ID, nil); err != nil {
+			return err
+		}
+		return s.rdb.Del(ctx, auth.RevokedUserKey(user.ID)).Err()
+	}
+
+	now := time.Now()
+	if err := s.userRepo.SetDeactivated(user.ID, &now); err != nil {
+		return err
+	}
+	if err := s.rdb.Set(ctx, auth.RevokedUserKey(user.ID), "deactivated", s.tokenExpiry).Err(); err != nil {
+		return err
+	}
+	s.hub.ForceLogout(user.ID, "", ws.CloseBanned)
+	return s.userRepo.UpdateOnlineStatus(user.ID, false)
+}
+
+// patchSCIMUser applies one PATCH operation to a user resource. Attributes
+// GoTalk doesn't store (title, phoneNumbers, ...) are ignored.
+func patchSCIMUser(u *model.SCIMUser, op model.SCIMPatchOperation) error {
+	switch strings.ToLower(op.Op) {
+	case "add", "replace":
+		if op.Path != "" {
+			return setSCIMUserAttribute(u, op.Path, op.Value)
+		}
+		var attrs map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &attrs); err != nil {
+			return ErrSCIMInvalidPatch
+		}
+		for path, value := range attrs {
+			if err := setSCIMUserAttribute(u, path, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "remove":
+		switch strings.ToLower(op.Path) {
+		case "externalid":
+			u.ExternalID = ""
+		case "displayname":
+			u.DisplayName = ""
+		default:
+			return ErrSCIMInvalidPatch
+		}
+		return nil
+	}
+	return ErrSCIMInvalidPatch
+}
+
+func setSCIMUserAttribute(u *model.SCIMUser, path string, value json.RawMessage) error {
+	var err error
+	path = strings.ToLower(path)
+	switch path {
+	case "active":
+		var active bool
+		active, err = scimBool(value)
+		u.Active = &active
+	case "username":
+		err = json.Unmarshal(value, &u.UserName)
+	case "displayname":
+		err = json.Unmarshal(value, &u.DisplayName)
+	case "externalid":
+		err = json.Unmarshal(value, &u.ExternalID)
+	case "name":
+		err = json.Unmarshal(value, &u.Name)
+	case "name.formatted", "name.givenname", "name.familyname":
+		if u.Name == nil {
+			u.Name = &model.SCIMName{}
+		}
+		switch path {
+		case "name.formatted":
+			err = json.Unmarshal(value, &u.Name.Formatted)
+		case "name.givenname":
+			u.Name.Formatted = ""
+			err = json.Unmarshal(value, &u.Name.GivenName)
+		default:
+			u.Name.Formatted = ""
+			err = json.Unmarshal(value, &u.Name.FamilyName)
+		}
+	case "emails":
+		err = json.Unmarshal(value, &u.Emails)
+	default:
+		// emails[type eq "work"].value
+		if strings.HasPrefix(path, "emails[") && strings.HasSuffix(path, "].value") {
+			var email string
+			err = json.Unmarshal(value, &email)
+			u.Emails = []model.SCIMEmail{{Value: email, Type: "work", Primary: true}}
+		}
+	}
+	if err != nil {
+		return ErrSCIMInvalidPatch
+	}
+	return nil
+}
+
+// ==================== Groups ====================
+
+// ListGroups returns a page of provisioned groups matching a SCIM filter and the total number of matches
+func (s *SCIMService) ListGroups(filter string, startIndex, count int, withMembers bool) ([]model.SCIMGroup, int64, error) {
+	f, matchesNone, err := parseSCIMFilter(filter, true)
+	if err != nil || matchesNone {
+		return []model.SCIMGroup{}, 0, err
+	}
+	offset, limit := scimWindow(startIndex, count)
+	conversations, total, err := s.convRepo.ListProvisioned(f, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	result := make([]model.SCIMGroup, len(conversations))
+	for i := range conversations {
+		result[i] = conversations[i].ToSCIMGroup(withMembers)
+	}
+	return result, total, nil
+}
+
+// GetGroup returns a provisioned group conversation as a SCIM Group
+func (s *SCIMService) GetGroup(id uuid.UUID, withMembers bool) (*model.SCIMGroup, error) {
+	conv, err := s.findGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	resource := conv.ToSCIMGroup(withMembers)
+	return &resource, nil
+}
+
+// CreateGroup creates a group conversation managed by the identity provider
+func (s *SCIMService) CreateGroup(req model.SCIMGroup) (*model.SCIMGroup, error) {
+	memberIDs, err := s.memberIDs(req.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	conv := &model.Conversation{
+		Name:        req.DisplayName,
+		Type:        model.ConversationTypeGroup,
+		ExternalID:  optionalString(req.ExternalID),
+		Provisioned: true,
+	}
+	for _, id := range memberIDs {
+		conv.Members = append(conv.Members, model.ConversationMember{UserID: id, Role: model.MemberRoleMember})
+	}
+	if err := s.convRepo.Create(conv); err != nil {
+		return nil, err
+	}
+
+	go s.notifyAdded(conv.ID, conv.Name, memberIDs)
+	return s.GetGroup(conv.ID, true)
+}
+
+// ReplaceGroup overwrites a group's name, external ID and members (PUT)
+func (s *SCIMService) ReplaceGroup(id uuid.UUID, req model.SCIMGroup) (*model.SCIMGroup, error) {
+	conv, err := s.findGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	memberIDs, err := s.memberIDs(req.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.convRepo.UpdateDirectoryAttributes(id, req.DisplayName, optionalString(req.ExternalID)); err != nil {
+		return nil, err
+	}
+
+	want := make(map[uuid.UUID]bool, len(memberIDs))
+	for _, memberID := range memberIDs {
+		want[memberID] = true
+	}
+	current := make(map[uuid.UUID]bool, len(conv.Members))
+	for _, m := range conv.Members {
+		current[m.UserID] = true
+		if !want[m.UserID] {
+			if err := s.convRepo.RemoveMember(id, m.UserID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var added []uuid.UUID
+	for _, memberID := range memberIDs {
+		if current[memberID] {
+			continue
+		}
+		if err := s.convRepo.RestoreOrAddMember(id, memberID, model.MemberRoleMember); err != nil {
+			return nil, err
+		}
+		added = append(added, memberID)
+	}
+	s.members.Invalidate(context.Background(), id)
+
+	go s.notifyAdded(id, req.DisplayName, added)
+	return s.GetGroup(id, true)
+}
+
+// PatchGroup applies SCIM PATCH operations to a group
+func (s *SCIMService) PatchGroup(id uuid.UUID, req model.SCIMPatchRequest) (*model.SCIMGroup, error) {
+	conv, err := s.findGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	resource := conv.ToSCIMGroup(true)
+	for _, op := range req.Operations {
+		if err := patchSCIMGroup(&resource, op); err != nil {
+			return nil, err
+		}
+	}
+	return s.ReplaceGroup(id, resource)
+}
+
+// DeleteGroup deletes a provisioned group conversation
+func (s *SCIMService) DeleteGroup(id uuid.UUID) error {
+	if _, err := s.findGroup(id); err != nil {
+		return err
+	}
+	if err := s.convRepo.Delete(id); err != nil {
+		return err
+	}
+	s.members.Invalidate(context.Background(), id)
+	return nil
+}
+
+func (s *SCIMService) findGroup(id uuid.UUID) (*model.Conversation, error) {
+	conv, err := s.convRepo.FindProvisioned(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrGroupNotFound
+		}
+		return nil, err
+	}
+	return conv, nil
+}
+
+// memberIDs parses and deduplicates group members, which must be existing users
+func (s *SCIMService) memberIDs(members []model.SCIMMember) ([]uuid.UUID, error) {
+	seen := make(map[uuid.UUID]bool, len(members))
+	ids := make([]uuid.UUID, 0, len(members))
+	for _, m := range members {
+		id, err := uuid.Parse(m.Value)
+		if err != nil {
+			return nil, ErrSCIMUnknownUser
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	users, err := s.userRepo.FindByIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) != len(ids) {
+		return nil, ErrSCIMUnknownUser
+	}
+	return ids, nil
+}
+
+// notifyAdded tells users they were added to a provisioned group
+func (s *SCIMService) notifyAdded(convID uuid.UUID, name string, userIDs []uuid.UUID) {
+	if len(userIDs) == 0 {
+		return
+	}
+	_ = s.notifCenter.Notify(userIDs, model.NotificationTypeGroupInvite,
+		"New group", "You were added to "+name,
+		map[string]string{"conversation_id": convID.String()})
+}
+
+// patchSCIMGroup applies one PATCH operation to a group resource
+func patchSCIMGroup(g *model.SCIMGroup, op model.SCIMPatchOperation) error {
+	path := strings.ToLower(op.Path)
+	switch strings.ToLower(op.Op) {
+	case "add", "replace":
+		if path == "" {
+			var attrs map[string]json.RawMessage
+			if err := json.Unmarshal(op.Value, &attrs); err != nil {
+				return ErrSCIMInvalidPatch
+			}
+			for key, value := range attrs {
+				if err := setSCIMGroupAttribute(g, strings.ToLower(key), value, op.Op); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return setSCIMGroupAttribute(g, path, op.Value, op.Op)
+	case "remove":
+		if m := scimMemberPathPattern.FindStringSubmatch(op.Path); m != nil {
+			g.Members = removeSCIMMembers(g.Members, map[string]bool{m[1]: true})
+			return nil
+		}
+		if path != "members" {
+			return ErrSCIMInvalidPatch
+		}
+		if len(op.Value) == 0 {
+			g.Members = nil
+			return nil
+		}
+		var members []model.SCIMMember
+		if err := json.Unmarshal(op.Value, &members); err != nil {
+			return ErrSCIMInvalidPatch
+		}
+		remove := make(map[string]bool, len(members))
+		for _, m := range members {
+			remove[m.Value] = true
+		}
+		g.Members = removeSCIMMembers(g.Members, remove)
+		return nil
+	}
+	return ErrSCIMInvalidPatch
+}
+
+func setSCIMGroupAttribute(g *model.SCIMGroup, path string, value json.RawMessage, op string) error {
+	var err error
+	switch path {
+	case "displayname":
+		err = json.Unmarshal(value, &g.DisplayName)
+	case "externalid":
+		err = json.Unmarshal(value, &g.ExternalID)
+	case "members":
+		var members []model.SCIMMember
+		if err = json.Unmarshal(value, &members); err == nil {
+			if strings.EqualFold(op, "add") {
+				g.Members = append(g.Members, members...)
+			} else {
+				g.Members = members
+			}
+		}
+	case "id":
+		// Sent back unchanged by some providers
+	default:
+		return ErrSCIMInvalidPatch
+	}
+	if err != nil {
+		return ErrSCIMInvalidPatch
+	}
+	return nil
+}
+
+func removeSCIMMembers(members []model.SCIMMember, remove map[string]bool) []model.SCIMMember {
+	kept := members[:0]
+	for _, m := range members {
+		if !remove[m.Value] {
+			kept = append(kept, m)
+		}
+	}
+	return kept
+}
+
+// ==================== Helpers ====================
+
+// parseSCIMFilter turns a SCIM filter into a repository filter. Only a single
+// `attribute eq "value"` comparison is supported, which is what identity
+// providers use to look up existing users and groups. matchesNone is true when
+// the filter can't match anything (e.g. an id that isn't a UUID).
+func parseSCIMFilter(filter string, group bool) (f repository.DirectoryFilter, matchesNone bool, err error) {
+	if strings.TrimSpace(filter) == "" {
+		return f, false, nil
+	}
+	m := scimFilterPattern.FindStringSubmatch(filter)
+	if m == nil {
+		return f, false, ErrInvalidFilter
+	}
+	value, err := strconv.Unquote(m[3])
+	if err != nil {
+		return f, false, ErrInvalidFilter
+	}
+	if value == "" {
+		return f, true, nil
+	}
+
+	switch attr := strings.ToLower(m[1] + m[2]); {
+	case attr == "id":
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return f, true, nil
+		}
+		f.ID = &id
+	case attr == "externalid":
+		f.ExternalID = value
+	case !group && (attr == "username" || attr == "emails.value" || attr == "emails"):
+		f.Email = value
+	case group && attr == "displayname":
+		f.Name = value
+	default:
+		return f, false, ErrInvalidFilter
+	}
+	return f, false, nil
+}
+
+// scimEmail picks the user's email: the primary email, the first email, or userName
+func scimEmail(u model.SCIMUser) (string, error) {
+	email := u.UserName
+	for i, e := range u.Emails {
+		if e.Primary || i == 0 {
+			email = e.Value
+		}
+		if e.Primary {
+			break
+		}
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return "", ErrSCIMUserName
+	}
+	return email, nil
+}
+
+// scimFullName returns the account name: name.formatted, given and family
+// names, displayName or, failing those, the email's local part
+func scimFullName(u model.SCIMUser, email string) string {
+	var name string
+	if u.Name != nil {
+		name = strings.TrimSpace(u.Name.Formatted)
+		if name == "" {
+			name = strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+		}
+	}
+	if name == "" {
+		name = strings.TrimSpace(u.DisplayName)
+	}
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+	if len([]rune(name)) > 100 {
+		name = string([]rune(name)[:100])
+	}
+	return name
+}
+
+// scimDisplayName stores displayName only when it differs from the account name
+func scimDisplayName(displayName, name string) string {
+	if displayName = strings.TrimSpace(displayName); displayName == name {
+		return ""
+	}
+	return displayName
+}
+
+// scimBool reads a boolean that some providers send as "True"/"False"
+func scimBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(strings.ToLower(s))
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
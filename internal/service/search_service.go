@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+)
+
+const (
+	defaultSearchLimit = 20
+	indexScoreScale    = 10 // OpenSearch scores run from about 1 to 20; score/(score+scale) puts them in 0-1
+)
+
+// SearchService backs the universal search box: one query across the user's
+// messages, groups, people and files, mixed into a single ranked list.
+// Messages are searched with Postgres full-text search, or in OpenSearch when
+// a search index is configured.
+type SearchService struct {
+	msgRepo  *repository.MessageRepository
+	convRepo *repository.ConversationRepository
+	userRepo *repository.UserRepository
+	index    *MessageIndex // optional
+}
+
+func NewSearchService(msgRepo *repository.MessageRepository, convRepo *repository.ConversationRepository, userRepo *repository.UserRepository) *SearchService {
+	return &SearchService{msgRepo: msgRepo, convRepo: convRepo, userRepo: userRepo}
+}
+
+// UseIndex searches messages in an external index instead of Postgres
+func (s *SearchService) UseIndex(index *MessageIndex) {
+	s.index = index
+}
+
+// Search runs the query against each requested type and returns the best
+// results overall. Names score by how they match (whole, start, anywhere);
+// messages by their full-text rank, so people and groups named like the
+// query come first.
+func (s *SearchService) Search(ctx context.Context, userID uuid.UUID, req model.SearchRequest) (*model.SearchResponse, error) {
+	types, err := searchTypes(req.Types)
+	if err != nil {
+		return nil, err
+	}
+	var conversationID *uuid.UUID
+	if req.ConversationID != "" {
+		id, err := uuid.Parse(req.ConversationID)
+		if err != nil {
+			return nil, err
+		}
+		conversationID = &id
+		// Only messages and files belong to a conversation
+		types = slices.DeleteFunc(types, func(t model.SearchResultType) bool {
+			return t != model.SearchResultMessage && t != model.SearchResultFile
+		})
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	query := strings.TrimSpace(req.Q)
+	contacts, err := contactSet(s.userRepo, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []model.SearchResult{}
+	if slices.Contains(types, model.SearchResultUser) {
+		users, err := s.userRepo.SearchUsers(query, userID, limit)
+		if err != nil {
+			return nil, err
+		}
+		for i := range users {
+			resp := users[i].ToPublicResponse(userID, contacts[users[i].ID])
+			score := max(nameScore(users[i].Name, query), nameScore(users[i].DisplayName, query), nameScore(users[i].Handle, query))
+			results = append(results, model.SearchResult{Type: model.SearchResultUser, Score: score, User: &resp})
+		}
+	}
+	if slices.Contains(types, model.SearchResultConversation) {
+		groups, err := s.convRepo.SearchGroups(userID, query, limit)
+		if err != nil {
+			return nil, err
+		}
+		for i := range groups {
+			results = append(results, model.SearchResult{Type: model.SearchResultConversation, Score: nameScore(groups[i].Name, query), Conversation: &groups[i]})
+		}
+	}
+	if slices.Contains(types, model.SearchResultFile) {
+		files, err := s.msgRepo.SearchFiles(userID, query, conversationID, limit)
+		if err != nil {
+			return nil, err
+		}
+		for i := range files {
+			results = append(results, model.SearchResult{Type: model.SearchResultFile, Score: nameScore(files[i].FileName, query), File: &files[i]})
+		}
+	}
+	if slices.Contains(types, model.SearchResultMessage) {
+		hits, err := s.searchMessages(ctx, userID, query, conversationID, limit)
+		if err != nil {
+			return nil, err
+		}
+		for i := range hits {
+			sender := &hits[i].Message.Sender
+			sender.ApplyPrivacy(userID, contacts[sender.ID])
+			results = append(results, model.SearchResult{Type: model.SearchResultMessage, Score: hits[i].Rank, Message: &hits[i].Message})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	counts := make(map[model.SearchResultType]int, len(types))
+	for _, t := range types {
+		counts[t] = 0
+	}
+	for _, r := range results {
+		counts[r.Type]++
+	}
+	return &model.SearchResponse{Query: query, Results: results, Counts: counts}, nil
+}
+
+// searchMessages finds the messages the user sees that match the query,
+// falling back to Postgres when the search index fails
+func (s *SearchService) searchMessages(ctx context.Context, userID uuid.UUID, query string, conversationID *uuid.UUID, limit int) ([]repository.MessageSearchHit, error) {
+	if s.index == nil {
+		return s.msgRepo.Search(userID, query, conversationID, limit)
+	}
+
+	conversationIDs := []uuid.UUID{}
+	if conversationID != nil {
+		conversationIDs = append(conversationIDs, *conversationID)
+	} else {
+		ids, err := s.convRepo.MemberConversationIDs(userID)
+		if err != nil {
+			return nil, err
+		}
+		conversationIDs = ids
+	}
+	if len(conversationIDs) == 0 {
+		return nil, nil
+	}
+	indexHits, err := s.index.Search(ctx, query, conversationIDs, limit)
+	if err != nil {
+		log.Printf("⚠️  Search index failed, searching Postgres: %v", err)
+		return s.msgRepo.Search(userID, query, conversationID, limit)
+	}
+
+	ids := make([]uuid.UUID, 0, len(indexHits))
+	for _, hit := range indexHits {
+		if id, err := uuid.Parse(hit.ID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	// Recheck what the user sees: membership and cleared history aren't
+	// indexed, and a message may be gone since it was
+	messages, err := s.msgRepo.FindVisible(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]repository.MessageSearchHit, 0, len(indexHits))
+	for _, hit := range indexHits {
+		id, _ := uuid.Parse(hit.ID)
+		if msg, ok := messages[id]; ok {
+			hits = append(hits, repository.MessageSearchHit{Message: msg, Rank: hit.Score / (hit.Score + indexScoreScale)})
+		}
+	}
+	return hits, nil
+}
+
+// searchTypes parses a comma-separated list of result types; empty means all
+func searchTypes(list string) ([]model.SearchResultType, error) {
+	if strings.TrimSpace(list) == "" {
+		return slices.Clone(model.SearchResultTypes), nil
+	}
+	var types []model.SearchResultType
+	for _, name := range strings.Split(list, ",") {
+		t := model.SearchResultType(strings.TrimSpace(name))
+		if !slices.Contains(model.SearchResultTypes, t) {
+			return nil, ErrInvalidSearchType
+		}
+		if !slices.Contains(types, t) {
+			types = append(types, t)
+		}
+	}
+	return types, nil
+}
+
+// nameScore rates how well a name matches the query: all of it, its start,
+// the start of a word, or anywhere
+func nameScore(name, query string) float64 {
+	name, query = strings.ToLower(name), strings.ToLower(query)
+	switch {
+	case name == "" || query == "":
+		return 0
+	case name == query:
+		return 1
+	case strings.HasPrefix(name, query):
+		return 0.8
+	case strings.Contains(name, " "+query):
+		return 0.7
+	case strings.Contains(name, query):
+		return 0.5
+	}
+	return 0
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"crypto/rand"
+	"errors"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/pkg/disposable"
+	"gorm.io/gorm"
+)
+
+// invitationAlphabet leaves out characters that are easy to misread: 0/O, 1/I/L
+const (
+	invitationAlphabet   = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+	invitationCodeLength = 12
+)
+
+// SignupPolicy decides who may create an account with email/password or Google.
+// Accounts created through SSO, SCIM or LDAP are managed by the identity provider
+// and don't go through it.
+type SignupPolicy struct {
+	AllowedDomains  []string // only these email domains may sign up; empty allows any
+	BlockedDomains  []string // these email domains may not
+	BlockDisposable bool     // reject throwaway email domains
+	InviteOnly      bool     // an admin-issued invitation code is required
+}
+
+// SignupService enforces the signup policy and manages invitation codes
+type SignupService struct {
+	invitationRepo *repository.InvitationRepository
+	policy         SignupPolicy
+	allowed        map[string]bool
+	blocked        map[string]bool
+}
+
+func NewSignupService(invitationRepo *repository.InvitationRepository, policy SignupPolicy) *SignupService {
+	return &SignupService{
+		invitationRepo: invitationRepo,
+		policy:         policy,
+		allowed:        domainSet(policy.AllowedDomains),
+		blocked:        domainSet(policy.BlockedDomains),
+	}
+}
+
+func domainSet(domains []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, d := range domains {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			set[d] = true
+		}
+	}
+	return set
+}
+
+// Admit checks that a new account may be created for the email and, when
+// registration is invitation-only, uses up one use of the invitation code.
+// Call Release with the same code if the account can't be created after all.
+func (s *SignupService) Admit(email, inviteCode string) error {
+	_, domain, _ := strings.Cut(strings.ToLower(email), "@")
+	if len(s.allowed) > 0 && !s.allowed[domain] {
+		return ErrSignupNotAllowed
+	}
+	if s.blocked[domain] {
+		return ErrSignupNotAllowed
+	}
+	if s.policy.BlockDisposable && disposable.Domain(domain) {
+		return ErrDisposableEmail
+	}
+
+	if !s.policy.InviteOnly {
+		return nil
+	}
+	code := normalizeInvitationCode(inviteCode)
+	if code == "" {
+		return ErrInvitationRequired
+	}
+	redeemed, err := s.invitationRepo.Redeem(code, email, time.Now())
+	if err != nil {
+		return err
+	}
+	if !redeemed {
+		return ErrInvitationInvalid
+	}
+	return nil
+}
+
+// Release gives back the invitation use taken by Admit
+func (s *SignupService) Release(inviteCode string) {
+	if !s.policy.InviteOnly {
+		return
+	}
+	if err := s.invitationRepo.Release(normalizeInvitationCode(inviteCode)); err != nil {
+		log.Printf("⚠️  Failed to release invitation use: %v", err)
+	}
+}
+
+// CreateInvitation issues an invitation code
+func (s *SignupService) CreateInvitation(adminID uuid.UUID, req model.CreateInvitationRequest) (*model.Invitation, error) {
+	code, err := generateInvitationCode()
+	if err != nil {
+		return nil, err
+	}
+	invitation := &model.Invitation{
+		Code:      code,
+		Email:     strings.ToLower(req.Email),
+		MaxUses:   1,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: adminID,
+	}
+	if req.MaxUses != nil {
+		invitation.MaxUses = *req.MaxUses
+	}
+	if err := s.invitationRepo.Create(invitation); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// ListInvitations returns every invitation, newest first
+func (s *SignupService) ListInvitations() ([]model.Invitation, error) {
+	return s.invitationRepo.List()
+}
+
+// DeleteInvitation revokes an invitation; signups it already admitted stay
+func (s *SignupService) DeleteInvitation(id uuid.UUID) error {
+	err := s.invitationRepo.Delete(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvitationNotFound
+	}
+	return err
+}
+
+// normalizeInvitationCode lets people type codes in lowercase or with spaces and dashes
+func normalizeInvitationCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
+
+func generateInvitationCode() (string, error) {
+	code := make([]byte, invitationCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(invitationAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = invitationAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
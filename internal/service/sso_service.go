@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/quocanhngo/gotalk/pkg/auth"
+	"github.com/quocanhngo/gotalk/pkg/oidc"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	ssoStateKeyPrefix = "gotalk:sso:state:" // pending sign-ins, by OAuth state
+	ssoCodeKeyPrefix  = "gotalk:sso:code:"  // one-time codes handed to the frontend, by code
+
+	ssoStateTTL = 10 * time.Minute // time to finish signing in at the provider
+	ssoCodeTTL  = time.Minute      // time for the frontend to trade the code for a token
+)
+
+// SSOService signs users in through the organization's OpenID Connect provider.
+// Accounts are matched by the provider's subject, then linked by email or
+// created on first sign-in.
+type SSOService struct {
+	userRepo       *repository.UserRepository
+	authService    *AuthService
+	jwtManager     *auth.JWTManager
+	rdb            *redis.Client
+	provider       *oidc.Provider // nil when SSO is not configured
+	allowedDomains map[string]bool
+}
+
+// ssoLogin is what a pending sign-in needs to finish the code exchange
+type ssoLogin struct {
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// ssoSession is what a one-time code stands for
+type ssoSession struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func NewSSOService(
+	userRepo *repository.UserRepository,
+	authService *AuthService,
+	jwtManager *auth.JWTManager,
+	rdb *redis.Client,
+	provider *oidc.Provider,
+	allowedDomains []string,
+) *SSOService {
+	domains := make(map[string]bool)
+	for _, d := range allowedDomains {
+		if d = strings.ToLower(strings.TrimSpace(d)); d != "" {
+			domains[d] = true
+		}
+	}
+	return &SSOService{
+		userRepo:       userRepo,
+		authService:    authService,
+		jwtManager:     jwtManager,
+		rdb:            rdb,
+		provider:       provider,
+		allowedDomains: domains,
+	}
+}
+
+// LoginURL starts a sign-in and returns the provider's login page to redirect to
+func (s *SSOService) LoginURL(ctx context.Context) (string, error) {
+	if s.provider == nil {
+		return "", ErrSSONotConfigured
+	}
+
+	state, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+	login := ssoLogin{}
+	if login.Nonce, err = oidc.RandomString(); err != nil {
+		return "", err
+	}
+	if login.Verifier, err = oidc.RandomString(); err != nil {
+		return "", err
+	}
+
+	url, err := s.provider.AuthCodeURL(ctx, state, login.Nonce, login.Verifier)
+	if err != nil {
+		log.Printf("❌ SSO provider unavailable: %v", err)
+		return "", ErrSSOFailed
+	}
+	data, _ := json.Marshal(login)
+	if err := s.rdb.Set(ctx, ssoStateKeyPrefix+state, data, ssoStateTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store sso state: %w", err)
+	}
+	return url, nil
+}
+
+// Callback finishes a sign-in with the code and state the provider redirected
+// back with. It returns a one-time code for the frontend to trade for a token,
+// so the token itself never appears in a URL.
+func (s *SSOService) Callback(ctx context.Context, code, state string, client model.ClientInfo) (string, error) {
+	if s.provider == nil {
+		return "", ErrSSONotConfigured
+	}
+	if code == "" || state == "" {
+		return "", ErrSSOFailed
+	}
+
+	// The state is single-use, which also stops a callback URL from being replayed
+	data, err := s.rdb.GetDel(ctx, ssoStateKeyPrefix+state).Bytes()
+	if err != nil {
+		return "", ErrSSOFailed
+	}
+	var login ssoLogin
+	if err := json.Unmarshal(data, &login); err != nil {
+		return "", ErrSSOFailed
+	}
+
+	claims, err := s.provider.Exchange(ctx, code, login.Verifier, login.Nonce)
+	if err != nil {
+		log.Printf("⚠️  SSO sign-in rejected: %v", err)
+		return "", ErrSSOFailed
+	}
+
+	user, err := s.findOrCreateUser(claims)
+	if err != nil {
+		return "", err
+	}
+	if !user.IsActive() {
+		return "", ErrAccountDeactivated
+	}
+
+	go s.authService.recordLogin(user, client)
+
+	oneTimeCode, err := oidc.RandomString()
+	if err != nil {
+		return "", err
+	}
+	data, _ = json.Marshal(ssoSession{UserID: user.ID})
+	if err := s.rdb.Set(ctx, ssoCodeKeyPrefix+oneTimeCode, data, ssoCodeTTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store sso code: %w", err)
+	}
+	return oneTimeCode, nil
+}
+
+// ExchangeCode trades a one-time code from Callback for a session token
+func (s *SSOService) ExchangeCode(ctx context.Context, code string) (*model.LoginResponse, error) {
+	if s.provider == nil {
+		return nil, ErrSSONotConfigured
+	}
+
+	data, err := s.rdb.GetDel(ctx, ssoCodeKeyPrefix+code).Bytes()
+	if err != nil {
+		return nil, ErrSSOFailed
+	}
+	var session ssoSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, ErrSSOFailed
+	}
+
+	user, err := s.userRepo.FindByID(session.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.IsActive() {
+		return nil, ErrAccountDeactivated
+	}
+
+	token, err := s.jwtManager.GenerateToken(user.ID, user.Email, user.Name)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+	if err := s.authService.StartSession(user, token); err != nil {
+		return nil, err
+	}
+	_ = s.userRepo.UpdateOnlineStatus(user.ID, true)
+
+	return &model.LoginResponse{
+		Token: token,
+		User:  user.ToResponse(),
+	}, nil
+}
+
+// findOrCreateUser returns the account for the provider's claims: the one
+// already linked to the subject, else an existing account with the same email
+// (linked now), else a new account
+func (s *SSOService) findOrCreateUser(claims *oidc.Claims) (*model.User, error) {
+	user, err := s.userRepo.FindBySSOSubject(claims.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return nil, ErrSSOFailed.WithMessage("the identity provider did not share an email address")
+	}
+	domain := email[at+1:]
+	if len(s.allowedDomains) > 0 && !s.allowedDomains[domain] {
+		return nil, ErrSSODomainNotAllowed
+	}
+
+	user, err = s.userRepo.FindByEmail(email)
+	if err == nil {
+		// Only take over an existing account when the provider vouches for the
+		// address: it says so, or it is the organization's own domain
+		if !bool(claims.EmailVerified) && !s.allowedDomains[domain] {
+			return nil, ErrEmailTaken.WithMessage("an account with this email already exists. Sign in with your password instead")
+		}
+		if err := s.userRepo.LinkSSOSubject(user.ID, claims.Subject); err != nil {
+			return nil, fmt.Errorf("failed to link sso account: %w", err)
+		}
+		log.Printf("🔗 Linked %s to single sign-on", email)
+		return s.userRepo.FindByID(user.ID)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name = email[:at]
+	}
+	subject := claims.Subject
+	now := time.Now()
+	user = &model.User{
+		Email:                 email,
+		Name:                  name,
+		Avatar:                claims.Picture,
+		SSOSubject:            &subject,
+		AuthProvider:          model.AuthProviderSSO,
+		EmailVerifiedAt:       &now,
+		Theme:                 "system",
+		IsNotificationEnabled: true,
+		Language:              "vi",
+	}
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	log.Printf("👤 Created %s on first single sign-on", email)
+	return user, nil
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"gorm.io/gorm"
+)
+
+var templateNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{1,49}$`)
+
+// TemplateService manages conversation templates and creates groups from them
+type TemplateService struct {
+	templateRepo *repository.TemplateRepository
+	chatService  *ChatService
+}
+
+func NewTemplateService(templateRepo *repository.TemplateRepository, chatService *ChatService) *TemplateService {
+	return &TemplateService{templateRepo: templateRepo, chatService: chatService}
+}
+
+// List returns every template by name
+func (s *TemplateService) List() ([]model.ConversationTemplate, error) {
+	return s.templateRepo.List()
+}
+
+// Create adds a template
+func (s *TemplateService) Create(req model.ConversationTemplateRequest) (*model.ConversationTemplate, error) {
+	template := &model.ConversationTemplate{}
+	if err := applyTemplateRequest(template, req); err != nil {
+		return nil, err
+	}
+	if err := s.templateRepo.Create(template); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrTemplateNameTaken
+		}
+		return nil, err
+	}
+	return template, nil
+}
+
+// Update replaces a template; groups already created from it are unaffected
+func (s *TemplateService) Update(id uuid.UUID, req model.ConversationTemplateRequest) (*model.ConversationTemplate, error) {
+	template, err := s.templateRepo.FindByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+	if err := applyTemplateRequest(template, req); err != nil {
+		return nil, err
+	}
+	if err := s.templateRepo.Update(template); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrTemplateNameTaken
+		}
+		return nil, err
+	}
+	return template, nil
+}
+
+// Delete removes a template
+func (s *TemplateService) Delete(id uuid.UUID) error {
+	err := s.templateRepo.Delete(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrTemplateNotFound
+	}
+	return err
+}
+
+// CreateConversation creates a group from a template, like ChatService's
+// CreateConversation, then posts the template's messages to it as the creator
+// and pins those the template says to
+func (s *TemplateService) CreateConversation(creatorID uuid.UUID, req model.CreateFromTemplateRequest) (*model.Conversation, error) {
+	template, err := s.templateRepo.FindByName(req.Template)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = template.Title
+	}
+	conv, err := s.chatService.CreateConversation(creatorID, model.CreateConversationRequest{
+		Type:      model.ConversationTypeGroup,
+		Name:      name,
+		MemberIDs: req.MemberIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The group exists either way, so a message that fails is only logged
+	for _, message := range template.Messages {
+		posted, err := s.chatService.PostSystemMessage(conv.ID, creatorID, message.Content, message.Entities)
+		if err != nil {
+			log.Printf("⚠️  Failed to post template %s message to conversation %s: %v", template.Name, conv.ID, err)
+			continue
+		}
+		if message.Pin {
+			if _, err := s.chatService.PinMessage(conv.ID, creatorID, model.PinMessageRequest{MessageID: posted.ID}); err != nil {
+				log.Printf("⚠️  Failed to pin template %s message in conversation %s: %v", template.Name, conv.ID, err)
+			}
+		}
+	}
+
+	return s.chatService.GetConversation(conv.ID, creatorID)
+}
+
+// applyTemplateRequest validates a template request and copies it onto the template
+func applyTemplateRequest(template *model.ConversationTemplate, req model.ConversationTemplateRequest) error {
+	if !templateNamePattern.MatchString(req.Name) {
+		return ErrTemplateName
+	}
+	messages := make([]model.TemplateMessage, 0, len(req.Messages))
+	for _, message := range req.Messages {
+		content := model.CleanContent(message.Content)
+		if strings.TrimSpace(content) == "" {
+			return ErrMessageEmpty
+		}
+		if err := message.Entities.Normalize(content); err != nil {
+			return ErrInvalidEntities.WithMessage(err.Error())
+		}
+		messages = append(messages, model.TemplateMessage{Content: content, Entities: message.Entities, Pin: message.Pin})
+	}
+
+	template.Name = req.Name
+	template.Title = strings.TrimSpace(req.Title)
+	template.Description = strings.TrimSpace(req.Description)
+	template.Messages = messages
+	return nil
+}
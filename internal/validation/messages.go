@@ -0,0 +1,106 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const defaultLanguage = "en"
+
+// messages holds the field error templates per language; %s is the rule's parameter
+var messages = map[string]map[string]string{
+	"en": {
+		"summary":              "one or more fields are invalid",
+		"invalid":              "is invalid",
+		"type":                 "must be a %s",
+		"required":             "is required",
+		"required_without_all": "is required unless %s is provided",
+		"email":                "must be a valid email address",
+		"url":                  "must be a valid URL",
+		"uuid":                 "must be a valid UUID",
+		"oneof":                "must be one of: %s",
+		"datetime":             "must match the format %s",
+		"timezone":             "must be a valid IANA time zone (e.g. Asia/Ho_Chi_Minh)",
+		"len":                  "must be %s",
+		"len_string":           "must be exactly %s characters",
+		"len_items":            "must contain exactly %s items",
+		"min":                  "must be at least %s",
+		"min_string":           "must be at least %s characters",
+		"min_items":            "must contain at least %s items",
+		"max":                  "must be at most %s",
+		"max_string":           "must be at most %s characters",
+		"max_items":            "must contain at most %s items",
+		"gt":                   "must be greater than %s",
+		"gte":                  "must be at least %s",
+		"lt":                   "must be less than %s",
+		"lte":                  "must be at most %s",
+	},
+	"vi": {
+		"summary":              "một hoặc nhiều trường không hợp lệ",
+		"invalid":              "không hợp lệ",
+		"type":                 "phải có kiểu %s",
+		"required":             "là bắt buộc",
+		"required_without_all": "là bắt buộc nếu không có %s",
+		"email":                "phải là địa chỉ email hợp lệ",
+		"url":                  "phải là URL hợp lệ",
+		"uuid":                 "phải là UUID hợp lệ",
+		"oneof":                "phải là một trong: %s",
+		"datetime":             "phải theo định dạng %s",
+		"timezone":             "phải là múi giờ IANA hợp lệ (ví dụ Asia/Ho_Chi_Minh)",
+		"len":                  "phải bằng %s",
+		"len_string":           "phải có đúng %s ký tự",
+		"len_items":            "phải có đúng %s phần tử",
+		"min":                  "phải lớn hơn hoặc bằng %s",
+		"min_string":           "phải có ít nhất %s ký tự",
+		"min_items":            "phải có ít nhất %s phần tử",
+		"max":                  "phải nhỏ hơn hoặc bằng %s",
+		"max_string":           "phải có tối đa %s ký tự",
+		"max_items":            "phải có tối đa %s phần tử",
+		"gt":                   "phải lớn hơn %s",
+		"gte":                  "phải lớn hơn hoặc bằng %s",
+		"lt":                   "phải nhỏ hơn %s",
+		"lte":                  "phải nhỏ hơn hoặc bằng %s",
+	},
+}
+
+// message renders the error for one failed rule
+func message(lang string, fe validator.FieldError) string {
+	key, param := fe.Tag(), fe.Param()
+
+	switch key {
+	case "len", "min", "max":
+		switch fe.Kind() {
+		case reflect.String:
+			key += "_string"
+		case reflect.Slice, reflect.Array, reflect.Map:
+			key += "_items"
+		}
+	case "oneof":
+		param = strings.Join(strings.Fields(param), ", ")
+	case "required_without_all":
+		names := strings.Fields(param)
+		for i, name := range names {
+			names[i] = snakeCase(name)
+		}
+		param = strings.Join(names, " / ")
+	}
+	return format(lang, key, param)
+}
+
+func format(lang, key, param string) string {
+	templates, ok := messages[lang]
+	if !ok {
+		templates = messages[defaultLanguage]
+	}
+	tmpl, ok := templates[key]
+	if !ok {
+		tmpl = templates["invalid"]
+	}
+	if strings.Contains(tmpl, "%s") {
+		return fmt.Sprintf(tmpl, param)
+	}
+	return tmpl
+}
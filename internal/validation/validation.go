@@ -0,0 +1,101 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/quocanhngo/gotalk/internal/model"
+)
+
+func init() {
+	// Report fields by their JSON (or query) name rather than the Go field name
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(fieldName)
+	}
+}
+
+// Fields converts a binding error into per-field errors with messages in lang.
+// It returns false for errors that aren't about specific fields (e.g. malformed JSON).
+func Fields(err error, lang string) ([]model.FieldError, bool) {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]model.FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, model.FieldError{
+				Field:   fieldPath(fe.Namespace()),
+				Rule:    fe.Tag(),
+				Message: message(lang, fe),
+			})
+		}
+		return fields, true
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []model.FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: format(lang, "type", typeErr.Type.Kind().String()),
+		}}, true
+	}
+	return nil, false
+}
+
+// Summary is the top-level message for a response carrying field errors
+func Summary(lang string) string {
+	return format(lang, "summary", "")
+}
+
+// Language picks a supported language from the user's setting, then the
+// Accept-Language header, defaulting to English
+func Language(userLang, acceptLanguage string) string {
+	for _, candidate := range []string{userLang, acceptLanguage} {
+		tag := strings.ToLower(strings.TrimSpace(candidate))
+		if len(tag) < 2 {
+			continue
+		}
+		if _, ok := messages[tag[:2]]; ok {
+			return tag[:2]
+		}
+	}
+	return defaultLanguage
+}
+
+func fieldName(f reflect.StructField) string {
+	for _, key := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(f.Tag.Get(key), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+// fieldPath drops the struct name: "SendMessageRequest.attachments[0].url" -> "attachments[0].url"
+func fieldPath(namespace string) string {
+	if _, rest, ok := strings.Cut(namespace, "."); ok {
+		return rest
+	}
+	return namespace
+}
+
+// snakeCase converts Go field names in rule params ("FileURL") to JSON names ("file_url")
+func snakeCase(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && unicode.IsLower(runes[i-1]) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -1,8 +1,9 @@
 package ws
 
 import (
-	"encoding/json"
 	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,38 +11,124 @@ import (
 	"github.com/quocanhngo/gotalk/internal/model"
 )
 
-const (
-	// Time allowed to write a message to the peer
-	writeWait = 10 * time.Second
-
-	// Time allowed to read the next pong message from the peer
-	pongWait = 60 * time.Second
-
-	// Send pings to peer with this period. Must be less than pongWait
-	pingPeriod = (pongWait * 9) / 10
-
-	// Maximum message size allowed from peer
-	maxMessageSize = 512 * 1024 // 512 KB
-)
+// Time allowed to write a message to the peer; ping timing and the read limit
+// come from the hub's Keepalive
+const writeWait = 10 * time.Second
 
 // Client represents a single WebSocket connection
 type Client struct {
 	hub    *Hub
 	conn   *websocket.Conn
-	send   chan []byte
+	queue  *sendQueue
+	codec  Codec     // negotiated through the WebSocket subprotocol
+	ID     uuid.UUID // identifies the connection among the user's others
 	UserID uuid.UUID
 	Name   string
+
+	compressed bool // permessage-deflate was negotiated
+
+	// When the peer last sent anything, in Unix nanoseconds, and whether it
+	// was warned since that the connection looks dead
+	lastSeen atomic.Int64
+	unstable atomic.Bool
+
+	connectedAt time.Time
+	closeReason atomic.Pointer[CloseReason] // set by Close
+
+	// The token the connection is authenticated with; refresh_token replaces it
+	authMu       sync.Mutex
+	credentials  Credentials
+	expiryWarned bool
+
+	// Users whose online/offline events this connection receives, and whether
+	// it was removed from the hub; both guarded by the hub's mutex
+	presenceSubs map[uuid.UUID]bool
+	gone         bool
 }
 
-// NewClient creates a new WebSocket client
+// NewClient creates a new WebSocket client with the hub's outbound queue size,
+// encoding events as negotiated during the handshake
 func NewClient(hub *Hub, conn *websocket.Conn, userID uuid.UUID, name string) *Client {
 	return &Client{
 		hub:    hub,
 		conn:   conn,
-		send:   make(chan []byte, 256),
+		queue:  newSendQueue(hub.queueSize),
+		codec:  CodecFor(conn.Subprotocol()),
+		ID:     uuid.New(),
 		UserID: userID,
 		Name:   name,
+
+		connectedAt: time.Now(),
+	}
+}
+
+// Credentials is the token a connection is authenticated with
+type Credentials struct {
+	Token     string
+	IssuedAt  time.Time
+	ExpiresAt time.Time // zero if it doesn't expire
+}
+
+// SetCredentials records the token the connection is authenticated with
+func (c *Client) SetCredentials(credentials Credentials) {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	c.credentials, c.expiryWarned = credentials, false
+}
+
+// Credentials returns the token the connection is authenticated with
+func (c *Client) Credentials() Credentials {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	return c.credentials
+}
+
+// WarnExpiryOnce reports true the first time it's called for the current
+// token, so its expiry is announced once
+func (c *Client) WarnExpiryOnce() bool {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	warn := !c.expiryWarned
+	c.expiryWarned = true
+	return warn
+}
+
+// UseCompression records that permessage-deflate was negotiated for the
+// connection (see OffersCompression) and applies the hub's level
+func (c *Client) UseCompression() {
+	c.compressed = true
+	if err := c.conn.SetCompressionLevel(c.hub.compression.Level); err != nil {
+		log.Printf("⚠️  Invalid WebSocket compression level %d: %v", c.hub.compression.Level, err)
+	}
+}
+
+// Send queues an event for this connection only
+func (c *Client) Send(event *model.WSEvent) error {
+	c.hub.checkEvent(event)
+	data, err := c.codec.Marshal(event)
+	if err != nil {
+		return err
 	}
+	c.hub.metrics.queued(event.Type, c.hub.trySend(c, data, policyFor(event.Type)))
+	c.hub.metrics.fannedOut(event.Type, 1)
+	return nil
+}
+
+// Compressed reports whether the connection uses permessage-deflate
+func (c *Client) Compressed() bool {
+	return c.compressed
+}
+
+// Codec returns the encoding the client negotiated
+func (c *Client) Codec() Codec {
+	return c.codec
+}
+
+// disconnect drops a client that can't keep up. Closing the connection ends
+// both pumps and the read pump unregisters the client.
+func (c *Client) disconnect() {
+	c.queue.close()
+	c.conn.Close()
 }
 
 // MessageHandler is a callback for processing incoming WebSocket messages
@@ -51,44 +138,58 @@ type MessageHandler func(client *Client, event model.WSEvent)
 // Runs in a per-client goroutine
 func (c *Client) ReadPump(handler MessageHandler) {
 	defer func() {
-		c.hub.unregister <- c
+		c.hub.Unregister(c)
 		c.conn.Close()
 	}()
 
-	c.conn.SetReadLimit(maxMessageSize)
-	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetReadLimit(c.hub.keepalive.MaxMessageSize)
+	c.alive()
 	c.conn.SetPongHandler(func(string) error {
-		c.conn.SetReadDeadline(time.Now().Add(pongWait))
+		c.alive()
 		return nil
 	})
 
 	for {
-		_, message, err := c.conn.ReadMessage()
+		messageType, message, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket error: %v", err)
 			}
 			break
 		}
+		c.alive()
 
-		// Parse the incoming event
+		// Parse the incoming event: text frames are JSON, binary frames MessagePack
+		decoder := JSONCodec
+		if messageType == websocket.BinaryMessage {
+			decoder = MsgPackCodec
+		}
 		var event model.WSEvent
-		if err := json.Unmarshal(message, &event); err != nil {
-			log.Printf("Error parsing WebSocket message: %v", err)
+		if err := decoder.Unmarshal(message, &event); err != nil {
+			// The write pump closes the connection, which ends this loop
+			log.Printf("Error parsing WebSocket message from %s, closing: %v", c.UserID, err)
+			c.Close(CloseProtocolError)
 			continue
 		}
 
 		// Handle the event via callback
 		if handler != nil {
-			handler(c, event)
+			c.handle(handler, event)
 		}
 	}
 }
 
+// handle runs the handler for one event; a panic is reported and the
+// connection stays open
+func (c *Client) handle(handler MessageHandler, event model.WSEvent) {
+	defer c.hub.recoverPanic("event "+event.Type, map[string]string{"user_id": c.UserID.String()})
+	handler(c, event)
+}
+
 // WritePump pumps messages from the hub to the WebSocket connection
 // Runs in a per-client goroutine
 func (c *Client) WritePump() {
-	ticker := time.NewTicker(pingPeriod)
+	ticker := time.NewTicker(c.hub.keepalive.PingInterval)
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
@@ -96,36 +197,72 @@ func (c *Client) WritePump() {
 
 	for {
 		select {
-		case message, ok := <-c.send:
+		case <-c.queue.ready:
+			events, closed := c.queue.drain()
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				// Hub closed the channel
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+			if closed {
+				// Close was called or the hub removed the client
+				c.writeClose()
 				return
 			}
-
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
+			if len(events) == 0 {
+				continue
 			}
-			w.Write(message)
 
-			// Write any queued messages to the current WebSocket frame
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write([]byte("\n"))
-				w.Write(<-c.send)
+			if err := c.write(events); err != nil {
+				return
 			}
 
-			if err := w.Close(); err != nil {
+		case now := <-ticker.C:
+			c.conn.SetWriteDeadline(now.Add(writeWait))
+			if err := c.checkStable(now); err != nil {
 				return
 			}
-
-		case <-ticker.C:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
 		}
 	}
 }
+
+// write sends queued events. JSON events are batched into one text frame, one
+// event per line; binary encodings get a frame per event.
+func (c *Client) write(events [][]byte) error {
+	if c.codec.MessageType() == websocket.BinaryMessage {
+		for _, event := range events {
+			c.compressFrame(len(event))
+			if err := c.conn.WriteMessage(websocket.BinaryMessage, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	size := len(events) - 1 // newlines
+	for _, event := range events {
+		size += len(event)
+	}
+	c.compressFrame(size)
+	w, err := c.conn.NextWriter(websocket.TextMessage)
+	if err != nil {
+		return err
+	}
+	for i, event := range events {
+		if i > 0 {
+			w.Write([]byte("\n"))
+		}
+		w.Write(event)
+	}
+	return w.Close()
+}
+
+// compressFrame turns compression on for the next frame when it was
+// negotiated and the frame reaches the threshold
+func (c *Client) compressFrame(size int) {
+	compress := c.compressed && size >= c.hub.compression.Threshold
+	c.conn.EnableWriteCompression(compress)
+	if compress {
+		c.hub.compressedFrames.Add(1)
+		c.hub.compressedBytes.Add(int64(size))
+	}
+}
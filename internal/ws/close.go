@@ -0,0 +1,206 @@
+package ws
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/quocanhngo/gotalk/internal/model"
+)
+
+// CloseReason is why the server closes a connection. Clients get it twice: as
+// a final disconnect event, then as the close frame's code and reason text.
+type CloseReason struct {
+	Code       int
+	Reason     string
+	Reconnect  string        // model.Reconnect*
+	RetryAfter time.Duration // suggested wait before reconnecting, if any
+}
+
+// Application close codes; RFC 6455 leaves 4000-4999 to applications
+var (
+	CloseProtocolError   = CloseReason{Code: 4000, Reason: "protocol_error", Reconnect: model.ReconnectRetry}
+	CloseAuthExpired     = CloseReason{Code: 4001, Reason: "auth_expired", Reconnect: model.ReconnectReauthenticate}
+	CloseLoggedOut       = CloseReason{Code: 4002, Reason: "logged_out", Reconnect: model.ReconnectReauthenticate}
+	CloseBanned          = CloseReason{Code: 4003, Reason: "banned", Reconnect: model.ReconnectNever}
+	CloseSessionLimit    = CloseReason{Code: 4008, Reason: "session_limit", Reconnect: model.ReconnectNever}
+	CloseSessionReplaced = CloseReason{Code: 4009, Reason: "session_replaced", Reconnect: model.ReconnectNever}
+	CloseServerDrain     = CloseReason{Code: 4012, Reason: "server_drain", Reconnect: model.ReconnectRetry}
+)
+
+// closeReasons finds a reason by its code, for closes requested by other instances
+var closeReasons = map[int]CloseReason{
+	CloseProtocolError.Code:   CloseProtocolError,
+	CloseAuthExpired.Code:     CloseAuthExpired,
+	CloseLoggedOut.Code:       CloseLoggedOut,
+	CloseBanned.Code:          CloseBanned,
+	CloseSessionLimit.Code:    CloseSessionLimit,
+	CloseSessionReplaced.Code: CloseSessionReplaced,
+	CloseServerDrain.Code:     CloseServerDrain,
+}
+
+// drainRetryWindow spreads the reconnects of a draining instance's clients
+const drainRetryWindow = 10 * time.Second
+
+func (r CloseReason) event() *model.WSEvent {
+	return &model.WSEvent{
+		Type: model.WSEventDisconnect,
+		Payload: model.DisconnectEvent{
+			Code:              r.Code,
+			Reason:            r.Reason,
+			Reconnect:         r.Reconnect,
+			RetryAfterSeconds: int(r.RetryAfter / time.Second),
+		},
+	}
+}
+
+// Close sends the disconnect event after the events already queued, then
+// closes the connection with the reason's close code. The first reason wins.
+func (c *Client) Close(reason CloseReason) {
+	if c.closeReason.CompareAndSwap(nil, &reason) {
+		c.queue.close()
+	}
+}
+
+// closing reports whether Close was called
+func (c *Client) closing() bool {
+	return c.closeReason.Load() != nil
+}
+
+// writeClose ends the connection from the write pump: with the disconnect
+// event and its close code when Close was called, with a bare close frame otherwise
+func (c *Client) writeClose() {
+	reason := c.closeReason.Load()
+	if reason == nil {
+		c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+		return
+	}
+	if data, err := c.codec.Marshal(reason.event()); err == nil {
+		c.write([][]byte{data})
+	}
+	c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(reason.Code, reason.Reason))
+}
+
+// Drain closes every connection on this instance with server_drain, each told
+// to wait a random part of drainRetryWindow so they don't all reconnect at
+// once, and waits until they're gone or ctx is done. Called on shutdown.
+func (h *Hub) Drain(ctx context.Context) {
+	h.mu.RLock()
+	count := 0
+	for _, clients := range h.clients {
+		for client := range clients {
+			reason := CloseServerDrain
+			reason.RetryAfter = time.Second + rand.N(drainRetryWindow)
+			client.Close(reason)
+			count++
+		}
+	}
+	h.mu.RUnlock()
+	if count == 0 {
+		return
+	}
+	log.Printf("🚰 Draining %d WebSocket connections", count)
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.mu.RLock()
+			remaining := len(h.clients)
+			h.mu.RUnlock()
+			if remaining == 0 {
+				return
+			}
+		}
+	}
+}
+
+// UseConnectionLimit caps each user's connections on this instance; a new
+// connection over the limit closes the user's oldest with session_limit. 0
+// means unlimited.
+func (h *Hub) UseConnectionLimit(perUser int) {
+	h.connectionLimit = perUser
+}
+
+// enforceConnectionLimit closes the oldest connections of a user who is about
+// to get one more than the limit. Callers hold h.mu.
+func (h *Hub) enforceConnectionLimit(clients map[*Client]bool) {
+	if h.connectionLimit <= 0 {
+		return
+	}
+	open := make([]*Client, 0, len(clients))
+	for client := range clients {
+		if !client.closing() {
+			open = append(open, client)
+		}
+	}
+	for len(open) >= h.connectionLimit {
+		oldest := 0
+		for i, client := range open {
+			if client.connectedAt.Before(open[oldest].connectedAt) {
+				oldest = i
+			}
+		}
+		open[oldest].Close(CloseSessionLimit)
+		open = append(open[:oldest], open[oldest+1:]...)
+	}
+}
+
+// ForceLogout is published to close a user's connections on every instance
+type ForceLogout struct {
+	UserID uuid.UUID `json:"user_id"`
+	Token  string    `json:"token,omitempty"`  // only connections authenticated with it; empty means all
+	Except string    `json:"except,omitempty"` // spare connections authenticated with this token
+	Code   int       `json:"code"`
+}
+
+// ForceLogout closes a user's connections on every instance right away: those
+// authenticated with token, or all of them when token is empty. Used when
+// tokens are revoked, so connections don't wait for the periodic check.
+func (h *Hub) ForceLogout(userID uuid.UUID, token string, reason CloseReason) {
+	h.publishToRedis(&TargetedEvent{ForceLogout: &ForceLogout{
+		UserID: userID,
+		Token:  token,
+		Code:   reason.Code,
+	}})
+}
+
+// ForceLogoutExcept closes a user's connections on every instance except those
+// authenticated with keepToken, e.g. when a new single session replaces them
+func (h *Hub) ForceLogoutExcept(userID uuid.UUID, keepToken string, reason CloseReason) {
+	h.publishToRedis(&TargetedEvent{ForceLogout: &ForceLogout{
+		UserID: userID,
+		Except: keepToken,
+		Code:   reason.Code,
+	}})
+}
+
+// forceLogoutLocal closes the local connections a ForceLogout targets
+func (h *Hub) forceLogoutLocal(logout *ForceLogout) {
+	reason, ok := closeReasons[logout.Code]
+	if !ok {
+		reason = CloseAuthExpired
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	closed := 0
+	for client := range h.clients[logout.UserID] {
+		token := client.Credentials().Token
+		if (logout.Token != "" && token != logout.Token) || (logout.Except != "" && token == logout.Except) {
+			continue
+		}
+		client.Close(reason)
+		closed++
+	}
+	if closed > 0 {
+		log.Printf("🔒 Force-closed %d connections of %s (%s)", closed, logout.UserID, reason.Reason)
+	}
+}
@@ -0,0 +1,154 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+
+	"github.com/gorilla/websocket"
+	"github.com/ugorji/go/codec"
+)
+
+// SubprotocolMsgPack is the WebSocket subprotocol clients request to receive
+// and send events as MessagePack instead of JSON
+const SubprotocolMsgPack = "gotalk.msgpack.v1"
+
+// Subprotocols lists the subprotocols the server accepts, for the upgrader
+var Subprotocols = []string{SubprotocolMsgPack}
+
+// Codec encodes events on the wire. The negotiated subprotocol picks one per connection.
+type Codec interface {
+	Name() string
+	// MessageType is the WebSocket frame type the codec writes
+	MessageType() int
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	JSONCodec    Codec = jsonCodec{}
+	MsgPackCodec Codec = msgpackCodec{}
+)
+
+// CodecFor returns the codec for a negotiated subprotocol; JSON when none was
+func CodecFor(subprotocol string) Codec {
+	if subprotocol == SubprotocolMsgPack {
+		return MsgPackCodec
+	}
+	return JSONCodec
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string     { return "json" }
+func (jsonCodec) MessageType() int { return websocket.TextMessage }
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// msgpackHandle decodes maps with string keys and strings as Go strings, so
+// decoded payloads look like the ones json.Unmarshal produces
+var msgpackHandle = func() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{}
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	h.RawToString = true
+	h.WriteExt = true
+	return h
+}()
+
+// msgpackCodec carries the same document as the JSON encoding: values go
+// through their JSON form first, so IDs and timestamps stay strings and
+// json tags, omitempty and custom MarshalJSON methods apply unchanged.
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string     { return "msgpack" }
+func (msgpackCodec) MessageType() int { return websocket.BinaryMessage }
+
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	if err := codec.NewEncoderBytes(&out, msgpackHandle).Encode(compactNumbers(doc)); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	var doc interface{}
+	if err := codec.NewDecoderBytes(data, msgpackHandle).Decode(&doc); err != nil {
+		return err
+	}
+	// Back through JSON so the target's json tags and UnmarshalJSON methods apply
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// compactNumbers turns json.Numbers into integers where possible, which
+// MessagePack stores in as few bytes as the value needs
+func compactNumbers(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, child := range x {
+			x[k] = compactNumbers(child)
+		}
+	case []interface{}:
+		for i, child := range x {
+			x[i] = compactNumbers(child)
+		}
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return n
+		}
+		f, _ := x.Float64()
+		return f
+	}
+	return v
+}
+
+// encodings marshals an event at most once per codec, for fan-out to
+// clients that negotiated different encodings
+type encodings struct {
+	event interface{}
+	data  map[Codec][]byte
+	errs  map[Codec]error
+}
+
+func newEncodings(event interface{}) *encodings {
+	return &encodings{event: event, data: make(map[Codec][]byte, 2), errs: make(map[Codec]error)}
+}
+
+// get returns the event in the codec's encoding. A codec that fails keeps
+// failing for this event; fresh is true the first time.
+func (e *encodings) get(c Codec) (data []byte, fresh bool, err error) {
+	if data, ok := e.data[c]; ok {
+		return data, false, nil
+	}
+	if err, ok := e.errs[c]; ok {
+		return nil, false, err
+	}
+	data, err = c.Marshal(e.event)
+	if err != nil {
+		e.errs[c] = err
+		return nil, true, err
+	}
+	e.data[c] = data
+	return data, true, nil
+}
@@ -0,0 +1,29 @@
+package ws
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Compression configures the permessage-deflate extension (RFC 7692).
+// gorilla/websocket always negotiates no context takeover, so every message is
+// compressed on its own and connections keep no compression window between messages.
+type Compression struct {
+	Enabled   bool
+	Level     int // flate level, 1 (fastest) to 9 (smallest)
+	Threshold int // frames smaller than this many bytes are sent uncompressed
+}
+
+// OffersCompression reports whether a handshake request offers permessage-deflate,
+// in which case an upgrader with EnableCompression negotiates it
+func OffersCompression(header http.Header) bool {
+	for _, value := range header.Values("Sec-WebSocket-Extensions") {
+		for _, ext := range strings.Split(value, ",") {
+			name, _, _ := strings.Cut(ext, ";")
+			if strings.EqualFold(strings.TrimSpace(name), "permessage-deflate") {
+				return true
+			}
+		}
+	}
+	return false
+}
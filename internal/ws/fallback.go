@@ -0,0 +1,182 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RedisFallback configures the Hub while Redis is unreachable. Events are then
+// delivered to this instance's connections right away, and buffered for the
+// other instances until Redis is back. Typing, presence and other ephemeral
+// updates aren't buffered: they would be stale by then.
+type RedisFallback struct {
+	SingleInstance bool          // there are no other instances, so nothing is buffered
+	BufferSize     int           // events buffered for other instances; the oldest are discarded beyond
+	BufferMaxAge   time.Duration // buffered events older than this when Redis is back are discarded
+}
+
+// DefaultRedisFallback is used until UseRedisFallback is called
+var DefaultRedisFallback = RedisFallback{
+	BufferSize:   10000,
+	BufferMaxAge: 2 * time.Minute,
+}
+
+// UseRedisFallback sets how events are delivered while Redis is unreachable
+func (h *Hub) UseRedisFallback(fallback RedisFallback) {
+	h.redisFallback = fallback
+}
+
+const (
+	// Wait between attempts to subscribe again, doubling up to the maximum
+	resubscribeMinBackoff = 500 * time.Millisecond
+	resubscribeMaxBackoff = 30 * time.Second
+
+	// A subscriber that received nothing for this long pings Redis
+	subscriberPingInterval = 10 * time.Second
+
+	// How often a degraded hub checks whether Redis is back
+	recoveryCheckInterval = time.Second
+)
+
+// redisState tracks Redis outages and the events buffered during them
+type redisState struct {
+	down    atomic.Bool
+	since   atomic.Int64 // start of the current outage, in Unix nanoseconds
+	outages atomic.Int64
+
+	mu        sync.Mutex
+	buffer    []bufferedPublish
+	discarded atomic.Int64 // buffered events lost to the size or age limit
+}
+
+type bufferedPublish struct {
+	at   time.Time
+	data []byte
+}
+
+// Degraded reports whether Redis is unreachable, so events only reach this
+// instance's connections for now
+func (h *Hub) Degraded() bool {
+	return h.redis.down.Load()
+}
+
+// redisLost switches to local delivery after a Redis error
+func (h *Hub) redisLost(err error) {
+	if h.redis.down.CompareAndSwap(false, true) {
+		h.redis.since.Store(time.Now().UnixNano())
+		h.redis.outages.Add(1)
+		log.Printf("⚠️  Redis unreachable, delivering WS events to local connections only: %v", err)
+	}
+}
+
+// publishLocally delivers an event that couldn't be published to this
+// instance's connections, and buffers it for the other instances. The
+// buffered copy carries this instance's ID, so it isn't delivered here twice.
+func (h *Hub) publishLocally(event *TargetedEvent) error {
+	h.dispatch(event)
+	if h.redisFallback.SingleInstance || !buffered(event) {
+		return nil
+	}
+
+	replay := *event
+	replay.Origin = h.instanceID
+	data, err := json.Marshal(&replay)
+	if err != nil {
+		return err
+	}
+
+	h.redis.mu.Lock()
+	if !h.redis.down.Load() {
+		// Redis came back and the buffer was flushed meanwhile
+		h.redis.mu.Unlock()
+		return h.rdb.Publish(context.Background(), redisChannel, data).Err()
+	}
+	defer h.redis.mu.Unlock()
+	h.redis.buffer = append(h.redis.buffer, bufferedPublish{at: time.Now(), data: data})
+	h.trimBuffer()
+	return nil
+}
+
+// trimBuffer discards the oldest buffered events beyond the buffer size.
+// Callers hold h.redis.mu.
+func (h *Hub) trimBuffer() {
+	if over := len(h.redis.buffer) - h.redisFallback.BufferSize; over > 0 {
+		h.redis.buffer = append(h.redis.buffer[:0], h.redis.buffer[over:]...)
+		h.redis.discarded.Add(int64(over))
+	}
+}
+
+// buffered reports whether an event is worth delivering to other instances late
+func buffered(event *TargetedEvent) bool {
+	switch {
+	case event.ForceLogout != nil:
+		return true
+	case event.Event != nil:
+		return policyFor(event.Event.Type) == PolicyNeverDrop
+	}
+	return false
+}
+
+// redisRestored leaves degraded mode when Redis answers again: it announces
+// this instance's presence again and publishes the buffered events. It's
+// called by the subscriber, which is subscribed again by then.
+func (h *Hub) redisRestored(ctx context.Context) {
+	if !h.redis.down.Load() {
+		return
+	}
+	if err := h.rdb.Ping(ctx).Err(); err != nil {
+		return
+	}
+	h.resyncPresence(ctx)
+
+	published, stale, err := h.flushBuffer(ctx)
+	if err != nil {
+		log.Printf("⚠️  Redis failed again while publishing buffered WS events: %v", err)
+		return
+	}
+	outage := time.Since(time.Unix(0, h.redis.since.Load())).Round(time.Second)
+	log.Printf("✅ Redis is back after %s: published %d buffered WS events, discarded %d too old", outage, published, stale)
+}
+
+// flushBuffer publishes the buffered events in order and leaves degraded mode
+// once the buffer is empty. On error, the unpublished events stay buffered.
+func (h *Hub) flushBuffer(ctx context.Context) (published, stale int, err error) {
+	for {
+		h.redis.mu.Lock()
+		batch := h.redis.buffer
+		h.redis.buffer = nil
+		if len(batch) == 0 {
+			h.redis.down.Store(false)
+			h.redis.mu.Unlock()
+			return published, stale, nil
+		}
+		h.redis.mu.Unlock()
+
+		for i, p := range batch {
+			if time.Since(p.at) > h.redisFallback.BufferMaxAge {
+				stale++
+				h.redis.discarded.Add(1)
+				continue
+			}
+			if err := h.rdb.Publish(ctx, redisChannel, p.data).Err(); err != nil {
+				h.redis.mu.Lock()
+				h.redis.buffer = append(batch[i:], h.redis.buffer...)
+				h.trimBuffer()
+				h.redis.mu.Unlock()
+				return published, stale, err
+			}
+			published++
+		}
+	}
+}
+
+// bufferedCount returns how many events wait for Redis
+func (h *Hub) bufferedCount() int {
+	h.redis.mu.Lock()
+	defer h.redis.mu.Unlock()
+	return len(h.redis.buffer)
+}
@@ -3,11 +3,17 @@ package ws
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/pkg/errreport"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -30,27 +36,103 @@ type Hub struct {
 	// Redis client for Pub/Sub (horizontal scaling)
 	rdb *redis.Client
 
+	// Unique ID of this server instance (for cross-instance presence)
+	instanceID string
+
 	// Callback when user comes online/offline
 	onStatusChange func(userID uuid.UUID, online bool)
+
+	// Receives panics recovered in hub and client goroutines
+	reporter *errreport.Reporter
+
+	// Outbound queue length of each client, and what happened to overflowing events
+	queueSize       int
+	dropped         atomic.Int64
+	slowDisconnects atomic.Int64
+
+	// permessage-deflate settings, and frames/bytes sent compressed
+	compression      Compression
+	compressedFrames atomic.Int64
+	compressedBytes  atomic.Int64
+
+	// Ping/pong timing and read limit, and connection_unstable warnings sent
+	keepalive        Keepalive
+	unstableWarnings atomic.Int64
+
+	// Connections allowed per user on this instance; 0 means unlimited
+	connectionLimit int
+
+	// Who is typing in large conversations, summarized every second
+	typing *typingState
+
+	// Presence subscriptions: watched user ID -> local connections watching them
+	presenceSubs map[uuid.UUID]map[*Client]bool
+
+	// Per-event-type counters and histograms, for capacity planning
+	metrics *hubMetrics
+
+	// Checks outgoing events against the documented schemas; nil when off
+	validator *EventValidator
+
+	// Local delivery and buffering while Redis is unreachable
+	redisFallback RedisFallback
+	redis         redisState
 }
 
-// NewHub creates a new WebSocket Hub
-func NewHub(rdb *redis.Client, onStatusChange func(userID uuid.UUID, online bool)) *Hub {
+// NewHub creates a new WebSocket Hub whose clients queue up to queueSize
+// outbound events (DefaultQueueSize when 0)
+func NewHub(rdb *redis.Client, queueSize int, onStatusChange func(userID uuid.UUID, online bool)) *Hub {
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
 	return &Hub{
+		queueSize:      queueSize,
 		clients:        make(map[uuid.UUID]map[*Client]bool),
 		register:       make(chan *Client),
 		unregister:     make(chan *Client),
 		broadcast:      make(chan *model.WSEvent, 256),
 		rdb:            rdb,
+		instanceID:     uuid.New().String(),
 		onStatusChange: onStatusChange,
+		keepalive:      DefaultKeepalive,
+		typing:         newTypingState(),
+		presenceSubs:   make(map[uuid.UUID]map[*Client]bool),
+		metrics:        newHubMetrics(),
+		redisFallback:  DefaultRedisFallback,
 	}
 }
 
-// Run starts the Hub's main event loop
+// UseErrorReporter reports panics recovered in hub and client goroutines
+func (h *Hub) UseErrorReporter(reporter *errreport.Reporter) {
+	h.reporter = reporter
+}
+
+// UseCompression sets the permessage-deflate settings for new connections
+func (h *Hub) UseCompression(compression Compression) {
+	h.compression = compression
+}
+
+// Compression returns the permessage-deflate settings
+func (h *Hub) Compression() Compression {
+	return h.compression
+}
+
+// Run starts the Hub's main event loop. The loop, the Redis subscriber and the
+// presence heartbeat are restarted if they panic; the subscriber subscribes
+// again, with backoff, when Redis goes away.
 func (h *Hub) Run(ctx context.Context) {
 	// Start Redis subscriber in a goroutine
-	go h.subscribeRedis(ctx)
+	go h.keepRunning(ctx, "redis subscriber", h.subscribeRedis)
+
+	// Publish this instance's presence so crashed instances can be detected
+	h.registerPresence(ctx)
+	go h.keepRunning(ctx, "presence", h.runPresence)
+	go h.keepRunning(ctx, "typing summaries", h.runTypingSummaries)
+
+	h.keepRunning(ctx, "event loop", h.loop)
+}
 
+func (h *Hub) loop(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
@@ -75,6 +157,11 @@ func (h *Hub) Register(client *Client) {
 	h.register <- client
 }
 
+// Unregister queues a client for removal; its send channel is closed by the hub loop
+func (h *Hub) Unregister(client *Client) {
+	h.unregister <- client
+}
+
 // addClient registers a new client connection
 func (h *Hub) addClient(client *Client) {
 	h.mu.Lock()
@@ -82,19 +169,13 @@ func (h *Hub) addClient(client *Client) {
 
 	if _, ok := h.clients[client.UserID]; !ok {
 		h.clients[client.UserID] = make(map[*Client]bool)
-		// User just came online (first connection)
+		h.markPresent(client.UserID)
+		// User just came online (first connection); the callback emits the online event
 		if h.onStatusChange != nil {
-			go h.onStatusChange(client.UserID, true)
+			go h.statusChanged(client.UserID, true)
 		}
-		// Broadcast online event
-		h.publishToRedis(&model.WSEvent{
-			Type: model.WSEventOnline,
-			Payload: model.OnlineEvent{
-				UserID:   client.UserID,
-				IsOnline: true,
-			},
-		})
 	}
+	h.enforceConnectionLimit(h.clients[client.UserID])
 	h.clients[client.UserID][client] = true
 	log.Printf("✅ Client connected: %s (total connections: %d)", client.UserID, len(h.clients[client.UserID]))
 }
@@ -104,29 +185,66 @@ func (h *Hub) removeClient(client *Client) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	if clients, ok := h.clients[client.UserID]; ok {
+	// Only the hub loop removes clients and closes their queue
+	if clients, ok := h.clients[client.UserID]; ok && clients[client] {
 		delete(clients, client)
-		close(client.send)
+		client.queue.close()
+		h.dropPresenceSubscriptions(client)
 
 		if len(clients) == 0 {
 			// User has no more connections (offline)
 			delete(h.clients, client.UserID)
+			h.markAbsent(client.UserID)
+			// The callback emits the offline event
 			if h.onStatusChange != nil {
-				go h.onStatusChange(client.UserID, false)
+				go h.statusChanged(client.UserID, false)
 			}
-			// Broadcast offline event
-			h.publishToRedis(&model.WSEvent{
-				Type: model.WSEventOffline,
-				Payload: model.OnlineEvent{
-					UserID:   client.UserID,
-					IsOnline: false,
-				},
-			})
 		}
 	}
 	log.Printf("❌ Client disconnected: %s", client.UserID)
 }
 
+// statusChanged runs the online/offline callback
+func (h *Hub) statusChanged(userID uuid.UUID, online bool) {
+	defer h.recoverPanic("status change", map[string]string{"user_id": userID.String()})
+	h.onStatusChange(userID, online)
+}
+
+// keepRunning runs a long-lived loop until ctx is done, restarting it after a panic
+func (h *Hub) keepRunning(ctx context.Context, name string, loop func(ctx context.Context)) {
+	for {
+		func() {
+			defer h.recoverPanic(name, nil)
+			loop(ctx)
+		}()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+			log.Printf("🔁 Restarting hub %s", name)
+		}
+	}
+}
+
+// recoverPanic reports a panic in a hub goroutine instead of crashing the
+// server. It must be deferred directly.
+func (h *Hub) recoverPanic(where string, tags map[string]string) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	event := h.reporter.PanicEvent(recovered)
+	event.Tags["component"] = "ws"
+	event.Tags["goroutine"] = where
+	for k, v := range tags {
+		event.Tags[k] = v
+	}
+	if userID, ok := tags["user_id"]; ok {
+		event.UserID = userID
+	}
+	h.reporter.Report(event)
+}
+
 // SendToUser sends an event to a specific user (all their connections)
 func (h *Hub) SendToUser(userID uuid.UUID, event *model.WSEvent) {
 	// Publish to Redis so all instances can deliver
@@ -136,6 +254,16 @@ func (h *Hub) SendToUser(userID uuid.UUID, event *model.WSEvent) {
 	})
 }
 
+// SendToUserExcept sends an event to a user's connections other than the
+// one with ID exceptClientID, e.g. to sync their other devices
+func (h *Hub) SendToUserExcept(userID, exceptClientID uuid.UUID, event *model.WSEvent) {
+	h.publishToRedis(&TargetedEvent{
+		TargetUserID:   userID,
+		ExceptClientID: exceptClientID,
+		Event:          event,
+	})
+}
+
 // SendToUsers sends an event to multiple users
 func (h *Hub) SendToUsers(userIDs []uuid.UUID, event *model.WSEvent) {
 	for _, userID := range userIDs {
@@ -143,27 +271,49 @@ func (h *Hub) SendToUsers(userIDs []uuid.UUID, event *model.WSEvent) {
 	}
 }
 
+// Deliver sends an event to multiple users like SendToUsers, but stops at and
+// returns the first publish error so the caller can retry. While Redis is
+// unreachable, an event delivered locally and buffered counts as sent.
+func (h *Hub) Deliver(ctx context.Context, userIDs []uuid.UUID, event *model.WSEvent) error {
+	for _, userID := range userIDs {
+		if err := h.publish(ctx, &TargetedEvent{TargetUserID: userID, Event: event}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Broadcast sends an event to every connected user on all instances
+func (h *Hub) Broadcast(event *model.WSEvent) {
+	h.publishToRedis(&TargetedEvent{Event: event})
+}
+
 // sendToLocalUser sends an event to a user on this instance only
 func (h *Hub) sendToLocalUser(userID uuid.UUID, event *model.WSEvent) {
+	h.sendToLocalUserExcept(userID, uuid.Nil, event)
+}
+
+// sendToLocalUserExcept sends an event to a user's connections on this
+// instance other than exceptClientID (uuid.Nil for all of them)
+func (h *Hub) sendToLocalUserExcept(userID, exceptClientID uuid.UUID, event *model.WSEvent) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	if clients, ok := h.clients[userID]; ok {
-		data, err := json.Marshal(event)
-		if err != nil {
-			log.Printf("Error marshaling event: %v", err)
-			return
-		}
+	clients, ok := h.clients[userID]
+	if !ok {
+		return
+	}
+	if exceptClientID != uuid.Nil {
+		others := make(map[*Client]bool, len(clients))
 		for client := range clients {
-			select {
-			case client.send <- data:
-			default:
-				// Client's send buffer is full, close connection
-				close(client.send)
-				delete(clients, client)
+			if client.ID != exceptClientID {
+				others[client] = true
 			}
 		}
+		clients = others
 	}
+	h.checkEvent(event)
+	h.metrics.fannedOut(event.Type, h.sendToClients(clients, event, newEncodings(event)))
 }
 
 // broadcastToLocal sends an event to all connected local clients
@@ -171,22 +321,106 @@ func (h *Hub) broadcastToLocal(event *model.WSEvent) {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	data, err := json.Marshal(event)
-	if err != nil {
-		log.Printf("Error marshaling broadcast event: %v", err)
-		return
+	h.checkEvent(event)
+	encoded := newEncodings(event)
+	sent := 0
+	for _, clients := range h.clients {
+		sent += h.sendToClients(clients, event, encoded)
 	}
+	h.metrics.fannedOut(event.Type, sent)
+}
 
+// sendToClients queues an event for each client in its negotiated encoding
+// and returns how many it was handed to. Callers hold h.mu.
+func (h *Hub) sendToClients(clients map[*Client]bool, event *model.WSEvent, encoded *encodings) int {
+	policy := policyFor(event.Type)
+	sent := 0
+	for client := range clients {
+		data, fresh, err := encoded.get(client.codec)
+		if err != nil {
+			// Only the clients of that codec miss the event
+			if fresh {
+				log.Printf("Error encoding %s event as %s: %v", event.Type, client.codec.Name(), err)
+			}
+			continue
+		}
+		h.metrics.queued(event.Type, h.trySend(client, data, policy))
+		sent++
+	}
+	return sent
+}
+
+// trySend queues data for a client without blocking. When the client's queue
+// is full, ephemeral events are dropped and a client that can't take a chat
+// message is disconnected.
+func (h *Hub) trySend(client *Client, data []byte, policy Policy) pushResult {
+	result := client.queue.push(data, policy)
+	switch result {
+	case pushDropped:
+		h.dropped.Add(1)
+	case pushOverflow:
+		h.slowDisconnects.Add(1)
+		log.Printf("🐢 Disconnecting slow client %s: outbound queue full (%d events)", client.UserID, h.queueSize)
+		client.disconnect()
+	}
+	return result
+}
+
+// Stats reports the clients' outbound queues, compression use, keepalive
+// warnings and per-event-type traffic on this instance
+func (h *Hub) Stats() model.WSStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats := model.WSStats{
+		QueueSize:       h.queueSize,
+		Dropped:         h.dropped.Load(),
+		SlowDisconnects: h.slowDisconnects.Load(),
+
+		CompressedFrames: h.compressedFrames.Load(),
+		CompressedBytes:  h.compressedBytes.Load(),
+
+		UnstableWarnings: h.unstableWarnings.Load(),
+
+		Degraded:        h.Degraded(),
+		RedisOutages:    h.redis.outages.Load(),
+		Buffered:        h.bufferedCount(),
+		BufferDiscarded: h.redis.discarded.Load(),
+
+		PublishErrors:    h.metrics.publishErrors.Load(),
+		PublishLatencyMs: h.metrics.publishLatency.snapshot(),
+		Events:           h.metrics.snapshot(),
+	}
+	for _, clients := range h.clients {
+		for client := range clients {
+			queued := client.queue.depth()
+			stats.Clients++
+			if client.compressed {
+				stats.CompressedClients++
+			}
+			stats.Queued += queued
+			if queued > stats.MaxDepth {
+				stats.MaxDepth = queued
+			}
+		}
+	}
+	return stats
+}
+
+// Clients returns the connections on this instance that aren't being closed
+func (h *Hub) Clients() []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var list []*Client
 	for _, clients := range h.clients {
 		for client := range clients {
-			select {
-			case client.send <- data:
-			default:
-				close(client.send)
-				delete(clients, client)
+			if !client.closing() {
+				list = append(list, client)
 			}
 		}
 	}
+	return list
 }
 
 // IsUserOnline checks if a user has any active connections on this instance
@@ -211,61 +445,152 @@ func (h *Hub) GetOnlineUserIDs() []uuid.UUID {
 
 // ========== Redis Pub/Sub for Horizontal Scaling ==========
 
-// TargetedEvent wraps an event with a target user ID for Redis Pub/Sub
+// TargetedEvent wraps an event with a target user ID for Redis Pub/Sub. Typing
+// updates of large conversations, presence updates and forced logouts travel
+// in it without an event.
 type TargetedEvent struct {
-	TargetUserID uuid.UUID      `json:"target_user_id,omitempty"`
-	Event        *model.WSEvent `json:"event"`
+	TargetUserID   uuid.UUID       `json:"target_user_id,omitempty"`
+	ExceptClientID uuid.UUID       `json:"except_client_id,omitempty"` // skip this connection of the target user
+	Origin         string          `json:"origin,omitempty"`           // instance that already delivered it locally, while Redis was down
+	Event          *model.WSEvent  `json:"event"`
+	Typing         *TypingUpdate   `json:"typing,omitempty"`
+	Presence       *PresenceUpdate `json:"presence,omitempty"`
+	ForceLogout    *ForceLogout    `json:"force_logout,omitempty"`
 }
 
 // publishToRedis publishes an event to Redis for cross-instance communication
-func (h *Hub) publishToRedis(data interface{}) {
-	jsonData, err := json.Marshal(data)
-	if err != nil {
-		log.Printf("Error marshaling for Redis: %v", err)
-		return
+func (h *Hub) publishToRedis(event *TargetedEvent) {
+	if err := h.publish(context.Background(), event); err != nil {
+		log.Printf("Error publishing to Redis: %v", err)
 	}
+}
 
-	if err := h.rdb.Publish(context.Background(), redisChannel, jsonData).Err(); err != nil {
-		log.Printf("Error publishing to Redis: %v", err)
+// publish marshals an event and publishes it on the hub's Redis channel. When
+// Redis is unreachable the event is delivered locally and buffered instead.
+func (h *Hub) publish(ctx context.Context, event *TargetedEvent) error {
+	if h.redis.down.Load() {
+		return h.publishLocally(event)
+	}
+	jsonData, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
 	}
+	start := time.Now()
+	err = h.rdb.Publish(ctx, redisChannel, jsonData).Err()
+	h.metrics.published(publishedType(event), time.Since(start), err)
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+	h.redisLost(err)
+	return h.publishLocally(event)
 }
 
-// subscribeRedis subscribes to Redis and delivers events to local clients
+// subscribeRedis subscribes to Redis and delivers events to local clients.
+// When the subscription is lost the hub delivers locally and subscribes again
+// with exponential backoff.
 func (h *Hub) subscribeRedis(ctx context.Context) {
+	backoff := resubscribeMinBackoff
+	for {
+		subscribed, err := h.receiveRedis(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		h.redisLost(err)
+		if subscribed {
+			backoff = resubscribeMinBackoff
+		}
+		log.Printf("⚠️  Redis Pub/Sub subscription lost, subscribing again in %s: %v", backoff, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, resubscribeMaxBackoff)
+	}
+}
+
+// receiveRedis subscribes and delivers events until the subscription fails,
+// reporting whether it was subscribed at all
+func (h *Hub) receiveRedis(ctx context.Context) (bool, error) {
 	pubsub := h.rdb.Subscribe(ctx, redisChannel)
 	defer pubsub.Close()
 
-	ch := pubsub.Channel()
+	// The first reply confirms the subscription
+	if _, err := pubsub.ReceiveTimeout(ctx, subscriberPingInterval); err != nil {
+		return false, err
+	}
 	log.Println("Redis Pub/Sub subscriber started")
+	h.redisRestored(ctx)
 
+	lastCheck := time.Now()
 	for {
-		select {
-		case <-ctx.Done():
-			return
-		case msg := <-ch:
-			var targeted TargetedEvent
-			// Try to unmarshal as TargetedEvent
-			if err := json.Unmarshal([]byte(msg.Payload), &targeted); err != nil {
-				log.Printf("Error unmarshaling Redis message: %v", err)
-				continue
+		timeout := subscriberPingInterval
+		if h.redis.down.Load() {
+			timeout = recoveryCheckInterval
+		}
+		msg, err := pubsub.ReceiveTimeout(ctx, timeout)
+		var netErr net.Error
+		switch {
+		case errors.As(err, &netErr) && netErr.Timeout():
+			// Nothing received for a while: make sure the connection is alive
+			if err := pubsub.Ping(ctx); err != nil {
+				return true, err
 			}
-
-			// Check if it's a valid TargetedEvent wrapper
-			if targeted.Event != nil {
-				if targeted.TargetUserID != uuid.Nil {
-					// Targeted event - send to specific user
-					h.sendToLocalUser(targeted.TargetUserID, targeted.Event)
-				} else {
-					// Broadcast event wrapped in TargetedEvent (target_user_id is nil/empty)
-					h.broadcastToLocal(targeted.Event)
-				}
-			} else {
-				// Fallback: It might be a raw WSEvent (e.g. from addClient/removeClient)
-				var wsEvent model.WSEvent
-				if err := json.Unmarshal([]byte(msg.Payload), &wsEvent); err == nil && wsEvent.Type != "" {
-					h.broadcastToLocal(&wsEvent)
-				}
+		case err != nil:
+			return true, err
+		default:
+			if msg, ok := msg.(*redis.Message); ok {
+				h.receive([]byte(msg.Payload))
 			}
 		}
+
+		// A failed publish leaves the subscription working; check when Redis answers again
+		if h.redis.down.Load() && time.Since(lastCheck) >= recoveryCheckInterval {
+			lastCheck = time.Now()
+			h.redisRestored(ctx)
+		}
+	}
+}
+
+// receive delivers an event received from Redis to local clients
+func (h *Hub) receive(payload []byte) {
+	var targeted TargetedEvent
+	// Try to unmarshal as TargetedEvent
+	if err := json.Unmarshal(payload, &targeted); err != nil {
+		log.Printf("Error unmarshaling Redis message: %v", err)
+		return
+	}
+	if targeted.Origin == h.instanceID {
+		return // delivered here when it was buffered
+	}
+	if h.dispatch(&targeted) {
+		return
+	}
+	// Fallback: It might be a raw WSEvent (e.g. from addClient/removeClient)
+	var wsEvent model.WSEvent
+	if err := json.Unmarshal(payload, &wsEvent); err == nil && wsEvent.Type != "" {
+		h.broadcastToLocal(&wsEvent)
+	}
+}
+
+// dispatch delivers a targeted event to local clients, reporting false when
+// it carries nothing
+func (h *Hub) dispatch(targeted *TargetedEvent) bool {
+	switch {
+	case targeted.Typing != nil:
+		h.typing.apply(targeted.Typing, time.Now())
+	case targeted.Presence != nil:
+		h.sendPresenceToLocal(targeted.Presence)
+	case targeted.ForceLogout != nil:
+		h.forceLogoutLocal(targeted.ForceLogout)
+	case targeted.Event != nil && targeted.TargetUserID != uuid.Nil:
+		// Targeted event - send to specific user
+		h.sendToLocalUserExcept(targeted.TargetUserID, targeted.ExceptClientID, targeted.Event)
+	case targeted.Event != nil:
+		// Broadcast event wrapped in TargetedEvent (target_user_id is nil/empty)
+		h.broadcastToLocal(targeted.Event)
+	default:
+		return false
 	}
+	return true
 }
@@ -0,0 +1,285 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestHub runs a hub's event loop without Redis: events go through the
+// degraded mode's local delivery
+func newTestHub(t *testing.T, queueSize int) *Hub {
+	t.Helper()
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 50 * time.Millisecond})
+	t.Cleanup(func() { rdb.Close() })
+
+	hub := NewHub(rdb, queueSize, nil)
+	hub.UseRedisFallback(RedisFallback{SingleInstance: true})
+	hub.redisLost(errors.New("no Redis in tests"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		hub.loop(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return hub
+}
+
+// testServer upgrades connections for the user in ?user= and registers them
+// with the hub. Connections with ?slow=1 get no write pump, so their queue
+// fills up as a client that stopped reading would.
+func testServer(t *testing.T, hub *Hub, clients chan<- *Client) *httptest.Server {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		client := NewClient(hub, conn, uuid.MustParse(r.URL.Query().Get("user")), "test")
+		hub.Register(client)
+		if r.URL.Query().Get("slow") == "" {
+			go client.WritePump()
+		}
+		clients <- client
+		client.ReadPump(nil)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func dial(t *testing.T, srv *httptest.Server, userID uuid.UUID, slow bool) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/?user=" + userID.String()
+	if slow {
+		url += "&slow=1"
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// TestHubConcurrentSendAndUnregister sends to, broadcasts to and evicts
+// clients while they disconnect and are unregistered; run it with -race
+func TestHubConcurrentSendAndUnregister(t *testing.T) {
+	const (
+		users          = 8
+		connsPerUser   = 4
+		sendsPerWorker = 200
+	)
+	hub := newTestHub(t, 8)
+	serverClients := make(chan *Client, users*connsPerUser)
+	srv := testServer(t, hub, serverClients)
+
+	userIDs := make([]uuid.UUID, users)
+	var conns []*websocket.Conn
+	for i := range userIDs {
+		userIDs[i] = uuid.New()
+		for j := 0; j < connsPerUser; j++ {
+			// One slow connection per user overflows and is evicted
+			conns = append(conns, dial(t, srv, userIDs[i], j == 0))
+		}
+	}
+	var readers sync.WaitGroup
+	for _, conn := range conns {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+	}
+	registered := make([]*Client, 0, len(conns))
+	for range conns {
+		registered = append(registered, <-serverClients)
+	}
+	waitFor(t, "every client registered", func() bool { return connections(hub) == len(conns) })
+
+	message := func(i int) *model.WSEvent {
+		return &model.WSEvent{Type: model.WSEventNewMessage, Payload: map[string]int{"n": i}}
+	}
+	typing := &model.WSEvent{Type: model.WSEventTyping, Payload: model.TypingEvent{UserID: userIDs[0]}}
+
+	var wg sync.WaitGroup
+	for _, userID := range userIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < sendsPerWorker; i++ {
+				hub.SendToUser(userID, message(i))
+				hub.SendToUser(userID, typing)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < sendsPerWorker; i++ {
+			hub.Broadcast(message(i))
+		}
+	}()
+	for _, client := range registered {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < sendsPerWorker/4; i++ {
+				if err := client.Send(message(i)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	// Half the connections go away mid-stream: some from the client side,
+	// some unregistered by the server while their read pump still runs
+	for i, client := range registered {
+		if i%2 == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			time.Sleep(time.Millisecond)
+			if i%4 == 1 {
+				conns[i].Close()
+			} else {
+				hub.Unregister(client)
+				client.Close(CloseLoggedOut)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if hub.slowDisconnects.Load() == 0 {
+		t.Error("no slow client was disconnected")
+	}
+
+	// Everyone leaves; unregistering twice is harmless
+	for i, conn := range conns {
+		conn.Close()
+		hub.Unregister(registered[i])
+	}
+	readers.Wait()
+	waitFor(t, "every client removed", func() bool { return connections(hub) == 0 })
+	for _, client := range registered {
+		if result := client.queue.push([]byte("{}"), PolicyNeverDrop); result != pushClosed {
+			t.Fatalf("queue of a removed client accepted an event (%d)", result)
+		}
+	}
+}
+
+// TestSendQueueOverflow checks the overflow policies of a full queue
+func TestSendQueueOverflow(t *testing.T) {
+	q := newSendQueue(2)
+	if r := q.push([]byte("typing"), PolicyDropOldest); r != pushQueued {
+		t.Fatalf("push into empty queue: %d", r)
+	}
+	q.push([]byte("message 1"), PolicyNeverDrop)
+
+	// The ephemeral event makes room for a chat message
+	if r := q.push([]byte("message 2"), PolicyNeverDrop); r != pushDropped {
+		t.Fatalf("push evicting typing: %d", r)
+	}
+	// Only chat messages left: an ephemeral event is dropped, a message overflows
+	if r := q.push([]byte("typing"), PolicyDropOldest); r != pushDropped {
+		t.Fatalf("push of typing into a full queue: %d", r)
+	}
+	if r := q.push([]byte("message 3"), PolicyNeverDrop); r != pushOverflow {
+		t.Fatalf("push of a message into a full queue: %d", r)
+	}
+
+	events, closed := q.drain()
+	if closed || len(events) != 2 || string(events[0]) != "message 1" || string(events[1]) != "message 2" {
+		t.Fatalf("drain = %q, %v", events, closed)
+	}
+	q.close()
+	if r := q.push([]byte("message 4"), PolicyNeverDrop); r != pushClosed {
+		t.Fatalf("push into a closed queue: %d", r)
+	}
+	if _, closed := q.drain(); !closed {
+		t.Fatal("drain of a closed, empty queue isn't closed")
+	}
+}
+
+// failingCodec can't encode anything
+type failingCodec struct{ jsonCodec }
+
+func (failingCodec) Name() string { return "failing" }
+
+func (failingCodec) Marshal(v interface{}) ([]byte, error) {
+	return nil, errors.New("can't encode")
+}
+
+// TestSendSkipsClientsThatCantEncode checks that a codec failing to encode an
+// event only costs its own clients the event
+func TestSendSkipsClientsThatCantEncode(t *testing.T) {
+	hub := newTestHub(t, 8)
+	clients := map[*Client]bool{}
+	for i := 0; i < 8; i++ {
+		codec := JSONCodec
+		if i%2 == 0 {
+			codec = failingCodec{}
+		}
+		clients[&Client{queue: newSendQueue(8), codec: codec, UserID: uuid.New()}] = true
+	}
+
+	event := &model.WSEvent{Type: model.WSEventOnline, Payload: model.OnlineEvent{UserID: uuid.New()}}
+	hub.mu.RLock()
+	sent := hub.sendToClients(clients, event, newEncodings(event))
+	hub.mu.RUnlock()
+
+	if sent != 4 {
+		t.Errorf("sent to %d clients, want 4", sent)
+	}
+	for client := range clients {
+		events, _ := client.queue.drain()
+		want := 1
+		if client.codec.Name() == "failing" {
+			want = 0
+		}
+		if len(events) != want {
+			t.Errorf("%s client got %d events, want %d", client.codec.Name(), len(events), want)
+		}
+	}
+}
+
+func connections(hub *Hub) int {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	n := 0
+	for _, clients := range hub.clients {
+		n += len(clients)
+	}
+	return n
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,60 @@
+package ws
+
+import (
+	"time"
+
+	"github.com/quocanhngo/gotalk/internal/model"
+)
+
+// Keepalive configures how the server finds dead connections. It pings every
+// PingInterval; a connection that sent nothing, pong included, for a whole
+// interval gets a connection_unstable warning, and one silent for PongTimeout
+// is closed. Mobile clients that switch networks leave half-open connections
+// behind that only this catches.
+type Keepalive struct {
+	PingInterval   time.Duration
+	PongTimeout    time.Duration // must be longer than PingInterval
+	MaxMessageSize int64         // bytes; larger inbound messages close the connection
+}
+
+// DefaultKeepalive is used until UseKeepalive is called
+var DefaultKeepalive = Keepalive{
+	PingInterval:   20 * time.Second,
+	PongTimeout:    45 * time.Second,
+	MaxMessageSize: 512 * 1024,
+}
+
+// UseKeepalive sets the ping/pong timing and read limit for new connections
+func (h *Hub) UseKeepalive(keepalive Keepalive) {
+	h.keepalive = keepalive
+}
+
+// alive records inbound traffic and pushes the connection's deadline back
+func (c *Client) alive() {
+	now := time.Now()
+	c.lastSeen.Store(now.UnixNano())
+	c.unstable.Store(false)
+	c.conn.SetReadDeadline(now.Add(c.hub.keepalive.PongTimeout))
+}
+
+// checkStable warns the client, once per silence, that its connection looks
+// dead and will be closed. Called by the write pump, the connection's only writer.
+func (c *Client) checkStable(now time.Time) error {
+	silent := now.Sub(time.Unix(0, c.lastSeen.Load()))
+	if silent < c.hub.keepalive.PingInterval || c.unstable.Swap(true) {
+		return nil
+	}
+
+	data, err := c.codec.Marshal(&model.WSEvent{
+		Type: model.WSEventConnectionUnstable,
+		Payload: model.ConnectionUnstableEvent{
+			SilentSeconds:   int(silent / time.Second),
+			ClosesInSeconds: int((c.hub.keepalive.PongTimeout - silent) / time.Second),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	c.hub.unstableWarnings.Add(1)
+	return c.write([][]byte{data})
+}
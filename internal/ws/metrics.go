@@ -0,0 +1,163 @@
+package ws
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quocanhngo/gotalk/internal/model"
+)
+
+// Histogram bounds: local connections reached per delivery, and Redis
+// publish latency in milliseconds
+var (
+	fanOutBounds         = []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000}
+	publishLatencyBounds = []float64{0.25, 0.5, 1, 2.5, 5, 10, 25, 50, 100, 250}
+)
+
+// histogram counts observations into fixed buckets
+type histogram struct {
+	mu     sync.Mutex
+	bounds []float64
+	counts []int64 // per bound, plus one for observations above the last
+	count  int64
+	sum    float64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{bounds: bounds, counts: make([]int64, len(bounds)+1)}
+}
+
+func (h *histogram) observe(v float64) {
+	i := 0
+	for i < len(h.bounds) && v > h.bounds[i] {
+		i++
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[i]++
+	h.count++
+	h.sum += v
+}
+
+// snapshot returns the histogram with cumulative buckets, like Prometheus
+func (h *histogram) snapshot() model.Histogram {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	snapshot := model.Histogram{Count: h.count, Sum: h.sum, Buckets: make([]model.HistogramBucket, 0, len(h.counts))}
+	var cumulative int64
+	for i, n := range h.counts {
+		cumulative += n
+		le := "+Inf"
+		if i < len(h.bounds) {
+			le = strconv.FormatFloat(h.bounds[i], 'f', -1, 64)
+		}
+		snapshot.Buckets = append(snapshot.Buckets, model.HistogramBucket{Le: le, Count: cumulative})
+	}
+	return snapshot
+}
+
+// eventMetrics instruments one event type
+type eventMetrics struct {
+	published atomic.Int64
+	delivered atomic.Int64
+	dropped   atomic.Int64
+	invalid   atomic.Int64
+	fanOut    *histogram
+}
+
+// hubMetrics instruments the hub per event type since startup
+type hubMetrics struct {
+	mu     sync.RWMutex
+	events map[string]*eventMetrics
+
+	publishErrors  atomic.Int64
+	publishLatency *histogram
+}
+
+func newHubMetrics() *hubMetrics {
+	return &hubMetrics{
+		events:         make(map[string]*eventMetrics),
+		publishLatency: newHistogram(publishLatencyBounds),
+	}
+}
+
+// event returns the metrics of an event type, creating them on first use
+func (m *hubMetrics) event(eventType string) *eventMetrics {
+	m.mu.RLock()
+	e, ok := m.events[eventType]
+	m.mu.RUnlock()
+	if ok {
+		return e
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.events[eventType]; ok {
+		return e
+	}
+	e = &eventMetrics{fanOut: newHistogram(fanOutBounds)}
+	m.events[eventType] = e
+	return e
+}
+
+// published records a publish to Redis and how long it took
+func (m *hubMetrics) published(eventType string, took time.Duration, err error) {
+	m.publishLatency.observe(float64(took) / float64(time.Millisecond))
+	if err != nil {
+		m.publishErrors.Add(1)
+		return
+	}
+	m.event(eventType).published.Add(1)
+}
+
+// queued records the outcome of queueing an event for one connection
+func (m *hubMetrics) queued(eventType string, result pushResult) {
+	switch result {
+	case pushQueued:
+		m.event(eventType).delivered.Add(1)
+	case pushDropped, pushOverflow:
+		m.event(eventType).dropped.Add(1)
+	}
+}
+
+// fannedOut records how many local connections one delivery reached
+func (m *hubMetrics) fannedOut(eventType string, connections int) {
+	m.event(eventType).fanOut.observe(float64(connections))
+}
+
+// snapshot returns the per-event-type stats
+func (m *hubMetrics) snapshot() map[string]model.WSEventStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	events := make(map[string]model.WSEventStats, len(m.events))
+	for eventType, e := range m.events {
+		events[eventType] = model.WSEventStats{
+			Published: e.published.Load(),
+			Delivered: e.delivered.Load(),
+			Dropped:   e.dropped.Load(),
+			Invalid:   e.invalid.Load(),
+			FanOut:    e.fanOut.snapshot(),
+		}
+	}
+	return events
+}
+
+// publishedType names the event type of something published on the hub's
+// Redis channel
+func publishedType(targeted *TargetedEvent) string {
+	switch {
+	case targeted.Event != nil:
+		return targeted.Event.Type
+	case targeted.Typing != nil:
+		return model.WSEventTypingSummary
+	case targeted.Presence != nil && targeted.Presence.Event != nil:
+		return targeted.Presence.Event.Type
+	case targeted.ForceLogout != nil:
+		return model.WSEventDisconnect
+	}
+	return "other"
+}
@@ -0,0 +1,152 @@
+package ws
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cross-instance presence: each instance keeps a Redis SET of the users it has
+// connections for, plus a heartbeat key. When an instance dies its heartbeat
+// expires and its set is discarded, so crashed instances don't leave users online.
+const (
+	presenceInstancesKey    = "gotalk:presence:instances" // SET of instance IDs
+	presenceUsersKeyPrefix  = "gotalk:presence:users:"    // SET of user IDs connected to an instance
+	presenceAliveKeyPrefix  = "gotalk:presence:alive:"    // heartbeat key per instance
+	presenceHeartbeatPeriod = 10 * time.Second
+	presenceHeartbeatTTL    = 30 * time.Second
+)
+
+func (h *Hub) presenceUsersKey() string {
+	return presenceUsersKeyPrefix + h.instanceID
+}
+
+// registerPresence announces this instance, which owns no connections yet
+func (h *Hub) registerPresence(ctx context.Context) {
+	h.rdb.Del(ctx, h.presenceUsersKey())
+	h.rdb.SAdd(ctx, presenceInstancesKey, h.instanceID)
+	h.heartbeat(ctx)
+}
+
+// resyncPresence announces this instance and its connected users again after
+// a Redis outage, during which the heartbeat lapsed and presence updates failed
+func (h *Hub) resyncPresence(ctx context.Context) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	h.registerPresence(ctx)
+	if len(h.clients) == 0 {
+		return
+	}
+	userIDs := make([]interface{}, 0, len(h.clients))
+	for userID := range h.clients {
+		userIDs = append(userIDs, userID.String())
+	}
+	if err := h.rdb.SAdd(ctx, h.presenceUsersKey(), userIDs...).Err(); err != nil {
+		log.Printf("⚠️  Failed to restore presence of %d users: %v", len(userIDs), err)
+	}
+}
+
+// runPresence keeps this instance's heartbeat alive until ctx is cancelled
+func (h *Hub) runPresence(ctx context.Context) {
+	ticker := time.NewTicker(presenceHeartbeatPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Graceful shutdown: drop our presence right away instead of waiting for the TTL
+			cleanup := context.Background()
+			h.rdb.Del(cleanup, h.presenceUsersKey(), presenceAliveKeyPrefix+h.instanceID)
+			h.rdb.SRem(cleanup, presenceInstancesKey, h.instanceID)
+			return
+		case <-ticker.C:
+			h.heartbeat(ctx)
+		}
+	}
+}
+
+func (h *Hub) heartbeat(ctx context.Context) {
+	if err := h.rdb.Set(ctx, presenceAliveKeyPrefix+h.instanceID, time.Now().Unix(), presenceHeartbeatTTL).Err(); err != nil {
+		log.Printf("⚠️ Presence heartbeat failed: %v", err)
+	}
+}
+
+// markPresent records that this instance has at least one connection for the user
+func (h *Hub) markPresent(userID uuid.UUID) {
+	h.rdb.SAdd(context.Background(), h.presenceUsersKey(), userID.String())
+}
+
+// markAbsent records that this instance has no more connections for the user
+func (h *Hub) markAbsent(userID uuid.UUID) {
+	h.rdb.SRem(context.Background(), h.presenceUsersKey(), userID.String())
+}
+
+// liveInstanceKeys returns the presence sets of instances with a live heartbeat,
+// discarding the sets of instances that stopped heartbeating (crashed)
+func (h *Hub) liveInstanceKeys(ctx context.Context) ([]string, error) {
+	instances, err := h.rdb.SMembers(ctx, presenceInstancesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(instances))
+	for _, id := range instances {
+		alive, err := h.rdb.Exists(ctx, presenceAliveKeyPrefix+id).Result()
+		if err != nil {
+			return nil, err
+		}
+		if alive == 0 {
+			log.Printf("🧹 Discarding presence of dead instance %s", id)
+			h.rdb.Del(ctx, presenceUsersKeyPrefix+id)
+			h.rdb.SRem(ctx, presenceInstancesKey, id)
+			continue
+		}
+		keys = append(keys, presenceUsersKeyPrefix+id)
+	}
+	return keys, nil
+}
+
+// PresentUserIDs returns users connected to any live instance
+func (h *Hub) PresentUserIDs(ctx context.Context) (map[uuid.UUID]bool, error) {
+	keys, err := h.liveInstanceKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	present := make(map[uuid.UUID]bool)
+	if len(keys) == 0 {
+		return present, nil
+	}
+
+	members, err := h.rdb.SUnion(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		if id, err := uuid.Parse(m); err == nil {
+			present[id] = true
+		}
+	}
+	return present, nil
+}
+
+// IsUserPresent checks whether the user is connected to any live instance
+func (h *Hub) IsUserPresent(ctx context.Context, userID uuid.UUID) (bool, error) {
+	keys, err := h.liveInstanceKeys(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, key := range keys {
+		ok, err := h.rdb.SIsMember(ctx, key, userID.String()).Result()
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,155 @@
+package ws
+
+import (
+	"sync"
+
+	"github.com/quocanhngo/gotalk/internal/model"
+)
+
+// DefaultQueueSize is the per-client outbound queue length used when none is configured
+const DefaultQueueSize = 256
+
+// Policy decides what happens to an event when a client's outbound queue is full
+type Policy int
+
+const (
+	// PolicyNeverDrop keeps the event; if nothing droppable can make room the
+	// client is disconnected so it resyncs on reconnect instead of silently
+	// missing it
+	PolicyNeverDrop Policy = iota
+	// PolicyDropOldest makes room by discarding the oldest droppable event;
+	// for ephemeral state where only the latest value matters
+	PolicyDropOldest
+)
+
+// eventPolicies lists the ephemeral events; every other event is PolicyNeverDrop
+var eventPolicies = map[string]Policy{
+	model.WSEventTyping:        PolicyDropOldest,
+	model.WSEventStopTyping:    PolicyDropOldest,
+	model.WSEventTypingSummary: PolicyDropOldest,
+	model.WSEventOnline:        PolicyDropOldest,
+	model.WSEventOffline:       PolicyDropOldest,
+	model.WSEventStatusChanged: PolicyDropOldest,
+}
+
+// policyFor returns the overflow policy for an event type
+func policyFor(eventType string) Policy {
+	return eventPolicies[eventType]
+}
+
+type queuedEvent struct {
+	data   []byte
+	policy Policy
+}
+
+// sendQueue is a client's bounded outbound queue. The hub pushes without
+// blocking and the client's write pump drains it.
+type sendQueue struct {
+	mu     sync.Mutex
+	events []queuedEvent
+	size   int
+	closed bool
+
+	// ready has a value whenever there's something to write or the queue was closed
+	ready chan struct{}
+}
+
+func newSendQueue(size int) *sendQueue {
+	return &sendQueue{
+		events: make([]queuedEvent, 0, size),
+		size:   size,
+		ready:  make(chan struct{}, 1),
+	}
+}
+
+// pushResult tells the hub what a push did
+type pushResult int
+
+const (
+	pushQueued   pushResult = iota
+	pushDropped             // the event or an older ephemeral one was discarded
+	pushOverflow            // a never-drop event didn't fit; the client must be disconnected
+	pushClosed              // the queue was closed; the event was ignored
+)
+
+// push queues data, applying the policy when the queue is full
+func (q *sendQueue) push(data []byte, policy Policy) pushResult {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return pushClosed
+	}
+
+	result := pushQueued
+	if len(q.events) >= q.size {
+		if !q.evictOldestDroppable() {
+			if policy == PolicyDropOldest {
+				// The queue is all chat messages; the ephemeral event is the one to go
+				return pushDropped
+			}
+			return pushOverflow
+		}
+		result = pushDropped
+	}
+
+	q.events = append(q.events, queuedEvent{data: data, policy: policy})
+	q.signal()
+	return result
+}
+
+// evictOldestDroppable removes the oldest PolicyDropOldest event, if any
+func (q *sendQueue) evictOldestDroppable() bool {
+	for i, e := range q.events {
+		if e.policy == PolicyDropOldest {
+			q.events = append(q.events[:i], q.events[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// drain takes every queued event. closed is true once the queue was closed
+// and nothing is left to write.
+func (q *sendQueue) drain() (events [][]byte, closed bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.events) == 0 {
+		return nil, q.closed
+	}
+	if q.closed {
+		q.signal() // the close is still to be written
+	}
+	events = make([][]byte, len(q.events))
+	for i, e := range q.events {
+		events[i] = e.data
+	}
+	q.events = q.events[:0]
+	return events, false
+}
+
+// close stops accepting events; the write pump flushes what's queued and
+// closes the connection. Safe to call more than once.
+func (q *sendQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		q.signal()
+	}
+}
+
+// depth returns the number of queued events
+func (q *sendQueue) depth() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.events)
+}
+
+func (q *sendQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,307 @@
+package ws
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+)
+
+// eventSchemaJSON is the registry of WebSocket events, one JSON Schema per
+// event type, written by cmd/genapi from the WSEvent constants and their
+// payload types
+//
+//go:embed events.schema.json
+var eventSchemaJSON []byte
+
+// jsonSchema is the subset of JSON Schema the registry uses
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 schemaTypes            `json:"type"`
+	Const                *string                `json:"const"`
+	Format               string                 `json:"format"`
+	Enum                 []interface{}          `json:"enum"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties json.RawMessage        `json:"additionalProperties"` // false, or the values' schema
+	Items                *jsonSchema            `json:"items"`
+	AnyOf                []*jsonSchema          `json:"anyOf"`
+	OneOf                []*jsonSchema          `json:"oneOf"`
+	Defs                 map[string]*jsonSchema `json:"$defs"`
+
+	closed bool        // additionalProperties is false
+	values *jsonSchema // additionalProperties is a schema
+}
+
+// schemaTypes is a schema's "type": one type or a list of them
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return json.Unmarshal(data, (*[]string)(t))
+	}
+	var one string
+	if err := json.Unmarshal(data, &one); err != nil {
+		return err
+	}
+	*t = schemaTypes{one}
+	return nil
+}
+
+// EventValidator checks outgoing events against the registry, so a payload
+// that drifted from its documented shape shows up in development instead of
+// in clients
+type EventValidator struct {
+	defs   map[string]*jsonSchema
+	events map[string]*jsonSchema // event type -> schema of its frame
+
+	reported sync.Map // violations already logged
+}
+
+// NewEventValidator loads the registry
+func NewEventValidator() (*EventValidator, error) {
+	var root jsonSchema
+	if err := json.Unmarshal(eventSchemaJSON, &root); err != nil {
+		return nil, fmt.Errorf("events.schema.json: %w", err)
+	}
+	v := &EventValidator{defs: root.Defs, events: make(map[string]*jsonSchema, len(root.OneOf))}
+	for _, def := range root.Defs {
+		if err := def.prepare(); err != nil {
+			return nil, fmt.Errorf("events.schema.json: %w", err)
+		}
+	}
+	for _, one := range root.OneOf {
+		frame, err := v.resolve(one)
+		if err != nil {
+			return nil, fmt.Errorf("events.schema.json: %w", err)
+		}
+		eventType := frame.Properties["type"]
+		if eventType == nil || eventType.Const == nil {
+			return nil, fmt.Errorf("events.schema.json: %s has no constant type", one.Ref)
+		}
+		v.events[*eventType.Const] = frame
+	}
+	return v, nil
+}
+
+// prepare decodes additionalProperties, here and in nested schemas
+func (s *jsonSchema) prepare() error {
+	switch raw := bytes.TrimSpace(s.AdditionalProperties); {
+	case len(raw) == 0, bytes.Equal(raw, []byte("true")):
+	case bytes.Equal(raw, []byte("false")):
+		s.closed = true
+	default:
+		s.values = &jsonSchema{}
+		if err := json.Unmarshal(raw, s.values); err != nil {
+			return err
+		}
+	}
+
+	nested := []*jsonSchema{s.Items, s.values}
+	for _, p := range s.Properties {
+		nested = append(nested, p)
+	}
+	nested = append(nested, s.AnyOf...)
+	nested = append(nested, s.OneOf...)
+	for _, n := range nested {
+		if n == nil {
+			continue
+		}
+		if err := n.prepare(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate checks an event as it will be encoded
+func (v *EventValidator) Validate(event *model.WSEvent) error {
+	frame, ok := v.events[event.Type]
+	if !ok {
+		return fmt.Errorf("%s is not a documented event type", event.Type)
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+	return v.validate(frame, doc, "")
+}
+
+// UseEventValidator checks every outgoing event against the registry,
+// logging and counting the ones that don't match. Meant for development: it
+// encodes each event once more.
+func (h *Hub) UseEventValidator(validator *EventValidator) {
+	h.validator = validator
+}
+
+// checkEvent validates an outgoing event when a validator is set
+func (h *Hub) checkEvent(event *model.WSEvent) {
+	if h.validator == nil {
+		return
+	}
+	if err := h.validator.Validate(event); err != nil {
+		h.metrics.event(event.Type).invalid.Add(1)
+		h.validator.report(event, err)
+	}
+}
+
+// report logs a violation once, so a frequent event doesn't flood the log
+func (v *EventValidator) report(event *model.WSEvent, err error) {
+	if _, logged := v.reported.LoadOrStore(event.Type+": "+err.Error(), true); !logged {
+		log.Printf("⚠️  WS event %s doesn't match events.schema.json: %v", event.Type, err)
+	}
+}
+
+func (v *EventValidator) resolve(s *jsonSchema) (*jsonSchema, error) {
+	for s.Ref != "" {
+		def, ok := v.defs[strings.TrimPrefix(s.Ref, "#/$defs/")]
+		if !ok {
+			return nil, fmt.Errorf("unknown $ref %s", s.Ref)
+		}
+		s = def
+	}
+	return s, nil
+}
+
+// validate checks a decoded JSON value against a schema; path locates it in the frame
+func (v *EventValidator) validate(s *jsonSchema, value interface{}, path string) error {
+	s, err := v.resolve(s)
+	if err != nil {
+		return err
+	}
+
+	if len(s.AnyOf) > 0 || len(s.OneOf) > 0 {
+		var errs []string
+		for _, alternative := range append(slices.Clone(s.AnyOf), s.OneOf...) {
+			err := v.validate(alternative, value, path)
+			if err == nil {
+				return nil
+			}
+			// "or null" alternatives only explain a failure when the value is null
+			if value == nil || !slices.Equal(alternative.Type, schemaTypes{"null"}) {
+				errs = append(errs, err.Error())
+			}
+		}
+		if len(errs) == 1 {
+			return errors.New(errs[0])
+		}
+		return fmt.Errorf("%s matches none of: %s", where(path), strings.Join(errs, "; "))
+	}
+
+	if len(s.Type) > 0 && !hasType(s.Type, value) {
+		return fmt.Errorf("%s is %s, want %s", where(path), jsonType(value), strings.Join(s.Type, " or "))
+	}
+	if s.Const != nil && value != *s.Const {
+		return fmt.Errorf("%s is %v, want %q", where(path), value, *s.Const)
+	}
+	// Go encodes unset enum fields as ""
+	if len(s.Enum) > 0 && value != "" && !slices.Contains(s.Enum, value) {
+		return fmt.Errorf("%s is %v, want one of %v", where(path), value, s.Enum)
+	}
+
+	switch value := value.(type) {
+	case string:
+		switch s.Format {
+		case "uuid":
+			if _, err := uuid.Parse(value); err != nil {
+				return fmt.Errorf("%s is %q, want a UUID", where(path), value)
+			}
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339Nano, value); err != nil {
+				return fmt.Errorf("%s is %q, want an RFC 3339 time", where(path), value)
+			}
+		}
+
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range value {
+				if err := v.validate(s.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := value[name]; !ok {
+				return fmt.Errorf("%s lacks %s", where(path), name)
+			}
+		}
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			prop, ok := s.Properties[key]
+			switch {
+			case ok:
+			case s.values != nil:
+				prop = s.values
+			case s.closed:
+				return fmt.Errorf("%s is not documented", child)
+			default:
+				continue
+			}
+			if err := v.validate(prop, value[key], child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// hasType reports whether a decoded value is of one of the types; integers are numbers too
+func hasType(types schemaTypes, value interface{}) bool {
+	typ := jsonType(value)
+	return slices.Contains(types, typ) || typ == "integer" && slices.Contains(types, "number")
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func where(path string) string {
+	if path == "" {
+		return "the event"
+	}
+	return path
+}
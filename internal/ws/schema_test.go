@@ -0,0 +1,152 @@
+package ws
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+)
+
+// sampleEvents builds an event of every documented type the way the server
+// sends it. A new event type needs a sample here.
+func sampleEvents() map[string]any {
+	now := time.Now()
+	userID, convID, msgID := uuid.New(), uuid.New(), uuid.New()
+	user := model.User{ID: userID, Email: "ann@example.com", DisplayName: "Ann", CreatedAt: now, UpdatedAt: now}
+	attachment := model.MessageAttachment{
+		ID: uuid.New(), MessageID: msgID, Type: model.AttachmentTypeVideo, URL: "https://files.example.com/videos/a.mp4",
+		FileName: "a.mp4", FileSize: 1 << 20, MimeType: "video/mp4", ProcessingStatus: model.ProcessingStatusReady, CreatedAt: now,
+	}
+	message := model.Message{
+		ID: msgID, ConversationID: convID, SenderID: userID, Sender: user, Content: "hello",
+		Type: model.MessageTypeText, Attachments: []model.MessageAttachment{attachment}, CreatedAt: now, UpdatedAt: now,
+	}
+
+	return map[string]any{
+		model.WSEventNewMessage:           message,
+		model.WSEventTyping:               model.TypingEvent{ConversationID: convID, UserID: userID},
+		model.WSEventStopTyping:           model.TypingEvent{ConversationID: convID, UserID: userID},
+		model.WSEventOnline:               model.OnlineEvent{UserID: userID},
+		model.WSEventOffline:              model.OnlineEvent{UserID: userID},
+		model.WSEventMessageRead:          model.MessageReadEvent{ConversationID: convID, UserID: userID},
+		model.WSEventCallOffer:            model.CallOfferEvent{},
+		model.WSEventCallAnswer:           model.CallAnswerEvent{},
+		model.WSEventCallICE:              model.ICECandidateEvent{},
+		model.WSEventCallHangup:           model.CallHangupEvent{},
+		model.WSEventCallRenegotiate:      model.CallRenegotiateEvent{},
+		model.WSEventCallScreenShare:      model.CallScreenShareEvent{},
+		model.WSEventCallTrackState:       model.CallTrackStateEvent{},
+		model.WSEventCallChat:             model.CallChatEvent{},
+		model.WSEventCallReaction:         model.CallReactionEvent{},
+		model.WSEventAttachmentProcessed:  attachment,
+		model.WSEventNotification:         model.Notification{ID: uuid.New(), UserID: userID, CreatedAt: now},
+		model.WSEventStatusChanged:        model.StatusChangedEvent{UserID: userID},
+		model.WSEventBootstrap:            model.BootstrapEvent{},
+		model.WSEventConversationsRead:    model.ConversationsReadEvent{ConversationIDs: []uuid.UUID{convID}},
+		model.WSEventMessageDelivered:     model.MessageDeliveredEvent{ConversationID: convID, MessageID: msgID},
+		model.WSEventConversationDeleted:  model.ConversationChangeEvent{ConversationID: convID},
+		model.WSEventConversationRestored: model.ConversationChangeEvent{ConversationID: convID},
+		model.WSEventHistoryCleared:       model.HistoryClearedEvent{ConversationID: convID},
+		model.WSEventConversationFrozen:   model.ConversationFrozenEvent{ConversationID: convID},
+		model.WSEventRolesChanged:         model.RolesChangedEvent{ConversationID: convID},
+		model.WSEventMemberRoleChanged:    model.MemberRoleChangedEvent{ConversationID: convID, UserID: userID},
+		model.WSEventMemberAdded:          model.MemberAddedEvent{ConversationID: convID},
+		model.WSEventPinsChanged:          model.PinsChangedEvent{ConversationID: convID},
+		model.WSEventError:                model.ErrorResponse{Code: "invalid_request", Error: "bad event"},
+		model.WSEventTypingSummary:        model.TypingSummaryEvent{ConversationID: convID},
+		model.WSEventPresenceSubscribe:    model.PresenceSubscription{UserIDs: []uuid.UUID{userID}},
+		model.WSEventPresenceUnsubscribe:  model.PresenceSubscription{UserIDs: []uuid.UUID{userID}},
+		model.WSEventPresenceState:        model.PresenceStateEvent{},
+		model.WSEventConnectionUnstable:   model.ConnectionUnstableEvent{},
+		model.WSEventDisconnect:           model.DisconnectEvent{},
+		model.WSEventRefreshToken:         model.RefreshTokenRequest{Token: "jwt"},
+		model.WSEventTokenExpiring:        model.TokenExpiryEvent{ExpiresAt: now},
+		model.WSEventTokenRefreshed:       model.TokenExpiryEvent{ExpiresAt: now},
+		model.WSEventSetPresence:          model.SetPresenceRequest{State: "away"},
+		model.WSEventPresenceChanged:      model.PresenceResponse{},
+	}
+}
+
+func TestEventsMatchSchema(t *testing.T) {
+	validator, err := NewEventValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	samples := sampleEvents()
+
+	for _, eventType := range declaredEventTypes(t) {
+		payload, ok := samples[eventType]
+		if !ok {
+			t.Errorf("%s: no sample event in sampleEvents", eventType)
+			continue
+		}
+		if err := validator.Validate(&model.WSEvent{Type: eventType, Payload: payload}); err != nil {
+			t.Errorf("%s: %v", eventType, err)
+		}
+	}
+	for eventType := range validator.events {
+		if _, ok := samples[eventType]; !ok {
+			t.Errorf("%s is in events.schema.json but not declared in internal/model", eventType)
+		}
+	}
+}
+
+func TestValidateRejectsDrift(t *testing.T) {
+	validator, err := NewEventValidator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, event := range map[string]*model.WSEvent{
+		"unknown type":  {Type: "no_such_event", Payload: struct{}{}},
+		"wrong payload": {Type: model.WSEventTyping, Payload: map[string]any{"conversation_id": 42}},
+	} {
+		if err := validator.Validate(event); err == nil {
+			t.Errorf("%s: validated", name)
+		}
+	}
+}
+
+// declaredEventTypes reads the WSEvent constants documented with a payload
+// type, as cmd/genapi does to write the schema
+func declaredEventTypes(t *testing.T) []string {
+	t.Helper()
+	files, err := filepath.Glob("../model/*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	fset := token.NewFileSet()
+	for _, name := range files {
+		file, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			spec, ok := n.(*ast.ValueSpec)
+			if !ok || spec.Comment == nil || !strings.Contains(spec.Comment.Text(), "payload:") {
+				return true
+			}
+			for i, ident := range spec.Names {
+				if !strings.HasPrefix(ident.Name, "WSEvent") || i >= len(spec.Values) {
+					continue
+				}
+				if lit, ok := spec.Values[i].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					value, _ := strconv.Unquote(lit.Value)
+					types = append(types, value)
+				}
+			}
+			return true
+		})
+	}
+	if len(types) == 0 {
+		t.Fatal("no WSEvent constants found in internal/model")
+	}
+	return types
+}
@@ -0,0 +1,116 @@
+package ws
+
+import (
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+)
+
+// MaxPresenceSubscriptions caps how many users one connection can watch
+const MaxPresenceSubscriptions = 1000
+
+// PresenceUpdate is published once when a user goes online or offline. Each
+// instance delivers it only to its connections subscribed to the user.
+type PresenceUpdate struct {
+	UserID    uuid.UUID      `json:"user_id"`
+	Event     *model.WSEvent `json:"event"`
+	ViewerIDs []uuid.UUID    `json:"viewer_ids,omitempty"` // who may see it; empty means anyone
+}
+
+// SubscribePresence makes a connection receive the online/offline events of
+// the given users, up to MaxPresenceSubscriptions in all. It returns the
+// users the connection now watches among them.
+func (h *Hub) SubscribePresence(client *Client, userIDs []uuid.UUID) []uuid.UUID {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if client.gone {
+		return nil
+	}
+	if client.presenceSubs == nil {
+		client.presenceSubs = make(map[uuid.UUID]bool)
+	}
+	watched := make([]uuid.UUID, 0, len(userIDs))
+	for _, userID := range userIDs {
+		if !client.presenceSubs[userID] {
+			if len(client.presenceSubs) >= MaxPresenceSubscriptions {
+				continue
+			}
+			client.presenceSubs[userID] = true
+			if h.presenceSubs[userID] == nil {
+				h.presenceSubs[userID] = make(map[*Client]bool)
+			}
+			h.presenceSubs[userID][client] = true
+		}
+		watched = append(watched, userID)
+	}
+	return watched
+}
+
+// UnsubscribePresence stops a connection's online/offline events for the given users
+func (h *Hub) UnsubscribePresence(client *Client, userIDs []uuid.UUID) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, userID := range userIDs {
+		if client.presenceSubs[userID] {
+			h.unwatch(client, userID)
+		}
+	}
+}
+
+// dropPresenceSubscriptions forgets a disconnected client's subscriptions. Callers hold h.mu.
+func (h *Hub) dropPresenceSubscriptions(client *Client) {
+	for userID := range client.presenceSubs {
+		h.unwatch(client, userID)
+	}
+	client.gone = true
+}
+
+// unwatch removes one subscription. Callers hold h.mu.
+func (h *Hub) unwatch(client *Client, userID uuid.UUID) {
+	delete(client.presenceSubs, userID)
+	if subscribers, ok := h.presenceSubs[userID]; ok {
+		delete(subscribers, client)
+		if len(subscribers) == 0 {
+			delete(h.presenceSubs, userID)
+		}
+	}
+}
+
+// PublishPresence sends a user's online/offline event, on every instance, to
+// the connections subscribed to the user. viewerIDs restricts who may see it;
+// nil allows anyone.
+func (h *Hub) PublishPresence(userID uuid.UUID, event *model.WSEvent, viewerIDs []uuid.UUID) {
+	h.publishToRedis(&TargetedEvent{Presence: &PresenceUpdate{
+		UserID:    userID,
+		Event:     event,
+		ViewerIDs: viewerIDs,
+	}})
+}
+
+// sendPresenceToLocal delivers a presence update to the local subscribers allowed to see it
+func (h *Hub) sendPresenceToLocal(update *PresenceUpdate) {
+	var allowed map[uuid.UUID]bool
+	if len(update.ViewerIDs) > 0 {
+		allowed = make(map[uuid.UUID]bool, len(update.ViewerIDs))
+		for _, id := range update.ViewerIDs {
+			allowed[id] = true
+		}
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	subscribers := h.presenceSubs[update.UserID]
+	if len(subscribers) == 0 {
+		return
+	}
+	recipients := make(map[*Client]bool, len(subscribers))
+	for client := range subscribers {
+		if allowed == nil || allowed[client.UserID] {
+			recipients[client] = true
+		}
+	}
+	h.checkEvent(update.Event)
+	h.metrics.fannedOut(update.Event.Type, h.sendToClients(recipients, update.Event, newEncodings(update.Event)))
+}
@@ -0,0 +1,187 @@
+package ws
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+)
+
+// Typing in large conversations is aggregated: instead of a typing/stop_typing
+// event to every member per keystroke, each instance sends its members one
+// typing_summary per conversation at most every typingSummaryInterval.
+const (
+	// TypingSummaryThreshold is the member count above which a conversation
+	// gets typing summaries instead of individual typing events
+	TypingSummaryThreshold = 10
+
+	typingSummaryInterval = time.Second
+	typingTTL             = 6 * time.Second // typers who stop sending typing events are dropped after this
+	typingSummaryNames    = 3               // names listed in a summary; Count has the rest
+)
+
+// TypingUpdate is published, once for all members, when a member of a large
+// conversation starts or stops typing
+type TypingUpdate struct {
+	ConversationID uuid.UUID   `json:"conversation_id"`
+	UserID         uuid.UUID   `json:"user_id"`
+	Name           string      `json:"name"`
+	Typing         bool        `json:"typing"`
+	MemberIDs      []uuid.UUID `json:"member_ids"`
+}
+
+type typer struct {
+	userID  uuid.UUID
+	name    string
+	since   time.Time
+	expires time.Time
+}
+
+// conversationTyping is who is typing in one conversation, as last summarized
+type conversationTyping struct {
+	typers    map[uuid.UUID]*typer
+	memberIDs []uuid.UUID // from the latest update
+	changed   bool        // a summary is due
+}
+
+// typingState holds the typing state of every large conversation with typers;
+// each instance tracks all of them and summarizes to its own clients
+type typingState struct {
+	mu            sync.Mutex
+	conversations map[uuid.UUID]*conversationTyping
+}
+
+func newTypingState() *typingState {
+	return &typingState{conversations: make(map[uuid.UUID]*conversationTyping)}
+}
+
+// UpdateTyping records that a member of a large conversation started or
+// stopped typing, on every instance
+func (h *Hub) UpdateTyping(convID uuid.UUID, memberIDs []uuid.UUID, userID uuid.UUID, name string, typing bool) {
+	h.publishToRedis(&TargetedEvent{Typing: &TypingUpdate{
+		ConversationID: convID,
+		UserID:         userID,
+		Name:           name,
+		Typing:         typing,
+		MemberIDs:      memberIDs,
+	}})
+}
+
+// apply records a typing update received from any instance
+func (t *typingState) apply(u *TypingUpdate, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	conv, ok := t.conversations[u.ConversationID]
+	if !ok {
+		if !u.Typing {
+			return
+		}
+		conv = &conversationTyping{typers: make(map[uuid.UUID]*typer)}
+		t.conversations[u.ConversationID] = conv
+	}
+	conv.memberIDs = u.MemberIDs
+
+	existing, ok := conv.typers[u.UserID]
+	switch {
+	case u.Typing && ok:
+		existing.expires = now.Add(typingTTL) // still typing: no news
+	case u.Typing:
+		conv.typers[u.UserID] = &typer{userID: u.UserID, name: u.Name, since: now, expires: now.Add(typingTTL)}
+		conv.changed = true
+	case ok:
+		delete(conv.typers, u.UserID)
+		conv.changed = true
+	}
+}
+
+// typingSummary is a due summary of one conversation
+type typingSummary struct {
+	conversationID uuid.UUID
+	memberIDs      []uuid.UUID
+	typers         []*typer // in the order they started typing
+}
+
+// due drops expired typers and returns the conversations whose typers changed
+// since their last summary. Conversations nobody types in anymore are forgotten
+// after their final, empty summary.
+func (t *typingState) due(now time.Time) []typingSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var summaries []typingSummary
+	for convID, conv := range t.conversations {
+		for userID, ty := range conv.typers {
+			if now.After(ty.expires) {
+				delete(conv.typers, userID)
+				conv.changed = true
+			}
+		}
+		if !conv.changed {
+			continue
+		}
+		conv.changed = false
+
+		typers := make([]*typer, 0, len(conv.typers))
+		for _, ty := range conv.typers {
+			typers = append(typers, ty)
+		}
+		slices.SortFunc(typers, func(a, b *typer) int { return a.since.Compare(b.since) })
+		summaries = append(summaries, typingSummary{conversationID: convID, memberIDs: conv.memberIDs, typers: typers})
+		if len(conv.typers) == 0 {
+			delete(t.conversations, convID)
+		}
+	}
+	return summaries
+}
+
+// runTypingSummaries sends the due typing summaries to local clients every
+// typingSummaryInterval until ctx is cancelled
+func (h *Hub) runTypingSummaries(ctx context.Context) {
+	ticker := time.NewTicker(typingSummaryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, summary := range h.typing.due(now) {
+				h.sendTypingSummary(summary)
+			}
+		}
+	}
+}
+
+// sendTypingSummary sends a summary to the conversation's members connected
+// here. Typers get one that leaves themselves out.
+func (h *Hub) sendTypingSummary(summary typingSummary) {
+	everyone := newTypingSummaryEvent(summary, uuid.Nil)
+	for _, memberID := range summary.memberIDs {
+		if !h.IsUserOnline(memberID) {
+			continue
+		}
+		event := everyone
+		if slices.ContainsFunc(summary.typers, func(ty *typer) bool { return ty.userID == memberID }) {
+			event = newTypingSummaryEvent(summary, memberID)
+		}
+		h.sendToLocalUser(memberID, event)
+	}
+}
+
+func newTypingSummaryEvent(summary typingSummary, viewerID uuid.UUID) *model.WSEvent {
+	payload := model.TypingSummaryEvent{ConversationID: summary.conversationID, Names: []string{}}
+	for _, ty := range summary.typers {
+		if ty.userID == viewerID {
+			continue
+		}
+		if len(payload.Names) < typingSummaryNames {
+			payload.Names = append(payload.Names, ty.name)
+		}
+		payload.Count++
+	}
+	return &model.WSEvent{Type: model.WSEventTypingSummary, Payload: payload}
+}
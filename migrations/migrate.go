@@ -4,6 +4,7 @@ import (
 	"embed"
 	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 
 	"github.com/golang-migrate/migrate/v4"
@@ -16,14 +17,9 @@ var migrationFiles embed.FS
 
 // Run executes all pending up migrations
 func Run(dbURL string) error {
-	source, err := iofs.New(migrationFiles, "sql")
-	if err != nil {
-		return fmt.Errorf("failed to read migration files: %w", err)
-	}
-
-	m, err := migrate.NewWithSourceInstance("iofs", source, dbURL)
+	m, err := newMigrator(dbURL)
 	if err != nil {
-		return fmt.Errorf("failed to create migrator: %w", err)
+		return err
 	}
 	defer m.Close()
 
@@ -42,14 +38,9 @@ func Run(dbURL string) error {
 
 // Rollback reverts the last migration
 func Rollback(dbURL string) error {
-	source, err := iofs.New(migrationFiles, "sql")
-	if err != nil {
-		return fmt.Errorf("failed to read migration files: %w", err)
-	}
-
-	m, err := migrate.NewWithSourceInstance("iofs", source, dbURL)
+	m, err := newMigrator(dbURL)
 	if err != nil {
-		return fmt.Errorf("failed to create migrator: %w", err)
+		return err
 	}
 	defer m.Close()
 
@@ -61,6 +52,114 @@ func Rollback(dbURL string) error {
 	return nil
 }
 
+// Force records version as applied without running any migration and clears
+// the dirty flag, to recover after a migration failed halfway and its changes
+// were fixed or reverted by hand. Use -1 to record that nothing is applied.
+func Force(dbURL string, version int) error {
+	latest, err := LatestVersion()
+	if err != nil {
+		return err
+	}
+	if version < -1 || version > int(latest) {
+		return fmt.Errorf("version must be between -1 and %d, got %d", latest, version)
+	}
+
+	m, err := newMigrator(dbURL)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Force(version); err != nil {
+		return fmt.Errorf("force failed: %w", err)
+	}
+
+	log.Printf("✅ Migration version forced to %d", version)
+	return nil
+}
+
+// MigrationStatus describes the database's migration state
+type MigrationStatus struct {
+	Version uint // applied version, 0 if none
+	Dirty   bool // the last migration failed halfway and needs fixing by hand
+	Latest  uint // newest version embedded in this binary
+}
+
+// Pending reports whether the binary has migrations the database hasn't applied
+func (s MigrationStatus) Pending() bool {
+	return s.Version < s.Latest
+}
+
+// Diverged returns why the database shouldn't be migrated automatically: the
+// last migration failed halfway, or the database is newer than this binary
+// (e.g. after rolling back a deploy). Nil when it's safe to apply pending ones.
+func (s MigrationStatus) Diverged() error {
+	if s.Dirty {
+		return fmt.Errorf("migration %d failed halfway and left the database dirty; fix it by hand, then force the version", s.Version)
+	}
+	if s.Version > s.Latest {
+		return fmt.Errorf("database is at version %d but this binary only knows migrations up to %d", s.Version, s.Latest)
+	}
+	return nil
+}
+
+// Status returns the database's migration version and the latest available one
+func Status(dbURL string) (*MigrationStatus, error) {
+	latest, err := LatestVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := newMigrator(dbURL)
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return nil, fmt.Errorf("failed to read migration version: %w", err)
+	}
+	return &MigrationStatus{Version: version, Dirty: dirty, Latest: latest}, nil
+}
+
+// LatestVersion returns the newest migration version embedded in the binary
+func LatestVersion() (uint, error) {
+	source, err := iofs.New(migrationFiles, "sql")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migration files: %w", err)
+	}
+	defer source.Close()
+
+	version, err := source.First()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migration files: %w", err)
+	}
+	for {
+		next, err := source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read migration files: %w", err)
+		}
+		version = next
+	}
+}
+
+func newMigrator(dbURL string) (*migrate.Migrate, error) {
+	source, err := iofs.New(migrationFiles, "sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration files: %w", err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", source, dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migrator: %w", err)
+	}
+	return m, nil
+}
+
 // BuildDBURL constructs a PostgreSQL connection URL from components
 func BuildDBURL(host, port, user, password, dbName, sslMode string) string {
 	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
@@ -0,0 +1,3 @@
+ALTER TABLE message_attachments DROP COLUMN IF EXISTS processing_status;
+ALTER TABLE message_attachments DROP COLUMN IF EXISTS poster_url;
+ALTER TABLE message_attachments DROP COLUMN IF EXISTS processed_url;
@@ -0,0 +1,3 @@
+ALTER TABLE message_attachments ADD COLUMN IF NOT EXISTS processed_url VARCHAR(1000);
+ALTER TABLE message_attachments ADD COLUMN IF NOT EXISTS poster_url VARCHAR(1000);
+ALTER TABLE message_attachments ADD COLUMN IF NOT EXISTS processing_status VARCHAR(20) DEFAULT '';
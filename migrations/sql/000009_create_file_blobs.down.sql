@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_message_attachments_blob_hash;
+ALTER TABLE message_attachments DROP COLUMN IF EXISTS blob_hash;
+DROP TABLE IF EXISTS file_blobs;
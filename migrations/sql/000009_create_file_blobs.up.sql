@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS file_blobs (
+    hash CHAR(64) PRIMARY KEY,
+    object_key VARCHAR(500) NOT NULL,
+    url VARCHAR(1000) NOT NULL UNIQUE,
+    mime_type VARCHAR(100),
+    size BIGINT DEFAULT 0,
+    ref_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_file_blobs_unreferenced ON file_blobs(updated_at) WHERE ref_count = 0;
+
+ALTER TABLE message_attachments ADD COLUMN IF NOT EXISTS blob_hash CHAR(64) REFERENCES file_blobs(hash) ON DELETE SET NULL;
+CREATE INDEX idx_message_attachments_blob_hash ON message_attachments(blob_hash);
@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_digest_enabled BOOLEAN DEFAULT FALSE;
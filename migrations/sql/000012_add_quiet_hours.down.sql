@@ -0,0 +1,6 @@
+ALTER TABLE users DROP COLUMN IF EXISTS timezone;
+ALTER TABLE users DROP COLUMN IF EXISTS quiet_hours_allow_mentions;
+ALTER TABLE users DROP COLUMN IF EXISTS quiet_hours_summary;
+ALTER TABLE users DROP COLUMN IF EXISTS quiet_hours_end;
+ALTER TABLE users DROP COLUMN IF EXISTS quiet_hours_start;
+ALTER TABLE users DROP COLUMN IF EXISTS quiet_hours_enabled;
@@ -0,0 +1,6 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS quiet_hours_enabled BOOLEAN DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS quiet_hours_start VARCHAR(5) DEFAULT '22:00';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS quiet_hours_end VARCHAR(5) DEFAULT '07:00';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS quiet_hours_summary BOOLEAN DEFAULT TRUE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS quiet_hours_allow_mentions BOOLEAN DEFAULT TRUE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) DEFAULT 'UTC';
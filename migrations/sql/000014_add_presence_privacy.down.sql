@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS online_privacy;
+ALTER TABLE users DROP COLUMN IF EXISTS last_seen_privacy;
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_seen_privacy VARCHAR(20) DEFAULT 'everyone';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS online_privacy VARCHAR(20) DEFAULT 'everyone';
@@ -0,0 +1,24 @@
+CREATE EXTENSION IF NOT EXISTS unaccent;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS handle VARCHAR(30);
+
+-- Backfill handles from names (accents stripped, only a-z 0-9 _). Duplicates, too-short
+-- and reserved handles get a suffix from the user ID so every handle is unique.
+WITH bases AS (
+    SELECT id, created_at,
+           COALESCE(NULLIF(LEFT(regexp_replace(lower(unaccent(name)), '[^a-z0-9_]', '', 'g'), 20), ''), 'user') AS base
+    FROM users
+    WHERE handle IS NULL
+), ranked AS (
+    SELECT id, base, ROW_NUMBER() OVER (PARTITION BY base ORDER BY created_at, id) AS rn
+    FROM bases
+)
+UPDATE users u
+SET handle = CASE
+    WHEN r.rn = 1 AND length(r.base) >= 3 AND r.base NOT IN ('all', 'everyone', 'here', 'admin', 'support', 'gotalk') THEN r.base
+    ELSE r.base || '_' || substr(replace(u.id::text, '-', ''), 1, 6)
+END
+FROM ranked r
+WHERE u.id = r.id;
+
+ALTER TABLE users ALTER COLUMN handle SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_handle ON users(handle);
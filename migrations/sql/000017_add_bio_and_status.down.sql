@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_users_status_expires_at;
+ALTER TABLE users DROP COLUMN IF EXISTS status_expires_at;
+ALTER TABLE users DROP COLUMN IF EXISTS status_emoji;
+ALTER TABLE users DROP COLUMN IF EXISTS status_message;
+ALTER TABLE users DROP COLUMN IF EXISTS bio;
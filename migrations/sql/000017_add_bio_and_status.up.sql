@@ -0,0 +1,5 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS bio VARCHAR(500) DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_message VARCHAR(100) DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_emoji VARCHAR(16) DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS status_expires_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_users_status_expires_at ON users(status_expires_at) WHERE status_expires_at IS NOT NULL;
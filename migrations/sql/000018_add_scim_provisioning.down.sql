@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_conversations_provisioned;
+ALTER TABLE conversations DROP COLUMN IF EXISTS provisioned;
+ALTER TABLE conversations DROP COLUMN IF EXISTS external_id;
+DROP INDEX IF EXISTS idx_users_external_id;
+ALTER TABLE users DROP COLUMN IF EXISTS deactivated_at;
+ALTER TABLE users DROP COLUMN IF EXISTS external_id;
@@ -0,0 +1,9 @@
+-- SCIM directory sync: identity providers reference users and groups by their own externalId,
+-- deactivate users instead of deleting them, and manage group conversations they provisioned
+ALTER TABLE users ADD COLUMN IF NOT EXISTS external_id VARCHAR(255);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_external_id ON users(external_id) WHERE external_id IS NOT NULL;
+
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS external_id VARCHAR(255);
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS provisioned BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX IF NOT EXISTS idx_conversations_provisioned ON conversations(provisioned) WHERE provisioned;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_users_sso_subject;
+ALTER TABLE users DROP COLUMN IF EXISTS sso_subject;
+-- Postgres can't drop an enum value; move SSO-only accounts back to email (password reset)
+UPDATE users SET auth_provider = 'email' WHERE auth_provider = 'sso';
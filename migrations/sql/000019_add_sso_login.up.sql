@@ -0,0 +1,4 @@
+-- Enterprise SSO (OpenID Connect): accounts are linked to the provider's subject identifier
+ALTER TYPE auth_provider ADD VALUE IF NOT EXISTS 'sso';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS sso_subject VARCHAR(255);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_sso_subject ON users(sso_subject) WHERE sso_subject IS NOT NULL;
@@ -0,0 +1,13 @@
+-- Transactional outbox: fan-out work (WebSocket broadcast, push) written in the same
+-- transaction as the message and published by a worker with at-least-once delivery
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    type VARCHAR(50) NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    available_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_outbox_events_available ON outbox_events(available_at);
@@ -0,0 +1,3 @@
+ALTER TABLE conversations DROP COLUMN IF EXISTS imported_from;
+ALTER TABLE messages DROP COLUMN IF EXISTS imported_sender;
+ALTER TABLE messages DROP COLUMN IF EXISTS imported;
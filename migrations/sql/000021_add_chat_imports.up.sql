@@ -0,0 +1,6 @@
+-- Chat history imported from other apps (WhatsApp, Telegram): messages keep their original
+-- timestamps and the sender's name from the export, since those people may have no GoTalk account
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS imported BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS imported_sender VARCHAR(100);
+
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS imported_from VARCHAR(20);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS matrix_room_links;
+DROP INDEX IF EXISTS idx_users_matrix_id;
+ALTER TABLE users DROP COLUMN IF EXISTS matrix_id;
+-- Postgres can't drop an enum value; bridged Matrix users become deactivated email accounts
+UPDATE users SET auth_provider = 'email', deactivated_at = COALESCE(deactivated_at, NOW()) WHERE auth_provider = 'matrix';
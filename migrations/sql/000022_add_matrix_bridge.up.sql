@@ -0,0 +1,11 @@
+-- Matrix bridge: conversations linked to Matrix rooms, and local stand-ins for the
+-- remote Matrix users who post in them (they can't sign in)
+ALTER TYPE auth_provider ADD VALUE IF NOT EXISTS 'matrix';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS matrix_id VARCHAR(255);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_matrix_id ON users(matrix_id) WHERE matrix_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS matrix_room_links (
+    conversation_id UUID PRIMARY KEY REFERENCES conversations(id) ON DELETE CASCADE,
+    room_id VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
@@ -0,0 +1,2 @@
+-- Per-message notification emails for offline members, answerable by replying to the email
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_email_notification_enabled BOOLEAN DEFAULT FALSE;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_conv_members_join_order;
@@ -0,0 +1,2 @@
+-- Member previews and GET /conversations/:id/members page through members in join order
+CREATE INDEX IF NOT EXISTS idx_conv_members_join_order ON conversation_members(conversation_id, joined_at, id) WHERE deleted_at IS NULL;
@@ -0,0 +1,11 @@
+-- Delivery receipts: when a recipient's device received a message, reported by
+-- the client like read receipts. Message info combines both per recipient.
+CREATE TABLE IF NOT EXISTS delivery_receipts (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    message_id   UUID        NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id      UUID        NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    delivered_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_delivery_receipts_message_user ON delivery_receipts(message_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_delivery_receipts_user ON delivery_receipts(user_id);
@@ -0,0 +1 @@
+ALTER TABLE conversation_members DROP COLUMN IF EXISTS cleared_before;
@@ -0,0 +1,3 @@
+-- Clear history: messages sent before cleared_before are hidden from this
+-- member only; the other members keep them
+ALTER TABLE conversation_members ADD COLUMN IF NOT EXISTS cleared_before TIMESTAMPTZ;
@@ -0,0 +1,2 @@
+-- Frozen (announcement) conversations: only admins can post
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS frozen BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+-- Per-user limits count the groups and direct conversations a user created
+CREATE INDEX IF NOT EXISTS idx_conversations_creator ON conversations(creator_id, type, created_at);
@@ -0,0 +1,2 @@
+-- Single-session mode: signing in ends the user's other sessions
+ALTER TABLE users ADD COLUMN IF NOT EXISTS single_session BOOLEAN NOT NULL DEFAULT FALSE;
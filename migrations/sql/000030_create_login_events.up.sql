@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS login_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    fingerprint VARCHAR(64) NOT NULL,
+    device VARCHAR(100),
+    user_agent VARCHAR(500),
+    ip VARCHAR(45),
+    location VARCHAR(255),
+    country_code VARCHAR(2),
+    new_device BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_login_events_user_created ON login_events(user_id, created_at DESC);
+CREATE INDEX idx_login_events_user_fingerprint ON login_events(user_id, fingerprint);
@@ -0,0 +1,6 @@
+-- Hashed codes can't be turned back into codes: pending ones are expired
+UPDATE otp_codes SET used_at = NOW() WHERE used_at IS NULL;
+ALTER TABLE otp_codes DROP COLUMN IF EXISTS attempts;
+UPDATE otp_codes SET code_hash = '';
+ALTER TABLE otp_codes ALTER COLUMN code_hash TYPE VARCHAR(6);
+ALTER TABLE otp_codes RENAME COLUMN code_hash TO code;
@@ -0,0 +1,7 @@
+-- OTP codes are stored as HMAC-SHA256 hashes with a count of wrong guesses.
+-- Pending plaintext codes can't be hashed without the key: they are expired
+-- and users request new ones.
+UPDATE otp_codes SET used_at = NOW() WHERE used_at IS NULL;
+ALTER TABLE otp_codes RENAME COLUMN code TO code_hash;
+ALTER TABLE otp_codes ALTER COLUMN code_hash TYPE VARCHAR(64);
+ALTER TABLE otp_codes ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Postgres can't drop an enum value; the links themselves are removed
+DELETE FROM otp_codes WHERE purpose = 'magic_link';
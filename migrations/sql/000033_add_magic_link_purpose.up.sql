@@ -0,0 +1,2 @@
+-- Magic sign-in links are stored like OTP codes, with their own purpose
+ALTER TYPE otp_purpose ADD VALUE IF NOT EXISTS 'magic_link';
@@ -0,0 +1,2 @@
+-- Postgres can't drop an enum value; move LDAP-only accounts back to email (password reset)
+UPDATE users SET auth_provider = 'email' WHERE auth_provider = 'ldap';
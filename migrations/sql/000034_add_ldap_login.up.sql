@@ -0,0 +1,2 @@
+-- LDAP / Active Directory sign-in: passwords are checked by the directory, which also provisions the accounts
+ALTER TYPE auth_provider ADD VALUE IF NOT EXISTS 'ldap';
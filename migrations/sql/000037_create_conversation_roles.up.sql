@@ -0,0 +1,9 @@
+-- Custom roles of group conversations, and changed permissions of the member role
+CREATE TABLE IF NOT EXISTS conversation_roles (
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    name VARCHAR(20) NOT NULL,
+    permissions JSONB NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (conversation_id, name)
+);
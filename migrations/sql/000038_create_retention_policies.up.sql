@@ -0,0 +1,26 @@
+-- How long messages are kept, deployment-wide (conversation_id NULL) or per conversation
+CREATE TABLE IF NOT EXISTS retention_policies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    conversation_id UUID UNIQUE REFERENCES conversations(id) ON DELETE CASCADE,
+    max_age_days INTEGER NOT NULL,
+    updated_by UUID,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- At most one deployment-wide policy
+CREATE UNIQUE INDEX idx_retention_policies_deployment ON retention_policies ((conversation_id IS NULL)) WHERE conversation_id IS NULL;
+
+-- Administrative and compliance actions
+CREATE TABLE IF NOT EXISTS audit_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    action VARCHAR(50) NOT NULL,
+    actor_id UUID,
+    conversation_id UUID,
+    details JSONB,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_events_action ON audit_events(action);
+CREATE INDEX idx_audit_events_conversation_id ON audit_events(conversation_id);
+CREATE INDEX idx_audit_events_created_at ON audit_events(created_at);
@@ -0,0 +1,13 @@
+-- Users and conversations whose messages are kept regardless of retention and deletion
+CREATE TABLE IF NOT EXISTS legal_holds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID,
+    conversation_id UUID,
+    reason VARCHAR(500) NOT NULL,
+    created_by UUID NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    CHECK ((user_id IS NULL) <> (conversation_id IS NULL))
+);
+
+CREATE INDEX idx_legal_holds_user_id ON legal_holds(user_id);
+CREATE INDEX idx_legal_holds_conversation_id ON legal_holds(conversation_id);
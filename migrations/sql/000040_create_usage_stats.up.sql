@@ -0,0 +1,7 @@
+-- Daily usage aggregates; no per-user data
+CREATE TABLE IF NOT EXISTS usage_stats (
+    day DATE NOT NULL,
+    metric VARCHAR(40) NOT NULL,
+    value BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, metric)
+);
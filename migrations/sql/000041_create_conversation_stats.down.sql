@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS conversation_member_daily_stats;
+DROP TABLE IF EXISTS conversation_daily_stats;
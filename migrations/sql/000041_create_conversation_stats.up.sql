@@ -0,0 +1,18 @@
+-- Daily rollups of group activity, behind GET /conversations/:id/insights
+CREATE TABLE IF NOT EXISTS conversation_daily_stats (
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    messages BIGINT NOT NULL DEFAULT 0,
+    joins BIGINT NOT NULL DEFAULT 0,
+    leaves BIGINT NOT NULL DEFAULT 0,
+    hours JSONB NOT NULL,
+    PRIMARY KEY (conversation_id, day)
+);
+
+CREATE TABLE IF NOT EXISTS conversation_member_daily_stats (
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    user_id UUID NOT NULL,
+    messages BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (conversation_id, day, user_id)
+);
@@ -0,0 +1,3 @@
+-- Full-text search over message content (GET /search). 'simple' doesn't stem,
+-- so it works the same for every language.
+CREATE INDEX IF NOT EXISTS idx_messages_content_search ON messages USING GIN (to_tsvector('simple', content));
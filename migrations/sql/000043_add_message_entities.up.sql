@@ -0,0 +1,3 @@
+-- Formatting spans over a message's plain text (bold, italic, code, links...),
+-- see model.MessageEntity
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS entities jsonb;
@@ -0,0 +1,2 @@
+-- Language of code messages (type 'code'), for syntax highlighting
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS language varchar(20);
@@ -0,0 +1,20 @@
+-- One-to-one calls, recorded from their WebRTC signaling
+CREATE TABLE IF NOT EXISTS calls (
+    id UUID PRIMARY KEY,
+    conversation_id UUID,
+    caller_id UUID NOT NULL,
+    callee_id UUID NOT NULL,
+    type VARCHAR(10),
+    status VARCHAR(20) NOT NULL DEFAULT 'ringing',
+    answered_at TIMESTAMPTZ,
+    ended_at TIMESTAMPTZ,
+    renegotiations INTEGER NOT NULL DEFAULT 0,
+    screen_shares INTEGER NOT NULL DEFAULT 0,
+    media_state JSONB,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_calls_conversation_id ON calls(conversation_id);
+CREATE INDEX idx_calls_caller_id ON calls(caller_id);
+CREATE INDEX idx_calls_callee_id ON calls(callee_id);
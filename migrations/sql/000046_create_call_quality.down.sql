@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS call_stats;
+DROP TABLE IF EXISTS call_feedback;
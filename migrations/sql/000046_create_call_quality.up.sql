@@ -0,0 +1,29 @@
+-- Participants' ratings of calls
+CREATE TABLE IF NOT EXISTS call_feedback (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    call_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    rating INTEGER NOT NULL,
+    issues JSONB,
+    comment VARCHAR(1000),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_call_feedback_call_user ON call_feedback(call_id, user_id);
+
+-- WebRTC stats reported during calls, a row per report
+CREATE TABLE IF NOT EXISTS call_stats (
+    id BIGSERIAL PRIMARY KEY,
+    call_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    recorded_at TIMESTAMPTZ NOT NULL,
+    packet_loss DOUBLE PRECISION NOT NULL DEFAULT 0,
+    rtt_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+    jitter_ms DOUBLE PRECISION NOT NULL DEFAULT 0,
+    network VARCHAR(20),
+    region VARCHAR(2)
+);
+
+CREATE INDEX idx_call_stats_call_id ON call_stats(call_id);
+CREATE INDEX idx_call_stats_recorded_at ON call_stats(recorded_at);
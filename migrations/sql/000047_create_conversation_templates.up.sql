@@ -0,0 +1,28 @@
+-- Templates that set up new groups with messages posted on creation
+CREATE TABLE IF NOT EXISTS conversation_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(50) NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    description VARCHAR(500),
+    messages JSONB,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_conversation_templates_name ON conversation_templates(name);
+
+-- The predefined templates; admins can edit or delete them
+INSERT INTO conversation_templates (name, title, description, messages) VALUES
+(
+    'project',
+    'New project',
+    'A project room with a kickoff checklist',
+    '[{"content": "Project checklist\n☐ Agree on the goal and scope\n☐ Assign an owner for each workstream\n☐ Set the milestones and deadlines\n☐ Share the docs and designs here\n☐ Schedule a weekly check-in", "entities": [{"type": "bold", "offset": 0, "length": 17}]}]'
+),
+(
+    'support',
+    'Support',
+    'A support channel that greets people with how to get help',
+    '[{"content": "Welcome to the support channel! 👋\nDescribe your problem in one message, with what you expected and what happened instead. Screenshots help. Someone from the team will reply here as soon as possible."}]'
+)
+ON CONFLICT (name) DO NOTHING;
@@ -0,0 +1,13 @@
+-- Automatic actions groups take when members are added
+CREATE TABLE IF NOT EXISTS onboarding_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL,
+    content TEXT,
+    role VARCHAR(20),
+    after_days INTEGER NOT NULL DEFAULT 0,
+    created_by UUID NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_onboarding_rules_conversation_id ON onboarding_rules(conversation_id);
@@ -0,0 +1,5 @@
+ALTER TABLE messages DROP COLUMN IF EXISTS auto_reply;
+
+ALTER TABLE users DROP COLUMN IF EXISTS auto_reply_ends_at;
+ALTER TABLE users DROP COLUMN IF EXISTS auto_reply_starts_at;
+ALTER TABLE users DROP COLUMN IF EXISTS auto_reply_text;
@@ -0,0 +1,6 @@
+-- Users' away messages, and the flag on the messages sent from them
+ALTER TABLE users ADD COLUMN IF NOT EXISTS auto_reply_text VARCHAR(1000) DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS auto_reply_starts_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS auto_reply_ends_at TIMESTAMPTZ;
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS auto_reply BOOLEAN DEFAULT FALSE;
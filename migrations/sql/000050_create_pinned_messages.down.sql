@@ -0,0 +1,5 @@
+UPDATE conversation_templates
+SET messages = messages #- '{0,pin}'
+WHERE name = 'project' AND jsonb_array_length(messages) > 0;
+
+DROP TABLE IF EXISTS pinned_messages;
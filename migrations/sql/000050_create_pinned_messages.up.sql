@@ -0,0 +1,17 @@
+-- Messages pinned to the top of conversations, in order; at most one is the announcement banner
+CREATE TABLE IF NOT EXISTS pinned_messages (
+    conversation_id UUID NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    announcement BOOLEAN NOT NULL DEFAULT FALSE,
+    pinned_by UUID NOT NULL,
+    pinned_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (conversation_id, message_id)
+);
+
+CREATE UNIQUE INDEX idx_pinned_messages_announcement ON pinned_messages(conversation_id) WHERE announcement;
+
+-- The project template's checklist is pinned in new project rooms
+UPDATE conversation_templates
+SET messages = jsonb_set(messages, '{0,pin}', 'true')
+WHERE name = 'project' AND jsonb_array_length(messages) > 0;
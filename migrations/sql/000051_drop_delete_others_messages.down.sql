@@ -0,0 +1,2 @@
+-- Which roles had the permission isn't kept: nothing to restore
+SELECT 1;
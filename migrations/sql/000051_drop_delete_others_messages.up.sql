@@ -0,0 +1,3 @@
+-- delete_others_messages was never enforced, as messages can't be deleted: roles lose it
+UPDATE conversation_roles SET permissions = permissions - 'delete_others_messages', updated_at = NOW()
+WHERE permissions ? 'delete_others_messages';
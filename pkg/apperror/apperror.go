@@ -0,0 +1,84 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Error is an error the API can report to clients: a stable machine-readable
+// code (which determines the HTTP status), a human-readable message and optional details
+type Error struct {
+	Code    Code
+	Message string
+	Details interface{}
+	cause   error
+}
+
+// New creates an error with the given code and message
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Is matches errors with the same code, so errors.Is(err, ErrNotFound) holds
+// for copies made with WithMessage, WithDetails or Wrap
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// Status returns the HTTP status for the error's code
+func (e *Error) Status() int {
+	if entry, ok := catalog[e.Code]; ok {
+		return entry.Status
+	}
+	return http.StatusInternalServerError
+}
+
+// Cause returns the underlying error, if any
+func (e *Error) Cause() error {
+	return e.cause
+}
+
+// WithMessage returns a copy with a different message
+func (e *Error) WithMessage(message string) *Error {
+	c := *e
+	c.Message = message
+	return &c
+}
+
+// WithDetails returns a copy carrying extra data for the client (e.g. the offending fields)
+func (e *Error) WithDetails(details interface{}) *Error {
+	c := *e
+	c.Details = details
+	return &c
+}
+
+// Wrap returns a copy that records the underlying cause
+func (e *Error) Wrap(cause error) *Error {
+	c := *e
+	c.cause = cause
+	return &c
+}
+
+// From converts any error into an *Error; errors that aren't one become internal errors
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return ErrInternal.Wrap(err)
+}
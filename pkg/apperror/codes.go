@@ -0,0 +1,150 @@
+package apperror
+
+import "net/http"
+
+// Code is a stable, machine-readable error identifier returned to clients
+type Code string
+
+const (
+	// Generic codes
+	CodeInvalidRequest       Code = "invalid_request"
+	CodeUnauthorized         Code = "unauthorized"
+	CodeForbidden            Code = "forbidden"
+	CodeNotFound             Code = "not_found"
+	CodeConflict             Code = "conflict"
+	CodePayloadTooLarge      Code = "payload_too_large"
+	CodeUnsupportedMediaType Code = "unsupported_media_type"
+	CodeRateLimited          Code = "rate_limited"
+	CodeInternal             Code = "internal_error"
+	CodeUnavailable          Code = "service_unavailable"
+	CodeFeatureDisabled      Code = "feature_disabled"
+
+	// Auth and account codes
+	CodeEmailTaken           Code = "email_taken"
+	CodeInvalidCredentials   Code = "invalid_credentials"
+	CodeEmailNotVerified     Code = "email_not_verified"
+	CodeEmailAlreadyVerified Code = "email_already_verified"
+	CodeGoogleAccount        Code = "google_account"
+	CodeInvalidGoogleToken   Code = "invalid_google_token"
+	CodeInvalidOTP           Code = "invalid_otp"
+	CodeOTPAttemptsExceeded  Code = "otp_attempts_exceeded"
+	CodeUserNotFound         Code = "user_not_found"
+	CodeHandleInvalid        Code = "handle_invalid"
+	CodeHandleTaken          Code = "handle_taken"
+	CodeAccountDeactivated   Code = "account_deactivated"
+	CodeSSOAccount           Code = "sso_account"
+	CodeSSONotConfigured     Code = "sso_not_configured"
+	CodeSSOFailed            Code = "sso_failed"
+	CodeSSODomainNotAllowed  Code = "sso_domain_not_allowed"
+	CodeLDAPAccount          Code = "ldap_account"
+	CodeSignupNotAllowed     Code = "signup_not_allowed"
+	CodeDisposableEmail      Code = "disposable_email"
+	CodeInvitationRequired   Code = "invitation_required"
+	CodeInvitationInvalid    Code = "invitation_invalid"
+
+	// Chat codes
+	CodeNotMember          Code = "not_member"
+	CodeInvalidMembers     Code = "invalid_members"
+	CodeGroupTooLarge      Code = "group_too_large"
+	CodeConversationFrozen Code = "conversation_frozen"
+	CodeGroupLimitReached  Code = "group_limit_reached"
+	CodeDirectLimitReached Code = "direct_limit_reached"
+	CodeNotPermitted       Code = "not_permitted"
+	CodeMessageTooLong     Code = "message_too_long"
+
+	// Notification codes
+	CodeNotificationNotFound Code = "notification_not_found"
+
+	// Directory sync (SCIM) codes
+	CodeInvalidFilter Code = "invalid_filter"
+
+	// OAuth provider codes
+	CodeOAuthNotConfigured Code = "oauth_not_configured"
+	CodeInvalidRedirectURI Code = "invalid_redirect_uri"
+	CodeInvalidScope       Code = "invalid_scope"
+	CodeInsufficientScope  Code = "insufficient_scope"
+)
+
+// CatalogEntry describes one error code
+type CatalogEntry struct {
+	Code        Code
+	Status      int
+	Description string
+}
+
+// Catalog lists every error code in the order they are documented
+var Catalog = []CatalogEntry{
+	{CodeInvalidRequest, http.StatusBadRequest, "The request body, query or path parameters are malformed or fail validation"},
+	{CodeUnauthorized, http.StatusUnauthorized, "Missing, invalid, expired or revoked access token"},
+	{CodeForbidden, http.StatusForbidden, "The caller is not allowed to perform this action"},
+	{CodeNotFound, http.StatusNotFound, "The requested resource does not exist"},
+	{CodeConflict, http.StatusConflict, "The request conflicts with the current state of the resource"},
+	{CodePayloadTooLarge, http.StatusRequestEntityTooLarge, "The uploaded file exceeds the size limit"},
+	{CodeUnsupportedMediaType, http.StatusUnsupportedMediaType, "The uploaded file type is not allowed"},
+	{CodeRateLimited, http.StatusTooManyRequests, "Too many requests; retry later"},
+	{CodeInternal, http.StatusInternalServerError, "Unexpected server error; the message is not exposed"},
+	{CodeUnavailable, http.StatusServiceUnavailable, "A downstream service (storage, mail, push) is unavailable"},
+	{CodeFeatureDisabled, http.StatusForbidden, "The feature is switched off by an operator (feature flag)"},
+
+	{CodeEmailTaken, http.StatusConflict, "An account with this email already exists"},
+	{CodeInvalidCredentials, http.StatusUnauthorized, "Email or password is incorrect"},
+	{CodeEmailNotVerified, http.StatusForbidden, "The account's email has not been verified yet"},
+	{CodeEmailAlreadyVerified, http.StatusConflict, "The account's email is already verified"},
+	{CodeGoogleAccount, http.StatusBadRequest, "The account signs in with Google and has no password"},
+	{CodeInvalidGoogleToken, http.StatusUnauthorized, "The Google ID token is invalid or for another client"},
+	{CodeInvalidOTP, http.StatusBadRequest, "The verification code is wrong, expired or used up"},
+	{CodeOTPAttemptsExceeded, http.StatusBadRequest, "Too many wrong guesses used up the verification code; request a new one"},
+	{CodeUserNotFound, http.StatusNotFound, "No user matches the given ID, email or handle"},
+	{CodeHandleInvalid, http.StatusBadRequest, "The handle does not meet the format rules"},
+	{CodeHandleTaken, http.StatusConflict, "The handle is already in use or reserved"},
+	{CodeAccountDeactivated, http.StatusForbidden, "The account was deactivated by the organization's identity provider"},
+	{CodeSSOAccount, http.StatusBadRequest, "The account signs in with single sign-on and has no password"},
+	{CodeSSONotConfigured, http.StatusNotFound, "Single sign-on is not set up on this deployment"},
+	{CodeSSOFailed, http.StatusUnauthorized, "The single sign-on response was invalid, expired or already used"},
+	{CodeSSODomainNotAllowed, http.StatusForbidden, "The email domain may not sign in with single sign-on"},
+	{CodeLDAPAccount, http.StatusBadRequest, "The account signs in with its directory (LDAP) password, which GoTalk can't reset"},
+	{CodeSignupNotAllowed, http.StatusForbidden, "The email domain may not sign up on this deployment"},
+	{CodeDisposableEmail, http.StatusForbidden, "Disposable email addresses may not sign up"},
+	{CodeInvitationRequired, http.StatusForbidden, "Registration is invitation-only and no invitation code was given"},
+	{CodeInvitationInvalid, http.StatusForbidden, "The invitation code is unknown, expired, used up or for another email address"},
+
+	{CodeNotMember, http.StatusForbidden, "The caller is not a member of the conversation"},
+	{CodeInvalidMembers, http.StatusBadRequest, "The member list is invalid for this conversation type"},
+	{CodeGroupTooLarge, http.StatusBadRequest, "The group would exceed the maximum group size"},
+	{CodeConversationFrozen, http.StatusForbidden, "The conversation is frozen and only members who manage its settings can post"},
+	{CodeGroupLimitReached, http.StatusForbidden, "The user has created as many groups as allowed"},
+	{CodeDirectLimitReached, http.StatusTooManyRequests, "The user has started as many new direct conversations as allowed in 24 hours"},
+	{CodeNotPermitted, http.StatusForbidden, "The caller's role in the conversation lacks the permission the action needs"},
+	{CodeMessageTooLong, http.StatusUnprocessableEntity, "The message text is longer than the deployment allows; details.max_length gives the limit in characters"},
+
+	{CodeNotificationNotFound, http.StatusNotFound, "The notification does not exist"},
+
+	{CodeInvalidFilter, http.StatusBadRequest, "The SCIM filter is malformed or not supported"},
+
+	{CodeOAuthNotConfigured, http.StatusNotFound, "GoTalk is not set up as an OAuth provider on this deployment"},
+	{CodeInvalidRedirectURI, http.StatusBadRequest, "The redirect URI is malformed or not registered for the OAuth app"},
+	{CodeInvalidScope, http.StatusBadRequest, "A requested scope is unknown or not allowed for the OAuth app"},
+	{CodeInsufficientScope, http.StatusForbidden, "The app's or personal access token lacks the scope the endpoint needs, or such tokens may not call it"},
+}
+
+var catalog = func() map[Code]CatalogEntry {
+	m := make(map[Code]CatalogEntry, len(Catalog))
+	for _, e := range Catalog {
+		m[e.Code] = e
+	}
+	return m
+}()
+
+// Generic errors; use WithMessage for a more specific message
+var (
+	ErrInvalidRequest       = New(CodeInvalidRequest, "invalid request")
+	ErrUnauthorized         = New(CodeUnauthorized, "unauthorized")
+	ErrForbidden            = New(CodeForbidden, "forbidden")
+	ErrNotFound             = New(CodeNotFound, "not found")
+	ErrConflict             = New(CodeConflict, "conflict")
+	ErrPayloadTooLarge      = New(CodePayloadTooLarge, "payload too large")
+	ErrUnsupportedMediaType = New(CodeUnsupportedMediaType, "unsupported media type")
+	ErrRateLimited          = New(CodeRateLimited, "too many requests")
+	ErrInternal             = New(CodeInternal, "internal server error")
+	ErrUnavailable          = New(CodeUnavailable, "service unavailable")
+)
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -13,13 +14,22 @@ type Claims struct {
 	UserID   uuid.UUID `json:"user_id"`
 	Email    string    `json:"email"`
 	Name     string    `json:"name"`
+	ClientID string    `json:"client_id,omitempty"` // OAuth app the token was issued to; empty for the user's own sessions
+	Scope    string    `json:"scope,omitempty"`     // space-separated scopes granted to the app
 	jwt.RegisteredClaims
 }
 
 // JWTManager handles JWT token operations
 type JWTManager struct {
-	secret []byte
-	expiry time.Duration
+	mu      sync.RWMutex
+	secret  []byte
+	retired []retiredSecret // rotated-out secrets, accepted until their tokens expire
+	expiry  time.Duration
+}
+
+type retiredSecret struct {
+	secret    []byte
+	retiredAt time.Time
 }
 
 // NewJWTManager creates a new JWT manager
@@ -42,20 +52,50 @@ func (j *JWTManager) GenerateToken(userID uuid.UUID, email, name string) (string
 			Issuer:    "gotalk",
 		},
 	}
+	return j.sign(claims)
+}
+
+// GenerateScopedToken creates a token for an OAuth app acting for a user,
+// limited to the space-separated scope. expiry must not exceed the manager's,
+// or the token would outlive a key rotation.
+func (j *JWTManager) GenerateScopedToken(userID uuid.UUID, email, name, clientID, scope string, expiry time.Duration) (string, error) {
+	claims := &Claims{
+		UserID:   userID,
+		Email:    email,
+		Name:     name,
+		ClientID: clientID,
+		Scope:    scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    "gotalk",
+		},
+	}
+	return j.sign(claims)
+}
+
+func (j *JWTManager) sign(claims *Claims) (string, error) {
+	j.mu.RLock()
+	secret := j.secret
+	j.mu.RUnlock()
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.secret)
+	return token.SignedString(secret)
 }
 
 // ValidateToken parses and validates a JWT token
 func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return j.secret, nil
-	})
+	j.mu.RLock()
+	secret, retired := j.secret, j.retired
+	j.mu.RUnlock()
 
+	token, err := parseToken(tokenString, secret)
+	for i := len(retired) - 1; i >= 0 && errors.Is(err, jwt.ErrTokenSignatureInvalid); i-- {
+		// Signed before a key rotation
+		if time.Since(retired[i].retiredAt) < j.expiry {
+			token, err = parseToken(tokenString, retired[i].secret)
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -67,3 +107,32 @@ func (j *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 
 	return claims, nil
 }
+
+// SetSecret rotates the signing key. Tokens signed with the old key stay valid
+// until they expire.
+func (j *JWTManager) SetSecret(secret string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if secret == string(j.secret) {
+		return
+	}
+
+	now := time.Now()
+	retired := []retiredSecret{}
+	for _, r := range j.retired {
+		if now.Sub(r.retiredAt) < j.expiry {
+			retired = append(retired, r)
+		}
+	}
+	j.retired = append(retired, retiredSecret{secret: j.secret, retiredAt: now})
+	j.secret = []byte(secret)
+}
+
+func parseToken(tokenString string, secret []byte) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return secret, nil
+	})
+}
@@ -0,0 +1,12 @@
+package auth
+
+import "strings"
+
+// PersonalTokenPrefix starts every personal access token, which tells them
+// apart from JWTs (and lets secret scanners spot leaked ones)
+const PersonalTokenPrefix = "gtk_"
+
+// IsPersonalToken tells whether a bearer token is a personal access token
+func IsPersonalToken(tokenString string) bool {
+	return strings.HasPrefix(tokenString, PersonalTokenPrefix)
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	revokedUserKeyPrefix     = "gotalk:auth:revoked-user:"
+	sessionsRevokedKeyPrefix = "gotalk:auth:sessions-revoked:"
+	sessionKeyPrefix         = "gotalk:auth:session:"
+	appRevokedKeyPrefix      = "gotalk:auth:app-revoked:"
+	clientRevokedKeyPrefix   = "gotalk:auth:client-revoked:"
+)
+
+// RevokedUserKey is the Redis key that, while it exists, rejects every token
+// issued to the user (e.g. after the account is deactivated)
+func RevokedUserKey(userID uuid.UUID) string {
+	return revokedUserKeyPrefix + userID.String()
+}
+
+// SessionsRevokedKey is the Redis key holding when (Unix seconds) the user
+// last logged out on all devices; tokens issued until then are rejected
+func SessionsRevokedKey(userID uuid.UUID) string {
+	return sessionsRevokedKeyPrefix + userID.String()
+}
+
+// SessionKey is the Redis key that, under the single-session policy, holds the
+// TokenHash of the user's only valid token
+func SessionKey(userID uuid.UUID) string {
+	return sessionKeyPrefix + userID.String()
+}
+
+// AppRevokedKey is the Redis key holding when (Unix seconds) the user last
+// disconnected an OAuth app; the app's tokens issued until then are rejected
+func AppRevokedKey(userID uuid.UUID, clientID string) string {
+	return appRevokedKeyPrefix + userID.String() + ":" + clientID
+}
+
+// ClientRevokedKey is the Redis key that, while it exists, rejects every token
+// issued to a deleted OAuth app
+func ClientRevokedKey(clientID string) string {
+	return clientRevokedKeyPrefix + clientID
+}
+
+// TokenHash identifies a token without storing it
+func TokenHash(tokenString string) string {
+	sum := sha256.Sum256([]byte(tokenString))
+	return hex.EncodeToString(sum[:])
+}
+
+// BlacklistKey is the Redis key that rejects a single logged-out token
+func BlacklistKey(tokenString string) string {
+	return "blacklist:" + tokenString
+}
+
+// RevocationKeys are the Redis keys that can revoke a token. Read them with
+// MGET and pass the values to Revoked.
+func RevocationKeys(tokenString string, userID uuid.UUID) []string {
+	return []string{BlacklistKey(tokenString), RevokedUserKey(userID), SessionsRevokedKey(userID), SessionKey(userID)}
+}
+
+// Revoked tells from the values of RevocationKeys whether a token issued at
+// issuedAt was revoked: logged out, its user deactivated, issued before the
+// user logged out on all devices, or replaced by a newer single session
+func Revoked(values []interface{}, tokenString string, issuedAt time.Time) bool {
+	if len(values) != 4 {
+		return false
+	}
+	if values[0] != nil || values[1] != nil {
+		return true
+	}
+	if session, ok := values[3].(string); ok && session != TokenHash(tokenString) {
+		return true
+	}
+	return revokedSince(values[2], issuedAt)
+}
+
+// AppRevocationKeys are the Redis keys that can revoke a token issued to an
+// OAuth app. Logging out on all devices and the single-session policy only
+// end the user's own sessions, so they aren't among them.
+func AppRevocationKeys(tokenString string, userID uuid.UUID, clientID string) []string {
+	return []string{BlacklistKey(tokenString), RevokedUserKey(userID), ClientRevokedKey(clientID), AppRevokedKey(userID, clientID)}
+}
+
+// AppRevoked tells from the values of AppRevocationKeys whether an app token
+// issued at issuedAt was revoked: revoked by the app, its user deactivated,
+// the app deleted, or issued before the user disconnected the app
+func AppRevoked(values []interface{}, issuedAt time.Time) bool {
+	if len(values) != 4 {
+		return false
+	}
+	if values[0] != nil || values[1] != nil || values[2] != nil {
+		return true
+	}
+	return revokedSince(values[3], issuedAt)
+}
+
+// revokedSince tells whether a token issued at issuedAt predates a revocation
+// time stored as Unix seconds
+func revokedSince(value interface{}, issuedAt time.Time) bool {
+	revokedAt, ok := value.(string)
+	if !ok {
+		return false
+	}
+	secs, err := strconv.ParseInt(revokedAt, 10, 64)
+	return err == nil && issuedAt.Unix() <= secs
+}
+
+// Expiry returns how long issued tokens stay valid, i.e. how long a revocation must be kept
+func (j *JWTManager) Expiry() time.Duration {
+	return j.expiry
+}
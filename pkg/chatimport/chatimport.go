@@ -0,0 +1,104 @@
+// Package chatimport parses chat histories exported from other messaging apps
+// (WhatsApp, Telegram) into a common form that can be stored as GoTalk messages.
+package chatimport
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Source is the app an archive was exported from
+type Source string
+
+const (
+	SourceWhatsApp Source = "whatsapp"
+	SourceTelegram Source = "telegram"
+)
+
+// maxTranscriptSize caps the chat text or JSON read from an archive
+const maxTranscriptSize = 256 << 20
+
+var (
+	ErrUnknownSource = errors.New("unknown import source")
+	ErrNoTranscript  = errors.New("the archive does not contain a chat export")
+	ErrNoMessages    = errors.New("the chat export contains no messages")
+	ErrAccountExport = errors.New("this is a full account export; export a single chat instead")
+)
+
+// Chat is a parsed chat history
+type Chat struct {
+	Name     string
+	Messages []Message
+}
+
+// Message is one message of an imported chat
+type Message struct {
+	Sender string
+	Time   time.Time
+	Text   string
+	// Attachments are paths of files inside the archive; they may be missing
+	// when the chat was exported without media
+	Attachments []string
+}
+
+// Options tune parsing
+type Options struct {
+	// Location is the time zone of timestamps that don't carry one (WhatsApp
+	// exports use the phone's local time). Defaults to UTC.
+	Location *time.Location
+}
+
+// Parse reads a chat export archive from the given app
+func Parse(source Source, zr *zip.Reader, opts Options) (*Chat, error) {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+
+	var (
+		chat *Chat
+		err  error
+	)
+	switch source {
+	case SourceWhatsApp:
+		chat, err = parseWhatsApp(zr, opts)
+	case SourceTelegram:
+		chat, err = parseTelegram(zr, opts)
+	default:
+		return nil, ErrUnknownSource
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(chat.Messages) == 0 {
+		return nil, ErrNoMessages
+	}
+	return chat, nil
+}
+
+// readFile reads a whole archive entry, up to maxTranscriptSize
+func readFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxTranscriptSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxTranscriptSize {
+		return nil, fmt.Errorf("%s is larger than %d MB", f.Name, maxTranscriptSize>>20)
+	}
+	return data, nil
+}
+
+// cleanText trims the invisible marks some apps put around message text
+func cleanText(s string) string {
+	s = strings.NewReplacer("\u200e", "", "\u200f", "", "\u202a", "", "\u202c", "").Replace(s)
+	return strings.TrimSpace(s)
+}
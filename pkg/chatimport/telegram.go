@@ -0,0 +1,125 @@
+package chatimport
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Telegram Desktop exports a single chat (Export chat history, JSON format) as
+// a folder with result.json and the media in subfolders (photos/, files/, ...).
+type telegramExport struct {
+	Name     string            `json:"name"`
+	Messages []telegramMessage `json:"messages"`
+	Chats    json.RawMessage   `json:"chats"` // only in full account exports
+}
+
+type telegramMessage struct {
+	Type         string          `json:"type"` // "message" or "service"
+	Date         string          `json:"date"` // local time, 2006-01-02T15:04:05
+	DateUnixtime string          `json:"date_unixtime"`
+	From         *string         `json:"from"` // null for deleted accounts
+	Text         json.RawMessage `json:"text"` // a string, or a list of strings and entities
+	Photo        string          `json:"photo"`
+	File         string          `json:"file"`
+}
+
+// telegramMissingFile prefixes the placeholder used for media left out of the export
+const telegramMissingFile = "(File not included"
+
+func parseTelegram(zr *zip.Reader, opts Options) (*Chat, error) {
+	var result *zip.File
+	for _, f := range zr.File {
+		if path.Base(f.Name) == "result.json" {
+			result = f
+			break
+		}
+	}
+	if result == nil {
+		return nil, ErrNoTranscript
+	}
+	data, err := readFile(result)
+	if err != nil {
+		return nil, err
+	}
+
+	var export telegramExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, err
+	}
+	if len(export.Chats) > 0 && string(export.Chats) != "null" {
+		return nil, ErrAccountExport
+	}
+
+	dir := path.Dir(result.Name)
+	chat := &Chat{Name: export.Name}
+	if chat.Name == "" {
+		chat.Name = "Telegram chat"
+	}
+	for _, m := range export.Messages {
+		if m.Type != "message" {
+			continue
+		}
+		t, ok := telegramTime(m, opts.Location)
+		if !ok {
+			continue
+		}
+
+		msg := Message{Sender: "Deleted Account", Time: t, Text: cleanText(telegramText(m.Text))}
+		if m.From != nil && *m.From != "" {
+			msg.Sender = *m.From
+		}
+		for _, file := range []string{m.Photo, m.File} {
+			if file != "" && !strings.HasPrefix(file, telegramMissingFile) {
+				msg.Attachments = append(msg.Attachments, path.Join(dir, file))
+			}
+		}
+		if msg.Text == "" && len(msg.Attachments) == 0 {
+			continue
+		}
+		chat.Messages = append(chat.Messages, msg)
+	}
+	return chat, nil
+}
+
+// telegramTime prefers the Unix timestamp newer exports carry over the local date
+func telegramTime(m telegramMessage, loc *time.Location) (time.Time, bool) {
+	if m.DateUnixtime != "" {
+		if sec, err := strconv.ParseInt(m.DateUnixtime, 10, 64); err == nil {
+			return time.Unix(sec, 0), true
+		}
+	}
+	t, err := time.ParseInLocation("2006-01-02T15:04:05", m.Date, loc)
+	return t, err == nil
+}
+
+// telegramText flattens formatted text (bold, links, mentions, ...) to plain text
+func telegramText(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+
+	var parts []json.RawMessage
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		var text string
+		if err := json.Unmarshal(part, &text); err == nil {
+			b.WriteString(text)
+			continue
+		}
+		var entity struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(part, &entity); err == nil {
+			b.WriteString(entity.Text)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,223 @@
+package chatimport
+
+import (
+	"archive/zip"
+	"bufio"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WhatsApp exports a chat as a zip with a text transcript (_chat.txt on iOS,
+// "WhatsApp Chat with <name>.txt" on Android) next to the media files.
+//
+//	iOS:     [31/12/2023, 21:41:05] Alice: Hello
+//	Android: 31/12/2023, 21:41 - Alice: Hello
+//
+// The date order (day or month first) depends on the phone's locale.
+var (
+	whatsAppIOSLine     = regexp.MustCompile(`^\[(\d{1,4}[./-]\d{1,2}[./-]\d{1,4}),? (\d{1,2}[:.]\d{2}(?:[:.]\d{2})?(?: ?[AaPp]\.? ?[Mm]\.?)?)\] (.*)$`)
+	whatsAppAndroidLine = regexp.MustCompile(`^(\d{1,4}[./-]\d{1,2}[./-]\d{1,4}),? (\d{1,2}[:.]\d{2}(?:[:.]\d{2})?(?: ?[AaPp]\.? ?[Mm]\.?)?) - (.*)$`)
+	whatsAppAttachedIOS = regexp.MustCompile(`<attached: ([^>]+)>`)
+	whatsAppDateParts   = regexp.MustCompile(`\d+`)
+)
+
+const whatsAppFileAttached = " (file attached)"
+
+type whatsAppLine struct {
+	date, clock, sender, text string
+}
+
+func parseWhatsApp(zr *zip.Reader, opts Options) (*Chat, error) {
+	transcript := whatsAppTranscript(zr)
+	if transcript == nil {
+		return nil, ErrNoTranscript
+	}
+	data, err := readFile(transcript)
+	if err != nil {
+		return nil, err
+	}
+	dir := path.Dir(transcript.Name)
+
+	// Normalize the no-break spaces some locales put before AM/PM
+	text := strings.NewReplacer("\u202f", " ", "\u00a0", " ", "\r\n", "\n").Replace(string(data))
+
+	var (
+		lines   []whatsAppLine
+		current *whatsAppLine
+	)
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 64*1024), maxTranscriptSize)
+	for scanner.Scan() {
+		line := strings.TrimLeft(scanner.Text(), "\u200e\ufeff")
+		m := whatsAppIOSLine.FindStringSubmatch(line)
+		if m == nil {
+			m = whatsAppAndroidLine.FindStringSubmatch(line)
+		}
+		if m == nil {
+			// Continuation of a multi-line message
+			if current != nil {
+				current.text += "\n" + line
+			}
+			continue
+		}
+
+		sender, body, ok := strings.Cut(m[3], ": ")
+		if !ok {
+			// System line ("Messages are end-to-end encrypted", "Alice joined", ...)
+			current = nil
+			continue
+		}
+		lines = append(lines, whatsAppLine{date: m[1], clock: m[2], sender: cleanText(sender), text: body})
+		current = &lines[len(lines)-1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	order := whatsAppDateOrder(lines)
+	chat := &Chat{Name: whatsAppChatName(transcript.Name)}
+	for _, l := range lines {
+		t, ok := parseWhatsAppTime(l.date, l.clock, order, opts.Location)
+		if !ok {
+			continue
+		}
+		msg := Message{Sender: l.sender, Time: t}
+		msg.Text, msg.Attachments = whatsAppAttachments(l.text, dir)
+		if msg.Text == "" && len(msg.Attachments) == 0 {
+			continue
+		}
+		chat.Messages = append(chat.Messages, msg)
+	}
+	return chat, nil
+}
+
+// whatsAppTranscript finds the chat transcript in the archive
+func whatsAppTranscript(zr *zip.Reader) *zip.File {
+	var fallback *zip.File
+	for _, f := range zr.File {
+		base := path.Base(f.Name)
+		if base == "_chat.txt" || strings.HasPrefix(base, "WhatsApp Chat") && strings.HasSuffix(base, ".txt") {
+			return f
+		}
+		if fallback == nil && strings.HasSuffix(base, ".txt") {
+			fallback = f
+		}
+	}
+	return fallback
+}
+
+// whatsAppChatName takes the chat name from an Android transcript's file name
+func whatsAppChatName(fileName string) string {
+	base := strings.TrimSuffix(path.Base(fileName), ".txt")
+	for _, prefix := range []string{"WhatsApp Chat with ", "WhatsApp Chat - "} {
+		if name, ok := strings.CutPrefix(base, prefix); ok {
+			return name
+		}
+	}
+	return "WhatsApp chat"
+}
+
+// whatsAppAttachments pulls attachment references out of a message's text
+func whatsAppAttachments(text, dir string) (string, []string) {
+	var files []string
+	text = whatsAppAttachedIOS.ReplaceAllStringFunc(text, func(s string) string {
+		files = append(files, path.Join(dir, whatsAppAttachedIOS.FindStringSubmatch(s)[1]))
+		return ""
+	})
+
+	// Android puts the file on the first line and an optional caption below
+	first, rest, _ := strings.Cut(text, "\n")
+	if name, ok := strings.CutSuffix(cleanText(first), whatsAppFileAttached); ok {
+		files = append(files, path.Join(dir, name))
+		text = rest
+	}
+	return cleanText(text), files
+}
+
+// dateOrder is the order of the parts of a WhatsApp date
+type dateOrder int
+
+const (
+	dayMonthYear dateOrder = iota
+	monthDayYear
+	yearMonthDay
+)
+
+// whatsAppDateOrder works out the locale's date order from the dates in the
+// export: a first part above 12 can only be a day, a second part above 12 can
+// only be a day too. With nothing conclusive day-first is assumed, as most
+// locales use it.
+func whatsAppDateOrder(lines []whatsAppLine) dateOrder {
+	for _, l := range lines {
+		parts := whatsAppDateParts.FindAllString(l.date, 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if len(parts[0]) == 4 {
+			return yearMonthDay
+		}
+		first, _ := strconv.Atoi(parts[0])
+		second, _ := strconv.Atoi(parts[1])
+		if first > 12 {
+			return dayMonthYear
+		}
+		if second > 12 {
+			return monthDayYear
+		}
+	}
+	return dayMonthYear
+}
+
+func parseWhatsAppTime(date, clock string, order dateOrder, loc *time.Location) (time.Time, bool) {
+	parts := whatsAppDateParts.FindAllString(date, 3)
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	n := make([]int, 3)
+	for i, p := range parts {
+		n[i], _ = strconv.Atoi(p)
+	}
+
+	var year, month, day int
+	switch order {
+	case yearMonthDay:
+		year, month, day = n[0], n[1], n[2]
+	case monthDayYear:
+		month, day, year = n[0], n[1], n[2]
+	default:
+		day, month, year = n[0], n[1], n[2]
+	}
+	if year < 100 {
+		year += 2000
+	}
+
+	clock = strings.ToLower(strings.NewReplacer(".", ":", " ", "").Replace(clock))
+	pm := strings.HasSuffix(clock, "pm") || strings.HasSuffix(clock, "p:m:")
+	am := strings.HasSuffix(clock, "am") || strings.HasSuffix(clock, "a:m:")
+	clock = strings.TrimRight(clock, "apm:")
+
+	fields := strings.Split(clock, ":")
+	if len(fields) < 2 {
+		return time.Time{}, false
+	}
+	hour, _ := strconv.Atoi(fields[0])
+	minute, _ := strconv.Atoi(fields[1])
+	second := 0
+	if len(fields) > 2 {
+		second, _ = strconv.Atoi(fields[2])
+	}
+	if pm && hour < 12 {
+		hour += 12
+	}
+	if am && hour == 12 {
+		hour = 0
+	}
+
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}, false
+	}
+	return time.Date(year, time.Month(month), day, hour, minute, second, 0, loc), true
+}
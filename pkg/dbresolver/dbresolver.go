@@ -0,0 +1,56 @@
+package dbresolver
+
+import (
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+// replicaKey marks a statement as safe to run on a read replica
+const replicaKey = "gotalk:read_replica"
+
+// Resolver is a GORM plugin that routes reads marked with Replica to the read
+// replicas (round-robin). Everything else, and anything inside a transaction,
+// stays on the primary.
+type Resolver struct {
+	replicas []gorm.ConnPool
+	next     atomic.Uint64
+}
+
+// New returns a resolver for the given replica connection pools; with none,
+// every query stays on the primary
+func New(replicas ...gorm.ConnPool) *Resolver {
+	return &Resolver{replicas: replicas}
+}
+
+func (r *Resolver) Name() string {
+	return "gotalk:dbresolver"
+}
+
+// Initialize registers the routing callbacks (gorm.Plugin)
+func (r *Resolver) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Query().Before("gorm:query").Register("gotalk:dbresolver", r.route); err != nil {
+		return err
+	}
+	return db.Callback().Row().Before("gorm:row").Register("gotalk:dbresolver", r.route)
+}
+
+func (r *Resolver) route(db *gorm.DB) {
+	if len(r.replicas) == 0 {
+		return
+	}
+	if replica, _ := db.Get(replicaKey); replica != true {
+		return
+	}
+	if _, inTx := db.Statement.ConnPool.(gorm.TxCommitter); inTx {
+		return // reads in a transaction must see its writes
+	}
+	db.Statement.ConnPool = r.replicas[(r.next.Add(1)-1)%uint64(len(r.replicas))]
+}
+
+// Replica marks the queries built from db as safe to serve from a read replica.
+// Use it for reads that tolerate replication lag (history, lists, search), never
+// for reads that must see a write the caller just made.
+func Replica(db *gorm.DB) *gorm.DB {
+	return db.Set(replicaKey, true)
+}
@@ -0,0 +1,40 @@
+// Package disposable recognizes throwaway email domains, from a list embedded
+// in domains.txt
+package disposable
+
+import (
+	_ "embed"
+	"strings"
+)
+
+//go:embed domains.txt
+var list string
+
+var domains = parse(list)
+
+func parse(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.ToLower(strings.TrimSpace(line))
+		if line != "" && !strings.HasPrefix(line, "#") {
+			set[line] = true
+		}
+	}
+	return set
+}
+
+// Domain reports whether an email domain, or a domain it is under, is disposable
+func Domain(domain string) bool {
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+	for domain != "" {
+		if domains[domain] {
+			return true
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			return false
+		}
+		domain = parent
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package errreport
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Config selects where errors are reported. Both backends may be set; with
+// neither, events are only logged.
+type Config struct {
+	SentryDSN    string
+	RollbarToken string
+	Environment  string
+	Release      string
+}
+
+// Event is one reported error, typically a recovered panic
+type Event struct {
+	ID      uuid.UUID // incident ID, also returned to the client
+	Time    time.Time
+	Message string
+	Frames  []Frame // outermost call first, the panicking function last
+	Request *Request
+	UserID  string
+	Tags    map[string]string
+}
+
+// IncidentID returns the ID in the form the backends index it by
+func (e *Event) IncidentID() string {
+	return strings.ReplaceAll(e.ID.String(), "-", "")
+}
+
+// Stack formats the frames like a goroutine trace, innermost first, for logs
+func (e *Event) Stack() string {
+	var b strings.Builder
+	for i := len(e.Frames) - 1; i >= 0; i-- {
+		fmt.Fprintf(&b, "%s\n\t%s:%d\n", e.Frames[i].Function, e.Frames[i].File, e.Frames[i].Line)
+	}
+	return b.String()
+}
+
+// Frame is one entry of a stack trace
+type Frame struct {
+	Function string // fully qualified, e.g. github.com/x/y/pkg.(*T).Method
+	File     string
+	Line     int
+	InApp    bool // belongs to this module rather than a dependency or the runtime
+}
+
+// Request is the HTTP request being served when the error happened
+type Request struct {
+	Method     string
+	URL        string
+	Route      string
+	RemoteAddr string
+	Headers    map[string]string
+}
+
+// backend sends events to an error tracking service
+type backend interface {
+	Name() string
+	Send(ctx context.Context, r *Reporter, event *Event) error
+}
+
+// maxInFlight bounds concurrent sends so a panic storm can't pile up goroutines
+const maxInFlight = 16
+
+// Reporter sends events to the configured backends in the background. A nil
+// *Reporter only logs.
+type Reporter struct {
+	backends    []backend
+	client      *http.Client
+	environment string
+	release     string
+	serverName  string
+	module      string // main module path, to tell application frames apart
+	inFlight    chan struct{}
+}
+
+// New creates a Reporter for the configured backends
+func New(cfg Config) (*Reporter, error) {
+	r := &Reporter{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		environment: cfg.Environment,
+		release:     cfg.Release,
+		inFlight:    make(chan struct{}, maxInFlight),
+	}
+	r.serverName, _ = os.Hostname()
+	if info, ok := debug.ReadBuildInfo(); ok {
+		r.module = info.Main.Path
+		if r.release == "" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			r.release = info.Main.Version
+		}
+	}
+
+	if cfg.SentryDSN != "" {
+		b, err := newSentryBackend(cfg.SentryDSN)
+		if err != nil {
+			return nil, err
+		}
+		r.backends = append(r.backends, b)
+	}
+	if cfg.RollbarToken != "" {
+		r.backends = append(r.backends, newRollbarBackend(cfg.RollbarToken))
+	}
+	return r, nil
+}
+
+// Backends returns the names of the configured backends
+func (r *Reporter) Backends() []string {
+	if r == nil {
+		return nil
+	}
+	names := make([]string, len(r.backends))
+	for i, b := range r.backends {
+		names[i] = b.Name()
+	}
+	return names
+}
+
+// Report logs the event with its stack trace and sends it to every backend
+// without blocking the caller. Events are dropped (but still logged) when too
+// many sends are already in flight.
+func (r *Reporter) Report(event *Event) {
+	log.Printf("🔥 Incident %s: %s\n%s", event.IncidentID(), event.Message, event.Stack())
+	if r == nil || len(r.backends) == 0 {
+		return
+	}
+
+	select {
+	case r.inFlight <- struct{}{}:
+	default:
+		log.Printf("⚠️  Error reporting backlog full, incident %s not sent", event.IncidentID())
+		return
+	}
+	go func() {
+		defer func() { <-r.inFlight }()
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		for _, b := range r.backends {
+			if err := b.Send(ctx, r, event); err != nil {
+				log.Printf("⚠️  Failed to report incident %s to %s: %v", event.IncidentID(), b.Name(), err)
+			}
+		}
+	}()
+}
+
+// PanicEvent builds an event for a value returned by recover(), with the stack
+// of the panicking goroutine. Call it from the deferred function that recovers.
+func (r *Reporter) PanicEvent(recovered interface{}) *Event {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []Frame
+	for {
+		frame, more := frames.Next()
+		if frame.Function == "runtime.gopanic" {
+			// Everything so far is the recovery code itself
+			stack = stack[:0]
+		} else {
+			stack = append(stack, Frame{
+				Function: frame.Function,
+				File:     frame.File,
+				Line:     frame.Line,
+				InApp:    r.inApp(frame.Function),
+			})
+		}
+		if !more {
+			break
+		}
+	}
+	// Outermost call first, like the backends expect
+	for i, j := 0, len(stack)-1; i < j; i, j = i+1, j-1 {
+		stack[i], stack[j] = stack[j], stack[i]
+	}
+
+	return &Event{
+		ID:      uuid.New(),
+		Time:    time.Now(),
+		Message: fmt.Sprint(recovered),
+		Frames:  stack,
+		Tags:    map[string]string{},
+	}
+}
+
+func (r *Reporter) inApp(function string) bool {
+	if r == nil || r.module == "" {
+		return false
+	}
+	return strings.HasPrefix(function, r.module+"/") || strings.HasPrefix(function, r.module+".")
+}
+
+// splitFunction splits a qualified function name into its package path and name:
+// github.com/x/y/pkg.(*T).Method -> github.com/x/y/pkg, (*T).Method
+func splitFunction(function string) (pkg, name string) {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot], function[slash+2+dot:]
+	}
+	return "", function
+}
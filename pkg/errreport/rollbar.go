@@ -0,0 +1,89 @@
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const rollbarEndpoint = "https://api.rollbar.com/api/1/item/"
+
+// rollbarBackend sends events to Rollbar with a project access token (post_server_item scope)
+type rollbarBackend struct {
+	token string
+}
+
+func newRollbarBackend(token string) *rollbarBackend {
+	return &rollbarBackend{token: token}
+}
+
+func (b *rollbarBackend) Name() string {
+	return "rollbar"
+}
+
+func (b *rollbarBackend) Send(ctx context.Context, r *Reporter, event *Event) error {
+	frames := make([]map[string]interface{}, len(event.Frames))
+	for i, f := range event.Frames {
+		frames[i] = map[string]interface{}{
+			"filename": f.File,
+			"lineno":   f.Line,
+			"method":   f.Function,
+		}
+	}
+
+	data := map[string]interface{}{
+		"uuid":         event.ID.String(),
+		"timestamp":    event.Time.Unix(),
+		"level":        "critical",
+		"environment":  r.environment,
+		"code_version": r.release,
+		"platform":     "go",
+		"language":     "go",
+		"server":       map[string]string{"host": r.serverName},
+		"custom":       event.Tags,
+		"body": map[string]interface{}{
+			"trace": map[string]interface{}{
+				"frames":    frames,
+				"exception": map[string]string{"class": "panic", "message": event.Message},
+			},
+		},
+	}
+	if event.Request != nil {
+		data["request"] = map[string]interface{}{
+			"url":     event.Request.URL,
+			"method":  event.Request.Method,
+			"headers": event.Request.Headers,
+			"user_ip": event.Request.RemoteAddr,
+		}
+		data["context"] = event.Request.Method + " " + event.Request.Route
+	}
+	if event.UserID != "" {
+		data["person"] = map[string]string{"id": event.UserID}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"data": data})
+	if err != nil {
+		return fmt.Errorf("failed to encode rollbar item: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rollbarEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Rollbar-Access-Token", b.token)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("rollbar returned %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// sentryBackend sends events to Sentry's store endpoint, derived from a DSN like
+// https://<public key>@o123.ingest.sentry.io/<project id>
+type sentryBackend struct {
+	endpoint  string
+	publicKey string
+}
+
+func newSentryBackend(dsn string) (*sentryBackend, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User == nil {
+		return nil, fmt.Errorf("invalid Sentry DSN")
+	}
+	path := strings.TrimSuffix(u.Path, "/")
+	slash := strings.LastIndex(path, "/")
+	projectID := path[slash+1:]
+	if projectID == "" {
+		return nil, fmt.Errorf("invalid Sentry DSN: missing project ID")
+	}
+	return &sentryBackend{
+		endpoint:  fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, path[:slash], projectID),
+		publicKey: u.User.Username(),
+	}, nil
+}
+
+func (b *sentryBackend) Name() string {
+	return "sentry"
+}
+
+func (b *sentryBackend) Send(ctx context.Context, r *Reporter, event *Event) error {
+	frames := make([]map[string]interface{}, len(event.Frames))
+	for i, f := range event.Frames {
+		module, function := splitFunction(f.Function)
+		frames[i] = map[string]interface{}{
+			"function": function,
+			"module":   module,
+			"abs_path": f.File,
+			"lineno":   f.Line,
+			"in_app":   f.InApp,
+		}
+	}
+
+	payload := map[string]interface{}{
+		"event_id":    event.IncidentID(),
+		"timestamp":   event.Time.UTC().Format("2006-01-02T15:04:05.000Z"),
+		"level":       "fatal",
+		"platform":    "go",
+		"logger":      "gotalk",
+		"server_name": r.serverName,
+		"environment": r.environment,
+		"release":     r.release,
+		"tags":        event.Tags,
+		"exception": map[string]interface{}{
+			"values": []map[string]interface{}{{
+				"type":       "panic",
+				"value":      event.Message,
+				"mechanism":  map[string]interface{}{"type": "recover", "handled": true},
+				"stacktrace": map[string]interface{}{"frames": frames},
+			}},
+		},
+	}
+	if event.Request != nil {
+		payload["request"] = map[string]interface{}{
+			"method":  event.Request.Method,
+			"url":     event.Request.URL,
+			"headers": event.Request.Headers,
+		}
+		payload["transaction"] = event.Request.Method + " " + event.Request.Route
+	}
+	if event.UserID != "" || event.Request != nil {
+		user := map[string]string{"id": event.UserID}
+		if event.Request != nil {
+			user["ip_address"] = event.Request.RemoteAddr
+		}
+		payload["user"] = user
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode sentry event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", "Sentry sentry_version=7, sentry_client=gotalk/1.0, sentry_key="+b.publicKey)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("sentry returned %d: %s", resp.StatusCode, strings.TrimSpace(string(raw)))
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Package geoip locates IP addresses with MaxMind's GeoIP2 / GeoLite2 City
+// web service. GeoLite2 accounts are free and use the geolite.info host.
+package geoip
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultHost serves the free GeoLite2 web service; paid GeoIP2 accounts use geoip.maxmind.com
+const DefaultHost = "geolite.info"
+
+// Config holds MaxMind web service credentials
+type Config struct {
+	AccountID  string
+	LicenseKey string
+	Host       string // DefaultHost when empty
+}
+
+// Location is where an IP address is, as far as MaxMind knows; fields may be empty
+type Location struct {
+	City        string
+	Country     string
+	CountryCode string // ISO 3166-1 alpha-2
+}
+
+// String formats the location as "City, Country", or whichever part is known
+func (l Location) String() string {
+	switch {
+	case l.City != "" && l.Country != "":
+		return l.City + ", " + l.Country
+	case l.Country != "":
+		return l.Country
+	default:
+		return l.City
+	}
+}
+
+// Locator looks up IP addresses. A nil *Locator finds nothing.
+type Locator struct {
+	cfg    Config
+	client *http.Client
+}
+
+// New creates a locator; without credentials it returns nil, which disables lookups
+func New(cfg Config) *Locator {
+	if cfg.AccountID == "" || cfg.LicenseKey == "" {
+		return nil
+	}
+	if cfg.Host == "" {
+		cfg.Host = DefaultHost
+	}
+	return &Locator{cfg: cfg, client: &http.Client{Timeout: 3 * time.Second}}
+}
+
+// city is the part of the City response we use
+type city struct {
+	City struct {
+		Names map[string]string `json:"names"`
+	} `json:"city"`
+	Country struct {
+		ISOCode string            `json:"iso_code"`
+		Names   map[string]string `json:"names"`
+	} `json:"country"`
+}
+
+// Lookup returns the location of an IP address. Private, loopback and
+// unparseable addresses, and a nil locator, give an empty location.
+func (l *Locator) Lookup(ctx context.Context, ip string) (Location, error) {
+	addr := net.ParseIP(ip)
+	if l == nil || addr == nil || addr.IsPrivate() || addr.IsLoopback() || addr.IsUnspecified() || addr.IsLinkLocalUnicast() {
+		return Location{}, nil
+	}
+
+	url := "https://" + l.cfg.Host + "/geoip/v2.1/city/" + addr.String()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Location{}, err
+	}
+	req.SetBasicAuth(l.cfg.AccountID, l.cfg.LicenseKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return Location{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return Location{}, nil // e.g. IP_ADDRESS_NOT_FOUND, IP_ADDRESS_RESERVED
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Location{}, fmt.Errorf("geoip: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var result city
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Location{}, fmt.Errorf("geoip: invalid response: %w", err)
+	}
+	return Location{
+		City:        result.City.Names["en"],
+		Country:     result.Country.Names["en"],
+		CountryCode: result.Country.ISOCode,
+	}, nil
+}
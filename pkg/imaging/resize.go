@@ -0,0 +1,177 @@
+package imaging
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // register GIF decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// Fit defines how an image is fitted into the requested box
+type Fit string
+
+const (
+	FitContain Fit = "contain" // scale down to fit inside the box, keep aspect ratio
+	FitCover   Fit = "cover"   // scale to fill the box, keep aspect ratio, crop the overflow
+	FitFill    Fit = "fill"    // stretch to exactly the box, ignore aspect ratio
+)
+
+// MaxSourcePixels guards against decompression bombs (40 megapixels)
+const MaxSourcePixels = 40_000_000
+
+// ErrUnsupportedFormat is returned for formats the standard library cannot decode (e.g. webp)
+var ErrUnsupportedFormat = errors.New("unsupported image format")
+
+// ParseFit parses a fit mode, defaulting to contain
+func ParseFit(s string) (Fit, bool) {
+	switch Fit(s) {
+	case "", FitContain:
+		return FitContain, true
+	case FitCover:
+		return FitCover, true
+	case FitFill:
+		return FitFill, true
+	}
+	return "", false
+}
+
+// Decode reads an image, rejecting unsupported formats and oversized sources
+func Decode(r io.ReadSeeker) (image.Image, string, error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil, "", ErrUnsupportedFormat
+		}
+		return nil, "", err
+	}
+	if cfg.Width*cfg.Height > MaxSourcePixels {
+		return nil, "", fmt.Errorf("image too large: %dx%d", cfg.Width, cfg.Height)
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, "", err
+	}
+	img, format, err := image.Decode(r)
+	if err != nil {
+		return nil, "", err
+	}
+	return img, format, nil
+}
+
+// Encode writes the image in the given format. GIFs are re-encoded as PNG
+// (only the first frame is resized). Returns the resulting MIME type.
+func Encode(w io.Writer, img image.Image, format string) (string, error) {
+	switch format {
+	case "jpeg":
+		return "image/jpeg", jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	case "png", "gif":
+		return "image/png", png.Encode(w, img)
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// Resize fits src into a width x height box. A zero width or height is derived
+// from the aspect ratio. Images are never upscaled beyond their original size.
+func Resize(src image.Image, width, height int, fit Fit) image.Image {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if sw == 0 || sh == 0 {
+		return src
+	}
+
+	// Derive missing dimension from the aspect ratio
+	if width <= 0 && height <= 0 {
+		return src
+	}
+	if width <= 0 {
+		width = max(1, sw*height/sh)
+		fit = FitFill
+	}
+	if height <= 0 {
+		height = max(1, sh*width/sw)
+		fit = FitFill
+	}
+
+	crop := b
+	switch fit {
+	case FitContain:
+		// Shrink the box to the source aspect ratio
+		if sw*height > sh*width {
+			height = max(1, sh*width/sw)
+		} else {
+			width = max(1, sw*height/sh)
+		}
+	case FitCover:
+		// Crop the source (centered) to the box aspect ratio
+		if sw*height > sh*width {
+			cw := sh * width / height
+			crop.Min.X += (sw - cw) / 2
+			crop.Max.X = crop.Min.X + cw
+		} else {
+			ch := sw * height / width
+			crop.Min.Y += (sh - ch) / 2
+			crop.Max.Y = crop.Min.Y + ch
+		}
+	}
+
+	// Never upscale
+	if width > crop.Dx() || height > crop.Dy() {
+		scale := min(float64(crop.Dx())/float64(width), float64(crop.Dy())/float64(height))
+		width = max(1, int(float64(width)*scale))
+		height = max(1, int(float64(height)*scale))
+	}
+
+	return resample(toRGBA(src), crop, width, height)
+}
+
+// toRGBA converts any image to *image.RGBA (premultiplied) for fast pixel access
+func toRGBA(src image.Image) *image.RGBA {
+	if rgba, ok := src.(*image.RGBA); ok {
+		return rgba
+	}
+	b := src.Bounds()
+	dst := image.NewRGBA(b)
+	draw.Draw(dst, b, src, b.Min, draw.Src)
+	return dst
+}
+
+// resample scales the crop rectangle of src to dw x dh using a box filter
+// (area averaging), which gives good quality for downscaling
+func resample(src *image.RGBA, crop image.Rectangle, dw, dh int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	cw, ch := crop.Dx(), crop.Dy()
+
+	for y := 0; y < dh; y++ {
+		sy0 := crop.Min.Y + y*ch/dh
+		sy1 := max(sy0+1, crop.Min.Y+(y+1)*ch/dh)
+
+		for x := 0; x < dw; x++ {
+			sx0 := crop.Min.X + x*cw/dw
+			sx1 := max(sx0+1, crop.Min.X+(x+1)*cw/dw)
+
+			var r, g, bl, a, n uint32
+			for sy := sy0; sy < sy1; sy++ {
+				off := src.PixOffset(sx0, sy)
+				for sx := sx0; sx < sx1; sx++ {
+					r += uint32(src.Pix[off])
+					g += uint32(src.Pix[off+1])
+					bl += uint32(src.Pix[off+2])
+					a += uint32(src.Pix[off+3])
+					off += 4
+					n++
+				}
+			}
+
+			d := dst.PixOffset(x, y)
+			dst.Pix[d] = uint8(r / n)
+			dst.Pix[d+1] = uint8(g / n)
+			dst.Pix[d+2] = uint8(bl / n)
+			dst.Pix[d+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
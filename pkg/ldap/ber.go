@@ -0,0 +1,212 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BER (X.690) encoding of the subset of ASN.1 that LDAP uses: single-byte
+// tags and definite lengths
+
+const (
+	classUniversal   byte = 0x00
+	classApplication byte = 0x40
+	classContext     byte = 0x80
+	constructedBit   byte = 0x20
+
+	tagBoolean     = 1
+	tagInteger     = 2
+	tagOctetString = 4
+	tagEnumerated  = 10
+	tagSequence    = 16
+
+	maxPacketSize = 16 << 20 // larger responses are refused rather than buffered
+)
+
+var errMalformed = errors.New("ldap: malformed BER packet")
+
+// packet is one BER element; primitive ones have a value, constructed ones children
+type packet struct {
+	class       byte
+	constructed bool
+	tag         byte
+	value       []byte
+	children    []*packet
+}
+
+func (p *packet) encode() []byte {
+	content := p.value
+	if p.constructed {
+		content = nil
+		for _, child := range p.children {
+			content = append(content, child.encode()...)
+		}
+	}
+	id := p.class | p.tag
+	if p.constructed {
+		id |= constructedBit
+	}
+	out := append([]byte{id}, encodeLength(len(content))...)
+	return append(out, content...)
+}
+
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+// parsePacket decodes one element and its children
+func parsePacket(data []byte) (*packet, []byte, error) {
+	if len(data) < 2 {
+		return nil, nil, errMalformed
+	}
+	id := data[0]
+	if id&0x1f == 0x1f {
+		return nil, nil, fmt.Errorf("ldap: multi-byte BER tags are not supported")
+	}
+	length, n, err := decodeLength(data[1:])
+	if err != nil {
+		return nil, nil, err
+	}
+	start := 1 + n
+	if length > len(data)-start {
+		return nil, nil, errMalformed
+	}
+	p := &packet{
+		class:       id & 0xc0,
+		constructed: id&constructedBit != 0,
+		tag:         id & 0x1f,
+	}
+	content := data[start : start+length]
+	if p.constructed {
+		for len(content) > 0 {
+			child, rest, err := parsePacket(content)
+			if err != nil {
+				return nil, nil, err
+			}
+			p.children = append(p.children, child)
+			content = rest
+		}
+	} else {
+		p.value = content
+	}
+	return p, data[start+length:], nil
+}
+
+// decodeLength returns a definite length and how many bytes encoded it
+func decodeLength(data []byte) (int, int, error) {
+	if len(data) == 0 {
+		return 0, 0, errMalformed
+	}
+	if data[0] < 0x80 {
+		return int(data[0]), 1, nil
+	}
+	n := int(data[0] & 0x7f)
+	if n == 0 || n > 4 || len(data) < 1+n {
+		return 0, 0, errMalformed // indefinite lengths aren't allowed in LDAP
+	}
+	length := 0
+	for _, b := range data[1 : 1+n] {
+		length = length<<8 | int(b)
+	}
+	return length, 1 + n, nil
+}
+
+// readPacket reads one complete element from the connection
+func readPacket(r *bufio.Reader) (*packet, error) {
+	header := make([]byte, 2, 6)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[1] >= 0x80 {
+		extra := make([]byte, header[1]&0x7f)
+		if _, err := io.ReadFull(r, extra); err != nil {
+			return nil, err
+		}
+		header = append(header, extra...)
+	}
+	length, _, err := decodeLength(header[1:])
+	if err != nil {
+		return nil, err
+	}
+	if length > maxPacketSize {
+		return nil, fmt.Errorf("ldap: response of %d bytes is too large", length)
+	}
+	data := make([]byte, len(header)+length)
+	copy(data, header)
+	if _, err := io.ReadFull(r, data[len(header):]); err != nil {
+		return nil, err
+	}
+	p, _, err := parsePacket(data)
+	return p, err
+}
+
+// ==================== Builders ====================
+
+func sequence(children ...*packet) *packet {
+	return &packet{class: classUniversal, constructed: true, tag: tagSequence, children: children}
+}
+
+func octetString(s string) *packet {
+	return &packet{class: classUniversal, tag: tagOctetString, value: []byte(s)}
+}
+
+func integer(n int64) *packet {
+	return &packet{class: classUniversal, tag: tagInteger, value: encodeInt(n)}
+}
+
+func enumerated(n int64) *packet {
+	return &packet{class: classUniversal, tag: tagEnumerated, value: encodeInt(n)}
+}
+
+func boolean(b bool) *packet {
+	v := byte(0)
+	if b {
+		v = 0xff
+	}
+	return &packet{class: classUniversal, tag: tagBoolean, value: []byte{v}}
+}
+
+// application builds a constructed [APPLICATION n] element
+func application(tag byte, children ...*packet) *packet {
+	return &packet{class: classApplication, constructed: true, tag: tag, children: children}
+}
+
+// contextPrimitive builds a primitive [n] element
+func contextPrimitive(tag byte, value []byte) *packet {
+	return &packet{class: classContext, tag: tag, value: value}
+}
+
+// contextConstructed builds a constructed [n] element
+func contextConstructed(tag byte, children ...*packet) *packet {
+	return &packet{class: classContext, constructed: true, tag: tag, children: children}
+}
+
+// encodeInt encodes a two's complement integer in the fewest bytes
+func encodeInt(n int64) []byte {
+	b := []byte{byte(n)}
+	for n > 127 || n < -128 {
+		n >>= 8
+		b = append([]byte{byte(n)}, b...)
+	}
+	return b
+}
+
+func decodeInt(b []byte) (int64, error) {
+	if len(b) == 0 || len(b) > 8 {
+		return 0, errMalformed
+	}
+	n := int64(int8(b[0]))
+	for _, c := range b[1:] {
+		n = n<<8 | int64(c)
+	}
+	return n, nil
+}
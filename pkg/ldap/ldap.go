@@ -0,0 +1,420 @@
+// Package ldap is a small LDAPv3 client: simple bind, paged subtree search
+// and StartTLS, which is what password verification and directory sync need
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Protocol operation tags (RFC 4511 section 4.2 onwards)
+const (
+	opBindRequest      = 0
+	opBindResponse     = 1
+	opUnbindRequest    = 2
+	opSearchRequest    = 3
+	opSearchEntry      = 4
+	opSearchDone       = 5
+	opSearchReference  = 19
+	opExtendedRequest  = 23
+	opExtendedResponse = 24
+)
+
+const (
+	protocolVersion  = 3
+	derefNever       = 0
+	tagSimpleAuth    = 0 // [0] in BindRequest.authentication
+	tagControls      = 0 // [0] in LDAPMessage
+	pagedResultsOID  = "1.2.840.113556.1.4.319"
+	startTLSOID      = "1.3.6.1.4.1.1466.20037"
+	defaultOpTimeout = 30 * time.Second
+)
+
+// Result codes the callers care about
+const (
+	ResultSuccess            = 0
+	ResultSizeLimitExceeded  = 4
+	ResultNoSuchObject       = 32
+	ResultInvalidCredentials = 49
+)
+
+// Search scopes
+type Scope int
+
+const (
+	ScopeBaseObject   Scope = 0
+	ScopeSingleLevel  Scope = 1
+	ScopeWholeSubtree Scope = 2
+)
+
+// ErrEmptyPassword is returned by Bind for an empty password: servers treat
+// that as an unauthenticated bind, which succeeds for any DN
+var ErrEmptyPassword = errors.New("ldap: empty password")
+
+// Error is a non-success result from the server
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ldap: result code %d", e.Code)
+	}
+	return fmt.Sprintf("ldap: result code %d: %s", e.Code, e.Message)
+}
+
+// IsInvalidCredentials reports whether err is a bind rejected for a wrong DN or password
+func IsInvalidCredentials(err error) bool {
+	var ldapErr *Error
+	return errors.As(err, &ldapErr) && ldapErr.Code == ResultInvalidCredentials
+}
+
+// Conn is a connection to an LDAP server. Operations run one at a time.
+type Conn struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	lastID int64
+}
+
+// Dial connects to an ldap:// or ldaps:// URL. tlsConfig is used for ldaps
+// and StartTLS; nil verifies the server against the system roots.
+func Dial(ctx context.Context, rawURL string, tlsConfig *tls.Config) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: invalid URL: %w", err)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		switch u.Scheme {
+		case "ldap":
+			host = net.JoinHostPort(u.Hostname(), "389")
+		case "ldaps":
+			host = net.JoinHostPort(u.Hostname(), "636")
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: defaultOpTimeout}
+	var conn net.Conn
+	switch u.Scheme {
+	case "ldap":
+		conn, err = dialer.DialContext(ctx, "tcp", host)
+	case "ldaps":
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: withServerName(tlsConfig, u.Hostname())}).DialContext(ctx, "tcp", host)
+	default:
+		return nil, fmt.Errorf("ldap: unsupported URL scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+func withServerName(cfg *tls.Config, host string) *tls.Config {
+	if cfg == nil {
+		cfg = &tls.Config{}
+	}
+	cfg = cfg.Clone()
+	if cfg.ServerName == "" {
+		cfg.ServerName = host
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+	return cfg
+}
+
+// StartTLS upgrades a plain ldap:// connection to TLS
+func (c *Conn) StartTLS(ctx context.Context, tlsConfig *tls.Config) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resp, err := c.roundTrip(ctx, application(opExtendedRequest, contextPrimitive(0, []byte(startTLSOID))), nil, opExtendedResponse)
+	if err != nil {
+		return err
+	}
+	if err := resultError(resp); err != nil {
+		return err
+	}
+
+	host, _, _ := net.SplitHostPort(c.conn.RemoteAddr().String())
+	tlsConn := tls.Client(c.conn, withServerName(tlsConfig, host))
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return fmt.Errorf("ldap: StartTLS handshake: %w", err)
+	}
+	c.conn = tlsConn
+	c.reader = bufio.NewReader(tlsConn)
+	return nil
+}
+
+// Bind authenticates the connection as dn with a simple bind. Wrong
+// credentials give an error for which IsInvalidCredentials is true.
+func (c *Conn) Bind(ctx context.Context, dn, password string) error {
+	if password == "" {
+		return ErrEmptyPassword
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	req := application(opBindRequest,
+		integer(protocolVersion),
+		octetString(dn),
+		contextPrimitive(tagSimpleAuth, []byte(password)),
+	)
+	resp, err := c.roundTrip(ctx, req, nil, opBindResponse)
+	if err != nil {
+		return err
+	}
+	return resultError(resp)
+}
+
+// SearchRequest describes a search
+type SearchRequest struct {
+	BaseDN     string
+	Scope      Scope
+	Filter     string   // RFC 4515, e.g. (&(objectClass=person)(mail=a@b.c))
+	Attributes []string // empty returns all user attributes
+	SizeLimit  int      // 0 means the server's limit
+	PageSize   int      // fetch in pages of this many entries; 0 disables paging
+}
+
+// Entry is one search result
+type Entry struct {
+	DN         string
+	Attributes map[string][]string // keyed by lowercase attribute name; values may be binary
+}
+
+// Get returns the first value of an attribute, or ""
+func (e *Entry) Get(attr string) string {
+	if values := e.Attributes[strings.ToLower(attr)]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// GetAll returns every value of an attribute
+func (e *Entry) GetAll(attr string) []string {
+	return e.Attributes[strings.ToLower(attr)]
+}
+
+// Search runs a search and returns every matching entry, following the paged
+// results control when PageSize is set. Referrals are ignored.
+func (c *Conn) Search(ctx context.Context, req SearchRequest) ([]*Entry, error) {
+	filter, err := compileFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	attrs := sequence()
+	for _, attr := range req.Attributes {
+		attrs.children = append(attrs.children, octetString(attr))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var entries []*Entry
+	cookie := ""
+	for {
+		op := application(opSearchRequest,
+			octetString(req.BaseDN),
+			enumerated(int64(req.Scope)),
+			enumerated(derefNever),
+			integer(int64(req.SizeLimit)),
+			integer(0),
+			boolean(false),
+			filter,
+			attrs,
+		)
+		var controls []*packet
+		if req.PageSize > 0 {
+			controls = append(controls, pagedResultsControl(req.PageSize, cookie))
+		}
+		id, err := c.send(ctx, op, controls)
+		if err != nil {
+			return nil, err
+		}
+
+		cookie = ""
+		for done := false; !done; {
+			msg, err := c.receive(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			op := msg.children[1]
+			switch {
+			case op.class != classApplication:
+				return nil, errMalformed
+			case op.tag == opSearchEntry:
+				entry, err := parseEntry(op)
+				if err != nil {
+					return nil, err
+				}
+				entries = append(entries, entry)
+			case op.tag == opSearchReference:
+			case op.tag == opSearchDone:
+				if err := resultError(op); err != nil {
+					return entries, err
+				}
+				cookie = pagedResultsCookie(msg)
+				done = true
+			default:
+				return nil, fmt.Errorf("ldap: unexpected response tag %d", op.tag)
+			}
+		}
+		if req.PageSize == 0 || cookie == "" {
+			return entries, nil
+		}
+	}
+}
+
+// Close sends an unbind and closes the connection
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lastID++
+	unbind := &packet{class: classApplication, tag: opUnbindRequest}
+	c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	c.conn.Write(sequence(integer(c.lastID), unbind).encode())
+	return c.conn.Close()
+}
+
+// ==================== Messages ====================
+
+// roundTrip sends a request and returns its single response operation. Callers hold c.mu.
+func (c *Conn) roundTrip(ctx context.Context, op *packet, controls []*packet, responseTag byte) (*packet, error) {
+	id, err := c.send(ctx, op, controls)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := c.receive(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	resp := msg.children[1]
+	if resp.class != classApplication || resp.tag != responseTag {
+		return nil, fmt.Errorf("ldap: unexpected response tag %d", resp.tag)
+	}
+	return resp, nil
+}
+
+// send writes an LDAPMessage and returns its message ID. Callers hold c.mu.
+func (c *Conn) send(ctx context.Context, op *packet, controls []*packet) (int64, error) {
+	c.lastID++
+	msg := sequence(integer(c.lastID), op)
+	if len(controls) > 0 {
+		msg.children = append(msg.children, contextConstructed(tagControls, controls...))
+	}
+
+	defer c.watch(ctx)()
+	if _, err := c.conn.Write(msg.encode()); err != nil {
+		return 0, err
+	}
+	return c.lastID, nil
+}
+
+// receive reads the next message for id. Callers hold c.mu.
+func (c *Conn) receive(ctx context.Context, id int64) (*packet, error) {
+	defer c.watch(ctx)()
+	for {
+		msg, err := readPacket(c.reader)
+		if err != nil {
+			return nil, err
+		}
+		if msg.tag != tagSequence || len(msg.children) < 2 {
+			return nil, errMalformed
+		}
+		msgID, err := decodeInt(msg.children[0].value)
+		if err != nil {
+			return nil, err
+		}
+		switch msgID {
+		case id:
+			return msg, nil
+		case 0:
+			// Unsolicited notification, in practice a notice of disconnection
+			if err := resultError(msg.children[1]); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("ldap: server closed the connection")
+		}
+	}
+}
+
+// watch applies ctx's deadline, or the default timeout, to the connection
+// and interrupts blocked I/O when ctx is cancelled. Call the returned func when done.
+func (c *Conn) watch(ctx context.Context) func() {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultOpTimeout)
+	}
+	c.conn.SetDeadline(deadline)
+	conn := c.conn
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	return func() { stop() }
+}
+
+// resultError decodes an LDAPResult and returns an *Error unless it's a success
+func resultError(op *packet) error {
+	if len(op.children) < 3 {
+		return errMalformed
+	}
+	code, err := decodeInt(op.children[0].value)
+	if err != nil {
+		return err
+	}
+	if code == ResultSuccess {
+		return nil
+	}
+	return &Error{Code: int(code), Message: string(op.children[2].value)}
+}
+
+func parseEntry(op *packet) (*Entry, error) {
+	if len(op.children) < 2 {
+		return nil, errMalformed
+	}
+	entry := &Entry{DN: string(op.children[0].value), Attributes: make(map[string][]string)}
+	for _, attr := range op.children[1].children {
+		if len(attr.children) < 2 {
+			return nil, errMalformed
+		}
+		name := strings.ToLower(string(attr.children[0].value))
+		for _, v := range attr.children[1].children {
+			entry.Attributes[name] = append(entry.Attributes[name], string(v.value))
+		}
+	}
+	return entry, nil
+}
+
+func pagedResultsControl(size int, cookie string) *packet {
+	value := sequence(integer(int64(size)), octetString(cookie)).encode()
+	return sequence(octetString(pagedResultsOID), boolean(false), octetString(string(value)))
+}
+
+// pagedResultsCookie returns the cookie for the next page, or "" after the last
+func pagedResultsCookie(msg *packet) string {
+	if len(msg.children) < 3 {
+		return ""
+	}
+	for _, control := range msg.children[2].children {
+		if len(control.children) < 2 || string(control.children[0].value) != pagedResultsOID {
+			continue
+		}
+		value := control.children[len(control.children)-1]
+		inner, _, err := parsePacket(value.value)
+		if err != nil || len(inner.children) < 2 {
+			return ""
+		}
+		return string(inner.children[1].value)
+	}
+	return ""
+}
@@ -0,0 +1,183 @@
+package mailer
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// ErrNoTextBody means an inbound email has no text/plain part to read a reply from
+var ErrNoTextBody = errors.New("inbound email has no text body")
+
+const (
+	maxInboundText = 1 << 20 // bytes of text read from an inbound email
+	maxMIMEDepth   = 5       // nested multiparts followed looking for the text
+)
+
+// InboundMail is a received email, reduced to what's needed to post a reply
+type InboundMail struct {
+	MessageID  string
+	From       string   // lowercased sender address
+	Recipients []string // lowercased To, Cc and delivery addresses
+	Text       string   // the new text, without the quoted original or signature
+}
+
+// ParseInbound reads a raw RFC 5322 email (as forwarded by inbound mail webhooks)
+func ParseInbound(r io.Reader) (*InboundMail, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email: %w", err)
+	}
+
+	from, err := msg.Header.AddressList("From")
+	if err != nil || len(from) == 0 {
+		return nil, fmt.Errorf("invalid email: no sender")
+	}
+
+	inbound := &InboundMail{
+		MessageID: strings.Trim(msg.Header.Get("Message-Id"), "<> "),
+		From:      strings.ToLower(from[0].Address),
+	}
+	for _, key := range []string{"To", "Cc", "Delivered-To", "X-Original-To"} {
+		addresses, err := msg.Header.AddressList(key)
+		if err != nil {
+			continue
+		}
+		for _, addr := range addresses {
+			inbound.Recipients = append(inbound.Recipients, strings.ToLower(addr.Address))
+		}
+	}
+
+	text, err := textBody(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body, 0)
+	if err != nil {
+		return nil, err
+	}
+	inbound.Text = StripQuotedReply(text)
+	return inbound, nil
+}
+
+// textBody finds the first text/plain part and decodes it to UTF-8
+func textBody(contentType, encoding string, body io.Reader, depth int) (string, error) {
+	mediaType, params := "text/plain", map[string]string{} // RFC 2045 default
+	if contentType != "" {
+		var err error
+		if mediaType, params, err = mime.ParseMediaType(contentType); err != nil {
+			return "", ErrNoTextBody
+		}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxMIMEDepth {
+			return "", ErrNoTextBody
+		}
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return "", ErrNoTextBody
+			}
+			if err != nil {
+				return "", err
+			}
+			if strings.HasPrefix(part.Header.Get("Content-Disposition"), "attachment") {
+				continue
+			}
+			text, err := textBody(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part, depth+1)
+			if !errors.Is(err, ErrNoTextBody) {
+				return text, err
+			}
+		}
+	}
+	if mediaType != "text/plain" {
+		return "", ErrNoTextBody
+	}
+
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, &lineJoiner{r: body})
+	}
+	if charset := strings.ToLower(params["charset"]); charset != "" && charset != "utf-8" && charset != "us-ascii" {
+		if enc, err := htmlindex.Get(charset); err == nil {
+			body = enc.NewDecoder().Reader(body)
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, maxInboundText))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// lineJoiner drops the line breaks base64 bodies are wrapped with
+type lineJoiner struct {
+	r io.Reader
+}
+
+func (l *lineJoiner) Read(p []byte) (int, error) {
+	for {
+		n, err := l.r.Read(p)
+		kept := 0
+		for _, b := range p[:n] {
+			if b != '\r' && b != '\n' {
+				p[kept] = b
+				kept++
+			}
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
+
+var (
+	// "On Mon, Jan 2, 2006 at 3:04 PM Alice <alice@example.com> wrote:" and its translations
+	attributionLine = regexp.MustCompile(`(?i)^(On|Le|Am|El|Op|Il|Em|Vào)\s.+(wrote|écrit|schrieb|escribió|schreef|scritto|escreveu|viết)\s*:$`)
+	// Outlook's separators between the reply and the original
+	originalMarker  = regexp.MustCompile(`(?i)^(-{2,}\s*Original Message\s*-{2,}|_{10,})$`)
+	mobileSignature = regexp.MustCompile(`(?i)^Sent from my \w+`)
+)
+
+// StripQuotedReply keeps the text a reply adds above the quoted original,
+// dropping the quote, its attribution line and the signature
+func StripQuotedReply(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	end := len(lines)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		next := ""
+		if i+1 < len(lines) {
+			next = strings.TrimSpace(lines[i+1])
+		}
+		// Clients wrap long attribution lines, so check it joined with the next line too
+		if strings.HasPrefix(trimmed, ">") || line == "-- " || originalMarker.MatchString(trimmed) ||
+			attributionLine.MatchString(trimmed) || attributionLine.MatchString(trimmed+" "+next) ||
+			(trimmed == "" && strings.HasPrefix(next, "From: ")) {
+			end = i
+			break
+		}
+	}
+	lines = lines[:end]
+
+	// Trailing blank lines and "Sent from my iPhone"
+	for len(lines) > 0 {
+		last := strings.TrimSpace(lines[len(lines)-1])
+		if last != "" && !mobileSignature.MatchString(last) {
+			break
+		}
+		lines = lines[:len(lines)-1]
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
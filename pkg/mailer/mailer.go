@@ -1,207 +1,220 @@
 package mailer
 
 import (
-	"bytes"
+	"context"
 	"fmt"
-	"html/template"
 	"log"
-	"net/smtp"
+	"net/http"
+	"time"
 )
 
-// Config holds SMTP configuration
+// Supported providers
+const (
+	ProviderSMTP     = "smtp"
+	ProviderSES      = "ses"
+	ProviderSendGrid = "sendgrid"
+	ProviderMailgun  = "mailgun"
+)
+
+// sendTimeout bounds a single delivery attempt for API-based providers
+const sendTimeout = 30 * time.Second
+
+// Config holds mailer configuration. Provider selects the backend; only the
+// matching provider section needs to be filled in.
 type Config struct {
-	Host     string
-	Port     string
-	Username string
-	Password string
+	Provider string
 	From     string
 	FromName string
+
+	SMTP     SMTPConfig
+	SES      SESConfig
+	SendGrid SendGridConfig
+	Mailgun  MailgunConfig
 }
 
-// Mailer handles sending emails
+// Mailer renders email templates and sends them through the configured provider
 type Mailer struct {
-	config Config
+	config   Config
+	provider Provider
+	queue    *Queue // when set, emails are queued instead of sent inline
+}
+
+// New creates a new Mailer instance with the configured provider
+func New(cfg Config) (*Mailer, error) {
+	provider, err := newProvider(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Mailer{config: cfg, provider: provider}, nil
 }
 
-// New creates a new Mailer instance
-func New(cfg Config) *Mailer {
-	return &Mailer{config: cfg}
+// newProvider builds the provider selected by config (SMTP by default)
+func newProvider(cfg Config) (Provider, error) {
+	client := &http.Client{Timeout: sendTimeout}
+
+	switch cfg.Provider {
+	case "", ProviderSMTP:
+		provider, err := newSMTPProvider(cfg.SMTP)
+		if err != nil {
+			return nil, err
+		}
+		return provider, nil
+	case ProviderSES:
+		if cfg.SES.Region == "" || cfg.SES.AccessKeyID == "" || cfg.SES.SecretAccessKey == "" {
+			return nil, fmt.Errorf("ses provider requires region, access key ID and secret access key")
+		}
+		return newSESProvider(cfg.SES, client), nil
+	case ProviderSendGrid:
+		if cfg.SendGrid.APIKey == "" {
+			return nil, fmt.Errorf("sendgrid provider requires an API key")
+		}
+		return newSendGridProvider(cfg.SendGrid, client), nil
+	case ProviderMailgun:
+		if cfg.Mailgun.Domain == "" || cfg.Mailgun.APIKey == "" {
+			return nil, fmt.Errorf("mailgun provider requires a domain and API key")
+		}
+		return newMailgunProvider(cfg.Mailgun, client), nil
+	}
+	return nil, fmt.Errorf("unknown mail provider: %q", cfg.Provider)
+}
+
+// UseQueue routes all sends through the given queue (delivered by its worker)
+func (m *Mailer) UseQueue(q *Queue) {
+	m.queue = q
+}
+
+// ProviderName returns the name of the active provider
+func (m *Mailer) ProviderName() string {
+	return m.provider.Name()
+}
+
+// DigestConversation is a conversation line in the unread digest email
+type DigestConversation struct {
+	Name        string
+	UnreadCount int64
 }
 
 // SendOTP sends an OTP verification email
 func (m *Mailer) SendOTP(toEmail, username, code string, expiryMinutes int) error {
-	subject := "GoTalk - Verify your email address"
-
-	body, err := m.renderOTPTemplate(username, code, expiryMinutes)
-	if err != nil {
-		return fmt.Errorf("failed to render email template: %w", err)
-	}
+	return m.sendTemplate(toEmail, "GoTalk - Verify your email address", emailOTP, username, map[string]interface{}{
+		"Code":          code,
+		"ExpiryMinutes": expiryMinutes,
+	})
+}
 
-	return m.send(toEmail, subject, body)
+// SendMagicLink sends a one-time sign-in link
+func (m *Mailer) SendMagicLink(toEmail, username, url string, expiryMinutes int) error {
+	return m.sendTemplate(toEmail, "GoTalk - Your sign-in link", emailMagicLink, username, map[string]interface{}{
+		"URL":           url,
+		"ExpiryMinutes": expiryMinutes,
+	})
 }
 
 // SendPasswordReset sends a password reset OTP email
 func (m *Mailer) SendPasswordReset(toEmail, username, code string, expiryMinutes int) error {
-	subject := "GoTalk - Reset your password"
+	return m.sendTemplate(toEmail, "GoTalk - Reset your password", emailPasswordReset, username, map[string]interface{}{
+		"Code":          code,
+		"ExpiryMinutes": expiryMinutes,
+	})
+}
 
-	body, err := m.renderPasswordResetTemplate(username, code, expiryMinutes)
-	if err != nil {
-		return fmt.Errorf("failed to render email template: %w", err)
-	}
+// SendWelcome sends the welcome email after a user verifies their address
+func (m *Mailer) SendWelcome(toEmail, username string) error {
+	return m.sendTemplate(toEmail, "Welcome to GoTalk 👋", emailWelcome, username, nil)
+}
 
-	return m.send(toEmail, subject, body)
+// LoginAlert is a sign-in in the new login alert email
+type LoginAlert struct {
+	Device    string
+	IP        string
+	Location  string // empty when unknown
+	At        time.Time
+	RevokeURL string // signs out everywhere; empty leaves the link out
 }
 
-// send delivers an email via SMTP
-func (m *Mailer) send(to, subject, htmlBody string) error {
-	addr := fmt.Sprintf("%s:%s", m.config.Host, m.config.Port)
+// SendNewLoginAlert warns a user about a sign-in from an unrecognized device
+func (m *Mailer) SendNewLoginAlert(toEmail, username string, alert LoginAlert) error {
+	return m.sendTemplate(toEmail, "GoTalk - New sign-in to your account", emailNewLogin, username, map[string]interface{}{
+		"Device":    alert.Device,
+		"IP":        alert.IP,
+		"Location":  alert.Location,
+		"Time":      alert.At.UTC().Format("Jan 2, 2006 15:04 UTC"),
+		"RevokeURL": alert.RevokeURL,
+	})
+}
 
-	headers := map[string]string{
-		"From":         fmt.Sprintf("%s <%s>", m.config.FromName, m.config.From),
-		"To":           to,
-		"Subject":      subject,
-		"MIME-Version": "1.0",
-		"Content-Type": "text/html; charset=\"utf-8\"",
-	}
+// SendPasswordChanged confirms that a user's password was changed
+func (m *Mailer) SendPasswordChanged(toEmail, username string, at time.Time) error {
+	return m.sendTemplate(toEmail, "GoTalk - Your password was changed", emailPasswordChanged, username, map[string]interface{}{
+		"Time": at.UTC().Format("Jan 2, 2006 15:04 UTC"),
+	})
+}
 
-	var msg bytes.Buffer
-	for k, v := range headers {
-		msg.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
-	}
-	msg.WriteString("\r\n")
-	msg.WriteString(htmlBody)
+// SendUnreadDigest sends the weekly summary of unread conversations
+func (m *Mailer) SendUnreadDigest(toEmail, username string, totalUnread int64, conversations []DigestConversation) error {
+	subject := fmt.Sprintf("GoTalk - You have %d unread messages", totalUnread)
+	return m.sendTemplate(toEmail, subject, emailUnreadDigest, username, map[string]interface{}{
+		"TotalUnread":   totalUnread,
+		"Conversations": conversations,
+	})
+}
 
-	var auth smtp.Auth
-	if m.config.Username != "" && m.config.Password != "" {
-		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
-	}
+// MessageNotification is a new message in the message notification email
+type MessageNotification struct {
+	SenderName       string
+	ConversationName string // empty for direct messages
+	Content          string
+}
 
-	err := smtp.SendMail(addr, auth, m.config.From, []string{to}, msg.Bytes())
+// SendMessageNotification tells an offline user about a new message. Replies
+// to replyTo are posted back to the conversation.
+func (m *Mailer) SendMessageNotification(toEmail, username, replyTo string, n MessageNotification) error {
+	subject := fmt.Sprintf("GoTalk - %s sent you a message", n.SenderName)
+	if n.ConversationName != "" {
+		subject = fmt.Sprintf("GoTalk - %s sent a message in %s", n.SenderName, n.ConversationName)
+	}
+	body, err := emailMessageNotification.render(username, map[string]interface{}{"Message": n})
 	if err != nil {
-		log.Printf("❌ Failed to send email to %s: %v", to, err)
-		return fmt.Errorf("failed to send email: %w", err)
+		return fmt.Errorf("failed to render email template: %w", err)
 	}
-
-	log.Printf("📧 Email sent to %s: %s", to, subject)
-	return nil
+	return m.send(Message{To: toEmail, Subject: subject, HTML: body, ReplyTo: replyTo})
 }
 
-// renderOTPTemplate returns the HTML body for OTP verification email
-func (m *Mailer) renderOTPTemplate(username, code string, expiryMinutes int) (string, error) {
-	tmpl := `<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="utf-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-</head>
-<body style="margin:0;padding:0;background-color:#0f0f23;font-family:'Segoe UI',Tahoma,Geneva,Verdana,sans-serif;">
-    <div style="max-width:500px;margin:40px auto;background:linear-gradient(135deg,#1a1a2e 0%,#16213e 100%);border-radius:16px;overflow:hidden;border:1px solid rgba(99,102,241,0.2);">
-        <!-- Header -->
-        <div style="background:linear-gradient(135deg,#6366f1 0%,#8b5cf6 100%);padding:32px;text-align:center;">
-            <h1 style="color:#fff;margin:0;font-size:28px;font-weight:700;">🚀 GoTalk</h1>
-            <p style="color:rgba(255,255,255,0.85);margin:8px 0 0;font-size:14px;">Email Verification</p>
-        </div>
-
-        <!-- Body -->
-        <div style="padding:32px;">
-            <p style="color:#e2e8f0;font-size:16px;line-height:1.6;margin:0 0 24px;">
-                Hi <strong style="color:#a78bfa;">{{.Username}}</strong>,
-            </p>
-            <p style="color:#94a3b8;font-size:14px;line-height:1.6;margin:0 0 24px;">
-                Your verification code is:
-            </p>
-
-            <!-- OTP Code -->
-            <div style="background:rgba(99,102,241,0.1);border:2px dashed rgba(99,102,241,0.4);border-radius:12px;padding:24px;text-align:center;margin:0 0 24px;">
-                <span style="font-size:36px;font-weight:800;letter-spacing:8px;color:#818cf8;font-family:'Courier New',monospace;">{{.Code}}</span>
-            </div>
-
-            <p style="color:#64748b;font-size:13px;line-height:1.5;margin:0 0 8px;">
-                ⏰ This code expires in <strong style="color:#f59e0b;">{{.ExpiryMinutes}} minutes</strong>.
-            </p>
-            <p style="color:#64748b;font-size:13px;line-height:1.5;margin:0;">
-                If you didn't create a GoTalk account, please ignore this email.
-            </p>
-        </div>
-
-        <!-- Footer -->
-        <div style="padding:16px 32px;border-top:1px solid rgba(99,102,241,0.1);text-align:center;">
-            <p style="color:#475569;font-size:12px;margin:0;">© 2026 GoTalk. All rights reserved.</p>
-        </div>
-    </div>
-</body>
-</html>`
-
-	t, err := template.New("otp").Parse(tmpl)
+// sendTemplate renders an email template and sends the result
+func (m *Mailer) sendTemplate(to, subject string, e email, username string, fields map[string]interface{}) error {
+	body, err := e.render(username, fields)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("failed to render email template: %w", err)
 	}
+	return m.send(Message{To: to, Subject: subject, HTML: body})
+}
 
-	var buf bytes.Buffer
-	err = t.Execute(&buf, map[string]interface{}{
-		"Username":      username,
-		"Code":          code,
-		"ExpiryMinutes": expiryMinutes,
-	})
-	return buf.String(), err
-}
-
-// renderPasswordResetTemplate returns the HTML body for password reset email
-func (m *Mailer) renderPasswordResetTemplate(username, code string, expiryMinutes int) (string, error) {
-	tmpl := `<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="utf-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-</head>
-<body style="margin:0;padding:0;background-color:#0f0f23;font-family:'Segoe UI',Tahoma,Geneva,Verdana,sans-serif;">
-    <div style="max-width:500px;margin:40px auto;background:linear-gradient(135deg,#1a1a2e 0%,#16213e 100%);border-radius:16px;overflow:hidden;border:1px solid rgba(239,68,68,0.2);">
-        <!-- Header -->
-        <div style="background:linear-gradient(135deg,#ef4444 0%,#dc2626 100%);padding:32px;text-align:center;">
-            <h1 style="color:#fff;margin:0;font-size:28px;font-weight:700;">🔐 GoTalk</h1>
-            <p style="color:rgba(255,255,255,0.85);margin:8px 0 0;font-size:14px;">Password Reset</p>
-        </div>
-
-        <!-- Body -->
-        <div style="padding:32px;">
-            <p style="color:#e2e8f0;font-size:16px;line-height:1.6;margin:0 0 24px;">
-                Hi <strong style="color:#fca5a5;">{{.Username}}</strong>,
-            </p>
-            <p style="color:#94a3b8;font-size:14px;line-height:1.6;margin:0 0 24px;">
-                We received a request to reset your password. Use this code:
-            </p>
-
-            <!-- OTP Code -->
-            <div style="background:rgba(239,68,68,0.1);border:2px dashed rgba(239,68,68,0.4);border-radius:12px;padding:24px;text-align:center;margin:0 0 24px;">
-                <span style="font-size:36px;font-weight:800;letter-spacing:8px;color:#f87171;font-family:'Courier New',monospace;">{{.Code}}</span>
-            </div>
-
-            <p style="color:#64748b;font-size:13px;line-height:1.5;margin:0 0 8px;">
-                ⏰ This code expires in <strong style="color:#f59e0b;">{{.ExpiryMinutes}} minutes</strong>.
-            </p>
-            <p style="color:#64748b;font-size:13px;line-height:1.5;margin:0;">
-                If you didn't request a password reset, please ignore this email and your password will remain unchanged.
-            </p>
-        </div>
-
-        <!-- Footer -->
-        <div style="padding:16px 32px;border-top:1px solid rgba(239,68,68,0.1);text-align:center;">
-            <p style="color:#475569;font-size:12px;margin:0;">© 2026 GoTalk. All rights reserved.</p>
-        </div>
-    </div>
-</body>
-</html>`
-
-	t, err := template.New("reset").Parse(tmpl)
-	if err != nil {
-		return "", err
+// send queues an email from the configured sender, or delivers it inline when
+// no queue is configured
+func (m *Mailer) send(msg Message) error {
+	msg.FromEmail = m.config.From
+	msg.FromName = m.config.FromName
+
+	if m.queue != nil {
+		if err := m.queue.Enqueue(context.Background(), msg); err != nil {
+			return fmt.Errorf("failed to queue email: %w", err)
+		}
+		return nil
 	}
 
-	var buf bytes.Buffer
-	err = t.Execute(&buf, map[string]interface{}{
-		"Username":      username,
-		"Code":          code,
-		"ExpiryMinutes": expiryMinutes,
-	})
-	return buf.String(), err
+	return m.Deliver(context.Background(), msg)
+}
+
+// Deliver sends an email through the configured provider right away
+func (m *Mailer) Deliver(ctx context.Context, msg Message) error {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+
+	if err := m.provider.Send(ctx, msg); err != nil {
+		log.Printf("❌ Failed to send email to %s via %s: %v", msg.To, m.provider.Name(), err)
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	log.Printf("📧 Email sent to %s via %s: %s", msg.To, m.provider.Name(), msg.Subject)
+	return nil
 }
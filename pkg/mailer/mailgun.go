@@ -0,0 +1,93 @@
+package mailer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const mailgunDefaultBaseURL = "https://api.mailgun.net"
+
+// MailgunConfig holds Mailgun API configuration
+type MailgunConfig struct {
+	Domain  string
+	APIKey  string
+	BaseURL string // https://api.eu.mailgun.net for EU domains
+}
+
+// mailgunProvider sends emails through the Mailgun Messages API
+type mailgunProvider struct {
+	config MailgunConfig
+	client *http.Client
+}
+
+func newMailgunProvider(cfg MailgunConfig, client *http.Client) *mailgunProvider {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = mailgunDefaultBaseURL
+	}
+	return &mailgunProvider{config: cfg, client: client}
+}
+
+func (p *mailgunProvider) Name() string {
+	return "mailgun"
+}
+
+// Send delivers an email via the Mailgun API
+func (p *mailgunProvider) Send(ctx context.Context, msg Message) error {
+	form := url.Values{}
+	form.Set("from", fmt.Sprintf("%s <%s>", msg.FromName, msg.FromEmail))
+	form.Set("to", msg.To)
+	form.Set("subject", msg.Subject)
+	form.Set("html", msg.HTML)
+	if msg.ReplyTo != "" {
+		form.Set("h:Reply-To", msg.ReplyTo)
+	}
+
+	endpoint := fmt.Sprintf("%s/v3/%s/messages", strings.TrimRight(p.config.BaseURL, "/"), p.config.Domain)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth("api", p.config.APIKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return &SendError{Provider: p.Name(), Kind: ErrUnavailable, Message: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	return p.mapError(resp)
+}
+
+// mapError reads Mailgun's {"message": "..."} response
+func (p *mailgunProvider) mapError(resp *http.Response) error {
+	var payload struct {
+		Message string `json:"message"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err := json.Unmarshal(raw, &payload); err != nil || payload.Message == "" {
+		payload.Message = strings.TrimSpace(string(raw))
+	}
+
+	kind := kindFromHTTPStatus(resp.StatusCode)
+	lower := strings.ToLower(payload.Message)
+	if resp.StatusCode == http.StatusBadRequest && (strings.Contains(lower, "to parameter") || strings.Contains(lower, "recipient")) {
+		kind = ErrInvalidRecipient
+	}
+
+	return &SendError{
+		Provider: p.Name(),
+		Kind:     kind,
+		Code:     strconv.Itoa(resp.StatusCode),
+		Message:  payload.Message,
+	}
+}
@@ -0,0 +1,72 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Message is a provider-agnostic email
+type Message struct {
+	FromEmail string `json:"from_email"`
+	FromName  string `json:"from_name"`
+	To        string `json:"to"`
+	Subject   string `json:"subject"`
+	HTML      string `json:"html"`
+	ReplyTo   string `json:"reply_to,omitempty"`
+}
+
+// Provider delivers emails through a specific backend (SMTP, SES, SendGrid, Mailgun)
+type Provider interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+// Provider-independent error kinds. Provider errors wrap one of these so callers
+// can decide whether a send is worth retrying without knowing the backend.
+var (
+	ErrInvalidRecipient = errors.New("invalid recipient")
+	ErrAuthFailed       = errors.New("mail provider authentication failed")
+	ErrRejected         = errors.New("message rejected by mail provider")
+	ErrRateLimited      = errors.New("mail provider rate limit exceeded")
+	ErrUnavailable      = errors.New("mail provider unavailable")
+)
+
+// SendError describes a failed send with the provider's own code and message
+type SendError struct {
+	Provider string
+	Kind     error  // one of the Err* kinds above
+	Code     string // provider-specific code (SMTP reply code, SES error type, HTTP status)
+	Message  string
+}
+
+func (e *SendError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%s: %v (%s): %s", e.Provider, e.Kind, e.Code, e.Message)
+	}
+	return fmt.Sprintf("%s: %v: %s", e.Provider, e.Kind, e.Message)
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Kind
+}
+
+// IsTemporary reports whether a send error is transient and may succeed on retry
+func IsTemporary(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrUnavailable)
+}
+
+// kindFromHTTPStatus maps an HTTP API response status to an error kind
+func kindFromHTTPStatus(status int) error {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrAuthFailed
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case status >= 500:
+		return ErrUnavailable
+	default:
+		return ErrRejected
+	}
+}
@@ -0,0 +1,248 @@
+package mailer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	queueKey = "gotalk:mail:queue" // LIST of jobs ready to send
+	retryKey = "gotalk:mail:retry" // ZSET of jobs waiting for their next attempt (score = unix time)
+	deadKey  = "gotalk:mail:dead"  // HASH of job ID -> job that exhausted its retries
+
+	defaultMaxAttempts = 6
+	retryBaseDelay     = 30 * time.Second
+	retryMaxDelay      = time.Hour
+	retryPollInterval  = 5 * time.Second
+)
+
+// ErrJobNotFound is returned when a dead-lettered job does not exist
+var ErrJobNotFound = errors.New("email job not found")
+
+// Job is a queued email and its delivery history
+type Job struct {
+	ID        string     `json:"id"`
+	Message   Message    `json:"message"`
+	Attempts  int        `json:"attempts"`
+	LastError string     `json:"last_error,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	FailedAt  *time.Time `json:"failed_at,omitempty"`
+}
+
+// QueueStats summarizes the queue state
+type QueueStats struct {
+	Queued   int64 `json:"queued"`
+	Retrying int64 `json:"retrying"`
+	Dead     int64 `json:"dead"`
+}
+
+// Queue is a Redis-backed email queue with exponential-backoff retries and a
+// dead-letter set. Any instance can enqueue; workers on every instance consume.
+type Queue struct {
+	rdb         *redis.Client
+	mailer      *Mailer
+	maxAttempts int
+}
+
+// NewQueue creates a queue that delivers through the given mailer
+func NewQueue(rdb *redis.Client, mailer *Mailer) *Queue {
+	return &Queue{rdb: rdb, mailer: mailer, maxAttempts: defaultMaxAttempts}
+}
+
+// Enqueue adds an email to the queue
+func (q *Queue) Enqueue(ctx context.Context, msg Message) error {
+	job := &Job{
+		ID:        uuid.New().String(),
+		Message:   msg,
+		CreatedAt: time.Now(),
+	}
+	return q.push(ctx, job)
+}
+
+// Run starts the worker and the retry scheduler, blocking until ctx is cancelled
+func (q *Queue) Run(ctx context.Context) {
+	log.Println("📬 Email queue worker started")
+	go q.scheduleRetries(ctx)
+
+	for {
+		result, err := q.rdb.BRPop(ctx, 5*time.Second, queueKey).Result()
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if !errors.Is(err, redis.Nil) {
+				log.Printf("⚠️  Email queue error: %v", err)
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+
+		var job Job
+		if err := json.Unmarshal([]byte(result[1]), &job); err != nil {
+			log.Printf("⚠️  Dropping malformed email job: %v", err)
+			continue
+		}
+		q.process(ctx, &job)
+	}
+}
+
+// process attempts delivery and schedules a retry or dead-letters the job on failure
+func (q *Queue) process(ctx context.Context, job *Job) {
+	job.Attempts++
+	err := q.mailer.Deliver(ctx, job.Message)
+	if err == nil {
+		return
+	}
+	job.LastError = err.Error()
+
+	// Permanent failures (bad recipient, rejected, auth) won't succeed on retry
+	if !IsTemporary(err) || job.Attempts >= q.maxAttempts {
+		q.deadLetter(ctx, job)
+		return
+	}
+
+	delay := retryDelay(job.Attempts)
+	data, _ := json.Marshal(job)
+	if err := q.rdb.ZAdd(ctx, retryKey, redis.Z{
+		Score:  float64(time.Now().Add(delay).Unix()),
+		Member: data,
+	}).Err(); err != nil {
+		log.Printf("❌ Failed to schedule email retry %s: %v", job.ID, err)
+		q.deadLetter(ctx, job)
+		return
+	}
+	log.Printf("🔁 Email %s to %s failed (attempt %d/%d), retrying in %s", job.ID, job.Message.To, job.Attempts, q.maxAttempts, delay)
+}
+
+// scheduleRetries moves due jobs from the retry set back onto the queue
+func (q *Queue) scheduleRetries(ctx context.Context) {
+	ticker := time.NewTicker(retryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			due, err := q.rdb.ZRangeByScore(ctx, retryKey, &redis.ZRangeBy{
+				Min: "-inf",
+				Max: strconv.FormatInt(time.Now().Unix(), 10),
+			}).Result()
+			if err != nil {
+				continue
+			}
+			for _, member := range due {
+				// ZREM guards against another instance moving the same job
+				if removed, _ := q.rdb.ZRem(ctx, retryKey, member).Result(); removed == 1 {
+					q.rdb.LPush(ctx, queueKey, member)
+				}
+			}
+		}
+	}
+}
+
+// deadLetter stores a job that will not be retried automatically
+func (q *Queue) deadLetter(ctx context.Context, job *Job) {
+	now := time.Now()
+	job.FailedAt = &now
+	data, _ := json.Marshal(job)
+	if err := q.rdb.HSet(ctx, deadKey, job.ID, data).Err(); err != nil {
+		log.Printf("❌ Failed to dead-letter email %s: %v", job.ID, err)
+		return
+	}
+	log.Printf("☠️  Email %s to %s dead-lettered after %d attempt(s): %s", job.ID, job.Message.To, job.Attempts, job.LastError)
+}
+
+// DeadLetters returns all dead-lettered jobs, most recent failure first
+func (q *Queue) DeadLetters(ctx context.Context) ([]Job, error) {
+	raw, err := q.rdb.HGetAll(ctx, deadKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]Job, 0, len(raw))
+	for _, data := range raw {
+		var job Job
+		if err := json.Unmarshal([]byte(data), &job); err == nil {
+			jobs = append(jobs, job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].FailedAt != nil && jobs[j].FailedAt != nil && jobs[i].FailedAt.After(*jobs[j].FailedAt)
+	})
+	return jobs, nil
+}
+
+// Resend moves a dead-lettered job back onto the queue with a fresh attempt budget
+func (q *Queue) Resend(ctx context.Context, id string) error {
+	data, err := q.rdb.HGet(ctx, deadKey, id).Result()
+	if errors.Is(err, redis.Nil) {
+		return ErrJobNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var job Job
+	if err := json.Unmarshal([]byte(data), &job); err != nil {
+		return fmt.Errorf("malformed email job: %w", err)
+	}
+	job.Attempts = 0
+	job.FailedAt = nil
+
+	if err := q.push(ctx, &job); err != nil {
+		return err
+	}
+	return q.rdb.HDel(ctx, deadKey, id).Err()
+}
+
+// Discard permanently removes a dead-lettered job
+func (q *Queue) Discard(ctx context.Context, id string) error {
+	removed, err := q.rdb.HDel(ctx, deadKey, id).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+// Stats returns the number of queued, retrying and dead-lettered jobs
+func (q *Queue) Stats(ctx context.Context) (*QueueStats, error) {
+	pipe := q.rdb.Pipeline()
+	queued := pipe.LLen(ctx, queueKey)
+	retrying := pipe.ZCard(ctx, retryKey)
+	dead := pipe.HLen(ctx, deadKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	return &QueueStats{Queued: queued.Val(), Retrying: retrying.Val(), Dead: dead.Val()}, nil
+}
+
+// push serializes a job onto the ready queue
+func (q *Queue) push(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return q.rdb.LPush(ctx, queueKey, data).Err()
+}
+
+// retryDelay returns the exponential backoff for the given attempt (30s, 1m, 2m, 4m, ... capped at 1h)
+func retryDelay(attempt int) time.Duration {
+	delay := retryBaseDelay << (attempt - 1)
+	if delay <= 0 || delay > retryMaxDelay {
+		return retryMaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,101 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const sendGridEndpoint = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGridConfig holds SendGrid API configuration
+type SendGridConfig struct {
+	APIKey string
+}
+
+// sendGridProvider sends emails through the SendGrid v3 Mail Send API
+type sendGridProvider struct {
+	config SendGridConfig
+	client *http.Client
+}
+
+func newSendGridProvider(cfg SendGridConfig, client *http.Client) *sendGridProvider {
+	return &sendGridProvider{config: cfg, client: client}
+}
+
+func (p *sendGridProvider) Name() string {
+	return "sendgrid"
+}
+
+// Send delivers an email via the SendGrid API
+func (p *sendGridProvider) Send(ctx context.Context, msg Message) error {
+	payload := map[string]interface{}{
+		"personalizations": []map[string]interface{}{
+			{"to": []map[string]string{{"email": msg.To}}},
+		},
+		"from":    map[string]string{"email": msg.FromEmail, "name": msg.FromName},
+		"subject": msg.Subject,
+		"content": []map[string]string{{"type": "text/html", "value": msg.HTML}},
+	}
+	if msg.ReplyTo != "" {
+		payload["reply_to"] = map[string]string{"email": msg.ReplyTo}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode sendgrid request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sendGridEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return &SendError{Provider: p.Name(), Kind: ErrUnavailable, Message: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	return p.mapError(resp)
+}
+
+// mapError reads SendGrid's {"errors":[{"message","field"}]} response
+func (p *sendGridProvider) mapError(resp *http.Response) error {
+	var payload struct {
+		Errors []struct {
+			Message string `json:"message"`
+			Field   string `json:"field"`
+		} `json:"errors"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	_ = json.Unmarshal(raw, &payload)
+
+	kind := kindFromHTTPStatus(resp.StatusCode)
+	messages := make([]string, 0, len(payload.Errors))
+	for _, e := range payload.Errors {
+		messages = append(messages, e.Message)
+		if resp.StatusCode == http.StatusBadRequest && strings.HasPrefix(e.Field, "personalizations") {
+			kind = ErrInvalidRecipient
+		}
+	}
+	if len(messages) == 0 {
+		messages = append(messages, strings.TrimSpace(string(raw)))
+	}
+
+	return &SendError{
+		Provider: p.Name(),
+		Kind:     kind,
+		Code:     strconv.Itoa(resp.StatusCode),
+		Message:  strings.Join(messages, "; "),
+	}
+}
@@ -0,0 +1,169 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SESConfig holds Amazon SES (v2 API) configuration
+type SESConfig struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // optional, for temporary credentials
+}
+
+// sesProvider sends emails through the Amazon SES v2 SendEmail API (SigV4-signed)
+type sesProvider struct {
+	config SESConfig
+	client *http.Client
+}
+
+func newSESProvider(cfg SESConfig, client *http.Client) *sesProvider {
+	return &sesProvider{config: cfg, client: client}
+}
+
+func (p *sesProvider) Name() string {
+	return "ses"
+}
+
+// Send delivers an email via SES
+func (p *sesProvider) Send(ctx context.Context, msg Message) error {
+	payload := map[string]interface{}{
+		"FromEmailAddress": fmt.Sprintf("%s <%s>", msg.FromName, msg.FromEmail),
+		"Destination":      map[string][]string{"ToAddresses": {msg.To}},
+		"Content": map[string]interface{}{
+			"Simple": map[string]interface{}{
+				"Subject": map[string]string{"Data": msg.Subject, "Charset": "UTF-8"},
+				"Body": map[string]interface{}{
+					"Html": map[string]string{"Data": msg.HTML, "Charset": "UTF-8"},
+				},
+			},
+		},
+	}
+	if msg.ReplyTo != "" {
+		payload["ReplyToAddresses"] = []string{msg.ReplyTo}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode ses request: %w", err)
+	}
+
+	host := fmt.Sprintf("email.%s.amazonaws.com", p.config.Region)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+host+"/v2/email/outbound-emails", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	p.sign(req, host, body, time.Now().UTC())
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return &SendError{Provider: p.Name(), Kind: ErrUnavailable, Message: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	return p.mapError(resp)
+}
+
+// mapError maps SES error types (x-amzn-ErrorType header) to error kinds
+func (p *sesProvider) mapError(resp *http.Response) error {
+	var payload struct {
+		Message string `json:"message"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err := json.Unmarshal(raw, &payload); err != nil || payload.Message == "" {
+		payload.Message = strings.TrimSpace(string(raw))
+	}
+
+	// Header looks like "MessageRejected:http://internal.amazon.com/..."
+	code := strings.SplitN(resp.Header.Get("X-Amzn-Errortype"), ":", 2)[0]
+	if code == "" {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+
+	kind := kindFromHTTPStatus(resp.StatusCode)
+	switch code {
+	case "TooManyRequestsException", "ThrottlingException", "LimitExceededException":
+		kind = ErrRateLimited
+	case "MessageRejected", "MailFromDomainNotVerifiedException", "AccountSuspendedException", "SendingPausedException":
+		kind = ErrRejected
+	case "UnrecognizedClientException", "InvalidSignatureException", "AccessDeniedException":
+		kind = ErrAuthFailed
+	case "BadRequestException":
+		if strings.Contains(strings.ToLower(payload.Message), "address") {
+			kind = ErrInvalidRecipient
+		}
+	}
+
+	return &SendError{Provider: p.Name(), Kind: kind, Code: code, Message: payload.Message}
+}
+
+// sign adds AWS Signature Version 4 headers to the request
+func (p *sesProvider) sign(req *http.Request, host string, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("Host", host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if p.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", p.config.SessionToken)
+	}
+
+	signedHeaders := "content-type;host;x-amz-date"
+	canonicalHeaders := "content-type:" + req.Header.Get("Content-Type") + "\n" +
+		"host:" + host + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+	if p.config.SessionToken != "" {
+		signedHeaders += ";x-amz-security-token"
+		canonicalHeaders += "x-amz-security-token:" + p.config.SessionToken + "\n"
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + p.config.Region + "/ses/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+p.config.SecretAccessKey), date)
+	key = hmacSHA256(key, p.config.Region)
+	key = hmacSHA256(key, "ses")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		p.config.AccessKeyID, scope, signedHeaders, signature,
+	))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,247 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// SMTP TLS modes
+const (
+	SMTPTLSAuto     = "auto"     // upgrade with STARTTLS when the server offers it (implicit TLS on port 465)
+	SMTPTLSNone     = "none"     // plain connection, never upgrade
+	SMTPTLSStartTLS = "starttls" // require STARTTLS
+	SMTPTLSImplicit = "tls"      // TLS from the first byte (SMTPS)
+)
+
+const (
+	smtpDefaultTimeout  = 10 * time.Second
+	smtpDefaultPoolSize = 2
+	smtpMaxIdle         = 30 * time.Second // servers commonly drop idle clients after 30-60s
+)
+
+// SMTPConfig holds SMTP server configuration
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+
+	TLSMode  string        // auto (default), none, starttls, tls
+	Timeout  time.Duration // dial and per-command I/O timeout
+	PoolSize int           // idle connections kept open between sends
+}
+
+// smtpProvider sends emails through an SMTP server, reusing connections
+// across sends so bursts of OTP emails don't pay a handshake each time
+type smtpProvider struct {
+	config SMTPConfig
+	pool   chan *smtpConn
+}
+
+// smtpConn is an authenticated client and the raw connection used to set deadlines
+type smtpConn struct {
+	client   *smtp.Client
+	conn     net.Conn
+	lastUsed time.Time
+}
+
+func newSMTPProvider(cfg SMTPConfig) (*smtpProvider, error) {
+	switch cfg.TLSMode {
+	case "":
+		cfg.TLSMode = SMTPTLSAuto
+	case SMTPTLSAuto, SMTPTLSNone, SMTPTLSStartTLS, SMTPTLSImplicit:
+	default:
+		return nil, fmt.Errorf("unknown smtp tls mode: %q", cfg.TLSMode)
+	}
+	if cfg.TLSMode == SMTPTLSAuto && cfg.Port == "465" {
+		cfg.TLSMode = SMTPTLSImplicit
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = smtpDefaultTimeout
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = smtpDefaultPoolSize
+	}
+	return &smtpProvider{config: cfg, pool: make(chan *smtpConn, cfg.PoolSize)}, nil
+}
+
+func (p *smtpProvider) Name() string {
+	return "smtp"
+}
+
+// Send delivers an email via SMTP
+func (p *smtpProvider) Send(ctx context.Context, msg Message) error {
+	c, err := p.acquire(ctx)
+	if err != nil {
+		return p.mapError(err)
+	}
+
+	if err := p.transaction(ctx, c, msg); err != nil {
+		c.client.Close()
+		return p.mapError(err)
+	}
+
+	p.release(c)
+	return nil
+}
+
+// transaction runs MAIL/RCPT/DATA on an open connection
+func (p *smtpProvider) transaction(ctx context.Context, c *smtpConn, msg Message) error {
+	p.setDeadline(ctx, c.conn)
+
+	if err := c.client.Mail(msg.FromEmail); err != nil {
+		return err
+	}
+	if err := c.client.Rcpt(msg.To); err != nil {
+		return err
+	}
+	w, err := c.client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buildMIME(msg)); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// acquire returns a pooled connection that still answers, or dials a new one
+func (p *smtpProvider) acquire(ctx context.Context) (*smtpConn, error) {
+	for {
+		select {
+		case c := <-p.pool:
+			if time.Since(c.lastUsed) > smtpMaxIdle {
+				c.client.Close()
+				continue
+			}
+			// RSET verifies the server hasn't dropped us and clears any half-finished state
+			p.setDeadline(ctx, c.conn)
+			if err := c.client.Reset(); err != nil {
+				c.client.Close()
+				continue
+			}
+			return c, nil
+		default:
+			return p.dial(ctx)
+		}
+	}
+}
+
+// release returns a healthy connection to the pool, closing it if the pool is full
+func (p *smtpProvider) release(c *smtpConn) {
+	c.lastUsed = time.Now()
+	select {
+	case p.pool <- c:
+	default:
+		c.client.Quit()
+	}
+}
+
+// dial connects, negotiates TLS according to the configured mode and authenticates
+func (p *smtpProvider) dial(ctx context.Context) (*smtpConn, error) {
+	addr := net.JoinHostPort(p.config.Host, p.config.Port)
+	tlsConfig := &tls.Config{ServerName: p.config.Host}
+	dialer := &net.Dialer{Timeout: p.config.Timeout}
+
+	var conn net.Conn
+	var err error
+	if p.config.TLSMode == SMTPTLSImplicit {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.setDeadline(ctx, conn)
+
+	client, err := smtp.NewClient(conn, p.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if p.config.TLSMode == SMTPTLSStartTLS || p.config.TLSMode == SMTPTLSAuto {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				client.Close()
+				return nil, err
+			}
+		} else if p.config.TLSMode == SMTPTLSStartTLS {
+			client.Close()
+			return nil, errors.New("server does not support STARTTLS")
+		}
+	}
+
+	if p.config.Username != "" && p.config.Password != "" {
+		if ok, _ := client.Extension("AUTH"); ok {
+			auth := smtp.PlainAuth("", p.config.Username, p.config.Password, p.config.Host)
+			if err := client.Auth(auth); err != nil {
+				client.Close()
+				return nil, err
+			}
+		}
+	}
+
+	return &smtpConn{client: client, conn: conn}, nil
+}
+
+// setDeadline bounds the next I/O by the configured timeout or the context deadline, whichever is sooner
+func (p *smtpProvider) setDeadline(ctx context.Context, conn net.Conn) {
+	deadline := time.Now().Add(p.config.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+}
+
+// mapError converts SMTP reply codes into provider-independent error kinds
+func (p *smtpProvider) mapError(err error) error {
+	var tpErr *textproto.Error
+	if !errors.As(err, &tpErr) {
+		// Connection-level failure (DNS, refused, timeout, TLS handshake)
+		return &SendError{Provider: p.Name(), Kind: ErrUnavailable, Message: err.Error()}
+	}
+
+	kind := ErrRejected
+	switch {
+	case tpErr.Code == 530 || tpErr.Code == 534 || tpErr.Code == 535:
+		kind = ErrAuthFailed
+	case tpErr.Code == 550 || tpErr.Code == 551 || tpErr.Code == 553:
+		kind = ErrInvalidRecipient
+	case tpErr.Code >= 400 && tpErr.Code < 500:
+		// 4xx replies are transient by definition (greylisting, mailbox busy, shutting down)
+		kind = ErrUnavailable
+	}
+	return &SendError{Provider: p.Name(), Kind: kind, Code: strconv.Itoa(tpErr.Code), Message: tpErr.Msg}
+}
+
+// buildMIME renders the raw RFC 5322 message with an HTML body
+func buildMIME(msg Message) []byte {
+	headers := []struct{ key, value string }{
+		{"From", fmt.Sprintf("%s <%s>", msg.FromName, msg.FromEmail)},
+		{"To", msg.To},
+		{"Subject", msg.Subject},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/html; charset=\"utf-8\""},
+	}
+	if msg.ReplyTo != "" {
+		headers = append(headers, struct{ key, value string }{"Reply-To", msg.ReplyTo})
+	}
+
+	var buf bytes.Buffer
+	for _, h := range headers {
+		buf.WriteString(fmt.Sprintf("%s: %s\r\n", h.key, h.value))
+	}
+	buf.WriteString("\r\n")
+	buf.WriteString(msg.HTML)
+	return buf.Bytes()
+}
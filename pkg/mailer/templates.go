@@ -0,0 +1,103 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// theme holds the accent colors of an email. Values are template.CSS so
+// html/template does not sanitize rgba() values inside style attributes.
+type theme struct {
+	From           template.CSS // header gradient start
+	To             template.CSS // header gradient end
+	Border         template.CSS
+	Divider        template.CSS
+	Highlight      template.CSS // username
+	Code           template.CSS
+	CodeBackground template.CSS
+	CodeBorder     template.CSS
+}
+
+var (
+	themeIndigo = theme{
+		From: "#6366f1", To: "#8b5cf6",
+		Border: "rgba(99,102,241,0.2)", Divider: "rgba(99,102,241,0.1)",
+		Highlight: "#a78bfa", Code: "#818cf8",
+		CodeBackground: "rgba(99,102,241,0.1)", CodeBorder: "rgba(99,102,241,0.4)",
+	}
+	themeRed = theme{
+		From: "#ef4444", To: "#dc2626",
+		Border: "rgba(239,68,68,0.2)", Divider: "rgba(239,68,68,0.1)",
+		Highlight: "#fca5a5", Code: "#f87171",
+		CodeBackground: "rgba(239,68,68,0.1)", CodeBorder: "rgba(239,68,68,0.4)",
+	}
+	themeGreen = theme{
+		From: "#10b981", To: "#059669",
+		Border: "rgba(16,185,129,0.2)", Divider: "rgba(16,185,129,0.1)",
+		Highlight: "#6ee7b7", Code: "#34d399",
+		CodeBackground: "rgba(16,185,129,0.1)", CodeBorder: "rgba(16,185,129,0.4)",
+	}
+	themeAmber = theme{
+		From: "#f59e0b", To: "#d97706",
+		Border: "rgba(245,158,11,0.2)", Divider: "rgba(245,158,11,0.1)",
+		Highlight: "#fcd34d", Code: "#fbbf24",
+		CodeBackground: "rgba(245,158,11,0.1)", CodeBorder: "rgba(245,158,11,0.4)",
+	}
+)
+
+// templates maps an email name to its layout + content template set
+var templates = map[string]*template.Template{}
+
+func init() {
+	for _, name := range []string{"otp", "password_reset", "magic_link", "welcome", "new_login", "password_changed", "unread_digest", "message_notification"} {
+		templates[name] = template.Must(template.ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html"))
+	}
+}
+
+// email describes a rendered email: its header and the template-specific fields
+type email struct {
+	template string
+	icon     string
+	title    string
+	theme    theme
+}
+
+var (
+	emailOTP                 = email{template: "otp", icon: "🚀", title: "Email Verification", theme: themeIndigo}
+	emailPasswordReset       = email{template: "password_reset", icon: "🔐", title: "Password Reset", theme: themeRed}
+	emailMagicLink           = email{template: "magic_link", icon: "✨", title: "Sign in to GoTalk", theme: themeIndigo}
+	emailWelcome             = email{template: "welcome", icon: "👋", title: "Welcome to GoTalk", theme: themeIndigo}
+	emailNewLogin            = email{template: "new_login", icon: "🔔", title: "New Sign-in Detected", theme: themeAmber}
+	emailPasswordChanged     = email{template: "password_changed", icon: "✅", title: "Password Changed", theme: themeGreen}
+	emailUnreadDigest        = email{template: "unread_digest", icon: "📬", title: "Your Weekly Digest", theme: themeIndigo}
+	emailMessageNotification = email{template: "message_notification", icon: "💬", title: "New Message", theme: themeIndigo}
+)
+
+// render executes the email's template with the given username and fields
+func (e email) render(username string, fields map[string]interface{}) (string, error) {
+	t, ok := templates[e.template]
+	if !ok {
+		return "", fmt.Errorf("unknown email template: %s", e.template)
+	}
+
+	data := map[string]interface{}{
+		"Icon":     e.icon,
+		"Title":    e.title,
+		"Theme":    e.theme,
+		"Username": username,
+	}
+	for k, v := range fields {
+		data[k] = v
+	}
+
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, "layout", data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,202 @@
+// Package matrix is a minimal Matrix application service: a client for the
+// homeserver's client-server API that acts as the service's virtual users,
+// and the types of the transactions the homeserver pushes to the service.
+package matrix
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Config describes the application service registration
+type Config struct {
+	HomeserverURL string // client-server API base, e.g. https://matrix.example.com
+	ServerName    string // the part after the colon in user IDs, e.g. example.com
+	ASToken       string // as_token: authenticates the service to the homeserver
+	UserPrefix    string // localpart prefix of the service's virtual users, e.g. gotalk_
+	BotLocalpart  string // sender_localpart of the registration
+}
+
+// Error is an error response from the homeserver
+type Error struct {
+	Status  int
+	ErrCode string `json:"errcode"`
+	Message string `json:"error"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("matrix: %d %s: %s", e.Status, e.ErrCode, e.Message)
+}
+
+// IsErrCode reports whether err is a homeserver error with the given errcode
+func IsErrCode(err error, code string) bool {
+	var mErr *Error
+	return errors.As(err, &mErr) && mErr.ErrCode == code
+}
+
+// Client calls the homeserver as the application service
+type Client struct {
+	cfg    Config
+	client *http.Client
+}
+
+// New creates an application service client
+func New(cfg Config) *Client {
+	cfg.HomeserverURL = strings.TrimRight(cfg.HomeserverURL, "/")
+	return &Client{cfg: cfg, client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// UserID returns the full Matrix ID for a localpart on our server
+func (c *Client) UserID(localpart string) string {
+	return "@" + localpart + ":" + c.cfg.ServerName
+}
+
+// BotUserID is the service's own user
+func (c *Client) BotUserID() string {
+	return c.UserID(c.cfg.BotLocalpart)
+}
+
+// VirtualLocalpart returns the localpart of the virtual user standing in for a local account
+func (c *Client) VirtualLocalpart(key string) string {
+	return c.cfg.UserPrefix + key
+}
+
+// VirtualUserID returns the ID of the virtual user standing in for a local account
+func (c *Client) VirtualUserID(key string) string {
+	return c.UserID(c.VirtualLocalpart(key))
+}
+
+// IsVirtualUser reports whether a Matrix user belongs to the service's
+// namespace (its virtual users or the bot), i.e. isn't a real remote user
+func (c *Client) IsVirtualUser(userID string) bool {
+	return userID == c.BotUserID() ||
+		strings.HasPrefix(userID, "@"+c.cfg.UserPrefix) && strings.HasSuffix(userID, ":"+c.cfg.ServerName)
+}
+
+// Register creates a virtual user; registering an existing one is not an error
+func (c *Client) Register(ctx context.Context, localpart string) error {
+	err := c.do(ctx, http.MethodPost, "/_matrix/client/v3/register", "", map[string]string{
+		"type":     "m.login.application_service",
+		"username": localpart,
+	}, nil)
+	if IsErrCode(err, "M_USER_IN_USE") {
+		return nil
+	}
+	return err
+}
+
+// SetDisplayName sets a virtual user's display name
+func (c *Client) SetDisplayName(ctx context.Context, userID, name string) error {
+	path := "/_matrix/client/v3/profile/" + url.PathEscape(userID) + "/displayname"
+	return c.do(ctx, http.MethodPut, path, userID, map[string]string{"displayname": name}, nil)
+}
+
+// Join makes a user join a room (by ID or alias) and returns the room ID
+func (c *Client) Join(ctx context.Context, userID, room string) (string, error) {
+	var resp struct {
+		RoomID string `json:"room_id"`
+	}
+	path := "/_matrix/client/v3/join/" + url.PathEscape(room)
+	if err := c.do(ctx, http.MethodPost, path, userID, struct{}{}, &resp); err != nil {
+		return "", err
+	}
+	return resp.RoomID, nil
+}
+
+// Invite invites a user to a room, as another user
+func (c *Client) Invite(ctx context.Context, asUserID, roomID, userID string) error {
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomID) + "/invite"
+	err := c.do(ctx, http.MethodPost, path, asUserID, map[string]string{"user_id": userID}, nil)
+	if IsErrCode(err, "M_FORBIDDEN") {
+		// Already in the room (or the room is public and needs no invite)
+		return nil
+	}
+	return err
+}
+
+// Leave makes a user leave a room
+func (c *Client) Leave(ctx context.Context, userID, roomID string) error {
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomID) + "/leave"
+	return c.do(ctx, http.MethodPost, path, userID, struct{}{}, nil)
+}
+
+// SendMessage sends an m.room.message event. txnID makes retries idempotent.
+func (c *Client) SendMessage(ctx context.Context, userID, roomID, txnID string, content MessageContent) (string, error) {
+	var resp struct {
+		EventID string `json:"event_id"`
+	}
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomID) + "/send/m.room.message/" + url.PathEscape(txnID)
+	if err := c.do(ctx, http.MethodPut, path, userID, content, &resp); err != nil {
+		return "", err
+	}
+	return resp.EventID, nil
+}
+
+// SetTyping starts or stops a user's typing notification in a room
+func (c *Client) SetTyping(ctx context.Context, userID, roomID string, typing bool, timeout time.Duration) error {
+	body := map[string]interface{}{"typing": typing}
+	if typing {
+		body["timeout"] = timeout.Milliseconds()
+	}
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomID) + "/typing/" + url.PathEscape(userID)
+	return c.do(ctx, http.MethodPut, path, userID, body, nil)
+}
+
+// MediaURL turns an mxc:// content URI into an HTTP download link on the homeserver
+func (c *Client) MediaURL(mxc string) string {
+	serverAndID, ok := strings.CutPrefix(mxc, "mxc://")
+	if !ok {
+		return ""
+	}
+	return c.cfg.HomeserverURL + "/_matrix/media/v3/download/" + serverAndID
+}
+
+// do sends a request as the given user (empty = the bot) and decodes the response into out
+func (c *Client) do(ctx context.Context, method, path, asUser string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	endpoint := c.cfg.HomeserverURL + path
+	if asUser != "" {
+		endpoint += "?user_id=" + url.QueryEscape(asUser)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.cfg.ASToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("matrix: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		mErr := &Error{Status: resp.StatusCode}
+		if json.Unmarshal(respBody, mErr) != nil || mErr.ErrCode == "" {
+			mErr.ErrCode = "M_UNKNOWN"
+			mErr.Message = strings.TrimSpace(string(respBody))
+		}
+		return mErr
+	}
+	if out != nil {
+		return json.Unmarshal(respBody, out)
+	}
+	return nil
+}
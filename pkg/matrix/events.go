@@ -0,0 +1,82 @@
+package matrix
+
+import "encoding/json"
+
+// Event types the bridge handles
+const (
+	EventMessage = "m.room.message"
+	EventMember  = "m.room.member"
+	EventTyping  = "m.typing"
+)
+
+// Membership states of m.room.member events
+const (
+	MembershipJoin   = "join"
+	MembershipLeave  = "leave"
+	MembershipBan    = "ban"
+	MembershipInvite = "invite"
+)
+
+// Message types of m.room.message events
+const (
+	MsgText   = "m.text"
+	MsgNotice = "m.notice"
+	MsgEmote  = "m.emote"
+)
+
+// Transaction is a batch of events the homeserver pushes to the service
+// (PUT /_matrix/app/v1/transactions/{txnId})
+type Transaction struct {
+	Events []Event `json:"events"`
+	// Ephemeral events (typing) are only sent when the registration has
+	// receive_ephemeral: true; older homeservers use the MSC2409 field name
+	Ephemeral        []Event `json:"ephemeral"`
+	EphemeralMSC2409 []Event `json:"de.sorunome.msc2409.ephemeral"`
+}
+
+// EphemeralEvents returns the transaction's ephemeral events under either field name
+func (t *Transaction) EphemeralEvents() []Event {
+	if len(t.Ephemeral) > 0 {
+		return t.Ephemeral
+	}
+	return t.EphemeralMSC2409
+}
+
+// Event is a room event
+type Event struct {
+	Type           string          `json:"type"`
+	EventID        string          `json:"event_id"`
+	RoomID         string          `json:"room_id"`
+	Sender         string          `json:"sender"`
+	StateKey       *string         `json:"state_key,omitempty"`
+	OriginServerTS int64           `json:"origin_server_ts"`
+	Content        json.RawMessage `json:"content"`
+}
+
+// MessageContent is the content of an m.room.message event
+type MessageContent struct {
+	MsgType       string `json:"msgtype"`
+	Body          string `json:"body"`
+	Format        string `json:"format,omitempty"`
+	FormattedBody string `json:"formatted_body,omitempty"`
+	URL           string `json:"url,omitempty"` // mxc:// URI of media messages
+	RelatesTo     *struct {
+		RelType string `json:"rel_type"`
+	} `json:"m.relates_to,omitempty"`
+}
+
+// IsEdit reports whether the message replaces an earlier one (rel_type m.replace)
+func (c *MessageContent) IsEdit() bool {
+	return c.RelatesTo != nil && c.RelatesTo.RelType == "m.replace"
+}
+
+// MemberContent is the content of an m.room.member event
+type MemberContent struct {
+	Membership  string `json:"membership"`
+	DisplayName string `json:"displayname"`
+}
+
+// TypingContent is the content of an m.typing ephemeral event
+type TypingContent struct {
+	UserIDs []string `json:"user_ids"`
+}
@@ -0,0 +1,213 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	apnsProductionURL = "https://api.push.apple.com"
+	apnsSandboxURL    = "https://api.sandbox.push.apple.com"
+
+	// Apple rejects provider tokens older than an hour and throttles refreshes
+	// more often than every 20 minutes
+	apnsTokenLifetime = 50 * time.Minute
+	apnsTimeout       = 10 * time.Second
+)
+
+// APNsConfig holds token-based (.p8) APNs authentication settings
+type APNsConfig struct {
+	KeyFile  string // path to the AuthKey_XXXXXXXXXX.p8 file
+	KeyID    string
+	TeamID   string
+	BundleID string // apns-topic
+	Sandbox  bool
+}
+
+// apnsProvider sends notifications directly to Apple Push Notification service over HTTP/2
+type apnsProvider struct {
+	config APNsConfig
+	key    *ecdsa.PrivateKey
+	client *http.Client
+	url    string
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+func newAPNsProvider(cfg APNsConfig) (*apnsProvider, error) {
+	if cfg.KeyID == "" || cfg.TeamID == "" || cfg.BundleID == "" {
+		return nil, fmt.Errorf("apns requires key ID, team ID and bundle ID")
+	}
+
+	pem, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read apns key: %w", err)
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(pem)
+	if err != nil {
+		return nil, fmt.Errorf("invalid apns key: %w", err)
+	}
+
+	url := apnsProductionURL
+	if cfg.Sandbox {
+		url = apnsSandboxURL
+	}
+
+	return &apnsProvider{
+		config: cfg,
+		key:    key,
+		client: &http.Client{Timeout: apnsTimeout}, // net/http negotiates HTTP/2 over TLS
+		url:    url,
+	}, nil
+}
+
+func (p *apnsProvider) Name() string {
+	return "apns"
+}
+
+// Send delivers the notification to each device token
+func (p *apnsProvider) Send(ctx context.Context, tokens []string, push Push) ([]string, error) {
+	body, err := p.payload(push)
+	if err != nil {
+		return nil, err
+	}
+
+	bearer, err := p.authToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var invalid []string
+	var lastErr error
+	for _, token := range tokens {
+		reason, status, err := p.sendOne(ctx, token, bearer, body, push.VoIP)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		switch {
+		case status == http.StatusOK:
+		case status == http.StatusGone || reason == "BadDeviceToken" || reason == "DeviceTokenNotForTopic":
+			invalid = append(invalid, token)
+		default:
+			log.Printf("⚠️ APNs failure for token %s: %d %s", token, status, reason)
+		}
+	}
+
+	if lastErr != nil && len(invalid) == 0 {
+		return nil, lastErr
+	}
+	return invalid, nil
+}
+
+// sendOne posts a single notification and returns APNs' rejection reason, if any
+func (p *apnsProvider) sendOne(ctx context.Context, token, bearer string, body []byte, voip bool) (string, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url+"/3/device/"+token, bytes.NewReader(body))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Authorization", "bearer "+bearer)
+	if voip {
+		// PushKit: the app's VoIP topic, and no point delivering a ring late
+		req.Header.Set("apns-topic", p.config.BundleID+".voip")
+		req.Header.Set("apns-push-type", "voip")
+		req.Header.Set("apns-expiration", "0")
+	} else {
+		req.Header.Set("apns-topic", p.config.BundleID)
+		req.Header.Set("apns-push-type", "alert")
+	}
+	req.Header.Set("apns-priority", "10")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("apns request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return "", resp.StatusCode, nil
+	}
+
+	var apnsErr struct {
+		Reason string `json:"reason"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	_ = json.Unmarshal(raw, &apnsErr)
+
+	if apnsErr.Reason == "ExpiredProviderToken" {
+		p.invalidateToken()
+	}
+	return apnsErr.Reason, resp.StatusCode, nil
+}
+
+// payload builds the aps dictionary with custom data keys at the top level.
+// VoIP pushes carry only the data: the app reports the call to CallKit, which
+// shows the incoming-call screen.
+func (p *apnsProvider) payload(push Push) ([]byte, error) {
+	if push.VoIP {
+		payload := map[string]interface{}{"aps": map[string]interface{}{}}
+		for k, v := range push.Data {
+			payload[k] = v
+		}
+		return json.Marshal(payload)
+	}
+
+	payload := map[string]interface{}{
+		"aps": map[string]interface{}{
+			"alert": map[string]string{
+				"title": push.Title,
+				"body":  push.Body,
+			},
+			"sound": "default",
+		},
+	}
+	for k, v := range push.Data {
+		payload[k] = v
+	}
+	return json.Marshal(payload)
+}
+
+// authToken returns a cached ES256 provider token, signing a new one when it gets old
+func (p *apnsProvider) authToken() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" && time.Since(p.issuedAt) < apnsTokenLifetime {
+		return p.token, nil
+	}
+
+	now := time.Now()
+	t := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": p.config.TeamID,
+		"iat": now.Unix(),
+	})
+	t.Header["kid"] = p.config.KeyID
+
+	signed, err := t.SignedString(p.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign apns token: %w", err)
+	}
+	p.token = signed
+	p.issuedAt = now
+	return signed, nil
+}
+
+func (p *apnsProvider) invalidateToken() {
+	p.mu.Lock()
+	p.token = ""
+	p.mu.Unlock()
+}
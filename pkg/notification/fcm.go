@@ -4,95 +4,89 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	firebase "firebase.google.com/go/v4"
 	"firebase.google.com/go/v4/messaging"
-	"github.com/google/uuid"
-	"github.com/quocanhngo/gotalk/internal/repository"
 	"google.golang.org/api/option"
 )
 
-// NotificationService handles FCM notifications
-type NotificationService struct {
-	client   *messaging.Client
-	userRepo *repository.UserRepository
-}
+// fcmCallTTL drops a call push that couldn't be delivered while still ringing
+const fcmCallTTL = 30 * time.Second
 
-// NewNotificationService creates a new FCM notification service
-func NewNotificationService(credentialsFile string, userRepo *repository.UserRepository) (*NotificationService, error) {
-	if credentialsFile == "" {
-		log.Println("⚠️ Firebase credentials not provided, push notifications disabled")
-		return nil, nil
-	}
+// fcmProvider sends notifications through Firebase Cloud Messaging (Android, web,
+// and iOS devices registered with FCM tokens)
+type fcmProvider struct {
+	client *messaging.Client
+}
 
+func newFCMProvider(credentialsFile string) (*fcmProvider, error) {
 	opt := option.WithCredentialsFile(credentialsFile)
 	app, err := firebase.NewApp(context.Background(), nil, opt)
 	if err != nil {
-		// Log warning instead of error to not block server startup
-		log.Printf("⚠️ Failed to initialize Firebase app: %v (push notifications disabled)", err)
-		return nil, nil
+		return nil, fmt.Errorf("failed to initialize Firebase app: %w", err)
 	}
 
 	client, err := app.Messaging(context.Background())
 	if err != nil {
-		log.Printf("⚠️ Failed to get messaging client: %v", err)
-		return nil, nil
+		return nil, fmt.Errorf("failed to get messaging client: %w", err)
 	}
+	return &fcmProvider{client: client}, nil
+}
 
-	log.Println("✅ Firebase FCM initialized")
-	return &NotificationService{
-		client:   client,
-		userRepo: userRepo,
-	}, nil
+func (p *fcmProvider) Name() string {
+	return "fcm"
 }
 
-// SendMessageNotification sends a push notification for a new chat message
-func (s *NotificationService) SendMessageNotification(ctx context.Context, receiverID uuid.UUID, senderName string, content string, conversationID uuid.UUID) error {
-	if s == nil || s.client == nil {
-		return nil
-	}
+// Send multicasts the notification and reports unregistered tokens
+func (p *fcmProvider) Send(ctx context.Context, tokens []string, push Push) ([]string, error) {
+	message := p.message(tokens, push)
 
-	// Check if user has notifications enabled
-	user, err := s.userRepo.FindByID(receiverID)
+	br, err := p.client.SendEachForMulticast(ctx, message)
 	if err != nil {
-		return err
-	}
-	if !user.IsNotificationEnabled {
-		return nil
+		return nil, fmt.Errorf("error sending multicast message: %w", err)
 	}
 
-	// Get user devices
-	devices, err := s.userRepo.GetUserDevices(receiverID)
-	if err != nil {
-		return err
-	}
-
-	if len(devices) == 0 {
-		return nil
-	}
-
-	if content == "" {
-		content = "Sent an attachment"
+	var invalid []string
+	if br.FailureCount > 0 {
+		for idx, resp := range br.Responses {
+			if resp.Success {
+				continue
+			}
+			// registration-token-not-registered (app uninstalled, token rotated) or a
+			// token minted for another Firebase project will never succeed again
+			if messaging.IsUnregistered(resp.Error) || messaging.IsSenderIDMismatch(resp.Error) {
+				invalid = append(invalid, tokens[idx])
+				continue
+			}
+			log.Printf("⚠️ FCM failure for token %s: %v", tokens[idx], resp.Error)
+		}
 	}
+	return invalid, nil
+}
 
-	// Prepare token list
-	tokens := make([]string, 0, len(devices))
-	for _, d := range devices {
-		tokens = append(tokens, d.FCMToken)
+// message builds the multicast: a notification shown by the system, or for
+// calls a high-priority data message that wakes the app to ring
+func (p *fcmProvider) message(tokens []string, push Push) *messaging.MulticastMessage {
+	if push.VoIP {
+		ttl := fcmCallTTL
+		return &messaging.MulticastMessage{
+			Tokens: tokens,
+			Data:   push.Data,
+			Android: &messaging.AndroidConfig{
+				Priority: "high",
+				TTL:      &ttl,
+			},
+		}
 	}
 
-	// Create message
-	message := &messaging.MulticastMessage{
+	return &messaging.MulticastMessage{
 		Tokens: tokens,
 		Notification: &messaging.Notification{
-			Title: senderName,
-			Body:  content,
-		},
-		Data: map[string]string{
-			"type":            "new_message",
-			"conversation_id": conversationID.String(),
-			"sender_name":     senderName,
+			Title: push.Title,
+			Body:  push.Body,
 		},
+		Data: push.Data,
 		Android: &messaging.AndroidConfig{
 			Priority: "high",
 			Notification: &messaging.AndroidNotification{
@@ -107,21 +101,4 @@ func (s *NotificationService) SendMessageNotification(ctx context.Context, recei
 			},
 		},
 	}
-
-	// Send
-	br, err := s.client.SendMulticast(ctx, message)
-	if err != nil {
-		return fmt.Errorf("error sending multicast message: %w", err)
-	}
-
-	if br.FailureCount > 0 {
-		// Log failures
-		for idx, resp := range br.Responses {
-			if !resp.Success {
-				log.Printf("⚠️ FCM failure for token %s: %v", tokens[idx], resp.Error)
-			}
-		}
-	}
-
-	return nil
 }
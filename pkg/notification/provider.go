@@ -0,0 +1,23 @@
+package notification
+
+import "context"
+
+// Push is a provider-agnostic push notification
+type Push struct {
+	Title string
+	Body  string
+	Data  map[string]string
+
+	// VoIP marks an incoming call: APNs delivers it through PushKit to VoIP
+	// tokens, FCM as a high-priority data-only message the app turns into a
+	// native incoming-call screen
+	VoIP bool
+}
+
+// Provider delivers push notifications to device tokens of one platform family.
+// Send returns the tokens the provider reported as permanently invalid so the
+// caller can prune them.
+type Provider interface {
+	Name() string
+	Send(ctx context.Context, tokens []string, push Push) (invalid []string, err error)
+}
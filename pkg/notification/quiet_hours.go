@@ -0,0 +1,129 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	quietPendingKeyPrefix = "gotalk:quiet:pending:" // HASH conversation ID -> suppressed message count
+	quietDueKey           = "gotalk:quiet:due"      // ZSET user ID -> unix time their quiet hours end
+	quietPendingTTL       = 48 * time.Hour
+	quietPollInterval     = time.Minute
+)
+
+// mentionPattern matches @handle tokens; the handle ends at the first character
+// that can't be part of one, so "@ann" doesn't match "@anna"
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@(\w{1,30})\b`)
+
+// MentionedHandles returns the lowercased handles mentioned in a message
+func MentionedHandles(content string) map[string]bool {
+	handles := map[string]bool{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		handles[strings.ToLower(m[1])] = true
+	}
+	return handles
+}
+
+// IsMention reports whether a message mentions the user by @handle or mentions everyone
+func IsMention(content, handle string) bool {
+	handles := MentionedHandles(content)
+	if handles["all"] || handles["everyone"] {
+		return true
+	}
+	return handle != "" && handles[strings.ToLower(handle)]
+}
+
+// deferForQuietHours records a suppressed push so a summary can be sent when the window ends
+func (s *NotificationService) deferForQuietHours(ctx context.Context, user *model.User, conversationID uuid.UUID, endsAt time.Time) {
+	if !user.QuietHoursSummary || s.rdb == nil {
+		return
+	}
+
+	key := quietPendingKeyPrefix + user.ID.String()
+	pipe := s.rdb.TxPipeline()
+	pipe.HIncrBy(ctx, key, conversationID.String(), 1)
+	pipe.Expire(ctx, key, quietPendingTTL)
+	pipe.ZAdd(ctx, quietDueKey, redis.Z{Score: float64(endsAt.Unix()), Member: user.ID.String()})
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("⚠️ Failed to defer push for user %s: %v", user.ID, err)
+	}
+}
+
+// RunQuietHoursSummary sends a summary push to users whose quiet hours just ended,
+// blocking until ctx is cancelled
+func (s *NotificationService) RunQuietHoursSummary(ctx context.Context) {
+	if s == nil || s.rdb == nil {
+		return
+	}
+
+	ticker := time.NewTicker(quietPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			due, err := s.rdb.ZRangeByScore(ctx, quietDueKey, &redis.ZRangeBy{
+				Min: "-inf",
+				Max: strconv.FormatInt(time.Now().Unix(), 10),
+			}).Result()
+			if err != nil {
+				continue
+			}
+			for _, member := range due {
+				// ZREM guards against another instance sending the same summary
+				if removed, _ := s.rdb.ZRem(ctx, quietDueKey, member).Result(); removed == 1 {
+					s.sendQuietHoursSummary(ctx, member)
+				}
+			}
+		}
+	}
+}
+
+// sendQuietHoursSummary pushes one notification summarizing what was suppressed
+func (s *NotificationService) sendQuietHoursSummary(ctx context.Context, member string) {
+	userID, err := uuid.Parse(member)
+	if err != nil {
+		return
+	}
+
+	key := quietPendingKeyPrefix + member
+	pending, err := s.rdb.HGetAll(ctx, key).Result()
+	if err != nil || len(pending) == 0 {
+		return
+	}
+	s.rdb.Del(ctx, key)
+
+	var total int64
+	var lastConv string
+	for convID, count := range pending {
+		n, _ := strconv.ParseInt(count, 10, 64)
+		total += n
+		lastConv = convID
+	}
+	if total == 0 {
+		return
+	}
+
+	body := fmt.Sprintf("You received %d new messages in %d conversations during quiet hours", total, len(pending))
+	data := map[string]string{"type": "quiet_hours_summary"}
+	if len(pending) == 1 {
+		body = fmt.Sprintf("You received %d new messages during quiet hours", total)
+		data["conversation_id"] = lastConv
+	}
+
+	if err := s.sendToUser(ctx, userID, Push{Title: "GoTalk", Body: body, Data: data}); err != nil {
+		log.Printf("⚠️ Failed to send quiet hours summary to user %s: %v", userID, err)
+	}
+}
@@ -0,0 +1,271 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/quocanhngo/gotalk/internal/model"
+	"github.com/quocanhngo/gotalk/internal/repository"
+	"github.com/redis/go-redis/v9"
+)
+
+// Config holds the credentials for each push provider. Providers without
+// credentials are disabled.
+type Config struct {
+	FirebaseCredentialsFile string
+	APNs                    APNsConfig
+	VAPID                   VAPIDConfig
+}
+
+// NotificationService routes push notifications to the provider matching each device
+type NotificationService struct {
+	fcm      Provider
+	apns     Provider
+	webPush  *webPushProvider
+	userRepo *repository.UserRepository
+	rdb      *redis.Client
+}
+
+// NewNotificationService creates a notification service with every configured provider.
+// It returns nil when no provider is available so push stays optional.
+func NewNotificationService(cfg Config, userRepo *repository.UserRepository, rdb *redis.Client) (*NotificationService, error) {
+	s := &NotificationService{userRepo: userRepo, rdb: rdb}
+
+	if cfg.FirebaseCredentialsFile != "" {
+		fcm, err := newFCMProvider(cfg.FirebaseCredentialsFile)
+		if err != nil {
+			// Log warning instead of error to not block server startup
+			log.Printf("⚠️ %v (FCM push disabled)", err)
+		} else {
+			s.fcm = fcm
+			log.Println("✅ Firebase FCM initialized")
+		}
+	}
+
+	if cfg.APNs.KeyFile != "" {
+		apns, err := newAPNsProvider(cfg.APNs)
+		if err != nil {
+			log.Printf("⚠️ Failed to initialize APNs: %v (iOS devices fall back to FCM)", err)
+		} else {
+			s.apns = apns
+			log.Println("✅ APNs initialized")
+		}
+	}
+
+	if cfg.VAPID.PrivateKey != "" {
+		webPush, err := newWebPushProvider(cfg.VAPID)
+		if err != nil {
+			log.Printf("⚠️ Failed to initialize Web Push: %v", err)
+		} else {
+			s.webPush = webPush
+			log.Println("✅ Web Push (VAPID) initialized")
+		}
+	}
+
+	if s.fcm == nil && s.apns == nil && s.webPush == nil {
+		log.Println("⚠️ No push provider configured, push notifications disabled")
+		return nil, nil
+	}
+	return s, nil
+}
+
+// SendMessageNotification sends a push notification for a new chat message
+func (s *NotificationService) SendMessageNotification(ctx context.Context, receiverID uuid.UUID, senderName string, content string, conversationID uuid.UUID) error {
+	if s == nil {
+		return nil
+	}
+
+	// Check if user has notifications enabled
+	user, err := s.userRepo.FindByID(receiverID)
+	if err != nil {
+		return err
+	}
+	if !user.IsNotificationEnabled {
+		return nil
+	}
+
+	// Quiet hours: hold the push (mentions may still break through)
+	if quiet, endsAt := user.QuietHoursWindow(time.Now()); quiet {
+		if !user.QuietHoursAllowMentions || !IsMention(content, user.Handle) {
+			s.deferForQuietHours(ctx, user, conversationID, endsAt)
+			return nil
+		}
+	}
+
+	if content == "" {
+		content = "Sent an attachment"
+	}
+
+	return s.sendToUser(ctx, receiverID, Push{
+		Title: senderName,
+		Body:  content,
+		Data: map[string]string{
+			"type":            "new_message",
+			"conversation_id": conversationID.String(),
+			"sender_name":     senderName,
+		},
+	})
+}
+
+// Call is an incoming call to ring on the callee's devices
+type Call struct {
+	ID             uuid.UUID
+	CallerID       uuid.UUID
+	CallerName     string
+	ConversationID uuid.UUID
+	CallType       string // "audio" or "video"
+}
+
+// SendCallNotification rings a callee who has no live connection. iOS devices
+// with a PushKit token get a VoIP push and Android ones a high-priority data
+// message, so the app can show the native incoming-call screen; iOS devices
+// without a PushKit token and browsers get a regular notification.
+func (s *NotificationService) SendCallNotification(ctx context.Context, calleeID uuid.UUID, call Call) error {
+	if s == nil {
+		return nil
+	}
+
+	user, err := s.userRepo.FindByID(calleeID)
+	if err != nil {
+		return err
+	}
+	// A call missed this way still shows up as a missed_call notification
+	if !user.IsNotificationEnabled {
+		return nil
+	}
+	if quiet, _ := user.QuietHoursWindow(time.Now()); quiet {
+		return nil
+	}
+
+	devices, err := s.userRepo.GetUserDevices(calleeID)
+	if err != nil {
+		return err
+	}
+	hasVoIP := s.apns != nil && slices.ContainsFunc(devices, func(d model.UserDevice) bool {
+		return d.DeviceType == model.DeviceTypeIOSVoIP
+	})
+
+	ring := make(map[Provider][]string)  // VoIP / data pushes
+	alert := make(map[Provider][]string) // regular notifications
+	for _, d := range devices {
+		switch {
+		case d.DeviceType == model.DeviceTypeIOSVoIP:
+			if s.apns != nil {
+				ring[s.apns] = append(ring[s.apns], d.FCMToken)
+			}
+		case d.DeviceType == model.DeviceTypeIOS:
+			// The PushKit token rings the phone; without one, fall back to an alert
+			if p := s.providerFor(d.DeviceType); p != nil && !hasVoIP {
+				alert[p] = append(alert[p], d.FCMToken)
+			}
+		default:
+			if p := s.providerFor(d.DeviceType); p != nil {
+				ring[p] = append(ring[p], d.FCMToken)
+			}
+		}
+	}
+
+	kind := "call"
+	if call.CallType == "video" {
+		kind = "video call"
+	}
+	push := Push{
+		Title: call.CallerName,
+		Body:  "Incoming " + kind,
+		Data: map[string]string{
+			"type":            "incoming_call",
+			"call_id":         call.ID.String(),
+			"caller_id":       call.CallerID.String(),
+			"caller_name":     call.CallerName,
+			"conversation_id": call.ConversationID.String(),
+			"call_type":       call.CallType,
+		},
+	}
+	s.send(ctx, calleeID, alert, push)
+	push.VoIP = true
+	s.send(ctx, calleeID, ring, push)
+	push.VoIP = false
+	s.sendWebPush(ctx, calleeID, push)
+	return nil
+}
+
+// sendToUser delivers a push to all of a user's devices, grouped by provider,
+// and prunes tokens the providers report as invalid
+func (s *NotificationService) sendToUser(ctx context.Context, userID uuid.UUID, push Push) error {
+	devices, err := s.userRepo.GetUserDevices(userID)
+	if err != nil {
+		return err
+	}
+
+	groups := make(map[Provider][]string)
+	for _, d := range devices {
+		if p := s.providerFor(d.DeviceType); p != nil {
+			groups[p] = append(groups[p], d.FCMToken)
+		}
+	}
+	s.send(ctx, userID, groups, push)
+	s.sendWebPush(ctx, userID, push)
+	return nil
+}
+
+// send delivers a push to the grouped device tokens and prunes the ones the
+// providers report as invalid
+func (s *NotificationService) send(ctx context.Context, userID uuid.UUID, groups map[Provider][]string, push Push) {
+	for provider, tokens := range groups {
+		invalid, err := provider.Send(ctx, tokens, push)
+		if err != nil {
+			log.Printf("⚠️ %s push to user %s failed: %v", provider.Name(), userID, err)
+			continue
+		}
+		if len(invalid) > 0 {
+			if err := s.userRepo.DeleteDevicesByToken(invalid); err != nil {
+				log.Printf("⚠️ Failed to prune invalid %s tokens: %v", provider.Name(), err)
+			} else {
+				log.Printf("🧹 Pruned %d invalid %s device tokens", len(invalid), provider.Name())
+			}
+		}
+	}
+}
+
+// sendWebPush delivers a push to the user's browser subscriptions, removing expired ones
+func (s *NotificationService) sendWebPush(ctx context.Context, userID uuid.UUID, push Push) {
+	if s.webPush == nil {
+		return
+	}
+
+	subs, err := s.userRepo.GetWebPushSubscriptions(userID)
+	if err != nil {
+		log.Printf("⚠️ Failed to load web push subscriptions for user %s: %v", userID, err)
+		return
+	}
+
+	for _, sub := range subs {
+		err := s.webPush.Send(ctx, sub, push)
+		if errors.Is(err, ErrSubscriptionGone) {
+			_ = s.userRepo.DeleteWebPushSubscription(sub.Endpoint)
+			continue
+		}
+		if err != nil {
+			log.Printf("⚠️ Web push to user %s failed: %v", userID, err)
+		}
+	}
+}
+
+// providerFor picks APNs for iOS devices when configured, FCM for everything
+// else. VoIP tokens only take calls, see SendCallNotification.
+func (s *NotificationService) providerFor(deviceType string) Provider {
+	if deviceType == model.DeviceTypeIOSVoIP {
+		return nil
+	}
+	if deviceType == model.DeviceTypeIOS && s.apns != nil {
+		return s.apns
+	}
+	if s.fcm != nil {
+		return s.fcm
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/quocanhngo/gotalk/internal/model"
+)
+
+const (
+	webPushTTL        = 24 * time.Hour // how long the push service keeps an undelivered message
+	webPushTimeout    = 10 * time.Second
+	webPushTokenTTL   = 12 * time.Hour // VAPID JWTs may be valid for at most 24h
+	webPushRecordSize = 4096
+)
+
+// ErrSubscriptionGone is returned when the push service no longer knows the subscription
+var ErrSubscriptionGone = errors.New("web push subscription expired or unsubscribed")
+
+// VAPIDConfig holds the application server keys used to sign Web Push requests
+type VAPIDConfig struct {
+	PublicKey  string // base64url uncompressed P-256 point (given to browsers as applicationServerKey)
+	PrivateKey string // base64url raw P-256 scalar
+	Subject    string // mailto: or https: contact for push services
+}
+
+// GenerateVAPIDKeys creates a new base64url-encoded VAPID key pair
+func GenerateVAPIDKeys() (publicKey, privateKey string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+		base64.RawURLEncoding.EncodeToString(key.Bytes()), nil
+}
+
+// webPushProvider delivers notifications to browser push services (RFC 8030)
+// with aes128gcm payload encryption (RFC 8291) and VAPID authentication (RFC 8292)
+type webPushProvider struct {
+	config    VAPIDConfig
+	signKey   *ecdsa.PrivateKey
+	publicKey string
+	client    *http.Client
+}
+
+func newWebPushProvider(cfg VAPIDConfig) (*webPushProvider, error) {
+	if cfg.Subject == "" {
+		return nil, fmt.Errorf("vapid subject is required")
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cfg.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vapid private key: %w", err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vapid private key: %w", err)
+	}
+
+	// jwt signs with crypto/ecdsa keys; rebuild one from the raw scalar and point
+	pub := key.PublicKey().Bytes()
+	signKey := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(pub[1:33]),
+			Y:     new(big.Int).SetBytes(pub[33:]),
+		},
+		D: new(big.Int).SetBytes(raw),
+	}
+
+	return &webPushProvider{
+		config:    cfg,
+		signKey:   signKey,
+		publicKey: base64.RawURLEncoding.EncodeToString(pub),
+		client:    &http.Client{Timeout: webPushTimeout},
+	}, nil
+}
+
+// Send encrypts the notification for one subscription and posts it to its push service
+func (p *webPushProvider) Send(ctx context.Context, sub model.WebPushSubscription, push Push) error {
+	payload, err := json.Marshal(map[string]interface{}{
+		"title": push.Title,
+		"body":  push.Body,
+		"data":  push.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	body, err := encryptWebPush(sub, payload)
+	if err != nil {
+		return err
+	}
+
+	auth, err := p.vapidAuthorization(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", auth)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", fmt.Sprintf("%d", int(webPushTTL.Seconds())))
+	req.Header.Set("Urgency", "high")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("web push request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrSubscriptionGone
+	default:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("web push rejected (%d): %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+}
+
+// vapidAuthorization builds the "vapid t=<jwt>, k=<public key>" header for the endpoint's origin
+func (p *webPushProvider) vapidAuthorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid push endpoint: %w", err)
+	}
+
+	t := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(webPushTokenTTL).Unix(),
+		"sub": p.config.Subject,
+	})
+	signed, err := t.SignedString(p.signKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign vapid token: %w", err)
+	}
+	return fmt.Sprintf("vapid t=%s, k=%s", signed, p.publicKey), nil
+}
+
+// encryptWebPush encrypts a payload for a subscription as a single aes128gcm record (RFC 8291)
+func encryptWebPush(sub model.WebPushSubscription, payload []byte) ([]byte, error) {
+	uaPublicRaw, err := base64.RawURLEncoding.DecodeString(sub.P256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	authSecret, err := base64.RawURLEncoding.DecodeString(sub.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth secret: %w", err)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicRaw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+
+	// Ephemeral application server key, one per message
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	// IKM = HKDF(auth_secret, ecdh_secret, "WebPush: info" || 0x00 || ua_public || as_public)
+	prkKey, err := hkdf.Extract(sha256.New, sharedSecret, authSecret)
+	if err != nil {
+		return nil, err
+	}
+	keyInfo := "WebPush: info\x00" + string(uaPublicRaw) + string(asPublic)
+	ikm, err := hkdf.Expand(sha256.New, prkKey, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	prk, err := hkdf.Extract(sha256.New, ikm, salt)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Expand(sha256.New, prk, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// 0x02 marks the last (and only) record
+	plaintext := append(append([]byte{}, payload...), 0x02)
+	if len(plaintext)+gcm.Overhead() > webPushRecordSize {
+		return nil, fmt.Errorf("web push payload too large")
+	}
+
+	// Header: salt(16) || record size(4) || key id length(1) || key id (as_public)
+	var buf bytes.Buffer
+	buf.Write(salt)
+	binary.Write(&buf, binary.BigEndian, uint32(webPushRecordSize))
+	buf.WriteByte(byte(len(asPublic)))
+	buf.Write(asPublic)
+	buf.Write(gcm.Seal(nil, nonce, plaintext, nil))
+	return buf.Bytes(), nil
+}
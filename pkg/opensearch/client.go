@@ -0,0 +1,237 @@
+// Package opensearch is a minimal client for the OpenSearch (or
+// Elasticsearch) REST API, for the optional full-text index of messages
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Config says where the index lives
+type Config struct {
+	URL      string // e.g. https://search.example.com:9200
+	Index    string // index name, e.g. gotalk-messages
+	Username string // basic auth; empty for none
+	Password string
+}
+
+// Message is a message as indexed
+type Message struct {
+	ID             string    `json:"-"`
+	ConversationID string    `json:"conversation_id"`
+	SenderID       string    `json:"sender_id"`
+	Content        string    `json:"content"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Hit is a message matching a search
+type Hit struct {
+	ID    string
+	Score float64 // BM25 relevance, unbounded
+}
+
+// Error is an error response from the cluster
+type Error struct {
+	Status int
+	Type   string
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("opensearch: %d %s: %s", e.Status, e.Type, e.Reason)
+}
+
+// mapping keeps the ID fields exact and analyzes content with the standard
+// analyzer, which doesn't stem and so works for every language
+const mapping = `{
+  "mappings": {
+    "properties": {
+      "conversation_id": {"type": "keyword"},
+      "sender_id": {"type": "keyword"},
+      "content": {"type": "text", "analyzer": "standard"},
+      "created_at": {"type": "date"}
+    }
+  }
+}`
+
+// Client indexes and searches messages in one index
+type Client struct {
+	cfg    Config
+	client *http.Client
+}
+
+// New creates a client
+func New(cfg Config) *Client {
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+	return &Client{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// EnsureIndex creates the index with its mapping unless it exists
+func (c *Client) EnsureIndex(ctx context.Context) error {
+	err := c.do(ctx, http.MethodPut, "/"+url.PathEscape(c.cfg.Index), "application/json", strings.NewReader(mapping), nil)
+	var osErr *Error
+	if errors.As(err, &osErr) && osErr.Type == "resource_already_exists_exception" {
+		return nil
+	}
+	return err
+}
+
+// DeleteIndex drops the index and everything in it; a missing index is not an error
+func (c *Client) DeleteIndex(ctx context.Context) error {
+	err := c.do(ctx, http.MethodDelete, "/"+url.PathEscape(c.cfg.Index), "", nil, nil)
+	var osErr *Error
+	if errors.As(err, &osErr) && osErr.Status == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// Index adds or replaces one message
+func (c *Client) Index(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	path := "/" + url.PathEscape(c.cfg.Index) + "/_doc/" + url.PathEscape(msg.ID)
+	return c.do(ctx, http.MethodPut, path, "application/json", bytes.NewReader(body), nil)
+}
+
+// Bulk adds or replaces many messages in one request
+func (c *Client) Bulk(ctx context.Context, messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, msg := range messages {
+		action := map[string]map[string]string{"index": {"_index": c.cfg.Index, "_id": msg.ID}}
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+		if err := enc.Encode(msg); err != nil {
+			return err
+		}
+	}
+
+	var resp struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			ID    string `json:"_id"`
+			Error *struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", &body, &resp); err != nil {
+		return err
+	}
+	if resp.Errors {
+		for _, item := range resp.Items {
+			for _, result := range item {
+				if result.Error != nil {
+					return fmt.Errorf("opensearch: indexing %s: %s: %s", result.ID, result.Error.Type, result.Error.Reason)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Search finds the messages of the given conversations matching a query in
+// simple query string syntax ("quoted phrases", -excluded words, | for or),
+// best match first
+func (c *Client) Search(ctx context.Context, query string, conversationIDs []string, limit int) ([]Hit, error) {
+	request := map[string]interface{}{
+		"size":    limit,
+		"_source": false,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"must": map[string]interface{}{
+					"simple_query_string": map[string]interface{}{
+						"query":            query,
+						"fields":           []string{"content"},
+						"default_operator": "and",
+					},
+				},
+				"filter": map[string]interface{}{
+					"terms": map[string]interface{}{"conversation_id": conversationIDs},
+				},
+			},
+		},
+		"sort": []interface{}{"_score", map[string]string{"created_at": "desc"}},
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Hits struct {
+			Hits []struct {
+				ID    string  `json:"_id"`
+				Score float64 `json:"_score"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	path := "/" + url.PathEscape(c.cfg.Index) + "/_search"
+	if err := c.do(ctx, http.MethodPost, path, "application/json", bytes.NewReader(body), &resp); err != nil {
+		return nil, err
+	}
+	hits := make([]Hit, 0, len(resp.Hits.Hits))
+	for _, h := range resp.Hits.Hits {
+		hits = append(hits, Hit{ID: h.ID, Score: h.Score})
+	}
+	return hits, nil
+}
+
+func (c *Client) do(ctx context.Context, method, path, contentType string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.cfg.URL+path, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.cfg.Username != "" {
+		req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("opensearch: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var errResp struct {
+			Error struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		}
+		osErr := &Error{Status: resp.StatusCode}
+		if json.Unmarshal(respBody, &errResp) == nil && errResp.Error.Type != "" {
+			osErr.Type, osErr.Reason = errResp.Error.Type, errResp.Error.Reason
+		} else {
+			osErr.Type, osErr.Reason = "unknown", strings.TrimSpace(string(respBody))
+		}
+		return osErr
+	}
+	if out != nil {
+		return json.Unmarshal(respBody, out)
+	}
+	return nil
+}
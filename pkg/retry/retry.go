@@ -0,0 +1,46 @@
+// Package retry calls an operation until it succeeds, waiting exponentially
+// longer after each failure, e.g. to wait for a database at startup
+package retry
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff says how long to wait between attempts
+type Backoff struct {
+	Initial time.Duration // after the first failure
+	Max     time.Duration // waits double up to this
+}
+
+// Do calls fn until it returns nil or ctx is done. After a failure it calls
+// onRetry, when not nil, and waits: Initial, then twice as long each time up
+// to Max, less up to a fifth at random so many callers don't retry in step.
+// When ctx ends first, the last error is returned with ctx's.
+func Do(ctx context.Context, backoff Backoff, fn func(ctx context.Context) error, onRetry func(attempt int, err error, wait time.Duration)) error {
+	wait := backoff.Initial
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		}
+
+		jittered := wait - time.Duration(rand.Int64N(int64(wait)/5+1))
+		if onRetry != nil {
+			onRetry(attempt, err, jittered)
+		}
+		timer := time.NewTimer(jittered)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		case <-timer.C:
+		}
+		wait = min(2*wait, backoff.Max)
+	}
+}
@@ -0,0 +1,125 @@
+// Package secrets resolves secret references such as
+// "vault://secret/data/gotalk#jwt_secret" or "awssm://prod/gotalk#db_password"
+// against a secrets backend (HashiCorp Vault, AWS Secrets Manager).
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Reference schemes of the supported backends
+const (
+	SchemeVault             = "vault"
+	SchemeAWSSecretsManager = "awssm"
+)
+
+// Backend fetches one secret: a set of named fields. Backends whose secrets are
+// plain strings return them under the empty field name.
+type Backend interface {
+	Fetch(ctx context.Context, path string) (map[string]string, error)
+}
+
+// Ref points to one field of a secret, e.g. vault://secret/data/gotalk#jwt_secret
+type Ref struct {
+	Scheme string // backend name, e.g. "vault" or "awssm"
+	Path   string
+	Field  string // empty for the whole secret
+}
+
+func (r Ref) String() string {
+	s := r.Scheme + "://" + r.Path
+	if r.Field != "" {
+		s += "#" + r.Field
+	}
+	return s
+}
+
+// ParseRef parses a reference, reporting false for values that aren't one
+func ParseRef(value string) (Ref, bool) {
+	scheme, rest, ok := strings.Cut(value, "://")
+	if !ok || scheme == "" || strings.ContainsAny(scheme, " /") {
+		return Ref{}, false
+	}
+	path, field, _ := strings.Cut(rest, "#")
+	if path == "" {
+		return Ref{}, false
+	}
+	return Ref{Scheme: scheme, Path: path, Field: field}, true
+}
+
+// Resolver resolves references with the backend registered for their scheme
+type Resolver struct {
+	backends map[string]Backend
+}
+
+func NewResolver(backends map[string]Backend) *Resolver {
+	return &Resolver{backends: backends}
+}
+
+// IsRef reports whether value is a reference to a supported backend, as
+// opposed to a plain value (which may still look like a URL)
+func IsRef(value string) bool {
+	ref, ok := ParseRef(value)
+	return ok && (ref.Scheme == SchemeVault || ref.Scheme == SchemeAWSSecretsManager)
+}
+
+// ResolveAll resolves the references in refs (name -> reference), fetching each
+// secret once, and returns the values by name
+func (r *Resolver) ResolveAll(ctx context.Context, refs map[string]string) (map[string]string, error) {
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fetched := map[string]map[string]string{}
+	values := make(map[string]string, len(refs))
+	for _, name := range names {
+		ref, ok := ParseRef(refs[name])
+		if !ok {
+			return nil, fmt.Errorf("%s: %q is not a secret reference", name, refs[name])
+		}
+		backend, ok := r.backends[ref.Scheme]
+		if !ok {
+			return nil, fmt.Errorf("%s: no %s secrets backend is configured", name, ref.Scheme)
+		}
+
+		cacheKey := ref.Scheme + "://" + ref.Path
+		secret, ok := fetched[cacheKey]
+		if !ok {
+			var err error
+			if secret, err = backend.Fetch(ctx, ref.Path); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			fetched[cacheKey] = secret
+		}
+
+		value, err := field(secret, ref)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// field picks the referenced field; without one the secret must hold a single value
+func field(secret map[string]string, ref Ref) (string, error) {
+	if ref.Field != "" {
+		value, ok := secret[ref.Field]
+		if !ok {
+			return "", fmt.Errorf("secret %s has no field %q", ref.Scheme+"://"+ref.Path, ref.Field)
+		}
+		return value, nil
+	}
+	if len(secret) != 1 {
+		return "", fmt.Errorf("secret %s has %d fields, name one with #field", ref, len(secret))
+	}
+	for _, value := range secret {
+		return value, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,75 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// VaultConfig holds HashiCorp Vault connection settings
+type VaultConfig struct {
+	Addr      string // e.g. https://vault.example.com:8200
+	Token     string
+	Namespace string // Vault Enterprise namespace, optional
+}
+
+// Vault reads secrets from Vault's KV secrets engine (version 1 or 2). With
+// KV v2 the path includes "data", e.g. vault://secret/data/gotalk#jwt_secret.
+type Vault struct {
+	config VaultConfig
+	client *http.Client
+}
+
+func NewVault(cfg VaultConfig) *Vault {
+	cfg.Addr = strings.TrimSuffix(cfg.Addr, "/")
+	return &Vault{config: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Fetch reads the secret at path
+func (v *Vault) Fetch(ctx context.Context, path string) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.config.Addr+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.config.Token)
+	if v.config.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.config.Namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("vault: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("vault: GET %s: %s", path, resp.Status)
+	}
+
+	var payload struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return nil, fmt.Errorf("vault: invalid response for %s: %w", path, err)
+	}
+
+	// KV v2 nests the fields under data.data, next to data.metadata
+	data := payload.Data
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, ok := data["metadata"]; ok {
+			data = inner
+		}
+	}
+
+	secret := make(map[string]string, len(data))
+	for k, value := range data {
+		if s, ok := value.(string); ok {
+			secret[k] = s
+		} else {
+			secret[k] = fmt.Sprint(value)
+		}
+	}
+	return secret, nil
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"log"
 	"mime/multipart"
+	"net/url"
 	"path/filepath"
 	"strings"
 	"time"
@@ -31,9 +32,16 @@ type UploadResult struct {
 	MimeType string
 }
 
+// Folders that, unlike the rest of the bucket, aren't publicly readable
+const (
+	ExportsFolder = "exports" // conversation exports, downloaded through presigned URLs
+	ImportsFolder = "imports" // chat archives waiting to be imported
+)
+
 // MinIOStorage implements Storage interface using MinIO
 type MinIOStorage struct {
 	client    *minio.Client
+	signer    *minio.Client // signs presigned URLs for the public host
 	bucket    string
 	endpoint  string
 	publicURL string // External URL
@@ -73,7 +81,7 @@ func NewMinIO(cfg Config) (*MinIOStorage, error) {
 		}
 		log.Printf("📦 Created MinIO bucket: %s", cfg.Bucket)
 
-		// Set bucket policy to public read
+		// Set bucket policy to public read, except for exports and imports
 		policy := `{
 			"Version": "2012-10-17",
 			"Statement": [{
@@ -81,6 +89,14 @@ func NewMinIO(cfg Config) (*MinIOStorage, error) {
 				"Principal": {"AWS": ["*"]},
 				"Action": ["s3:GetObject"],
 				"Resource": ["arn:aws:s3:::` + cfg.Bucket + `/*"]
+			}, {
+				"Effect": "Deny",
+				"Principal": {"AWS": ["*"]},
+				"Action": ["s3:GetObject"],
+				"Resource": [
+					"arn:aws:s3:::` + cfg.Bucket + `/` + ExportsFolder + `/*",
+					"arn:aws:s3:::` + cfg.Bucket + `/` + ImportsFolder + `/*"
+				]
 			}]
 		}`
 		if err := client.SetBucketPolicy(ctx, cfg.Bucket, policy); err != nil {
@@ -88,8 +104,28 @@ func NewMinIO(cfg Config) (*MinIOStorage, error) {
 		}
 	}
 
+	// Presigned URLs are signed for the host they're fetched from, so with a
+	// public URL they need a client that addresses MinIO by that host. Signing
+	// happens locally; the fixed region keeps it from looking the bucket up.
+	signer := client
+	if cfg.PublicURL != "" {
+		public, err := url.Parse(cfg.PublicURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MinIO public URL: %w", err)
+		}
+		signer, err = minio.New(public.Host, &minio.Options{
+			Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+			Secure: public.Scheme == "https",
+			Region: "us-east-1",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create MinIO signer: %w", err)
+		}
+	}
+
 	return &MinIOStorage{
 		client:    client,
+		signer:    signer,
 		bucket:    cfg.Bucket,
 		endpoint:  cfg.Endpoint,
 		publicURL: cfg.PublicURL,
@@ -165,6 +201,97 @@ func (s *MinIOStorage) UploadFromReader(ctx context.Context, reader io.Reader, s
 	}, nil
 }
 
+// Open returns a seekable reader for an object. The caller must close it.
+func (s *MinIOStorage) Open(ctx context.Context, objectName string) (io.ReadSeekCloser, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	// GetObject is lazy; Stat surfaces "not found" before the caller starts reading
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return obj, nil
+}
+
+// ObjectInfo describes a stored object, for conditional and range requests
+type ObjectInfo struct {
+	Size         int64
+	ContentType  string
+	ETag         string // quoted, as sent in HTTP headers
+	LastModified time.Time
+}
+
+// OpenObject is Open, also returning the object's metadata
+func (s *MinIOStorage) OpenObject(ctx context.Context, objectName string) (io.ReadSeekCloser, *ObjectInfo, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	stat, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return obj, &ObjectInfo{
+		Size:         stat.Size,
+		ContentType:  stat.ContentType,
+		ETag:         `"` + stat.ETag + `"`,
+		LastModified: stat.LastModified,
+	}, nil
+}
+
+// DownloadToFile downloads an object into a local file (e.g. for media processing)
+func (s *MinIOStorage) DownloadToFile(ctx context.Context, objectName, filePath string) error {
+	if err := s.client.FGetObject(ctx, s.bucket, objectName, filePath, minio.GetObjectOptions{}); err != nil {
+		return fmt.Errorf("failed to download file: %w", err)
+	}
+	return nil
+}
+
+// UploadFromFile uploads a local file under the given object name
+func (s *MinIOStorage) UploadFromFile(ctx context.Context, filePath, objectName, contentType string) (*UploadResult, error) {
+	info, err := s.client.FPutObject(ctx, s.bucket, objectName, filePath, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	return &UploadResult{
+		URL:      s.GetPublicURL(objectName),
+		Key:      objectName,
+		FileSize: info.Size,
+		MimeType: contentType,
+	}, nil
+}
+
+// PresignedURL returns a time-limited download link for an object. The
+// browser saves it as downloadName.
+func (s *MinIOStorage) PresignedURL(ctx context.Context, objectName, downloadName string, expiry time.Duration) (string, error) {
+	params := url.Values{}
+	if downloadName != "" {
+		params.Set("response-content-disposition", fmt.Sprintf("attachment; filename=%q", downloadName))
+	}
+	u, err := s.signer.PresignedGetObject(ctx, s.bucket, objectName, expiry, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign URL: %w", err)
+	}
+	return u.String(), nil
+}
+
+// KeyFromURL extracts the object key from a public URL produced by GetPublicURL.
+// Returns false if the URL does not point to this bucket.
+func (s *MinIOStorage) KeyFromURL(url string) (string, bool) {
+	prefix := s.GetPublicURL("")
+	if !strings.HasPrefix(url, prefix) {
+		return "", false
+	}
+	key := strings.TrimPrefix(url, prefix)
+	return key, key != ""
+}
+
 // detectContentType returns MIME type based on file extension
 func detectContentType(ext string) string {
 	ext = strings.ToLower(ext)
@@ -193,8 +320,18 @@ func detectContentType(ext string) string {
 		return "audio/wav"
 	case ".pdf":
 		return "application/pdf"
-	case ".doc", ".docx":
+	case ".doc":
 		return "application/msword"
+	case ".xls":
+		return "application/vnd.ms-excel"
+	case ".ppt":
+		return "application/vnd.ms-powerpoint"
+	case ".docx":
+		return mimeDocx
+	case ".xlsx":
+		return mimeXlsx
+	case ".pptx":
+		return mimePptx
 	case ".zip":
 		return "application/zip"
 	default:
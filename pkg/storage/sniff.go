@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// sniffLen is the number of leading bytes inspected to detect the content type
+const sniffLen = 512
+
+// Office Open XML documents: ZIP packages told apart by their top-level folder
+const (
+	mimeDocx = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	mimeXlsx = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	mimePptx = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+)
+
+var ooxmlFolders = []struct{ prefix, mimeType string }{
+	{"word/", mimeDocx},
+	{"xl/", mimeXlsx},
+	{"ppt/", mimePptx},
+}
+
+// typeContainers maps types that sniffing can only detect by their container
+// to that container: any ZIP may be an OOXML document, and Word, Excel and
+// PowerPoint share the legacy OLE2 format
+var typeContainers = map[string]string{
+	mimeDocx:                        "application/zip",
+	mimeXlsx:                        "application/zip",
+	mimePptx:                        "application/zip",
+	"application/vnd.ms-excel":      "application/msword",
+	"application/vnd.ms-powerpoint": "application/msword",
+}
+
+// mimeAliases maps non-canonical MIME types (as sent by browsers or returned by
+// http.DetectContentType) to the canonical types used by the upload allow-list
+var mimeAliases = map[string]string{
+	"image/jpg":                    "image/jpeg",
+	"image/pjpeg":                  "image/jpeg",
+	"audio/mp3":                    "audio/mpeg",
+	"audio/x-wav":                  "audio/wav",
+	"audio/wave":                   "audio/wav",
+	"audio/vnd.wave":               "audio/wav",
+	"application/ogg":              "audio/ogg",
+	"application/x-zip-compressed": "application/zip",
+}
+
+// NormalizeMimeType lowercases a MIME type, strips parameters (e.g. charset)
+// and resolves known aliases to their canonical form
+func NormalizeMimeType(contentType string) string {
+	ct := strings.ToLower(strings.TrimSpace(contentType))
+	if i := strings.Index(ct, ";"); i >= 0 {
+		ct = strings.TrimSpace(ct[:i])
+	}
+	if canonical, ok := mimeAliases[ct]; ok {
+		return canonical
+	}
+	return ct
+}
+
+// MatchesSniffedType reports whether a file declared as the normalized type
+// declared may have sniffed as sniffed: the same type, or the container of a
+// type whose content sniffing couldn't identify, e.g. a ZIP for a .xlsx
+func MatchesSniffedType(declared, sniffed string) bool {
+	return declared == sniffed || typeContainers[declared] == sniffed
+}
+
+// SniffContentType detects the MIME type of a file from its content rather than
+// trusting the client-supplied header. It reads the first 512 bytes and rewinds
+// the reader so the file can be uploaded afterwards.
+func SniffContentType(r io.ReadSeeker) (string, error) {
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", fmt.Errorf("failed to read file header: %w", err)
+	}
+	buf = buf[:n]
+
+	// Magic numbers not covered (or covered too loosely) by http.DetectContentType
+	ct := sniffMagic(buf)
+	if ct == "application/zip" {
+		ct = sniffOOXML(r, buf)
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to rewind file: %w", err)
+	}
+	if ct != "" {
+		return ct, nil
+	}
+
+	return NormalizeMimeType(http.DetectContentType(buf)), nil
+}
+
+// sniffMagic checks magic numbers for documents, archives and media containers
+func sniffMagic(buf []byte) string {
+	switch {
+	// Legacy MS Office (OLE2 compound document)
+	case bytes.HasPrefix(buf, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}):
+		return "application/msword"
+
+	// ZIP archive - sniffOOXML tells OOXML documents apart
+	case bytes.HasPrefix(buf, []byte("PK\x03\x04")):
+		return "application/zip"
+
+	// QuickTime (.mov) - ISO BMFF with the "qt  " brand
+	case len(buf) >= 12 && bytes.Equal(buf[4:8], []byte("ftyp")) && bytes.Equal(buf[8:12], []byte("qt  ")):
+		return "video/quicktime"
+
+	// MP3 without ID3 tag (MPEG audio frame sync)
+	case len(buf) >= 2 && buf[0] == 0xFF && (buf[1]&0xE0) == 0xE0 && (buf[1]&0x06) != 0:
+		return "audio/mpeg"
+	}
+	return ""
+}
+
+// sniffOOXML returns the OOXML type of a ZIP from its entry names, or
+// application/zip. Files that can be read at any offset, as uploads can, are
+// checked against the whole central directory, since Word, Excel and
+// PowerPoint don't put their folder's entries first; other readers only
+// against the entries starting in the sniffed bytes.
+func sniffOOXML(r io.ReadSeeker, buf []byte) string {
+	var names []string
+	if ra, ok := r.(io.ReaderAt); ok {
+		if size, err := r.Seek(0, io.SeekEnd); err == nil {
+			if zr, err := zip.NewReader(ra, size); err == nil {
+				for _, f := range zr.File {
+					names = append(names, f.Name)
+				}
+			}
+		}
+	}
+	if names == nil {
+		names = localEntryNames(buf)
+	}
+
+	for _, name := range names {
+		for _, folder := range ooxmlFolders {
+			if strings.HasPrefix(name, folder.prefix) {
+				return folder.mimeType
+			}
+		}
+	}
+	return "application/zip"
+}
+
+// localEntryNames reads the names of the ZIP local file headers in buf,
+// stopping at the first entry that doesn't fit
+func localEntryNames(buf []byte) []string {
+	var names []string
+	for len(buf) >= 30 && bytes.HasPrefix(buf, []byte("PK\x03\x04")) {
+		flags := binary.LittleEndian.Uint16(buf[6:])
+		compressed := int(binary.LittleEndian.Uint32(buf[18:]))
+		nameLen := int(binary.LittleEndian.Uint16(buf[26:]))
+		extraLen := int(binary.LittleEndian.Uint16(buf[28:]))
+		if 30+nameLen > len(buf) {
+			break
+		}
+		names = append(names, string(buf[30:30+nameLen]))
+		// Sizes of streamed entries follow their data, so the next header can't be found
+		if flags&0x08 != 0 {
+			break
+		}
+		next := 30 + nameLen + extraLen + compressed
+		if next > len(buf) {
+			break
+		}
+		buf = buf[next:]
+	}
+	return names
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+)
+
+// zipWith builds a ZIP of empty entries with the given names, in order, with
+// their sizes in the local headers as Office writes them
+func zipWith(t *testing.T, names ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		if _, err := zw.CreateRaw(&zip.FileHeader{Name: name, Method: zip.Store}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// readSeeker hides bytes.Reader's ReadAt, as a reader that can't be read at
+// an offset would
+type readSeeker struct{ io.ReadSeeker }
+
+func TestSniffOOXML(t *testing.T) {
+	// Office writes docProps and _rels before the application's folder
+	lead := []string{"[Content_Types].xml", "_rels/.rels", "docProps/core.xml", "docProps/app.xml"}
+	for name, tc := range map[string]struct {
+		entries []string
+		want    string
+	}{
+		"docx":          {append(lead, "word/document.xml"), mimeDocx},
+		"xlsx":          {append(lead, "xl/workbook.xml"), mimeXlsx},
+		"pptx":          {append(lead, "ppt/presentation.xml"), mimePptx},
+		"docx, no lead": {[]string{"word/document.xml"}, mimeDocx},
+		"zip":           {[]string{"photos/a.jpg", "notes.txt"}, "application/zip"},
+	} {
+		data := zipWith(t, tc.entries...)
+		got, err := SniffContentType(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: sniffed %s, want %s", name, got, tc.want)
+		}
+
+		// Without ReadAt only the local headers in the first 512 bytes count
+		got, err = SniffContentType(readSeeker{bytes.NewReader(data)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%s without ReadAt: sniffed %s, want %s", name, got, tc.want)
+		}
+	}
+}
+
+func TestMatchesSniffedType(t *testing.T) {
+	for _, tc := range []struct {
+		declared, sniffed string
+		want              bool
+	}{
+		{mimeXlsx, mimeXlsx, true},
+		{mimeDocx, "application/zip", true},
+		{"application/vnd.ms-excel", "application/msword", true},
+		{mimeDocx, mimeXlsx, false},
+		{"application/zip", mimeDocx, false},
+		{"application/pdf", "application/zip", false},
+	} {
+		if got := MatchesSniffedType(tc.declared, tc.sniffed); got != tc.want {
+			t.Errorf("MatchesSniffedType(%s, %s) = %v", tc.declared, tc.sniffed, got)
+		}
+	}
+}
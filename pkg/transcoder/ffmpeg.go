@@ -0,0 +1,156 @@
+package transcoder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+)
+
+// Config holds ffmpeg binary configuration
+type Config struct {
+	FFmpegPath  string
+	FFprobePath string
+}
+
+// Result describes the renditions produced for a single video
+type Result struct {
+	VideoPath  string  // H.264/AAC MP4 with faststart (streaming friendly)
+	PosterPath string  // JPEG poster frame
+	Duration   float64 // seconds
+	Width      int
+	Height     int
+}
+
+// Transcoder converts uploaded videos into streaming-friendly formats using ffmpeg
+type Transcoder struct {
+	ffmpeg  string
+	ffprobe string
+}
+
+// New creates a new Transcoder, verifying that ffmpeg and ffprobe are available
+func New(cfg Config) (*Transcoder, error) {
+	ffmpeg, err := exec.LookPath(cfg.FFmpegPath)
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not found: %w", err)
+	}
+	ffprobe, err := exec.LookPath(cfg.FFprobePath)
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe not found: %w", err)
+	}
+	return &Transcoder{ffmpeg: ffmpeg, ffprobe: ffprobe}, nil
+}
+
+// Transcode converts inputPath to H.264/AAC MP4 and extracts a poster frame into outDir
+func (t *Transcoder) Transcode(ctx context.Context, inputPath, outDir string) (*Result, error) {
+	info, err := t.Probe(ctx, inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	videoPath := filepath.Join(outDir, "video.mp4")
+	if err := t.run(ctx, t.ffmpeg,
+		"-y", "-i", inputPath,
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "23", "-pix_fmt", "yuv420p",
+		// H.264 requires even dimensions
+		"-vf", "scale=trunc(iw/2)*2:trunc(ih/2)*2",
+		"-c:a", "aac", "-b:a", "128k",
+		// Move the moov atom to the front so playback can start before the download completes
+		"-movflags", "+faststart",
+		videoPath,
+	); err != nil {
+		return nil, fmt.Errorf("failed to transcode video: %w", err)
+	}
+
+	// Grab the poster 1s in, or the first frame for very short clips
+	seek := "1"
+	if info.Duration < 1 {
+		seek = "0"
+	}
+	posterPath := filepath.Join(outDir, "poster.jpg")
+	if err := t.run(ctx, t.ffmpeg,
+		"-y", "-ss", seek, "-i", inputPath,
+		"-frames:v", "1", "-q:v", "2",
+		posterPath,
+	); err != nil {
+		return nil, fmt.Errorf("failed to extract poster frame: %w", err)
+	}
+
+	return &Result{
+		VideoPath:  videoPath,
+		PosterPath: posterPath,
+		Duration:   info.Duration,
+		Width:      info.Width,
+		Height:     info.Height,
+	}, nil
+}
+
+// ProbeResult contains basic stream metadata reported by ffprobe
+type ProbeResult struct {
+	Duration float64
+	Width    int
+	Height   int
+}
+
+// Probe reads duration and dimensions of the first video stream
+func (t *Transcoder) Probe(ctx context.Context, inputPath string) (*ProbeResult, error) {
+	cmd := exec.CommandContext(ctx, t.ffprobe,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=width,height:format=duration",
+		"-of", "json",
+		inputPath,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w: %s", err, stderr.String())
+	}
+
+	var probe struct {
+		Streams []struct {
+			Width  int `json:"width"`
+			Height int `json:"height"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+	if len(probe.Streams) == 0 {
+		return nil, fmt.Errorf("no video stream found")
+	}
+
+	duration, _ := strconv.ParseFloat(probe.Format.Duration, 64)
+	return &ProbeResult{
+		Duration: duration,
+		Width:    probe.Streams[0].Width,
+		Height:   probe.Streams[0].Height,
+	}, nil
+}
+
+// run executes a command and includes stderr in the returned error
+func (t *Transcoder) run(ctx context.Context, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, lastLine(stderr.Bytes()))
+	}
+	return nil
+}
+
+// lastLine returns the last non-empty line of ffmpeg's output (usually the actual error)
+func lastLine(b []byte) string {
+	lines := bytes.Split(bytes.TrimSpace(b), []byte("\n"))
+	if len(lines) == 0 {
+		return ""
+	}
+	return string(lines[len(lines)-1])
+}
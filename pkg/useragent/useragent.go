@@ -0,0 +1,65 @@
+// Package useragent names the browser and operating system behind a
+// User-Agent header well enough to show users, e.g. "Chrome on Windows"
+package useragent
+
+import "strings"
+
+// browsers is checked in order: many user agents also claim to be the ones after them
+var browsers = []struct{ token, name string }{
+	{"edg/", "Edge"},
+	{"opr/", "Opera"},
+	{"samsungbrowser/", "Samsung Internet"},
+	{"firefox/", "Firefox"},
+	{"fxios/", "Firefox"},
+	{"crios/", "Chrome"},
+	{"chrome/", "Chrome"},
+	{"safari/", "Safari"},
+	{"okhttp/", "GoTalk for Android"},
+	{"cfnetwork/", "GoTalk for iOS"},
+	{"curl/", "curl"},
+}
+
+var systems = []struct{ token, name string }{
+	{"windows", "Windows"},
+	{"iphone", "iOS"},
+	{"ipad", "iPadOS"},
+	{"android", "Android"},
+	{"cros", "ChromeOS"},
+	{"mac os x", "macOS"},
+	{"macintosh", "macOS"},
+	{"linux", "Linux"},
+}
+
+// Parse returns the browser and operating system names; either is empty when unknown
+func Parse(userAgent string) (browser, os string) {
+	ua := strings.ToLower(userAgent)
+	for _, b := range browsers {
+		if strings.Contains(ua, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, s := range systems {
+		if strings.Contains(ua, s.token) {
+			os = s.name
+			break
+		}
+	}
+	return browser, os
+}
+
+// Describe names the device for a person: "Chrome on Windows", "Android",
+// or "Unknown device"
+func Describe(userAgent string) string {
+	browser, os := Parse(userAgent)
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	default:
+		return "Unknown device"
+	}
+}